@@ -3,6 +3,8 @@ package authorizer
 import (
 	"context"
 	"io"
+	"os"
+	"sync"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kit/tracing"
@@ -14,35 +16,182 @@ var _ influxdb.BackupService = (*BackupService)(nil)
 // against it appropriately.
 type BackupService struct {
 	s influxdb.BackupService
+
+	// scopes holds the options of the scoped backups created through the
+	// service, keyed by backup ID, so their files can be fetched with the
+	// same permissions that were needed to create them. Scopes are dropped
+	// once all files of their backup have been fetched or it is removed.
+	mu     sync.Mutex
+	scopes map[int]influxdb.BackupOptions
 }
 
 // NewBackupService constructs an instance of an authorizing backup service.
 func NewBackupService(s influxdb.BackupService) *BackupService {
 	return &BackupService{
-		s: s,
+		s:      s,
+		scopes: make(map[int]influxdb.BackupOptions),
+	}
+}
+
+// authorizeReadBackup checks that the authorizer on context may read all data
+// included in a backup with the given options.
+func authorizeReadBackup(ctx context.Context, opts influxdb.BackupOptions) error {
+	switch {
+	case opts.BucketID != nil && opts.OrgID != nil:
+		return authorizeReadBucket(ctx, *opts.OrgID, *opts.BucketID)
+	case opts.OrgID != nil && opts.BucketID == nil:
+		p, err := influxdb.NewPermission(influxdb.ReadAction, influxdb.BucketsResourceType, *opts.OrgID)
+		if err != nil {
+			return err
+		}
+		return IsAllowed(ctx, *p)
+	default:
+		return IsAllowedAll(ctx, influxdb.ReadAllPermissions())
 	}
 }
 
-func (b BackupService) CreateBackup(ctx context.Context) (int, []string, error) {
+func (b *BackupService) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	if err := IsAllowedAll(ctx, influxdb.ReadAllPermissions()); err != nil {
+	if err := authorizeReadBackup(ctx, opts); err != nil {
 		return 0, nil, err
 	}
-	return b.s.CreateBackup(ctx)
+	id, files, err := b.s.CreateBackup(ctx, opts)
+	if err != nil {
+		return 0, nil, err
+	}
+	if opts.Scoped() {
+		b.mu.Lock()
+		b.pruneScopes()
+		b.scopes[id] = opts
+		b.mu.Unlock()
+	}
+	return id, files, nil
 }
 
-func (b BackupService) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
+func (b *BackupService) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
-	if err := IsAllowedAll(ctx, influxdb.ReadAllPermissions()); err != nil {
+	b.mu.Lock()
+	opts := b.scopes[backupID]
+	b.mu.Unlock()
+
+	if err := authorizeReadBackup(ctx, opts); err != nil {
+		return err
+	}
+	if err := b.s.FetchBackupFile(ctx, backupID, backupFile, w); err != nil {
 		return err
 	}
-	return b.s.FetchBackupFile(ctx, backupID, backupFile, w)
+
+	if opts.Scoped() {
+		b.mu.Lock()
+		if !b.pending(backupID) {
+			delete(b.scopes, backupID)
+		}
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+// pruneScopes drops the scopes of the backups without files left to fetch. It
+// must be called with b.mu held.
+func (b *BackupService) pruneScopes() {
+	for id := range b.scopes {
+		if !b.pending(id) {
+			delete(b.scopes, id)
+		}
+	}
 }
 
-func (b BackupService) InternalBackupPath(backupID int) string {
+// pending reports whether files of the backup are left to fetch. Backup files
+// are removed once fetched, and backups that fail to be created are removed
+// altogether.
+func (b *BackupService) pending(backupID int) bool {
+	f, err := os.Open(b.s.InternalBackupPath(backupID))
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	defer f.Close()
+
+	names, _ := f.Readdirnames(1)
+	return len(names) > 0
+}
+
+func (b *BackupService) InternalBackupPath(backupID int) string {
 	return b.s.InternalBackupPath(backupID)
 }
+
+var _ influxdb.RestoreService = (*RestoreService)(nil)
+
+// RestoreService wraps a influxdb.RestoreService and authorizes actions
+// against it appropriately.
+type RestoreService struct {
+	s          influxdb.RestoreService
+	orgService OrganizationService
+}
+
+// NewRestoreService constructs an instance of an authorizing restore service.
+func NewRestoreService(orgSvc OrganizationService, s influxdb.RestoreService) *RestoreService {
+	return &RestoreService{
+		s:          s,
+		orgService: orgSvc,
+	}
+}
+
+// RestoreBucketMetadata checks to see if the authorizer on context may create
+// buckets in the organization of the restored bucket.
+func (b *RestoreService) RestoreBucketMetadata(ctx context.Context, m influxdb.BucketMetadataBackup) (*influxdb.Bucket, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	p, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.BucketsResourceType, m.Bucket.OrgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := IsAllowed(ctx, *p); err != nil {
+		return nil, err
+	}
+	return b.s.RestoreBucketMetadata(ctx, m)
+}
+
+// RestoreBucket checks to see if the authorizer on context has write access to
+// the bucket the data is restored into.
+func (b *RestoreService) RestoreBucket(ctx context.Context, bucketID influxdb.ID, src *influxdb.Bucket, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	orgID, err := b.orgService.FindResourceOrganizationID(ctx, influxdb.BucketsResourceType, bucketID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeWriteBucket(ctx, orgID, bucketID); err != nil {
+		return err
+	}
+	return b.s.RestoreBucket(ctx, bucketID, src, r)
+}
+
+// RestoreTSMFile checks to see if the authorizer on context has operator
+// permissions, as the file may hold data of any organization.
+func (b *RestoreService) RestoreTSMFile(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return b.s.RestoreTSMFile(ctx, r)
+}
+
+// RestoreKVStore checks to see if the authorizer on context has operator
+// permissions, as all metadata is replaced.
+func (b *RestoreService) RestoreKVStore(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return b.s.RestoreKVStore(ctx, r)
+}
@@ -0,0 +1,105 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.BackupScheduleService = (*BackupScheduleService)(nil)
+
+// BackupScheduleService wraps a influxdb.BackupScheduleService and authorizes
+// actions against it appropriately. Scheduled backups are taken by the server
+// with access to all data, so backup schedules are managed by operators only.
+type BackupScheduleService struct {
+	s influxdb.BackupScheduleService
+}
+
+// NewBackupScheduleService constructs an instance of an authorizing backup schedule service.
+func NewBackupScheduleService(s influxdb.BackupScheduleService) *BackupScheduleService {
+	return &BackupScheduleService{
+		s: s,
+	}
+}
+
+func authorizeBackupSchedules(ctx context.Context) error {
+	return IsAllowedAll(ctx, influxdb.OperPermissions())
+}
+
+// FindBackupScheduleByID checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) FindBackupScheduleByID(ctx context.Context, id influxdb.ID) (*influxdb.BackupSchedule, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return nil, err
+	}
+	return s.s.FindBackupScheduleByID(ctx, id)
+}
+
+// FindBackupSchedules checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) FindBackupSchedules(ctx context.Context, filter influxdb.BackupScheduleFilter) ([]*influxdb.BackupSchedule, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return nil, 0, err
+	}
+	return s.s.FindBackupSchedules(ctx, filter)
+}
+
+// CreateBackupSchedule checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) CreateBackupSchedule(ctx context.Context, bs *influxdb.BackupSchedule) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return err
+	}
+	return s.s.CreateBackupSchedule(ctx, bs)
+}
+
+// UpdateBackupSchedule checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) UpdateBackupSchedule(ctx context.Context, id influxdb.ID, upd influxdb.BackupScheduleUpdate) (*influxdb.BackupSchedule, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return nil, err
+	}
+	return s.s.UpdateBackupSchedule(ctx, id, upd)
+}
+
+// DeleteBackupSchedule checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) DeleteBackupSchedule(ctx context.Context, id influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return err
+	}
+	return s.s.DeleteBackupSchedule(ctx, id)
+}
+
+// FindBackupRuns checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) FindBackupRuns(ctx context.Context, scheduleID influxdb.ID) ([]*influxdb.BackupRun, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return nil, 0, err
+	}
+	return s.s.FindBackupRuns(ctx, scheduleID)
+}
+
+// FindBackupRunByID checks to see if the authorizer on context has operator permissions.
+func (s *BackupScheduleService) FindBackupRunByID(ctx context.Context, scheduleID, runID influxdb.ID) (*influxdb.BackupRun, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := authorizeBackupSchedules(ctx); err != nil {
+		return nil, err
+	}
+	return s.s.FindBackupRunByID(ctx, scheduleID, runID)
+}
@@ -0,0 +1,101 @@
+package authorizer_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+)
+
+// fileBackupService creates backups of a single file in directories of dir,
+// and removes backup files once fetched.
+type fileBackupService struct {
+	dir string
+	n   int
+}
+
+func (s *fileBackupService) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
+	s.n++
+	if err := os.Mkdir(s.InternalBackupPath(s.n), 0777); err != nil {
+		return 0, nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(s.InternalBackupPath(s.n), "a.tsm"), nil, 0666); err != nil {
+		return 0, nil, err
+	}
+	return s.n, []string{"a.tsm"}, nil
+}
+
+func (s *fileBackupService) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
+	return os.Remove(filepath.Join(s.InternalBackupPath(backupID), backupFile))
+}
+
+func (s *fileBackupService) InternalBackupPath(backupID int) string {
+	return filepath.Join(s.dir, fmt.Sprint(backupID))
+}
+
+func TestBackupService_ScopedFetch(t *testing.T) {
+	const orgID, bucketID = influxdb.ID(10), influxdb.ID(1)
+
+	dir, err := ioutil.TempDir("", "authorizer_backup_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := &fileBackupService{dir: dir}
+	s := authorizer.NewBackupService(fs)
+
+	p, err := influxdb.NewPermissionAtID(bucketID, influxdb.ReadAction, influxdb.BucketsResourceType, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := influxdbcontext.SetAuthorizer(context.Background(), &Authorizer{[]influxdb.Permission{*p}})
+	opts := influxdb.BackupOptions{OrgID: idPtr(orgID), BucketID: idPtr(bucketID)}
+
+	// The files of a scoped backup are fetched with the permissions needed to
+	// create it.
+	id, files, err := s.CreateBackup(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FetchBackupFile(ctx, id, files[0], ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once all files are fetched, the scope of the backup is dropped, so that
+	// fetching from the backup again requires access to all data.
+	if err := ioutil.WriteFile(filepath.Join(fs.InternalBackupPath(id), files[0]), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FetchBackupFile(ctx, id, files[0], ioutil.Discard); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("unexpected error fetching from fetched backup: %v", err)
+	}
+
+	// The scopes of removed backups are dropped when other backups are created.
+	id, _, err = s.CreateBackup(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(fs.InternalBackupPath(id)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.CreateBackup(ctx, opts); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(fs.InternalBackupPath(id), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(fs.InternalBackupPath(id), files[0]), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.FetchBackupFile(ctx, id, files[0], ioutil.Discard); influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+		t.Fatalf("unexpected error fetching from removed backup: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.BucketSchemaService = (*BucketSchemaService)(nil)
+
+// BucketSchemaService wraps a influxdb.BucketSchemaService and authorizes
+// actions against it appropriately. The schema of a bucket can be read by
+// those who can read the bucket and managed by those who can write to it.
+type BucketSchemaService struct {
+	s  influxdb.BucketSchemaService
+	bs influxdb.BucketService
+}
+
+// NewBucketSchemaService constructs an instance of an authorizing bucket
+// schema service. The buckets the schemas belong to are found with bs.
+func NewBucketSchemaService(s influxdb.BucketSchemaService, bs influxdb.BucketService) *BucketSchemaService {
+	return &BucketSchemaService{
+		s:  s,
+		bs: bs,
+	}
+}
+
+// FindBucketSchema checks to see if the authorizer on context has read access to the bucket.
+func (s *BucketSchemaService) FindBucketSchema(ctx context.Context, bucketID influxdb.ID) (*influxdb.BucketSchema, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	b, err := s.bs.FindBucketByID(ctx, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeReadBucket(ctx, b.OrgID, b.ID); err != nil {
+		return nil, err
+	}
+	return s.s.FindBucketSchema(ctx, bucketID)
+}
+
+// PutBucketSchema checks to see if the authorizer on context has write access
+// to the bucket and the bucket nonconforming points are routed to.
+func (s *BucketSchemaService) PutBucketSchema(ctx context.Context, bs *influxdb.BucketSchema) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	ids := []influxdb.ID{bs.BucketID}
+	if bs.Action == influxdb.SchemaActionRoute {
+		ids = append(ids, bs.RouteBucketID)
+	}
+	for _, id := range ids {
+		b, err := s.bs.FindBucketByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := authorizeWriteBucket(ctx, b.OrgID, b.ID); err != nil {
+			return err
+		}
+	}
+	return s.s.PutBucketSchema(ctx, bs)
+}
+
+// DeleteBucketSchema checks to see if the authorizer on context has write access to the bucket.
+func (s *BucketSchemaService) DeleteBucketSchema(ctx context.Context, bucketID influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	b, err := s.bs.FindBucketByID(ctx, bucketID)
+	if err != nil {
+		return err
+	}
+	if err := authorizeWriteBucket(ctx, b.OrgID, b.ID); err != nil {
+		return err
+	}
+	return s.s.DeleteBucketSchema(ctx, bucketID)
+}
@@ -0,0 +1,57 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.CardinalityService = (*CardinalityService)(nil)
+
+// CardinalityService wraps a influxdb.CardinalityService and authorizes actions
+// against it appropriately.
+type CardinalityService struct {
+	s influxdb.CardinalityService
+}
+
+// NewCardinalityService constructs an instance of an authorizing cardinality service.
+func NewCardinalityService(s influxdb.CardinalityService) *CardinalityService {
+	return &CardinalityService{s: s}
+}
+
+// FindCardinality checks to see if the authorizer on context has read access
+// to the requested bucket, or filters the buckets to those it has read access to.
+func (s *CardinalityService) FindCardinality(ctx context.Context, filter influxdb.CardinalityFilter) ([]*influxdb.BucketCardinality, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if filter.BucketID != nil {
+		if err := authorizeReadBucket(ctx, filter.OrgID, *filter.BucketID); err != nil {
+			return nil, err
+		}
+		return s.s.FindCardinality(ctx, filter)
+	}
+
+	bs, err := s.s.FindCardinality(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// This filters without allocating
+	// https://github.com/golang/go/wiki/SliceTricks#filtering-without-allocating
+	buckets := bs[:0]
+	for _, b := range bs {
+		err := authorizeReadBucket(ctx, b.OrgID, b.BucketID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			return nil, err
+		}
+
+		if influxdb.ErrorCode(err) == influxdb.EUnauthorized {
+			continue
+		}
+
+		buckets = append(buckets, b)
+	}
+	return buckets, nil
+}
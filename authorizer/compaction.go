@@ -0,0 +1,66 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.CompactionService = (*CompactionService)(nil)
+
+// CompactionService wraps a influxdb.CompactionService and authorizes actions
+// against it appropriately. Compactions span the data of all organizations, so
+// all actions require operator permissions.
+type CompactionService struct {
+	s influxdb.CompactionService
+}
+
+// NewCompactionService constructs an instance of an authorizing compaction service.
+func NewCompactionService(s influxdb.CompactionService) *CompactionService {
+	return &CompactionService{s: s}
+}
+
+// FindCompactions checks to see if the authorizer on context has operator permissions.
+func (s *CompactionService) FindCompactions(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.FindCompactions(ctx)
+}
+
+// CompactBucket checks to see if the authorizer on context has operator permissions.
+func (s *CompactionService) CompactBucket(ctx context.Context, orgID, bucketID influxdb.ID, optimize bool) ([]*influxdb.Compaction, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.CompactBucket(ctx, orgID, bucketID, optimize)
+}
+
+// SetCompactionsPaused checks to see if the authorizer on context has operator permissions.
+func (s *CompactionService) SetCompactionsPaused(ctx context.Context, paused bool) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return s.s.SetCompactionsPaused(ctx, paused)
+}
+
+// CancelCompaction checks to see if the authorizer on context has operator permissions.
+func (s *CompactionService) CancelCompaction(ctx context.Context, id int) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return s.s.CancelCompaction(ctx, id)
+}
@@ -0,0 +1,105 @@
+package authorizer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	influxdbcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+)
+
+func TestDBRPMappingService_FindMany(t *testing.T) {
+	svc := &mock.DBRPMappingService{
+		FindManyFn: func(ctx context.Context, filter influxdb.DBRPMappingFilter, opt ...influxdb.FindOptions) ([]*influxdb.DBRPMapping, int, error) {
+			return []*influxdb.DBRPMapping{
+				{Cluster: "c", Database: "db", RetentionPolicy: "rp1", OrganizationID: 10, BucketID: 1},
+				{Cluster: "c", Database: "db", RetentionPolicy: "rp2", OrganizationID: 10, BucketID: 2},
+				{Cluster: "c", Database: "db", RetentionPolicy: "rp3", OrganizationID: 11, BucketID: 3},
+			}, 3, nil
+		},
+	}
+	s := authorizer.NewDBRPMappingService(svc)
+
+	ctx := influxdbcontext.SetAuthorizer(context.Background(), &Authorizer{[]influxdb.Permission{
+		{
+			Action: "read",
+			Resource: influxdb.Resource{
+				Type:  influxdb.BucketsResourceType,
+				OrgID: influxdbtesting.IDPtr(10),
+			},
+		},
+	}})
+
+	ms, n, err := s.FindMany(ctx, influxdb.DBRPMappingFilter{})
+	influxdbtesting.ErrorsEqual(t, err, nil)
+
+	exp := []*influxdb.DBRPMapping{
+		{Cluster: "c", Database: "db", RetentionPolicy: "rp1", OrganizationID: 10, BucketID: 1},
+		{Cluster: "c", Database: "db", RetentionPolicy: "rp2", OrganizationID: 10, BucketID: 2},
+	}
+	if diff := cmp.Diff(ms, exp); diff != "" {
+		t.Errorf("mappings are different -got/+want\ndiff %s", diff)
+	}
+	if n != 2 {
+		t.Errorf("unexpected count: %d", n)
+	}
+}
+
+func TestDBRPMappingService_Create(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []influxdb.Permission
+		err         error
+	}{
+		{
+			name: "authorized to create dbrp mapping",
+			permissions: []influxdb.Permission{
+				{
+					Action: "write",
+					Resource: influxdb.Resource{
+						Type: influxdb.BucketsResourceType,
+						ID:   influxdbtesting.IDPtr(1),
+					},
+				},
+			},
+		},
+		{
+			name: "unauthorized to create dbrp mapping",
+			permissions: []influxdb.Permission{
+				{
+					Action: "read",
+					Resource: influxdb.Resource{
+						Type: influxdb.BucketsResourceType,
+						ID:   influxdbtesting.IDPtr(1),
+					},
+				},
+			},
+			err: &influxdb.Error{
+				Msg:  "write:orgs/000000000000000a/buckets/0000000000000001 is unauthorized",
+				Code: influxdb.EUnauthorized,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := authorizer.NewDBRPMappingService(mock.NewDBRPMappingService())
+
+			ctx := context.Background()
+			ctx = influxdbcontext.SetAuthorizer(ctx, &Authorizer{tt.permissions})
+
+			err := s.Create(ctx, &influxdb.DBRPMapping{
+				Cluster:         "c",
+				Database:        "db",
+				RetentionPolicy: "rp",
+				OrganizationID:  10,
+				BucketID:        1,
+			})
+			influxdbtesting.ErrorsEqual(t, err, tt.err)
+		})
+	}
+}
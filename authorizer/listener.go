@@ -0,0 +1,150 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.ListenerService = (*ListenerService)(nil)
+
+// ListenerService wraps a influxdb.ListenerService and authorizes actions
+// against it appropriately.
+type ListenerService struct {
+	s influxdb.ListenerService
+}
+
+// NewListenerService constructs an instance of an authorizing listener service.
+func NewListenerService(s influxdb.ListenerService) *ListenerService {
+	return &ListenerService{
+		s: s,
+	}
+}
+
+func newListenerPermission(a influxdb.Action, orgID, id influxdb.ID) (*influxdb.Permission, error) {
+	return influxdb.NewPermissionAtID(id, a, influxdb.ListenersResourceType, orgID)
+}
+
+func authorizeReadListener(ctx context.Context, orgID, id influxdb.ID) error {
+	p, err := newListenerPermission(influxdb.ReadAction, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func authorizeWriteListener(ctx context.Context, orgID, id influxdb.ID) error {
+	p, err := newListenerPermission(influxdb.WriteAction, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindListenerByID checks to see if the authorizer on context has read access to the id provided.
+func (s *ListenerService) FindListenerByID(ctx context.Context, id influxdb.ID) (*influxdb.Listener, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	l, err := s.s.FindListenerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeReadListener(ctx, l.OrgID, id); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// FindListeners retrieves all listeners that match the provided filter and then filters the list down to only the resources that are authorized.
+func (s *ListenerService) FindListeners(ctx context.Context, filter influxdb.ListenerFilter) ([]*influxdb.Listener, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	ls, _, err := s.s.FindListeners(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// This filters without allocating
+	// https://github.com/golang/go/wiki/SliceTricks#filtering-without-allocating
+	listeners := ls[:0]
+	for _, l := range ls {
+		err := authorizeReadListener(ctx, l.OrgID, l.ID)
+		if err != nil && influxdb.ErrorCode(err) != influxdb.EUnauthorized {
+			return nil, 0, err
+		}
+
+		if influxdb.ErrorCode(err) == influxdb.EUnauthorized {
+			continue
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, len(listeners), nil
+}
+
+// CreateListener checks to see if the authorizer on context has write access to the listeners of the organization.
+func (s *ListenerService) CreateListener(ctx context.Context, l *influxdb.Listener) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	p, err := influxdb.NewPermission(influxdb.WriteAction, influxdb.ListenersResourceType, l.OrgID)
+	if err != nil {
+		return err
+	}
+
+	if err := IsAllowed(ctx, *p); err != nil {
+		return err
+	}
+
+	return s.s.CreateListener(ctx, l)
+}
+
+// UpdateListener checks to see if the authorizer on context has write access to the listener provided.
+func (s *ListenerService) UpdateListener(ctx context.Context, id influxdb.ID, upd influxdb.ListenerUpdate) (*influxdb.Listener, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	l, err := s.FindListenerByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := authorizeWriteListener(ctx, l.OrgID, id); err != nil {
+		return nil, err
+	}
+
+	return s.s.UpdateListener(ctx, id, upd)
+}
+
+// DeleteListener checks to see if the authorizer on context has write access to the listener provided.
+func (s *ListenerService) DeleteListener(ctx context.Context, id influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	l, err := s.FindListenerByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := authorizeWriteListener(ctx, l.OrgID, id); err != nil {
+		return err
+	}
+
+	return s.s.DeleteListener(ctx, id)
+}
@@ -0,0 +1,56 @@
+package authorizer
+
+import (
+	"context"
+	"io"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.ReplicationService = (*ReplicationService)(nil)
+
+// ReplicationService wraps a influxdb.ReplicationService and authorizes actions
+// against it appropriately. Replication spans the data and metadata of all
+// organizations, so all actions require operator permissions.
+type ReplicationService struct {
+	s influxdb.ReplicationService
+}
+
+// NewReplicationService constructs an instance of an authorizing replication service.
+func NewReplicationService(s influxdb.ReplicationService) *ReplicationService {
+	return &ReplicationService{s: s}
+}
+
+// FindReplicationStatus checks to see if the authorizer on context has operator permissions.
+func (s *ReplicationService) FindReplicationStatus(ctx context.Context) (*influxdb.ReplicationStatus, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.FindReplicationStatus(ctx)
+}
+
+// StreamReplication checks to see if the authorizer on context has operator permissions.
+func (s *ReplicationService) StreamReplication(ctx context.Context, pos influxdb.ReplicationPosition, w io.Writer) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return s.s.StreamReplication(ctx, pos, w)
+}
+
+// PromoteStandby checks to see if the authorizer on context has operator permissions.
+func (s *ReplicationService) PromoteStandby(ctx context.Context) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return err
+	}
+	return s.s.PromoteStandby(ctx)
+}
@@ -0,0 +1,33 @@
+package authorizer
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.SeriesGCService = (*SeriesGCService)(nil)
+
+// SeriesGCService wraps a influxdb.SeriesGCService and authorizes actions
+// against it appropriately. The series garbage collector spans the data of all
+// organizations, so it requires operator permissions.
+type SeriesGCService struct {
+	s influxdb.SeriesGCService
+}
+
+// NewSeriesGCService constructs an instance of an authorizing series garbage collection service.
+func NewSeriesGCService(s influxdb.SeriesGCService) *SeriesGCService {
+	return &SeriesGCService{s: s}
+}
+
+// CollectSeries checks to see if the authorizer on context has operator permissions.
+func (s *SeriesGCService) CollectSeries(ctx context.Context) (*influxdb.SeriesGC, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := IsAllowedAll(ctx, influxdb.OperPermissions()); err != nil {
+		return nil, err
+	}
+	return s.s.CollectSeries(ctx)
+}
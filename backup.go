@@ -3,13 +3,26 @@ package influxdb
 import (
 	"context"
 	"io"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// BackupManifestFilename is the name of the file, written alongside the data
+	// and metadata files of a backup, that describes the contents of the backup.
+	BackupManifestFilename = "manifest.json"
+
+	// BackupBucketsFilename is the name of the file holding the metadata of the
+	// buckets in a backup scoped to an organization or bucket. Such backups do
+	// not include the metadata database.
+	BackupBucketsFilename = "buckets.json"
 )
 
 // BackupService represents the data backup functions of InfluxDB.
 type BackupService interface {
 	// CreateBackup creates a local copy (hard links) of the TSM data for all orgs and buckets.
 	// The return values are used to download each backup file.
-	CreateBackup(context.Context) (backupID int, backupFiles []string, err error)
+	CreateBackup(ctx context.Context, opts BackupOptions) (backupID int, backupFiles []string, err error)
 	// FetchBackupFile downloads one backup file, data or metadata.
 	FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error
 	// InternalBackupPath is a utility to determine the on-disk location of a backup fileset.
@@ -21,3 +34,127 @@ type KVBackupService interface {
 	// Backup creates a live backup copy of the metadata database.
 	Backup(ctx context.Context, w io.Writer) error
 }
+
+// KVRestoreService represents the meta data restore functions of InfluxDB.
+type KVRestoreService interface {
+	// Restore replaces the metadata database of a running instance with the
+	// backup copy read from r.
+	Restore(ctx context.Context, r io.Reader) error
+}
+
+// RestoreService represents the data restore functions of InfluxDB that work
+// against a running instance.
+type RestoreService interface {
+	// RestoreBucketMetadata creates a bucket from the backed up metadata, along
+	// with its labels and user resource mappings. The bucket is
+	// created with a new ID, in the organization and with the name of m.Bucket.
+	RestoreBucketMetadata(ctx context.Context, m BucketMetadataBackup) (*Bucket, error)
+	// RestoreBucket loads the data of the backed up bucket src, from the TSM file
+	// read from r, into the bucket with ID bucketID.
+	RestoreBucket(ctx context.Context, bucketID ID, src *Bucket, r io.Reader) error
+	// RestoreTSMFile loads all data of the TSM file read from r. The file must
+	// not have tombstones.
+	RestoreTSMFile(ctx context.Context, r io.Reader) error
+	// RestoreKVStore replaces the metadata database with the backup copy read from r.
+	RestoreKVStore(ctx context.Context, r io.Reader) error
+}
+
+// BackupSink stores backups outside of the instance they were taken from. A
+// backup is stored as a set of files under a path, and is complete once its
+// manifest has been stored.
+type BackupSink interface {
+	// PutBackupFile stores the named file of the backup at path. The content
+	// read from r must have the given size and hex encoded SHA-256 checksum,
+	// or the file is not stored.
+	PutBackupFile(ctx context.Context, path, name string, r io.Reader, size int64, checksum string) error
+	// ListBackups returns the paths of the complete backups stored directly
+	// under dir, oldest first.
+	ListBackups(ctx context.Context, dir string) ([]string, error)
+	// DeleteBackup removes the backup at path with all of its files.
+	DeleteBackup(ctx context.Context, path string) error
+}
+
+// BackupOptions control which data files are included in a backup.
+type BackupOptions struct {
+	// Base is the manifest of an earlier backup. When set, the backup is
+	// incremental: TSM and tombstone files that are unchanged since Base are
+	// left out of the new backup and referenced from the manifest instead.
+	Base *BackupManifest `json:"base,omitempty"`
+
+	// OrgID and BucketID limit the backup to the data and metadata of one
+	// organization, or one bucket in it.
+	OrgID    *ID `json:"orgID,omitempty"`
+	BucketID *ID `json:"bucketID,omitempty"`
+}
+
+// Scoped reports whether the backup is limited to an organization or bucket.
+func (o BackupOptions) Scoped() bool {
+	return o.OrgID != nil || o.BucketID != nil
+}
+
+// BucketMetadataBackup is the metadata of a bucket in a scoped backup.
+type BucketMetadataBackup struct {
+	Bucket               Bucket                 `json:"bucket"`
+	Labels               []*Label               `json:"labels,omitempty"`
+	UserResourceMappings []*UserResourceMapping `json:"userResourceMappings,omitempty"`
+}
+
+// BackupManifest describes the files making up a backup. The manifest of an
+// incremental backup lists every file needed for a restore, including those
+// carried over from earlier backups in the chain.
+type BackupManifest struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// Parent is the directory of the backup this backup is incremental to,
+	// relative to the directory of this backup. It is empty for a full backup.
+	Parent string               `json:"parent,omitempty"`
+	Files  []BackupManifestFile `json:"files"`
+}
+
+// BackupManifestFile describes a single file of a backup.
+type BackupManifestFile struct {
+	FileName   string `json:"fileName"`
+	Generation int    `json:"generation,omitempty"`
+	Sequence   int    `json:"sequence,omitempty"`
+	Size       int64  `json:"size"`
+	// Checksum is the hex encoded SHA-256 of the file contents.
+	Checksum string `json:"checksum,omitempty"`
+	// Location is the directory of the backup holding the file when it was
+	// carried over from an earlier backup, relative to the directory of the
+	// backup the manifest belongs to. It is empty if the file is part of that
+	// backup. Manifests written by earlier versions hold absolute paths.
+	Location string `json:"location,omitempty"`
+}
+
+// Path returns the path of the file, given the directory of the backup whose
+// manifest lists it.
+func (f BackupManifestFile) Path(dir string) string {
+	if filepath.IsAbs(f.Location) {
+		return filepath.Join(f.Location, f.FileName)
+	}
+	return filepath.Join(dir, f.Location, f.FileName)
+}
+
+// File returns the entry for the named file, if the manifest has one.
+func (m *BackupManifest) File(name string) (BackupManifestFile, bool) {
+	if m == nil {
+		return BackupManifestFile{}, false
+	}
+	for _, f := range m.Files {
+		if f.FileName == name {
+			return f, true
+		}
+	}
+	return BackupManifestFile{}, false
+}
+
+// Unchanged reports whether f is present in the manifest with the same
+// generation, sequence and size, so that it need not be copied again. If f has
+// a checksum, the file must be listed with the same checksum too; files that
+// never change once written, such as TSM files, are compared without one.
+func (m *BackupManifest) Unchanged(f BackupManifestFile) bool {
+	prev, ok := m.File(f.FileName)
+	if !ok || prev.Generation != f.Generation || prev.Sequence != f.Sequence || prev.Size != f.Size {
+		return false
+	}
+	return f.Checksum == "" || f.Checksum == prev.Checksum
+}
@@ -0,0 +1,141 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var _ influxdb.BackupSink = (*FileSink)(nil)
+
+// FileSink stores backups in a directory of the local file system, such as a
+// mounted network share. Each backup is a directory holding its files.
+type FileSink struct {
+	root string
+}
+
+// NewFileSink returns a new FileSink storing backups under the directory root.
+func NewFileSink(root string) *FileSink {
+	return &FileSink{root: root}
+}
+
+// dir returns the directory in the file system of the sink path p. Paths can
+// not refer to directories outside of the root of the sink.
+func (s *FileSink) dir(p string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+p)))
+}
+
+// PutBackupFile writes the named file of the backup at p. The file is written to
+// a temporary file first and only moved into place if its size and checksum match.
+func (s *FileSink) PutBackupFile(ctx context.Context, p, name string, r io.Reader, size int64, checksum string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if name != filepath.Base(name) {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("invalid backup file name %q", name),
+		}
+	}
+
+	dir := s.dir(p)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := verifyUpload(name, n, hex.EncodeToString(h.Sum(nil)), size, checksum); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, name))
+}
+
+// ListBackups returns the paths of the directories under dir that hold a
+// backup manifest.
+func (s *FileSink) ListBackups(ctx context.Context, dir string) ([]string, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	fis, err := ioutil.ReadDir(s.dir(dir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, fi := range fis {
+		if !fi.IsDir() {
+			continue
+		}
+		p := path.Join(dir, fi.Name())
+		if _, err := os.Stat(filepath.Join(s.dir(p), influxdb.BackupManifestFilename)); err != nil {
+			continue
+		}
+		backups = append(backups, p)
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// DeleteBackup removes the directory of the backup at p.
+func (s *FileSink) DeleteBackup(ctx context.Context, p string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	dir := s.dir(p)
+	if dir == filepath.Clean(s.root) {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "backup path is required",
+		}
+	}
+	return os.RemoveAll(dir)
+}
+
+// verifyUpload checks that the size and checksum of an uploaded file match the
+// expected ones.
+func verifyUpload(name string, size int64, checksum string, expSize int64, expChecksum string) error {
+	if size != expSize {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("backup file %s has size %d, expected %d", name, size, expSize),
+		}
+	}
+	if checksum != expChecksum {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("backup file %s has checksum %s, expected %s", name, checksum, expChecksum),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package backup_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/backup"
+)
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// testSink exercises the behaviour shared by all backup sinks.
+func testSink(t *testing.T, sink influxdb.BackupSink) {
+	t.Helper()
+	ctx := context.Background()
+
+	put := func(p, name, content string) {
+		t.Helper()
+		if err := sink.PutBackupFile(ctx, p, name, strings.NewReader(content), int64(len(content)), checksum(content)); err != nil {
+			t.Fatalf("error putting %s/%s: %v", p, name, err)
+		}
+	}
+
+	put("nightly/b1", "000000001-000000001.tsm", "data")
+	put("nightly/b1", influxdb.BackupManifestFilename, "{}")
+	put("nightly/b2", influxdb.BackupManifestFilename, "{}")
+	// Incomplete backups without a manifest are not listed.
+	put("nightly/b3", "000000002-000000001.tsm", "data")
+	put("other", influxdb.BackupManifestFilename, "{}")
+
+	if err := sink.PutBackupFile(ctx, "nightly/b4", "x.tsm", strings.NewReader("data"), 4, checksum("atad")); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+
+	backups, err := sink.ListBackups(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("error listing backups: %v", err)
+	}
+	if exp := []string{"nightly/b1", "nightly/b2"}; !reflect.DeepEqual(backups, exp) {
+		t.Fatalf("got backups %v, exp %v", backups, exp)
+	}
+
+	if err := sink.DeleteBackup(ctx, "nightly/b1"); err != nil {
+		t.Fatalf("error deleting backup: %v", err)
+	}
+	if err := sink.DeleteBackup(ctx, ""); err == nil {
+		t.Fatal("expected error deleting the root of the sink")
+	}
+
+	backups, err = sink.ListBackups(ctx, "nightly")
+	if err != nil {
+		t.Fatalf("error listing backups: %v", err)
+	}
+	if exp := []string{"nightly/b2"}; !reflect.DeepEqual(backups, exp) {
+		t.Fatalf("got backups %v, exp %v", backups, exp)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup-sink-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	testSink(t, backup.NewFileSink(dir))
+
+	if _, err := os.Stat(filepath.Join(dir, "nightly", "b1")); !os.IsNotExist(err) {
+		t.Fatalf("deleted backup still exists: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nightly", "b4", "x.tsm")); !os.IsNotExist(err) {
+		t.Fatalf("file with checksum mismatch was stored: %v", err)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "nightly", "b3", "000000002-000000001.tsm"))
+	if err != nil || string(b) != "data" {
+		t.Fatalf("got file content %q, %v", b, err)
+	}
+}
@@ -0,0 +1,56 @@
+package backup
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+)
+
+// BucketMetadata returns the metadata of the user buckets included in a backup
+// scoped by opts, as written to the buckets file of the backup.
+func BucketMetadata(ctx context.Context, opts influxdb.BackupOptions, bs influxdb.BucketService, ls influxdb.LabelService, us influxdb.UserResourceMappingService) ([]influxdb.BucketMetadataBackup, error) {
+	var buckets []*influxdb.Bucket
+	if opts.BucketID != nil {
+		b, err := bs.FindBucketByID(ctx, *opts.BucketID)
+		if err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	} else {
+		found, _, err := bs.FindBuckets(ctx, influxdb.BucketFilter{OrganizationID: opts.OrgID})
+		if err != nil {
+			return nil, err
+		}
+		buckets = found
+	}
+
+	ms := make([]influxdb.BucketMetadataBackup, 0, len(buckets))
+	for _, b := range buckets {
+		if b.Type == influxdb.BucketTypeSystem {
+			continue
+		}
+
+		labels, err := ls.FindResourceLabels(ctx, influxdb.LabelMappingFilter{
+			ResourceID:   b.ID,
+			ResourceType: influxdb.BucketsResourceType,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		urms, _, err := us.FindUserResourceMappings(ctx, influxdb.UserResourceMappingFilter{
+			ResourceID:   b.ID,
+			ResourceType: influxdb.BucketsResourceType,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ms = append(ms, influxdb.BucketMetadataBackup{
+			Bucket:               *b,
+			Labels:               labels,
+			UserResourceMappings: urms,
+		})
+	}
+	return ms, nil
+}
@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/s3"
+)
+
+// DefaultS3Region is the region requests to S3-compatible storage are signed
+// for if none is configured.
+const DefaultS3Region = s3.DefaultRegion
+
+// S3Config configures a S3Sink.
+type S3Config struct {
+	// Endpoint is the base URL of the storage service, such as
+	// https://s3.us-east-1.amazonaws.com or http://localhost:9000.
+	Endpoint string
+	// Region is the region requests are signed for.
+	Region string
+	// Bucket is the name of the bucket backups are stored in.
+	Bucket string
+	// Prefix is prepended to the keys of all stored backup files.
+	Prefix string
+
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+var _ influxdb.BackupSink = (*S3Sink)(nil)
+
+// S3Sink stores backups in a bucket of an S3-compatible object storage service.
+// The files of a backup are stored as objects sharing the path of the backup as
+// key prefix.
+type S3Sink struct {
+	config S3Config
+	client *s3.Client
+}
+
+// NewS3Sink returns a new S3Sink for the given configuration.
+func NewS3Sink(c S3Config) *S3Sink {
+	return &S3Sink{
+		config: c,
+		client: s3.NewClient(s3.Config{
+			Endpoint:        c.Endpoint,
+			Region:          c.Region,
+			Bucket:          c.Bucket,
+			AccessKeyID:     c.AccessKeyID,
+			SecretAccessKey: c.SecretAccessKey,
+		}),
+	}
+}
+
+// key returns the object key of the named file of the backup at p.
+func (s *S3Sink) key(p, name string) string {
+	return strings.TrimPrefix(path.Join("/", s.config.Prefix, p, name), "/")
+}
+
+// PutBackupFile uploads the named file of the backup at p. The checksum is sent
+// as the payload hash of the signed request, so the storage service rejects the
+// upload if the content does not match it.
+func (s *S3Sink) PutBackupFile(ctx context.Context, p, name string, r io.Reader, size int64, checksum string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	h := sha256.New()
+	cr := &countingReader{r: io.TeeReader(r, h)}
+
+	key := s.key(p, name)
+	if err := s.client.PutObject(ctx, key, cr, size, checksum); err != nil {
+		return err
+	}
+
+	// Not all S3-compatible services verify the payload hash, so the uploaded
+	// content is checked here as well.
+	if err := verifyUpload(name, cr.n, hex.EncodeToString(h.Sum(nil)), size, checksum); err != nil {
+		if derr := s.client.DeleteObject(ctx, key); derr != nil {
+			return fmt.Errorf("%v; failed to delete uploaded object: %v", err, derr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ListBackups returns the paths of the backups under dir for which a manifest
+// has been uploaded.
+func (s *S3Sink) ListBackups(ctx context.Context, dir string) ([]string, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	prefix := s.key(dir, "")
+	if prefix != "" {
+		prefix += "/"
+	}
+	keys, err := s.client.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, key := range keys {
+		rel := strings.TrimPrefix(key, prefix)
+		i := strings.Index(rel, "/")
+		if i <= 0 || rel[i+1:] != influxdb.BackupManifestFilename {
+			continue
+		}
+		backups = append(backups, path.Join(dir, rel[:i]))
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// DeleteBackup deletes all objects of the backup at p.
+func (s *S3Sink) DeleteBackup(ctx context.Context, p string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if strings.Trim(path.Clean("/"+p), "/") == "" {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "backup path is required",
+		}
+	}
+
+	keys, err := s.client.ListObjects(ctx, s.key(p, "")+"/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.client.DeleteObject(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
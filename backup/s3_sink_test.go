@@ -0,0 +1,41 @@
+package backup_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb/backup"
+	"github.com/influxdata/influxdb/pkg/s3/s3test"
+)
+
+func TestS3Sink(t *testing.T) {
+	server := s3test.NewServer(t, "backups")
+	defer server.Close()
+
+	sink, err := backup.NewSink(backup.SinkConfig{
+		URL:               "s3://backups/influxdb/",
+		S3Endpoint:        server.URL,
+		S3AccessKeyID:     s3test.AccessKeyID,
+		S3SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("error creating sink: %v", err)
+	}
+
+	testSink(t, sink)
+
+	if b, _ := server.Object("influxdb/nightly/b3/000000002-000000001.tsm"); string(b) != "data" {
+		t.Fatalf("got object content %q, exp %q", b, "data")
+	}
+	for _, key := range server.Keys() {
+		if strings.HasPrefix(key, "influxdb/nightly/b1/") || strings.HasPrefix(key, "influxdb/nightly/b4/") {
+			t.Fatalf("unexpected object %s", key)
+		}
+	}
+
+	// Content that is shorter than announced is not stored.
+	if err := sink.PutBackupFile(context.Background(), "b5", "x.tsm", strings.NewReader("dat"), 4, checksum("data")); err == nil {
+		t.Fatal("expected size mismatch")
+	}
+}
@@ -0,0 +1,164 @@
+package backup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"go.uber.org/zap"
+)
+
+// DefaultSchedulerInterval is how often the Scheduler checks for due backups.
+const DefaultSchedulerInterval = time.Second
+
+// Scheduler runs the active backup schedules, uploading a backup whenever one
+// is due and recording the run in the history of its schedule. Backups run one
+// at a time; a schedule that comes due while another backup runs is run after
+// it. Backups that came due while the server was not running are skipped.
+type Scheduler struct {
+	ScheduleService influxdb.BackupScheduleService
+	RunService      influxdb.BackupRunService
+
+	// Upload creates a backup and uploads it, typically Uploader.Upload.
+	Upload func(ctx context.Context, opts UploadOptions) (string, error)
+
+	// Interval is how often the schedules are checked. It defaults to
+	// DefaultSchedulerInterval.
+	Interval time.Duration
+
+	Logger *zap.Logger
+
+	now func() time.Time
+
+	mu   sync.Mutex
+	next map[influxdb.ID]scheduled
+}
+
+// scheduled is the time a schedule, as of its last update, is next due.
+type scheduled struct {
+	updatedAt time.Time
+	due       time.Time
+}
+
+// Run checks the schedules until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = DefaultSchedulerInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.runDue(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) logger() *zap.Logger {
+	if s.Logger == nil {
+		return zap.NewNop()
+	}
+	return s.Logger
+}
+
+func (s *Scheduler) clock() time.Time {
+	if s.now == nil {
+		return time.Now().UTC()
+	}
+	return s.now().UTC()
+}
+
+// runDue runs the backups of all active schedules that are due.
+func (s *Scheduler) runDue(ctx context.Context) {
+	schedules, _, err := s.ScheduleService.FindBackupSchedules(ctx, influxdb.BackupScheduleFilter{Status: influxdb.Active.Ptr()})
+	if err != nil {
+		s.logger().Error("Failed to find backup schedules", zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next == nil {
+		s.next = make(map[influxdb.ID]scheduled)
+	}
+	active := make(map[influxdb.ID]bool, len(schedules))
+
+	for _, bs := range schedules {
+		active[bs.ID] = true
+
+		now := s.clock()
+		next, ok := s.next[bs.ID]
+		if !ok || !next.updatedAt.Equal(bs.UpdatedAt) {
+			// New and changed schedules are due at their next time from now.
+			due, err := bs.Next(now)
+			if err != nil {
+				s.logger().Error("Invalid backup schedule", zap.String("schedule_id", bs.ID.String()), zap.Error(err))
+				continue
+			}
+			s.next[bs.ID] = scheduled{updatedAt: bs.UpdatedAt, due: due}
+			continue
+		}
+		if now.Before(next.due) {
+			continue
+		}
+
+		s.run(ctx, bs, next.due)
+
+		due, err := bs.Next(s.clock())
+		if err != nil {
+			s.logger().Error("Invalid backup schedule", zap.String("schedule_id", bs.ID.String()), zap.Error(err))
+			delete(s.next, bs.ID)
+			continue
+		}
+		s.next[bs.ID] = scheduled{updatedAt: bs.UpdatedAt, due: due}
+	}
+
+	for id := range s.next {
+		if !active[id] {
+			delete(s.next, id)
+		}
+	}
+}
+
+// run takes the backup of the schedule due at scheduledFor.
+func (s *Scheduler) run(ctx context.Context, bs *influxdb.BackupSchedule, scheduledFor time.Time) {
+	log := s.logger().With(zap.String("schedule_id", bs.ID.String()), zap.Time("scheduled_for", scheduledFor))
+
+	r := &influxdb.BackupRun{
+		ScheduleID:   bs.ID,
+		Status:       influxdb.BackupRunStarted,
+		ScheduledFor: scheduledFor,
+		StartedAt:    s.clock(),
+	}
+	if err := s.RunService.CreateBackupRun(ctx, r); err != nil {
+		log.Error("Failed to record backup run", zap.Error(err))
+		return
+	}
+
+	p, err := s.Upload(ctx, UploadOptions{
+		BackupOptions: influxdb.BackupOptions{OrgID: bs.OrgID, BucketID: bs.BucketID},
+		Path:          bs.Path,
+		Retention:     bs.Retention,
+	})
+	r.FinishedAt = s.clock()
+	r.Path = p
+	if err != nil {
+		log.Error("Scheduled backup failed", zap.Error(err))
+		r.Status = influxdb.BackupRunFailed
+		r.Error = err.Error()
+	} else {
+		r.Status = influxdb.BackupRunSuccess
+	}
+
+	if err := s.RunService.UpdateBackupRun(ctx, r); err != nil {
+		log.Error("Failed to record backup run", zap.Error(err))
+	}
+}
@@ -0,0 +1,97 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing kv service: %v", err)
+	}
+
+	bs := &influxdb.BackupSchedule{
+		Name:      "hourly",
+		Cron:      "0 * * * *",
+		Path:      "hourly",
+		Retention: 24,
+	}
+	if err := svc.CreateBackupSchedule(ctx, bs); err != nil {
+		t.Fatalf("error creating backup schedule: %v", err)
+	}
+
+	var (
+		now     = time.Date(2019, 10, 1, 10, 30, 0, 0, time.UTC)
+		uploads []UploadOptions
+		fail    bool
+	)
+	s := &Scheduler{
+		ScheduleService: svc,
+		RunService:      svc,
+		Upload: func(ctx context.Context, opts UploadOptions) (string, error) {
+			uploads = append(uploads, opts)
+			if fail {
+				return "", errors.New("sink unavailable")
+			}
+			return opts.Path + "/backup", nil
+		},
+		Logger: zaptest.NewLogger(t),
+		now:    func() time.Time { return now },
+	}
+
+	// The first check only schedules the backup.
+	s.runDue(ctx)
+	now = now.Add(29 * time.Minute)
+	s.runDue(ctx)
+	if len(uploads) != 0 {
+		t.Fatalf("unexpected uploads before schedule is due: %v", uploads)
+	}
+
+	now = time.Date(2019, 10, 1, 11, 0, 1, 0, time.UTC)
+	s.runDue(ctx)
+	s.runDue(ctx)
+	if len(uploads) != 1 {
+		t.Fatalf("got %d uploads, exp 1", len(uploads))
+	}
+	if got := uploads[0]; got.Path != "hourly" || got.Retention != 24 || got.Scoped() {
+		t.Fatalf("unexpected upload options %+v", got)
+	}
+
+	fail = true
+	now = now.Add(time.Hour)
+	s.runDue(ctx)
+
+	runs, n, err := svc.FindBackupRuns(ctx, bs.ID)
+	if err != nil {
+		t.Fatalf("error finding runs: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("got %d runs, exp 2", n)
+	}
+	if r := runs[0]; r.Status != influxdb.BackupRunFailed || r.Error != "sink unavailable" || !r.ScheduledFor.Equal(time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected latest run %+v", r)
+	}
+	if r := runs[1]; r.Status != influxdb.BackupRunSuccess || r.Path != "hourly/backup" || !r.ScheduledFor.Equal(time.Date(2019, 10, 1, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected first run %+v", r)
+	}
+
+	// Inactive schedules are not run.
+	if _, err := svc.UpdateBackupSchedule(ctx, bs.ID, influxdb.BackupScheduleUpdate{Status: influxdb.Inactive.Ptr()}); err != nil {
+		t.Fatalf("error updating backup schedule: %v", err)
+	}
+	now = now.Add(time.Hour)
+	s.runDue(ctx)
+	if len(uploads) != 2 {
+		t.Fatalf("got %d uploads, exp 2", len(uploads))
+	}
+}
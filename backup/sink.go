@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+)
+
+// SinkConfig configures the sink backups are uploaded to.
+type SinkConfig struct {
+	// URL locates the sink. It is either a directory, optionally given as a
+	// file:// URL, or an s3://bucket/prefix URL for S3-compatible storage.
+	URL string
+
+	// S3Endpoint is the base URL of the S3-compatible storage service. It
+	// defaults to the AWS endpoint of S3Region.
+	S3Endpoint        string
+	S3Region          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+}
+
+// NewSink returns the sink described by c.
+func NewSink(c SinkConfig) (influxdb.BackupSink, error) {
+	if !strings.Contains(c.URL, "://") {
+		return NewFileSink(c.URL), nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid backup sink URL",
+			Err:  err,
+		}
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewFileSink(u.Path), nil
+	case "s3":
+		region := c.S3Region
+		if region == "" {
+			region = DefaultS3Region
+		}
+		endpoint := c.S3Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return NewS3Sink(S3Config{
+			Endpoint:        endpoint,
+			Region:          region,
+			Bucket:          u.Host,
+			Prefix:          strings.Trim(u.Path, "/"),
+			AccessKeyID:     c.S3AccessKeyID,
+			SecretAccessKey: c.S3SecretAccessKey,
+		}), nil
+	default:
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("unsupported backup sink scheme %q", u.Scheme),
+		}
+	}
+}
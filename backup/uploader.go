@@ -0,0 +1,282 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/bolt"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// NameFormat is the time format of the names of uploaded backups. Names sort in
+// the order the backups were created.
+const NameFormat = "20060102T150405.000Z"
+
+// UploadOptions control what is backed up by an Uploader, and where the backup
+// is stored in the sink.
+type UploadOptions struct {
+	influxdb.BackupOptions
+
+	// Path is the directory of the sink the backup is stored in.
+	Path string
+	// Retention is the number of backups kept in Path. Older backups are
+	// deleted once the backup is uploaded.
+	Retention int
+}
+
+// Uploader creates backups of the running instance and streams them to a
+// BackupSink. Uploaded backups have the same layout as those downloaded with
+// influx backup, so they can be restored the same way once copied back.
+type Uploader struct {
+	BackupService              influxdb.BackupService
+	KVBackupService            influxdb.KVBackupService
+	BucketService              influxdb.BucketService
+	LabelService               influxdb.LabelService
+	UserResourceMappingService influxdb.UserResourceMappingService
+
+	Sink influxdb.BackupSink
+
+	// Retention is the number of backups kept in each directory of the sink if
+	// the upload options do not set one. Zero keeps all backups.
+	Retention int
+
+	// CredentialsPath is the path of the credentials file included in backups
+	// of the whole instance, stored under its base name. It is left out if empty.
+	CredentialsPath string
+
+	Logger *zap.Logger
+}
+
+// Upload creates a backup with the given options and uploads it to the sink. It
+// returns the path of the backup in the sink.
+func (u *Uploader) Upload(ctx context.Context, opts UploadOptions) (string, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if opts.Base != nil {
+		return "", &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "incremental backups can not be uploaded to a backup sink",
+		}
+	}
+	if opts.BucketID != nil && opts.OrgID == nil {
+		b, err := u.BucketService.FindBucketByID(ctx, *opts.BucketID)
+		if err != nil {
+			return "", err
+		}
+		opts.OrgID = &b.OrgID
+	}
+
+	id, files, err := u.BackupService.CreateBackup(ctx, opts.BackupOptions)
+	if err != nil {
+		return "", err
+	}
+	dir := u.BackupService.InternalBackupPath(id)
+	defer os.RemoveAll(dir)
+
+	extra, err := u.writeMetadata(ctx, opts.BackupOptions, dir)
+	if err != nil {
+		return "", err
+	}
+	files = append(files, extra...)
+
+	manifest, err := completeManifest(dir, files)
+	if err != nil {
+		return "", err
+	}
+
+	p := path.Join(opts.Path, manifest.CreatedAt.Format(NameFormat))
+	if err := u.upload(ctx, p, dir, manifest); err != nil {
+		if derr := u.Sink.DeleteBackup(ctx, p); derr != nil {
+			err = multierr.Append(err, derr)
+		}
+		return "", err
+	}
+	u.logger().Info("Uploaded backup", zap.String("path", p), zap.Int("files", len(manifest.Files)))
+
+	retention := opts.Retention
+	if retention == 0 {
+		retention = u.Retention
+	}
+	if err := u.applyRetention(ctx, opts.Path, retention); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// writeMetadata writes the metadata included in the backup to dir, and returns
+// the names of the files written. Scoped backups hold the metadata of their
+// buckets, and others the metadata database and credentials of the instance.
+func (u *Uploader) writeMetadata(ctx context.Context, opts influxdb.BackupOptions, dir string) ([]string, error) {
+	if opts.Scoped() {
+		ms, err := BucketMetadata(ctx, opts, u.BucketService, u.LabelService, u.UserResourceMappingService)
+		if err != nil {
+			return nil, err
+		}
+		err = writeFile(filepath.Join(dir, influxdb.BackupBucketsFilename), func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(ms)
+		})
+		return []string{influxdb.BackupBucketsFilename}, err
+	}
+
+	if err := writeFile(filepath.Join(dir, bolt.DefaultFilename), func(w io.Writer) error {
+		return u.KVBackupService.Backup(ctx, w)
+	}); err != nil {
+		return nil, err
+	}
+	files := []string{bolt.DefaultFilename}
+
+	if u.CredentialsPath != "" {
+		name := filepath.Base(u.CredentialsPath)
+		if err := copyFile(u.CredentialsPath, filepath.Join(dir, name)); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+// upload stores the files listed in the manifest at p in the sink, followed by
+// the manifest itself.
+func (u *Uploader) upload(ctx context.Context, p, dir string, manifest *influxdb.BackupManifest) error {
+	for _, mf := range manifest.Files {
+		f, err := os.Open(filepath.Join(dir, mf.FileName))
+		if err != nil {
+			return err
+		}
+		err = u.Sink.PutBackupFile(ctx, p, mf.FileName, f, mf.Size, mf.Checksum)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return u.Sink.PutBackupFile(ctx, p, influxdb.BackupManifestFilename, &buf, int64(buf.Len()), hex.EncodeToString(sum[:]))
+}
+
+// applyRetention deletes all but the newest retention backups in dir.
+func (u *Uploader) applyRetention(ctx context.Context, dir string, retention int) error {
+	if retention <= 0 {
+		return nil
+	}
+
+	backups, err := u.Sink.ListBackups(ctx, dir)
+	if err != nil {
+		return err
+	}
+	for len(backups) > retention {
+		if err := u.Sink.DeleteBackup(ctx, backups[0]); err != nil {
+			return err
+		}
+		u.logger().Info("Deleted expired backup", zap.String("path", backups[0]))
+		backups = backups[1:]
+	}
+	return nil
+}
+
+func (u *Uploader) logger() *zap.Logger {
+	if u.Logger == nil {
+		return zap.NewNop()
+	}
+	return u.Logger
+}
+
+// completeManifest reads the manifest of the backup in dir, if it has one, and
+// fills in the sizes and checksums of the given files. Files that the manifest
+// does not list, such as the metadata, are added to it.
+func completeManifest(dir string, files []string) (*influxdb.BackupManifest, error) {
+	manifest := &influxdb.BackupManifest{CreatedAt: time.Now().UTC()}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, influxdb.BackupManifestFilename)); err == nil {
+		if err := json.Unmarshal(b, manifest); err != nil {
+			return nil, fmt.Errorf("failed to decode backup manifest: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	index := make(map[string]int, len(manifest.Files))
+	for i, f := range manifest.Files {
+		index[f.FileName] = i
+	}
+
+	for _, name := range files {
+		if name == influxdb.BackupManifestFilename {
+			continue
+		}
+		size, sum, err := checksumFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(manifest.Files)
+			manifest.Files = append(manifest.Files, influxdb.BackupManifestFile{FileName: name})
+		}
+		manifest.Files[i].Size = size
+		manifest.Files[i].Checksum = sum
+	}
+	return manifest, nil
+}
+
+// checksumFile returns the size and hex encoded SHA-256 checksum of the file at path.
+func checksumFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return n, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeFile creates the file at path with the content written by fn.
+func writeFile(path string, fn func(w io.Writer) error) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if err := fn(f); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	return f.Close()
+}
+
+func copyFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return writeFile(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
@@ -0,0 +1,156 @@
+package backup_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/backup"
+	"github.com/influxdata/influxdb/bolt"
+	"github.com/influxdata/influxdb/mock"
+)
+
+// fakeBackupService creates backups holding a single TSM file, with manifests
+// created a minute apart.
+type fakeBackupService struct {
+	dir     string
+	n       int
+	created time.Time
+}
+
+func (s *fakeBackupService) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
+	s.n++
+	dir := s.InternalBackupPath(s.n)
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return 0, nil, err
+	}
+
+	data := fmt.Sprintf("data %d", s.n)
+	if err := ioutil.WriteFile(filepath.Join(dir, "000000001-000000001.tsm"), []byte(data), 0666); err != nil {
+		return 0, nil, err
+	}
+
+	m := influxdb.BackupManifest{
+		CreatedAt: s.created.Add(time.Duration(s.n) * time.Minute),
+		Files: []influxdb.BackupManifestFile{
+			{FileName: "000000001-000000001.tsm", Generation: 1, Sequence: 1, Size: int64(len(data))},
+		},
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, influxdb.BackupManifestFilename), b, 0666); err != nil {
+		return 0, nil, err
+	}
+	return s.n, []string{"000000001-000000001.tsm", influxdb.BackupManifestFilename}, nil
+}
+
+func (s *fakeBackupService) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
+	panic("not implemented")
+}
+
+func (s *fakeBackupService) InternalBackupPath(backupID int) string {
+	return filepath.Join(s.dir, fmt.Sprint(backupID))
+}
+
+func TestUploader_Upload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup-uploader-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	credentials := filepath.Join(dir, "credentials")
+	if err := ioutil.WriteFile(credentials, []byte("token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	sinkDir := filepath.Join(dir, "sink")
+	bs := &fakeBackupService{
+		dir:     filepath.Join(dir, "backups"),
+		created: time.Date(2019, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	u := &backup.Uploader{
+		BackupService: bs,
+		KVBackupService: &mock.Store{
+			BackupFn: func(ctx context.Context, w io.Writer) error {
+				_, err := io.WriteString(w, "bolt")
+				return err
+			},
+		},
+		Sink:            backup.NewFileSink(sinkDir),
+		Retention:       2,
+		CredentialsPath: credentials,
+	}
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		p, err := u.Upload(context.Background(), backup.UploadOptions{Path: "nightly"})
+		if err != nil {
+			t.Fatalf("error uploading backup: %v", err)
+		}
+		paths = append(paths, p)
+
+		if _, err := os.Stat(bs.InternalBackupPath(bs.n)); !os.IsNotExist(err) {
+			t.Fatalf("local backup files not removed: %v", err)
+		}
+	}
+	if exp := "nightly/20191001T000100.000Z"; paths[0] != exp {
+		t.Fatalf("got backup path %s, exp %s", paths[0], exp)
+	}
+
+	backups, err := u.Sink.ListBackups(context.Background(), "nightly")
+	if err != nil {
+		t.Fatalf("error listing backups: %v", err)
+	}
+	if exp := paths[1:]; !reflect.DeepEqual(backups, exp) {
+		t.Fatalf("got backups %v, exp %v", backups, exp)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(sinkDir, filepath.FromSlash(paths[2]), influxdb.BackupManifestFilename))
+	if err != nil {
+		t.Fatalf("error reading manifest: %v", err)
+	}
+	var m influxdb.BackupManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("error decoding manifest: %v", err)
+	}
+
+	exp := []influxdb.BackupManifestFile{
+		{FileName: "000000001-000000001.tsm", Generation: 1, Sequence: 1, Size: 6, Checksum: checksum("data 3")},
+		{FileName: bolt.DefaultFilename, Size: 4, Checksum: checksum("bolt")},
+		{FileName: "credentials", Size: 5, Checksum: checksum("token")},
+	}
+	if !reflect.DeepEqual(m.Files, exp) {
+		t.Fatalf("got manifest files %+v, exp %+v", m.Files, exp)
+	}
+
+	for _, f := range exp {
+		b, err := ioutil.ReadFile(filepath.Join(sinkDir, filepath.FromSlash(paths[2]), f.FileName))
+		if err != nil {
+			t.Fatalf("error reading %s: %v", f.FileName, err)
+		}
+		if got := checksum(string(b)); got != f.Checksum {
+			t.Fatalf("got checksum %s for %s, exp %s", got, f.FileName, f.Checksum)
+		}
+	}
+}
+
+func TestUploader_Upload_Incremental(t *testing.T) {
+	u := &backup.Uploader{}
+	_, err := u.Upload(context.Background(), backup.UploadOptions{
+		BackupOptions: influxdb.BackupOptions{Base: &influxdb.BackupManifest{}},
+	})
+	if influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("expected invalid error: got %v", err)
+	}
+}
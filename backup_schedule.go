@@ -0,0 +1,182 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/cron"
+)
+
+const (
+	// BackupRunStarted is the status of a backup run in progress.
+	BackupRunStarted = "started"
+	// BackupRunSuccess is the status of a backup run that uploaded its backup.
+	BackupRunSuccess = "success"
+	// BackupRunFailed is the status of a backup run that did not complete.
+	BackupRunFailed = "failed"
+
+	// MaxBackupRuns is the number of runs kept in the history of a backup schedule.
+	MaxBackupRuns = 100
+)
+
+var (
+	// ErrBackupScheduleNotFound is returned when a backup schedule is not found.
+	ErrBackupScheduleNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "backup schedule not found",
+	}
+
+	// ErrBackupRunNotFound is returned when a run of a backup schedule is not found.
+	ErrBackupRunNotFound = &Error{
+		Code: ENotFound,
+		Msg:  "backup run not found",
+	}
+)
+
+// BackupSchedule describes backups the server takes periodically and uploads
+// to its backup sink.
+type BackupSchedule struct {
+	ID          ID     `json:"id,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Cron is the cron expression, in UTC, of the times backups are taken.
+	Cron string `json:"cron"`
+	// Path is the directory of the backup sink the backups are uploaded to.
+	Path string `json:"path,omitempty"`
+	// Retention is the number of backups kept in Path. If zero, the retention
+	// configured for the server applies.
+	Retention int `json:"retention,omitempty"`
+	// OrgID and BucketID limit the backups to one organization, or one
+	// bucket in it.
+	OrgID    *ID    `json:"orgID,omitempty"`
+	BucketID *ID    `json:"bucketID,omitempty"`
+	Status   Status `json:"status"`
+	CRUDLog
+}
+
+// Valid returns an error if the backup schedule is not valid.
+func (s *BackupSchedule) Valid() error {
+	if s.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "backup schedule name is required",
+		}
+	}
+	if _, err := cron.ParseUTC(s.Cron); err != nil {
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid cron expression %q", s.Cron),
+			Err:  err,
+		}
+	}
+	if s.Retention < 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "backup schedule retention must not be negative",
+		}
+	}
+	return s.Status.Valid()
+}
+
+// Next returns the time of the next backup after from.
+func (s *BackupSchedule) Next(from time.Time) (time.Time, error) {
+	c, err := cron.ParseUTC(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Next(from)
+}
+
+// BackupScheduleUpdate is the set of changes to a backup schedule.
+type BackupScheduleUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	Cron        *string `json:"cron,omitempty"`
+	Path        *string `json:"path,omitempty"`
+	Retention   *int    `json:"retention,omitempty"`
+	Status      *Status `json:"status,omitempty"`
+}
+
+// Apply applies the changes of the update to the backup schedule.
+func (u BackupScheduleUpdate) Apply(s *BackupSchedule) {
+	if u.Name != nil {
+		s.Name = *u.Name
+	}
+	if u.Description != nil {
+		s.Description = *u.Description
+	}
+	if u.Cron != nil {
+		s.Cron = *u.Cron
+	}
+	if u.Path != nil {
+		s.Path = *u.Path
+	}
+	if u.Retention != nil {
+		s.Retention = *u.Retention
+	}
+	if u.Status != nil {
+		s.Status = *u.Status
+	}
+}
+
+// BackupScheduleFilter represents a set of filters that restrict the returned
+// backup schedules.
+type BackupScheduleFilter struct {
+	Name   *string
+	Status *Status
+}
+
+// BackupRun is a single run of a backup schedule.
+type BackupRun struct {
+	ID         ID     `json:"id,omitempty"`
+	ScheduleID ID     `json:"scheduleID"`
+	Status     string `json:"status"`
+	// ScheduledFor is the time the backup was due according to the schedule.
+	ScheduledFor time.Time `json:"scheduledFor"`
+	StartedAt    time.Time `json:"startedAt,omitempty"`
+	FinishedAt   time.Time `json:"finishedAt,omitempty"`
+	// Path is the path of the uploaded backup in the backup sink.
+	Path string `json:"path,omitempty"`
+	// Error describes why the run failed.
+	Error string `json:"error,omitempty"`
+}
+
+// BackupScheduleService represents a service for managing backup schedules
+// and looking up their run history.
+type BackupScheduleService interface {
+	// FindBackupScheduleByID returns a single backup schedule by ID.
+	FindBackupScheduleByID(ctx context.Context, id ID) (*BackupSchedule, error)
+
+	// FindBackupSchedules returns a list of backup schedules that match filter
+	// and the total count of matching backup schedules.
+	FindBackupSchedules(ctx context.Context, filter BackupScheduleFilter) ([]*BackupSchedule, int, error)
+
+	// CreateBackupSchedule creates a new backup schedule and sets s.ID with
+	// the new identifier.
+	CreateBackupSchedule(ctx context.Context, s *BackupSchedule) error
+
+	// UpdateBackupSchedule updates a single backup schedule with changeset.
+	// Returns the new backup schedule state after update.
+	UpdateBackupSchedule(ctx context.Context, id ID, upd BackupScheduleUpdate) (*BackupSchedule, error)
+
+	// DeleteBackupSchedule removes a backup schedule by ID, along with its run history.
+	DeleteBackupSchedule(ctx context.Context, id ID) error
+
+	// FindBackupRuns returns the runs of the backup schedule, newest first.
+	FindBackupRuns(ctx context.Context, scheduleID ID) ([]*BackupRun, int, error)
+
+	// FindBackupRunByID returns a single run of the backup schedule.
+	FindBackupRunByID(ctx context.Context, scheduleID, runID ID) (*BackupRun, error)
+}
+
+// BackupRunService records the runs of backup schedules.
+type BackupRunService interface {
+	// CreateBackupRun adds a run to the history of its backup schedule and
+	// sets r.ID with the new identifier. Only the latest MaxBackupRuns runs
+	// of a schedule are kept.
+	CreateBackupRun(ctx context.Context, r *BackupRun) error
+
+	// UpdateBackupRun stores the current state of the run.
+	UpdateBackupRun(ctx context.Context, r *BackupRun) error
+}
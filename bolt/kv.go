@@ -136,6 +136,93 @@ func (s *KVStore) Backup(ctx context.Context, w io.Writer) error {
 	})
 }
 
+// Restore replaces all K:Vs with those of the bolt database read from r.
+// The database is written next to the current one and checked, then its
+// buckets replace the current ones in a single transaction. The database
+// handle is kept, as it is shared with the other users of the bolt file.
+func (s *KVStore) Restore(ctx context.Context, r io.Reader) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	tmpPath := s.path + ".restore"
+	if err := writeFile(tmpPath, r); err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	src, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("unable to open boltdb file %v", err)
+	}
+	defer src.Close()
+
+	// The values of the restored database are only valid while its
+	// transaction is open, so it outlives the one writing them.
+	return src.View(func(stx *bolt.Tx) error {
+		for err := range stx.Check() {
+			return fmt.Errorf("invalid boltdb file: %v", err)
+		}
+
+		return s.db.Update(func(tx *bolt.Tx) error {
+			var names [][]byte
+			if err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+				names = append(names, append([]byte(nil), name...))
+				return nil
+			}); err != nil {
+				return err
+			}
+			for _, name := range names {
+				if err := tx.DeleteBucket(name); err != nil {
+					return err
+				}
+			}
+
+			return stx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				dst, err := tx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBucket(dst, b)
+			})
+		})
+	})
+}
+
+// writeFile writes the content of r to a new file at path.
+func writeFile(path string, r io.Reader) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// copyBucket copies the keys, nested buckets and sequence of src to dst.
+func copyBucket(dst, src *bolt.Bucket) error {
+	if err := dst.SetSequence(src.Sequence()); err != nil {
+		return err
+	}
+	return src.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return dst.Put(k, v)
+		}
+		child, err := dst.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBucket(child, src.Bucket(k))
+	})
+}
+
 // Tx is a light wrapper around a boltdb transaction. It implements kv.Tx.
 type Tx struct {
 	tx  *bolt.Tx
@@ -1,11 +1,17 @@
 package bolt_test
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	bbolt "github.com/coreos/bbolt"
+	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/kv"
 	platformtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
 )
 
 func initKVStore(f platformtesting.KVStoreFields, t *testing.T) (kv.Store, func()) {
@@ -39,3 +45,88 @@ func initKVStore(f platformtesting.KVStoreFields, t *testing.T) (kv.Store, func(
 func TestKVStore(t *testing.T) {
 	platformtesting.KVStore(initKVStore, t)
 }
+
+func TestKVStore_Restore(t *testing.T) {
+	put := func(s kv.Store, key, value string) {
+		t.Helper()
+		err := s.Update(context.Background(), func(tx kv.Tx) error {
+			b, err := tx.Bucket([]byte("bucket"))
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte(key), []byte(value))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(s kv.Store, key string) string {
+		t.Helper()
+		var value []byte
+		err := s.View(context.Background(), func(tx kv.Tx) error {
+			b, err := tx.Bucket([]byte("bucket"))
+			if err != nil {
+				return err
+			}
+			value, err = b.Get([]byte(key))
+			if kv.IsNotFound(err) {
+				return nil
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(value)
+	}
+
+	src, closeSrc, err := NewTestKVStore(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeSrc()
+	put(src, "a", "1")
+
+	var buf bytes.Buffer
+	if err := src.Backup(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// The store shares the database handle of a client, as in influxd.
+	client, closeClient, err := NewTestClient(t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeClient()
+	dst := bolt.NewKVStore(zaptest.NewLogger(t), client.Path)
+	dst.WithDB(client.DB())
+	put(dst, "b", "2")
+
+	// An invalid database is rejected and the current one kept.
+	if err := dst.Restore(context.Background(), strings.NewReader("not a bolt file")); err == nil {
+		t.Fatal("expected error restoring invalid database")
+	}
+	if got, exp := get(dst, "b"), "2"; got != exp {
+		t.Fatalf("got %q, exp %q", got, exp)
+	}
+
+	if err := dst.Restore(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := get(dst, "a"), "1"; got != exp {
+		t.Fatalf("got %q, exp %q", got, exp)
+	}
+	if got, exp := get(dst, "b"), ""; got != exp {
+		t.Fatalf("got %q, exp %q", got, exp)
+	}
+
+	// The handle is still open for the other users of the database.
+	if err := client.DB().View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket([]byte("bucket")).Get([]byte("a")); string(v) != "1" {
+			return fmt.Errorf("got %q, exp %q", v, "1")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
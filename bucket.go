@@ -2,6 +2,7 @@ package influxdb
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -33,16 +34,54 @@ const InfiniteRetention = 0
 
 // Bucket is a bucket. 🎉
 type Bucket struct {
-	ID                  ID            `json:"id,omitempty"`
-	OrgID               ID            `json:"orgID,omitempty"`
-	Type                BucketType    `json:"type"`
-	Name                string        `json:"name"`
-	Description         string        `json:"description"`
-	RetentionPolicyName string        `json:"rp,omitempty"` // This to support v1 sources
-	RetentionPeriod     time.Duration `json:"retentionPeriod"`
+	ID                  ID             `json:"id,omitempty"`
+	OrgID               ID             `json:"orgID,omitempty"`
+	Type                BucketType     `json:"type"`
+	Name                string         `json:"name"`
+	Description         string         `json:"description"`
+	RetentionPolicyName string         `json:"rp,omitempty"` // This to support v1 sources
+	RetentionPeriod     time.Duration  `json:"retentionPeriod"`
+	ColdStoragePeriod   time.Duration  `json:"coldStoragePeriod,omitempty"` // Age after which data is moved to cold storage, never if zero.
+	RollupPolicies      []RollupPolicy `json:"rollupPolicies,omitempty"`
+	Codecs              BlockCodecs    `json:"codecs,omitempty"`
+	MaxSeries           int64          `json:"maxSeries,omitempty"`      // Maximum number of series, unlimited if zero.
+	LastValueCache      bool           `json:"lastValueCache,omitempty"` // Keep the last value of each series in memory.
 	CRUDLog
 }
 
+// ValidMaxSeries returns an error if n is not a valid maximum number of series
+// of a bucket or organization. A maximum of zero means unlimited.
+func ValidMaxSeries(n int64) error {
+	if n < 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "maximum number of series must not be negative",
+		}
+	}
+	return nil
+}
+
+// BlockCodecs are the names of the codecs compressing the stored values of a
+// bucket by field type. Values of types without a codec use the default codec.
+type BlockCodecs map[SchemaFieldType]string
+
+// Valid returns an error if a field type is unknown or a codec is unnamed.
+// Whether the codecs exist is up to the storage engine.
+func (c BlockCodecs) Valid() error {
+	for typ, name := range c {
+		if err := typ.Valid(); err != nil {
+			return err
+		}
+		if name == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("codec of %s values must be named", typ),
+			}
+		}
+	}
+	return nil
+}
+
 // BucketType differentiates system buckets from user buckets.
 type BucketType int
 
@@ -99,9 +138,14 @@ type BucketService interface {
 // BucketUpdate represents updates to a bucket.
 // Only fields which are set are updated.
 type BucketUpdate struct {
-	Name            *string        `json:"name,omitempty"`
-	Description     *string        `json:"description,omitempty"`
-	RetentionPeriod *time.Duration `json:"retentionPeriod,omitempty"`
+	Name              *string         `json:"name,omitempty"`
+	Description       *string         `json:"description,omitempty"`
+	RetentionPeriod   *time.Duration  `json:"retentionPeriod,omitempty"`
+	ColdStoragePeriod *time.Duration  `json:"coldStoragePeriod,omitempty"`
+	RollupPolicies    *[]RollupPolicy `json:"rollupPolicies,omitempty"`
+	Codecs            *BlockCodecs    `json:"codecs,omitempty"`
+	MaxSeries         *int64          `json:"maxSeries,omitempty"`
+	LastValueCache    *bool           `json:"lastValueCache,omitempty"`
 }
 
 // BucketFilter represents a set of filter that restrict the returned results.
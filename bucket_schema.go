@@ -0,0 +1,199 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrBucketSchemaNotFound is returned when a bucket has no explicit schema.
+var ErrBucketSchemaNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "bucket schema not found",
+}
+
+// ops for bucket schema errors.
+var (
+	OpFindBucketSchema   = "FindBucketSchema"
+	OpPutBucketSchema    = "PutBucketSchema"
+	OpDeleteBucketSchema = "DeleteBucketSchema"
+)
+
+// SchemaFieldType is the type of the values of a field in a bucket schema.
+type SchemaFieldType string
+
+// Field types of bucket schemas.
+const (
+	SchemaFieldFloat    SchemaFieldType = "float"
+	SchemaFieldInteger  SchemaFieldType = "integer"
+	SchemaFieldUnsigned SchemaFieldType = "unsigned"
+	SchemaFieldString   SchemaFieldType = "string"
+	SchemaFieldBoolean  SchemaFieldType = "boolean"
+)
+
+// Valid returns an error if the field type is unknown.
+func (t SchemaFieldType) Valid() error {
+	switch t {
+	case SchemaFieldFloat, SchemaFieldInteger, SchemaFieldUnsigned, SchemaFieldString, SchemaFieldBoolean:
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("unknown field type %q", t),
+	}
+}
+
+// SchemaAction determines what happens to points written to a bucket that do
+// not conform to its schema.
+type SchemaAction string
+
+const (
+	// SchemaActionReject drops nonconforming points and fails the write.
+	SchemaActionReject SchemaAction = "reject"
+	// SchemaActionRoute writes nonconforming points to another bucket.
+	SchemaActionRoute SchemaAction = "route"
+)
+
+// BucketSchema is the explicit schema of a bucket. Points conform to it if
+// their measurement is in the schema, all their tag keys are tags of the
+// measurement, and their fields are fields of the measurement of the same
+// type. Tags may be omitted.
+type BucketSchema struct {
+	BucketID     ID                  `json:"bucketID"`
+	OrgID        ID                  `json:"orgID"`
+	Measurements []MeasurementSchema `json:"measurements"`
+	Action       SchemaAction        `json:"action"`
+	// RouteBucketID is the bucket of the same organization nonconforming
+	// points are written to if Action is SchemaActionRoute.
+	RouteBucketID ID `json:"routeBucketID,omitempty"`
+	CRUDLog
+}
+
+// MeasurementSchema is the schema of a measurement of a bucket.
+type MeasurementSchema struct {
+	Name   string        `json:"name"`
+	Tags   []string      `json:"tags"`
+	Fields []FieldSchema `json:"fields"`
+}
+
+// FieldSchema is the schema of a field of a measurement.
+type FieldSchema struct {
+	Name string          `json:"name"`
+	Type SchemaFieldType `json:"type"`
+}
+
+// Valid returns an error if the bucket schema is not valid.
+func (s *BucketSchema) Valid() error {
+	switch s.Action {
+	case SchemaActionReject:
+	case SchemaActionRoute:
+		if !s.RouteBucketID.Valid() {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "routing bucket schema requires a valid route bucket ID",
+			}
+		} else if s.RouteBucketID == s.BucketID {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "bucket schema must not route points to its own bucket",
+			}
+		}
+	default:
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("unknown bucket schema action %q", s.Action),
+		}
+	}
+
+	measurements := make(map[string]bool, len(s.Measurements))
+	for _, m := range s.Measurements {
+		if m.Name == "" {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "measurement name is required",
+			}
+		} else if measurements[m.Name] {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("measurement %q is defined more than once", m.Name),
+			}
+		}
+		measurements[m.Name] = true
+
+		tags := make(map[string]bool, len(m.Tags))
+		for _, k := range m.Tags {
+			if k == "" || tags[k] {
+				return &Error{
+					Code: EInvalid,
+					Msg:  fmt.Sprintf("measurement %q has an empty or duplicate tag key", m.Name),
+				}
+			}
+			tags[k] = true
+		}
+
+		fields := make(map[string]bool, len(m.Fields))
+		for _, f := range m.Fields {
+			if f.Name == "" || fields[f.Name] {
+				return &Error{
+					Code: EInvalid,
+					Msg:  fmt.Sprintf("measurement %q has an empty or duplicate field name", m.Name),
+				}
+			} else if tags[f.Name] {
+				return &Error{
+					Code: EInvalid,
+					Msg:  fmt.Sprintf("measurement %q has a tag and field named %q", m.Name, f.Name),
+				}
+			}
+			if err := f.Type.Valid(); err != nil {
+				return err
+			}
+			fields[f.Name] = true
+		}
+	}
+	return nil
+}
+
+// Measurement returns the schema of the measurement with the given name, or
+// nil if it is not in the bucket schema.
+func (s *BucketSchema) Measurement(name string) *MeasurementSchema {
+	for i := range s.Measurements {
+		if s.Measurements[i].Name == name {
+			return &s.Measurements[i]
+		}
+	}
+	return nil
+}
+
+// HasTag returns true if key is a tag key of the measurement.
+func (m *MeasurementSchema) HasTag(key string) bool {
+	for _, k := range m.Tags {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Field returns the schema of the field with the given name, or nil if it is
+// not a field of the measurement.
+func (m *MeasurementSchema) Field(name string) *FieldSchema {
+	for i := range m.Fields {
+		if m.Fields[i].Name == name {
+			return &m.Fields[i]
+		}
+	}
+	return nil
+}
+
+// BucketSchemaService represents a service for managing the explicit schemas
+// of buckets.
+type BucketSchemaService interface {
+	// FindBucketSchema returns the schema of a bucket.
+	FindBucketSchema(ctx context.Context, bucketID ID) (*BucketSchema, error)
+
+	// PutBucketSchema sets the schema of the bucket s.BucketID, replacing
+	// its current schema.
+	PutBucketSchema(ctx context.Context, s *BucketSchema) error
+
+	// DeleteBucketSchema removes the schema of a bucket.
+	DeleteBucketSchema(ctx context.Context, bucketID ID) error
+}
@@ -0,0 +1,69 @@
+package influxdb
+
+import "context"
+
+// Ops for cardinality errors.
+const (
+	OpFindCardinality = "FindCardinality"
+)
+
+// DefaultCardinalityTopValues is the number of tag values by series count
+// returned per tag key when no limit is given.
+const DefaultCardinalityTopValues = 10
+
+// BucketCardinality is the number of series of a bucket. The cardinality of
+// the measurements of the bucket is only included when a single bucket is
+// requested.
+type BucketCardinality struct {
+	OrgID        ID                        `json:"orgID"`
+	BucketID     ID                        `json:"bucketID"`
+	Series       int64                     `json:"series"`
+	Measurements []*MeasurementCardinality `json:"measurements,omitempty"`
+}
+
+// MeasurementCardinality is the number of series of a measurement, and the
+// cardinality of its tag keys.
+type MeasurementCardinality struct {
+	Name    string               `json:"name"`
+	Series  int64                `json:"series"`
+	TagKeys []*TagKeyCardinality `json:"tagKeys"`
+}
+
+// TagKeyCardinality is the number of distinct values of a tag key of a
+// measurement, and the values with the most series.
+type TagKeyCardinality struct {
+	Key       string                `json:"key"`
+	Values    int64                 `json:"values"`
+	TopValues []TagValueCardinality `json:"topValues"`
+}
+
+// TagValueCardinality is the number of series of a tag value.
+type TagValueCardinality struct {
+	Value  string `json:"value"`
+	Series int64  `json:"series"`
+}
+
+// CardinalityFilter selects the buckets and measurements of which to find
+// the cardinality.
+type CardinalityFilter struct {
+	OrgID ID
+
+	// BucketID selects a single bucket, of which the cardinality of the
+	// measurements and tag keys is included.
+	BucketID *ID
+
+	// Measurement restricts the measurements of a single bucket to one.
+	Measurement *string
+
+	// TopValues is the number of tag values with the most series returned per
+	// tag key. DefaultCardinalityTopValues is used if zero.
+	TopValues int
+}
+
+// CardinalityService lets operators find the buckets, measurements and tags
+// causing high series cardinality while the storage engine is running.
+type CardinalityService interface {
+	// FindCardinality returns the cardinality of the buckets matching filter,
+	// ordered by descending number of series.
+	FindCardinality(ctx context.Context, filter CardinalityFilter) ([]*BucketCardinality, error)
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
@@ -22,8 +28,17 @@ func cmdBackup() *cobra.Command {
 			`Backs up data and meta data for the running InfluxDB instance.
 Downloaded files are written to the directory indicated by --path.
 The target directory, and any parent directories, are created automatically.
-Data file have extension .tsm; meta data is written to %s in the same directory.`,
-			bolt.DefaultFilename),
+Data file have extension .tsm; meta data is written to %s in the same directory.
+A manifest of all files in the backup is written to %s.
+
+With --incremental-from, only data files that changed since the backup in the
+given directory are downloaded. The manifest references the unchanged files in
+the earlier backup, so restoring the new backup restores the whole chain.
+
+With --org or --bucket, the backup only holds the data of that organization or
+bucket. Instead of the meta data of the instance, the meta data of its buckets
+is written to %s. Such backups are restored with influx restore.`,
+			bolt.DefaultFilename, influxdb.BackupManifestFilename, influxdb.BackupBucketsFilename),
 		RunE: backupF,
 	}
 	opts := flagOpts{
@@ -35,6 +50,33 @@ Data file have extension .tsm; meta data is written to %s in the same directory.
 			Desc:     "directory path to write backup files to",
 			Required: true,
 		},
+		{
+			DestP: &backupFlags.IncrementalFrom,
+			Flag:  "incremental-from",
+			Desc:  "directory path of an earlier backup to create an incremental backup from",
+		},
+		{
+			DestP: &backupFlags.OrgID,
+			Flag:  "org-id",
+			Desc:  "The ID of the organization to back up",
+		},
+		{
+			DestP: &backupFlags.Org,
+			Flag:  "org",
+			Short: 'o',
+			Desc:  "The name of the organization to back up",
+		},
+		{
+			DestP: &backupFlags.BucketID,
+			Flag:  "bucket-id",
+			Desc:  "The ID of the bucket to back up",
+		},
+		{
+			DestP: &backupFlags.Bucket,
+			Flag:  "bucket",
+			Short: 'b',
+			Desc:  "The name of the bucket to back up",
+		},
 	}
 	opts.mustRegister(cmd)
 
@@ -42,7 +84,12 @@ Data file have extension .tsm; meta data is written to %s in the same directory.
 }
 
 var backupFlags struct {
-	Path string
+	Path            string
+	IncrementalFrom string
+	OrgID           string
+	Org             string
+	BucketID        string
+	Bucket          string
 }
 
 func init() {
@@ -73,8 +120,27 @@ func backupF(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("must specify path")
 	}
 
-	err := os.MkdirAll(backupFlags.Path, 0777)
-	if err != nil && !os.IsExist(err) {
+	var opts influxdb.BackupOptions
+	if err := backupScope(ctx, &opts); err != nil {
+		return err
+	}
+
+	var baseDir string
+	if backupFlags.IncrementalFrom != "" {
+		var err error
+		if baseDir, err = filepath.Abs(backupFlags.IncrementalFrom); err != nil {
+			return err
+		}
+		if opts.Base, err = readBackupManifest(baseDir); err != nil {
+			return fmt.Errorf("failed to read manifest of base backup: %v", err)
+		}
+	}
+
+	dir, err := filepath.Abs(backupFlags.Path)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil && !os.IsExist(err) {
 		return err
 	}
 
@@ -83,29 +149,205 @@ func backupF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	id, backupFilenames, err := backupService.CreateBackup(ctx)
+	id, backupFilenames, err := backupService.CreateBackup(ctx, opts)
 	if err != nil {
 		return err
 	}
 
 	fmt.Printf("Backup ID %d contains %d files\n", id, len(backupFilenames))
 
+	var (
+		manifest  *influxdb.BackupManifest
+		checksums = make(map[string]string, len(backupFilenames))
+	)
 	for _, backupFilename := range backupFilenames {
-		dest := filepath.Join(backupFlags.Path, backupFilename)
+		if backupFilename == influxdb.BackupManifestFilename {
+			var buf bytes.Buffer
+			if err := backupService.FetchBackupFile(ctx, id, backupFilename, &buf); err != nil {
+				return fmt.Errorf("error fetching file %s: %v", backupFilename, err)
+			}
+			manifest = &influxdb.BackupManifest{}
+			if err := json.NewDecoder(&buf).Decode(manifest); err != nil {
+				return fmt.Errorf("error decoding manifest: %v", err)
+			}
+			continue
+		}
+
+		dest := filepath.Join(dir, backupFilename)
 		w, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 		if err != nil {
 			return err
 		}
-		err = backupService.FetchBackupFile(ctx, id, backupFilename, w)
+		h := sha256.New()
+		err = backupService.FetchBackupFile(ctx, id, backupFilename, io.MultiWriter(w, h))
 		if err != nil {
 			return multierr.Append(fmt.Errorf("error fetching file %s: %v", backupFilename, err), w.Close())
 		}
 		if err = w.Close(); err != nil {
 			return err
 		}
+		checksums[backupFilename] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if manifest == nil {
+		if opts.Base != nil {
+			return fmt.Errorf("server did not return a backup manifest; incremental backups are not supported")
+		}
+		fmt.Printf("Backup complete")
+		return nil
+	}
+
+	if err := completeBackupManifest(manifest, opts.Base, baseDir, dir, checksums); err != nil {
+		return err
+	}
+	if err := writeBackupManifest(dir, manifest); err != nil {
+		return err
 	}
 
 	fmt.Printf("Backup complete")
 
 	return nil
 }
+
+// backupScope sets the organization and bucket to back up in opts from the
+// command line flags, looking up names where needed.
+func backupScope(ctx context.Context, opts *influxdb.BackupOptions) error {
+	if backupFlags.Org != "" && backupFlags.OrgID != "" {
+		return fmt.Errorf("please specify one of org or org-id")
+	}
+	if backupFlags.Bucket != "" && backupFlags.BucketID != "" {
+		return fmt.Errorf("please specify one of bucket or bucket-id")
+	}
+
+	if backupFlags.OrgID != "" {
+		id, err := influxdb.IDFromString(backupFlags.OrgID)
+		if err != nil {
+			return fmt.Errorf("failed to decode org-id: %v", err)
+		}
+		opts.OrgID = id
+	}
+	if backupFlags.BucketID != "" {
+		id, err := influxdb.IDFromString(backupFlags.BucketID)
+		if err != nil {
+			return fmt.Errorf("failed to decode bucket-id: %v", err)
+		}
+		opts.BucketID = id
+	}
+
+	switch {
+	case backupFlags.Bucket != "":
+		bs, err := newBucketService()
+		if err != nil {
+			return err
+		}
+		filter := influxdb.BucketFilter{Name: &backupFlags.Bucket, OrganizationID: opts.OrgID}
+		if backupFlags.Org != "" {
+			filter.Org = &backupFlags.Org
+		}
+		b, err := bs.FindBucket(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("failed to find bucket %q: %v", backupFlags.Bucket, err)
+		}
+		opts.OrgID, opts.BucketID = &b.OrgID, &b.ID
+	case backupFlags.Org != "":
+		orgSvc, err := newOrganizationService()
+		if err != nil {
+			return err
+		}
+		o, err := orgSvc.FindOrganization(ctx, influxdb.OrganizationFilter{Name: &backupFlags.Org})
+		if err != nil {
+			return fmt.Errorf("failed to find organization %q: %v", backupFlags.Org, err)
+		}
+		opts.OrgID = &o.ID
+	}
+	return nil
+}
+
+// completeBackupManifest checks the downloaded files against the checksums
+// listed by the server and points the entries for files that were not
+// downloaded at the backup in the chain that holds them. Downloaded files that
+// the server did not list, such as the metadata, are added to the manifest.
+// The directories baseDir and dir must be absolute; the manifest refers to
+// other backups by paths relative to dir.
+func completeBackupManifest(m, base *influxdb.BackupManifest, baseDir, dir string, checksums map[string]string) error {
+	if base != nil {
+		parent, err := filepath.Rel(dir, baseDir)
+		if err != nil {
+			return err
+		}
+		m.Parent = parent
+	}
+
+	listed := make(map[string]bool, len(m.Files))
+	for i := range m.Files {
+		f := &m.Files[i]
+		listed[f.FileName] = true
+
+		if sum, ok := checksums[f.FileName]; ok {
+			if f.Checksum != "" && f.Checksum != sum {
+				return fmt.Errorf("backup file %s has checksum %s, expected %s", f.FileName, sum, f.Checksum)
+			}
+			f.Checksum = sum
+			continue
+		}
+
+		prev, ok := base.File(f.FileName)
+		if !ok {
+			return fmt.Errorf("file %s is neither in the backup nor in the base backup", f.FileName)
+		}
+		location, err := filepath.Rel(dir, filepath.Dir(prev.Path(baseDir)))
+		if err != nil {
+			return err
+		}
+		f.Checksum = prev.Checksum
+		f.Location = location
+	}
+
+	var unlisted []string
+	for name := range checksums {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	sort.Strings(unlisted)
+
+	for _, name := range unlisted {
+		fi, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return err
+		}
+		m.Files = append(m.Files, influxdb.BackupManifestFile{
+			FileName: name,
+			Size:     fi.Size(),
+			Checksum: checksums[name],
+		})
+	}
+	return nil
+}
+
+func readBackupManifest(dir string) (*influxdb.BackupManifest, error) {
+	f, err := os.Open(filepath.Join(dir, influxdb.BackupManifestFilename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m influxdb.BackupManifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func writeBackupManifest(dir string, m *influxdb.BackupManifest) error {
+	f, err := os.OpenFile(filepath.Join(dir, influxdb.BackupManifestFilename), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(m); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	return f.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+)
+
+func TestCompleteBackupManifest(t *testing.T) {
+	root, err := ioutil.TempDir("", "influx-backup-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	full, base, dir := filepath.Join(root, "full"), filepath.Join(root, "base"), filepath.Join(root, "inc")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "influxd.bolt"), []byte("bolt"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	// The base backup is itself incremental to a full backup, and the
+	// manifests of each refer to the other backups by relative paths.
+	baseManifest := &influxdb.BackupManifest{
+		Parent: "../full",
+		Files: []influxdb.BackupManifestFile{
+			{FileName: "000000001-000000001.tsm", Size: 1, Checksum: "a", Location: "../full"},
+			{FileName: "000000002-000000001.tsm", Size: 2, Checksum: "b"},
+		},
+	}
+	m := &influxdb.BackupManifest{
+		Files: []influxdb.BackupManifestFile{
+			{FileName: "000000001-000000001.tsm", Size: 1, Checksum: "a"},
+			{FileName: "000000002-000000001.tsm", Size: 2, Checksum: "b"},
+			{FileName: "000000003-000000001.tsm", Size: 3, Checksum: "c"},
+		},
+	}
+	checksums := map[string]string{
+		"000000003-000000001.tsm": "c",
+		"influxd.bolt":            "d",
+	}
+	if err := completeBackupManifest(m, baseManifest, base, dir, checksums); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, exp := m.Parent, "../base"; got != exp {
+		t.Fatalf("got parent %q, exp %q", got, exp)
+	}
+	for _, tt := range []struct {
+		name string
+		path string
+	}{
+		{name: "000000001-000000001.tsm", path: full},
+		{name: "000000002-000000001.tsm", path: base},
+		{name: "000000003-000000001.tsm", path: dir},
+		{name: "influxd.bolt", path: dir},
+	} {
+		f, ok := m.File(tt.name)
+		if !ok {
+			t.Fatalf("manifest does not list %s", tt.name)
+		}
+		if got, exp := f.Path(dir), filepath.Join(tt.path, tt.name); got != exp {
+			t.Fatalf("got path %s, exp %s", got, exp)
+		}
+	}
+
+	// Downloaded files must match the checksums listed by the server.
+	m = &influxdb.BackupManifest{
+		Files: []influxdb.BackupManifestFile{
+			{FileName: "000000003-000000001.tsm", Size: 3, Checksum: "c"},
+		},
+	}
+	if err := completeBackupManifest(m, nil, "", dir, map[string]string{"000000003-000000001.tsm": "x"}); err == nil {
+		t.Fatal("expected an error for a checksum mismatch")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -14,14 +15,19 @@ import (
 
 type bucketSVCsFn func() (influxdb.BucketService, influxdb.OrganizationService, error)
 
+type bucketSchemaSVCFn func() (influxdb.BucketSchemaService, error)
+
 func cmdBucket(opts ...genericCLIOptFn) *cobra.Command {
-	return newCmdBucketBuilder(newBucketSVCs, opts...).cmd()
+	builder := newCmdBucketBuilder(newBucketSVCs, opts...)
+	builder.schemaSVCFn = newBucketSchemaSVC
+	return builder.cmd()
 }
 
 type cmdBucketBuilder struct {
 	genericCLIOpts
 
-	svcFn bucketSVCsFn
+	svcFn       bucketSVCsFn
+	schemaSVCFn bucketSchemaSVCFn
 
 	id          string
 	headers     bool
@@ -29,6 +35,10 @@ type cmdBucketBuilder struct {
 	description string
 	org         organization
 	retention   time.Duration
+
+	schemaFile    string
+	schemaAction  string
+	routeBucketID string
 }
 
 func newCmdBucketBuilder(svcsFn bucketSVCsFn, opts ...genericCLIOptFn) *cmdBucketBuilder {
@@ -56,6 +66,7 @@ func (b *cmdBucketBuilder) cmd() *cobra.Command {
 		b.cmdDelete(),
 		b.cmdFind(),
 		b.cmdUpdate(),
+		b.cmdSchema(),
 	)
 
 	return cmd
@@ -304,6 +315,168 @@ func (b *cmdBucketBuilder) cmdUpdateRunEFn(cmd *cobra.Command, args []string) er
 	return nil
 }
 
+func (b *cmdBucketBuilder) cmdSchema() *cobra.Command {
+	cmd := b.newCmd("schema", nil)
+	cmd.Short = "Bucket schema management commands"
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdSchemaGet(),
+		b.cmdSchemaSet(),
+		b.cmdSchemaDelete(),
+	)
+
+	return cmd
+}
+
+func (b *cmdBucketBuilder) cmdSchemaGet() *cobra.Command {
+	cmd := b.newCmd("get", b.cmdSchemaGetRunEFn)
+	cmd.Short = "Get the schema of a bucket"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The bucket ID (required)")
+	cmd.MarkFlagRequired("id")
+	cmd.Flags().BoolVar(&b.headers, "headers", true, "To print the table headers; defaults true")
+
+	return cmd
+}
+
+func (b *cmdBucketBuilder) cmdSchemaGetRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.schemaSVCFn()
+	if err != nil {
+		return err
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(b.id); err != nil {
+		return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+	}
+
+	schema, err := svc.FindBucketSchema(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve bucket schema: %v", err)
+	}
+
+	b.printBucketSchema(schema)
+	return nil
+}
+
+func (b *cmdBucketBuilder) cmdSchemaSet() *cobra.Command {
+	cmd := b.newCmd("set", b.cmdSchemaSetRunEFn)
+	cmd.Short = "Set the schema of a bucket"
+	cmd.Long = `Set the schema of a bucket, replacing its current schema.
+
+The schema file is a JSON document listing the measurements of the bucket:
+
+	{
+	  "measurements": [
+	    {
+	      "name": "cpu",
+	      "tags": ["host"],
+	      "fields": [{"name": "usage", "type": "float"}]
+	    }
+	  ]
+	}
+
+Field types are one of float, integer, unsigned, string and boolean.`
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The bucket ID (required)")
+	cmd.MarkFlagRequired("id")
+	cmd.Flags().StringVarP(&b.schemaFile, "file", "f", "", "Path to the JSON schema file (required)")
+	cmd.MarkFlagRequired("file")
+	cmd.Flags().StringVarP(&b.schemaAction, "action", "a", string(influxdb.SchemaActionReject), "Action taken on points not conforming to the schema; reject or route")
+	cmd.Flags().StringVar(&b.routeBucketID, "route-bucket-id", "", "The ID of the bucket nonconforming points are routed to")
+	cmd.Flags().BoolVar(&b.headers, "headers", true, "To print the table headers; defaults true")
+
+	return cmd
+}
+
+func (b *cmdBucketBuilder) cmdSchemaSetRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.schemaSVCFn()
+	if err != nil {
+		return err
+	}
+
+	var schema influxdb.BucketSchema
+	if err := schema.BucketID.DecodeFromString(b.id); err != nil {
+		return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+	}
+
+	f, err := os.Open(b.schemaFile)
+	if err != nil {
+		return fmt.Errorf("failed to open schema file: %v", err)
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(&schema); err != nil {
+		return fmt.Errorf("failed to decode schema file: %v", err)
+	}
+
+	schema.Action = influxdb.SchemaAction(b.schemaAction)
+	if b.routeBucketID != "" {
+		if err := schema.RouteBucketID.DecodeFromString(b.routeBucketID); err != nil {
+			return fmt.Errorf("failed to decode route bucket id %q: %v", b.routeBucketID, err)
+		}
+	}
+
+	if err := svc.PutBucketSchema(context.Background(), &schema); err != nil {
+		return fmt.Errorf("failed to set bucket schema: %v", err)
+	}
+
+	b.printBucketSchema(&schema)
+	return nil
+}
+
+func (b *cmdBucketBuilder) cmdSchemaDelete() *cobra.Command {
+	cmd := b.newCmd("delete", b.cmdSchemaDeleteRunEFn)
+	cmd.Short = "Delete the schema of a bucket"
+
+	cmd.Flags().StringVarP(&b.id, "id", "i", "", "The bucket ID (required)")
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func (b *cmdBucketBuilder) cmdSchemaDeleteRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.schemaSVCFn()
+	if err != nil {
+		return err
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(b.id); err != nil {
+		return fmt.Errorf("failed to decode bucket id %q: %v", b.id, err)
+	}
+
+	if err := svc.DeleteBucketSchema(context.Background(), id); err != nil {
+		return fmt.Errorf("failed to delete bucket schema: %v", err)
+	}
+	return nil
+}
+
+func (b *cmdBucketBuilder) printBucketSchema(schema *influxdb.BucketSchema) {
+	w := internal.NewTabWriter(b.w)
+	w.HideHeaders(!b.headers)
+	w.WriteHeaders("Measurement", "Kind", "Key", "Type")
+	for _, m := range schema.Measurements {
+		for _, t := range m.Tags {
+			w.Write(map[string]interface{}{
+				"Measurement": m.Name,
+				"Kind":        "tag",
+				"Key":         t,
+				"Type":        "string",
+			})
+		}
+		for _, f := range m.Fields {
+			w.Write(map[string]interface{}{
+				"Measurement": m.Name,
+				"Kind":        "field",
+				"Key":         f.Name,
+				"Type":        string(f.Type),
+			})
+		}
+	}
+	w.Flush()
+}
+
 func newBucketSVCs() (influxdb.BucketService, influxdb.OrganizationService, error) {
 	httpClient, err := newHTTPClient()
 	if err != nil {
@@ -314,3 +487,12 @@ func newBucketSVCs() (influxdb.BucketService, influxdb.OrganizationService, erro
 
 	return &http.BucketService{Client: httpClient}, orgSvc, nil
 }
+
+func newBucketSchemaSVC() (influxdb.BucketSchemaService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.BucketSchemaService{Client: httpClient}, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type compactionSVCsFn func() (influxdb.CompactionService, influxdb.BucketService, error)
+
+func cmdCompaction(opts ...genericCLIOptFn) *cobra.Command {
+	return newCmdCompactionBuilder(newCompactionSVCs, opts...).cmd()
+}
+
+type cmdCompactionBuilder struct {
+	genericCLIOpts
+
+	svcFn compactionSVCsFn
+
+	id       int
+	bucketID string
+	optimize bool
+	headers  bool
+}
+
+func newCmdCompactionBuilder(svcsFn compactionSVCsFn, opts ...genericCLIOptFn) *cmdCompactionBuilder {
+	opt := genericCLIOpts{
+		in: os.Stdin,
+		w:  os.Stdout,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &cmdCompactionBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcsFn,
+	}
+}
+
+func (b *cmdCompactionBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("compaction", nil)
+	cmd.Short = "Storage engine compaction management commands"
+	cmd.TraverseChildren = true
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdList(),
+		b.cmdBucket(),
+		b.cmdPause(),
+		b.cmdResume(),
+		b.cmdCancel(),
+	)
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdList() *cobra.Command {
+	cmd := b.newCmd("list", b.cmdListRunEFn)
+	cmd.Short = "List running and planned compactions"
+	cmd.Aliases = []string{"find", "ls"}
+
+	cmd.Flags().BoolVar(&b.headers, "headers", true, "To print the table headers; defaults true")
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdListRunEFn(cmd *cobra.Command, args []string) error {
+	svc, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	status, err := svc.FindCompactions(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve compactions: %v", err)
+	}
+
+	if status.Paused {
+		fmt.Fprintln(b.w, "Compactions are paused")
+	}
+	b.printCompactions(status.Compactions)
+	return nil
+}
+
+func (b *cmdCompactionBuilder) cmdBucket() *cobra.Command {
+	cmd := b.newCmd("bucket", b.cmdBucketRunEFn)
+	cmd.Short = "Compact the data of a bucket"
+	cmd.Long = `Schedule full compactions of the TSM files holding data of a bucket.
+
+With --optimize, optimize compactions are scheduled instead, which copy the
+blocks of the files where possible rather than decoding and merging them.`
+
+	cmd.Flags().StringVarP(&b.bucketID, "bucket-id", "i", "", "The bucket ID (required)")
+	cmd.MarkFlagRequired("bucket-id")
+	cmd.Flags().BoolVar(&b.optimize, "optimize", false, "Schedule optimize rather than full compactions")
+	cmd.Flags().BoolVar(&b.headers, "headers", true, "To print the table headers; defaults true")
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdBucketRunEFn(cmd *cobra.Command, args []string) error {
+	svc, bktSVC, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(b.bucketID); err != nil {
+		return fmt.Errorf("failed to decode bucket id %q: %v", b.bucketID, err)
+	}
+
+	bkt, err := bktSVC.FindBucketByID(context.Background(), id)
+	if err != nil {
+		return fmt.Errorf("failed to find bucket %q: %v", b.bucketID, err)
+	}
+
+	compactions, err := svc.CompactBucket(context.Background(), bkt.OrgID, bkt.ID, b.optimize)
+	if err != nil {
+		return fmt.Errorf("failed to compact bucket: %v", err)
+	}
+
+	b.printCompactions(compactions)
+	return nil
+}
+
+func (b *cmdCompactionBuilder) cmdPause() *cobra.Command {
+	cmd := b.newCmd("pause", b.cmdPausedRunEFn(true))
+	cmd.Short = "Pause compactions, aborting running compactions"
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdResume() *cobra.Command {
+	cmd := b.newCmd("resume", b.cmdPausedRunEFn(false))
+	cmd.Short = "Resume paused compactions"
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdPausedRunEFn(paused bool) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		svc, _, err := b.svcFn()
+		if err != nil {
+			return err
+		}
+
+		if err := svc.SetCompactionsPaused(context.Background(), paused); err != nil {
+			return fmt.Errorf("failed to set compactions paused: %v", err)
+		}
+		return nil
+	}
+}
+
+func (b *cmdCompactionBuilder) cmdCancel() *cobra.Command {
+	cmd := b.newCmd("cancel", b.cmdCancelRunEFn)
+	cmd.Short = "Cancel a running compaction"
+	cmd.Long = `Cancel a running compaction.
+
+The files of the compaction are left as they were and may be compacted again
+once planned. Pause compactions first to keep it from being restarted.`
+
+	cmd.Flags().IntVarP(&b.id, "id", "i", 0, "The compaction ID (required)")
+	cmd.MarkFlagRequired("id")
+
+	return cmd
+}
+
+func (b *cmdCompactionBuilder) cmdCancelRunEFn(cmd *cobra.Command, args []string) error {
+	svc, _, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.CancelCompaction(context.Background(), b.id); err != nil {
+		return fmt.Errorf("failed to cancel compaction: %v", err)
+	}
+	return nil
+}
+
+func (b *cmdCompactionBuilder) printCompactions(compactions []*influxdb.Compaction) {
+	w := internal.NewTabWriter(b.w)
+	w.HideHeaders(!b.headers)
+	w.WriteHeaders("ID", "Level", "State", "Files", "Size", "Started", "Progress")
+	for _, c := range compactions {
+		id, state, started := "", "planned", ""
+		if c.Running {
+			id, state = strconv.Itoa(c.ID), "running"
+		}
+		if c.StartedAt != nil {
+			started = c.StartedAt.Format(time.RFC3339)
+		}
+		w.Write(map[string]interface{}{
+			"ID":       id,
+			"Level":    compactionLevel(c.Level),
+			"State":    state,
+			"Files":    strings.Join(c.Files, ","),
+			"Size":     c.Size,
+			"Started":  started,
+			"Progress": fmt.Sprintf("%.0f%%", c.Progress*100),
+		})
+	}
+	w.Flush()
+}
+
+// compactionLevel returns the name of a compaction level.
+func compactionLevel(level int) string {
+	switch level {
+	case influxdb.CompactionLevelOptimize:
+		return "optimize"
+	case influxdb.CompactionLevelFull:
+		return "full"
+	}
+	return strconv.Itoa(level)
+}
+
+func newCompactionSVCs() (influxdb.CompactionService, influxdb.BucketService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &http.CompactionService{Client: httpClient}, &http.BucketService{Client: httpClient}, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCompactionService struct {
+	compactBucket func(orgID, bucketID influxdb.ID, optimize bool) []*influxdb.Compaction
+	paused        *bool
+	cancelled     int
+}
+
+func (s *fakeCompactionService) FindCompactions(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	return &influxdb.CompactionStatus{
+		Paused: true,
+		Compactions: []*influxdb.Compaction{
+			{ID: 3, Level: 2, Files: []string{"a.tsm", "b.tsm"}, Running: true, Progress: 0.5},
+			{Level: influxdb.CompactionLevelFull, Files: []string{"c.tsm", "d.tsm"}},
+		},
+	}, nil
+}
+
+func (s *fakeCompactionService) CompactBucket(ctx context.Context, orgID, bucketID influxdb.ID, optimize bool) ([]*influxdb.Compaction, error) {
+	return s.compactBucket(orgID, bucketID, optimize), nil
+}
+
+func (s *fakeCompactionService) SetCompactionsPaused(ctx context.Context, paused bool) error {
+	s.paused = &paused
+	return nil
+}
+
+func (s *fakeCompactionService) CancelCompaction(ctx context.Context, id int) error {
+	s.cancelled = id
+	return nil
+}
+
+func TestCmdCompaction(t *testing.T) {
+	setViperOptions()
+
+	orgID, bucketID := influxdb.ID(9000), influxdb.ID(9001)
+
+	newCmd := func(svc *fakeCompactionService, buf *bytes.Buffer, args ...string) error {
+		bktSVC := mock.NewBucketService()
+		bktSVC.FindBucketByIDFn = func(ctx context.Context, id influxdb.ID) (*influxdb.Bucket, error) {
+			return &influxdb.Bucket{ID: id, OrgID: orgID}, nil
+		}
+		svcFn := func() (influxdb.CompactionService, influxdb.BucketService, error) {
+			return svc, bktSVC, nil
+		}
+
+		cmd := newCmdCompactionBuilder(svcFn, out(buf)).cmd()
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	t.Run("list", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, newCmd(&fakeCompactionService{}, &buf, "list"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		assert.Equal(t, "Compactions are paused", lines[0])
+		assert.Equal(t, []string{"3", "2", "running", "a.tsm,b.tsm", "0", "50%"}, strings.Fields(lines[2]))
+		assert.Equal(t, []string{"full", "planned", "c.tsm,d.tsm", "0", "0%"}, strings.Fields(lines[3]))
+	})
+
+	t.Run("bucket", func(t *testing.T) {
+		var called bool
+		svc := &fakeCompactionService{
+			compactBucket: func(gotOrgID, gotBucketID influxdb.ID, optimize bool) []*influxdb.Compaction {
+				called = true
+				assert.Equal(t, orgID, gotOrgID)
+				assert.Equal(t, bucketID, gotBucketID)
+				assert.True(t, optimize)
+				return nil
+			},
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, newCmd(svc, &buf, "bucket", "--bucket-id="+bucketID.String(), "--optimize"))
+		assert.True(t, called)
+	})
+
+	t.Run("pause and resume", func(t *testing.T) {
+		svc := &fakeCompactionService{}
+		var buf bytes.Buffer
+		require.NoError(t, newCmd(svc, &buf, "pause"))
+		require.NotNil(t, svc.paused)
+		assert.True(t, *svc.paused)
+
+		require.NoError(t, newCmd(svc, &buf, "resume"))
+		assert.False(t, *svc.paused)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		svc := &fakeCompactionService{}
+		var buf bytes.Buffer
+		require.NoError(t, newCmd(svc, &buf, "cancel", "--id=7"))
+		assert.Equal(t, 7, svc.cancelled)
+	})
+}
@@ -25,9 +25,10 @@ func debugCmd() *cobra.Command {
 }
 
 var inspectReportTSMFlags struct {
-	pattern  string
-	exact    bool
-	detailed bool
+	pattern     string
+	exact       bool
+	detailed    bool
+	compression bool
 	organization
 	bucketID string
 	dataDir  string
@@ -63,6 +64,10 @@ in the following ways:
 	* Series cardinality for each measurement;
 	* Number of field keys for each measurement; and
 	* Number of tag values for each tag key.
+
+With the --compression flag, the blocks of the files are read to output the
+number of blocks and values, the size and the compression ratio for each block
+codec and type.
 `,
 		RunE: inspectReportTSMF,
 	}
@@ -70,6 +75,7 @@ in the following ways:
 	inspectReportTSMCommand.Flags().StringVarP(&inspectReportTSMFlags.pattern, "pattern", "", "", "only process TSM files containing pattern")
 	inspectReportTSMCommand.Flags().BoolVarP(&inspectReportTSMFlags.exact, "exact", "", false, "calculate and exact cardinality count. Warning, may use significant memory...")
 	inspectReportTSMCommand.Flags().BoolVarP(&inspectReportTSMFlags.detailed, "detailed", "", false, "emit series cardinality segmented by measurements, tag keys and fields. Warning, may take a while.")
+	inspectReportTSMCommand.Flags().BoolVarP(&inspectReportTSMFlags.compression, "compression", "", false, "read all blocks to emit the compression ratio by codec and block type. Warning, may take a while.")
 
 	inspectReportTSMFlags.organization.register(inspectReportTSMCommand, false)
 	inspectReportTSMCommand.Flags().StringVarP(&inspectReportTSMFlags.bucketID, "bucket-id", "", "", "process only data belonging to bucket ID. Requires org flag to be set.")
@@ -88,12 +94,13 @@ func inspectReportTSMF(cmd *cobra.Command, args []string) error {
 		return err
 	}
 	report := &tsm1.Report{
-		Stderr:   os.Stderr,
-		Stdout:   os.Stdout,
-		Dir:      inspectReportTSMFlags.dataDir,
-		Pattern:  inspectReportTSMFlags.pattern,
-		Detailed: inspectReportTSMFlags.detailed,
-		Exact:    inspectReportTSMFlags.exact,
+		Stderr:      os.Stderr,
+		Stdout:      os.Stdout,
+		Dir:         inspectReportTSMFlags.dataDir,
+		Pattern:     inspectReportTSMFlags.pattern,
+		Detailed:    inspectReportTSMFlags.detailed,
+		Exact:       inspectReportTSMFlags.exact,
+		Compression: inspectReportTSMFlags.compression,
 	}
 
 	if (inspectReportTSMFlags.organization.name == "" || inspectReportTSMFlags.organization.id == "") && inspectReportTSMFlags.bucketID != "" {
@@ -120,6 +120,7 @@ func influxCmd(opts ...genericCLIOptFn) *cobra.Command {
 		cmdAuth(),
 		cmdBackup(),
 		cmdBucket(runEWrapper),
+		cmdCompaction(runEWrapper),
 		cmdDelete(),
 		cmdOrganization(runEWrapper),
 		cmdPing(),
@@ -127,6 +128,8 @@ func influxCmd(opts ...genericCLIOptFn) *cobra.Command {
 		cmdQuery(),
 		cmdTranspile(),
 		cmdREPL(),
+		cmdReplication(runEWrapper),
+		cmdRestore(),
 		cmdSetup(),
 		cmdTask(),
 		cmdUser(runEWrapper),
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/cmd/influx/internal"
+	"github.com/influxdata/influxdb/http"
+	"github.com/spf13/cobra"
+)
+
+type replicationSVCFn func() (influxdb.ReplicationService, error)
+
+func cmdReplication(opts ...genericCLIOptFn) *cobra.Command {
+	return newCmdReplicationBuilder(newReplicationService, opts...).cmd()
+}
+
+type cmdReplicationBuilder struct {
+	genericCLIOpts
+
+	svcFn replicationSVCFn
+
+	headers bool
+}
+
+func newCmdReplicationBuilder(svcFn replicationSVCFn, opts ...genericCLIOptFn) *cmdReplicationBuilder {
+	opt := genericCLIOpts{
+		in: os.Stdin,
+		w:  os.Stdout,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+
+	return &cmdReplicationBuilder{
+		genericCLIOpts: opt,
+		svcFn:          svcFn,
+	}
+}
+
+func (b *cmdReplicationBuilder) cmd() *cobra.Command {
+	cmd := b.newCmd("replication", nil)
+	cmd.Short = "Hot-standby replication commands"
+	cmd.TraverseChildren = true
+	cmd.Run = seeHelp
+	cmd.AddCommand(
+		b.cmdStatus(),
+		b.cmdPromote(),
+	)
+
+	return cmd
+}
+
+func (b *cmdReplicationBuilder) cmdStatus() *cobra.Command {
+	cmd := b.newCmd("status", b.cmdStatusRunEFn)
+	cmd.Short = "Show the replication state of the instance"
+
+	cmd.Flags().BoolVar(&b.headers, "headers", true, "To print the table headers; defaults true")
+
+	return cmd
+}
+
+func (b *cmdReplicationBuilder) cmdStatusRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	status, err := svc.FindReplicationStatus(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to retrieve replication status: %v", err)
+	}
+
+	w := internal.NewTabWriter(b.w)
+	w.HideHeaders(!b.headers)
+	switch status.Role {
+	case influxdb.ReplicationRolePrimary:
+		w.WriteHeaders("Role", "Epoch", "Sequence", "Standbys")
+		w.Write(map[string]interface{}{
+			"Role":     status.Role,
+			"Epoch":    status.Position.Epoch,
+			"Sequence": status.Position.Sequence,
+			"Standbys": status.Standbys,
+		})
+	case influxdb.ReplicationRoleStandby:
+		w.WriteHeaders("Role", "Primary", "Connected", "Epoch", "Sequence", "Lag Entries", "Lag Seconds")
+		w.Write(map[string]interface{}{
+			"Role":        status.Role,
+			"Primary":     status.PrimaryURL,
+			"Connected":   status.Connected,
+			"Epoch":       status.Position.Epoch,
+			"Sequence":    status.Position.Sequence,
+			"Lag Entries": status.LagEntries,
+			"Lag Seconds": fmt.Sprintf("%.1f", status.LagSeconds),
+		})
+	default:
+		w.WriteHeaders("Role")
+		w.Write(map[string]interface{}{
+			"Role": status.Role,
+		})
+	}
+	w.Flush()
+	return nil
+}
+
+func (b *cmdReplicationBuilder) cmdPromote() *cobra.Command {
+	cmd := b.newCmd("promote", b.cmdPromoteRunEFn)
+	cmd.Short = "Promote a standby so that it accepts writes"
+	cmd.Long = `Promote a standby so that it accepts writes.
+
+The standby stops following its primary for good: stop writing to the primary
+before promoting its standby, and restart the promoted instance as a primary
+for other standbys to follow it.`
+
+	return cmd
+}
+
+func (b *cmdReplicationBuilder) cmdPromoteRunEFn(cmd *cobra.Command, args []string) error {
+	svc, err := b.svcFn()
+	if err != nil {
+		return err
+	}
+
+	if err := svc.PromoteStandby(context.Background()); err != nil {
+		return fmt.Errorf("failed to promote standby: %v", err)
+	}
+	fmt.Fprintln(b.w, "Standby promoted")
+	return nil
+}
+
+func newReplicationService() (influxdb.ReplicationService, error) {
+	httpClient, err := newHTTPClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.ReplicationService{Client: httpClient}, nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReplicationService struct {
+	status   *influxdb.ReplicationStatus
+	promoted bool
+}
+
+func (s *fakeReplicationService) FindReplicationStatus(ctx context.Context) (*influxdb.ReplicationStatus, error) {
+	return s.status, nil
+}
+
+func (s *fakeReplicationService) StreamReplication(ctx context.Context, pos influxdb.ReplicationPosition, w io.Writer) error {
+	return nil
+}
+
+func (s *fakeReplicationService) PromoteStandby(ctx context.Context) error {
+	s.promoted = true
+	return nil
+}
+
+func TestCmdReplication(t *testing.T) {
+	setViperOptions()
+
+	newCmd := func(svc *fakeReplicationService, buf *bytes.Buffer, args ...string) error {
+		svcFn := func() (influxdb.ReplicationService, error) {
+			return svc, nil
+		}
+
+		cmd := newCmdReplicationBuilder(svcFn, out(buf)).cmd()
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	t.Run("status", func(t *testing.T) {
+		var buf bytes.Buffer
+		svc := &fakeReplicationService{status: &influxdb.ReplicationStatus{
+			Role:       influxdb.ReplicationRoleStandby,
+			Position:   influxdb.ReplicationPosition{Epoch: 2, Sequence: 40},
+			PrimaryURL: "http://primary:9999",
+			Connected:  true,
+			LagEntries: 2,
+			LagSeconds: 0.5,
+		}}
+		require.NoError(t, newCmd(svc, &buf, "status"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.Equal(t, []string{"standby", "http://primary:9999", "true", "2", "40", "2", "0.5"}, strings.Fields(lines[1]))
+	})
+
+	t.Run("promote", func(t *testing.T) {
+		var buf bytes.Buffer
+		svc := &fakeReplicationService{}
+		require.NoError(t, newCmd(svc, &buf, "promote"))
+		assert.True(t, svc.promoted)
+	})
+}
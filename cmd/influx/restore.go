@@ -0,0 +1,492 @@
+package main
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/bolt"
+	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/spf13/cobra"
+	"go.uber.org/multierr"
+)
+
+func cmdRestore() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Restore a backup into InfluxDB",
+		Long: fmt.Sprintf(
+			`Restores a backup into the running InfluxDB instance. The backup is read
+from the directory indicated by --path.
+
+With --full, a backup created with influx backup is restored without stopping
+the server. The data files are added to the data of the instance, and its meta
+data is replaced with %s from the backup, so the token in use may no longer be
+valid afterwards. The credentials in the backup are written to the local
+credentials file.
+
+Otherwise, buckets are restored from a backup created with influx backup --org
+or --bucket, which must contain %s. Each bucket is restored into a new bucket,
+so existing buckets are left alone. By default all buckets in the backup are
+restored, with their original names, into their original organization. Use
+--bucket to restore a single bucket, --new-bucket to give it another name and
+--org to restore into another organization.`,
+			bolt.DefaultFilename, influxdb.BackupBucketsFilename),
+		RunE: restoreF,
+	}
+	opts := flagOpts{
+		{
+			DestP:    &restoreFlags.Path,
+			Flag:     "path",
+			Short:    'p',
+			Desc:     "directory path of the backup to restore",
+			Required: true,
+		},
+		{
+			DestP: &restoreFlags.Full,
+			Flag:  "full",
+			Desc:  "restore the meta data and all data of a full backup",
+		},
+		{
+			DestP: &restoreFlags.BucketID,
+			Flag:  "bucket-id",
+			Desc:  "The ID of the bucket in the backup to restore",
+		},
+		{
+			DestP: &restoreFlags.Bucket,
+			Flag:  "bucket",
+			Short: 'b',
+			Desc:  "The name of the bucket in the backup to restore",
+		},
+		{
+			DestP: &restoreFlags.NewBucket,
+			Flag:  "new-bucket",
+			Desc:  "The name of the bucket to restore into",
+		},
+		{
+			DestP: &restoreFlags.OrgID,
+			Flag:  "org-id",
+			Desc:  "The ID of the organization to restore into",
+		},
+		{
+			DestP: &restoreFlags.Org,
+			Flag:  "org",
+			Short: 'o',
+			Desc:  "The name of the organization to restore into",
+		},
+	}
+	opts.mustRegister(cmd)
+
+	return cmd
+}
+
+var restoreFlags struct {
+	Path      string
+	Full      bool
+	BucketID  string
+	Bucket    string
+	NewBucket string
+	OrgID     string
+	Org       string
+}
+
+func newRestoreService() (*http.RestoreService, error) {
+	return &http.RestoreService{
+		Addr:  flags.host,
+		Token: flags.token,
+	}, nil
+}
+
+func restoreF(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	if flags.local {
+		return fmt.Errorf("local flag not supported for restore command")
+	}
+
+	if restoreFlags.Path == "" {
+		return fmt.Errorf("must specify path")
+	}
+	if restoreFlags.Full {
+		if restoreFlags.Bucket != "" || restoreFlags.BucketID != "" || restoreFlags.NewBucket != "" ||
+			restoreFlags.Org != "" || restoreFlags.OrgID != "" {
+			return fmt.Errorf("full restore does not support bucket and org flags")
+		}
+		return restoreFullF(ctx)
+	}
+	if restoreFlags.Org != "" && restoreFlags.OrgID != "" {
+		return fmt.Errorf("please specify one of org or org-id")
+	}
+	if restoreFlags.Bucket != "" && restoreFlags.BucketID != "" {
+		return fmt.Errorf("please specify one of bucket or bucket-id")
+	}
+
+	buckets, err := readBackupBuckets(restoreFlags.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read buckets of backup: %v", err)
+	}
+	if buckets, err = selectRestoreBuckets(buckets); err != nil {
+		return err
+	}
+	if restoreFlags.NewBucket != "" && len(buckets) != 1 {
+		return fmt.Errorf("new-bucket requires a single bucket to be restored")
+	}
+
+	orgID, err := restoreOrgID(ctx)
+	if err != nil {
+		return err
+	}
+
+	files, err := backupDataFiles(restoreFlags.Path)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "influx-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	restoreService, err := newRestoreService()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range buckets {
+		src := m.Bucket
+		if orgID.Valid() {
+			m.Bucket.OrgID = orgID
+		}
+		if restoreFlags.NewBucket != "" {
+			m.Bucket.Name = restoreFlags.NewBucket
+		}
+
+		b, err := restoreService.RestoreBucketMetadata(ctx, m)
+		if err != nil {
+			return fmt.Errorf("failed to restore bucket %q: %v", src.Name, err)
+		}
+
+		for _, file := range files {
+			if err := restoreBucketFile(ctx, restoreService, b.ID, &src, file, tmpDir); err != nil {
+				return fmt.Errorf("failed to restore data of bucket %q from %s: %v", src.Name, file.Path, err)
+			}
+		}
+		fmt.Printf("Restored bucket %q as %q (ID %s)\n", src.Name, b.Name, b.ID)
+	}
+
+	fmt.Println("Restore complete")
+	return nil
+}
+
+// restoreBucketFile sends the data of the bucket in the TSM file to the server,
+// without the data deleted by its tombstones.
+func restoreBucketFile(ctx context.Context, s influxdb.RestoreService, bucketID influxdb.ID, src *influxdb.Bucket, file backupDataFile, dir string) error {
+	path, err := withoutTombstones(file, dir)
+	if err != nil || path == "" {
+		return err
+	}
+	if path != file.Path {
+		defer os.Remove(path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return s.RestoreBucket(ctx, bucketID, src, f)
+}
+
+// selectRestoreBuckets returns the buckets chosen by the command line flags.
+func selectRestoreBuckets(buckets []influxdb.BucketMetadataBackup) ([]influxdb.BucketMetadataBackup, error) {
+	if restoreFlags.Bucket == "" && restoreFlags.BucketID == "" {
+		return buckets, nil
+	}
+
+	for _, m := range buckets {
+		if m.Bucket.Name == restoreFlags.Bucket || m.Bucket.ID.String() == restoreFlags.BucketID {
+			return []influxdb.BucketMetadataBackup{m}, nil
+		}
+	}
+	if restoreFlags.Bucket != "" {
+		return nil, fmt.Errorf("bucket %q is not in the backup", restoreFlags.Bucket)
+	}
+	return nil, fmt.Errorf("bucket with id %q is not in the backup", restoreFlags.BucketID)
+}
+
+// restoreOrgID returns the ID of the organization to restore into, or an
+// invalid ID if the buckets are restored into their original organization.
+func restoreOrgID(ctx context.Context) (influxdb.ID, error) {
+	if restoreFlags.OrgID != "" {
+		id, err := influxdb.IDFromString(restoreFlags.OrgID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode org-id: %v", err)
+		}
+		return *id, nil
+	}
+	if restoreFlags.Org == "" {
+		return 0, nil
+	}
+
+	orgSvc, err := newOrganizationService()
+	if err != nil {
+		return 0, err
+	}
+	o, err := orgSvc.FindOrganization(ctx, influxdb.OrganizationFilter{Name: &restoreFlags.Org})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find organization %q: %v", restoreFlags.Org, err)
+	}
+	return o.ID, nil
+}
+
+func readBackupBuckets(dir string) ([]influxdb.BucketMetadataBackup, error) {
+	f, err := os.Open(filepath.Join(dir, influxdb.BackupBucketsFilename))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buckets []influxdb.BucketMetadataBackup
+	if err := json.NewDecoder(f).Decode(&buckets); err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// backupDataFile is a TSM file of a backup, along with the tombstone file
+// holding the deletes of its data, if any.
+type backupDataFile struct {
+	Path      string
+	Tombstone string
+}
+
+// backupDataFiles returns the TSM files of the backup in dir. If the backup has
+// a manifest, files carried over from earlier backups in an incremental chain
+// are included, and each file is paired with the tombstone file the manifest
+// lists for it, which may be in a later backup of the chain than the file.
+func backupDataFiles(dir string) ([]backupDataFile, error) {
+	m, err := readBackupManifest(dir)
+	if os.IsNotExist(err) {
+		paths, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+		if err != nil {
+			return nil, err
+		}
+		files := make([]backupDataFile, 0, len(paths))
+		for _, path := range paths {
+			f := backupDataFile{Path: path}
+			if tombstone := tombstoneFileName(path); fileExists(tombstone) {
+				f.Tombstone = tombstone
+			}
+			files = append(files, f)
+		}
+		return files, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read manifest of backup: %v", err)
+	}
+
+	locations := make(map[string]string, len(m.Files))
+	for _, f := range m.Files {
+		locations[f.FileName] = f.Path(dir)
+	}
+
+	var files []backupDataFile
+	for _, f := range m.Files {
+		if !strings.HasSuffix(f.FileName, "."+tsm1.TSMFileExtension) {
+			continue
+		}
+		files = append(files, backupDataFile{
+			Path:      locations[f.FileName],
+			Tombstone: locations[filepath.Base(tombstoneFileName(f.FileName))],
+		})
+	}
+	return files, nil
+}
+
+// tombstoneFileName returns the name of the tombstone file of the TSM file name.
+func tombstoneFileName(name string) string {
+	return strings.TrimSuffix(name, "."+tsm1.TSMFileExtension) + "." + tsm1.TombstoneFileExtension
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// restoreFullF streams all files of a full backup to the server. TSM files
+// with tombstones are rewritten without the deleted data first, and the meta
+// data is sent last so the data is restored with the token currently in use.
+func restoreFullF(ctx context.Context) error {
+	files, err := backupDataFiles(restoreFlags.Path)
+	if err != nil {
+		return err
+	}
+	boltPath := filepath.Join(restoreFlags.Path, bolt.DefaultFilename)
+	if _, err := os.Stat(boltPath); err != nil {
+		return fmt.Errorf("backup has no meta data: %v", err)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "influx-restore-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	restoreService, err := newRestoreService()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(pw)
+		for _, file := range files {
+			path, err := withoutTombstones(file, tmpDir)
+			if err == nil {
+				err = addTarFile(tw, path, filepath.Base(file.Path))
+			}
+			if err != nil {
+				pw.CloseWithError(fmt.Errorf("failed to add %s: %v", file.Path, err))
+				return
+			}
+			fmt.Printf("Sent %s\n", filepath.Base(file.Path))
+		}
+		if err := addTarFile(tw, boltPath, bolt.DefaultFilename); err != nil {
+			pw.CloseWithError(fmt.Errorf("failed to add %s: %v", boltPath, err))
+			return
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+
+	if err := restoreService.RestoreBackup(ctx, pr); err != nil {
+		pr.CloseWithError(err)
+		return fmt.Errorf("failed to restore backup: %v", err)
+	}
+
+	credPath := filepath.Join(restoreFlags.Path, http.DefaultTokenFile)
+	if token, err := ioutil.ReadFile(credPath); err == nil {
+		path, dir, err := defaultTokenPath()
+		if err != nil {
+			return err
+		}
+		if err := writeTokenToPath(string(token), path, dir); err != nil {
+			return fmt.Errorf("failed to restore credentials: %v", err)
+		}
+		fmt.Printf("Restored credentials to %s\n", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	fmt.Println("Restore complete")
+	return nil
+}
+
+// withoutTombstones returns the path of a copy of the TSM file, in dir, without
+// the data deleted by its tombstone file. If the file has no tombstones, its
+// own path is returned, and if all of its data is deleted, an empty path.
+func withoutTombstones(file backupDataFile, dir string) (string, error) {
+	if file.Tombstone == "" {
+		return file.Path, nil
+	}
+
+	// The reader applies the tombstone file alongside the TSM file, so both
+	// are linked into a directory of their own, as the tombstone file may be
+	// in another backup of the chain.
+	staging, err := ioutil.TempDir(dir, "staging-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(staging)
+
+	name := filepath.Base(file.Path)
+	path := filepath.Join(staging, name)
+	for src, dst := range map[string]string{
+		file.Path:      path,
+		file.Tombstone: filepath.Join(staging, filepath.Base(tombstoneFileName(name))),
+	} {
+		abs, err := filepath.Abs(src)
+		if err != nil {
+			return "", err
+		}
+		if err := os.Symlink(abs, dst); err != nil {
+			return "", err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	defer r.Close()
+
+	if !r.HasTombstones() {
+		return file.Path, nil
+	}
+
+	dst := filepath.Join(dir, filepath.Base(path))
+	fd, err := os.OpenFile(dst, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return "", err
+	}
+	// Write through a plain io.Writer so no stats file is created alongside.
+	w, err := tsm1.NewTSMWriter(struct{ io.Writer }{fd})
+	if err != nil {
+		fd.Close()
+		return "", err
+	}
+	n, err := tsm1.CopyPrefix(w, r, nil, nil)
+	if err == nil && n > 0 {
+		if err = w.WriteIndex(); err == nil {
+			err = w.Flush()
+		}
+	}
+	if err := multierr.Append(err, fd.Close()); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		// Everything in the file was deleted, so there is nothing to send.
+		return "", os.Remove(dst)
+	}
+	return dst, nil
+}
+
+// addTarFile writes the file at path to tw as an entry with the given name.
+// An empty path is skipped.
+func addTarFile(tw *tar.Writer, path, name string) error {
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{
+		Name: name,
+		Mode: 0600,
+		Size: fi.Size(),
+	}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestBackupDataFiles_IncrementalTombstones(t *testing.T) {
+	root, err := ioutil.TempDir("", "influx-restore-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	base, inc, tmp := filepath.Join(root, "base"), filepath.Join(root, "inc"), filepath.Join(root, "tmp")
+	for _, dir := range []string{base, inc, tmp} {
+		if err := os.Mkdir(dir, 0777); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The TSM file is in the base backup, while its tombstone file, written
+	// after the base backup was taken, is only in the incremental backup.
+	const name = "000000001-000000001.tsm"
+	f, err := os.Create(filepath.Join(base, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := tsm1.NewTSMWriter(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"cpu", "mem"} {
+		if err := w.Write([]byte(key), tsm1.Values{tsm1.NewValue(1, 1.0)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := tsm1.NewTombstoner(filepath.Join(inc, name), nil)
+	if err := ts.Add([][]byte{[]byte("cpu")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	m := influxdb.BackupManifest{
+		Parent: "../base",
+		Files: []influxdb.BackupManifestFile{
+			{FileName: name, Location: "../base"},
+			{FileName: "000000001-000000001.tombstone"},
+		},
+	}
+	if err := writeBackupManifest(inc, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := backupDataFiles(inc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d files, expected 1", len(files))
+	}
+
+	path, err := withoutTombstones(files[0], tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path == files[0].Path {
+		t.Fatal("tombstones of the incremental backup were not applied")
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := tsm1.NewTSMReader(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Contains([]byte("cpu")) || !r.Contains([]byte("mem")) {
+		t.Fatal("expected only the data of mem to be restored")
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	platform "github.com/influxdata/influxdb"
@@ -21,6 +24,7 @@ var writeFlags struct {
 	BucketID  string
 	Bucket    string
 	Precision string
+	Format    string
 }
 
 func cmdWrite() *cobra.Command {
@@ -28,7 +32,11 @@ func cmdWrite() *cobra.Command {
 		Use:   "write line protocol or @/path/to/points.txt",
 		Short: "Write points to InfluxDB",
 		Long: `Write a single line of line protocol to InfluxDB,
-or add an entire file specified with an @ prefix.`,
+or add an entire file specified with an @ prefix.
+
+Points may also be written as annotated CSV, such as the output of
+Flux queries, or as a JSON array of points, using the format flag.
+The format of a file defaults to the one of its .csv or .json extension.`,
 		Args: cobra.ExactArgs(1),
 		RunE: wrapCheckSetup(fluxWriteF),
 	}
@@ -69,6 +77,12 @@ or add an entire file specified with an @ prefix.`,
 			Desc:       "Precision of the timestamps of the lines",
 			Persistent: true,
 		},
+		{
+			DestP:      &writeFlags.Format,
+			Flag:       "format",
+			Desc:       "Format of the points: lp, csv or json",
+			Persistent: true,
+		},
 	}
 	opts.mustRegister(cmd)
 
@@ -133,6 +147,7 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 
 	bucketID, orgID := buckets[0].ID, buckets[0].OrgID
 
+	format := write.Format(writeFlags.Format)
 	var r io.Reader
 	if args[0] == "-" {
 		r = os.Stdin
@@ -143,10 +158,35 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 		}
 		defer f.Close()
 		r = f
+
+		if format == "" {
+			format = writeFormatFromPath(args[0][1:])
+		}
 	} else {
 		r = strings.NewReader(args[0])
 	}
 
+	if format == "" {
+		format = write.FormatLineProtocol
+	}
+	if err := format.Valid(); err != nil {
+		return err
+	}
+
+	// Points of other formats are converted to line protocol to be batched.
+	var conversion *write.Conversion
+	if format != write.FormatLineProtocol {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read points: %v", err)
+		}
+		conversion, err = write.Convert(format, data, writeFlags.Precision)
+		if err != nil {
+			return fmt.Errorf("failed to convert points: %v", err)
+		}
+		r = bytes.NewReader(conversion.LineProtocol)
+	}
+
 	s := write.Batcher{
 		Service: &http.WriteService{
 			Addr:               flags.host,
@@ -157,9 +197,67 @@ func fluxWriteF(cmd *cobra.Command, args []string) error {
 	}
 
 	ctx = signals.WithStandardSignals(ctx)
-	if err := s.Write(ctx, orgID, bucketID, r); err != nil && err != context.Canceled {
+	err = s.Write(ctx, orgID, bucketID, r)
+	if conversion != nil {
+		err = convertedWriteError(conversion, err)
+	}
+	if pwe, ok := err.(*platform.PartialWriteError); ok {
+		printPartialWrite(pwe)
+		return fmt.Errorf("failed to write data: %s", pwe.Message)
+	} else if err != nil && err != context.Canceled {
 		return fmt.Errorf("failed to write data: %v", err)
 	}
 
 	return nil
 }
+
+// convertedWriteError returns the error of a write of converted points, with
+// the lines of the rejections being those of the source of the points, and
+// the points that could not be converted rejected.
+func convertedWriteError(c *write.Conversion, err error) error {
+	pwe, ok := err.(*platform.PartialWriteError)
+	if err != nil && !ok {
+		return err
+	} else if !ok && len(c.Errors) == 0 {
+		return nil
+	}
+
+	var rejections []*platform.WriteRejection
+	if ok {
+		for _, r := range pwe.Rejections {
+			if r.Line > 0 && r.Line <= len(c.Sources) {
+				r.Line = c.Sources[r.Line-1]
+			}
+		}
+		rejections = pwe.Rejections
+	}
+	for _, e := range c.Errors {
+		rejections = append(rejections, &platform.WriteRejection{
+			Line:    e.Source,
+			Reason:  platform.WriteRejectedParseError,
+			Message: e.Err.Error(),
+		})
+	}
+	return platform.NewPartialWriteError(len(c.Sources)+len(c.Errors), rejections)
+}
+
+// printPartialWrite prints the summary of a write of which some lines were
+// rejected.
+func printPartialWrite(pwe *platform.PartialWriteError) {
+	fmt.Fprintf(os.Stderr, "%d lines accepted, %d lines rejected\n", pwe.Accepted, pwe.Rejected)
+	for _, r := range pwe.Rejections {
+		fmt.Fprintf(os.Stderr, "line %d: %s: %s\n", r.Line, r.Reason, r.Message)
+	}
+}
+
+// writeFormatFromPath returns the format of a file from its extension.
+func writeFormatFromPath(path string) write.Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return write.FormatCSV
+	case ".json":
+		return write.FormatJSON
+	default:
+		return write.FormatLineProtocol
+	}
+}
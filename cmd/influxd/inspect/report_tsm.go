@@ -13,9 +13,10 @@ import (
 
 // reportTSMFlags defines the `report-tsm` Command.
 var reportTSMFlags = struct {
-	pattern  string
-	exact    bool
-	detailed bool
+	pattern     string
+	exact       bool
+	detailed    bool
+	compression bool
 
 	orgID, bucketID string
 	dataDir         string
@@ -52,13 +53,18 @@ in the following ways:
 	* Series cardinality for each bucket;
 	* Series cardinality for each measurement;
 	* Number of field keys for each measurement; and
-	* Number of tag values for each tag key.`,
+	* Number of tag values for each tag key.
+
+With the --compression flag, the blocks of the files are read to output the
+number of blocks and values, the size and the compression ratio for each block
+codec and type.`,
 		RunE: inspectReportTSMF,
 	}
 
 	reportTSMCommand.Flags().StringVarP(&reportTSMFlags.pattern, "pattern", "", "", "only process TSM files containing pattern")
 	reportTSMCommand.Flags().BoolVarP(&reportTSMFlags.exact, "exact", "", false, "calculate and exact cardinality count. Warning, may use significant memory...")
 	reportTSMCommand.Flags().BoolVarP(&reportTSMFlags.detailed, "detailed", "", false, "emit series cardinality segmented by measurements, tag keys and fields. Warning, may take a while.")
+	reportTSMCommand.Flags().BoolVarP(&reportTSMFlags.compression, "compression", "", false, "read all blocks to emit the compression ratio by codec and block type. Warning, may take a while.")
 
 	reportTSMCommand.Flags().StringVarP(&reportTSMFlags.orgID, "org-id", "", "", "process only data belonging to organization ID.")
 	reportTSMCommand.Flags().StringVarP(&reportTSMFlags.bucketID, "bucket-id", "", "", "process only data belonging to bucket ID. Requires org flag to be set.")
@@ -76,12 +82,13 @@ in the following ways:
 // inspectReportTSMF runs the report-tsm tool.
 func inspectReportTSMF(cmd *cobra.Command, args []string) error {
 	report := &tsm1.Report{
-		Stderr:   os.Stderr,
-		Stdout:   os.Stdout,
-		Dir:      reportTSMFlags.dataDir,
-		Pattern:  reportTSMFlags.pattern,
-		Detailed: reportTSMFlags.detailed,
-		Exact:    reportTSMFlags.exact,
+		Stderr:      os.Stderr,
+		Stdout:      os.Stdout,
+		Dir:         reportTSMFlags.dataDir,
+		Pattern:     reportTSMFlags.pattern,
+		Detailed:    reportTSMFlags.detailed,
+		Exact:       reportTSMFlags.exact,
+		Compression: reportTSMFlags.compression,
 	}
 
 	if reportTSMFlags.orgID == "" && reportTSMFlags.bucketID != "" {
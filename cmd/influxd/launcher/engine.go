@@ -9,10 +9,12 @@ import (
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http"
+	"github.com/influxdata/influxdb/kit/check"
 	"github.com/influxdata/influxdb/kit/prom"
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/storage/readservice"
+	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxql"
@@ -29,10 +31,17 @@ type Engine interface {
 	readservice.Viewer
 	storage.PointsWriter
 	storage.BucketDeleter
+	storage.Restorer
 	prom.PrometheusCollector
 	influxdb.BackupService
+	influxdb.CompactionService
+	influxdb.CardinalityService
+	influxdb.SeriesGCService
+	storage.BucketCodecsSetter
+	check.NamedChecker
 
 	SeriesCardinality() int64
+	ApplyWALEntry(ctx context.Context, entry wal.WALEntry) error
 
 	WithLogger(log *zap.Logger)
 	Open(context.Context) error
@@ -115,6 +124,21 @@ func (t *TemporaryEngine) SeriesCardinality() int64 {
 	return t.engine.SeriesCardinality()
 }
 
+// ApplyWALEntry applies an entry replicated from the WAL of another engine.
+func (t *TemporaryEngine) ApplyWALEntry(ctx context.Context, entry wal.WALEntry) error {
+	return t.engine.ApplyWALEntry(ctx, entry)
+}
+
+// CheckName returns the name of the health check of the engine.
+func (t *TemporaryEngine) CheckName() string {
+	return t.engine.CheckName()
+}
+
+// Check reports whether the engine found corrupt files.
+func (t *TemporaryEngine) Check(ctx context.Context) check.Response {
+	return t.engine.Check(ctx)
+}
+
 // DeleteBucketRangePredicate will delete a bucket from the range and predicate.
 func (t *TemporaryEngine) DeleteBucketRangePredicate(ctx context.Context, orgID, bucketID influxdb.ID, min, max int64, pred influxdb.Predicate) error {
 	return t.engine.DeleteBucketRangePredicate(ctx, orgID, bucketID, min, max, pred)
@@ -142,6 +166,11 @@ func (t *TemporaryEngine) CreateCursorIterator(ctx context.Context) (tsdb.Cursor
 	return t.engine.CreateCursorIterator(ctx)
 }
 
+// CreateLastCursorIterator calls into the underlying engines CreateLastCursorIterator.
+func (t *TemporaryEngine) CreateLastCursorIterator(ctx context.Context) (tsdb.CursorIterator, error) {
+	return t.engine.CreateLastCursorIterator(ctx)
+}
+
 // CreateSeriesCursor calls into the underlying engines CreateSeriesCursor.
 func (t *TemporaryEngine) CreateSeriesCursor(ctx context.Context, req storage.SeriesCursorRequest, cond influxql.Expr) (storage.SeriesCursor, error) {
 	return t.engine.CreateSeriesCursor(ctx, req, cond)
@@ -168,14 +197,50 @@ func (t *TemporaryEngine) Flush(ctx context.Context) {
 	}
 }
 
-func (t *TemporaryEngine) CreateBackup(ctx context.Context) (int, []string, error) {
-	return t.engine.CreateBackup(ctx)
+func (t *TemporaryEngine) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
+	return t.engine.CreateBackup(ctx, opts)
 }
 
 func (t *TemporaryEngine) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
 	return t.engine.FetchBackupFile(ctx, backupID, backupFile, w)
 }
 
+func (t *TemporaryEngine) RestoreBucket(ctx context.Context, srcOrgID, srcBucketID, dstOrgID, dstBucketID influxdb.ID, r io.Reader) error {
+	return t.engine.RestoreBucket(ctx, srcOrgID, srcBucketID, dstOrgID, dstBucketID, r)
+}
+
+func (t *TemporaryEngine) RestoreTSMFile(ctx context.Context, r io.Reader) error {
+	return t.engine.RestoreTSMFile(ctx, r)
+}
+
 func (t *TemporaryEngine) InternalBackupPath(backupID int) string {
 	return t.engine.InternalBackupPath(backupID)
 }
+
+func (t *TemporaryEngine) FindCompactions(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	return t.engine.FindCompactions(ctx)
+}
+
+func (t *TemporaryEngine) CompactBucket(ctx context.Context, orgID, bucketID influxdb.ID, optimize bool) ([]*influxdb.Compaction, error) {
+	return t.engine.CompactBucket(ctx, orgID, bucketID, optimize)
+}
+
+func (t *TemporaryEngine) SetCompactionsPaused(ctx context.Context, paused bool) error {
+	return t.engine.SetCompactionsPaused(ctx, paused)
+}
+
+func (t *TemporaryEngine) CancelCompaction(ctx context.Context, id int) error {
+	return t.engine.CancelCompaction(ctx, id)
+}
+
+func (t *TemporaryEngine) SetBucketCodecs(ctx context.Context, orgID, bucketID influxdb.ID, codecs influxdb.BlockCodecs) error {
+	return t.engine.SetBucketCodecs(ctx, orgID, bucketID, codecs)
+}
+
+func (t *TemporaryEngine) FindCardinality(ctx context.Context, filter influxdb.CardinalityFilter) ([]*influxdb.BucketCardinality, error) {
+	return t.engine.FindCardinality(ctx, filter)
+}
+
+func (t *TemporaryEngine) CollectSeries(ctx context.Context) (*influxdb.SeriesGC, error) {
+	return t.engine.CollectSeries(ctx)
+}
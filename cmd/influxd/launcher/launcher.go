@@ -18,6 +18,7 @@ import (
 	"github.com/influxdata/flux"
 	platform "github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/authorizer"
+	"github.com/influxdata/influxdb/backup"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/chronograf/server"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
@@ -31,13 +32,16 @@ import (
 	"github.com/influxdata/influxdb/kit/signals"
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/listener"
 	influxlogger "github.com/influxdata/influxdb/logger"
 	"github.com/influxdata/influxdb/nats"
+	"github.com/influxdata/influxdb/pkg/s3"
 	"github.com/influxdata/influxdb/pkger"
 	infprom "github.com/influxdata/influxdb/prometheus"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/query/control"
 	"github.com/influxdata/influxdb/query/stdlib/influxdata/influxdb"
+	"github.com/influxdata/influxdb/replication"
 	"github.com/influxdata/influxdb/snowflake"
 	"github.com/influxdata/influxdb/source"
 	"github.com/influxdata/influxdb/storage"
@@ -49,8 +53,9 @@ import (
 	"github.com/influxdata/influxdb/task/backend/middleware"
 	"github.com/influxdata/influxdb/task/backend/scheduler"
 	"github.com/influxdata/influxdb/telemetry"
+	"github.com/influxdata/influxdb/toml"
 	_ "github.com/influxdata/influxdb/tsdb/tsi1" // needed for tsi1
-	_ "github.com/influxdata/influxdb/tsdb/tsm1" // needed for tsm1
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/influxdata/influxdb/vault"
 	pzap "github.com/influxdata/influxdb/zap"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -73,6 +78,20 @@ const (
 	JaegerTracing = "jaeger"
 )
 
+const (
+	// defaultAsyncWritesMaxSize is the default maximum size in bytes of the
+	// queue of asynchronous writes.
+	defaultAsyncWritesMaxSize = 10 << 30
+
+	// maxQueuedWriteSize is the maximum size in bytes of a queued write.
+	// Larger writes are rejected rather than queued.
+	maxQueuedWriteSize = 32 << 20
+
+	// writeReceiptsRetention is how long the receipts of queued writes are
+	// kept once written.
+	writeReceiptsRetention = 24 * time.Hour
+)
+
 // NewCommand creates the command to run influxdb.
 func NewCommand() *cobra.Command {
 	l := NewLauncher()
@@ -247,6 +266,158 @@ func buildLauncherCommand(l *Launcher, cmd *cobra.Command) {
 			Flag:  "vault-token",
 			Desc:  "vault authentication token",
 		},
+		{
+			DestP: (*time.Duration)(&l.StorageConfig.Engine.PartitionDuration),
+			Flag:  "storage-partition-duration",
+			Desc:  "duration of the time windows TSM files are partitioned by, per bucket. Disabled if 0.",
+		},
+		{
+			DestP:   (*time.Duration)(&l.StorageConfig.ScrubInterval),
+			Flag:    "storage-scrub-interval",
+			Default: storage.DefaultScrubInterval,
+			Desc:    "interval at which TSM and series files are read again to verify their integrity. Disabled if 0.",
+		},
+		{
+			DestP:   &l.scrubRate,
+			Flag:    "storage-scrub-rate",
+			Default: storage.DefaultScrubRate,
+			Desc:    "maximum number of bytes per second read by the storage scrubber. Unlimited if 0.",
+		},
+		{
+			DestP: &l.StorageConfig.ScrubQuarantine,
+			Flag:  "storage-scrub-quarantine",
+			Desc:  "remove corrupt TSM files found by the storage scrubber from use, keeping them with a .bad extension.",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.BlockStats,
+			Flag:  "storage-block-stats",
+			Desc:  "store the count, sum, minimum and maximum of numeric blocks in TSM files, so that aggregates can skip decoding them.",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.Cache.SpillToDisk,
+			Flag:  "storage-cache-spill-to-disk",
+			Desc:  "spill the least recently written values of the cache to temporary TSM files when it reaches its maximum size, rather than rejecting writes.",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.ColdStorage.URL,
+			Flag:  "storage-cold-storage",
+			Desc:  "directory or s3://bucket/prefix URL TSM files are moved to once older than the cold storage period of their bucket. Disabled if empty.",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.ColdStorage.S3Endpoint,
+			Flag:  "storage-cold-storage-s3-endpoint",
+			Desc:  "endpoint of the S3-compatible storage of the cold storage. Defaults to the AWS endpoint of the region",
+		},
+		{
+			DestP:   &l.StorageConfig.Engine.ColdStorage.S3Region,
+			Flag:    "storage-cold-storage-s3-region",
+			Default: s3.DefaultRegion,
+			Desc:    "region of the S3-compatible storage of the cold storage",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.ColdStorage.S3AccessKeyID,
+			Flag:  "storage-cold-storage-s3-access-key-id",
+			Desc:  "access key ID for the S3-compatible storage of the cold storage",
+		},
+		{
+			DestP: &l.StorageConfig.Engine.ColdStorage.S3SecretAccessKey,
+			Flag:  "storage-cold-storage-s3-secret-access-key",
+			Desc:  "secret access key for the S3-compatible storage of the cold storage",
+		},
+		{
+			DestP:   &l.coldStorageBlockCacheSize,
+			Flag:    "storage-cold-storage-block-cache-size",
+			Default: int(tsm1.DefaultColdStorageBlockCacheSize),
+			Desc:    "maximum size in bytes of the blocks read from cold storage that are cached in memory",
+		},
+		{
+			DestP: &l.StorageConfig.WAL.ArchivePath,
+			Flag:  "wal-archive-path",
+			Desc:  "path to archive WAL segments to for point-in-time recovery. Disabled if empty.",
+		},
+		{
+			DestP: &l.backupSinkConfig.URL,
+			Flag:  "backup-sink",
+			Desc:  "directory or s3://bucket/prefix URL to upload server-side backups to",
+		},
+		{
+			DestP: &l.backupSinkConfig.S3Endpoint,
+			Flag:  "backup-sink-s3-endpoint",
+			Desc:  "endpoint of the S3-compatible storage of the backup sink. Defaults to the AWS endpoint of the region",
+		},
+		{
+			DestP:   &l.backupSinkConfig.S3Region,
+			Flag:    "backup-sink-s3-region",
+			Default: backup.DefaultS3Region,
+			Desc:    "region of the S3-compatible storage of the backup sink",
+		},
+		{
+			DestP: &l.backupSinkConfig.S3AccessKeyID,
+			Flag:  "backup-sink-s3-access-key-id",
+			Desc:  "access key ID for the S3-compatible storage of the backup sink",
+		},
+		{
+			DestP: &l.backupSinkConfig.S3SecretAccessKey,
+			Flag:  "backup-sink-s3-secret-access-key",
+			Desc:  "secret access key for the S3-compatible storage of the backup sink",
+		},
+		{
+			DestP:   &l.backupRetention,
+			Flag:    "backup-retention",
+			Default: 0,
+			Desc:    "number of server-side backups to keep in each directory of the backup sink. Zero keeps all backups",
+		},
+		{
+			DestP: &l.replicationRole,
+			Flag:  "replication-role",
+			Desc:  "replication role of the instance: primary streams its writes, deletes and metadata changes to standbys, standby follows the primary at replication-primary-url. Disabled if empty.",
+		},
+		{
+			DestP: &l.replicationPrimaryURL,
+			Flag:  "replication-primary-url",
+			Desc:  "URL of the primary a standby follows",
+		},
+		{
+			DestP: &l.replicationPrimaryToken,
+			Flag:  "replication-primary-token",
+			Desc:  "operator token a standby streams from its primary with",
+		},
+		{
+			DestP:   &l.replicationLogSize,
+			Flag:    "replication-log-size",
+			Default: replication.DefaultLogSize,
+			Desc:    "maximum size in bytes of the recent writes, deletes and metadata changes a primary holds in memory for standbys to catch up with",
+		},
+		{
+			DestP:   &l.asyncWrites,
+			Flag:    "async-writes",
+			Default: false,
+			Desc:    "queue writes to disk and acknowledge them with receipts, writing them to storage asynchronously",
+		},
+		{
+			DestP:   &l.asyncWritesPath,
+			Flag:    "async-writes-path",
+			Default: filepath.Join(dir, "write-queue"),
+			Desc:    "path to the queue of asynchronous writes, which also stores the messages of the scrapers",
+		},
+		{
+			DestP:   &l.asyncWritesMaxSize,
+			Flag:    "async-writes-max-size",
+			Default: defaultAsyncWritesMaxSize,
+			Desc:    "maximum size in bytes of the queue of asynchronous writes. The oldest writes are removed from the queue once it is full, whether written or not",
+		},
+		{
+			DestP:   &l.asyncWritesWorkers,
+			Flag:    "async-writes-workers",
+			Default: 1,
+			Desc:    "number of workers writing queued writes to storage. The order of the writes is kept with a single worker only",
+		},
+		{
+			DestP:   &l.listenersDisabled,
+			Flag:    "listeners-disabled",
+			Default: false,
+			Desc:    "disable the MQTT and Kafka ingestion listeners, which can still be managed",
+		},
 		{
 			DestP:   &l.httpTLSCert,
 			Flag:    "tls-cert",
@@ -287,6 +458,27 @@ type Launcher struct {
 	enginePath      string
 	secretStore     string
 
+	backupSinkConfig backup.SinkConfig
+	backupRetention  int
+	backupUploader   *backup.Uploader
+
+	coldStorageBlockCacheSize int
+	scrubRate                 int
+
+	replicationRole         string
+	replicationPrimaryURL   string
+	replicationPrimaryToken string
+	replicationLogSize      int
+	replicationLog          *replication.Log
+	replicationService      *replication.Service
+
+	asyncWrites        bool
+	asyncWritesPath    string
+	asyncWritesMaxSize int
+	asyncWritesWorkers int
+
+	listenersDisabled bool
+
 	boltClient    *bolt.Client
 	kvService     *kv.Service
 	engine        Engine
@@ -370,6 +562,11 @@ func (m *Launcher) Shutdown(ctx context.Context) {
 
 	m.scheduler.Stop()
 
+	m.log.Info("Stopping", zap.String("service", "replication"))
+	if err := m.replicationService.Close(); err != nil {
+		m.log.Error("Failed to stop replication", zap.Error(err))
+	}
+
 	m.log.Info("Stopping", zap.String("service", "nats"))
 	m.natsServer.Close()
 
@@ -388,6 +585,13 @@ func (m *Launcher) Shutdown(ctx context.Context) {
 		m.log.Error("Failed to close engine", zap.Error(err))
 	}
 
+	// The replication log is closed once nothing can be appended to it anymore.
+	if m.replicationLog != nil {
+		if err := m.replicationLog.Close(); err != nil {
+			m.log.Error("Failed to close replication log", zap.Error(err))
+		}
+	}
+
 	m.wg.Wait()
 
 	if m.jaegerTracerCloser != nil {
@@ -481,18 +685,19 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		SessionLength: time.Duration(m.sessionLength) * time.Minute,
 	}
 
+	var kvStore kv.Store
 	flushers := flushers{}
 	switch m.storeType {
 	case BoltStore:
 		store := bolt.NewKVStore(m.log.With(zap.String("service", "kvstore-bolt")), m.boltPath)
 		store.WithDB(m.boltClient.DB())
-		m.kvService = kv.NewService(m.log.With(zap.String("store", "kv")), store, serviceConfig)
+		kvStore = store
 		if m.testing {
 			flushers = append(flushers, store)
 		}
 	case MemoryStore:
 		store := inmem.NewKVStore()
-		m.kvService = kv.NewService(m.log.With(zap.String("store", "kv")), store, serviceConfig)
+		kvStore = store
 		if m.testing {
 			flushers = append(flushers, store)
 		}
@@ -502,6 +707,30 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		return err
 	}
 
+	switch m.replicationRole {
+	case "":
+	case platform.ReplicationRolePrimary:
+		// The changes to the metadata store and storage engine are appended to
+		// the replication log from now on.
+		m.replicationLog = replication.NewLog(filepath.Join(filepath.Dir(m.boltPath), "replication.json"), m.replicationLogSize)
+		if err := m.replicationLog.Open(); err != nil {
+			m.log.Error("Failed to open replication log", zap.Error(err))
+			return err
+		}
+		kvStore = replication.NewStore(kvStore, m.replicationLog)
+	case platform.ReplicationRoleStandby:
+		if m.replicationPrimaryURL == "" {
+			err := fmt.Errorf("replication-primary-url is required for standbys")
+			m.log.Error("Failed to configure replication", zap.Error(err))
+			return err
+		}
+	default:
+		err := fmt.Errorf("unknown replication role %s; expected primary or standby", m.replicationRole)
+		m.log.Error("Failed to configure replication", zap.Error(err))
+		return err
+	}
+	m.kvService = kv.NewService(m.log.With(zap.String("store", "kv")), kvStore, serviceConfig)
+
 	if err := m.kvService.Initialize(ctx); err != nil {
 		m.log.Error("Failed to initialize kv service", zap.Error(err))
 		return err
@@ -562,13 +791,19 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		return err
 	}
 
+	m.StorageConfig.Engine.ColdStorage.BlockCacheSize = toml.Size(m.coldStorageBlockCacheSize)
+	m.StorageConfig.ScrubRate = toml.Size(m.scrubRate)
+	engineOptions := []storage.Option{storage.WithRetentionEnforcer(bucketSvc), storage.WithRollups(bucketSvc), storage.WithBucketCodecs(bucketSvc), storage.WithSeriesLimits(bucketSvc, orgSvc), storage.WithLastValueCache(bucketSvc)}
+	if m.replicationLog != nil {
+		engineOptions = append(engineOptions, storage.WithWALReplicator(m.replicationLog))
+	}
 	if m.testing {
 		// the testing engine will write/read into a temporary directory
-		engine := NewTemporaryEngine(m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+		engine := NewTemporaryEngine(m.StorageConfig, engineOptions...)
 		flushers = append(flushers, engine)
 		m.engine = engine
 	} else {
-		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, storage.WithRetentionEnforcer(bucketSvc))
+		m.engine = storage.NewEngine(m.enginePath, m.StorageConfig, engineOptions...)
 	}
 	m.engine.WithLogger(m.log)
 	if err := m.engine.Open(ctx); err != nil {
@@ -578,12 +813,61 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	// The Engine's metrics must be registered after it opens.
 	m.reg.MustRegister(m.engine.PrometheusCollectors()...)
 
+	switch m.replicationRole {
+	case platform.ReplicationRolePrimary:
+		m.replicationService = replication.NewPrimaryService(m.replicationLog)
+		m.reg.MustRegister(m.replicationLog.PrometheusCollectors()...)
+	case platform.ReplicationRoleStandby:
+		client, err := http.NewHTTPClient(m.replicationPrimaryURL, m.replicationPrimaryToken, false)
+		if err != nil {
+			m.log.Error("Failed to create replication client", zap.Error(err))
+			return err
+		}
+		follower := replication.NewFollower(&http.ReplicationService{Client: client}, m.engine, kvStore, filepath.Join(filepath.Dir(m.boltPath), "replication-position.json"))
+		follower.WithLogger(m.log)
+		if err := follower.Open(ctx); err != nil {
+			m.log.Error("Failed to start following primary", zap.Error(err))
+			return err
+		}
+		m.reg.MustRegister(follower.PrometheusCollectors()...)
+		m.replicationService = replication.NewStandbyService(follower, m.replicationPrimaryURL)
+	default:
+		m.replicationService = replication.NewService()
+	}
+
 	var (
-		deleteService platform.DeleteService = m.engine
-		pointsWriter  storage.PointsWriter   = m.engine
+		// Standbys only accept writes and deletes from their primary until promoted.
+		deleteService platform.DeleteService = m.replicationService.DeleteService(m.engine)
+		pointsWriter  storage.PointsWriter   = m.replicationService.PointsWriter(storage.NewSchemaPointsWriter(m.engine, m.kvService))
 		backupService platform.BackupService = m.engine
 	)
 
+	if m.backupSinkConfig.URL != "" {
+		sink, err := backup.NewSink(m.backupSinkConfig)
+		if err != nil {
+			m.log.Error("Failed to create backup sink", zap.Error(err))
+			return err
+		}
+
+		dir, err := fs.InfluxDir()
+		if err != nil {
+			m.log.Error("Failed to determine influx directory", zap.Error(err))
+			return err
+		}
+
+		m.backupUploader = &backup.Uploader{
+			BackupService:              backupService,
+			KVBackupService:            m.kvService,
+			BucketService:              bucketSvc,
+			LabelService:               labelSvc,
+			UserResourceMappingService: userResourceSvc,
+			Sink:                       sink,
+			Retention:                  m.backupRetention,
+			CredentialsPath:            filepath.Join(dir, http.DefaultTokenFile),
+			Logger:                     m.log.With(zap.String("service", "backup-uploader")),
+		}
+	}
+
 	// TODO(cwolff): Figure out a good default per-query memory limit:
 	//   https://github.com/influxdata/influxdb/issues/13642
 	const (
@@ -722,8 +1006,18 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 			return errors.New("unable to find free port for Nats server")
 		}
 	}
+	if m.asyncWrites {
+		// Leave room for the header of queued writes and the envelope of
+		// the streaming server.
+		natsOpts.MaxPayload = maxQueuedWriteSize + 1<<20
+	}
 	m.natsServer = nats.NewServer(&natsOpts)
 	m.natsPort = natsOpts.Port
+	if m.asyncWrites {
+		// Queued writes are stored on disk so that they survive restarts.
+		m.natsServer.StoreDir = m.asyncWritesPath
+		m.natsServer.LimitChannel(http.WriteQueueSubject, int64(m.asyncWritesMaxSize))
+	}
 
 	if err := m.natsServer.Open(); err != nil {
 		m.log.Error("Failed to start nats streaming server", zap.Error(err))
@@ -760,22 +1054,70 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		log.Info("Stopping")
 	}(m.log)
 
+	// Backup schedules can always be managed, but are only run if there is a
+	// backup sink to upload the backups to.
+	if m.backupUploader != nil {
+		backupScheduler := &backup.Scheduler{
+			ScheduleService: m.kvService,
+			RunService:      m.kvService,
+			Upload:          m.backupUploader.Upload,
+			Logger:          m.log.With(zap.String("service", "backup-scheduler")),
+		}
+
+		m.wg.Add(1)
+		go func(log *zap.Logger) {
+			defer m.wg.Done()
+			log = log.With(zap.String("service", "backup-scheduler"))
+			backupScheduler.Run(ctx)
+			log.Info("Stopping")
+		}(m.log)
+	}
+
+	// Listeners can always be managed, but are not run if disabled.
+	if !m.listenersDisabled {
+		listenerManager := &listener.Manager{
+			ListenerService:      m.kvService,
+			OffsetService:        m.kvService,
+			AuthorizationService: m.kvService,
+			UserService:          m.kvService,
+			PointsWriter:         pointsWriter,
+			Logger:               m.log.With(zap.String("service", "listeners")),
+		}
+
+		m.wg.Add(1)
+		go func(log *zap.Logger) {
+			defer m.wg.Done()
+			log = log.With(zap.String("service", "listeners"))
+			listenerManager.Run(ctx)
+			log.Info("Stopping")
+		}(m.log)
+	}
+
 	m.httpServer = &nethttp.Server{
 		Addr: m.httpBindAddress,
 	}
 
 	m.apibackend = &http.APIBackend{
-		AssetsPath:           m.assetsPath,
-		HTTPErrorHandler:     http.ErrorHandler(0),
-		Logger:               m.log,
-		SessionRenewDisabled: m.sessionRenewDisabled,
-		NewBucketService:     source.NewBucketService,
-		NewQueryService:      source.NewQueryService,
-		PointsWriter:         pointsWriter,
-		DeleteService:        deleteService,
-		BackupService:        backupService,
-		KVBackupService:      m.kvService,
-		AuthorizationService: authSvc,
+		AssetsPath:            m.assetsPath,
+		HTTPErrorHandler:      http.ErrorHandler(0),
+		Logger:                m.log,
+		SessionRenewDisabled:  m.sessionRenewDisabled,
+		NewBucketService:      source.NewBucketService,
+		NewQueryService:       source.NewQueryService,
+		PointsWriter:          pointsWriter,
+		DeleteService:         deleteService,
+		BackupService:         backupService,
+		KVBackupService:       m.kvService,
+		RestoreService:        storage.NewRestoreService(m.engine, m.kvService, bucketSvc, labelSvc, userResourceSvc),
+		BackupScheduleService: m.kvService,
+		CompactionService:     m.engine,
+		CardinalityService:    m.engine,
+		SeriesGCService:       m.engine,
+		ReplicationService:    m.replicationService,
+		ListenerService:       m.kvService,
+		BucketSchemaService:   m.kvService,
+		DBRPMappingService:    m.kvService,
+		AuthorizationService:  authSvc,
 		// Wrap the BucketService in a storage backed one that will ensure deleted buckets are removed from the storage engine.
 		BucketService:                   storage.NewBucketService(bucketSvc, m.engine),
 		SessionService:                  sessionSvc,
@@ -792,7 +1134,7 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		VariableService:                 variableSvc,
 		PasswordsService:                passwdsSvc,
 		OnboardingService:               onboardingSvc,
-		InfluxQLService:                 nil, // No InfluxQL support
+		InfluxQLService:                 storageQueryService,
 		FluxService:                     storageQueryService,
 		TaskService:                     taskSvc,
 		TelegrafService:                 telegrafSvc,
@@ -809,6 +1151,12 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 		QueryEventRecorder:              infprom.NewEventRecorder("query"),
 	}
 
+	if m.asyncWrites {
+		if err := m.runWriteQueue(ctx); err != nil {
+			return err
+		}
+	}
+
 	m.reg.MustRegister(m.apibackend.PrometheusCollectors()...)
 
 	var pkgSVC pkger.SVC
@@ -849,6 +1197,7 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 			m.reg,
 			http.WithLog(httpLogger),
 			http.WithAPIHandler(platformHandler),
+			http.WithHealthHandler(http.NewHealthHandler(m.engine)),
 		)
 
 		if logconf.Level == zap.DebugLevel {
@@ -908,6 +1257,57 @@ func (m *Launcher) run(ctx context.Context) (err error) {
 	return nil
 }
 
+// runWriteQueue queues the writes of the API to be written to storage
+// asynchronously by workers, and prunes the receipts of the writes.
+func (m *Launcher) runWriteQueue(ctx context.Context) error {
+	publisher := nats.NewSyncPublisher(fmt.Sprintf("write-queue-publisher-%d", m.natsPort), m.NatsURL())
+	if err := publisher.Open(); err != nil {
+		m.log.Error("Failed to connect to streaming server", zap.Error(err))
+		return err
+	}
+	m.apibackend.WriteQueue = publisher
+	m.apibackend.WriteQueueMaxBytes = maxQueuedWriteSize
+	m.apibackend.WriteReceiptService = m.kvService
+
+	log := m.log.With(zap.String("service", "write-queue"))
+	for i := 0; i < m.asyncWritesWorkers; i++ {
+		subscriber := nats.NewQueueSubscriber(fmt.Sprintf("write-queue-worker-%d-%d", m.natsPort, i), m.NatsURL())
+		if m.asyncWritesWorkers == 1 {
+			// A single write at a time keeps the writes in order, even
+			// when one is delivered again after failing.
+			subscriber.MaxInflight = 1
+		}
+		if err := subscriber.Open(); err != nil {
+			m.log.Error("Failed to connect to streaming server", zap.Error(err))
+			return err
+		}
+		handler := http.NewWriteQueueHandler(log, http.NewWriteBackend(log, m.apibackend))
+		if err := subscriber.Subscribe(http.WriteQueueSubject, http.WriteQueueGroup, handler); err != nil {
+			m.log.Error("Failed to subscribe to write queue", zap.Error(err))
+			return err
+		}
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				log.Info("Stopping")
+				return
+			case <-ticker.C:
+				if err := m.kvService.DeleteWriteReceipts(ctx, time.Now().Add(-writeReceiptsRetention)); err != nil {
+					log.Error("Failed to delete write receipts", zap.Error(err))
+				}
+			}
+		}
+	}()
+	return nil
+}
+
 // isAddressPortAvailable checks whether the address:port is available to listen,
 // by using net.Listen to verify that the port opens successfully, then closes the listener.
 func isAddressPortAvailable(address string, port int) (bool, error) {
@@ -1,18 +1,25 @@
 package restore
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/cmd/influxd/inspect"
 	"github.com/influxdata/influxdb/http"
 	"github.com/influxdata/influxdb/internal/fs"
 	"github.com/influxdata/influxdb/kit/cli"
 	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/spf13/cobra"
 )
 
@@ -30,21 +37,35 @@ Rebuilding the index and series file uses default options as in
 For additional performance options, run restore with "-rebuild-index false"
 and build-tsi afterwards.
 
+If the backup has a manifest, the sizes and checksums of the restored data
+files are verified against it. The manifest of an incremental backup also lists
+files held by earlier backups in its chain; those are restored from there, so
+--backup-path should point at the latest backup of the chain.
+
+If --wal-archive-path is given, the writes and deletes archived there since the
+backup was created are restored on top of it, up to the time given by --until,
+or all of them if --until is not given. The server replays them from the WAL
+when it starts. The WAL must have been archived with "influxd --wal-archive-path"
+while the backup was taken.
+
 NOTES:
 
 * The influxd server should not be running when using the restore tool
-  as it replaces all data and metadata.
+  as it replaces all data and metadata. To restore a backup into a running
+  server, use "influx restore --full" instead.
 `,
 	Args: cobra.ExactArgs(0),
 	RunE: restoreE,
 }
 
 var flags struct {
-	boltPath   string
-	enginePath string
-	credPath   string
-	backupPath string
-	rebuildTSI bool
+	boltPath    string
+	enginePath  string
+	credPath    string
+	backupPath  string
+	rebuildTSI  bool
+	archivePath string
+	until       string
 }
 
 func init() {
@@ -89,6 +110,18 @@ func init() {
 			Default: true,
 			Desc:    "if true, rebuild the TSI index and series file based on the given engine path (equivalent to influxd inspect build-tsi)",
 		},
+		{
+			DestP:   &flags.archivePath,
+			Flag:    "wal-archive-path",
+			Default: "",
+			Desc:    "path to archived WAL segments to restore on top of the backup",
+		},
+		{
+			DestP:   &flags.until,
+			Flag:    "until",
+			Default: "",
+			Desc:    "restore archived WAL entries written up to this time (RFC3339); requires --wal-archive-path",
+		},
 	}
 
 	cli.BindOptions(Command, opts)
@@ -99,6 +132,18 @@ func restoreE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no backup path given")
 	}
 
+	until := time.Now().UTC()
+	if flags.until != "" {
+		if flags.archivePath == "" {
+			return fmt.Errorf("--until requires --wal-archive-path")
+		}
+		t, err := time.Parse(time.RFC3339, flags.until)
+		if err != nil {
+			return fmt.Errorf("invalid --until time: %v", err)
+		}
+		until = t
+	}
+
 	if err := moveBolt(); err != nil {
 		return fmt.Errorf("failed to move existing bolt file: %v", err)
 	}
@@ -123,6 +168,12 @@ func restoreE(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to restore all TSM files: %v", err)
 	}
 
+	if flags.archivePath != "" {
+		if err := restoreWALArchive(until); err != nil {
+			return fmt.Errorf("failed to restore archived WAL: %v", err)
+		}
+	}
+
 	if flags.rebuildTSI {
 		sFilePath := filepath.Join(flags.enginePath, storage.DefaultSeriesFileDirectoryName)
 		indexPath := filepath.Join(flags.enginePath, storage.DefaultIndexDirectoryName)
@@ -232,8 +283,15 @@ func restoreEngine() error {
 		return err
 	}
 
+	manifest, err := readManifest(flags.backupPath)
+	if err != nil {
+		return err
+	} else if manifest != nil {
+		return restoreEngineFromManifest(manifest, dataDir)
+	}
+
 	count := 0
-	err := filepath.Walk(flags.backupPath, func(path string, info os.FileInfo, err error) error {
+	err = filepath.Walk(flags.backupPath, func(path string, info os.FileInfo, err error) error {
 		if strings.Contains(path, ".tsm") {
 			f, err := os.OpenFile(path, os.O_RDONLY, 0666)
 			if err != nil {
@@ -261,6 +319,115 @@ func restoreEngine() error {
 	return err
 }
 
+// restoreWALArchive writes the archived WAL entries written between the creation
+// of the backup and until to a new segment of the restored WAL, from which they
+// are replayed when the engine is opened.
+func restoreWALArchive(until time.Time) error {
+	manifest, err := readManifest(flags.backupPath)
+	if err != nil {
+		return err
+	} else if manifest == nil {
+		return fmt.Errorf("backup has no manifest to tell when it was created")
+	}
+
+	files, err := wal.ArchivedSegmentFileNames(flags.archivePath)
+	if err != nil {
+		return err
+	}
+
+	walPath := filepath.Join(flags.enginePath, storage.DefaultWALDirectoryName)
+	if err := os.MkdirAll(walPath, 0777); err != nil {
+		return err
+	}
+	segment := filepath.Join(walPath, fmt.Sprintf("%s%05d.%s", wal.WALFilePrefix, 1, wal.WALFileExtension))
+	f, err := os.OpenFile(segment, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := wal.CopyArchivedEntries(wal.NewWALSegmentWriter(f), files, manifest.CreatedAt, until)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored %d WAL entries written from %s until %s to %s\n",
+		n, manifest.CreatedAt.Format(time.RFC3339), until.Format(time.RFC3339), segment)
+	return f.Close()
+}
+
+// readManifest returns the manifest of the backup in dir, or nil if the backup
+// predates manifests.
+func readManifest(dir string) (*influxdb.BackupManifest, error) {
+	f, err := os.Open(filepath.Join(dir, influxdb.BackupManifestFilename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var m influxdb.BackupManifest
+	if err := json.NewDecoder(f).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to decode backup manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// restoreEngineFromManifest copies the TSM and tombstone files listed in the
+// manifest into dataDir. Files carried over from earlier backups of an
+// incremental chain are read from the backup holding them.
+func restoreEngineFromManifest(m *influxdb.BackupManifest, dataDir string) error {
+	count := 0
+	for _, f := range m.Files {
+		if ext := filepath.Ext(f.FileName); ext != "."+tsm1.TSMFileExtension && ext != "."+tsm1.TombstoneFileExtension {
+			continue
+		}
+
+		if err := copyVerified(f.Path(flags.backupPath), filepath.Join(dataDir, f.FileName), f); err != nil {
+			return err
+		}
+		if ext := filepath.Ext(f.FileName); ext == "."+tsm1.TSMFileExtension {
+			count++
+		}
+	}
+	fmt.Printf("Restored %d TSM files to %v\n", count, dataDir)
+	return nil
+}
+
+// copyVerified copies the file at src to dst, checking the size and checksum
+// recorded for it in the manifest.
+func copyVerified(src, dst string, mf influxdb.BackupManifestFile) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("error opening backup file: %v", err)
+	}
+	defer f.Close()
+
+	w, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), f)
+	if err != nil {
+		return err
+	}
+
+	if n != mf.Size {
+		return fmt.Errorf("backup file %s has size %d, expected %d", src, n, mf.Size)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); mf.Checksum != "" && sum != mf.Checksum {
+		return fmt.Errorf("backup file %s has checksum %s, expected %s", src, sum, mf.Checksum)
+	}
+	return w.Close()
+}
+
 func restoreFile(backup string, target string, filetype string) error {
 	f, err := os.Open(backup)
 	if err != nil {
@@ -0,0 +1,70 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// Ops for compaction errors.
+const (
+	OpFindCompactions      = "FindCompactions"
+	OpCompactBucket        = "CompactBucket"
+	OpSetCompactionsPaused = "SetCompactionsPaused"
+	OpCancelCompaction     = "CancelCompaction"
+)
+
+// ErrCompactionNotFound is returned when cancelling a compaction that is not running.
+var ErrCompactionNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "compaction not found",
+}
+
+// Compaction levels of compactions that are not level compactions.
+const (
+	CompactionLevelOptimize = 4
+	CompactionLevelFull     = 5
+)
+
+// Compaction describes a running or planned compaction of the TSM files of the
+// storage engine.
+type Compaction struct {
+	// ID identifies a running compaction. Planned compactions have no ID.
+	ID int `json:"id,omitempty"`
+	// Level is 1 to 3 for level compactions, CompactionLevelOptimize for
+	// optimize compactions and CompactionLevelFull for full compactions.
+	Level int      `json:"level"`
+	Files []string `json:"files"`
+	// Size is the total size of the files in bytes.
+	Size      int64      `json:"size"`
+	Running   bool       `json:"running"`
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// Progress is the approximate fraction of the data of a running
+	// compaction that has been compacted, between 0 and 1.
+	Progress float64 `json:"progress"`
+}
+
+// CompactionStatus describes the state of the compactions of the storage engine.
+type CompactionStatus struct {
+	Paused      bool          `json:"paused"`
+	Compactions []*Compaction `json:"compactions"`
+}
+
+// CompactionService lets operators observe and control the compactions of the
+// storage engine.
+type CompactionService interface {
+	// FindCompactions returns the running compactions, followed by the planned
+	// compactions.
+	FindCompactions(ctx context.Context) (*CompactionStatus, error)
+
+	// CompactBucket schedules compactions of the files holding data of a
+	// bucket and returns them. If optimize is true, an optimize rather than a
+	// full compaction is scheduled.
+	CompactBucket(ctx context.Context, orgID, bucketID ID, optimize bool) ([]*Compaction, error)
+
+	// SetCompactionsPaused pauses or resumes compactions. Running compactions
+	// are aborted when pausing.
+	SetCompactionsPaused(ctx context.Context, paused bool) error
+
+	// CancelCompaction aborts the running compaction with the given ID.
+	CancelCompaction(ctx context.Context, id int) error
+}
@@ -7,6 +7,31 @@ import (
 	"unicode"
 )
 
+// DefaultDBRPMappingCluster is the cluster of the dbrp mappings of the
+// databases and retention policies of the local instance.
+const DefaultDBRPMappingCluster = "default"
+
+// ErrDBRPMappingNotFound is returned when a dbrp mapping does not exist.
+var ErrDBRPMappingNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "dbrp mapping not found",
+}
+
+// ErrDBRPMappingExists is returned when creating a dbrp mapping that differs
+// from an existing one of the same cluster, database and retention policy.
+var ErrDBRPMappingExists = &Error{
+	Code: EConflict,
+	Msg:  "dbrp mapping already exists",
+}
+
+// ops for dbrp mapping errors.
+var (
+	OpFindDBRPMapping   = "FindDBRPMapping"
+	OpFindDBRPMappings  = "FindDBRPMappings"
+	OpCreateDBRPMapping = "CreateDBRPMapping"
+	OpDeleteDBRPMapping = "DeleteDBRPMapping"
+)
+
 // DBRPMappingService provides a mapping of cluster, database and retention policy to an organization ID and bucket ID.
 type DBRPMappingService interface {
 	// FindBy returns the dbrp mapping the for cluster, db and rp.
@@ -9,6 +9,7 @@ import (
 	"github.com/influxdata/influxdb/chronograf/server"
 	"github.com/influxdata/influxdb/http/metric"
 	"github.com/influxdata/influxdb/kit/prom"
+	"github.com/influxdata/influxdb/nats"
 	"github.com/influxdata/influxdb/query"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/prometheus/client_golang/prometheus"
@@ -43,6 +44,18 @@ type APIBackend struct {
 	// write request. A value of zero specifies there is no limit.
 	WriteParserMaxValues int
 
+	// WriteQueue queues writes to be written to storage asynchronously, with
+	// their receipts stored by WriteReceiptService. Writes are synchronous if
+	// it is nil.
+	WriteQueue nats.Publisher
+
+	// WriteQueueMaxBytes is the maximum size of the uncompressed bodies of
+	// queued writes. Their messages are larger by the size of a short header.
+	// A value of zero specifies there is no limit.
+	WriteQueueMaxBytes int
+
+	WriteReceiptService influxdb.WriteReceiptService
+
 	NewBucketService func(*influxdb.Source) (influxdb.BucketService, error)
 	NewQueryService  func(*influxdb.Source) (query.ProxyQueryService, error)
 
@@ -53,6 +66,14 @@ type APIBackend struct {
 	DeleteService                   influxdb.DeleteService
 	BackupService                   influxdb.BackupService
 	KVBackupService                 influxdb.KVBackupService
+	RestoreService                  influxdb.RestoreService
+	BackupScheduleService           influxdb.BackupScheduleService
+	BucketSchemaService             influxdb.BucketSchemaService
+	CompactionService               influxdb.CompactionService
+	CardinalityService              influxdb.CardinalityService
+	SeriesGCService                 influxdb.SeriesGCService
+	ReplicationService              influxdb.ReplicationService
+	ListenerService                 influxdb.ListenerService
 	AuthorizationService            influxdb.AuthorizationService
 	BucketService                   influxdb.BucketService
 	SessionService                  influxdb.SessionService
@@ -62,6 +83,7 @@ type APIBackend struct {
 	LabelService                    influxdb.LabelService
 	DashboardService                influxdb.DashboardService
 	DashboardOperationLogService    influxdb.DashboardOperationLogService
+	DBRPMappingService              influxdb.DBRPMappingService
 	BucketOperationLogService       influxdb.BucketOperationLogService
 	UserOperationLogService         influxdb.UserOperationLogService
 	OrganizationOperationLogService influxdb.OrganizationOperationLogService
@@ -135,6 +157,7 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 
 	bucketBackend := NewBucketBackend(b.Logger.With(zap.String("handler", "bucket")), b)
 	bucketBackend.BucketService = authorizer.NewBucketService(b.BucketService)
+	bucketBackend.BucketSchemaService = authorizer.NewBucketSchemaService(b.BucketSchemaService, b.BucketService)
 	h.Mount(prefixBuckets, NewBucketHandler(b.Logger, bucketBackend))
 
 	checkBackend := NewCheckBackend(b.Logger.With(zap.String("handler", "check")), b)
@@ -148,6 +171,11 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 	dashboardBackend.DashboardService = authorizer.NewDashboardService(b.DashboardService)
 	h.Mount(prefixDashboards, NewDashboardHandler(b.Logger, dashboardBackend))
 
+	dbrpBackend := NewDBRPMappingBackend(b.Logger.With(zap.String("handler", "dbrp")), b)
+	dbrpBackend.DBRPMappingService = authorizer.NewDBRPMappingService(b.DBRPMappingService)
+	dbrpBackend.BucketService = authorizer.NewBucketService(b.BucketService)
+	h.Mount(prefixDBRPs, NewDBRPMappingHandler(b.Logger, dbrpBackend))
+
 	deleteBackend := NewDeleteBackend(b.Logger.With(zap.String("handler", "delete")), b)
 	h.Mount(prefixDelete, NewDeleteHandler(b.Logger, deleteBackend))
 
@@ -217,7 +245,42 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 
 	backupBackend := NewBackupBackend(b)
 	backupBackend.BackupService = authorizer.NewBackupService(backupBackend.BackupService)
-	h.Mount(prefixBackup, NewBackupHandler(backupBackend))
+	backupBackend.RestoreService = authorizer.NewRestoreService(b.OrgLookupService, backupBackend.RestoreService)
+	backupBackend.BucketService = authorizer.NewBucketService(b.BucketService)
+	backupBackend.LabelService = authorizer.NewLabelService(b.LabelService)
+	backupHandler := NewBackupHandler(backupBackend)
+	h.Mount(prefixBackup, backupHandler)
+	h.Mount(prefixRestore, backupHandler)
+
+	backupScheduleBackend := NewBackupScheduleBackend(b.Logger.With(zap.String("handler", "backup_schedule")), b)
+	backupScheduleBackend.BackupScheduleService = authorizer.NewBackupScheduleService(b.BackupScheduleService)
+	h.Mount(prefixBackupSchedules, NewBackupScheduleHandler(b.Logger, backupScheduleBackend))
+
+	compactionBackend := NewCompactionBackend(b.Logger.With(zap.String("handler", "compaction")), b)
+	compactionBackend.CompactionService = authorizer.NewCompactionService(b.CompactionService)
+	h.Mount(prefixCompactions, NewCompactionHandler(b.Logger, compactionBackend))
+
+	cardinalityBackend := NewCardinalityBackend(b.Logger.With(zap.String("handler", "cardinality")), b)
+	cardinalityBackend.CardinalityService = authorizer.NewCardinalityService(b.CardinalityService)
+	h.Mount(prefixCardinality, NewCardinalityHandler(b.Logger, cardinalityBackend))
+
+	seriesGCBackend := NewSeriesGCBackend(b.Logger.With(zap.String("handler", "series_gc")), b)
+	seriesGCBackend.SeriesGCService = authorizer.NewSeriesGCService(b.SeriesGCService)
+	h.Mount(prefixSeriesGC, NewSeriesGCHandler(b.Logger, seriesGCBackend))
+
+	legacyBackend := NewLegacyBackend(b.Logger.With(zap.String("handler", "legacy")), b)
+	legacyHandler := NewLegacyHandler(b.Logger, legacyBackend)
+	h.Mount(prefixLegacyPing, legacyHandler)
+	h.Mount(prefixLegacyQuery, legacyHandler)
+	h.Mount(prefixLegacyWrite, legacyHandler)
+
+	listenerBackend := NewListenerBackend(b.Logger.With(zap.String("handler", "listener")), b)
+	listenerBackend.ListenerService = authorizer.NewListenerService(b.ListenerService)
+	h.Mount(prefixListeners, NewListenerHandler(b.Logger, listenerBackend))
+
+	replicationBackend := NewReplicationBackend(b.Logger.With(zap.String("handler", "replication")), b)
+	replicationBackend.ReplicationService = authorizer.NewReplicationService(b.ReplicationService)
+	h.Mount(prefixReplication, NewReplicationHandler(b.Logger, replicationBackend))
 
 	writeBackend := NewWriteBackend(b.Logger.With(zap.String("handler", "write")), b)
 	h.Mount(prefixWrite, NewWriteHandler(b.Logger, writeBackend,
@@ -236,14 +299,17 @@ func NewAPIHandler(b *APIBackend, opts ...APIHandlerOptFn) *APIHandler {
 var apiLinks = map[string]interface{}{
 	// when adding new links, please take care to keep this list alphabetical
 	// as this makes it easier to verify values against the swagger document.
-	"authorizations": "/api/v2/authorizations",
-	"backup":         "/api/v2/backup",
-	"buckets":        "/api/v2/buckets",
-	"dashboards":     "/api/v2/dashboards",
+	"authorizations":  "/api/v2/authorizations",
+	"backup":          "/api/v2/backup",
+	"backupSchedules": "/api/v2/backupSchedules",
+	"buckets":         "/api/v2/buckets",
+	"dashboards":      "/api/v2/dashboards",
+	"dbrps":           "/api/v2/dbrps",
 	"external": map[string]string{
 		"statusFeed": "https://www.influxdata.com/feed/json",
 	},
 	"labels":                "/api/v2/labels",
+	"listeners":             "/api/v2/listeners",
 	"variables":             "/api/v2/variables",
 	"me":                    "/api/v2/me",
 	"notificationRules":     "/api/v2/notificationRules",
@@ -255,6 +321,7 @@ var apiLinks = map[string]interface{}{
 		"analyze":     "/api/v2/query/analyze",
 		"suggestions": "/api/v2/query/suggestions",
 	},
+	"restore":  "/api/v2/restore",
 	"setup":    "/api/v2/setup",
 	"signin":   "/api/v2/signin",
 	"signout":  "/api/v2/signout",
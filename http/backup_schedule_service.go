@@ -0,0 +1,444 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixBackupSchedules = "/api/v2/backupSchedules"
+	backupScheduleIDPath  = prefixBackupSchedules + "/:id"
+	backupRunsPath        = backupScheduleIDPath + "/runs"
+	backupRunIDPath       = backupRunsPath + "/:rid"
+)
+
+// BackupScheduleBackend is all services and associated parameters required to
+// construct the BackupScheduleHandler.
+type BackupScheduleBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	BackupScheduleService influxdb.BackupScheduleService
+}
+
+// NewBackupScheduleBackend returns a new instance of BackupScheduleBackend.
+func NewBackupScheduleBackend(log *zap.Logger, b *APIBackend) *BackupScheduleBackend {
+	return &BackupScheduleBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		BackupScheduleService: b.BackupScheduleService,
+	}
+}
+
+// BackupScheduleHandler is the handler for backup schedules and their run history.
+type BackupScheduleHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	BackupScheduleService influxdb.BackupScheduleService
+}
+
+// NewBackupScheduleHandler returns a new instance of BackupScheduleHandler.
+func NewBackupScheduleHandler(log *zap.Logger, b *BackupScheduleBackend) *BackupScheduleHandler {
+	h := &BackupScheduleHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		BackupScheduleService: b.BackupScheduleService,
+	}
+
+	h.HandlerFunc("POST", prefixBackupSchedules, h.handlePostBackupSchedule)
+	h.HandlerFunc("GET", prefixBackupSchedules, h.handleGetBackupSchedules)
+	h.HandlerFunc("GET", backupScheduleIDPath, h.handleGetBackupSchedule)
+	h.HandlerFunc("PATCH", backupScheduleIDPath, h.handlePatchBackupSchedule)
+	h.HandlerFunc("DELETE", backupScheduleIDPath, h.handleDeleteBackupSchedule)
+
+	h.HandlerFunc("GET", backupRunsPath, h.handleGetBackupRuns)
+	h.HandlerFunc("GET", backupRunIDPath, h.handleGetBackupRun)
+
+	return h
+}
+
+type backupScheduleResponse struct {
+	*influxdb.BackupSchedule
+	Links map[string]string `json:"links"`
+}
+
+func newBackupScheduleResponse(bs *influxdb.BackupSchedule) *backupScheduleResponse {
+	return &backupScheduleResponse{
+		BackupSchedule: bs,
+		Links: map[string]string{
+			"self": fmt.Sprintf("%s/%s", prefixBackupSchedules, bs.ID),
+			"runs": fmt.Sprintf("%s/%s/runs", prefixBackupSchedules, bs.ID),
+		},
+	}
+}
+
+type backupSchedulesResponse struct {
+	BackupSchedules []*backupScheduleResponse `json:"backupSchedules"`
+	Links           map[string]string         `json:"links"`
+}
+
+func newBackupSchedulesResponse(schedules []*influxdb.BackupSchedule) *backupSchedulesResponse {
+	res := &backupSchedulesResponse{
+		BackupSchedules: make([]*backupScheduleResponse, 0, len(schedules)),
+		Links: map[string]string{
+			"self": prefixBackupSchedules,
+		},
+	}
+	for _, bs := range schedules {
+		res.BackupSchedules = append(res.BackupSchedules, newBackupScheduleResponse(bs))
+	}
+	return res
+}
+
+type backupRunResponse struct {
+	*influxdb.BackupRun
+	Links map[string]string `json:"links"`
+}
+
+func newBackupRunResponse(r *influxdb.BackupRun) *backupRunResponse {
+	return &backupRunResponse{
+		BackupRun: r,
+		Links: map[string]string{
+			"self":     fmt.Sprintf("%s/%s/runs/%s", prefixBackupSchedules, r.ScheduleID, r.ID),
+			"schedule": fmt.Sprintf("%s/%s", prefixBackupSchedules, r.ScheduleID),
+		},
+	}
+}
+
+type backupRunsResponse struct {
+	Runs  []*backupRunResponse `json:"runs"`
+	Links map[string]string    `json:"links"`
+}
+
+func newBackupRunsResponse(scheduleID influxdb.ID, runs []*influxdb.BackupRun) *backupRunsResponse {
+	res := &backupRunsResponse{
+		Runs: make([]*backupRunResponse, 0, len(runs)),
+		Links: map[string]string{
+			"self":     fmt.Sprintf("%s/%s/runs", prefixBackupSchedules, scheduleID),
+			"schedule": fmt.Sprintf("%s/%s", prefixBackupSchedules, scheduleID),
+		},
+	}
+	for _, r := range runs {
+		res.Runs = append(res.Runs, newBackupRunResponse(r))
+	}
+	return res
+}
+
+// decodeIDParam returns the ID in the named route parameter.
+func decodeIDParam(ctx context.Context, name string) (influxdb.ID, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	id := params.ByName(name)
+	if id == "" {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "url missing " + name,
+		}
+	}
+
+	var i influxdb.ID
+	if err := i.DecodeFromString(id); err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+// handlePostBackupSchedule is the HTTP handler for the POST /api/v2/backupSchedules route.
+func (h *BackupScheduleHandler) handlePostBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	bs := &influxdb.BackupSchedule{}
+	if err := json.NewDecoder(r.Body).Decode(bs); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid backup schedule",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.BackupScheduleService.CreateBackupSchedule(ctx, bs); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup schedule created", zap.String("backupSchedule", fmt.Sprint(bs)))
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newBackupScheduleResponse(bs)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetBackupSchedules is the HTTP handler for the GET /api/v2/backupSchedules route.
+func (h *BackupScheduleHandler) handleGetBackupSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var filter influxdb.BackupScheduleFilter
+	qp := r.URL.Query()
+	if name := qp.Get("name"); name != "" {
+		filter.Name = &name
+	}
+	if status := qp.Get("status"); status != "" {
+		s := influxdb.Status(status)
+		if err := s.Valid(); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		filter.Status = &s
+	}
+
+	schedules, _, err := h.BackupScheduleService.FindBackupSchedules(ctx, filter)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup schedules retrieved", zap.String("backupSchedules", fmt.Sprint(schedules)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBackupSchedulesResponse(schedules)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetBackupSchedule is the HTTP handler for the GET /api/v2/backupSchedules/:id route.
+func (h *BackupScheduleHandler) handleGetBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	bs, err := h.BackupScheduleService.FindBackupScheduleByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup schedule retrieved", zap.String("backupSchedule", fmt.Sprint(bs)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBackupScheduleResponse(bs)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePatchBackupSchedule is the HTTP handler for the PATCH /api/v2/backupSchedules/:id route.
+func (h *BackupScheduleHandler) handlePatchBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var upd influxdb.BackupScheduleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid backup schedule update",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	bs, err := h.BackupScheduleService.UpdateBackupSchedule(ctx, id, upd)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup schedule updated", zap.String("backupSchedule", fmt.Sprint(bs)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBackupScheduleResponse(bs)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleDeleteBackupSchedule is the HTTP handler for the DELETE /api/v2/backupSchedules/:id route.
+func (h *BackupScheduleHandler) handleDeleteBackupSchedule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.BackupScheduleService.DeleteBackupSchedule(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup schedule deleted", zap.String("backupScheduleID", id.String()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleGetBackupRuns is the HTTP handler for the GET /api/v2/backupSchedules/:id/runs route.
+func (h *BackupScheduleHandler) handleGetBackupRuns(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	runs, _, err := h.BackupScheduleService.FindBackupRuns(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup runs retrieved", zap.String("backupRuns", fmt.Sprint(runs)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBackupRunsResponse(id, runs)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetBackupRun is the HTTP handler for the GET /api/v2/backupSchedules/:id/runs/:rid route.
+func (h *BackupScheduleHandler) handleGetBackupRun(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	runID, err := decodeIDParam(ctx, "rid")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	run, err := h.BackupScheduleService.FindBackupRunByID(ctx, id, runID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Backup run retrieved", zap.String("backupRun", fmt.Sprint(run)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBackupRunResponse(run)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+var _ influxdb.BackupScheduleService = (*BackupScheduleService)(nil)
+
+// BackupScheduleService connects to Influx via HTTP using tokens to manage
+// backup schedules.
+type BackupScheduleService struct {
+	Client *httpc.Client
+}
+
+// FindBackupScheduleByID returns a single backup schedule by ID.
+func (s *BackupScheduleService) FindBackupScheduleByID(ctx context.Context, id influxdb.ID) (*influxdb.BackupSchedule, error) {
+	var bs influxdb.BackupSchedule
+	err := s.Client.
+		Get(prefixBackupSchedules, id.String()).
+		DecodeJSON(&bs).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &bs, nil
+}
+
+// FindBackupSchedules returns a list of backup schedules that match filter and
+// the total count of matching backup schedules.
+func (s *BackupScheduleService) FindBackupSchedules(ctx context.Context, filter influxdb.BackupScheduleFilter) ([]*influxdb.BackupSchedule, int, error) {
+	var params [][2]string
+	if filter.Name != nil {
+		params = append(params, [2]string{"name", *filter.Name})
+	}
+	if filter.Status != nil {
+		params = append(params, [2]string{"status", string(*filter.Status)})
+	}
+
+	var res struct {
+		BackupSchedules []*influxdb.BackupSchedule `json:"backupSchedules"`
+	}
+	err := s.Client.
+		Get(prefixBackupSchedules).
+		QueryParams(params...).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.BackupSchedules, len(res.BackupSchedules), nil
+}
+
+// CreateBackupSchedule creates a new backup schedule and sets bs.ID with the new identifier.
+func (s *BackupScheduleService) CreateBackupSchedule(ctx context.Context, bs *influxdb.BackupSchedule) error {
+	return s.Client.
+		PostJSON(bs, prefixBackupSchedules).
+		DecodeJSON(bs).
+		Do(ctx)
+}
+
+// UpdateBackupSchedule updates a single backup schedule with changeset.
+// Returns the new backup schedule state after update.
+func (s *BackupScheduleService) UpdateBackupSchedule(ctx context.Context, id influxdb.ID, upd influxdb.BackupScheduleUpdate) (*influxdb.BackupSchedule, error) {
+	var bs influxdb.BackupSchedule
+	err := s.Client.
+		PatchJSON(upd, prefixBackupSchedules, id.String()).
+		DecodeJSON(&bs).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &bs, nil
+}
+
+// DeleteBackupSchedule removes a backup schedule by ID, along with its run history.
+func (s *BackupScheduleService) DeleteBackupSchedule(ctx context.Context, id influxdb.ID) error {
+	return s.Client.
+		Delete(prefixBackupSchedules, id.String()).
+		StatusFn(func(resp *http.Response) error {
+			return CheckErrorStatus(http.StatusNoContent, resp)
+		}).
+		Do(ctx)
+}
+
+// FindBackupRuns returns the runs of the backup schedule, newest first.
+func (s *BackupScheduleService) FindBackupRuns(ctx context.Context, scheduleID influxdb.ID) ([]*influxdb.BackupRun, int, error) {
+	var res struct {
+		Runs []*influxdb.BackupRun `json:"runs"`
+	}
+	err := s.Client.
+		Get(prefixBackupSchedules, scheduleID.String(), "runs").
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Runs, len(res.Runs), nil
+}
+
+// FindBackupRunByID returns a single run of the backup schedule.
+func (s *BackupScheduleService) FindBackupRunByID(ctx context.Context, scheduleID, runID influxdb.ID) (*influxdb.BackupRun, error) {
+	var r influxdb.BackupRun
+	err := s.Client.
+		Get(prefixBackupSchedules, scheduleID.String(), "runs", runID.String()).
+		DecodeJSON(&r).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -11,13 +13,17 @@ import (
 	"path"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
+	influxbackup "github.com/influxdata/influxdb/backup"
 	"github.com/influxdata/influxdb/bolt"
 	"github.com/influxdata/influxdb/internal/fs"
 	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
@@ -29,8 +35,12 @@ type BackupBackend struct {
 	Logger *zap.Logger
 	influxdb.HTTPErrorHandler
 
-	BackupService   influxdb.BackupService
-	KVBackupService influxdb.KVBackupService
+	BackupService              influxdb.BackupService
+	KVBackupService            influxdb.KVBackupService
+	RestoreService             influxdb.RestoreService
+	BucketService              influxdb.BucketService
+	LabelService               influxdb.LabelService
+	UserResourceMappingService influxdb.UserResourceMappingService
 }
 
 // NewBackupBackend returns a new instance of BackupBackend.
@@ -38,9 +48,13 @@ func NewBackupBackend(b *APIBackend) *BackupBackend {
 	return &BackupBackend{
 		Logger: b.Logger.With(zap.String("handler", "backup")),
 
-		HTTPErrorHandler: b.HTTPErrorHandler,
-		BackupService:    b.BackupService,
-		KVBackupService:  b.KVBackupService,
+		HTTPErrorHandler:           b.HTTPErrorHandler,
+		BackupService:              b.BackupService,
+		KVBackupService:            b.KVBackupService,
+		RestoreService:             b.RestoreService,
+		BucketService:              b.BucketService,
+		LabelService:               b.LabelService,
+		UserResourceMappingService: b.UserResourceMappingService,
 	}
 }
 
@@ -49,8 +63,12 @@ type BackupHandler struct {
 	influxdb.HTTPErrorHandler
 	Logger *zap.Logger
 
-	BackupService   influxdb.BackupService
-	KVBackupService influxdb.KVBackupService
+	BackupService              influxdb.BackupService
+	KVBackupService            influxdb.KVBackupService
+	RestoreService             influxdb.RestoreService
+	BucketService              influxdb.BucketService
+	LabelService               influxdb.LabelService
+	UserResourceMappingService influxdb.UserResourceMappingService
 }
 
 const (
@@ -59,6 +77,10 @@ const (
 	backupFileParamName = "backup_file"
 	backupFilePath      = prefixBackup + "/:" + backupIDParamName + "/file/:" + backupFileParamName
 
+	prefixRestore         = "/api/v2/restore"
+	restoreBucketPath     = prefixRestore + "/bucket"
+	restoreBucketDataPath = restoreBucketPath + "/:id/data"
+
 	httpClientTimeout = time.Hour
 )
 
@@ -66,18 +88,30 @@ func composeBackupFilePath(backupID int, backupFile string) string {
 	return path.Join(prefixBackup, fmt.Sprint(backupID), "file", fmt.Sprint(backupFile))
 }
 
-// NewBackupHandler creates a new handler at /api/v2/backup to receive backup requests.
+func composeRestoreBucketDataPath(bucketID influxdb.ID) string {
+	return path.Join(restoreBucketPath, bucketID.String(), "data")
+}
+
+// NewBackupHandler creates a new handler at /api/v2/backup to receive backup requests,
+// and at /api/v2/restore to receive restore requests.
 func NewBackupHandler(b *BackupBackend) *BackupHandler {
 	h := &BackupHandler{
-		HTTPErrorHandler: b.HTTPErrorHandler,
-		Router:           NewRouter(b.HTTPErrorHandler),
-		Logger:           b.Logger,
-		BackupService:    b.BackupService,
-		KVBackupService:  b.KVBackupService,
+		HTTPErrorHandler:           b.HTTPErrorHandler,
+		Router:                     NewRouter(b.HTTPErrorHandler),
+		Logger:                     b.Logger,
+		BackupService:              b.BackupService,
+		KVBackupService:            b.KVBackupService,
+		RestoreService:             b.RestoreService,
+		BucketService:              b.BucketService,
+		LabelService:               b.LabelService,
+		UserResourceMappingService: b.UserResourceMappingService,
 	}
 
 	h.HandlerFunc(http.MethodPost, prefixBackup, h.handleCreate)
 	h.HandlerFunc(http.MethodGet, backupFilePath, h.handleFetchFile)
+	h.HandlerFunc(http.MethodPost, prefixRestore, h.handleRestore)
+	h.HandlerFunc(http.MethodPost, restoreBucketPath, h.handleRestoreBucketMetadata)
+	h.HandlerFunc(http.MethodPost, restoreBucketDataPath, h.handleRestoreBucketData)
 
 	return h
 }
@@ -93,7 +127,28 @@ func (h *BackupHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	id, files, err := h.BackupService.CreateBackup(ctx)
+	var opts influxdb.BackupOptions
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+			h.HandleHTTPError(ctx, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "invalid backup options",
+				Err:  err,
+			}, w)
+			return
+		}
+	}
+
+	if opts.BucketID != nil && opts.OrgID == nil {
+		b, err := h.BucketService.FindBucketByID(ctx, *opts.BucketID)
+		if err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		opts.OrgID = &b.OrgID
+	}
+
+	id, files, err := h.BackupService.CreateBackup(ctx, opts)
 	if err != nil {
 		h.HandleHTTPError(ctx, err, w)
 		return
@@ -101,6 +156,23 @@ func (h *BackupHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 
 	internalBackupPath := h.BackupService.InternalBackupPath(id)
 
+	if opts.Scoped() {
+		// Scoped backups hold the metadata of their buckets instead of the
+		// metadata database and credentials of the whole instance.
+		if err := h.backupBucketMetadata(ctx, opts, filepath.Join(internalBackupPath, influxdb.BackupBucketsFilename)); err != nil {
+			err = multierr.Append(err, os.RemoveAll(internalBackupPath))
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		files = append(files, influxdb.BackupBucketsFilename)
+
+		if err = json.NewEncoder(w).Encode(&backup{ID: id, Files: files}); err != nil {
+			err = multierr.Append(err, os.RemoveAll(internalBackupPath))
+			h.HandleHTTPError(ctx, err, w)
+		}
+		return
+	}
+
 	boltPath := filepath.Join(internalBackupPath, bolt.DefaultFilename)
 	boltFile, err := os.OpenFile(boltPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
 	if err != nil {
@@ -148,6 +220,24 @@ func (h *BackupHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// backupBucketMetadata writes the metadata of the user buckets included in a
+// scoped backup to the file at path.
+func (h *BackupHandler) backupBucketMetadata(ctx context.Context, opts influxdb.BackupOptions, path string) error {
+	ms, err := influxbackup.BucketMetadata(ctx, opts, h.BucketService, h.LabelService, h.UserResourceMappingService)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(ms); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	return f.Close()
+}
+
 func (h *BackupHandler) handleFetchFile(w http.ResponseWriter, r *http.Request) {
 	span, r := tracing.ExtractFromHTTPRequest(r, "BackupHandler.handleFetchFile")
 	defer span.Finish()
@@ -168,6 +258,137 @@ func (h *BackupHandler) handleFetchFile(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleRestore restores a full backup, sent as a tar archive of the backup
+// files, into the running instance. TSM files are loaded as they are read; the
+// metadata database is replaced when its entry is read, so it should be the
+// last entry of the archive.
+func (h *BackupHandler) handleRestore(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BackupHandler.handleRestore")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	tr := tar.NewReader(r.Body)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			h.HandleHTTPError(ctx, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "invalid backup archive",
+				Err:  err,
+			}, w)
+			return
+		}
+
+		name := path.Base(hdr.Name)
+		switch {
+		case name == bolt.DefaultFilename:
+			err = h.RestoreService.RestoreKVStore(ctx, tr)
+		case strings.HasSuffix(name, "."+tsm1.TSMFileExtension):
+			err = restoreError(h.RestoreService.RestoreTSMFile(ctx, tr))
+		case strings.HasSuffix(name, "."+tsm1.TombstoneFileExtension):
+			err = &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("tombstone file %s must be applied to its TSM file before restoring", name),
+			}
+		default:
+			h.Logger.Debug("Skipping backup file", zap.String("file", name))
+			continue
+		}
+		if err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		h.Logger.Info("Restored backup file", zap.String("file", name))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BackupHandler) handleRestoreBucketMetadata(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BackupHandler.handleRestoreBucketMetadata")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	var m influxdb.BucketMetadataBackup
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid bucket metadata",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	b, err := h.RestoreService.RestoreBucketMetadata(ctx, m)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.Logger.Debug("Bucket restored", zap.String("bucket", fmt.Sprint(b)))
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newBucketResponse(b, []*influxdb.Label{})); err != nil {
+		logEncodingError(h.Logger, r, err)
+		return
+	}
+}
+
+func (h *BackupHandler) handleRestoreBucketData(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BackupHandler.handleRestoreBucketData")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	var bucketID influxdb.ID
+	if err := bucketID.DecodeFromString(params.ByName("id")); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	// The source bucket identifies the data to restore in the TSM file.
+	var src influxdb.Bucket
+	qp := r.URL.Query()
+	if err := src.OrgID.DecodeFromString(qp.Get("srcOrgID")); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid srcOrgID",
+			Err:  err,
+		}, w)
+		return
+	}
+	if err := src.ID.DecodeFromString(qp.Get("srcBucketID")); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid srcBucketID",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.RestoreService.RestoreBucket(ctx, bucketID, &src, r.Body); err != nil {
+		h.HandleHTTPError(ctx, restoreError(err), w)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// restoreError returns the error of restoring data. Series that conflict with
+// the field types of stored data or exceed the series limits of their bucket
+// are left out of a restore, while the other series are restored.
+func restoreError(err error) error {
+	if pwe, ok := err.(tsdb.PartialWriteError); ok {
+		return &influxdb.Error{
+			Code: influxdb.EUnprocessableEntity,
+			Msg:  "some series were not restored",
+			Err:  pwe,
+		}
+	}
+	return err
+}
+
 // BackupService is the client implementation of influxdb.BackupService.
 type BackupService struct {
 	Addr               string
@@ -175,7 +396,7 @@ type BackupService struct {
 	InsecureSkipVerify bool
 }
 
-func (s *BackupService) CreateBackup(ctx context.Context) (int, []string, error) {
+func (s *BackupService) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
@@ -184,11 +405,17 @@ func (s *BackupService) CreateBackup(ctx context.Context) (int, []string, error)
 		return 0, nil, err
 	}
 
-	req, err := http.NewRequest(http.MethodPost, u.String(), nil)
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(b))
 	if err != nil {
 		return 0, nil, err
 	}
 	SetToken(s.Token, req)
+	req.Header.Set("Content-Type", "application/json")
 	req = req.WithContext(ctx)
 
 	hc := NewClient(u.Scheme, s.InsecureSkipVerify)
@@ -203,12 +430,12 @@ func (s *BackupService) CreateBackup(ctx context.Context) (int, []string, error)
 		return 0, nil, err
 	}
 
-	var b backup
-	if err = json.NewDecoder(resp.Body).Decode(&b); err != nil {
+	var bk backup
+	if err = json.NewDecoder(resp.Body).Decode(&bk); err != nil {
 		return 0, nil, err
 	}
 
-	return b.ID, b.Files, nil
+	return bk.ID, bk.Files, nil
 }
 
 func (s *BackupService) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
@@ -258,3 +485,196 @@ func defaultTokenPath() (string, error) {
 func (s *BackupService) InternalBackupPath(backupID int) string {
 	panic("internal method not implemented here")
 }
+
+// RestoreService is the client implementation of influxdb.RestoreService.
+type RestoreService struct {
+	Addr               string
+	Token              string
+	InsecureSkipVerify bool
+}
+
+func (s *RestoreService) RestoreBucketMetadata(ctx context.Context, m influxdb.BucketMetadataBackup) (*influxdb.Bucket, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	u, err := NewURL(s.Addr, restoreBucketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	SetToken(s.Token, req)
+	req.Header.Set("Content-Type", "application/json")
+	req = req.WithContext(ctx)
+
+	hc := NewClient(u.Scheme, s.InsecureSkipVerify)
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckError(resp); err != nil {
+		return nil, err
+	}
+
+	var br bucketResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		return nil, err
+	}
+	return br.toInfluxDB()
+}
+
+func (s *RestoreService) RestoreBucket(ctx context.Context, bucketID influxdb.ID, src *influxdb.Bucket, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	u, err := NewURL(s.Addr, composeRestoreBucketDataPath(bucketID))
+	if err != nil {
+		return err
+	}
+	qp := u.Query()
+	qp.Set("srcOrgID", src.OrgID.String())
+	qp.Set("srcBucketID", src.ID.String())
+	u.RawQuery = qp.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), r)
+	if err != nil {
+		return err
+	}
+	SetToken(s.Token, req)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req = req.WithContext(ctx)
+
+	hc := NewClient(u.Scheme, s.InsecureSkipVerify)
+	hc.Timeout = httpClientTimeout
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return CheckError(resp)
+}
+
+// RestoreBackup restores a full backup, read from r as a tar archive of the
+// backup files, into the instance.
+func (s *RestoreService) RestoreBackup(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	u, err := NewURL(s.Addr, prefixRestore)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), r)
+	if err != nil {
+		return err
+	}
+	SetToken(s.Token, req)
+	req.Header.Set("Content-Type", "application/x-tar")
+	req = req.WithContext(ctx)
+
+	hc := NewClient(u.Scheme, s.InsecureSkipVerify)
+	hc.Timeout = httpClientTimeout
+	resp, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return CheckError(resp)
+}
+
+func (s *RestoreService) RestoreTSMFile(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.restoreFile(ctx, "0."+tsm1.TSMFileExtension, r)
+}
+
+func (s *RestoreService) RestoreKVStore(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.restoreFile(ctx, bolt.DefaultFilename, r)
+}
+
+// restoreFile restores a single backup file, read from r, by streaming it as
+// the only entry of a backup archive.
+func (s *RestoreService) restoreFile(ctx context.Context, name string, r io.Reader) error {
+	// The size of an archive entry must be known before it is written, so
+	// files that can't be seeked are spooled to a temporary file first.
+	rs, ok := r.(io.ReadSeeker)
+	if !ok {
+		f, err := ioutil.TempFile("", "influx-restore-")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+
+		if _, err := io.Copy(f, r); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		rs = f
+	}
+	size, err := remainingSize(rs)
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		tw := tar.NewWriter(pw)
+		err := tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0600,
+			Size: size,
+		})
+		if err == nil {
+			_, err = io.Copy(tw, rs)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	err = s.RestoreBackup(ctx, pr)
+	// Unblock the writer if the request ended before the archive was read,
+	// and wait for it to stop reading from r.
+	pr.CloseWithError(io.ErrClosedPipe)
+	<-done
+	return err
+}
+
+// remainingSize returns the number of bytes left to read from rs.
+func remainingSize(rs io.Seeker) (int64, error) {
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := rs.Seek(cur, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return end - cur, nil
+}
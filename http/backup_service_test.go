@@ -0,0 +1,111 @@
+package http
+
+import (
+	"archive/tar"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestRestoreService_RestoreTSMFile(t *testing.T) {
+	const data = "tsm file contents"
+
+	tests := []struct {
+		name string
+		r    io.Reader
+	}{
+		{name: "seekable", r: strings.NewReader(data)},
+		{name: "not seekable", r: ioutil.NopCloser(strings.NewReader(data))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var name, body string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != prefixRestore {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				tr := tar.NewReader(r.Body)
+				hdr, err := tr.Next()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				b, err := ioutil.ReadAll(tr)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := tr.Next(); err != io.EOF {
+					t.Errorf("expected a single archive entry, got %v", err)
+				}
+				name, body = hdr.Name, string(b)
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer ts.Close()
+
+			s := &RestoreService{Addr: ts.URL}
+			if err := s.RestoreTSMFile(context.Background(), tt.r); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := name, "0."+tsm1.TSMFileExtension; got != want {
+				t.Errorf("unexpected file name: got %s want %s", got, want)
+			}
+			if body != data {
+				t.Errorf("unexpected file contents: got %q want %q", body, data)
+			}
+		})
+	}
+}
+
+func TestRestoreService_RestoreTSMFile_Error(t *testing.T) {
+	// The server fails the request without reading the archive.
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, `{"code":"internal error","message":"restore failed"}`)
+	}))
+	defer ts.Close()
+
+	s := &RestoreService{Addr: ts.URL}
+	r := strings.NewReader(strings.Repeat("x", 1<<20))
+	if err := s.RestoreTSMFile(context.Background(), r); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+// partialRestoreService leaves out series of restored data.
+type partialRestoreService struct {
+	influxdb.RestoreService
+}
+
+func (partialRestoreService) RestoreBucket(ctx context.Context, bucketID influxdb.ID, src *influxdb.Bucket, r io.Reader) error {
+	return tsdb.PartialWriteError{Reason: "field type conflict", Dropped: 1}
+}
+
+func TestBackupHandler_RestoreBucketData_PartialWrite(t *testing.T) {
+	h := NewBackupHandler(&BackupBackend{
+		Logger:           zaptest.NewLogger(t),
+		HTTPErrorHandler: ErrorHandler(0),
+		RestoreService:   partialRestoreService{},
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/restore/bucket/020f755c3c082000/data?srcOrgID=020f755c3c082001&srcBucketID=020f755c3c082002", strings.NewReader("tsm"))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusUnprocessableEntity; got != want {
+		t.Fatalf("unexpected status: got %d want %d", got, want)
+	}
+	if got, want := w.Header().Get(PlatformErrorCodeHeader), influxdb.EUnprocessableEntity; got != want {
+		t.Fatalf("unexpected error code: got %q want %q", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const bucketsIDSchemaPath = "/api/v2/buckets/:id/schema"
+
+type bucketSchemaResponse struct {
+	influxdb.BucketSchema
+	Links map[string]string `json:"links"`
+}
+
+func newBucketSchemaResponse(s *influxdb.BucketSchema) *bucketSchemaResponse {
+	return &bucketSchemaResponse{
+		BucketSchema: *s,
+		Links: map[string]string{
+			"self":   bucketSchemaIDPath(s.BucketID),
+			"bucket": bucketIDPath(s.BucketID),
+		},
+	}
+}
+
+func bucketSchemaIDPath(id influxdb.ID) string {
+	return path.Join(bucketIDPath(id), "schema")
+}
+
+// decodeBucketSchemaID returns the ID of the bucket of the schema route.
+func decodeBucketSchemaID(ctx context.Context) (influxdb.ID, error) {
+	params := httprouter.ParamsFromContext(ctx)
+	id := params.ByName("id")
+	if id == "" {
+		return 0, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "url missing id",
+		}
+	}
+
+	var i influxdb.ID
+	if err := i.DecodeFromString(id); err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+// handleGetBucketSchema is the HTTP handler for the GET /api/v2/buckets/:id/schema route.
+func (h *BucketHandler) handleGetBucketSchema(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BucketHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	id, err := decodeBucketSchemaID(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	s, err := h.BucketSchemaService.FindBucketSchema(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Bucket schema retrieved", zap.String("bucketID", id.String()))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBucketSchemaResponse(s)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePutBucketSchema is the HTTP handler for the PUT /api/v2/buckets/:id/schema route.
+func (h *BucketHandler) handlePutBucketSchema(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BucketHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	id, err := decodeBucketSchemaID(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var s influxdb.BucketSchema
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}, w)
+		return
+	}
+	s.BucketID = id
+	if s.Action == "" {
+		s.Action = influxdb.SchemaActionReject
+	}
+
+	if err := h.BucketSchemaService.PutBucketSchema(ctx, &s); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Bucket schema updated", zap.String("bucketID", id.String()))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newBucketSchemaResponse(&s)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleDeleteBucketSchema is the HTTP handler for the DELETE /api/v2/buckets/:id/schema route.
+func (h *BucketHandler) handleDeleteBucketSchema(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "BucketHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	id, err := decodeBucketSchemaID(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.BucketSchemaService.DeleteBucketSchema(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Bucket schema deleted", zap.String("bucketID", id.String()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// BucketSchemaService connects to Influx via HTTP using tokens to manage the
+// schemas of buckets.
+type BucketSchemaService struct {
+	Client *httpc.Client
+}
+
+var _ influxdb.BucketSchemaService = (*BucketSchemaService)(nil)
+
+// FindBucketSchema returns the schema of a bucket.
+func (s *BucketSchemaService) FindBucketSchema(ctx context.Context, bucketID influxdb.ID) (*influxdb.BucketSchema, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var resp bucketSchemaResponse
+	err := s.Client.
+		Get(bucketSchemaIDPath(bucketID)).
+		DecodeJSON(&resp).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &resp.BucketSchema, nil
+}
+
+// PutBucketSchema sets the schema of the bucket bs.BucketID, replacing its
+// current schema.
+func (s *BucketSchemaService) PutBucketSchema(ctx context.Context, bs *influxdb.BucketSchema) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if !bs.BucketID.Valid() {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   influxdb.OpPutBucketSchema,
+			Msg:  fmt.Sprintf("invalid bucket ID %q", bs.BucketID),
+		}
+	}
+
+	var resp bucketSchemaResponse
+	err := s.Client.
+		PutJSON(bs, bucketSchemaIDPath(bs.BucketID)).
+		DecodeJSON(&resp).
+		Do(ctx)
+	if err != nil {
+		return err
+	}
+	*bs = resp.BucketSchema
+	return nil
+}
+
+// DeleteBucketSchema removes the schema of a bucket.
+func (s *BucketSchemaService) DeleteBucketSchema(ctx context.Context, bucketID influxdb.ID) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		Delete(bucketSchemaIDPath(bucketID)).
+		Do(ctx)
+}
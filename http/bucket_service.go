@@ -23,6 +23,7 @@ type BucketBackend struct {
 	influxdb.HTTPErrorHandler
 
 	BucketService              influxdb.BucketService
+	BucketSchemaService        influxdb.BucketSchemaService
 	BucketOperationLogService  influxdb.BucketOperationLogService
 	UserResourceMappingService influxdb.UserResourceMappingService
 	LabelService               influxdb.LabelService
@@ -37,6 +38,7 @@ func NewBucketBackend(log *zap.Logger, b *APIBackend) *BucketBackend {
 		log:              log,
 
 		BucketService:              b.BucketService,
+		BucketSchemaService:        b.BucketSchemaService,
 		BucketOperationLogService:  b.BucketOperationLogService,
 		UserResourceMappingService: b.UserResourceMappingService,
 		LabelService:               b.LabelService,
@@ -52,6 +54,7 @@ type BucketHandler struct {
 	log *zap.Logger
 
 	BucketService              influxdb.BucketService
+	BucketSchemaService        influxdb.BucketSchemaService
 	BucketOperationLogService  influxdb.BucketOperationLogService
 	UserResourceMappingService influxdb.UserResourceMappingService
 	LabelService               influxdb.LabelService
@@ -79,6 +82,7 @@ func NewBucketHandler(log *zap.Logger, b *BucketBackend) *BucketHandler {
 		log:              log,
 
 		BucketService:              b.BucketService,
+		BucketSchemaService:        b.BucketSchemaService,
 		BucketOperationLogService:  b.BucketOperationLogService,
 		UserResourceMappingService: b.UserResourceMappingService,
 		LabelService:               b.LabelService,
@@ -92,6 +96,9 @@ func NewBucketHandler(log *zap.Logger, b *BucketBackend) *BucketHandler {
 	h.HandlerFunc("GET", bucketsIDLogPath, h.handleGetBucketLog)
 	h.HandlerFunc("PATCH", bucketsIDPath, h.handlePatchBucket)
 	h.HandlerFunc("DELETE", bucketsIDPath, h.handleDeleteBucket)
+	h.HandlerFunc("GET", bucketsIDSchemaPath, h.handleGetBucketSchema)
+	h.HandlerFunc("PUT", bucketsIDSchemaPath, h.handlePutBucketSchema)
+	h.HandlerFunc("DELETE", bucketsIDSchemaPath, h.handleDeleteBucketSchema)
 
 	memberBackend := MemberBackend{
 		HTTPErrorHandler:           b.HTTPErrorHandler,
@@ -132,13 +139,18 @@ func NewBucketHandler(log *zap.Logger, b *BucketBackend) *BucketHandler {
 
 // bucket is used for serialization/deserialization with duration string syntax.
 type bucket struct {
-	ID                  influxdb.ID     `json:"id,omitempty"`
-	OrgID               influxdb.ID     `json:"orgID,omitempty"`
-	Type                string          `json:"type"`
-	Description         string          `json:"description,omitempty"`
-	Name                string          `json:"name"`
-	RetentionPolicyName string          `json:"rp,omitempty"` // This to support v1 sources
-	RetentionRules      []retentionRule `json:"retentionRules"`
+	ID                  influxdb.ID          `json:"id,omitempty"`
+	OrgID               influxdb.ID          `json:"orgID,omitempty"`
+	Type                string               `json:"type"`
+	Description         string               `json:"description,omitempty"`
+	Name                string               `json:"name"`
+	RetentionPolicyName string               `json:"rp,omitempty"` // This to support v1 sources
+	RetentionRules      []retentionRule      `json:"retentionRules"`
+	ColdStorageSeconds  int64                `json:"coldStorageSeconds,omitempty"`
+	RollupPolicies      []rollupPolicy       `json:"rollupPolicies,omitempty"`
+	Codecs              influxdb.BlockCodecs `json:"codecs,omitempty"`
+	MaxSeries           int64                `json:"maxSeries,omitempty"`
+	LastValueCache      bool                 `json:"lastValueCache,omitempty"`
 	influxdb.CRUDLog
 }
 
@@ -160,6 +172,64 @@ func (rr *retentionRule) RetentionPeriod() (time.Duration, error) {
 	return t, nil
 }
 
+// coldStoragePeriod returns the cold storage period of a bucket given in
+// seconds. A period of zero disables cold storage.
+func coldStoragePeriod(seconds int64) (time.Duration, error) {
+	if seconds < 0 {
+		return 0, &influxdb.Error{
+			Code: influxdb.EUnprocessableEntity,
+			Msg:  "cold storage seconds must not be negative",
+		}
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// rollupPolicy is a rollup policy of a bucket.
+type rollupPolicy struct {
+	EverySeconds   int64                     `json:"everySeconds"`
+	Functions      []influxdb.RollupFunction `json:"functions"`
+	TargetBucketID influxdb.ID               `json:"targetBucketID"`
+}
+
+func toRollupPolicies(rps []rollupPolicy) ([]influxdb.RollupPolicy, error) {
+	if len(rps) == 0 {
+		return nil, nil
+	}
+
+	ps := make([]influxdb.RollupPolicy, 0, len(rps))
+	for _, rp := range rps {
+		p := influxdb.RollupPolicy{
+			Every:          time.Duration(rp.EverySeconds) * time.Second,
+			Functions:      rp.Functions,
+			TargetBucketID: rp.TargetBucketID,
+		}
+		if err := p.Valid(); err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EUnprocessableEntity,
+				Err:  err,
+			}
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+func newRollupPolicies(ps []influxdb.RollupPolicy) []rollupPolicy {
+	if len(ps) == 0 {
+		return nil
+	}
+
+	rps := make([]rollupPolicy, 0, len(ps))
+	for _, p := range ps {
+		rps = append(rps, rollupPolicy{
+			EverySeconds:   int64(p.Every.Round(time.Second) / time.Second),
+			Functions:      p.Functions,
+			TargetBucketID: p.TargetBucketID,
+		})
+	}
+	return rps
+}
+
 func (b *bucket) toInfluxDB() (*influxdb.Bucket, error) {
 	if b == nil {
 		return nil, nil
@@ -178,6 +248,16 @@ func (b *bucket) toInfluxDB() (*influxdb.Bucket, error) {
 		}
 	}
 
+	cold, err := coldStoragePeriod(b.ColdStorageSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := toRollupPolicies(b.RollupPolicies)
+	if err != nil {
+		return nil, err
+	}
+
 	return &influxdb.Bucket{
 		ID:                  b.ID,
 		OrgID:               b.OrgID,
@@ -186,6 +266,11 @@ func (b *bucket) toInfluxDB() (*influxdb.Bucket, error) {
 		Name:                b.Name,
 		RetentionPolicyName: b.RetentionPolicyName,
 		RetentionPeriod:     d,
+		ColdStoragePeriod:   cold,
+		RollupPolicies:      rollups,
+		Codecs:              b.Codecs,
+		MaxSeries:           b.MaxSeries,
+		LastValueCache:      b.LastValueCache,
 		CRUDLog:             b.CRUDLog,
 	}, nil
 }
@@ -212,15 +297,25 @@ func newBucket(pb *influxdb.Bucket) *bucket {
 		Description:         pb.Description,
 		RetentionPolicyName: pb.RetentionPolicyName,
 		RetentionRules:      rules,
+		ColdStorageSeconds:  int64(pb.ColdStoragePeriod.Round(time.Second) / time.Second),
+		RollupPolicies:      newRollupPolicies(pb.RollupPolicies),
+		Codecs:              pb.Codecs,
+		MaxSeries:           pb.MaxSeries,
+		LastValueCache:      pb.LastValueCache,
 		CRUDLog:             pb.CRUDLog,
 	}
 }
 
 // bucketUpdate is used for serialization/deserialization with retention rules.
 type bucketUpdate struct {
-	Name           *string         `json:"name,omitempty"`
-	Description    *string         `json:"description,omitempty"`
-	RetentionRules []retentionRule `json:"retentionRules,omitempty"`
+	Name               *string               `json:"name,omitempty"`
+	Description        *string               `json:"description,omitempty"`
+	RetentionRules     []retentionRule       `json:"retentionRules,omitempty"`
+	ColdStorageSeconds *int64                `json:"coldStorageSeconds,omitempty"`
+	RollupPolicies     *[]rollupPolicy       `json:"rollupPolicies,omitempty"`
+	Codecs             *influxdb.BlockCodecs `json:"codecs,omitempty"`
+	MaxSeries          *int64                `json:"maxSeries,omitempty"`
+	LastValueCache     *bool                 `json:"lastValueCache,omitempty"`
 }
 
 func (b *bucketUpdate) toInfluxDB() (*influxdb.BucketUpdate, error) {
@@ -239,11 +334,29 @@ func (b *bucketUpdate) toInfluxDB() (*influxdb.BucketUpdate, error) {
 		}
 	}
 
-	return &influxdb.BucketUpdate{
+	upd := &influxdb.BucketUpdate{
 		Name:            b.Name,
 		Description:     b.Description,
 		RetentionPeriod: &d,
-	}, nil
+	}
+	if b.ColdStorageSeconds != nil {
+		cold, err := coldStoragePeriod(*b.ColdStorageSeconds)
+		if err != nil {
+			return nil, err
+		}
+		upd.ColdStoragePeriod = &cold
+	}
+	if b.RollupPolicies != nil {
+		rollups, err := toRollupPolicies(*b.RollupPolicies)
+		if err != nil {
+			return nil, err
+		}
+		upd.RollupPolicies = &rollups
+	}
+	upd.Codecs = b.Codecs
+	upd.MaxSeries = b.MaxSeries
+	upd.LastValueCache = b.LastValueCache
+	return upd, nil
 }
 
 func newBucketUpdate(pb *influxdb.BucketUpdate) *bucketUpdate {
@@ -264,6 +377,19 @@ func newBucketUpdate(pb *influxdb.BucketUpdate) *bucketUpdate {
 			EverySeconds: d,
 		})
 	}
+
+	if pb.ColdStoragePeriod != nil {
+		d := int64((*pb.ColdStoragePeriod).Round(time.Second) / time.Second)
+		up.ColdStorageSeconds = &d
+	}
+
+	if pb.RollupPolicies != nil {
+		rps := newRollupPolicies(*pb.RollupPolicies)
+		up.RollupPolicies = &rps
+	}
+	up.Codecs = pb.Codecs
+	up.MaxSeries = pb.MaxSeries
+	up.LastValueCache = pb.LastValueCache
 	return up
 }
 
@@ -346,11 +472,16 @@ func (h *BucketHandler) handlePostBucket(w http.ResponseWriter, r *http.Request)
 }
 
 type postBucketRequest struct {
-	OrgID               influxdb.ID     `json:"orgID,omitempty"`
-	Name                string          `json:"name"`
-	Description         string          `json:"description"`
-	RetentionPolicyName string          `json:"rp,omitempty"` // This to support v1 sources
-	RetentionRules      []retentionRule `json:"retentionRules"`
+	OrgID               influxdb.ID          `json:"orgID,omitempty"`
+	Name                string               `json:"name"`
+	Description         string               `json:"description"`
+	RetentionPolicyName string               `json:"rp,omitempty"` // This to support v1 sources
+	RetentionRules      []retentionRule      `json:"retentionRules"`
+	ColdStorageSeconds  int64                `json:"coldStorageSeconds,omitempty"`
+	RollupPolicies      []rollupPolicy       `json:"rollupPolicies,omitempty"`
+	Codecs              influxdb.BlockCodecs `json:"codecs,omitempty"`
+	MaxSeries           int64                `json:"maxSeries,omitempty"`
+	LastValueCache      bool                 `json:"lastValueCache,omitempty"`
 }
 
 func (b postBucketRequest) Validate() error {
@@ -376,6 +507,16 @@ func (b postBucketRequest) toInfluxDB() (*influxdb.Bucket, error) {
 		}
 	}
 
+	cold, err := coldStoragePeriod(b.ColdStorageSeconds)
+	if err != nil {
+		return nil, err
+	}
+
+	rollups, err := toRollupPolicies(b.RollupPolicies)
+	if err != nil {
+		return nil, err
+	}
+
 	return &influxdb.Bucket{
 		OrgID:               b.OrgID,
 		Description:         b.Description,
@@ -383,7 +524,12 @@ func (b postBucketRequest) toInfluxDB() (*influxdb.Bucket, error) {
 		Type:                influxdb.BucketTypeUser,
 		RetentionPolicyName: b.RetentionPolicyName,
 		RetentionPeriod:     dur,
-	}, err
+		ColdStoragePeriod:   cold,
+		RollupPolicies:      rollups,
+		Codecs:              b.Codecs,
+		MaxSeries:           b.MaxSeries,
+		LastValueCache:      b.LastValueCache,
+	}, nil
 }
 
 func decodePostBucketRequest(ctx context.Context, r *http.Request) (*postBucketRequest, error) {
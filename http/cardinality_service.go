@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const prefixCardinality = "/api/v2/storage/cardinality"
+
+// CardinalityBackend is all services and associated parameters required to
+// construct the CardinalityHandler.
+type CardinalityBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	CardinalityService influxdb.CardinalityService
+}
+
+// NewCardinalityBackend returns a new instance of CardinalityBackend.
+func NewCardinalityBackend(log *zap.Logger, b *APIBackend) *CardinalityBackend {
+	return &CardinalityBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		CardinalityService: b.CardinalityService,
+	}
+}
+
+// CardinalityHandler is the handler for exploring the series cardinality of
+// the data stored by the storage engine.
+type CardinalityHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	CardinalityService influxdb.CardinalityService
+}
+
+// NewCardinalityHandler returns a new instance of CardinalityHandler.
+func NewCardinalityHandler(log *zap.Logger, b *CardinalityBackend) *CardinalityHandler {
+	h := &CardinalityHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		CardinalityService: b.CardinalityService,
+	}
+
+	h.HandlerFunc("GET", prefixCardinality, h.handleGetCardinality)
+
+	return h
+}
+
+type cardinalityResponse struct {
+	Buckets []*influxdb.BucketCardinality `json:"buckets"`
+}
+
+// handleGetCardinality is the HTTP handler for the GET /api/v2/storage/cardinality route.
+func (h *CardinalityHandler) handleGetCardinality(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "CardinalityHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	filter, err := decodeCardinalityFilter(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	buckets, err := h.CardinalityService.FindCardinality(ctx, filter)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Cardinality retrieved", zap.Int("buckets", len(buckets)))
+
+	if buckets == nil {
+		buckets = []*influxdb.BucketCardinality{}
+	}
+	if err := encodeResponse(ctx, w, http.StatusOK, cardinalityResponse{Buckets: buckets}); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+func decodeCardinalityFilter(r *http.Request) (influxdb.CardinalityFilter, error) {
+	var filter influxdb.CardinalityFilter
+	qp := r.URL.Query()
+
+	if id := qp.Get("orgID"); id != "" {
+		orgID, err := influxdb.IDFromString(id)
+		if err != nil {
+			return filter, err
+		}
+		filter.OrgID = *orgID
+	}
+
+	if id := qp.Get("bucketID"); id != "" {
+		bucketID, err := influxdb.IDFromString(id)
+		if err != nil {
+			return filter, err
+		}
+		filter.BucketID = bucketID
+	}
+
+	if m := qp.Get("measurement"); m != "" {
+		filter.Measurement = &m
+	}
+
+	if n := qp.Get("topValues"); n != "" {
+		topValues, err := strconv.Atoi(n)
+		if err != nil || topValues < 1 {
+			return filter, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "topValues must be a positive integer",
+			}
+		}
+		filter.TopValues = topValues
+	}
+
+	return filter, nil
+}
+
+var _ influxdb.CardinalityService = (*CardinalityService)(nil)
+
+// CardinalityService connects to Influx via HTTP using tokens to explore the
+// series cardinality of the data stored by the storage engine.
+type CardinalityService struct {
+	Client *httpc.Client
+}
+
+// FindCardinality returns the cardinality of the buckets matching filter.
+func (s *CardinalityService) FindCardinality(ctx context.Context, filter influxdb.CardinalityFilter) ([]*influxdb.BucketCardinality, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var params [][2]string
+	if filter.OrgID.Valid() {
+		params = append(params, [2]string{"orgID", filter.OrgID.String()})
+	}
+	if filter.BucketID != nil {
+		params = append(params, [2]string{"bucketID", filter.BucketID.String()})
+	}
+	if filter.Measurement != nil {
+		params = append(params, [2]string{"measurement", *filter.Measurement})
+	}
+	if filter.TopValues > 0 {
+		params = append(params, [2]string{"topValues", strconv.Itoa(filter.TopValues)})
+	}
+
+	var resp cardinalityResponse
+	err := s.Client.
+		Get(prefixCardinality).
+		QueryParams(params...).
+		DecodeJSON(&resp).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Buckets, nil
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixCompactions     = "/api/v2/storage/compactions"
+	compactionIDPath      = prefixCompactions + "/:id"
+	compactionsPausePath  = prefixCompactions + "/pause"
+	compactionsResumePath = prefixCompactions + "/resume"
+)
+
+// CompactionBackend is all services and associated parameters required to
+// construct the CompactionHandler.
+type CompactionBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	CompactionService influxdb.CompactionService
+}
+
+// NewCompactionBackend returns a new instance of CompactionBackend.
+func NewCompactionBackend(log *zap.Logger, b *APIBackend) *CompactionBackend {
+	return &CompactionBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		CompactionService: b.CompactionService,
+	}
+}
+
+// CompactionHandler is the handler for observing and controlling the
+// compactions of the storage engine.
+type CompactionHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	CompactionService influxdb.CompactionService
+}
+
+// NewCompactionHandler returns a new instance of CompactionHandler.
+func NewCompactionHandler(log *zap.Logger, b *CompactionBackend) *CompactionHandler {
+	h := &CompactionHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		CompactionService: b.CompactionService,
+	}
+
+	h.HandlerFunc("GET", prefixCompactions, h.handleGetCompactions)
+	h.HandlerFunc("POST", prefixCompactions, h.handlePostCompaction)
+	h.HandlerFunc("POST", compactionsPausePath, h.handlePauseCompactions)
+	h.HandlerFunc("POST", compactionsResumePath, h.handleResumeCompactions)
+	h.HandlerFunc("DELETE", compactionIDPath, h.handleDeleteCompaction)
+
+	return h
+}
+
+// compactBucketRequest is the request body of the POST /api/v2/storage/compactions route.
+type compactBucketRequest struct {
+	OrgID    influxdb.ID `json:"orgID"`
+	BucketID influxdb.ID `json:"bucketID"`
+	Optimize bool        `json:"optimize"`
+}
+
+type compactionsResponse struct {
+	Compactions []*influxdb.Compaction `json:"compactions"`
+}
+
+// handleGetCompactions is the HTTP handler for the GET /api/v2/storage/compactions route.
+func (h *CompactionHandler) handleGetCompactions(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "CompactionHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	status, err := h.CompactionService.FindCompactions(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Compactions retrieved", zap.Int("compactions", len(status.Compactions)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, status); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePostCompaction is the HTTP handler for the POST /api/v2/storage/compactions route.
+func (h *CompactionHandler) handlePostCompaction(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "CompactionHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	var req compactBucketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid compaction request",
+			Err:  err,
+		}, w)
+		return
+	}
+	if !req.OrgID.Valid() || !req.BucketID.Valid() {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "orgID and bucketID are required",
+		}, w)
+		return
+	}
+
+	compactions, err := h.CompactionService.CompactBucket(ctx, req.OrgID, req.BucketID, req.Optimize)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Bucket compactions scheduled", zap.String("bucketID", req.BucketID.String()), zap.Int("compactions", len(compactions)))
+
+	if err := encodeResponse(ctx, w, http.StatusAccepted, compactionsResponse{Compactions: compactions}); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePauseCompactions is the HTTP handler for the POST /api/v2/storage/compactions/pause route.
+func (h *CompactionHandler) handlePauseCompactions(w http.ResponseWriter, r *http.Request) {
+	h.setCompactionsPaused(w, r, true)
+}
+
+// handleResumeCompactions is the HTTP handler for the POST /api/v2/storage/compactions/resume route.
+func (h *CompactionHandler) handleResumeCompactions(w http.ResponseWriter, r *http.Request) {
+	h.setCompactionsPaused(w, r, false)
+}
+
+func (h *CompactionHandler) setCompactionsPaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "CompactionHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	if err := h.CompactionService.SetCompactionsPaused(ctx, paused); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Compactions paused", zap.Bool("paused", paused))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteCompaction is the HTTP handler for the DELETE /api/v2/storage/compactions/:id route.
+func (h *CompactionHandler) handleDeleteCompaction(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "CompactionHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	id, err := strconv.Atoi(params.ByName("id"))
+	if err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid compaction id",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.CompactionService.CancelCompaction(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Compaction cancelled", zap.Int("compactionID", id))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ influxdb.CompactionService = (*CompactionService)(nil)
+
+// CompactionService connects to Influx via HTTP using tokens to observe and
+// control the compactions of the storage engine.
+type CompactionService struct {
+	Client *httpc.Client
+}
+
+// FindCompactions returns the running compactions, followed by the planned compactions.
+func (s *CompactionService) FindCompactions(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var status influxdb.CompactionStatus
+	err := s.Client.
+		Get(prefixCompactions).
+		DecodeJSON(&status).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// CompactBucket schedules compactions of the files holding data of a bucket.
+func (s *CompactionService) CompactBucket(ctx context.Context, orgID, bucketID influxdb.ID, optimize bool) ([]*influxdb.Compaction, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	req := compactBucketRequest{
+		OrgID:    orgID,
+		BucketID: bucketID,
+		Optimize: optimize,
+	}
+
+	var resp compactionsResponse
+	err := s.Client.
+		PostJSON(req, prefixCompactions).
+		DecodeJSON(&resp).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Compactions, nil
+}
+
+// SetCompactionsPaused pauses or resumes compactions.
+func (s *CompactionService) SetCompactionsPaused(ctx context.Context, paused bool) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	path := compactionsResumePath
+	if paused {
+		path = compactionsPausePath
+	}
+	return s.Client.
+		Post(nil, path).
+		Do(ctx)
+}
+
+// CancelCompaction aborts the running compaction with the given ID.
+func (s *CompactionService) CancelCompaction(ctx context.Context, id int) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		Delete(prefixCompactions, fmt.Sprint(id)).
+		Do(ctx)
+}
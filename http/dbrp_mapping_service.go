@@ -0,0 +1,289 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const prefixDBRPs = "/api/v2/dbrps"
+
+// DBRPMappingBackend is all services and associated parameters required to
+// construct the DBRPMappingHandler.
+type DBRPMappingBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	DBRPMappingService influxdb.DBRPMappingService
+	BucketService      influxdb.BucketService
+}
+
+// NewDBRPMappingBackend returns a new instance of DBRPMappingBackend.
+func NewDBRPMappingBackend(log *zap.Logger, b *APIBackend) *DBRPMappingBackend {
+	return &DBRPMappingBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		DBRPMappingService: b.DBRPMappingService,
+		BucketService:      b.BucketService,
+	}
+}
+
+// DBRPMappingHandler is the handler of the mappings of the databases and
+// retention policies of the 1.x API to buckets.
+type DBRPMappingHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	DBRPMappingService influxdb.DBRPMappingService
+	BucketService      influxdb.BucketService
+}
+
+// NewDBRPMappingHandler returns a new instance of DBRPMappingHandler.
+func NewDBRPMappingHandler(log *zap.Logger, b *DBRPMappingBackend) *DBRPMappingHandler {
+	h := &DBRPMappingHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		DBRPMappingService: b.DBRPMappingService,
+		BucketService:      b.BucketService,
+	}
+
+	h.HandlerFunc("GET", prefixDBRPs, h.handleGetDBRPs)
+	h.HandlerFunc("POST", prefixDBRPs, h.handlePostDBRP)
+	h.HandlerFunc("DELETE", prefixDBRPs, h.handleDeleteDBRP)
+
+	return h
+}
+
+type dbrpsResponse struct {
+	DBRPs []*influxdb.DBRPMapping `json:"dbrps"`
+	Links map[string]string       `json:"links"`
+}
+
+func newDBRPsResponse(ms []*influxdb.DBRPMapping) *dbrpsResponse {
+	return &dbrpsResponse{
+		DBRPs: ms,
+		Links: map[string]string{
+			"self": prefixDBRPs,
+		},
+	}
+}
+
+// decodeDBRPMappingFilter returns the filter in the query parameters of r.
+// The cluster defaults to the one of the local instance.
+func decodeDBRPMappingFilter(r *http.Request) (influxdb.DBRPMappingFilter, error) {
+	qp := r.URL.Query()
+
+	cluster := qp.Get("cluster")
+	if cluster == "" {
+		cluster = influxdb.DefaultDBRPMappingCluster
+	}
+	filter := influxdb.DBRPMappingFilter{Cluster: &cluster}
+
+	if db := qp.Get("db"); db != "" {
+		filter.Database = &db
+	}
+	if rp := qp.Get("rp"); rp != "" {
+		filter.RetentionPolicy = &rp
+	}
+	if s := qp.Get("default"); s != "" {
+		isDefault, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "default must be a boolean",
+			}
+		}
+		filter.Default = &isDefault
+	}
+	return filter, nil
+}
+
+// handleGetDBRPs is the HTTP handler for the GET /api/v2/dbrps route.
+func (h *DBRPMappingHandler) handleGetDBRPs(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "DBRPMappingHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	filter, err := decodeDBRPMappingFilter(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	ms, _, err := h.DBRPMappingService.FindMany(ctx, filter)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("DBRP mappings retrieved", zap.String("dbrps", fmt.Sprint(ms)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newDBRPsResponse(ms)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePostDBRP is the HTTP handler for the POST /api/v2/dbrps route. The
+// organization of the mapping is the one of its bucket.
+func (h *DBRPMappingHandler) handlePostDBRP(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "DBRPMappingHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	m := &influxdb.DBRPMapping{}
+	if err := json.NewDecoder(r.Body).Decode(m); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid dbrp mapping",
+			Err:  err,
+		}, w)
+		return
+	}
+	if m.Cluster == "" {
+		m.Cluster = influxdb.DefaultDBRPMappingCluster
+	}
+
+	b, err := h.BucketService.FindBucketByID(ctx, m.BucketID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	m.OrganizationID = b.OrgID
+
+	if err := h.DBRPMappingService.Create(ctx, m); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("DBRP mapping created", zap.String("dbrp", fmt.Sprint(m)))
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, m); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleDeleteDBRP is the HTTP handler for the DELETE /api/v2/dbrps route.
+func (h *DBRPMappingHandler) handleDeleteDBRP(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "DBRPMappingHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	filter, err := decodeDBRPMappingFilter(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	if filter.Database == nil || filter.RetentionPolicy == nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "db and rp are required",
+		}, w)
+		return
+	}
+
+	if err := h.DBRPMappingService.Delete(ctx, *filter.Cluster, *filter.Database, *filter.RetentionPolicy); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("DBRP mapping deleted", zap.String("db", *filter.Database), zap.String("rp", *filter.RetentionPolicy))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ influxdb.DBRPMappingService = (*DBRPMappingService)(nil)
+
+// DBRPMappingService connects to Influx via HTTP using tokens to manage the
+// mappings of databases and retention policies to buckets.
+type DBRPMappingService struct {
+	Client *httpc.Client
+}
+
+// FindBy returns the dbrp mapping of the cluster, database and retention policy.
+func (s *DBRPMappingService) FindBy(ctx context.Context, cluster, db, rp string) (*influxdb.DBRPMapping, error) {
+	return s.Find(ctx, influxdb.DBRPMappingFilter{
+		Cluster:         &cluster,
+		Database:        &db,
+		RetentionPolicy: &rp,
+	})
+}
+
+// Find returns the first dbrp mapping matching the filter.
+func (s *DBRPMappingService) Find(ctx context.Context, filter influxdb.DBRPMappingFilter) (*influxdb.DBRPMapping, error) {
+	ms, n, err := s.FindMany(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, influxdb.ErrDBRPMappingNotFound
+	}
+	return ms[0], nil
+}
+
+// FindMany returns the dbrp mappings matching the filter, and their count.
+func (s *DBRPMappingService) FindMany(ctx context.Context, filter influxdb.DBRPMappingFilter, opt ...influxdb.FindOptions) ([]*influxdb.DBRPMapping, int, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var params [][2]string
+	if filter.Cluster != nil {
+		params = append(params, [2]string{"cluster", *filter.Cluster})
+	}
+	if filter.Database != nil {
+		params = append(params, [2]string{"db", *filter.Database})
+	}
+	if filter.RetentionPolicy != nil {
+		params = append(params, [2]string{"rp", *filter.RetentionPolicy})
+	}
+	if filter.Default != nil {
+		params = append(params, [2]string{"default", strconv.FormatBool(*filter.Default)})
+	}
+
+	var res dbrpsResponse
+	err := s.Client.
+		Get(prefixDBRPs).
+		QueryParams(params...).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.DBRPs, len(res.DBRPs), nil
+}
+
+// Create creates a dbrp mapping, mapping to a bucket of its organization.
+func (s *DBRPMappingService) Create(ctx context.Context, m *influxdb.DBRPMapping) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		PostJSON(m, prefixDBRPs).
+		DecodeJSON(m).
+		Do(ctx)
+}
+
+// Delete removes the dbrp mapping of the cluster, database and retention policy.
+func (s *DBRPMappingService) Delete(ctx context.Context, cluster, db, rp string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		Delete(prefixDBRPs).
+		QueryParams(
+			[2]string{"cluster", cluster},
+			[2]string{"db", db},
+			[2]string{"rp", rp},
+		).
+		Do(ctx)
+}
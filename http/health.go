@@ -1,8 +1,12 @@
 package http
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
+
+	"github.com/influxdata/influxdb/kit/check"
 )
 
 // HealthHandler returns the status of the process.
@@ -12,3 +16,41 @@ func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	fmt.Fprintln(w, msg)
 }
+
+// NewHealthHandler returns a handler reporting the status of the process and
+// of checks. The process is reported unhealthy if any check fails, while
+// warnings of checks are reported with the process healthy.
+func NewHealthHandler(checks ...check.Checker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp := check.Response{
+			Name:    "influxdb",
+			Message: "ready for queries and writes",
+			Status:  check.StatusPass,
+			Checks:  make(check.Responses, 0, len(checks)),
+		}
+		for _, c := range checks {
+			res := c.Check(r.Context())
+			if nc, ok := c.(check.NamedChecker); ok {
+				res.Name = nc.CheckName()
+			}
+			switch {
+			case res.Status == check.StatusFail:
+				resp.Status = check.StatusFail
+				resp.Message = "one or more checks failed"
+			case res.Status == check.StatusWarn && resp.Status == check.StatusPass:
+				resp.Status = check.StatusWarn
+				resp.Message = "one or more checks have warnings"
+			}
+			resp.Checks = append(resp.Checks, res)
+		}
+		sort.Sort(resp.Checks)
+
+		code := http.StatusOK
+		if resp.Status == check.StatusFail {
+			code = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	})
+}
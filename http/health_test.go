@@ -1,10 +1,15 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+
+	"github.com/influxdata/influxdb/kit/check"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -53,3 +58,57 @@ func TestHealthHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestNewHealthHandler(t *testing.T) {
+	warn := check.NamedFunc("storage", func(context.Context) check.Response {
+		return check.Warn("corrupt files quarantined")
+	})
+	fail := check.NamedFunc("query", func(context.Context) check.Response {
+		return check.Error(errors.New("unavailable"))
+	})
+
+	tests := []struct {
+		name       string
+		checks     []check.Checker
+		statusCode int
+		status     check.Status
+	}{
+		{
+			name:       "passing checks",
+			statusCode: http.StatusOK,
+			status:     check.StatusPass,
+		},
+		{
+			name:       "warnings are healthy",
+			checks:     []check.Checker{warn},
+			statusCode: http.StatusOK,
+			status:     check.StatusWarn,
+		},
+		{
+			name:       "failures are unhealthy",
+			checks:     []check.Checker{fail, warn},
+			statusCode: http.StatusServiceUnavailable,
+			status:     check.StatusFail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			NewHealthHandler(tt.checks...).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if w.Code != tt.statusCode {
+				t.Errorf("unexpected status code: got %d want %d", w.Code, tt.statusCode)
+			}
+			var resp check.Response
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.status {
+				t.Errorf("unexpected status: got %s want %s", resp.Status, tt.status)
+			}
+			if len(resp.Checks) != len(tt.checks) {
+				t.Errorf("unexpected checks: got %d want %d", len(resp.Checks), len(tt.checks))
+			}
+		})
+	}
+}
@@ -0,0 +1,548 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/NYTimes/gziphandler"
+	"github.com/influxdata/flux/iocounter"
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/authorizer"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/http/metric"
+	"github.com/influxdata/influxdb/kit/tracing"
+	kithttp "github.com/influxdata/influxdb/kit/transport/http"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/influxql"
+	"github.com/influxdata/influxdb/storage"
+	"go.uber.org/zap"
+)
+
+// Routes of the influxdb 1.x API.
+const (
+	prefixLegacyWrite = "/write"
+	prefixLegacyQuery = "/query"
+	prefixLegacyPing  = "/ping"
+)
+
+const (
+	// legacyDefaultRetentionPolicy is the retention policy of the buckets
+	// named after databases without dbrp mappings, when none is given.
+	legacyDefaultRetentionPolicy = "autogen"
+
+	// legacyDefaultChunkSize is the number of values of a series in each
+	// chunk of chunked query responses, unless set by the request.
+	legacyDefaultChunkSize = 10000
+)
+
+// LegacyBackend is all services and associated parameters required to
+// construct the LegacyHandler.
+type LegacyBackend struct {
+	influxdb.HTTPErrorHandler
+	log                *zap.Logger
+	WriteEventRecorder metric.EventRecorder
+	QueryEventRecorder metric.EventRecorder
+
+	MaxBatchSizeBytes    int64
+	WriteParserMaxBytes  int
+	WriteParserMaxLines  int
+	WriteParserMaxValues int
+
+	AuthorizationService influxdb.AuthorizationService
+	UserService          influxdb.UserService
+	BucketService        influxdb.BucketService
+	DBRPMappingService   influxdb.DBRPMappingService
+	PointsWriter         storage.PointsWriter
+	ProxyQueryService    query.ProxyQueryService
+}
+
+// NewLegacyBackend returns a new instance of LegacyBackend.
+func NewLegacyBackend(log *zap.Logger, b *APIBackend) *LegacyBackend {
+	return &LegacyBackend{
+		HTTPErrorHandler:   b.HTTPErrorHandler,
+		log:                log,
+		WriteEventRecorder: b.WriteEventRecorder,
+		QueryEventRecorder: b.QueryEventRecorder,
+
+		MaxBatchSizeBytes:    b.MaxBatchSizeBytes,
+		WriteParserMaxBytes:  b.WriteParserMaxBytes,
+		WriteParserMaxLines:  b.WriteParserMaxLines,
+		WriteParserMaxValues: b.WriteParserMaxValues,
+
+		AuthorizationService: b.AuthorizationService,
+		UserService:          b.UserService,
+		BucketService:        b.BucketService,
+		DBRPMappingService:   b.DBRPMappingService,
+		PointsWriter:         b.PointsWriter,
+		ProxyQueryService:    b.InfluxQLService,
+	}
+}
+
+// LegacyHandler serves the /write, /query and /ping routes of the influxdb
+// 1.x API. Databases and retention policies are resolved to buckets through
+// dbrp mappings, or else to the bucket named db/rp of the organization of the
+// token. Clients authenticate with a token as the password of the basic
+// authentication or of the p parameter, or with a token authorization header.
+type LegacyHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	AuthorizationService influxdb.AuthorizationService
+	UserService          influxdb.UserService
+	BucketService        influxdb.BucketService
+	DBRPMappingService   influxdb.DBRPMappingService
+	ProxyQueryService    query.ProxyQueryService
+
+	QueryEventRecorder metric.EventRecorder
+
+	// writeHandler parses and writes the points of the /write route.
+	writeHandler *WriteHandler
+}
+
+// NewLegacyHandler returns a new instance of LegacyHandler.
+func NewLegacyHandler(log *zap.Logger, b *LegacyBackend) *LegacyHandler {
+	h := &LegacyHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		AuthorizationService: b.AuthorizationService,
+		UserService:          b.UserService,
+		BucketService:        b.BucketService,
+		DBRPMappingService:   b.DBRPMappingService,
+		ProxyQueryService:    b.ProxyQueryService,
+		QueryEventRecorder:   b.QueryEventRecorder,
+
+		writeHandler: NewWriteHandler(log, &WriteBackend{
+			HTTPErrorHandler:   b.HTTPErrorHandler,
+			log:                log,
+			WriteEventRecorder: b.WriteEventRecorder,
+			PointsWriter:       b.PointsWriter,
+			BucketService:      b.BucketService,
+		},
+			WithMaxBatchSizeBytes(b.MaxBatchSizeBytes),
+			WithParserMaxBytes(b.WriteParserMaxBytes),
+			WithParserMaxLines(b.WriteParserMaxLines),
+			WithParserMaxValues(b.WriteParserMaxValues),
+		),
+	}
+
+	h.HandlerFunc("POST", prefixLegacyWrite, h.handleWrite)
+
+	// query reponses can optionally be gzip encoded
+	qh := gziphandler.GzipHandler(http.HandlerFunc(h.handleQuery))
+	h.Handler("GET", prefixLegacyQuery, qh)
+	h.Handler("POST", prefixLegacyQuery, qh)
+
+	h.HandlerFunc("GET", prefixLegacyPing, h.handlePing)
+	h.HandlerFunc("HEAD", prefixLegacyPing, h.handlePing)
+
+	return h
+}
+
+// ServeHTTP sets the version header of influxdb 1.x responses and serves the
+// request.
+func (h *LegacyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Influxdb-Build", "OSS")
+	w.Header().Set("X-Influxdb-Version", influxdb.GetBuildInfo().Version)
+	h.Router.ServeHTTP(w, r)
+}
+
+// handlePing is the HTTP handler for the GET /ping route.
+func (h *LegacyHandler) handlePing(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleWrite is the HTTP handler for the POST /write route.
+func (h *LegacyHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "LegacyHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	defer r.Body.Close()
+
+	var (
+		orgID        influxdb.ID
+		requestBytes int
+		sw           = kithttp.NewStatusResponseWriter(w)
+	)
+	w = sw
+	defer func() {
+		h.writeHandler.EventRecorder.Record(ctx, metric.Event{
+			OrgID:         orgID,
+			Endpoint:      r.URL.Path,
+			RequestBytes:  requestBytes,
+			ResponseBytes: sw.ResponseBytes(),
+			Status:        sw.Code(),
+		})
+	}()
+
+	a, err := h.authorize(ctx, r)
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+	ctx = pcontext.SetAuthorizer(ctx, a)
+
+	qp := r.URL.Query()
+	db, rp := qp.Get("db"), qp.Get("rp")
+	if db == "" {
+		h.handleLegacyError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "database is required",
+		}, w)
+		return
+	}
+
+	precision, err := decodeLegacyPrecision(qp.Get("precision"))
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+
+	log := h.log.With(zap.String("db", db), zap.String("rp", rp))
+
+	m, err := h.findBucket(ctx, a, db, rp)
+	if err != nil {
+		log.Info("Failed to find bucket", zap.Error(err))
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+	orgID = m.OrganizationID
+	span.LogKV("org_id", m.OrganizationID, "bucket_id", m.BucketID)
+
+	p, err := influxdb.NewPermissionAtID(m.BucketID, influxdb.WriteAction, influxdb.BucketsResourceType, m.OrganizationID)
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+	if !a.Allowed(*p) {
+		h.handleLegacyError(ctx, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "insufficient permissions for write",
+		}, w)
+		return
+	}
+
+	log = log.With(zap.String("org_id", m.OrganizationID.String()), zap.String("bucket_id", m.BucketID.String()))
+	bucket, err := h.BucketService.FindBucketByID(ctx, m.BucketID)
+	if err != nil {
+		log.Info("Failed to find bucket", zap.Error(err))
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+
+	requestBytes, err = h.writeHandler.writePoints(ctx, log, r, bucket, precision)
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeLegacyPrecision returns the precision of the timestamps of a 1.x
+// write.
+func decodeLegacyPrecision(p string) (string, error) {
+	switch p {
+	case "", "n":
+		p = "ns"
+	case "u":
+		p = "us"
+	}
+
+	if !models.ValidPrecision(p) {
+		return "", &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid precision; valid precision units are n, ns, u, us, ms, and s",
+		}
+	}
+	return p, nil
+}
+
+// findBucket returns the mapping of the database and retention policy of a
+// 1.x write. Without dbrp mapping, the bucket named db/rp of the organization
+// of the authorization is written to, with the retention policy defaulting to
+// autogen.
+func (h *LegacyHandler) findBucket(ctx context.Context, a *influxdb.Authorization, db, rp string) (*influxdb.DBRPMapping, error) {
+	cluster := influxdb.DefaultDBRPMappingCluster
+
+	var (
+		m   *influxdb.DBRPMapping
+		err error
+	)
+	if rp == "" {
+		isDefault := true
+		m, err = h.DBRPMappingService.Find(ctx, influxdb.DBRPMappingFilter{
+			Cluster:  &cluster,
+			Database: &db,
+			Default:  &isDefault,
+		})
+	} else {
+		m, err = h.DBRPMappingService.FindBy(ctx, cluster, db, rp)
+	}
+	if err == nil {
+		return m, nil
+	} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+		return nil, err
+	}
+
+	if rp == "" {
+		rp = legacyDefaultRetentionPolicy
+	}
+	name := db + "/" + rp
+	b, err := h.BucketService.FindBucket(ctx, influxdb.BucketFilter{
+		OrganizationID: &a.OrgID,
+		Name:           &name,
+	})
+	if influxdb.ErrorCode(err) == influxdb.ENotFound {
+		return nil, &influxdb.Error{
+			Code: influxdb.ENotFound,
+			Msg:  fmt.Sprintf("database not found: %q", db),
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &influxdb.DBRPMapping{
+		Cluster:         cluster,
+		Database:        db,
+		RetentionPolicy: rp,
+		OrganizationID:  b.OrgID,
+		BucketID:        b.ID,
+	}, nil
+}
+
+// handleQuery is the HTTP handler for the GET and POST /query routes.
+func (h *LegacyHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "LegacyHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+
+	var (
+		orgID influxdb.ID
+		sw    = kithttp.NewStatusResponseWriter(w)
+	)
+	w = sw
+	defer func() {
+		h.QueryEventRecorder.Record(ctx, metric.Event{
+			OrgID:         orgID,
+			Endpoint:      r.URL.Path,
+			ResponseBytes: sw.ResponseBytes(),
+			Status:        sw.Code(),
+		})
+	}()
+
+	a, err := h.authorize(ctx, r)
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+	ctx = pcontext.SetAuthorizer(ctx, a)
+	orgID = a.OrgID
+
+	// Unlike writes, queries only resolve the mappings of readable buckets.
+	dbrps := authorizer.NewDBRPMappingService(h.DBRPMappingService)
+	req, err := decodeLegacyQueryRequest(r, a, dbrps)
+	if err != nil {
+		h.handleLegacyError(ctx, err, w)
+		return
+	}
+	req.Request.Source = r.Header.Get("User-Agent")
+
+	dialect := req.Dialect.(*influxql.Dialect)
+	dialect.SetHeaders(w)
+
+	cw := iocounter.Writer{Writer: w}
+	if dialect.ChunkSize > 0 {
+		// Chunks are sent as soon as they are encoded.
+		cw.Writer = &legacyFlushWriter{w: w}
+	}
+	if _, err := h.ProxyQueryService.Query(ctx, &cw, req); err != nil {
+		if cw.Count() == 0 {
+			// Only record the error headers IFF nothing has been written to w.
+			h.handleLegacyError(ctx, err, w)
+			return
+		}
+		_ = tracing.LogError(span, err)
+		h.log.Info("Error writing response to client",
+			zap.String("handler", "legacy_query"),
+			zap.Error(err),
+		)
+	}
+}
+
+// decodeLegacyQueryRequest returns the request of the InfluxQL query of a 1.x
+// query request, with the parameters of its response.
+func decodeLegacyQueryRequest(r *http.Request, a *influxdb.Authorization, dbrps influxdb.DBRPMappingService) (*query.ProxyRequest, error) {
+	q := strings.TrimSpace(r.FormValue("q"))
+	if q == "" {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  `missing required parameter "q"`,
+		}
+	}
+
+	dialect := &influxql.Dialect{Encoding: influxql.JSON}
+	if r.FormValue("pretty") == "true" {
+		dialect.Encoding = influxql.JSONPretty
+	}
+
+	switch epoch := r.FormValue("epoch"); epoch {
+	case "":
+		dialect.TimeFormat = influxql.RFC3339Nano
+	case "h":
+		dialect.TimeFormat = influxql.Hour
+	case "m":
+		dialect.TimeFormat = influxql.Minute
+	case "s":
+		dialect.TimeFormat = influxql.Second
+	case "ms":
+		dialect.TimeFormat = influxql.Millisecond
+	case "u", "µ":
+		dialect.TimeFormat = influxql.Microsecond
+	case "n", "ns":
+		dialect.TimeFormat = influxql.Nanosecond
+	default:
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  fmt.Sprintf("invalid epoch %q", epoch),
+		}
+	}
+
+	if chunked, _ := strconv.ParseBool(r.FormValue("chunked")); chunked {
+		dialect.ChunkSize = legacyDefaultChunkSize
+		if s := r.FormValue("chunk_size"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n <= 0 {
+				return nil, &influxdb.Error{
+					Code: influxdb.EInvalid,
+					Msg:  "chunk_size must be a positive integer",
+				}
+			}
+			dialect.ChunkSize = n
+		}
+	}
+
+	compiler := influxql.NewCompiler(dbrps)
+	compiler.Cluster = influxdb.DefaultDBRPMappingCluster
+	compiler.DB = r.FormValue("db")
+	compiler.RP = r.FormValue("rp")
+	compiler.Query = q
+	compiler.FallbackToDBRP = true
+
+	return &query.ProxyRequest{
+		Request: query.Request{
+			Authorization:  a,
+			OrganizationID: a.OrgID,
+			Compiler:       compiler,
+		},
+		Dialect: dialect,
+	}, nil
+}
+
+// authorize returns the authorization of the token of a 1.x request.
+func (h *LegacyHandler) authorize(ctx context.Context, r *http.Request) (*influxdb.Authorization, error) {
+	token, err := legacyToken(r)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "unable to parse authentication credentials",
+			Err:  err,
+		}
+	}
+
+	a, err := h.AuthorizationService.FindAuthorizationByToken(ctx, token)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EUnauthorized,
+			Msg:  "authorization failed",
+		}
+	}
+
+	u, err := h.UserService.FindUserByID(ctx, a.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u.Status == "inactive" {
+		return nil, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "User is inactive",
+		}
+	}
+	return a, nil
+}
+
+// legacyToken returns the token of a 1.x request: the token of its
+// authorization header, or the password of its basic authentication or of its
+// p query parameter. User names are ignored.
+func legacyToken(r *http.Request) (string, error) {
+	if token, err := GetToken(r); err == nil {
+		return token, nil
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password, nil
+	}
+	if password := r.URL.Query().Get("p"); password != "" {
+		return password, nil
+	}
+	return "", ErrAuthHeaderMissing
+}
+
+// handleLegacyError encodes err as an influxdb 1.x error, with the status code
+// of its error code.
+func (h *LegacyHandler) handleLegacyError(ctx context.Context, err error, w http.ResponseWriter) {
+	// Errors other than influxdb errors are those of invalid queries, such as
+	// InfluxQL parsing errors.
+	code := influxdb.ErrorCode(err)
+	httpCode := http.StatusBadRequest
+	if _, ok := err.(*influxdb.Error); ok {
+		if c, ok := statusCodePlatformError[code]; ok {
+			httpCode = c
+		}
+	}
+
+	msg := err.Error()
+	if pwe, ok := err.(*influxdb.PartialWriteError); ok {
+		// The errors of 1.x partial writes have the reason of the first
+		// rejected line only.
+		code = pwe.Code
+		if len(pwe.Rejections) > 0 {
+			msg = fmt.Sprintf("%s: line %d: %s", pwe.Message, pwe.Rejections[0].Line, pwe.Rejections[0].Message)
+		}
+	}
+	w.Header().Set(PlatformErrorCodeHeader, code)
+	w.Header().Set("X-Influxdb-Error", msg)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if httpCode == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="InfluxDB"`)
+	}
+	w.WriteHeader(httpCode)
+
+	b, _ := json.Marshal(struct {
+		Err string `json:"error"`
+	}{Err: msg})
+	_, _ = w.Write(append(b, '\n'))
+}
+
+// legacyFlushWriter flushes the response after every write, so that chunks of
+// query results are sent as they are encoded.
+type legacyFlushWriter struct {
+	w http.ResponseWriter
+}
+
+func (fw *legacyFlushWriter) Write(p []byte) (int, error) {
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
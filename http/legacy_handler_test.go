@@ -0,0 +1,350 @@
+package http
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/flux"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http/metric"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/query"
+	"github.com/influxdata/influxdb/query/influxql"
+	querymock "github.com/influxdata/influxdb/query/mock"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap/zaptest"
+)
+
+// legacyFixture is a kv service with an organization and a token allowed to
+// read and write the bucket mapped to the database "mapped" and the bucket
+// "unmapped/autogen", but not the bucket mapped to the database "other".
+type legacyFixture struct {
+	svc      *kv.Service
+	mapped   *influxdb.Bucket
+	unmapped *influxdb.Bucket
+	token    string
+}
+
+func newLegacyFixture(t *testing.T) *legacyFixture {
+	t.Helper()
+
+	ctx := context.Background()
+	f := &legacyFixture{svc: newInMemKVSVC(t), token: "mytoken"}
+
+	user := &influxdb.User{Name: "user"}
+	if err := f.svc.CreateUser(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	org := &influxdb.Organization{Name: "org"}
+	if err := f.svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+
+	createBucket := func(name string) *influxdb.Bucket {
+		b := &influxdb.Bucket{OrgID: org.ID, Name: name}
+		if err := f.svc.CreateBucket(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	f.mapped = createBucket("mapped-bucket")
+	f.unmapped = createBucket("unmapped/autogen")
+	other := createBucket("other-bucket")
+
+	for db, b := range map[string]*influxdb.Bucket{"mapped": f.mapped, "other": other} {
+		if err := f.svc.Create(ctx, &influxdb.DBRPMapping{
+			Cluster:         influxdb.DefaultDBRPMappingCluster,
+			Database:        db,
+			RetentionPolicy: "autogen",
+			Default:         true,
+			OrganizationID:  org.ID,
+			BucketID:        b.ID,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var perms []influxdb.Permission
+	for _, b := range []*influxdb.Bucket{f.mapped, f.unmapped} {
+		for _, action := range []influxdb.Action{influxdb.ReadAction, influxdb.WriteAction} {
+			p, err := influxdb.NewPermissionAtID(b.ID, action, influxdb.BucketsResourceType, org.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			perms = append(perms, *p)
+		}
+	}
+	if err := f.svc.CreateAuthorization(ctx, &influxdb.Authorization{
+		OrgID:       org.ID,
+		UserID:      user.ID,
+		Token:       f.token,
+		Permissions: perms,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// serve serves a request with a LegacyHandler writing points to pw and
+// querying with queryFn.
+func (f *legacyFixture) serve(t *testing.T, r *http.Request, pw *mock.PointsWriter, queryFn func(context.Context, io.Writer, *query.ProxyRequest) (flux.Statistics, error)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	h := NewLegacyHandler(zaptest.NewLogger(t), &LegacyBackend{
+		HTTPErrorHandler:     DefaultErrorHandler,
+		WriteEventRecorder:   &metric.NopEventRecorder{},
+		QueryEventRecorder:   &metric.NopEventRecorder{},
+		AuthorizationService: f.svc,
+		UserService:          f.svc,
+		BucketService:        f.svc,
+		DBRPMappingService:   f.svc,
+		PointsWriter:         pw,
+		ProxyQueryService:    &querymock.ProxyQueryService{QueryF: queryFn},
+	})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// legacyError returns the body of the response of a 1.x error.
+func legacyError(t *testing.T, msg string) string {
+	t.Helper()
+	b, err := json.Marshal(map[string]string{"error": msg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b) + "\n"
+}
+
+func basicAuth(user, password string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+}
+
+func TestLegacyHandler_handleWrite(t *testing.T) {
+	f := newLegacyFixture(t)
+
+	tests := []struct {
+		name   string
+		query  string
+		auth   string
+		code   int
+		err    string
+		bucket *influxdb.Bucket
+	}{
+		{
+			name:   "basic authentication",
+			query:  "db=mapped",
+			auth:   basicAuth("user", f.token),
+			code:   http.StatusNoContent,
+			bucket: f.mapped,
+		},
+		{
+			name:   "p parameter",
+			query:  "db=mapped&u=user&p=" + f.token,
+			code:   http.StatusNoContent,
+			bucket: f.mapped,
+		},
+		{
+			name:   "token authorization header",
+			query:  "db=mapped&rp=autogen",
+			auth:   "Token " + f.token,
+			code:   http.StatusNoContent,
+			bucket: f.mapped,
+		},
+		{
+			name:   "bucket named after the database without dbrp mapping",
+			query:  "db=unmapped",
+			auth:   "Token " + f.token,
+			code:   http.StatusNoContent,
+			bucket: f.unmapped,
+		},
+		{
+			name:  "invalid token",
+			query: "db=mapped",
+			auth:  basicAuth("user", "invalid"),
+			code:  http.StatusUnauthorized,
+			err:   "authorization failed",
+		},
+		{
+			name:  "missing credentials",
+			query: "db=mapped",
+			code:  http.StatusUnauthorized,
+			err:   "unable to parse authentication credentials: authorization Header is missing",
+		},
+		{
+			name: "missing db",
+			auth: "Token " + f.token,
+			code: http.StatusBadRequest,
+			err:  "database is required",
+		},
+		{
+			name:  "missing dbrp mapping",
+			query: "db=missing",
+			auth:  "Token " + f.token,
+			code:  http.StatusNotFound,
+			err:   `database not found: "missing"`,
+		},
+		{
+			name:  "forbidden bucket",
+			query: "db=other",
+			auth:  "Token " + f.token,
+			code:  http.StatusForbidden,
+			err:   "insufficient permissions for write",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "http://localhost:9999/write?"+tt.query, strings.NewReader("cpu,host=a value=1 1\n"))
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			pw := &mock.PointsWriter{}
+			w := f.serve(t, r, pw, nil)
+
+			if got, want := w.Code, tt.code; got != want {
+				t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+			}
+			if tt.err != "" {
+				if got, want := w.Header().Get("X-Influxdb-Error"), tt.err; got != want {
+					t.Errorf("unexpected error: got %q want %q", got, want)
+				}
+				if got, want := w.Body.String(), legacyError(t, tt.err); got != want {
+					t.Errorf("unexpected body: got %s want %s", got, want)
+				}
+				if tt.code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+					t.Error("expected a WWW-Authenticate header")
+				}
+			}
+
+			if tt.bucket == nil {
+				if n := pw.WritePointsCalled(); n != 0 {
+					t.Errorf("expected no writes, got %d", n)
+				}
+				return
+			}
+			p := pw.Next()
+			if p == nil {
+				t.Fatal("expected a point to be written")
+			}
+			if got, want := string(p.Name()), tsdb.EncodeNameString(tt.bucket.OrgID, tt.bucket.ID); got != want {
+				t.Errorf("unexpected bucket of the point written: got %x want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestLegacyHandler_handleQuery(t *testing.T) {
+	f := newLegacyFixture(t)
+
+	tests := []struct {
+		name      string
+		query     string
+		auth      string
+		code      int
+		err       string
+		chunkSize int
+		body      string
+	}{
+		{
+			name:  "p parameter",
+			query: "db=mapped&q=SELECT+*+FROM+cpu&p=" + f.token,
+			code:  http.StatusOK,
+			body:  "results\n",
+		},
+		{
+			name:      "chunked response",
+			query:     "db=mapped&q=SELECT+*+FROM+cpu&chunked=true&chunk_size=2",
+			auth:      basicAuth("user", f.token),
+			code:      http.StatusOK,
+			chunkSize: 2,
+			body:      "results\nresults\n",
+		},
+		{
+			name:      "chunked response with the default chunk size",
+			query:     "db=mapped&q=SELECT+*+FROM+cpu&chunked=true",
+			auth:      "Token " + f.token,
+			code:      http.StatusOK,
+			chunkSize: legacyDefaultChunkSize,
+			body:      "results\nresults\n",
+		},
+		{
+			name:  "invalid chunk size",
+			query: "db=mapped&q=SELECT+*+FROM+cpu&chunked=true&chunk_size=0",
+			auth:  "Token " + f.token,
+			code:  http.StatusBadRequest,
+			err:   "chunk_size must be a positive integer",
+		},
+		{
+			name:  "missing query",
+			query: "db=mapped",
+			auth:  "Token " + f.token,
+			code:  http.StatusBadRequest,
+			err:   `missing required parameter "q"`,
+		},
+		{
+			name:  "invalid token",
+			query: "db=mapped&q=SELECT+*+FROM+cpu&p=invalid",
+			code:  http.StatusUnauthorized,
+			err:   "authorization failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *query.ProxyRequest
+			queryFn := func(ctx context.Context, w io.Writer, r *query.ProxyRequest) (flux.Statistics, error) {
+				req = r
+				// Chunked responses are written in parts.
+				n := 1
+				if r.Dialect.(*influxql.Dialect).ChunkSize > 0 {
+					n = 2
+				}
+				for i := 0; i < n; i++ {
+					if _, err := io.WriteString(w, "results\n"); err != nil {
+						return flux.Statistics{}, err
+					}
+				}
+				return flux.Statistics{}, nil
+			}
+
+			r := httptest.NewRequest("GET", "http://localhost:9999/query?"+tt.query, nil)
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			w := f.serve(t, r, nil, queryFn)
+
+			if got, want := w.Code, tt.code; got != want {
+				t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+			}
+			if tt.err != "" {
+				if got, want := w.Body.String(), legacyError(t, tt.err); got != want {
+					t.Errorf("unexpected body: got %s want %s", got, want)
+				}
+				if req != nil {
+					t.Error("expected no query")
+				}
+				return
+			}
+
+			if got, want := w.Body.String(), tt.body; got != want {
+				t.Errorf("unexpected body: got %q want %q", got, want)
+			}
+			dialect := req.Dialect.(*influxql.Dialect)
+			if got, want := dialect.ChunkSize, tt.chunkSize; got != want {
+				t.Errorf("unexpected chunk size: got %d want %d", got, want)
+			}
+			if got, want := w.Flushed, tt.chunkSize > 0; got != want {
+				t.Errorf("unexpected flushing of the response: got %v want %v", got, want)
+			}
+			if compiler := req.Request.Compiler.(*influxql.Compiler); compiler.DB != "mapped" {
+				t.Errorf("unexpected database %q", compiler.DB)
+			}
+		})
+	}
+}
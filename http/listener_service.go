@@ -0,0 +1,318 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixListeners = "/api/v2/listeners"
+	listenerIDPath  = prefixListeners + "/:id"
+)
+
+// ListenerBackend is all services and associated parameters required to
+// construct the ListenerHandler.
+type ListenerBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	ListenerService influxdb.ListenerService
+}
+
+// NewListenerBackend returns a new instance of ListenerBackend.
+func NewListenerBackend(log *zap.Logger, b *APIBackend) *ListenerBackend {
+	return &ListenerBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		ListenerService: b.ListenerService,
+	}
+}
+
+// ListenerHandler is the handler for ingestion listeners.
+type ListenerHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	ListenerService influxdb.ListenerService
+}
+
+// NewListenerHandler returns a new instance of ListenerHandler.
+func NewListenerHandler(log *zap.Logger, b *ListenerBackend) *ListenerHandler {
+	h := &ListenerHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		ListenerService: b.ListenerService,
+	}
+
+	h.HandlerFunc("POST", prefixListeners, h.handlePostListener)
+	h.HandlerFunc("GET", prefixListeners, h.handleGetListeners)
+	h.HandlerFunc("GET", listenerIDPath, h.handleGetListener)
+	h.HandlerFunc("PATCH", listenerIDPath, h.handlePatchListener)
+	h.HandlerFunc("DELETE", listenerIDPath, h.handleDeleteListener)
+
+	return h
+}
+
+type listenerResponse struct {
+	*influxdb.Listener
+	Links map[string]string `json:"links"`
+}
+
+func newListenerResponse(l *influxdb.Listener) *listenerResponse {
+	return &listenerResponse{
+		Listener: l,
+		Links: map[string]string{
+			"self":   fmt.Sprintf("%s/%s", prefixListeners, l.ID),
+			"org":    fmt.Sprintf("/api/v2/orgs/%s", l.OrgID),
+			"bucket": fmt.Sprintf("/api/v2/buckets/%s", l.BucketID),
+		},
+	}
+}
+
+type listenersResponse struct {
+	Listeners []*listenerResponse `json:"listeners"`
+	Links     map[string]string   `json:"links"`
+}
+
+func newListenersResponse(listeners []*influxdb.Listener) *listenersResponse {
+	res := &listenersResponse{
+		Listeners: make([]*listenerResponse, 0, len(listeners)),
+		Links: map[string]string{
+			"self": prefixListeners,
+		},
+	}
+	for _, l := range listeners {
+		res.Listeners = append(res.Listeners, newListenerResponse(l))
+	}
+	return res
+}
+
+// handlePostListener is the HTTP handler for the POST /api/v2/listeners route.
+func (h *ListenerHandler) handlePostListener(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	l := &influxdb.Listener{}
+	if err := json.NewDecoder(r.Body).Decode(l); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid listener",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	if err := h.ListenerService.CreateListener(ctx, l); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Listener created", zap.String("listener", fmt.Sprint(l)))
+
+	if err := encodeResponse(ctx, w, http.StatusCreated, newListenerResponse(l)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetListeners is the HTTP handler for the GET /api/v2/listeners route.
+func (h *ListenerHandler) handleGetListeners(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var filter influxdb.ListenerFilter
+	qp := r.URL.Query()
+	if orgID := qp.Get("orgID"); orgID != "" {
+		id, err := influxdb.IDFromString(orgID)
+		if err != nil {
+			h.HandleHTTPError(ctx, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "orgID is invalid",
+				Err:  err,
+			}, w)
+			return
+		}
+		filter.OrgID = id
+	}
+	if status := qp.Get("status"); status != "" {
+		s := influxdb.Status(status)
+		if err := s.Valid(); err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		filter.Status = &s
+	}
+
+	listeners, _, err := h.ListenerService.FindListeners(ctx, filter)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Listeners retrieved", zap.String("listeners", fmt.Sprint(listeners)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newListenersResponse(listeners)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleGetListener is the HTTP handler for the GET /api/v2/listeners/:id route.
+func (h *ListenerHandler) handleGetListener(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	l, err := h.ListenerService.FindListenerByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Listener retrieved", zap.String("listener", fmt.Sprint(l)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newListenerResponse(l)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePatchListener is the HTTP handler for the PATCH /api/v2/listeners/:id route.
+func (h *ListenerHandler) handlePatchListener(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var upd influxdb.ListenerUpdate
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid listener update",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	l, err := h.ListenerService.UpdateListener(ctx, id, upd)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Listener updated", zap.String("listener", fmt.Sprint(l)))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, newListenerResponse(l)); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handleDeleteListener is the HTTP handler for the DELETE /api/v2/listeners/:id route.
+func (h *ListenerHandler) handleDeleteListener(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := decodeIDParam(ctx, "id")
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := h.ListenerService.DeleteListener(ctx, id); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Listener deleted", zap.String("listenerID", id.String()))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+var _ influxdb.ListenerService = (*ListenerService)(nil)
+
+// ListenerService connects to Influx via HTTP using tokens to manage
+// ingestion listeners.
+type ListenerService struct {
+	Client *httpc.Client
+}
+
+// FindListenerByID returns a single listener by ID.
+func (s *ListenerService) FindListenerByID(ctx context.Context, id influxdb.ID) (*influxdb.Listener, error) {
+	var l influxdb.Listener
+	err := s.Client.
+		Get(prefixListeners, id.String()).
+		DecodeJSON(&l).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// FindListeners returns a list of listeners that match filter and the total
+// count of matching listeners.
+func (s *ListenerService) FindListeners(ctx context.Context, filter influxdb.ListenerFilter) ([]*influxdb.Listener, int, error) {
+	var params [][2]string
+	if filter.OrgID != nil {
+		params = append(params, [2]string{"orgID", filter.OrgID.String()})
+	}
+	if filter.Status != nil {
+		params = append(params, [2]string{"status", string(*filter.Status)})
+	}
+
+	var res struct {
+		Listeners []*influxdb.Listener `json:"listeners"`
+	}
+	err := s.Client.
+		Get(prefixListeners).
+		QueryParams(params...).
+		DecodeJSON(&res).
+		Do(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return res.Listeners, len(res.Listeners), nil
+}
+
+// CreateListener creates a new listener and sets l.ID with the new identifier.
+func (s *ListenerService) CreateListener(ctx context.Context, l *influxdb.Listener) error {
+	return s.Client.
+		PostJSON(l, prefixListeners).
+		DecodeJSON(l).
+		Do(ctx)
+}
+
+// UpdateListener updates a single listener with changeset.
+// Returns the new listener state after update.
+func (s *ListenerService) UpdateListener(ctx context.Context, id influxdb.ID, upd influxdb.ListenerUpdate) (*influxdb.Listener, error) {
+	var l influxdb.Listener
+	err := s.Client.
+		PatchJSON(upd, prefixListeners, id.String()).
+		DecodeJSON(&l).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// DeleteListener removes a listener by ID, along with its consumer offsets.
+func (s *ListenerService) DeleteListener(ctx context.Context, id influxdb.ID) error {
+	return s.Client.
+		Delete(prefixListeners, id.String()).
+		StatusFn(func(resp *http.Response) error {
+			return CheckErrorStatus(http.StatusNoContent, resp)
+		}).
+		Do(ctx)
+}
@@ -30,6 +30,13 @@ func NewPlatformHandler(b *APIBackend, opts ...APIHandlerOptFn) *PlatformHandler
 	h.RegisterNoAuthRoute("GET", "/api/v2/setup")
 	h.RegisterNoAuthRoute("GET", "/api/v2/swagger.json")
 
+	// The influxdb 1.x API authenticates its requests itself.
+	h.RegisterNoAuthRoute("POST", prefixLegacyWrite)
+	h.RegisterNoAuthRoute("GET", prefixLegacyQuery)
+	h.RegisterNoAuthRoute("POST", prefixLegacyQuery)
+	h.RegisterNoAuthRoute("GET", prefixLegacyPing)
+	h.RegisterNoAuthRoute("HEAD", prefixLegacyPing)
+
 	assetHandler := NewAssetHandler()
 	assetHandler.Path = b.AssetsPath
 
@@ -52,7 +59,8 @@ func (h *PlatformHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Serve the chronograf assets for any basepath that does not start with addressable parts
 	// of the platform API.
-	if !strings.HasPrefix(r.URL.Path, "/v1") &&
+	if !isLegacyPath(r.URL.Path) &&
+		!strings.HasPrefix(r.URL.Path, "/v1") &&
 		!strings.HasPrefix(r.URL.Path, "/api/v2") &&
 		!strings.HasPrefix(r.URL.Path, "/chronograf/") {
 		h.AssetHandler.ServeHTTP(w, r)
@@ -61,3 +69,13 @@ func (h *PlatformHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	h.APIHandler.ServeHTTP(w, r)
 }
+
+// isLegacyPath reports whether the path is a route of the influxdb 1.x API.
+func isLegacyPath(path string) bool {
+	switch path {
+	case prefixLegacyWrite, prefixLegacyQuery, prefixLegacyPing:
+		return true
+	default:
+		return false
+	}
+}
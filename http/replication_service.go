@@ -0,0 +1,224 @@
+package http
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const (
+	prefixReplication      = "/api/v2/replication"
+	replicationPromotePath = prefixReplication + "/promote"
+	replicationStreamPath  = prefixReplication + "/stream"
+)
+
+// ReplicationBackend is all services and associated parameters required to
+// construct the ReplicationHandler.
+type ReplicationBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	ReplicationService influxdb.ReplicationService
+}
+
+// NewReplicationBackend returns a new instance of ReplicationBackend.
+func NewReplicationBackend(log *zap.Logger, b *APIBackend) *ReplicationBackend {
+	return &ReplicationBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		ReplicationService: b.ReplicationService,
+	}
+}
+
+// ReplicationHandler is the handler streaming the replication log of a primary
+// to standbys, and promoting standbys.
+type ReplicationHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	ReplicationService influxdb.ReplicationService
+}
+
+// NewReplicationHandler returns a new instance of ReplicationHandler.
+func NewReplicationHandler(log *zap.Logger, b *ReplicationBackend) *ReplicationHandler {
+	h := &ReplicationHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		ReplicationService: b.ReplicationService,
+	}
+
+	h.HandlerFunc("GET", prefixReplication, h.handleGetReplication)
+	h.HandlerFunc("POST", replicationPromotePath, h.handlePromoteStandby)
+	h.HandlerFunc("GET", replicationStreamPath, h.handleStreamReplication)
+
+	return h
+}
+
+// handleGetReplication is the HTTP handler for the GET /api/v2/replication route.
+func (h *ReplicationHandler) handleGetReplication(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "ReplicationHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	status, err := h.ReplicationService.FindReplicationStatus(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, status); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+// handlePromoteStandby is the HTTP handler for the POST /api/v2/replication/promote route.
+func (h *ReplicationHandler) handlePromoteStandby(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "ReplicationHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	if err := h.ReplicationService.PromoteStandby(ctx); err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Info("Standby promoted")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleStreamReplication is the HTTP handler for the GET /api/v2/replication/stream route.
+func (h *ReplicationHandler) handleStreamReplication(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "ReplicationHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	pos, err := decodeReplicationPosition(r)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	h.log.Info("Standby streaming", zap.String("remote", r.RemoteAddr), zap.Uint64("epoch", pos.Epoch), zap.Uint64("sequence", pos.Sequence))
+	fw := &flushWriter{w: w}
+	err = h.ReplicationService.StreamReplication(ctx, pos, fw)
+	if err != nil && !fw.wrote {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Info("Standby stopped streaming", zap.String("remote", r.RemoteAddr), zap.Error(err))
+}
+
+func decodeReplicationPosition(r *http.Request) (influxdb.ReplicationPosition, error) {
+	var pos influxdb.ReplicationPosition
+	qp := r.URL.Query()
+
+	for _, p := range []struct {
+		name string
+		v    *uint64
+	}{
+		{"epoch", &pos.Epoch},
+		{"sequence", &pos.Sequence},
+	} {
+		s := qp.Get(p.name)
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return pos, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  p.name + " must be a non-negative integer",
+			}
+		}
+		*p.v = v
+	}
+
+	return pos, nil
+}
+
+// flushWriter writes the replication log to a response, flushing every write
+// so that entries reach standbys as soon as they are appended.
+type flushWriter struct {
+	w     http.ResponseWriter
+	wrote bool
+}
+
+func (fw *flushWriter) Write(p []byte) (int, error) {
+	if !fw.wrote {
+		fw.w.Header().Set("Content-Type", "application/octet-stream")
+		fw.w.WriteHeader(http.StatusOK)
+		fw.wrote = true
+	}
+
+	n, err := fw.w.Write(p)
+	if f, ok := fw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return n, err
+}
+
+var _ influxdb.ReplicationService = (*ReplicationService)(nil)
+
+// ReplicationService connects to Influx via HTTP using tokens to stream the
+// replication log of a primary and promote standbys.
+type ReplicationService struct {
+	Client *httpc.Client
+}
+
+// FindReplicationStatus returns the replication state of the instance.
+func (s *ReplicationService) FindReplicationStatus(ctx context.Context) (*influxdb.ReplicationStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var status influxdb.ReplicationStatus
+	err := s.Client.
+		Get(prefixReplication).
+		DecodeJSON(&status).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// StreamReplication writes the entries of the replication log of the primary
+// following pos to w as they are appended, until ctx is done or the stream fails.
+func (s *ReplicationService) StreamReplication(ctx context.Context, pos influxdb.ReplicationPosition, w io.Writer) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		Get(replicationStreamPath).
+		QueryParams(
+			[2]string{"epoch", strconv.FormatUint(pos.Epoch, 10)},
+			[2]string{"sequence", strconv.FormatUint(pos.Sequence, 10)},
+		).
+		Decode(func(resp *http.Response) error {
+			_, err := io.Copy(w, resp.Body)
+			return err
+		}).
+		Do(ctx)
+}
+
+// PromoteStandby stops a standby from following its primary and makes it
+// accept writes.
+func (s *ReplicationService) PromoteStandby(ctx context.Context) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.Client.
+		Post(nil, replicationPromotePath).
+		Do(ctx)
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/httpc"
+	"go.uber.org/zap"
+)
+
+const prefixSeriesGC = "/api/v2/storage/series/gc"
+
+// SeriesGCBackend is all services and associated parameters required to
+// construct the SeriesGCHandler.
+type SeriesGCBackend struct {
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	SeriesGCService influxdb.SeriesGCService
+}
+
+// NewSeriesGCBackend returns a new instance of SeriesGCBackend.
+func NewSeriesGCBackend(log *zap.Logger, b *APIBackend) *SeriesGCBackend {
+	return &SeriesGCBackend{
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		SeriesGCService: b.SeriesGCService,
+	}
+}
+
+// SeriesGCHandler is the handler for running the series garbage collector of
+// the storage engine on demand.
+type SeriesGCHandler struct {
+	*httprouter.Router
+	influxdb.HTTPErrorHandler
+	log *zap.Logger
+
+	SeriesGCService influxdb.SeriesGCService
+}
+
+// NewSeriesGCHandler returns a new instance of SeriesGCHandler.
+func NewSeriesGCHandler(log *zap.Logger, b *SeriesGCBackend) *SeriesGCHandler {
+	h := &SeriesGCHandler{
+		Router:           NewRouter(b.HTTPErrorHandler),
+		HTTPErrorHandler: b.HTTPErrorHandler,
+		log:              log,
+
+		SeriesGCService: b.SeriesGCService,
+	}
+
+	h.HandlerFunc("POST", prefixSeriesGC, h.handlePostSeriesGC)
+
+	return h
+}
+
+// handlePostSeriesGC is the HTTP handler for the POST /api/v2/storage/series/gc route.
+func (h *SeriesGCHandler) handlePostSeriesGC(w http.ResponseWriter, r *http.Request) {
+	span, r := tracing.ExtractFromHTTPRequest(r, "SeriesGCHandler")
+	defer span.Finish()
+
+	ctx := r.Context()
+	gc, err := h.SeriesGCService.CollectSeries(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	h.log.Debug("Series collected", zap.Int64("seriesRemoved", gc.SeriesRemoved), zap.Int64("segmentsRemoved", gc.SegmentsRemoved))
+
+	if err := encodeResponse(ctx, w, http.StatusOK, gc); err != nil {
+		logEncodingError(h.log, r, err)
+		return
+	}
+}
+
+var _ influxdb.SeriesGCService = (*SeriesGCService)(nil)
+
+// SeriesGCService connects to Influx via HTTP using tokens to run the series
+// garbage collector of the storage engine.
+type SeriesGCService struct {
+	Client *httpc.Client
+}
+
+// CollectSeries runs the series garbage collector and waits for it to complete.
+func (s *SeriesGCService) CollectSeries(ctx context.Context) (*influxdb.SeriesGC, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var gc influxdb.SeriesGC
+	err := s.Client.
+		Post(nil, prefixSeriesGC).
+		DecodeJSON(&gc).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gc, nil
+}
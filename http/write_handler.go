@@ -1,13 +1,16 @@
 package http
 
 import (
+	"bytes"
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"time"
 
 	"github.com/influxdata/httprouter"
 	"github.com/influxdata/influxdb"
@@ -16,8 +19,10 @@ import (
 	"github.com/influxdata/influxdb/kit/tracing"
 	kithttp "github.com/influxdata/influxdb/kit/transport/http"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/nats"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/write"
 	"go.uber.org/zap"
 )
 
@@ -38,6 +43,11 @@ type WriteBackend struct {
 	PointsWriter        storage.PointsWriter
 	BucketService       influxdb.BucketService
 	OrganizationService influxdb.OrganizationService
+
+	// WriteQueue queues writes to be written asynchronously, if not nil.
+	WriteQueue          nats.Publisher
+	WriteQueueMaxBytes  int
+	WriteReceiptService influxdb.WriteReceiptService
 }
 
 // NewWriteBackend returns a new instance of WriteBackend.
@@ -50,6 +60,10 @@ func NewWriteBackend(log *zap.Logger, b *APIBackend) *WriteBackend {
 		PointsWriter:        b.PointsWriter,
 		BucketService:       b.BucketService,
 		OrganizationService: b.OrganizationService,
+
+		WriteQueue:          b.WriteQueue,
+		WriteQueueMaxBytes:  b.WriteQueueMaxBytes,
+		WriteReceiptService: b.WriteReceiptService,
 	}
 }
 
@@ -64,6 +78,9 @@ type WriteHandler struct {
 
 	PointsWriter storage.PointsWriter
 
+	WriteQueue          nats.Publisher
+	WriteReceiptService influxdb.WriteReceiptService
+
 	EventRecorder metric.EventRecorder
 
 	maxBatchSizeBytes int64
@@ -71,6 +88,8 @@ type WriteHandler struct {
 	parserMaxBytes    int
 	parserMaxLines    int
 	parserMaxValues   int
+
+	writeQueueMaxBytes int
 }
 
 // WriteHandlerOption is a functional option for a *WriteHandler
@@ -129,7 +148,11 @@ func NewWriteHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOptio
 		PointsWriter:        b.PointsWriter,
 		BucketService:       b.BucketService,
 		OrganizationService: b.OrganizationService,
+		WriteQueue:          b.WriteQueue,
+		WriteReceiptService: b.WriteReceiptService,
 		EventRecorder:       b.WriteEventRecorder,
+
+		writeQueueMaxBytes: b.WriteQueueMaxBytes,
 	}
 
 	for _, opt := range opts {
@@ -148,6 +171,9 @@ func NewWriteHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOptio
 	}
 
 	h.HandlerFunc("POST", prefixWrite, h.handleWrite)
+	if h.WriteReceiptService != nil {
+		h.HandlerFunc("GET", prefixWriteReceipts+"/:id", h.handleGetWriteReceipt)
+	}
 	return h
 }
 
@@ -249,6 +275,50 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.WriteQueue != nil {
+		var receipt *influxdb.WriteReceipt
+		receipt, requestBytes, err = h.queueWrite(ctx, log, r, bucket, req.Precision)
+		if err != nil {
+			h.HandleHTTPError(ctx, err, w)
+			return
+		}
+		if err := encodeResponse(ctx, w, http.StatusAccepted, receipt); err != nil {
+			logEncodingError(log, r, err)
+		}
+		return
+	}
+
+	requestBytes, err = h.writePoints(ctx, log, r, bucket, req.Precision)
+	if pwe, ok := err.(*influxdb.PartialWriteError); ok {
+		w.Header().Set(PlatformErrorCodeHeader, pwe.Code)
+		if err := encodeResponse(ctx, w, http.StatusBadRequest, pwe); err != nil {
+			logEncodingError(log, r, err)
+		}
+		return
+	} else if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writePoints parses the points of the body of r, in the format of its
+// content type, and writes them to the bucket. It returns the size of the
+// body read. The points of the lines that are valid are written even if
+// others are rejected; the rejected lines are reported by an
+// *influxdb.PartialWriteError.
+func (h *WriteHandler) writePoints(ctx context.Context, log *zap.Logger, r *http.Request, bucket *influxdb.Bucket, precision string) (int, error) {
+	data, err := h.readBody(ctx, log, r)
+	if err != nil {
+		return 0, err
+	}
+	_, err = h.writeData(ctx, log, bucket, precision, r.Header.Get("Content-Type"), data)
+	return len(data), err
+}
+
+// readBody returns the uncompressed body of a write request.
+func (h *WriteHandler) readBody(ctx context.Context, log *zap.Logger, r *http.Request) ([]byte, error) {
 	data, err := readWriteRequest(ctx, r.Body, r.Header.Get("Content-Encoding"), h.maxBatchSizeBytes)
 	if err != nil {
 		log.Error("Error reading body", zap.Error(err))
@@ -260,34 +330,73 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 			code = influxdb.EInvalid
 		}
 
-		handleError(err, code, "unable to read data")
-		return
+		return nil, &influxdb.Error{
+			Code: code,
+			Op:   "http/handleWrite",
+			Msg:  "unable to read data",
+			Err:  err,
+		}
 	}
 
-	requestBytes = len(data)
-	if requestBytes == 0 {
-		handleError(err, influxdb.EInvalid, "writing requires points")
-		return
+	if len(data) == 0 {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   "http/handleWrite",
+			Msg:  "writing requires points",
+		}
 	}
+	return data, nil
+}
 
-	span, _ = tracing.StartSpanFromContextWithOperationName(ctx, "encoding and parsing")
-	encoded := tsdb.EncodeName(org.ID, bucket.ID)
-	mm := models.EscapeMeasurement(encoded[:])
+// writeData parses the points of data, in the format of the content type,
+// and writes them to the bucket. It returns the number of lines written.
+func (h *WriteHandler) writeData(ctx context.Context, log *zap.Logger, bucket *influxdb.Bucket, precision, contentType string, data []byte) (int, error) {
+	handleError := func(err error, code, message string) error {
+		return &influxdb.Error{
+			Code: code,
+			Op:   "http/handleWrite",
+			Msg:  message,
+			Err:  err,
+		}
+	}
 
-	var options []models.ParserOption
-	if len(h.parserOptions) > 0 {
-		options = make([]models.ParserOption, 0, len(h.parserOptions)+1)
-		options = append(options, h.parserOptions...)
+	var wl writeLines
+	if format := write.FormatFromContentType(contentType); format != write.FormatLineProtocol {
+		span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "converting to line protocol")
+		span.LogKV("format", string(format))
+		c, err := write.Convert(format, data, precision)
+		span.Finish()
+		if err != nil {
+			log.Error("Error converting points", zap.String("format", string(format)), zap.Error(err))
+			return 0, handleError(err, influxdb.EInvalid, "")
+		}
+		data, wl.sources = c.LineProtocol, c.Sources
+		for _, e := range c.Errors {
+			wl.rejectSource(e.Source, influxdb.WriteRejectedParseError, e.Err.Error())
+		}
 	}
 
-	if req.Precision != nil {
-		options = append(options, req.Precision)
+	span, _ := tracing.StartSpanFromContextWithOperationName(ctx, "encoding and parsing")
+	encoded := tsdb.EncodeName(bucket.OrgID, bucket.ID)
+	mm := models.EscapeMeasurement(encoded[:])
+
+	options := make([]models.ParserOption, 0, len(h.parserOptions)+2)
+	options = append(options, h.parserOptions...)
+	options = append(options, models.WithParserPointLines(&wl.points))
+
+	if precision != "ns" {
+		options = append(options, models.WithParserPrecision(precision))
 	}
 
 	points, err := models.ParsePointsWithOptions(data, mm, options...)
 	span.LogKV("values_total", len(points))
 	span.Finish()
-	if err != nil {
+	if pe, ok := err.(*models.ParseError); ok {
+		log.Info("Rejected lines that could not be parsed", zap.Int("lines", len(pe.Lines)), zap.Error(err))
+		for _, le := range pe.Lines {
+			wl.reject(le.Line, influxdb.WriteRejectedParseError, le.Err.Error())
+		}
+	} else if err != nil {
 		log.Error("Error parsing points", zap.Error(err))
 
 		code := influxdb.EInvalid
@@ -297,17 +406,136 @@ func (h *WriteHandler) handleWrite(w http.ResponseWriter, r *http.Request) {
 			code = influxdb.ETooLarge
 		}
 
-		handleError(err, code, "")
-		return
+		return 0, handleError(err, code, "")
+	}
+
+	// Points older than the retention period of the bucket would be deleted
+	// by the next enforcement of the retention policy.
+	if bucket.RetentionPeriod > 0 {
+		min := time.Now().Add(-bucket.RetentionPeriod)
+		points = wl.filter(points, func(pt models.Point) string {
+			if pt.Time().Before(min) {
+				return fmt.Sprintf("point time %s is older than the retention period %s of the bucket", pt.Time().UTC().Format(time.RFC3339Nano), bucket.RetentionPeriod)
+			}
+			return ""
+		}, influxdb.WriteRejectedOutOfRetention)
+	}
+
+	if len(points) > 0 {
+		if err := h.PointsWriter.WritePoints(ctx, points); err != nil {
+			if !wl.rejectDrops(points, err) {
+				log.Error("Error writing points", zap.Error(err))
+				if influxdb.ErrorCode(err) == influxdb.EUnprocessableEntity {
+					// Points were rejected, for example by the schema of the bucket.
+					return 0, err
+				}
+				return 0, handleError(err, influxdb.EInternal, "unexpected error writing points to database")
+			}
+			log.Info("Rejected points that could not be written", zap.Error(err))
+		}
+	}
+
+	if err := wl.err(); err != nil {
+		return 0, err
+	}
+	return wl.lines(), nil
+}
+
+// writeLines keeps track of the lines of the points of a write, and of the
+// rejected lines.
+type writeLines struct {
+	sources []int // the source of each line of the line protocol, if converted
+	points  []int // the line of the line protocol of each point
+
+	rejections []*influxdb.WriteRejection
+	rejected   map[influxdb.WriteRejection]bool
+}
+
+// source returns the source line of a line of the line protocol.
+func (wl *writeLines) source(line int) int {
+	if wl.sources == nil || line < 1 || line > len(wl.sources) {
+		return line
 	}
+	return wl.sources[line-1]
+}
 
-	if err := h.PointsWriter.WritePoints(ctx, points); err != nil {
-		log.Error("Error writing points", zap.Error(err))
-		handleError(err, influxdb.EInternal, "unexpected error writing points to database")
+// reject rejects a line of the line protocol.
+func (wl *writeLines) reject(line int, reason, message string) {
+	wl.rejectSource(wl.source(line), reason, message)
+}
+
+// rejectSource rejects a source line, once for each reason and message.
+func (wl *writeLines) rejectSource(line int, reason, message string) {
+	r := influxdb.WriteRejection{Line: line, Reason: reason, Message: message}
+	if wl.rejected[r] {
 		return
 	}
+	if wl.rejected == nil {
+		wl.rejected = make(map[influxdb.WriteRejection]bool)
+	}
+	wl.rejected[r] = true
+	wl.rejections = append(wl.rejections, &r)
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// filter returns the points for which fn returns no reason, and rejects the
+// lines of the others.
+func (wl *writeLines) filter(points []models.Point, fn func(models.Point) string, reason string) []models.Point {
+	out := points[:0]
+	lines := wl.points[:0]
+	for i, pt := range points {
+		if message := fn(pt); message != "" {
+			wl.reject(wl.points[i], reason, message)
+			continue
+		}
+		out = append(out, pt)
+		lines = append(lines, wl.points[i])
+	}
+	wl.points = lines
+	return out
+}
+
+// rejectDrops rejects the lines of the points dropped by a write of points,
+// if err is the partial write error of the write and it has the reasons the
+// points were dropped for.
+func (wl *writeLines) rejectDrops(points []models.Point, err error) bool {
+	if e, ok := err.(*influxdb.Error); ok && e.Err != nil {
+		err = e.Err
+	}
+	pwe, ok := err.(tsdb.PartialWriteError)
+	if !ok || len(pwe.Drops) == 0 {
+		return false
+	}
+
+	indexes := make(map[models.Point]int, len(points))
+	for i, pt := range points {
+		indexes[pt] = i
+	}
+	for _, d := range pwe.Drops {
+		if i, ok := indexes[d.Point]; ok {
+			wl.reject(wl.points[i], d.Code, d.Reason)
+		}
+	}
+	return true
+}
+
+// lines returns the number of source lines with points or rejected.
+func (wl *writeLines) lines() int {
+	lines := make(map[int]struct{}, len(wl.points)+len(wl.rejections))
+	for _, line := range wl.points {
+		lines[wl.source(line)] = struct{}{}
+	}
+	for _, r := range wl.rejections {
+		lines[r.Line] = struct{}{}
+	}
+	return len(lines)
+}
+
+// err returns the partial write error of the rejected lines, if any.
+func (wl *writeLines) err() error {
+	if len(wl.rejections) == 0 {
+		return nil
+	}
+	return influxdb.NewPartialWriteError(wl.lines(), wl.rejections)
 }
 
 func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest, error) {
@@ -325,15 +553,10 @@ func decodeWriteRequest(ctx context.Context, r *http.Request) (*postWriteRequest
 		}
 	}
 
-	var precision models.ParserOption
-	if p != "ns" {
-		precision = models.WithParserPrecision(p)
-	}
-
 	return &postWriteRequest{
 		Bucket:    qp.Get("bucket"),
 		Org:       qp.Get("org"),
-		Precision: precision,
+		Precision: p,
 	}, nil
 }
 
@@ -373,7 +596,7 @@ func readWriteRequest(ctx context.Context, rc io.ReadCloser, encoding string, ma
 type postWriteRequest struct {
 	Org       string
 	Bucket    string
-	Precision models.ParserOption
+	Precision string
 }
 
 // WriteService sends data over HTTP to influxdb via line protocol.
@@ -443,6 +666,30 @@ func (s *WriteService) Write(ctx context.Context, orgID, bucketID influxdb.ID, r
 	}
 	defer resp.Body.Close()
 
+	return checkWriteError(resp)
+}
+
+// checkWriteError returns the *influxdb.PartialWriteError of a write of which
+// some lines were rejected, or the error of the response as CheckError does.
+func checkWriteError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusBadRequest {
+		return CheckError(resp)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  err.Error(),
+		}
+	}
+
+	var pwe influxdb.PartialWriteError
+	if err := json.Unmarshal(body, &pwe); err == nil && pwe.Rejections != nil {
+		return &pwe
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
 	return CheckError(resp)
 }
 
@@ -3,6 +3,7 @@ package http
 import (
 	"compress/gzip"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,12 +11,16 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/http/metric"
 	httpmock "github.com/influxdata/influxdb/http/mock"
 	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
 	influxtesting "github.com/influxdata/influxdb/testing"
+	"github.com/influxdata/influxdb/tsdb"
 	"go.uber.org/zap/zaptest"
 )
 
@@ -220,7 +225,45 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 			},
 			wants: wants{
 				code: 400,
-				body: `{"code":"invalid","message":"unable to parse 'invalid': missing fields"}`,
+				body: `{"code":"invalid","message":"partial write: 1 of 1 lines rejected","accepted":0,"rejected":1,"rejections":[{"line":1,"reason":"parse_error","message":"missing fields"}]}` + "\n",
+			},
+		},
+		{
+			name: "invalid lines are rejected and the others written",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				body:   "m1,t1=v1 f1=1\ninvalid\nm1,t1=v1 f1=2\nm1,t1=v1 f1=\n",
+			},
+			state: state{
+				org:    testOrg("043e0780ee2b1000"),
+				bucket: testBucket("043e0780ee2b1000", "04504b356e23b000"),
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"partial write: 2 of 4 lines rejected","accepted":2,"rejected":2,"rejections":[{"line":2,"reason":"parse_error","message":"missing fields"},{"line":4,"reason":"parse_error","message":"missing field value"}]}` + "\n",
+			},
+		},
+		{
+			name: "points older than the retention period are rejected",
+			request: request{
+				org:    "043e0780ee2b1000",
+				bucket: "04504b356e23b000",
+				auth:   bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+				body:   "m1,t1=v1 f1=1 1000000000\nm1,t1=v1 f1=2",
+			},
+			state: state{
+				org: testOrg("043e0780ee2b1000"),
+				bucket: &influxdb.Bucket{
+					ID:              influxtesting.MustIDBase16("04504b356e23b000"),
+					OrgID:           influxtesting.MustIDBase16("043e0780ee2b1000"),
+					RetentionPeriod: time.Hour,
+				},
+			},
+			wants: wants{
+				code: 400,
+				body: `{"code":"invalid","message":"partial write: 1 of 2 lines rejected","accepted":1,"rejected":1,"rejections":[{"line":1,"reason":"out_of_retention","message":"point time 1970-01-01T00:00:01Z is older than the retention period 1h0m0s of the bucket"}]}` + "\n",
 			},
 		},
 		{
@@ -375,6 +418,79 @@ func TestWriteHandler_handleWrite(t *testing.T) {
 	}
 }
 
+// droppingPointsWriter drops the points of a field, as storage drops those
+// of fields whose type conflicts.
+type droppingPointsWriter struct {
+	conflicts string
+}
+
+func (w *droppingPointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	var drops []tsdb.Drop
+	for _, pt := range points {
+		if string(pt.Tags().Get(models.FieldKeyTagKeyBytes)) == w.conflicts {
+			drops = append(drops, tsdb.Drop{Point: pt, Code: influxdb.WriteRejectedTypeConflict, Reason: "field type conflict"})
+		}
+	}
+	if len(drops) == 0 {
+		return nil
+	}
+	return tsdb.PartialWriteError{Reason: "field type conflict", Dropped: len(drops), Drops: drops}
+}
+
+func TestWriteHandler_handleWrite_droppedPoints(t *testing.T) {
+	orgs := mock.NewOrganizationService()
+	orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+		return testOrg("043e0780ee2b1000"), nil
+	}
+	buckets := mock.NewBucketService()
+	buckets.FindBucketFn = func(context.Context, influxdb.BucketFilter) (*influxdb.Bucket, error) {
+		return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+	}
+
+	b := &APIBackend{
+		HTTPErrorHandler:    DefaultErrorHandler,
+		Logger:              zaptest.NewLogger(t),
+		OrganizationService: orgs,
+		BucketService:       buckets,
+		PointsWriter:        &droppingPointsWriter{conflicts: "f2"},
+		WriteEventRecorder:  &metric.NopEventRecorder{},
+	}
+	writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), b))
+	handler := httpmock.NewAuthMiddlewareHandler(writeHandler, bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"))
+
+	// The points of the lines are written by field; a line is rejected if
+	// any of its fields is dropped, and once per reason.
+	r := httptest.NewRequest("POST", "http://localhost:9999/api/v2/write?org=043e0780ee2b1000&bucket=04504b356e23b000",
+		strings.NewReader("m1 f1=1\nm1 f1=2,f2=3\nm1 f2=4,f3=5\n"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got, want := w.Code, http.StatusBadRequest; got != want {
+		t.Errorf("unexpected status code: got %d want %d", got, want)
+	}
+	if got, want := w.Header().Get(PlatformErrorCodeHeader), influxdb.EInvalid; got != want {
+		t.Errorf("unexpected error code header: got %q want %q", got, want)
+	}
+
+	var pwe influxdb.PartialWriteError
+	if err := json.NewDecoder(w.Body).Decode(&pwe); err != nil {
+		t.Fatal(err)
+	}
+	want := influxdb.PartialWriteError{
+		Code:     influxdb.EInvalid,
+		Message:  "partial write: 2 of 3 lines rejected",
+		Accepted: 1,
+		Rejected: 2,
+		Rejections: []*influxdb.WriteRejection{
+			{Line: 2, Reason: influxdb.WriteRejectedTypeConflict, Message: "field type conflict"},
+			{Line: 3, Reason: influxdb.WriteRejectedTypeConflict, Message: "field type conflict"},
+		},
+	}
+	if diff := cmp.Diff(want, pwe); diff != "" {
+		t.Errorf("unexpected partial write error: -want/+got\n%s", diff)
+	}
+}
+
 var DefaultErrorHandler = ErrorHandler(0)
 
 func bucketWritePermission(org, bucket string) *influxdb.Authorization {
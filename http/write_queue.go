@@ -0,0 +1,232 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/httprouter"
+	"github.com/influxdata/influxdb"
+	pcontext "github.com/influxdata/influxdb/context"
+	"github.com/influxdata/influxdb/nats"
+	"go.uber.org/zap"
+)
+
+const (
+	// WriteQueueSubject is the subject writes are queued to.
+	WriteQueueSubject = "writes"
+	// WriteQueueGroup is the queue group of the workers writing the queued
+	// writes to storage.
+	WriteQueueGroup = "writes"
+
+	prefixWriteReceipts = prefixWrite + "/receipts"
+)
+
+// queuedWrite is the header of a queued write. The message of the write is
+// the JSON header followed by a newline and the uncompressed body of the
+// write.
+type queuedWrite struct {
+	ReceiptID   influxdb.ID `json:"receiptID"`
+	OrgID       influxdb.ID `json:"orgID"`
+	BucketID    influxdb.ID `json:"bucketID"`
+	Precision   string      `json:"precision"`
+	ContentType string      `json:"contentType"`
+}
+
+func encodeQueuedWrite(qw *queuedWrite, data []byte) ([]byte, error) {
+	header, err := json.Marshal(qw)
+	if err != nil {
+		return nil, err
+	}
+	msg := make([]byte, 0, len(header)+1+len(data))
+	msg = append(msg, header...)
+	msg = append(msg, '\n')
+	return append(msg, data...), nil
+}
+
+func decodeQueuedWrite(msg []byte) (*queuedWrite, []byte, error) {
+	i := bytes.IndexByte(msg, '\n')
+	if i == -1 {
+		return nil, nil, fmt.Errorf("missing header of queued write")
+	}
+	var qw queuedWrite
+	if err := json.Unmarshal(msg[:i], &qw); err != nil {
+		return nil, nil, err
+	}
+	return &qw, msg[i+1:], nil
+}
+
+// queueWrite queues the points of the body of r to be written to the bucket
+// asynchronously. It returns the pending receipt of the write, and the size
+// of the body read. The receipt is marked as failed if the write cannot be
+// queued.
+func (h *WriteHandler) queueWrite(ctx context.Context, log *zap.Logger, r *http.Request, bucket *influxdb.Bucket, precision string) (*influxdb.WriteReceipt, int, error) {
+	data, err := h.readBody(ctx, log, r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if h.writeQueueMaxBytes > 0 && len(data) > h.writeQueueMaxBytes {
+		return nil, len(data), &influxdb.Error{
+			Code: influxdb.ETooLarge,
+			Op:   "http/handleWrite",
+			Msg:  fmt.Sprintf("points batch is too large to be queued; the maximum is %d bytes", h.writeQueueMaxBytes),
+		}
+	}
+
+	receipt := &influxdb.WriteReceipt{
+		OrgID:    bucket.OrgID,
+		BucketID: bucket.ID,
+	}
+	if err := h.WriteReceiptService.CreateWriteReceipt(ctx, receipt); err != nil {
+		return nil, len(data), err
+	}
+
+	msg, err := encodeQueuedWrite(&queuedWrite{
+		ReceiptID:   receipt.ID,
+		OrgID:       bucket.OrgID,
+		BucketID:    bucket.ID,
+		Precision:   precision,
+		ContentType: r.Header.Get("Content-Type"),
+	}, data)
+	if err == nil {
+		err = h.WriteQueue.Publish(WriteQueueSubject, bytes.NewReader(msg))
+	}
+	if err != nil {
+		log.Error("Error queueing write", zap.Error(err))
+
+		receipt.Status = influxdb.WriteReceiptFailed
+		receipt.Message = "unable to queue write"
+		if err := h.WriteReceiptService.UpdateWriteReceipt(ctx, receipt); err != nil {
+			log.Error("Failed to update write receipt", zap.Error(err))
+		}
+
+		return nil, len(data), &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   "http/handleWrite",
+			Msg:  "unable to queue write",
+			Err:  err,
+		}
+	}
+	return receipt, len(data), nil
+}
+
+// handleGetWriteReceipt is the HTTP handler for the GET /api/v2/write/receipts/:id route.
+func (h *WriteHandler) handleGetWriteReceipt(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	a, err := pcontext.GetAuthorizer(ctx)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	var id influxdb.ID
+	if err := id.DecodeFromString(httprouter.ParamsFromContext(ctx).ByName("id")); err != nil {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid write receipt ID",
+			Err:  err,
+		}, w)
+		return
+	}
+
+	receipt, err := h.WriteReceiptService.FindWriteReceiptByID(ctx, id)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+
+	// Receipts are seen by those who may write to their bucket.
+	p, err := influxdb.NewPermissionAtID(receipt.BucketID, influxdb.WriteAction, influxdb.BucketsResourceType, receipt.OrgID)
+	if err != nil {
+		h.HandleHTTPError(ctx, err, w)
+		return
+	}
+	if !a.Allowed(*p) {
+		h.HandleHTTPError(ctx, &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "insufficient permissions to read write receipt",
+		}, w)
+		return
+	}
+
+	if err := encodeResponse(ctx, w, http.StatusOK, receipt); err != nil {
+		logEncodingError(h.log, r, err)
+	}
+}
+
+// WriteQueueHandler writes the queued writes to storage, and sets the
+// status of their receipts.
+type WriteQueueHandler struct {
+	h *WriteHandler
+}
+
+var _ nats.Handler = (*WriteQueueHandler)(nil)
+
+// NewWriteQueueHandler returns a handler of queued writes, writing them as
+// a WriteHandler would.
+func NewWriteQueueHandler(log *zap.Logger, b *WriteBackend, opts ...WriteHandlerOption) *WriteQueueHandler {
+	return &WriteQueueHandler{h: NewWriteHandler(log, b, opts...)}
+}
+
+// Process writes a queued write and acks it once its receipt is updated.
+// Writes failing for internal errors are not acked, so that they are
+// delivered again; writes queued after them are only delivered in the
+// meantime if the subscription has more than one write in flight.
+func (qh *WriteQueueHandler) Process(s nats.Subscription, m nats.Message) {
+	ctx := context.Background()
+	h := qh.h
+
+	qw, data, err := decodeQueuedWrite(m.Data())
+	if err != nil {
+		h.log.Error("Failed to decode queued write", zap.Error(err))
+		_ = m.Ack()
+		return
+	}
+	log := h.log.With(zap.String("receipt_id", qw.ReceiptID.String()), zap.String("bucket_id", qw.BucketID.String()))
+
+	var accepted int
+	bucket, err := h.BucketService.FindBucketByID(ctx, qw.BucketID)
+	if err == nil {
+		accepted, err = h.writeData(ctx, log, bucket, qw.Precision, qw.ContentType, data)
+	}
+
+	receipt := &influxdb.WriteReceipt{
+		ID:       qw.ReceiptID,
+		OrgID:    qw.OrgID,
+		BucketID: qw.BucketID,
+	}
+	switch e := err.(type) {
+	case nil:
+		receipt.Status = influxdb.WriteReceiptWritten
+		receipt.Accepted = accepted
+	case *influxdb.PartialWriteError:
+		receipt.Status = influxdb.WriteReceiptPartial
+		receipt.Message = e.Message
+		receipt.Accepted = e.Accepted
+		receipt.Rejected = e.Rejected
+		receipt.Rejections = e.Rejections
+	default:
+		if influxdb.ErrorCode(err) == influxdb.EInternal {
+			log.Error("Failed to write queued write", zap.Error(err))
+			return
+		}
+		receipt.Status = influxdb.WriteReceiptFailed
+		receipt.Message = err.Error()
+	}
+
+	if err := h.WriteReceiptService.UpdateWriteReceipt(ctx, receipt); err != nil {
+		if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			log.Error("Failed to update write receipt", zap.Error(err))
+			return
+		}
+		log.Info("Write receipt not found", zap.Error(err))
+	}
+
+	if err := m.Ack(); err != nil {
+		log.Info("Failed to ack queued write", zap.Error(err))
+	}
+}
@@ -0,0 +1,330 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/http/metric"
+	httpmock "github.com/influxdata/influxdb/http/mock"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/mock"
+	"go.uber.org/zap/zaptest"
+)
+
+// queuePublisher keeps the messages published to the write queue.
+type queuePublisher struct {
+	err      error
+	messages [][]byte
+}
+
+func (p *queuePublisher) Publish(subject string, r io.Reader) error {
+	if p.err != nil {
+		return p.err
+	}
+	msg, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// writeQueueFixture is the backend of a write handler queueing the writes
+// to a bucket, with receipts stored in a kv service.
+type writeQueueFixture struct {
+	backend   *APIBackend
+	publisher *queuePublisher
+	store     kv.Store
+	auth      *influxdb.Authorization
+}
+
+func newWriteQueueFixture(t *testing.T) *writeQueueFixture {
+	t.Helper()
+
+	orgs := mock.NewOrganizationService()
+	orgs.FindOrganizationF = func(ctx context.Context, filter influxdb.OrganizationFilter) (*influxdb.Organization, error) {
+		return testOrg("043e0780ee2b1000"), nil
+	}
+	buckets := mock.NewBucketService()
+	buckets.FindBucketFn = func(context.Context, influxdb.BucketFilter) (*influxdb.Bucket, error) {
+		return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+	}
+	buckets.FindBucketByIDFn = func(context.Context, influxdb.ID) (*influxdb.Bucket, error) {
+		return testBucket("043e0780ee2b1000", "04504b356e23b000"), nil
+	}
+
+	store := inmem.NewKVStore()
+	receipts := kv.NewService(zaptest.NewLogger(t), store)
+	if err := receipts.Initialize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	f := &writeQueueFixture{
+		publisher: &queuePublisher{},
+		store:     store,
+		auth:      bucketWritePermission("043e0780ee2b1000", "04504b356e23b000"),
+	}
+	f.backend = &APIBackend{
+		HTTPErrorHandler:    DefaultErrorHandler,
+		Logger:              zaptest.NewLogger(t),
+		OrganizationService: orgs,
+		BucketService:       buckets,
+		PointsWriter:        &mock.PointsWriter{},
+		WriteEventRecorder:  &metric.NopEventRecorder{},
+		WriteQueue:          f.publisher,
+		WriteReceiptService: receipts,
+	}
+	return f
+}
+
+// serve serves a request with the write handler, authorized by f.auth.
+func (f *writeQueueFixture) serve(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	writeHandler := NewWriteHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), f.backend))
+	handler := httpmock.NewAuthMiddlewareHandler(writeHandler, f.auth)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(method, "http://localhost:9999"+path, strings.NewReader(body)))
+	return w
+}
+
+// write queues a write of the body, and returns its receipt.
+func (f *writeQueueFixture) write(t *testing.T, body string) *influxdb.WriteReceipt {
+	t.Helper()
+
+	w := f.serve(t, "POST", "/api/v2/write?org=043e0780ee2b1000&bucket=04504b356e23b000", body)
+	if got, want := w.Code, http.StatusAccepted; got != want {
+		t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+	}
+	var receipt influxdb.WriteReceipt
+	if err := json.NewDecoder(w.Body).Decode(&receipt); err != nil {
+		t.Fatal(err)
+	}
+	return &receipt
+}
+
+// receipts returns the receipts stored.
+func (f *writeQueueFixture) receipts(t *testing.T) []*influxdb.WriteReceipt {
+	t.Helper()
+
+	var receipts []*influxdb.WriteReceipt
+	err := f.store.View(context.Background(), func(tx kv.Tx) error {
+		b, err := tx.Bucket([]byte("writereceiptsv1"))
+		if err != nil {
+			return err
+		}
+		c, err := b.ForwardCursor(nil)
+		if err != nil {
+			return err
+		}
+		for k, v := c.Next(); k != nil; k, v = c.Next() {
+			var r influxdb.WriteReceipt
+			if err := json.Unmarshal(v, &r); err != nil {
+				return err
+			}
+			receipts = append(receipts, &r)
+		}
+		return c.Err()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return receipts
+}
+
+func TestWriteHandler_queueWrite(t *testing.T) {
+	f := newWriteQueueFixture(t)
+
+	receipt := f.write(t, "m1,t1=v1 f1=1\n")
+	if receipt.Status != influxdb.WriteReceiptPending || !receipt.ID.Valid() {
+		t.Fatalf("expected a pending receipt, got %+v", receipt)
+	}
+	if len(f.publisher.messages) != 1 {
+		t.Fatalf("expected 1 queued write, got %d", len(f.publisher.messages))
+	}
+
+	qw, data, err := decodeQueuedWrite(f.publisher.messages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qw.ReceiptID != receipt.ID || qw.BucketID != receipt.BucketID || string(data) != "m1,t1=v1 f1=1\n" {
+		t.Fatalf("unexpected queued write %+v: %q", qw, data)
+	}
+}
+
+func TestWriteHandler_queueWrite_tooLarge(t *testing.T) {
+	f := newWriteQueueFixture(t)
+	f.backend.WriteQueueMaxBytes = 8
+
+	w := f.serve(t, "POST", "/api/v2/write?org=043e0780ee2b1000&bucket=04504b356e23b000", "m1,t1=v1 f1=1\n")
+	if got, want := w.Code, http.StatusRequestEntityTooLarge; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+
+	// No receipt is left pending for a write that was never queued.
+	if receipts := f.receipts(t); len(receipts) != 0 {
+		t.Fatalf("expected no receipts, got %d", len(receipts))
+	}
+}
+
+func TestWriteHandler_queueWrite_publishError(t *testing.T) {
+	f := newWriteQueueFixture(t)
+	f.publisher.err = errors.New("streaming server unavailable")
+
+	w := f.serve(t, "POST", "/api/v2/write?org=043e0780ee2b1000&bucket=04504b356e23b000", "m1,t1=v1 f1=1\n")
+	if got, want := w.Code, http.StatusInternalServerError; got != want {
+		t.Fatalf("unexpected status code: got %d want %d", got, want)
+	}
+
+	// The receipt of the write that could not be queued is failed.
+	receipts := f.receipts(t)
+	if len(receipts) != 1 {
+		t.Fatalf("expected 1 receipt, got %d", len(receipts))
+	}
+	if receipts[0].Status != influxdb.WriteReceiptFailed {
+		t.Fatalf("expected a failed receipt, got %+v", receipts[0])
+	}
+}
+
+// queueMessage is a queued write delivered to a WriteQueueHandler.
+type queueMessage struct {
+	data  []byte
+	acked bool
+}
+
+func (m *queueMessage) Data() []byte { return m.data }
+func (m *queueMessage) Ack() error {
+	m.acked = true
+	return nil
+}
+
+// process processes the queued writes with a WriteQueueHandler, and returns
+// their messages.
+func (f *writeQueueFixture) process(t *testing.T) []*queueMessage {
+	t.Helper()
+
+	qh := NewWriteQueueHandler(zaptest.NewLogger(t), NewWriteBackend(zaptest.NewLogger(t), f.backend))
+	var messages []*queueMessage
+	for _, data := range f.publisher.messages {
+		m := &queueMessage{data: data}
+		qh.Process(nil, m)
+		messages = append(messages, m)
+	}
+	f.publisher.messages = nil
+	return messages
+}
+
+// getReceipt gets the receipt with the receipts route.
+func (f *writeQueueFixture) getReceipt(t *testing.T, id influxdb.ID) *influxdb.WriteReceipt {
+	t.Helper()
+
+	w := f.serve(t, "GET", "/api/v2/write/receipts/"+id.String(), "")
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+	}
+	var receipt influxdb.WriteReceipt
+	if err := json.NewDecoder(w.Body).Decode(&receipt); err != nil {
+		t.Fatal(err)
+	}
+	return &receipt
+}
+
+func TestWriteQueueHandler_Process(t *testing.T) {
+	f := newWriteQueueFixture(t)
+	pw := f.backend.PointsWriter.(*mock.PointsWriter)
+
+	written := f.write(t, "m1,t1=v1 f1=1\nm1,t1=v1 f1=2\n")
+	partial := f.write(t, "m1,t1=v1 f1=3\ninvalid\n")
+	for _, m := range f.process(t) {
+		if !m.acked {
+			t.Fatal("expected the queued write to be acked")
+		}
+	}
+	if n := pw.WritePointsCalled(); n != 2 {
+		t.Fatalf("expected 2 writes, got %d", n)
+	}
+
+	if got := f.getReceipt(t, written.ID); got.Status != influxdb.WriteReceiptWritten || got.Accepted != 2 || got.Rejected != 0 {
+		t.Fatalf("unexpected receipt of written write %+v", got)
+	}
+
+	got := f.getReceipt(t, partial.ID)
+	if got.Status != influxdb.WriteReceiptPartial || got.Accepted != 1 || got.Rejected != 1 {
+		t.Fatalf("unexpected receipt of partial write %+v", got)
+	}
+	if len(got.Rejections) != 1 || got.Rejections[0].Line != 2 || got.Rejections[0].Reason != influxdb.WriteRejectedParseError {
+		t.Fatalf("unexpected rejections %+v", got.Rejections)
+	}
+}
+
+func TestWriteQueueHandler_Process_internalError(t *testing.T) {
+	f := newWriteQueueFixture(t)
+	f.backend.PointsWriter = &mock.PointsWriter{Err: errors.New("disk full")}
+
+	receipt := f.write(t, "m1,t1=v1 f1=1\n")
+
+	// The write is not acked so that it is delivered again, and its receipt
+	// stays pending meanwhile.
+	if m := f.process(t); m[0].acked {
+		t.Fatal("expected the queued write not to be acked")
+	}
+	if got := f.getReceipt(t, receipt.ID); got.Status != influxdb.WriteReceiptPending {
+		t.Fatalf("expected a pending receipt, got %+v", got)
+	}
+}
+
+func TestWriteHandler_handleGetWriteReceipt(t *testing.T) {
+	f := newWriteQueueFixture(t)
+	receipt := f.write(t, "m1,t1=v1 f1=1\n")
+
+	tests := []struct {
+		name string
+		id   string
+		auth *influxdb.Authorization
+		code int
+	}{
+		{
+			name: "receipt of a bucket written to",
+			id:   receipt.ID.String(),
+			auth: f.auth,
+			code: http.StatusOK,
+		},
+		{
+			name: "receipt of a bucket not written to",
+			id:   receipt.ID.String(),
+			auth: bucketWritePermission("043e0780ee2b1000", "04504b356e23b001"),
+			code: http.StatusForbidden,
+		},
+		{
+			name: "invalid ID",
+			id:   "invalid",
+			auth: f.auth,
+			code: http.StatusBadRequest,
+		},
+		{
+			name: "receipt not found",
+			id:   "020f755c3c082000",
+			auth: f.auth,
+			code: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f.auth = tt.auth
+			w := f.serve(t, "GET", "/api/v2/write/receipts/"+tt.id, "")
+			if got, want := w.Code, tt.code; got != want {
+				t.Errorf("unexpected status code: got %d want %d: %s", got, want, w.Body.String())
+			}
+		})
+	}
+}
@@ -61,6 +61,10 @@ func (s *KVStore) Backup(ctx context.Context, w io.Writer) error {
 	panic("not implemented")
 }
 
+func (s *KVStore) Restore(ctx context.Context, r io.Reader) error {
+	panic("not implemented")
+}
+
 // Flush removes all data from the buckets.  Used for testing.
 func (s *KVStore) Flush(ctx context.Context) {
 	s.mu.Lock()
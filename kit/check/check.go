@@ -17,6 +17,9 @@ type Status string
 const (
 	// StatusFail indicates a specific check has failed.
 	StatusFail Status = "fail"
+	// StatusWarn indicates a specific check has passed, with a problem that
+	// needs attention.
+	StatusWarn Status = "warn"
 	// StatusPass indicates a specific check has passed.
 	StatusPass Status = "pass"
 
@@ -86,8 +89,8 @@ func (c *Check) CheckHealth(ctx context.Context) Response {
 	}
 	for i, ch := range c.healthChecks {
 		resp := ch.Check(ctx)
-		if resp.Status != StatusPass && !overriding {
-			response.Status = resp.Status
+		if !overriding {
+			response.Status = worseStatus(response.Status, resp.Status)
 		}
 		response.Checks[i] = resp
 	}
@@ -114,8 +117,8 @@ func (c *Check) CheckReady(ctx context.Context) Response {
 	}
 	for i, c := range c.readyChecks {
 		resp := c.Check(ctx)
-		if resp.Status != StatusPass && !overriding {
-			response.Status = resp.Status
+		if !overriding {
+			response.Status = worseStatus(response.Status, resp.Status)
 		}
 		response.Checks[i] = resp
 	}
@@ -123,6 +126,15 @@ func (c *Check) CheckReady(ctx context.Context) Response {
 	return response
 }
 
+// worseStatus returns the worse of two statuses. A warning does not hide a
+// failure.
+func worseStatus(a, b Status) Status {
+	if b == StatusFail || (b == StatusWarn && a == StatusPass) {
+		return b
+	}
+	return a
+}
+
 // SetPassthrough allows you to set a handler to use if the request is not a ready or health check.
 // This can be useful if you intend to use this as a middleware.
 func (c *Check) SetPassthrough(h http.Handler) {
@@ -188,7 +200,7 @@ func (c *Check) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // accompanying the payload is the primary means for signaling the status of the
 // checks. The possible status codes are:
 //
-// - 200 OK: All checks pass.
+// - 200 OK: All checks pass, possibly with warnings.
 // - 503 Service Unavailable: Some checks are failing.
 // - 500 Internal Server Error: There was a problem serializing the Response.
 func writeResponse(w http.ResponseWriter, resp Response) {
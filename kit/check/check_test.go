@@ -75,6 +75,39 @@ func TestAddUnHealthyCheck(t *testing.T) {
 	}
 }
 
+func TestAddWarningCheck(t *testing.T) {
+	h := NewCheck()
+	h.AddHealthCheck(mockFail("failure"))
+	h.AddHealthCheck(mockCheck{status: StatusWarn, name: "warning"})
+	h.AddHealthCheck(mockPass("success"))
+
+	// A warning does not hide a failure.
+	if r := h.CheckHealth(context.Background()); r.Status != StatusFail {
+		t.Errorf("Health should fail, not %s", r.Status)
+	}
+
+	h = NewCheck()
+	h.AddHealthCheck(mockCheck{status: StatusWarn, name: "warning"})
+	h.AddHealthCheck(mockPass("success"))
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("a warning should be served with status 200, not %d", resp.StatusCode)
+	}
+	r, err := respBuilder(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != StatusWarn {
+		t.Errorf("Health should warn, not %s", r.Status)
+	}
+}
+
 func buildCheckWithServer() (*Check, *httptest.Server) {
 	c := NewCheck()
 	return c, httptest.NewServer(c)
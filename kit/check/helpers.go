@@ -64,6 +64,15 @@ func Info(msg string, args ...interface{}) Response {
 	}
 }
 
+// Warn is a utility function to generate a healthy status with a warning as a
+// printf message.
+func Warn(msg string, args ...interface{}) Response {
+	return Response{
+		Status:  StatusWarn,
+		Message: fmt.Sprintf(msg, args...),
+	}
+}
+
 // Error is a utility function for creating a response from an error message.
 func Error(err error) Response {
 	return Response{
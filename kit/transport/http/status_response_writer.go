@@ -56,3 +56,11 @@ func (w *StatusResponseWriter) StatusCodeClass() string {
 	}
 	return class
 }
+
+// Flush sends any buffered data to the client, if the underlying ResponseWriter
+// supports flushing.
+func (w *StatusResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -8,3 +8,7 @@ import (
 func (s *Service) Backup(ctx context.Context, w io.Writer) error {
 	return s.kv.Backup(ctx, w)
 }
+
+func (s *Service) Restore(ctx context.Context, r io.Reader) error {
+	return s.kv.Restore(ctx, r)
+}
@@ -0,0 +1,495 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var (
+	backupScheduleBucket    = []byte("backupschedulesv1")
+	backupScheduleRunBucket = []byte("backupschedulerunsv1")
+)
+
+var (
+	_ influxdb.BackupScheduleService = (*Service)(nil)
+	_ influxdb.BackupRunService      = (*Service)(nil)
+)
+
+func (s *Service) initializeBackupSchedules(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(backupScheduleBucket); err != nil {
+		return err
+	}
+	if _, err := tx.Bucket(backupScheduleRunBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindBackupScheduleByID returns a single backup schedule by ID.
+func (s *Service) FindBackupScheduleByID(ctx context.Context, id influxdb.ID) (*influxdb.BackupSchedule, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var bs *influxdb.BackupSchedule
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		bs, err = s.findBackupScheduleByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+func (s *Service) findBackupScheduleByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.BackupSchedule, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(backupScheduleBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, influxdb.ErrBackupScheduleNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bs influxdb.BackupSchedule
+	if err := json.Unmarshal(v, &bs); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return &bs, nil
+}
+
+// FindBackupSchedules returns a list of backup schedules that match filter and
+// the total count of matching backup schedules.
+func (s *Service) FindBackupSchedules(ctx context.Context, filter influxdb.BackupScheduleFilter) ([]*influxdb.BackupSchedule, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	schedules := []*influxdb.BackupSchedule{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(backupScheduleBucket)
+		if err != nil {
+			return err
+		}
+
+		cur, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			bs := &influxdb.BackupSchedule{}
+			if err := json.Unmarshal(v, bs); err != nil {
+				return &influxdb.Error{
+					Code: influxdb.EInternal,
+					Err:  err,
+				}
+			}
+			if filter.Name != nil && bs.Name != *filter.Name {
+				continue
+			}
+			if filter.Status != nil && bs.Status != *filter.Status {
+				continue
+			}
+			schedules = append(schedules, bs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return schedules, len(schedules), nil
+}
+
+// CreateBackupSchedule creates a new backup schedule and sets bs.ID with the
+// new identifier. Schedules are active unless created with another status.
+func (s *Service) CreateBackupSchedule(ctx context.Context, bs *influxdb.BackupSchedule) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if bs.Status == "" {
+		bs.Status = influxdb.Active
+	}
+	if err := bs.Valid(); err != nil {
+		return err
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if err := s.uniqueBackupScheduleName(ctx, tx, bs); err != nil {
+			return err
+		}
+
+		bs.ID = s.IDGenerator.ID()
+		now := s.TimeGenerator.Now()
+		bs.SetCreatedAt(now)
+		bs.SetUpdatedAt(now)
+		return s.putBackupSchedule(ctx, tx, bs)
+	})
+}
+
+// uniqueBackupScheduleName checks that no other backup schedule has the name of bs.
+func (s *Service) uniqueBackupScheduleName(ctx context.Context, tx Tx, bs *influxdb.BackupSchedule) error {
+	b, err := tx.Bucket(backupScheduleBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		var other influxdb.BackupSchedule
+		if err := json.Unmarshal(v, &other); err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		if other.Name == bs.Name && other.ID != bs.ID {
+			return &influxdb.Error{
+				Code: influxdb.EConflict,
+				Msg:  "backup schedule with name " + bs.Name + " already exists",
+			}
+		}
+	}
+	return nil
+}
+
+// PutBackupSchedule will put a backup schedule without setting an ID.
+func (s *Service) PutBackupSchedule(ctx context.Context, bs *influxdb.BackupSchedule) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putBackupSchedule(ctx, tx, bs)
+	})
+}
+
+func (s *Service) putBackupSchedule(ctx context.Context, tx Tx, bs *influxdb.BackupSchedule) error {
+	v, err := json.Marshal(bs)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := bs.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(backupScheduleBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodedID, v)
+}
+
+// UpdateBackupSchedule updates a single backup schedule with changeset.
+// Returns the new backup schedule state after update.
+func (s *Service) UpdateBackupSchedule(ctx context.Context, id influxdb.ID, upd influxdb.BackupScheduleUpdate) (*influxdb.BackupSchedule, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var bs *influxdb.BackupSchedule
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var err error
+		if bs, err = s.findBackupScheduleByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		upd.Apply(bs)
+		if err := bs.Valid(); err != nil {
+			return err
+		}
+		if upd.Name != nil {
+			if err := s.uniqueBackupScheduleName(ctx, tx, bs); err != nil {
+				return err
+			}
+		}
+
+		bs.SetUpdatedAt(s.TimeGenerator.Now())
+		return s.putBackupSchedule(ctx, tx, bs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bs, nil
+}
+
+// DeleteBackupSchedule removes a backup schedule by ID, along with its run history.
+func (s *Service) DeleteBackupSchedule(ctx context.Context, id influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findBackupScheduleByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(backupScheduleBucket)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(encodedID); err != nil {
+			return err
+		}
+
+		keys, err := s.backupRunKeys(tx, id)
+		if err != nil {
+			return err
+		}
+		return s.deleteBackupRuns(tx, keys)
+	})
+}
+
+// backupRunKey returns the key of a run, which sorts the runs of a schedule in
+// the order they were created.
+func backupRunKey(scheduleID, runID influxdb.ID) ([]byte, error) {
+	sid, err := scheduleID.Encode()
+	if err != nil {
+		return nil, err
+	}
+	rid, err := runID.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return append(sid, rid...), nil
+}
+
+// backupRunKeys returns the keys of all runs of the schedule, oldest first.
+func (s *Service) backupRunKeys(tx Tx, scheduleID influxdb.ID) ([][]byte, error) {
+	prefix, err := scheduleID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(backupScheduleRunBucket)
+	if err != nil {
+		return nil, err
+	}
+	cur, err := b.Cursor()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys [][]byte
+	for k, _ := cur.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cur.Next() {
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	return keys, nil
+}
+
+func (s *Service) deleteBackupRuns(tx Tx, keys [][]byte) error {
+	b, err := tx.Bucket(backupScheduleRunBucket)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindBackupRuns returns the runs of the backup schedule, newest first.
+func (s *Service) FindBackupRuns(ctx context.Context, scheduleID influxdb.ID) ([]*influxdb.BackupRun, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	runs := []*influxdb.BackupRun{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		if _, err := s.findBackupScheduleByID(ctx, tx, scheduleID); err != nil {
+			return err
+		}
+
+		keys, err := s.backupRunKeys(tx, scheduleID)
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(backupScheduleRunBucket)
+		if err != nil {
+			return err
+		}
+
+		for i := len(keys) - 1; i >= 0; i-- {
+			v, err := b.Get(keys[i])
+			if err != nil {
+				return err
+			}
+			r, err := unmarshalBackupRun(v)
+			if err != nil {
+				return err
+			}
+			runs = append(runs, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return runs, len(runs), nil
+}
+
+// FindBackupRunByID returns a single run of the backup schedule.
+func (s *Service) FindBackupRunByID(ctx context.Context, scheduleID, runID influxdb.ID) (*influxdb.BackupRun, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var r *influxdb.BackupRun
+	err := s.kv.View(ctx, func(tx Tx) error {
+		key, err := backupRunKey(scheduleID, runID)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+
+		b, err := tx.Bucket(backupScheduleRunBucket)
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(key)
+		if IsNotFound(err) {
+			return influxdb.ErrBackupRunNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		r, err = unmarshalBackupRun(v)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// CreateBackupRun adds a run to the history of its backup schedule and sets
+// r.ID with the new identifier. The oldest runs of the schedule are deleted
+// to keep at most influxdb.MaxBackupRuns of them.
+func (s *Service) CreateBackupRun(ctx context.Context, r *influxdb.BackupRun) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findBackupScheduleByID(ctx, tx, r.ScheduleID); err != nil {
+			return err
+		}
+
+		r.ID = s.IDGenerator.ID()
+		if err := s.putBackupRun(tx, r); err != nil {
+			return err
+		}
+
+		keys, err := s.backupRunKeys(tx, r.ScheduleID)
+		if err != nil {
+			return err
+		}
+		if n := len(keys) - influxdb.MaxBackupRuns; n > 0 {
+			return s.deleteBackupRuns(tx, keys[:n])
+		}
+		return nil
+	})
+}
+
+// UpdateBackupRun stores the current state of the run.
+func (s *Service) UpdateBackupRun(ctx context.Context, r *influxdb.BackupRun) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		key, err := backupRunKey(r.ScheduleID, r.ID)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Err:  err,
+			}
+		}
+
+		b, err := tx.Bucket(backupScheduleRunBucket)
+		if err != nil {
+			return err
+		}
+		if _, err := b.Get(key); IsNotFound(err) {
+			return influxdb.ErrBackupRunNotFound
+		} else if err != nil {
+			return err
+		}
+		return s.putBackupRun(tx, r)
+	})
+}
+
+// PutBackupRun will put a run of a backup schedule without setting an ID.
+func (s *Service) PutBackupRun(ctx context.Context, r *influxdb.BackupRun) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putBackupRun(tx, r)
+	})
+}
+
+func (s *Service) putBackupRun(tx Tx, r *influxdb.BackupRun) error {
+	key, err := backupRunKey(r.ScheduleID, r.ID)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	v, err := json.Marshal(r)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(backupScheduleRunBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, v)
+}
+
+func unmarshalBackupRun(v []byte) (*influxdb.BackupRun, error) {
+	r := &influxdb.BackupRun{}
+	if err := json.Unmarshal(v, r); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,76 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBoltBackupScheduleService(t *testing.T) {
+	influxdbtesting.BackupScheduleService(initBoltBackupScheduleService, t)
+}
+
+func TestInmemBackupScheduleService(t *testing.T) {
+	influxdbtesting.BackupScheduleService(initInmemBackupScheduleService, t)
+}
+
+func initBoltBackupScheduleService(f influxdbtesting.BackupScheduleFields, t *testing.T) (influxdbtesting.BackupScheduleRunService, func()) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initBackupScheduleService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initInmemBackupScheduleService(f influxdbtesting.BackupScheduleFields, t *testing.T) (influxdbtesting.BackupScheduleRunService, func()) {
+	s, closeInmem, err := NewTestInmemStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initBackupScheduleService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeInmem()
+	}
+}
+
+func initBackupScheduleService(s kv.Store, f influxdbtesting.BackupScheduleFields, t *testing.T) (influxdbtesting.BackupScheduleRunService, func()) {
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	svc.IDGenerator = f.IDGenerator
+	svc.TimeGenerator = f.TimeGenerator
+	if svc.TimeGenerator == nil {
+		svc.TimeGenerator = influxdb.RealTimeGenerator{}
+	}
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing backup schedule service: %v", err)
+	}
+	for _, bs := range f.BackupSchedules {
+		if err := svc.PutBackupSchedule(ctx, bs); err != nil {
+			t.Fatalf("failed to populate backup schedules: %v", err)
+		}
+	}
+	for _, r := range f.BackupRuns {
+		if err := svc.PutBackupRun(ctx, r); err != nil {
+			t.Fatalf("failed to populate backup runs: %v", err)
+		}
+	}
+	return svc, func() {
+		for _, bs := range f.BackupSchedules {
+			if err := svc.DeleteBackupSchedule(ctx, bs.ID); err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+				t.Logf("failed to remove backup schedule: %v", err)
+			}
+		}
+	}
+}
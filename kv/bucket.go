@@ -464,6 +464,18 @@ func (s *Service) createBucket(ctx context.Context, tx Tx, b *influxdb.Bucket) (
 		return err
 	}
 
+	if err := s.validRollupPolicies(ctx, tx, b); err != nil {
+		return err
+	}
+
+	if err := b.Codecs.Valid(); err != nil {
+		return err
+	}
+
+	if err := influxdb.ValidMaxSeries(b.MaxSeries); err != nil {
+		return err
+	}
+
 	if b.ID, err = s.generateBucketID(ctx, tx); err != nil {
 		return err
 	}
@@ -609,6 +621,59 @@ func (s *Service) validBucketName(ctx context.Context, tx Tx, b *influxdb.Bucket
 	return err
 }
 
+// validRollupPolicies returns an error if a rollup policy of the bucket is
+// invalid, targets a bucket of another organization, or makes data roll up
+// into the bucket itself, directly or through the policies of other buckets.
+func (s *Service) validRollupPolicies(ctx context.Context, tx Tx, b *influxdb.Bucket) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	seen := make(map[influxdb.ID]bool)
+	var visit func(id influxdb.ID) error
+	visit = func(id influxdb.ID) error {
+		if id == b.ID {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "rollup policies must not roll data up into its source bucket",
+			}
+		} else if seen[id] {
+			return nil
+		}
+		seen[id] = true
+
+		target, err := s.findBucketByID(ctx, tx, id)
+		if err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "rollup target bucket not found",
+				Err:  err,
+			}
+		}
+		if target.OrgID != b.OrgID {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "rollup target bucket must belong to the organization of the source bucket",
+			}
+		}
+		for _, p := range target.RollupPolicies {
+			if err := visit(p.TargetBucketID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, p := range b.RollupPolicies {
+		if err := p.Valid(); err != nil {
+			return err
+		}
+		if err := visit(p.TargetBucketID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UpdateBucket updates a bucket according the parameters set on upd.
 func (s *Service) UpdateBucket(ctx context.Context, id influxdb.ID, upd influxdb.BucketUpdate) (*influxdb.Bucket, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
@@ -649,6 +714,35 @@ func (s *Service) updateBucket(ctx context.Context, tx Tx, id influxdb.ID, upd i
 		b.RetentionPeriod = *upd.RetentionPeriod
 	}
 
+	if upd.ColdStoragePeriod != nil {
+		b.ColdStoragePeriod = *upd.ColdStoragePeriod
+	}
+
+	if upd.RollupPolicies != nil {
+		b.RollupPolicies = *upd.RollupPolicies
+		if err := s.validRollupPolicies(ctx, tx, b); err != nil {
+			return nil, err
+		}
+	}
+
+	if upd.Codecs != nil {
+		if err := upd.Codecs.Valid(); err != nil {
+			return nil, err
+		}
+		b.Codecs = *upd.Codecs
+	}
+
+	if upd.MaxSeries != nil {
+		if err := influxdb.ValidMaxSeries(*upd.MaxSeries); err != nil {
+			return nil, err
+		}
+		b.MaxSeries = *upd.MaxSeries
+	}
+
+	if upd.LastValueCache != nil {
+		b.LastValueCache = *upd.LastValueCache
+	}
+
 	if upd.Description != nil {
 		b.Description = *upd.Description
 	}
@@ -754,6 +848,10 @@ func (s *Service) deleteBucket(ctx context.Context, tx Tx, id influxdb.ID) error
 		}
 	}
 
+	if err := s.deleteBucketSchema(ctx, tx, id); err != nil {
+		return err
+	}
+
 	if err := s.deleteUserResourceMappings(ctx, tx, influxdb.UserResourceMappingFilter{
 		ResourceID:   id,
 		ResourceType: influxdb.BucketsResourceType,
@@ -0,0 +1,188 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var bucketSchemaBucket = []byte("bucketschemasv1")
+
+var _ influxdb.BucketSchemaService = (*Service)(nil)
+
+func (s *Service) initializeBucketSchemas(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(bucketSchemaBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindBucketSchema returns the schema of a bucket.
+func (s *Service) FindBucketSchema(ctx context.Context, bucketID influxdb.ID) (*influxdb.BucketSchema, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var bs *influxdb.BucketSchema
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		bs, err = s.findBucketSchema(ctx, tx, bucketID)
+		return err
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindBucketSchema,
+			Err: err,
+		}
+	}
+	return bs, nil
+}
+
+func (s *Service) findBucketSchema(ctx context.Context, tx Tx, bucketID influxdb.ID) (*influxdb.BucketSchema, error) {
+	encodedID, err := bucketID.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(bucketSchemaBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, influxdb.ErrBucketSchemaNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var bs influxdb.BucketSchema
+	if err := json.Unmarshal(v, &bs); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return &bs, nil
+}
+
+// PutBucketSchema sets the schema of the bucket bs.BucketID, replacing its
+// current schema. The organization of the schema is set to the one of the
+// bucket.
+func (s *Service) PutBucketSchema(ctx context.Context, bs *influxdb.BucketSchema) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := bs.Valid(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		b, err := s.findBucketByID(ctx, tx, bs.BucketID)
+		if err != nil {
+			return err
+		}
+		bs.OrgID = b.OrgID
+
+		if bs.Action == influxdb.SchemaActionRoute {
+			route, err := s.findBucketByID(ctx, tx, bs.RouteBucketID)
+			if err != nil {
+				return &influxdb.Error{
+					Code: influxdb.EInvalid,
+					Msg:  "route bucket not found",
+					Err:  err,
+				}
+			}
+			if route.OrgID != b.OrgID {
+				return &influxdb.Error{
+					Code: influxdb.EInvalid,
+					Msg:  "route bucket must belong to the organization of the bucket",
+				}
+			}
+		}
+
+		now := s.TimeGenerator.Now()
+		if prev, err := s.findBucketSchema(ctx, tx, bs.BucketID); err == nil {
+			bs.CreatedAt = prev.CreatedAt
+		} else if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			bs.SetCreatedAt(now)
+		} else {
+			return err
+		}
+		bs.SetUpdatedAt(now)
+
+		return s.putBucketSchema(ctx, tx, bs)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpPutBucketSchema,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+func (s *Service) putBucketSchema(ctx context.Context, tx Tx, bs *influxdb.BucketSchema) error {
+	v, err := json.Marshal(bs)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := bs.BucketID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(bucketSchemaBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodedID, v)
+}
+
+// DeleteBucketSchema removes the schema of a bucket.
+func (s *Service) DeleteBucketSchema(ctx context.Context, bucketID influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findBucketSchema(ctx, tx, bucketID); err != nil {
+			return err
+		}
+		return s.deleteBucketSchema(ctx, tx, bucketID)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteBucketSchema,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+func (s *Service) deleteBucketSchema(ctx context.Context, tx Tx, bucketID influxdb.ID) error {
+	encodedID, err := bucketID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(bucketSchemaBucket)
+	if err != nil {
+		return err
+	}
+	return b.Delete(encodedID)
+}
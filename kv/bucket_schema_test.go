@@ -0,0 +1,109 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/mock"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBoltBucketSchemaService(t *testing.T) {
+	influxdbtesting.BucketSchemaService(initBoltBucketSchemaService, t)
+}
+
+func TestInmemBucketSchemaService(t *testing.T) {
+	influxdbtesting.BucketSchemaService(initInmemBucketSchemaService, t)
+}
+
+func initBoltBucketSchemaService(f influxdbtesting.BucketSchemaFields, t *testing.T) (influxdb.BucketSchemaService, func()) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initBucketSchemaService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initInmemBucketSchemaService(f influxdbtesting.BucketSchemaFields, t *testing.T) (influxdb.BucketSchemaService, func()) {
+	s, closeInmem, err := NewTestInmemStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initBucketSchemaService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeInmem()
+	}
+}
+
+func initBucketSchemaService(s kv.Store, f influxdbtesting.BucketSchemaFields, t *testing.T) (*kv.Service, func()) {
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing bucket schema service: %v", err)
+	}
+	for _, b := range f.Buckets {
+		if err := svc.PutBucket(ctx, b); err != nil {
+			t.Fatalf("failed to populate buckets: %v", err)
+		}
+	}
+	// Schemas are created at the time of their fixture.
+	for _, bs := range f.BucketSchemas {
+		svc.TimeGenerator = mock.TimeGenerator{FakeValue: bs.CreatedAt}
+		if err := svc.PutBucketSchema(ctx, bs); err != nil {
+			t.Fatalf("failed to populate bucket schemas: %v", err)
+		}
+	}
+
+	svc.TimeGenerator = f.TimeGenerator
+	if svc.TimeGenerator == nil {
+		svc.TimeGenerator = influxdb.RealTimeGenerator{}
+	}
+	return svc, func() {
+		for _, b := range f.Buckets {
+			if err := svc.DeleteBucket(ctx, b.ID); err != nil {
+				t.Logf("failed to remove bucket: %v", err)
+			}
+		}
+	}
+}
+
+func TestBucketSchemaService_DeleteBucket(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+
+	bucket := &influxdb.Bucket{
+		ID:    influxdbtesting.MustIDBase16("020f755c3c083010"),
+		OrgID: influxdbtesting.MustIDBase16("020f755c3c083000"),
+		Name:  "metrics",
+	}
+	svc, done := initBucketSchemaService(s, influxdbtesting.BucketSchemaFields{
+		Buckets: []*influxdb.Bucket{bucket},
+		BucketSchemas: []*influxdb.BucketSchema{
+			{BucketID: bucket.ID, Action: influxdb.SchemaActionReject},
+		},
+	}, t)
+	defer done()
+
+	// Deleting a bucket deletes its schema.
+	ctx := context.Background()
+	if err := svc.DeleteBucket(ctx, bucket.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.FindBucketSchema(ctx, bucket.ID); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("expected not found error: got %v", err)
+	}
+}
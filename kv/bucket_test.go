@@ -3,6 +3,7 @@ package kv_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/influxdata/influxdb"
 	"github.com/influxdata/influxdb/kv"
@@ -63,3 +64,80 @@ func initBucketService(s kv.Store, f influxdbtesting.BucketFields, t *testing.T)
 		}
 	}
 }
+
+func TestService_RollupPolicies(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing service: %v", err)
+	}
+
+	org1 := &influxdb.Organization{Name: "org1"}
+	org2 := &influxdb.Organization{Name: "org2"}
+	for _, o := range []*influxdb.Organization{org1, org2} {
+		if err := svc.CreateOrganization(ctx, o); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	raw := &influxdb.Bucket{OrgID: org1.ID, Name: "raw"}
+	hourly := &influxdb.Bucket{OrgID: org1.ID, Name: "hourly"}
+	other := &influxdb.Bucket{OrgID: org2.ID, Name: "other"}
+	for _, b := range []*influxdb.Bucket{raw, hourly, other} {
+		if err := svc.CreateBucket(ctx, b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policy := func(target influxdb.ID) []influxdb.RollupPolicy {
+		return []influxdb.RollupPolicy{{
+			Every:          time.Hour,
+			Functions:      []influxdb.RollupFunction{influxdb.RollupMean, influxdb.RollupMax},
+			TargetBucketID: target,
+		}}
+	}
+
+	daily := &influxdb.Bucket{OrgID: org1.ID, Name: "daily", RollupPolicies: policy(hourly.ID)}
+	if err := svc.CreateBucket(ctx, daily); err != nil {
+		t.Fatalf("unexpected error creating bucket with rollup policy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		bucketID influxdb.ID
+		policies []influxdb.RollupPolicy
+		valid    bool
+	}{
+		{name: "valid", bucketID: raw.ID, policies: policy(hourly.ID), valid: true},
+		{name: "unknown function", bucketID: raw.ID, policies: []influxdb.RollupPolicy{{Every: time.Hour, Functions: []influxdb.RollupFunction{"median"}, TargetBucketID: hourly.ID}}},
+		{name: "no window", bucketID: raw.ID, policies: []influxdb.RollupPolicy{{Functions: []influxdb.RollupFunction{influxdb.RollupMean}, TargetBucketID: hourly.ID}}},
+		{name: "other organization", bucketID: raw.ID, policies: policy(other.ID)},
+		{name: "missing target", bucketID: raw.ID, policies: policy(influxdb.ID(1))},
+		{name: "self", bucketID: raw.ID, policies: policy(raw.ID)},
+		{name: "cycle", bucketID: hourly.ID, policies: policy(daily.ID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies := tt.policies
+			_, err := svc.UpdateBucket(ctx, tt.bucketID, influxdb.BucketUpdate{RollupPolicies: &policies})
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if !tt.valid && influxdb.ErrorCode(err) != influxdb.EInvalid {
+				t.Fatalf("got error %v, expected invalid", err)
+			}
+		})
+	}
+
+	b, err := svc.FindBucketByID(ctx, raw.ID)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(b.RollupPolicies) != 1 || b.RollupPolicies[0].TargetBucketID != hourly.ID {
+		t.Fatalf("unexpected rollup policies %+v", b.RollupPolicies)
+	}
+}
@@ -0,0 +1,264 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var dbrpMappingBucket = []byte("dbrpmappingsv1")
+
+var _ influxdb.DBRPMappingService = (*Service)(nil)
+
+func (s *Service) initializeDBRPMappings(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(dbrpMappingBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dbrpMappingKey returns the key of the mapping of a cluster, database and
+// retention policy. Their names cannot contain a slash, so the keys of the
+// mappings of a database share the prefix cluster/db/.
+func dbrpMappingKey(cluster, db, rp string) []byte {
+	return []byte(strings.Join([]string{cluster, db, rp}, "/"))
+}
+
+// FindBy returns the dbrp mapping of the cluster, database and retention policy.
+func (s *Service) FindBy(ctx context.Context, cluster, db, rp string) (*influxdb.DBRPMapping, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var m *influxdb.DBRPMapping
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		m, err = s.findDBRPMapping(ctx, tx, cluster, db, rp)
+		return err
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindDBRPMapping,
+			Err: err,
+		}
+	}
+	return m, nil
+}
+
+func (s *Service) findDBRPMapping(ctx context.Context, tx Tx, cluster, db, rp string) (*influxdb.DBRPMapping, error) {
+	b, err := tx.Bucket(dbrpMappingBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(dbrpMappingKey(cluster, db, rp))
+	if IsNotFound(err) {
+		return nil, influxdb.ErrDBRPMappingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m influxdb.DBRPMapping
+	if err := json.Unmarshal(v, &m); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return &m, nil
+}
+
+// Find returns the first dbrp mapping matching the filter, ordered by
+// cluster, database and retention policy.
+func (s *Service) Find(ctx context.Context, filter influxdb.DBRPMappingFilter) (*influxdb.DBRPMapping, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if filter.Cluster == nil && filter.Database == nil && filter.RetentionPolicy == nil && filter.Default == nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Op:   influxdb.OpFindDBRPMapping,
+			Msg:  "no filter parameters provided",
+		}
+	}
+
+	var m *influxdb.DBRPMapping
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return s.forEachDBRPMapping(ctx, tx, filter, func(dbrp *influxdb.DBRPMapping) bool {
+			m = dbrp
+			return false
+		})
+	})
+	if err == nil && m == nil {
+		err = influxdb.ErrDBRPMappingNotFound
+	}
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindDBRPMapping,
+			Err: err,
+		}
+	}
+	return m, nil
+}
+
+// FindMany returns the dbrp mappings matching the filter, and their count.
+func (s *Service) FindMany(ctx context.Context, filter influxdb.DBRPMappingFilter, opt ...influxdb.FindOptions) ([]*influxdb.DBRPMapping, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	ms := []*influxdb.DBRPMapping{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return s.forEachDBRPMapping(ctx, tx, filter, func(m *influxdb.DBRPMapping) bool {
+			ms = append(ms, m)
+			return true
+		})
+	})
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindDBRPMappings,
+			Err: err,
+		}
+	}
+	return ms, len(ms), nil
+}
+
+func (s *Service) forEachDBRPMapping(ctx context.Context, tx Tx, filter influxdb.DBRPMappingFilter, fn func(*influxdb.DBRPMapping) bool) error {
+	b, err := tx.Bucket(dbrpMappingBucket)
+	if err != nil {
+		return err
+	}
+
+	cur, err := b.Cursor()
+	if err != nil {
+		return err
+	}
+
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		m := &influxdb.DBRPMapping{}
+		if err := json.Unmarshal(v, m); err != nil {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Err:  err,
+			}
+		}
+		if !filterDBRPMapping(filter, m) {
+			continue
+		}
+		if !fn(m) {
+			break
+		}
+	}
+	return nil
+}
+
+func filterDBRPMapping(filter influxdb.DBRPMappingFilter, m *influxdb.DBRPMapping) bool {
+	return (filter.Cluster == nil || *filter.Cluster == m.Cluster) &&
+		(filter.Database == nil || *filter.Database == m.Database) &&
+		(filter.RetentionPolicy == nil || *filter.RetentionPolicy == m.RetentionPolicy) &&
+		(filter.Default == nil || *filter.Default == m.Default)
+}
+
+// Create creates a dbrp mapping. Creating a mapping identical to an existing
+// one is not an error. A default mapping replaces the previous default mapping
+// of the cluster and database.
+func (s *Service) Create(ctx context.Context, m *influxdb.DBRPMapping) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		prev, err := s.findDBRPMapping(ctx, tx, m.Cluster, m.Database, m.RetentionPolicy)
+		if err == nil {
+			if prev.Equal(m) {
+				return nil
+			}
+			return influxdb.ErrDBRPMappingExists
+		} else if influxdb.ErrorCode(err) != influxdb.ENotFound {
+			return err
+		}
+
+		if m.Default {
+			if err := s.clearDefaultDBRPMapping(ctx, tx, m.Cluster, m.Database); err != nil {
+				return err
+			}
+		}
+		return s.putDBRPMapping(ctx, tx, m)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateDBRPMapping,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// clearDefaultDBRPMapping unsets the default mapping of a cluster and database.
+func (s *Service) clearDefaultDBRPMapping(ctx context.Context, tx Tx, cluster, db string) error {
+	isDefault := true
+	filter := influxdb.DBRPMappingFilter{
+		Cluster:  &cluster,
+		Database: &db,
+		Default:  &isDefault,
+	}
+
+	var defaults []*influxdb.DBRPMapping
+	if err := s.forEachDBRPMapping(ctx, tx, filter, func(m *influxdb.DBRPMapping) bool {
+		defaults = append(defaults, m)
+		return true
+	}); err != nil {
+		return err
+	}
+
+	for _, m := range defaults {
+		m.Default = false
+		if err := s.putDBRPMapping(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) putDBRPMapping(ctx context.Context, tx Tx, m *influxdb.DBRPMapping) error {
+	v, err := json.Marshal(m)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(dbrpMappingBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(dbrpMappingKey(m.Cluster, m.Database, m.RetentionPolicy), v)
+}
+
+// Delete removes the dbrp mapping of the cluster, database and retention
+// policy. Deleting a mapping that does not exist is not an error.
+func (s *Service) Delete(ctx context.Context, cluster, db, rp string) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(dbrpMappingBucket)
+		if err != nil {
+			return err
+		}
+		return b.Delete(dbrpMappingKey(cluster, db, rp))
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteDBRPMapping,
+			Err: err,
+		}
+	}
+	return nil
+}
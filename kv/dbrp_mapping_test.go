@@ -0,0 +1,83 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBoltDBRPMappingService(t *testing.T) {
+	t.Run("CreateDBRPMapping", func(t *testing.T) { influxdbtesting.CreateDBRPMapping(initBoltDBRPMappingService, t) })
+	t.Run("FindDBRPMappings", func(t *testing.T) { influxdbtesting.FindDBRPMappings(initBoltDBRPMappingService, t) })
+	t.Run("FindDBRPMappingByKey", func(t *testing.T) { influxdbtesting.FindDBRPMappingByKey(initBoltDBRPMappingService, t) })
+	t.Run("FindDBRPMapping", func(t *testing.T) { influxdbtesting.FindDBRPMapping(initBoltDBRPMappingService, t) })
+	t.Run("DeleteDBRPMapping", func(t *testing.T) { influxdbtesting.DeleteDBRPMapping(initBoltDBRPMappingService, t) })
+}
+
+func initBoltDBRPMappingService(f influxdbtesting.DBRPMappingFields, t *testing.T) (influxdb.DBRPMappingService, func()) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initDBRPMappingService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initDBRPMappingService(s kv.Store, f influxdbtesting.DBRPMappingFields, t *testing.T) (influxdb.DBRPMappingService, func()) {
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing dbrp mapping service: %v", err)
+	}
+	if err := f.Populate(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+	return svc, func() {
+		if err := influxdbtesting.CleanupDBRPMappings(ctx, svc); err != nil {
+			t.Logf("failed to remove dbrp mappings: %v", err)
+		}
+	}
+}
+
+func TestDBRPMappingService_CreateDefault(t *testing.T) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+	defer closeBolt()
+
+	svc, done := initDBRPMappingService(s, influxdbtesting.DBRPMappingFields{}, t)
+	defer done()
+
+	ctx := context.Background()
+	for _, rp := range []string{"autogen", "weekly"} {
+		if err := svc.Create(ctx, &influxdb.DBRPMapping{
+			Cluster:         "cluster",
+			Database:        "db",
+			RetentionPolicy: rp,
+			Default:         true,
+			OrganizationID:  influxdbtesting.MustIDBase16("020f755c3c082000"),
+			BucketID:        influxdbtesting.MustIDBase16("020f755c3c082001"),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	isDefault := true
+	ms, _, err := svc.FindMany(ctx, influxdb.DBRPMappingFilter{Default: &isDefault})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 1 || ms[0].RetentionPolicy != "weekly" {
+		t.Fatalf("unexpected default mappings: %+v", ms)
+	}
+}
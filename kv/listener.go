@@ -0,0 +1,409 @@
+package kv
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var (
+	listenerBucket       = []byte("listenersv1")
+	listenerOffsetBucket = []byte("listeneroffsetsv1")
+)
+
+var (
+	_ influxdb.ListenerService       = (*Service)(nil)
+	_ influxdb.ListenerOffsetService = (*Service)(nil)
+)
+
+func (s *Service) initializeListeners(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(listenerBucket); err != nil {
+		return err
+	}
+	if _, err := tx.Bucket(listenerOffsetBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindListenerByID returns a single listener by ID.
+func (s *Service) FindListenerByID(ctx context.Context, id influxdb.ID) (*influxdb.Listener, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var l *influxdb.Listener
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		l, err = s.findListenerByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindListenerByID,
+			Err: err,
+		}
+	}
+	return l, nil
+}
+
+func (s *Service) findListenerByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.Listener, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(listenerBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, influxdb.ErrListenerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var l influxdb.Listener
+	if err := json.Unmarshal(v, &l); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return &l, nil
+}
+
+// FindListeners returns a list of listeners that match filter and the total
+// count of matching listeners.
+func (s *Service) FindListeners(ctx context.Context, filter influxdb.ListenerFilter) ([]*influxdb.Listener, int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	listeners := []*influxdb.Listener{}
+	err := s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(listenerBucket)
+		if err != nil {
+			return err
+		}
+
+		cur, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			l := &influxdb.Listener{}
+			if err := json.Unmarshal(v, l); err != nil {
+				return &influxdb.Error{
+					Code: influxdb.EInternal,
+					Err:  err,
+				}
+			}
+			if filter.OrgID != nil && l.OrgID != *filter.OrgID {
+				continue
+			}
+			if filter.Status != nil && l.Status != *filter.Status {
+				continue
+			}
+			listeners = append(listeners, l)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, &influxdb.Error{
+			Op:  influxdb.OpFindListeners,
+			Err: err,
+		}
+	}
+	return listeners, len(listeners), nil
+}
+
+// CreateListener creates a new listener and sets l.ID with the new
+// identifier. Listeners are active unless created with another status.
+func (s *Service) CreateListener(ctx context.Context, l *influxdb.Listener) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if l.Status == "" {
+		l.Status = influxdb.Active
+	}
+	if err := l.Valid(); err != nil {
+		return err
+	}
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		// The listener only writes with the authorization of a token its
+		// creator has, never with an authorization picked by ID.
+		l.AuthorizationID = 0
+		if err := s.authorizeListener(ctx, tx, l); err != nil {
+			return err
+		}
+
+		l.ID = s.IDGenerator.ID()
+		now := s.TimeGenerator.Now()
+		l.SetCreatedAt(now)
+		l.SetUpdatedAt(now)
+		return s.putListener(ctx, tx, l)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateListener,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// authorizeListener checks that the bucket of the listener is in its
+// organization, and that the token of the listener is allowed to write to the
+// bucket. It replaces the token with the ID of its authorization.
+func (s *Service) authorizeListener(ctx context.Context, tx Tx, l *influxdb.Listener) error {
+	bucket, err := s.findBucketByID(ctx, tx, l.BucketID)
+	if err != nil {
+		return err
+	}
+	if bucket.OrgID != l.OrgID {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "bucket of listener is not in its organization",
+		}
+	}
+
+	if l.Token == "" {
+		if l.AuthorizationID.Valid() {
+			return nil
+		}
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "listener requires a token",
+		}
+	}
+
+	a, err := s.findAuthorizationByToken(ctx, tx, l.Token)
+	if err != nil {
+		if influxdb.ErrorCode(err) == influxdb.ENotFound {
+			return &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "invalid listener token",
+			}
+		}
+		return err
+	}
+
+	p, err := influxdb.NewPermissionAtID(l.BucketID, influxdb.WriteAction, influxdb.BucketsResourceType, l.OrgID)
+	if err != nil {
+		return err
+	}
+	if !a.Allowed(*p) {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "listener token is not allowed to write to the bucket",
+		}
+	}
+
+	l.AuthorizationID = a.ID
+	l.Token = ""
+	return nil
+}
+
+// PutListener will put a listener without setting an ID.
+func (s *Service) PutListener(ctx context.Context, l *influxdb.Listener) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putListener(ctx, tx, l)
+	})
+}
+
+func (s *Service) putListener(ctx context.Context, tx Tx, l *influxdb.Listener) error {
+	v, err := json.Marshal(l)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := l.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(listenerBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodedID, v)
+}
+
+// UpdateListener updates a single listener with changeset.
+// Returns the new listener state after update.
+func (s *Service) UpdateListener(ctx context.Context, id influxdb.ID, upd influxdb.ListenerUpdate) (*influxdb.Listener, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var l *influxdb.Listener
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		var err error
+		if l, err = s.findListenerByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		upd.Apply(l)
+		if err := l.Valid(); err != nil {
+			return err
+		}
+		if err := s.authorizeListener(ctx, tx, l); err != nil {
+			return err
+		}
+
+		l.SetUpdatedAt(s.TimeGenerator.Now())
+		return s.putListener(ctx, tx, l)
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpUpdateListener,
+			Err: err,
+		}
+	}
+	return l, nil
+}
+
+// DeleteListener removes a listener by ID, along with its consumer offsets.
+func (s *Service) DeleteListener(ctx context.Context, id influxdb.ID) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		if _, err := s.findListenerByID(ctx, tx, id); err != nil {
+			return err
+		}
+
+		encodedID, err := id.Encode()
+		if err != nil {
+			return err
+		}
+		b, err := tx.Bucket(listenerBucket)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(encodedID); err != nil {
+			return err
+		}
+
+		ob, err := tx.Bucket(listenerOffsetBucket)
+		if err != nil {
+			return err
+		}
+		cur, err := ob.Cursor()
+		if err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		for k, _ := cur.Seek(encodedID); k != nil && bytes.HasPrefix(k, encodedID); k, _ = cur.Next() {
+			keys = append(keys, append([]byte(nil), k...))
+		}
+		for _, k := range keys {
+			if err := ob.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteListener,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// listenerOffsetKey returns the key of the offset of a partition of a topic,
+// prefixed by the ID of the listener.
+func listenerOffsetKey(id influxdb.ID, topic string, partition int) ([]byte, error) {
+	key, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+	key = append(key, topic...)
+	key = append(key, 0)
+	return strconv.AppendInt(key, int64(partition), 10), nil
+}
+
+// FindListenerOffset returns the offset of the next message of a partition of
+// a topic consumed by the listener, or -1 if the listener has not consumed the
+// partition yet.
+func (s *Service) FindListenerOffset(ctx context.Context, id influxdb.ID, topic string, partition int) (int64, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	key, err := listenerOffsetKey(id, topic, partition)
+	if err != nil {
+		return 0, err
+	}
+
+	offset := int64(-1)
+	err = s.kv.View(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(listenerOffsetBucket)
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(key)
+		if IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(v) != 8 {
+			return &influxdb.Error{
+				Code: influxdb.EInternal,
+				Msg:  "invalid listener offset",
+			}
+		}
+		offset = int64(binary.BigEndian.Uint64(v))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// SetListenerOffset sets the offset of the next message of a partition of a
+// topic consumed by the listener.
+func (s *Service) SetListenerOffset(ctx context.Context, id influxdb.ID, topic string, partition int, offset int64) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	key, err := listenerOffsetKey(id, topic, partition)
+	if err != nil {
+		return err
+	}
+
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], uint64(offset))
+	return s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(listenerOffsetBucket)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, v[:])
+	})
+}
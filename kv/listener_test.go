@@ -0,0 +1,81 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBoltListenerService(t *testing.T) {
+	influxdbtesting.ListenerService(initBoltListenerService, t)
+}
+
+func TestInmemListenerService(t *testing.T) {
+	influxdbtesting.ListenerService(initInmemListenerService, t)
+}
+
+func initBoltListenerService(f influxdbtesting.ListenerFields, t *testing.T) (influxdbtesting.ListenerAndOffsetService, func()) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initListenerService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initInmemListenerService(f influxdbtesting.ListenerFields, t *testing.T) (influxdbtesting.ListenerAndOffsetService, func()) {
+	s, closeInmem, err := NewTestInmemStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initListenerService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeInmem()
+	}
+}
+
+func initListenerService(s kv.Store, f influxdbtesting.ListenerFields, t *testing.T) (influxdbtesting.ListenerAndOffsetService, func()) {
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	svc.IDGenerator = f.IDGenerator
+	svc.TimeGenerator = f.TimeGenerator
+	if svc.TimeGenerator == nil {
+		svc.TimeGenerator = influxdb.RealTimeGenerator{}
+	}
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing listener service: %v", err)
+	}
+	for _, b := range f.Buckets {
+		if err := svc.PutBucket(ctx, b); err != nil {
+			t.Fatalf("failed to populate buckets: %v", err)
+		}
+	}
+	for _, a := range f.Authorizations {
+		if err := svc.PutAuthorization(ctx, a); err != nil {
+			t.Fatalf("failed to populate authorizations: %v", err)
+		}
+	}
+	for _, l := range f.Listeners {
+		if err := svc.PutListener(ctx, l); err != nil {
+			t.Fatalf("failed to populate listeners: %v", err)
+		}
+	}
+	return svc, func() {
+		for _, l := range f.Listeners {
+			if err := svc.DeleteListener(ctx, l.ID); err != nil && influxdb.ErrorCode(err) != influxdb.ENotFound {
+				t.Logf("failed to remove listener: %v", err)
+			}
+		}
+	}
+}
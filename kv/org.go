@@ -282,6 +282,10 @@ func (s *Service) createOrganization(ctx context.Context, tx Tx, o *influxdb.Org
 		return err
 	}
 
+	if err := influxdb.ValidMaxSeries(o.MaxSeries); err != nil {
+		return err
+	}
+
 	if o.ID, err = s.generateOrgID(ctx, tx); err != nil {
 		return err
 	}
@@ -444,6 +448,13 @@ func (s *Service) updateOrganization(ctx context.Context, tx Tx, id influxdb.ID,
 		o.Description = *upd.Description
 	}
 
+	if upd.MaxSeries != nil {
+		if err := influxdb.ValidMaxSeries(*upd.MaxSeries); err != nil {
+			return nil, err
+		}
+		o.MaxSeries = *upd.MaxSeries
+	}
+
 	o.UpdatedAt = s.Now()
 
 	if err := s.appendOrganizationEventToLog(ctx, tx, o.ID, organizationUpdatedEvent); err != nil {
@@ -89,6 +89,22 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeBackupSchedules(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := s.initializeBucketSchemas(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := s.initializeWriteReceipts(ctx, tx); err != nil {
+			return err
+		}
+
+		if err := s.initializeListeners(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeBuckets(ctx, tx); err != nil {
 			return err
 		}
@@ -97,6 +113,10 @@ func (s *Service) Initialize(ctx context.Context) error {
 			return err
 		}
 
+		if err := s.initializeDBRPMappings(ctx, tx); err != nil {
+			return err
+		}
+
 		if err := s.initializeKVLog(ctx, tx); err != nil {
 			return err
 		}
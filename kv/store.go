@@ -32,6 +32,8 @@ type Store interface {
 	Update(context.Context, func(Tx) error) error
 	// Backup copies all K:Vs to a writer, file format determined by implementation.
 	Backup(ctx context.Context, w io.Writer) error
+	// Restore replaces all K:Vs with those read from r, in the format written by Backup.
+	Restore(ctx context.Context, r io.Reader) error
 }
 
 // Tx is a transaction in the store.
@@ -0,0 +1,196 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+var writeReceiptBucket = []byte("writereceiptsv1")
+
+var _ influxdb.WriteReceiptService = (*Service)(nil)
+
+func (s *Service) initializeWriteReceipts(ctx context.Context, tx Tx) error {
+	if _, err := tx.Bucket(writeReceiptBucket); err != nil {
+		return err
+	}
+	return nil
+}
+
+// FindWriteReceiptByID returns a single write receipt by ID.
+func (s *Service) FindWriteReceiptByID(ctx context.Context, id influxdb.ID) (*influxdb.WriteReceipt, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var r *influxdb.WriteReceipt
+	err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		r, err = s.findWriteReceiptByID(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, &influxdb.Error{
+			Op:  influxdb.OpFindWriteReceiptByID,
+			Err: err,
+		}
+	}
+	return r, nil
+}
+
+func (s *Service) findWriteReceiptByID(ctx context.Context, tx Tx, id influxdb.ID) (*influxdb.WriteReceipt, error) {
+	encodedID, err := id.Encode()
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(writeReceiptBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := b.Get(encodedID)
+	if IsNotFound(err) {
+		return nil, influxdb.ErrWriteReceiptNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r influxdb.WriteReceipt
+	if err := json.Unmarshal(v, &r); err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+	return &r, nil
+}
+
+// CreateWriteReceipt creates a pending write receipt and sets r.ID with the
+// new identifier.
+func (s *Service) CreateWriteReceipt(ctx context.Context, r *influxdb.WriteReceipt) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		r.ID = s.IDGenerator.ID()
+		r.Status = influxdb.WriteReceiptPending
+		now := s.TimeGenerator.Now()
+		r.SetCreatedAt(now)
+		r.SetUpdatedAt(now)
+		return s.putWriteReceipt(ctx, tx, r)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpCreateWriteReceipt,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// UpdateWriteReceipt sets the status of a write receipt.
+func (s *Service) UpdateWriteReceipt(ctx context.Context, r *influxdb.WriteReceipt) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		prev, err := s.findWriteReceiptByID(ctx, tx, r.ID)
+		if err != nil {
+			return err
+		}
+		r.CreatedAt = prev.CreatedAt
+		r.SetUpdatedAt(s.TimeGenerator.Now())
+		return s.putWriteReceipt(ctx, tx, r)
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpUpdateWriteReceipt,
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// PutWriteReceipt will put a write receipt without setting an ID.
+func (s *Service) PutWriteReceipt(ctx context.Context, r *influxdb.WriteReceipt) error {
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return s.putWriteReceipt(ctx, tx, r)
+	})
+}
+
+func (s *Service) putWriteReceipt(ctx context.Context, tx Tx, r *influxdb.WriteReceipt) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Err:  err,
+		}
+	}
+
+	encodedID, err := r.ID.Encode()
+	if err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Err:  err,
+		}
+	}
+
+	b, err := tx.Bucket(writeReceiptBucket)
+	if err != nil {
+		return err
+	}
+	return b.Put(encodedID, v)
+}
+
+// DeleteWriteReceipts removes the write receipts last updated before a time.
+func (s *Service) DeleteWriteReceipts(ctx context.Context, before time.Time) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	err := s.kv.Update(ctx, func(tx Tx) error {
+		b, err := tx.Bucket(writeReceiptBucket)
+		if err != nil {
+			return err
+		}
+
+		cur, err := b.Cursor()
+		if err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		for k, v := cur.First(); k != nil; k, v = cur.Next() {
+			var r influxdb.WriteReceipt
+			if err := json.Unmarshal(v, &r); err != nil {
+				return &influxdb.Error{
+					Code: influxdb.EInternal,
+					Err:  err,
+				}
+			}
+			if r.UpdatedAt.Before(before) {
+				keys = append(keys, append([]byte(nil), k...))
+			}
+		}
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return &influxdb.Error{
+			Op:  influxdb.OpDeleteWriteReceipts,
+			Err: err,
+		}
+	}
+	return nil
+}
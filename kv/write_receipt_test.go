@@ -0,0 +1,70 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	influxdbtesting "github.com/influxdata/influxdb/testing"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestBoltWriteReceiptService(t *testing.T) {
+	influxdbtesting.WriteReceiptService(initBoltWriteReceiptService, t)
+}
+
+func TestInmemWriteReceiptService(t *testing.T) {
+	influxdbtesting.WriteReceiptService(initInmemWriteReceiptService, t)
+}
+
+func initBoltWriteReceiptService(f influxdbtesting.WriteReceiptFields, t *testing.T) (influxdb.WriteReceiptService, func()) {
+	s, closeBolt, err := NewTestBoltStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initWriteReceiptService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeBolt()
+	}
+}
+
+func initInmemWriteReceiptService(f influxdbtesting.WriteReceiptFields, t *testing.T) (influxdb.WriteReceiptService, func()) {
+	s, closeInmem, err := NewTestInmemStore(t)
+	if err != nil {
+		t.Fatalf("failed to create new kv store: %v", err)
+	}
+
+	svc, closeSvc := initWriteReceiptService(s, f, t)
+	return svc, func() {
+		closeSvc()
+		closeInmem()
+	}
+}
+
+func initWriteReceiptService(s kv.Store, f influxdbtesting.WriteReceiptFields, t *testing.T) (influxdb.WriteReceiptService, func()) {
+	svc := kv.NewService(zaptest.NewLogger(t), s)
+	svc.IDGenerator = f.IDGenerator
+	svc.TimeGenerator = f.TimeGenerator
+	if svc.TimeGenerator == nil {
+		svc.TimeGenerator = influxdb.RealTimeGenerator{}
+	}
+
+	ctx := context.Background()
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing write receipt service: %v", err)
+	}
+	for _, r := range f.WriteReceipts {
+		if err := svc.PutWriteReceipt(ctx, r); err != nil {
+			t.Fatalf("failed to populate write receipts: %v", err)
+		}
+	}
+	return svc, func() {
+		if err := svc.DeleteWriteReceipts(ctx, time.Now().Add(time.Hour)); err != nil {
+			t.Logf("failed to remove write receipts: %v", err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package influxdb
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	// ListenerTypeMQTT is the type of listeners subscribing to topics of MQTT brokers.
+	ListenerTypeMQTT = "mqtt"
+	// ListenerTypeKafka is the type of listeners consuming topics of Kafka brokers.
+	ListenerTypeKafka = "kafka"
+)
+
+// ErrListenerNotFound is returned when a listener is not found.
+var ErrListenerNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "listener not found",
+}
+
+// ops for listener errors.
+var (
+	OpFindListenerByID = "FindListenerByID"
+	OpFindListeners    = "FindListeners"
+	OpCreateListener   = "CreateListener"
+	OpUpdateListener   = "UpdateListener"
+	OpDeleteListener   = "DeleteListener"
+)
+
+// Listener consumes the messages of topics of MQTT or Kafka brokers, and
+// writes the points in their payloads to a bucket.
+type Listener struct {
+	ID          ID     `json:"id,omitempty"`
+	OrgID       ID     `json:"orgID"`
+	BucketID    ID     `json:"bucketID"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Type        string `json:"type"`
+	// Brokers are the addresses of the brokers, as tcp://host:port URLs for
+	// MQTT and host:port for Kafka.
+	Brokers []string `json:"brokers"`
+	// Topics are the topic filters subscribed to for MQTT, or the topics
+	// of which all the partitions are consumed for Kafka.
+	Topics []string `json:"topics"`
+	// Format is the format of the payloads: lp, json or csv. Payloads are
+	// line protocol by default.
+	Format string `json:"format,omitempty"`
+	// Precision is the precision of the timestamps of the payloads.
+	Precision string `json:"precision,omitempty"`
+	// QoS is the quality of service of the MQTT subscriptions.
+	QoS byte `json:"qos,omitempty"`
+	// Token is the API token the listener writes with. It is only set by
+	// those creating or updating the listener, and is never returned; the
+	// listener keeps the ID of its authorization instead.
+	Token           string `json:"token,omitempty"`
+	AuthorizationID ID     `json:"authorizationID,omitempty"`
+	Status          Status `json:"status"`
+	CRUDLog
+}
+
+// Valid returns an error if the listener is not valid.
+func (l *Listener) Valid() error {
+	if l.Name == "" {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "listener name is required",
+		}
+	}
+	if !l.OrgID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "listener requires a valid orgID",
+		}
+	}
+	if !l.BucketID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "listener requires a valid bucketID",
+		}
+	}
+	switch l.Type {
+	case ListenerTypeMQTT:
+		if l.QoS > 2 {
+			return &Error{
+				Code: EInvalid,
+				Msg:  fmt.Sprintf("invalid MQTT qos %d; valid qos are 0, 1 and 2", l.QoS),
+			}
+		}
+	case ListenerTypeKafka:
+		if l.QoS != 0 {
+			return &Error{
+				Code: EInvalid,
+				Msg:  "qos is only valid for MQTT listeners",
+			}
+		}
+	default:
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid listener type %q; valid types are mqtt and kafka", l.Type),
+		}
+	}
+	if len(l.Brokers) == 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "listener requires at least one broker",
+		}
+	}
+	if len(l.Topics) == 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "listener requires at least one topic",
+		}
+	}
+	switch l.Format {
+	case "", "lp", "json", "csv":
+	default:
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid format %q; valid formats are lp, csv and json", l.Format),
+		}
+	}
+	switch l.Precision {
+	case "", "ns", "us", "ms", "s":
+	default:
+		return &Error{
+			Code: EInvalid,
+			Msg:  fmt.Sprintf("invalid precision %q; valid precisions are ns, us, ms and s", l.Precision),
+		}
+	}
+	return l.Status.Valid()
+}
+
+// ListenerUpdate is the set of changes to a listener.
+type ListenerUpdate struct {
+	Name        *string   `json:"name,omitempty"`
+	Description *string   `json:"description,omitempty"`
+	Brokers     *[]string `json:"brokers,omitempty"`
+	Topics      *[]string `json:"topics,omitempty"`
+	Format      *string   `json:"format,omitempty"`
+	Precision   *string   `json:"precision,omitempty"`
+	QoS         *byte     `json:"qos,omitempty"`
+	// Token replaces the API token the listener writes with.
+	Token  *string `json:"token,omitempty"`
+	Status *Status `json:"status,omitempty"`
+}
+
+// Apply applies the changes of the update to the listener.
+func (u ListenerUpdate) Apply(l *Listener) {
+	if u.Name != nil {
+		l.Name = *u.Name
+	}
+	if u.Description != nil {
+		l.Description = *u.Description
+	}
+	if u.Brokers != nil {
+		l.Brokers = *u.Brokers
+	}
+	if u.Topics != nil {
+		l.Topics = *u.Topics
+	}
+	if u.Format != nil {
+		l.Format = *u.Format
+	}
+	if u.Precision != nil {
+		l.Precision = *u.Precision
+	}
+	if u.QoS != nil {
+		l.QoS = *u.QoS
+	}
+	if u.Token != nil {
+		l.Token = *u.Token
+	}
+	if u.Status != nil {
+		l.Status = *u.Status
+	}
+}
+
+// ListenerFilter represents a set of filters that restrict the returned
+// listeners.
+type ListenerFilter struct {
+	OrgID  *ID
+	Status *Status
+}
+
+// ListenerService represents a service for managing ingestion listeners.
+type ListenerService interface {
+	// FindListenerByID returns a single listener by ID.
+	FindListenerByID(ctx context.Context, id ID) (*Listener, error)
+
+	// FindListeners returns a list of listeners that match filter and the
+	// total count of matching listeners.
+	FindListeners(ctx context.Context, filter ListenerFilter) ([]*Listener, int, error)
+
+	// CreateListener creates a new listener and sets l.ID with the new
+	// identifier. The listener writes with the authorization of l.Token,
+	// which must be allowed to write to the bucket of the listener.
+	CreateListener(ctx context.Context, l *Listener) error
+
+	// UpdateListener updates a single listener with changeset.
+	// Returns the new listener state after update.
+	UpdateListener(ctx context.Context, id ID, upd ListenerUpdate) (*Listener, error)
+
+	// DeleteListener removes a listener by ID, along with its consumer offsets.
+	DeleteListener(ctx context.Context, id ID) error
+}
+
+// ListenerOffsetService stores the offsets of the messages consumed by
+// Kafka listeners, so that they resume where they stopped.
+type ListenerOffsetService interface {
+	// FindListenerOffset returns the offset of the next message of a
+	// partition of a topic consumed by the listener, or -1 if the listener
+	// has not consumed the partition yet.
+	FindListenerOffset(ctx context.Context, id ID, topic string, partition int) (int64, error)
+
+	// SetListenerOffset sets the offset of the next message of a partition
+	// of a topic consumed by the listener.
+	SetListenerOffset(ctx context.Context, id ID, topic string, partition int, offset int64) error
+}
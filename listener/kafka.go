@@ -0,0 +1,178 @@
+package listener
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+const (
+	// kafkaFirstOffset is the offset the readers of partitions that were
+	// never consumed seek to: the first offset of the partition.
+	kafkaFirstOffset = -1
+
+	// kafkaMaxWait is how long brokers wait for messages before answering
+	// a fetch without any.
+	kafkaMaxWait = time.Second
+
+	// kafkaMaxBytes is the maximum size of the messages of a fetch.
+	kafkaMaxBytes = 10 << 20
+
+	// kafkaCommitInterval is how often the offsets of the messages written
+	// are saved.
+	kafkaCommitInterval = time.Second
+)
+
+// runKafka consumes the partitions of the topics of the listener until ctx
+// is done. Listeners do not join consumer groups; the offset of each
+// partition is saved with the offset service once its messages are written,
+// so that consuming resumes there when the listener is restarted.
+func runKafka(ctx context.Context, l *influxdb.Listener, w *pointsWriter, offsets influxdb.ListenerOffsetService, log *zap.Logger) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for _, topic := range l.Topics {
+		partitions, ok := lookupPartitions(ctx, l.Brokers, topic, log)
+		if !ok {
+			return
+		}
+
+		for _, p := range partitions {
+			c := &kafkaConsumer{
+				listener:  l,
+				topic:     topic,
+				partition: p.ID,
+				writer:    w,
+				offsets:   offsets,
+				log:       log.With(zap.String("topic", topic), zap.Int("partition", p.ID)),
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.run(ctx)
+			}()
+		}
+	}
+}
+
+// lookupPartitions returns the partitions of a topic, trying each broker
+// until one answers. It returns false if ctx is done first.
+func lookupPartitions(ctx context.Context, brokers []string, topic string, log *zap.Logger) ([]kafka.Partition, bool) {
+	for {
+		for _, b := range brokers {
+			partitions, err := kafka.DefaultDialer.LookupPartitions(ctx, "tcp", b, topic)
+			if err == nil {
+				return partitions, true
+			}
+			if ctx.Err() != nil {
+				return nil, false
+			}
+			log.Error("Failed to look up Kafka partitions", zap.String("broker", b), zap.String("topic", topic), zap.Error(err))
+		}
+		if !wait(ctx, retryInterval) {
+			return nil, false
+		}
+	}
+}
+
+// kafkaConsumer consumes a partition of a topic.
+type kafkaConsumer struct {
+	listener  *influxdb.Listener
+	topic     string
+	partition int
+	writer    *pointsWriter
+	offsets   influxdb.ListenerOffsetService
+	log       *zap.Logger
+}
+
+func (c *kafkaConsumer) run(ctx context.Context) {
+	offset, ok := c.findOffset(ctx)
+	if !ok {
+		return
+	}
+
+	r := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   c.listener.Brokers,
+		Topic:     c.topic,
+		Partition: c.partition,
+		MinBytes:  1,
+		MaxBytes:  kafkaMaxBytes,
+		MaxWait:   kafkaMaxWait,
+	})
+	defer r.Close()
+
+	if offset < 0 {
+		offset = kafkaFirstOffset
+	}
+	if err := r.SetOffset(offset); err != nil {
+		c.log.Error("Failed to set Kafka offset", zap.Int64("offset", offset), zap.Error(err))
+		return
+	}
+
+	// committed is the offset last saved, and next the offset of the
+	// message after the last one written.
+	committed, next := offset, offset
+	lastCommit := time.Now()
+	defer func() {
+		// ctx is done, but the offset of the messages written is saved
+		// so that they are not written again.
+		if next != committed {
+			c.commit(context.Background(), next)
+		}
+	}()
+
+	for {
+		msg, err := r.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return
+			}
+			c.log.Error("Failed to fetch Kafka message", zap.Error(err))
+			if !wait(ctx, retryInterval) {
+				return
+			}
+			continue
+		}
+
+		if err := c.writer.writeRetry(ctx, msg.Value); err != nil {
+			return
+		}
+		next = msg.Offset + 1
+
+		if time.Since(lastCommit) >= kafkaCommitInterval {
+			if c.commit(ctx, next) {
+				committed = next
+			}
+			lastCommit = time.Now()
+		}
+	}
+}
+
+// findOffset returns the saved offset of the partition, or -1 if the
+// partition was never consumed. It returns false if ctx is done first.
+func (c *kafkaConsumer) findOffset(ctx context.Context) (int64, bool) {
+	for {
+		offset, err := c.offsets.FindListenerOffset(ctx, c.listener.ID, c.topic, c.partition)
+		if err == nil {
+			return offset, true
+		}
+		c.log.Error("Failed to find Kafka offset", zap.Error(err))
+		if !wait(ctx, retryInterval) {
+			return 0, false
+		}
+	}
+}
+
+// commit saves the offset of the partition, and returns whether it did.
+func (c *kafkaConsumer) commit(ctx context.Context, offset int64) bool {
+	if err := c.offsets.SetListenerOffset(ctx, c.listener.ID, c.topic, c.partition, offset); err != nil {
+		c.log.Error("Failed to save Kafka offset", zap.Int64("offset", offset), zap.Error(err))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,380 @@
+package listener_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+func TestManager_Kafka(t *testing.T) {
+	f := newFixture(t)
+	broker := newKafkaBroker(t, "sensors", 2)
+	defer broker.Close()
+
+	broker.produce(0, `[{"measurement": "temperature", "tags": {"sensor": "1"}, "fields": {"value": 21.5}, "time": 1600000000}]`)
+	broker.produce(1, `[{"measurement": "temperature", "tags": {"sensor": "2"}, "fields": {"value": 19}, "time": 1600000000}]`)
+	broker.produce(1, `[{"measurement": "temperature", "tags": {"sensor": "2"}, "fields": {"value": 19.5}, "time": 1600000001}]`)
+
+	l := &influxdb.Listener{
+		Type:      influxdb.ListenerTypeKafka,
+		Brokers:   []string{broker.Addr()},
+		Topics:    []string{"sensors"},
+		Format:    "json",
+		Precision: "s",
+	}
+	f.createListener(t, l)
+
+	pw := &mock.PointsWriter{}
+	stop := f.runManager(t, pw)
+	points := nextPoints(t, pw, 3)
+	stop()
+
+	values := make(map[float64]int64)
+	for _, p := range points {
+		fields, err := p.Fields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		values[fields["value"].(float64)] = p.Time().Unix()
+	}
+	if len(values) != 3 || values[21.5] != 1600000000 || values[19] != 1600000000 || values[19.5] != 1600000001 {
+		t.Fatalf("unexpected points %v", points)
+	}
+
+	// The offsets of the messages written are saved when the listener
+	// stops, and consuming resumes from them.
+	checkOffsets := func(want ...int64) {
+		t.Helper()
+		for partition, want := range want {
+			offset, err := f.svc.FindListenerOffset(context.Background(), l.ID, "sensors", partition)
+			if err != nil {
+				t.Fatalf("error finding offset: %v", err)
+			}
+			if offset != want {
+				t.Fatalf("expected offset %d of partition %d, got %d", want, partition, offset)
+			}
+		}
+	}
+	checkOffsets(1, 2)
+
+	broker.produce(0, `[{"measurement": "temperature", "tags": {"sensor": "1"}, "fields": {"value": 22}, "time": 1600000001}]`)
+
+	stop = f.runManager(t, pw)
+	points = nextPoints(t, pw, 1)
+	stop()
+
+	if fields, err := points[0].Fields(); err != nil || fields["value"] != 22.0 {
+		t.Fatalf("unexpected point %v", points[0])
+	}
+	if n := pw.WritePointsCalled(); n != 4 {
+		t.Fatalf("expected 4 writes, got %d", n)
+	}
+	checkOffsets(2, 2)
+}
+
+// kafkaBroker is a Kafka broker with a single topic, which serves the
+// requests that consumers of partitions make: metadata, offsets and fetch
+// requests, in the versions of the client.
+type kafkaBroker struct {
+	ln    net.Listener
+	topic string
+
+	mu         sync.Mutex
+	partitions [][][]byte
+	conns      map[net.Conn]struct{}
+	wg         sync.WaitGroup
+}
+
+const (
+	kafkaFetch       = 1
+	kafkaListOffsets = 2
+	kafkaMetadata    = 3
+
+	kafkaUnknownTopicOrPartition = 3
+
+	// kafkaMaxFetchWait is the longest fetch requests wait for messages.
+	kafkaMaxFetchWait = 100 * time.Millisecond
+)
+
+func newKafkaBroker(t *testing.T, topic string, partitions int) *kafkaBroker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &kafkaBroker{
+		ln:         ln,
+		topic:      topic,
+		partitions: make([][][]byte, partitions),
+		conns:      make(map[net.Conn]struct{}),
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.mu.Lock()
+			b.conns[conn] = struct{}{}
+			b.mu.Unlock()
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				b.serve(conn)
+			}()
+		}
+	}()
+	return b
+}
+
+func (b *kafkaBroker) Addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *kafkaBroker) Close() {
+	b.ln.Close()
+	b.mu.Lock()
+	for conn := range b.conns {
+		conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+// produce appends a message to a partition.
+func (b *kafkaBroker) produce(partition int, value string) {
+	b.mu.Lock()
+	b.partitions[partition] = append(b.partitions[partition], []byte(value))
+	b.mu.Unlock()
+}
+
+func (b *kafkaBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.conns, conn)
+		b.mu.Unlock()
+		conn.Close()
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		var size int32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return
+		}
+		req := make([]byte, size)
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+
+		d := &kafkaDecoder{buf: req}
+		apiKey := d.int16()
+		d.int16() // API version
+		correlationID := d.int32()
+		d.string() // client ID
+
+		e := &kafkaEncoder{}
+		e.int32(correlationID)
+		switch apiKey {
+		case kafkaMetadata:
+			b.metadata(d, e)
+		case kafkaListOffsets:
+			b.listOffsets(d, e)
+		case kafkaFetch:
+			b.fetch(d, e)
+		default:
+			return
+		}
+
+		res := e.buf.Bytes()
+		if err := binary.Write(conn, binary.BigEndian, int32(len(res))); err != nil {
+			return
+		}
+		if _, err := conn.Write(res); err != nil {
+			return
+		}
+	}
+}
+
+// metadata answers a metadata request (v0) with the broker as the leader of
+// all the partitions.
+func (b *kafkaBroker) metadata(d *kafkaDecoder, e *kafkaEncoder) {
+	topics := make([]string, d.int32())
+	for i := range topics {
+		topics[i] = d.string()
+	}
+
+	host, port, _ := net.SplitHostPort(b.Addr())
+	p, _ := strconv.Atoi(port)
+	e.int32(1)
+	e.int32(0) // node ID
+	e.string(host)
+	e.int32(int32(p))
+
+	e.int32(int32(len(topics)))
+	for _, topic := range topics {
+		if topic != b.topic {
+			e.int16(kafkaUnknownTopicOrPartition)
+			e.string(topic)
+			e.int32(0)
+			continue
+		}
+		e.int16(0)
+		e.string(topic)
+		e.int32(int32(len(b.partitions)))
+		for i := range b.partitions {
+			e.int16(0)
+			e.int32(int32(i))
+			e.int32(0) // leader
+			e.int32(1) // replicas
+			e.int32(0)
+			e.int32(1) // in-sync replicas
+			e.int32(0)
+		}
+	}
+}
+
+// listOffsets answers a list offsets request (v1) for the first or last
+// offset of a partition.
+func (b *kafkaBroker) listOffsets(d *kafkaDecoder, e *kafkaEncoder) {
+	d.int32() // replica ID
+	d.int32() // 1 topic
+	topic := d.string()
+	d.int32() // 1 partition
+	partition := d.int32()
+	t := d.int64()
+
+	var offset int64
+	if t == -1 {
+		b.mu.Lock()
+		offset = int64(len(b.partitions[partition]))
+		b.mu.Unlock()
+	}
+
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.int16(0)
+	e.int64(-1) // timestamp
+	e.int64(offset)
+}
+
+// fetch answers a fetch request (v1) with the messages of a partition from
+// an offset, waiting for messages if there are none.
+func (b *kafkaBroker) fetch(d *kafkaDecoder, e *kafkaEncoder) {
+	d.int32() // replica ID
+	maxWait := time.Duration(d.int32()) * time.Millisecond
+	d.int32() // min bytes
+	d.int32() // 1 topic
+	topic := d.string()
+	d.int32() // 1 partition
+	partition := d.int32()
+	offset := d.int64()
+
+	if maxWait > kafkaMaxFetchWait {
+		maxWait = kafkaMaxFetchWait
+	}
+	deadline := time.Now().Add(maxWait)
+
+	var messages [][]byte
+	for {
+		b.mu.Lock()
+		messages = b.partitions[partition]
+		b.mu.Unlock()
+		if int64(len(messages)) > offset || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	set := &kafkaEncoder{}
+	for i := offset; i < int64(len(messages)); i++ {
+		msg := &kafkaEncoder{}
+		msg.int8(0)   // magic
+		msg.int8(0)   // attributes
+		msg.int32(-1) // null key
+		msg.bytes(messages[i])
+
+		set.int64(i)
+		set.int32(int32(4 + msg.buf.Len()))
+		set.int32(int32(crc32.ChecksumIEEE(msg.buf.Bytes())))
+		set.buf.Write(msg.buf.Bytes())
+	}
+
+	e.int32(0) // throttle time
+	e.int32(1)
+	e.string(topic)
+	e.int32(1)
+	e.int32(partition)
+	e.int16(0)
+	e.int64(int64(len(messages))) // high watermark
+	e.int32(int32(set.buf.Len()))
+	e.buf.Write(set.buf.Bytes())
+}
+
+type kafkaDecoder struct {
+	buf []byte
+}
+
+func (d *kafkaDecoder) int16() int16 {
+	v := int16(binary.BigEndian.Uint16(d.buf))
+	d.buf = d.buf[2:]
+	return v
+}
+
+func (d *kafkaDecoder) int32() int32 {
+	v := int32(binary.BigEndian.Uint32(d.buf))
+	d.buf = d.buf[4:]
+	return v
+}
+
+func (d *kafkaDecoder) int64() int64 {
+	v := int64(binary.BigEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *kafkaDecoder) string() string {
+	n := int(d.int16())
+	if n < 0 {
+		return ""
+	}
+	v := string(d.buf[:n])
+	d.buf = d.buf[n:]
+	return v
+}
+
+type kafkaEncoder struct {
+	buf bytes.Buffer
+}
+
+func (e *kafkaEncoder) int8(v int8)   { e.buf.WriteByte(byte(v)) }
+func (e *kafkaEncoder) int16(v int16) { _ = binary.Write(&e.buf, binary.BigEndian, v) }
+func (e *kafkaEncoder) int32(v int32) { _ = binary.Write(&e.buf, binary.BigEndian, v) }
+func (e *kafkaEncoder) int64(v int64) { _ = binary.Write(&e.buf, binary.BigEndian, v) }
+
+func (e *kafkaEncoder) string(v string) {
+	e.int16(int16(len(v)))
+	e.buf.WriteString(v)
+}
+
+func (e *kafkaEncoder) bytes(v []byte) {
+	e.int32(int32(len(v)))
+	e.buf.Write(v)
+}
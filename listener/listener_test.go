@@ -0,0 +1,129 @@
+package listener_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/listener"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
+	"go.uber.org/zap/zaptest"
+)
+
+// fixture is a kv service with an organization, a bucket and a token
+// allowed to write to it.
+type fixture struct {
+	svc    *kv.Service
+	org    *influxdb.Organization
+	bucket *influxdb.Bucket
+	auth   *influxdb.Authorization
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	ctx := context.Background()
+	svc := kv.NewService(zaptest.NewLogger(t), inmem.NewKVStore())
+	if err := svc.Initialize(ctx); err != nil {
+		t.Fatalf("error initializing kv service: %v", err)
+	}
+
+	user := &influxdb.User{Name: "user"}
+	if err := svc.CreateUser(ctx, user); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	org := &influxdb.Organization{Name: "org"}
+	if err := svc.CreateOrganization(ctx, org); err != nil {
+		t.Fatalf("error creating organization: %v", err)
+	}
+	bucket := &influxdb.Bucket{OrgID: org.ID, Name: "bucket"}
+	if err := svc.CreateBucket(ctx, bucket); err != nil {
+		t.Fatalf("error creating bucket: %v", err)
+	}
+
+	p, err := influxdb.NewPermissionAtID(bucket.ID, influxdb.WriteAction, influxdb.BucketsResourceType, org.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := &influxdb.Authorization{OrgID: org.ID, UserID: user.ID, Token: "writer", Permissions: []influxdb.Permission{*p}}
+	if err := svc.CreateAuthorization(ctx, auth); err != nil {
+		t.Fatalf("error creating authorization: %v", err)
+	}
+
+	return &fixture{svc: svc, org: org, bucket: bucket, auth: auth}
+}
+
+// createListener creates a listener of the bucket with the token.
+func (f *fixture) createListener(t *testing.T, l *influxdb.Listener) {
+	t.Helper()
+
+	l.OrgID = f.org.ID
+	l.BucketID = f.bucket.ID
+	l.Name = "sensors"
+	l.Token = f.auth.Token
+	if err := f.svc.CreateListener(context.Background(), l); err != nil {
+		t.Fatalf("error creating listener: %v", err)
+	}
+}
+
+// runManager runs the listeners until the returned function is called.
+func (f *fixture) runManager(t *testing.T, pw *mock.PointsWriter) func() {
+	t.Helper()
+
+	m := &listener.Manager{
+		ListenerService:      f.svc,
+		OffsetService:        f.svc,
+		AuthorizationService: f.svc,
+		UserService:          f.svc,
+		PointsWriter:         pw,
+		Interval:             50 * time.Millisecond,
+		Logger:               zaptest.NewLogger(t),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// waitFor waits for cond to be true.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// nextPoints waits for n points to be written, and returns them.
+func nextPoints(t *testing.T, pw *mock.PointsWriter, n int) []models.Point {
+	t.Helper()
+
+	var points []models.Point
+	waitFor(t, "points", func() bool {
+		for len(points) < n {
+			p := pw.Next()
+			if p == nil {
+				return false
+			}
+			points = append(points, p)
+		}
+		return true
+	})
+	return points
+}
@@ -0,0 +1,224 @@
+// Package listener runs ingestion listeners, which consume the messages of
+// MQTT and Kafka brokers and write the points in their payloads to buckets.
+package listener
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultSyncInterval is how often the Manager checks for changed
+	// listeners and revoked tokens.
+	DefaultSyncInterval = 10 * time.Second
+
+	// retryInterval is how long listeners wait before connecting to brokers
+	// again, or writing points again after storage failed to write them.
+	retryInterval = 5 * time.Second
+)
+
+// Manager runs the active listeners. Listeners are restarted when they are
+// updated, and stopped when they are deactivated or deleted, or when their
+// token is no longer allowed to write to their bucket or its user is
+// inactive.
+type Manager struct {
+	ListenerService      influxdb.ListenerService
+	OffsetService        influxdb.ListenerOffsetService
+	AuthorizationService influxdb.AuthorizationService
+	UserService          influxdb.UserService
+
+	// PointsWriter writes the points of the listeners, as it writes those
+	// of the write API.
+	PointsWriter storage.PointsWriter
+
+	// Interval is how often the listeners are checked. It defaults to
+	// DefaultSyncInterval.
+	Interval time.Duration
+
+	Logger *zap.Logger
+
+	running map[influxdb.ID]*run
+	// denied are the listeners, as of their last update, not started as
+	// their token is not allowed to write to their bucket.
+	denied map[influxdb.ID]time.Time
+}
+
+// run is a running listener.
+type run struct {
+	updatedAt time.Time
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// Run runs the listeners until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	interval := m.Interval
+	if interval <= 0 {
+		interval = DefaultSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	m.sync(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			for id := range m.running {
+				m.stop(id)
+			}
+			return
+		case <-ticker.C:
+			m.sync(ctx)
+		}
+	}
+}
+
+func (m *Manager) logger() *zap.Logger {
+	if m.Logger == nil {
+		return zap.NewNop()
+	}
+	return m.Logger
+}
+
+// sync starts the active listeners that are not running, restarts those
+// that changed, and stops the others.
+func (m *Manager) sync(ctx context.Context) {
+	listeners, _, err := m.ListenerService.FindListeners(ctx, influxdb.ListenerFilter{Status: influxdb.Active.Ptr()})
+	if err != nil {
+		m.logger().Error("Failed to find listeners", zap.Error(err))
+		return
+	}
+
+	if m.running == nil {
+		m.running = make(map[influxdb.ID]*run)
+		m.denied = make(map[influxdb.ID]time.Time)
+	}
+	active := make(map[influxdb.ID]bool, len(listeners))
+
+	for _, l := range listeners {
+		log := m.logger().With(zap.String("listener_id", l.ID.String()))
+
+		authErr := m.authorize(ctx, l)
+		if r, ok := m.running[l.ID]; ok {
+			if authErr == nil && r.updatedAt.Equal(l.UpdatedAt) {
+				active[l.ID] = true
+				continue
+			}
+			m.stop(l.ID)
+		}
+
+		if authErr != nil {
+			if updatedAt, ok := m.denied[l.ID]; !ok || !updatedAt.Equal(l.UpdatedAt) {
+				log.Error("Listener is not allowed to write to its bucket", zap.Error(authErr))
+				m.denied[l.ID] = l.UpdatedAt
+			}
+			continue
+		}
+		delete(m.denied, l.ID)
+
+		active[l.ID] = true
+		m.start(ctx, l, log)
+	}
+
+	for id := range m.running {
+		if !active[id] {
+			m.stop(id)
+		}
+	}
+	for id := range m.denied {
+		if !active[id] {
+			delete(m.denied, id)
+		}
+	}
+}
+
+// authorize returns an error if the token of the listener is not allowed to
+// write to its bucket, for example because it was deactivated or deleted, or
+// if the user of the token is inactive.
+func (m *Manager) authorize(ctx context.Context, l *influxdb.Listener) error {
+	a, err := m.AuthorizationService.FindAuthorizationByID(ctx, l.AuthorizationID)
+	if err != nil {
+		return err
+	}
+
+	p, err := influxdb.NewPermissionAtID(l.BucketID, influxdb.WriteAction, influxdb.BucketsResourceType, l.OrgID)
+	if err != nil {
+		return err
+	}
+	if !a.Allowed(*p) {
+		return &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "listener token is not allowed to write to the bucket",
+		}
+	}
+
+	// As for the write API, the points of inactive users are not written.
+	if !a.UserID.Valid() {
+		return nil
+	}
+	u, err := m.UserService.FindUserByID(ctx, a.UserID)
+	if err != nil {
+		return err
+	}
+	if u.Status == "inactive" {
+		return &influxdb.Error{
+			Code: influxdb.EForbidden,
+			Msg:  "User is inactive",
+		}
+	}
+	return nil
+}
+
+// start runs the listener until it is stopped.
+func (m *Manager) start(ctx context.Context, l *influxdb.Listener, log *zap.Logger) {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &run{
+		updatedAt: l.UpdatedAt,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	m.running[l.ID] = r
+
+	w := &pointsWriter{
+		listener: l,
+		writer:   m.PointsWriter,
+		log:      log,
+	}
+
+	go func() {
+		defer close(r.done)
+		log.Info("Starting listener", zap.String("type", l.Type), zap.Strings("brokers", l.Brokers), zap.Strings("topics", l.Topics))
+		switch l.Type {
+		case influxdb.ListenerTypeMQTT:
+			runMQTT(ctx, l, w, log)
+		case influxdb.ListenerTypeKafka:
+			runKafka(ctx, l, w, m.OffsetService, log)
+		}
+		log.Info("Stopped listener")
+	}()
+}
+
+// stop stops a running listener and waits for it to return.
+func (m *Manager) stop(id influxdb.ID) {
+	r := m.running[id]
+	r.cancel()
+	<-r.done
+	delete(m.running, id)
+}
+
+// wait waits for the duration, and returns false if ctx is done first.
+func wait(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,73 @@
+package listener
+
+import (
+	"context"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/influxdata/influxdb"
+	"go.uber.org/zap"
+)
+
+// mqttDisconnectQuiesce is how long, in milliseconds, the client waits for
+// the messages in flight when disconnecting.
+const mqttDisconnectQuiesce = 250
+
+// runMQTT subscribes to the topic filters of the listener until ctx is done.
+// The session is persistent, so that brokers queue the messages published
+// with a QoS above 0 while the listener is disconnected. Messages are acked
+// once their points are written, and never if ctx is done first.
+func runMQTT(ctx context.Context, l *influxdb.Listener, w *pointsWriter, log *zap.Logger) {
+	disconnected := make(chan struct{})
+	opts := mqtt.NewClientOptions().
+		SetClientID("influxdb-listener-" + l.ID.String()).
+		SetCleanSession(false).
+		SetAutoReconnect(true).
+		SetOrderMatters(true)
+	for _, b := range l.Brokers {
+		opts.AddBroker(b)
+	}
+
+	// Messages are handled by the default handler, which handles each
+	// message once whatever the number of filters it matches.
+	opts.SetDefaultPublishHandler(func(_ mqtt.Client, msg mqtt.Message) {
+		if err := w.writeRetry(ctx, msg.Payload()); err != nil {
+			// The client acks messages once their handler returns, so the
+			// handler of a message that was not written returns once the
+			// client is disconnected, and its ack is never sent. Brokers
+			// bound the messages in flight well below the depth of the
+			// message channel of the client, which is not blocked from
+			// disconnecting meanwhile.
+			<-disconnected
+		}
+	})
+	opts.SetOnConnectHandler(func(c mqtt.Client) {
+		filters := make(map[string]byte, len(l.Topics))
+		for _, topic := range l.Topics {
+			filters[topic] = l.QoS
+		}
+		if t := c.SubscribeMultiple(filters, nil); t.Wait() && t.Error() != nil {
+			log.Error("Failed to subscribe to MQTT topics", zap.Error(t.Error()))
+		}
+	})
+	opts.SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+		log.Info("Lost connection to MQTT broker", zap.Error(err))
+	})
+
+	c := mqtt.NewClient(opts)
+	for {
+		t := c.Connect()
+		t.Wait()
+		err := t.Error()
+		if err == nil {
+			break
+		}
+		log.Error("Failed to connect to MQTT brokers", zap.Error(err))
+		if !wait(ctx, retryInterval) {
+			return
+		}
+	}
+
+	<-ctx.Done()
+	c.Disconnect(mqttDisconnectQuiesce)
+	close(disconnected)
+}
@@ -0,0 +1,295 @@
+package listener_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eclipse/paho.mqtt.golang/packets"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/models"
+)
+
+func TestManager_MQTT(t *testing.T) {
+	f := newFixture(t)
+	broker := newMQTTBroker(t)
+	defer broker.Close()
+
+	f.createListener(t, &influxdb.Listener{
+		Type:    influxdb.ListenerTypeMQTT,
+		Brokers: []string{"tcp://" + broker.Addr()},
+		Topics:  []string{"sensors/+/temperature"},
+		QoS:     1,
+	})
+
+	pw := &mock.PointsWriter{}
+	stop := f.runManager(t, pw)
+	defer stop()
+
+	if filters := broker.waitSubscribed(t); len(filters) != 1 || filters[0] != "sensors/+/temperature" {
+		t.Fatalf("unexpected subscription %v", filters)
+	}
+
+	broker.publish("sensors/1/temperature", 1, "temperature,sensor=1 value=21.5 1600000000000000000\ntemperature,sensor=1 value=22 1600000001000000000")
+	points := nextPoints(t, pw, 2)
+	for i, want := range []float64{21.5, 22} {
+		if m := points[i].Tags().GetString(models.MeasurementTagKey); m != "temperature" {
+			t.Fatalf("unexpected measurement %q", m)
+		}
+		fields, err := points[i].Fields()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fields["value"] != want {
+			t.Fatalf("unexpected fields %v, want value %v", fields, want)
+		}
+	}
+	broker.waitAcked(t, 1)
+
+	// Messages that are not line protocol are acked without being written,
+	// as they would never be.
+	broker.publish("sensors/2/temperature", 2, "not line protocol")
+	broker.waitAcked(t, 2)
+	if n := pw.WritePointsCalled(); n != 1 {
+		t.Fatalf("expected 1 write, got %d", n)
+	}
+
+	// The listener stops once its token is no longer allowed to write.
+	if _, err := f.svc.UpdateAuthorization(context.Background(), f.auth.ID, &influxdb.AuthorizationUpdate{Status: influxdb.Inactive.Ptr()}); err != nil {
+		t.Fatalf("error updating authorization: %v", err)
+	}
+	broker.waitDisconnected(t)
+}
+
+func TestManager_MQTT_InactiveUser(t *testing.T) {
+	f := newFixture(t)
+	broker := newMQTTBroker(t)
+	defer broker.Close()
+
+	f.createListener(t, &influxdb.Listener{
+		Type:    influxdb.ListenerTypeMQTT,
+		Brokers: []string{"tcp://" + broker.Addr()},
+		Topics:  []string{"sensors/#"},
+	})
+
+	stop := f.runManager(t, &mock.PointsWriter{})
+	defer stop()
+	broker.waitSubscribed(t)
+
+	// The listener stops once the user of its token is inactive, as the
+	// write API would no longer write the points of the user.
+	if _, err := f.svc.UpdateUser(context.Background(), f.auth.UserID, influxdb.UserUpdate{Status: influxdb.Inactive.Ptr()}); err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	broker.waitDisconnected(t)
+}
+
+func TestManager_MQTT_Stop(t *testing.T) {
+	f := newFixture(t)
+	broker := newMQTTBroker(t)
+	defer broker.Close()
+
+	f.createListener(t, &influxdb.Listener{
+		Type:    influxdb.ListenerTypeMQTT,
+		Brokers: []string{"tcp://" + broker.Addr()},
+		Topics:  []string{"sensors/#"},
+		QoS:     1,
+	})
+
+	pw := &mock.PointsWriter{Err: errors.New("storage unavailable")}
+	stop := f.runManager(t, pw)
+	broker.waitSubscribed(t)
+
+	broker.publish("sensors/1/temperature", 1, "temperature,sensor=1 value=21.5 1600000000000000000")
+	waitFor(t, "write", func() bool { return pw.WritePointsCalled() > 0 })
+
+	// The message is not written before the listener stops, so it is not
+	// acked either, and the broker delivers it again once reconnected.
+	stop()
+	broker.waitDisconnected(t)
+	select {
+	case id := <-broker.acked:
+		t.Fatalf("unexpected ack of message %d", id)
+	default:
+	}
+}
+
+// mqttBroker is an MQTT broker that delivers the messages it is asked to
+// publish to the clients subscribed to their topic.
+type mqttBroker struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	clients map[net.Conn][]string
+
+	subscribed   chan []string
+	acked        chan uint16
+	disconnected chan struct{}
+	wg           sync.WaitGroup
+}
+
+func newMQTTBroker(t *testing.T) *mqttBroker {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &mqttBroker{
+		ln:           ln,
+		clients:      make(map[net.Conn][]string),
+		subscribed:   make(chan []string, 8),
+		acked:        make(chan uint16, 8),
+		disconnected: make(chan struct{}, 8),
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b.wg.Add(1)
+			go func() {
+				defer b.wg.Done()
+				b.serve(conn)
+			}()
+		}
+	}()
+	return b
+}
+
+func (b *mqttBroker) Addr() string {
+	return b.ln.Addr().String()
+}
+
+func (b *mqttBroker) Close() {
+	b.ln.Close()
+	b.mu.Lock()
+	for conn := range b.clients {
+		conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *mqttBroker) serve(conn net.Conn) {
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, conn)
+		b.mu.Unlock()
+		conn.Close()
+		b.disconnected <- struct{}{}
+	}()
+
+	for {
+		p, err := packets.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		switch p := p.(type) {
+		case *packets.ConnectPacket:
+			b.mu.Lock()
+			b.clients[conn] = nil
+			b.mu.Unlock()
+			connack := packets.NewControlPacket(packets.Connack).(*packets.ConnackPacket)
+			connack.ReturnCode = packets.Accepted
+			b.write(conn, connack)
+		case *packets.SubscribePacket:
+			b.mu.Lock()
+			b.clients[conn] = append(b.clients[conn], p.Topics...)
+			b.mu.Unlock()
+			suback := packets.NewControlPacket(packets.Suback).(*packets.SubackPacket)
+			suback.MessageID = p.MessageID
+			suback.ReturnCodes = p.Qoss
+			b.write(conn, suback)
+			b.subscribed <- p.Topics
+		case *packets.PubackPacket:
+			b.acked <- p.MessageID
+		case *packets.PingreqPacket:
+			b.write(conn, packets.NewControlPacket(packets.Pingresp))
+		case *packets.DisconnectPacket:
+			return
+		}
+	}
+}
+
+func (b *mqttBroker) write(conn net.Conn, p packets.ControlPacket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_ = p.Write(conn)
+}
+
+// publish delivers a message with QoS 1 to the subscribed clients.
+func (b *mqttBroker) publish(topic string, id uint16, payload string) {
+	p := packets.NewControlPacket(packets.Publish).(*packets.PublishPacket)
+	p.Qos = 1
+	p.TopicName = topic
+	p.MessageID = id
+	p.Payload = []byte(payload)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for conn, filters := range b.clients {
+		for _, filter := range filters {
+			if matchTopic(filter, topic) {
+				_ = p.Write(conn)
+				break
+			}
+		}
+	}
+}
+
+func (b *mqttBroker) waitSubscribed(t *testing.T) []string {
+	t.Helper()
+	select {
+	case filters := <-b.subscribed:
+		return filters
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for subscription")
+		return nil
+	}
+}
+
+func (b *mqttBroker) waitAcked(t *testing.T, id uint16) {
+	t.Helper()
+	select {
+	case acked := <-b.acked:
+		if acked != id {
+			t.Fatalf("expected ack of message %d, got %d", id, acked)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for ack of message %d", id)
+	}
+}
+
+func (b *mqttBroker) waitDisconnected(t *testing.T) {
+	t.Helper()
+	select {
+	case <-b.disconnected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for disconnection")
+	}
+}
+
+// matchTopic returns whether the topic matches the filter.
+func matchTopic(filter, topic string) bool {
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
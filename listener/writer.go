@@ -0,0 +1,100 @@
+package listener
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/write"
+	"go.uber.org/zap"
+)
+
+// pointsWriter writes the points in the payloads of the messages of a
+// listener to its bucket.
+type pointsWriter struct {
+	listener *influxdb.Listener
+	writer   storage.PointsWriter
+	log      *zap.Logger
+}
+
+// write writes the points of a payload. Points that cannot be parsed, or
+// that are rejected by storage, are logged and skipped; an error is only
+// returned if storage failed to write the points, so that they may be
+// written again.
+func (w *pointsWriter) write(ctx context.Context, payload []byte) error {
+	format := write.Format(w.listener.Format)
+	if format == "" {
+		format = write.FormatLineProtocol
+	}
+	precision := w.listener.Precision
+	if precision == "" {
+		precision = "ns"
+	}
+
+	data := payload
+	if format != write.FormatLineProtocol {
+		c, err := write.Convert(format, payload, precision)
+		if err != nil {
+			w.log.Info("Rejected message that could not be converted", zap.Error(err))
+			return nil
+		}
+		if len(c.Errors) > 0 {
+			w.log.Info("Rejected points that could not be converted", zap.Int("points", len(c.Errors)), zap.Error(c.Errors[0]))
+		}
+		data = c.LineProtocol
+	}
+
+	encoded := tsdb.EncodeName(w.listener.OrgID, w.listener.BucketID)
+	mm := models.EscapeMeasurement(encoded[:])
+
+	var opts []models.ParserOption
+	if precision != "ns" {
+		opts = append(opts, models.WithParserPrecision(precision))
+	}
+	points, err := models.ParsePointsWithOptions(data, mm, opts...)
+	if err != nil {
+		w.log.Info("Rejected lines that could not be parsed", zap.Error(err))
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	if err := w.writer.WritePoints(ctx, points); err != nil {
+		if !rejected(err) {
+			return err
+		}
+		w.log.Info("Rejected points that could not be written", zap.Error(err))
+	}
+	return nil
+}
+
+// writeRetry writes the points of a payload, trying again until storage
+// writes them or ctx is done.
+func (w *pointsWriter) writeRetry(ctx context.Context, payload []byte) error {
+	for {
+		err := w.write(ctx, payload)
+		if err == nil {
+			return nil
+		}
+		w.log.Error("Failed to write points", zap.Error(err))
+		if !wait(ctx, retryInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// rejected returns whether err rejects some of the points written, rather
+// than being a failure to write them.
+func rejected(err error) bool {
+	if influxdb.ErrorCode(err) == influxdb.EUnprocessableEntity {
+		// Points were rejected, for example by the schema of the bucket.
+		return true
+	}
+	if e, ok := err.(*influxdb.Error); ok && e.Err != nil {
+		err = e.Err
+	}
+	_, ok := err.(tsdb.PartialWriteError)
+	return ok
+}
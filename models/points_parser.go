@@ -75,6 +75,39 @@ func WithParserStats(s *ParserStats) ParserOption {
 	}
 }
 
+// WithParserPointLines specifies that lines will contain the line number of each parsed point,
+// starting at 1. A line has a point for each of its fields.
+func WithParserPointLines(lines *[]int) ParserOption {
+	return func(pp *pointsParser) {
+		pp.pointLines = lines
+	}
+}
+
+// LineError is the error of a line which could not be parsed.
+type LineError struct {
+	Line int // Line number, starting at 1.
+	Text string
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("unable to parse '%s': %v", e.Text, e.Err)
+}
+
+// ParseError is the error returned by ParsePointsWithOptions when some lines could not be parsed.
+// The points of the other lines are returned with it.
+type ParseError struct {
+	Lines []*LineError
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Lines))
+	for i, le := range e.Lines {
+		msgs[i] = le.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
 type parserState int
 
 const (
@@ -94,6 +127,8 @@ type pointsParser struct {
 	points      []Point
 	state       parserState
 	stats       *ParserStats
+	pointLines  *[]int
+	line        int // line of the points being parsed
 }
 
 func newPointsParser(orgBucket []byte, opts ...ParserOption) *pointsParser {
@@ -125,16 +160,29 @@ func (pp *pointsParser) parsePoints(buf []byte) (err error) {
 	}
 
 	pp.points = make([]Point, 0, lineCount+1)
+	if pp.pointLines != nil {
+		*pp.pointLines = (*pp.pointLines)[:0]
+	}
 
 	var (
 		pos    int
 		block  []byte
-		failed []string
+		failed []*LineError
+		line   = 1
 	)
 	for pos < len(buf) && pp.state == parserStateOK {
+		lineStart := pos
 		pos, block = scanLine(buf, pos)
 		pos++
 
+		// A line may span several lines of buf if it has quoted newlines.
+		pp.line = line
+		end := pos
+		if end > len(buf) {
+			end = len(buf)
+		}
+		line += bytes.Count(buf[lineStart:end], []byte{'\n'})
+
 		if len(block) == 0 {
 			continue
 		}
@@ -156,6 +204,7 @@ func (pp *pointsParser) parsePoints(buf []byte) (err error) {
 			block = block[:len(block)-1]
 		}
 
+		n := len(pp.points)
 		err = pp.parsePointsAppend(block[start:])
 		if err != nil {
 			if errors.Is(err, errLimit) {
@@ -167,7 +216,13 @@ func (pp *pointsParser) parsePoints(buf []byte) (err error) {
 				break
 			}
 
-			failed = append(failed, fmt.Sprintf("unable to parse '%s': %v", string(block[start:]), err))
+			// Drop the points of the fields of the line parsed before the error.
+			pp.points = pp.points[:n]
+			if pp.pointLines != nil {
+				*pp.pointLines = (*pp.pointLines)[:n]
+			}
+
+			failed = append(failed, &LineError{Line: pp.line, Text: string(block[start:]), Err: err})
 		}
 	}
 
@@ -187,7 +242,7 @@ func (pp *pointsParser) parsePoints(buf []byte) (err error) {
 	}
 
 	if len(failed) > 0 {
-		return fmt.Errorf("%s", strings.Join(failed, "\n"))
+		return &ParseError{Lines: failed}
 	}
 
 	return nil
@@ -295,6 +350,9 @@ func (pp *pointsParser) append(p point) error {
 		return errLimit
 	}
 	pp.points = append(pp.points, &p)
+	if pp.pointLines != nil {
+		*pp.pointLines = append(*pp.pointLines, pp.line)
+	}
 	return nil
 }
 
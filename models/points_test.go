@@ -2494,6 +2494,38 @@ func TestParsePointsWithOptions(t *testing.T) {
 	}
 }
 
+func TestParsePointsWithOptions_PointLines(t *testing.T) {
+	buf := []byte("cpu a=1,b=2 1\n\n# comment\ncpu a=\"x\ny\" 2\ncpu a=1,b=bad 3\nmem c=3 4\nbad\n")
+	encoded := tsdb.EncodeName(influxdb.ID(1000), influxdb.ID(2000))
+	mm := models.EscapeMeasurement(encoded[:])
+
+	var lines []int
+	points, err := models.ParsePointsWithOptions(buf, mm, models.WithParserPointLines(&lines))
+
+	// The fields of a line are points, and lines with errors have no points.
+	if got, exp := len(points), 4; got != exp {
+		t.Fatalf("unexpected number of points: got %d, exp %d", got, exp)
+	}
+	if exp := []int{1, 1, 4, 7}; !cmp.Equal(lines, exp) {
+		t.Errorf("unexpected point lines; -got/+exp\n%s", cmp.Diff(lines, exp))
+	}
+
+	pe, ok := err.(*models.ParseError)
+	if !ok {
+		t.Fatalf("unexpected error type: %T", err)
+	}
+	var failed []int
+	for _, le := range pe.Lines {
+		failed = append(failed, le.Line)
+	}
+	if exp := []int{6, 8}; !cmp.Equal(failed, exp) {
+		t.Errorf("unexpected failed lines; -got/+exp\n%s", cmp.Diff(failed, exp))
+	}
+	if got, exp := pe.Lines[0].Error(), "unable to parse 'cpu a=1,b=bad 3': invalid boolean"; got != exp {
+		t.Errorf("unexpected line error: got %q, exp %q", got, exp)
+	}
+}
+
 func TestNewPointsWithBytesWithCorruptData(t *testing.T) {
 	corrupted := []byte{0, 0, 0, 3, 102, 111, 111, 0, 0, 0, 4, 61, 34, 65, 34, 1, 0, 0, 0, 14, 206, 86, 119, 24, 32, 72, 233, 168, 2, 148}
 	p, err := models.NewPointFromBytes(corrupted)
@@ -57,3 +57,40 @@ func (p *AsyncPublisher) Publish(subject string, r io.Reader) error {
 	_, err = p.Connection.PublishAsync(subject, data, ah)
 	return err
 }
+
+// SyncPublisher publishes messages, waiting for the server to store them.
+type SyncPublisher struct {
+	ClientID   string
+	Connection stan.Conn
+	Addr       string
+}
+
+func NewSyncPublisher(clientID string, addr string) *SyncPublisher {
+	return &SyncPublisher{
+		ClientID: clientID,
+		Addr:     addr,
+	}
+}
+
+// Open creates and maintains a connection to NATS server
+func (p *SyncPublisher) Open() error {
+	sc, err := stan.Connect(ServerName, p.ClientID, stan.NatsURL(p.Addr))
+	if err != nil {
+		return err
+	}
+	p.Connection = sc
+	return nil
+}
+
+// Publish returns once the server has stored the message.
+func (p *SyncPublisher) Publish(subject string, r io.Reader) error {
+	if p.Connection == nil {
+		return ErrNoNatsConnection
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return p.Connection.Publish(subject, data)
+}
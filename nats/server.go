@@ -16,6 +16,12 @@ var ErrNoNatsConnection = errors.New("nats connection has not been established.
 type Server struct {
 	serverOpts *server.Options
 	Server     *sserver.StanServer
+
+	// StoreDir is the directory the messages are stored in, so that they
+	// survive restarts. The messages are kept in memory if it is empty.
+	StoreDir string
+
+	channelLimits map[string]int64
 }
 
 // Open starts a NATS streaming server
@@ -24,6 +30,15 @@ func (s *Server) Open() error {
 	opts := sserver.GetDefaultOptions()
 	opts.StoreType = stores.TypeMemory
 	opts.ID = ServerName
+	if s.StoreDir != "" {
+		opts.StoreType = stores.TypeFile
+		opts.FilestoreDir = s.StoreDir
+	}
+	for subject, maxBytes := range s.channelLimits {
+		opts.StoreLimits.AddPerChannel(subject, &stores.ChannelLimits{
+			MsgStoreLimits: stores.MsgStoreLimits{MaxMsgs: -1, MaxBytes: maxBytes},
+		})
+	}
 
 	server, err := sserver.RunServerWithOpts(opts, s.serverOpts)
 	if err != nil {
@@ -35,6 +50,17 @@ func (s *Server) Open() error {
 	return nil
 }
 
+// LimitChannel limits the size of the messages a channel keeps to maxBytes,
+// instead of the default limits of the server. Once the limit is reached,
+// the oldest messages are removed whether they were delivered or not. It
+// must be called before Open.
+func (s *Server) LimitChannel(subject string, maxBytes int64) {
+	if s.channelLimits == nil {
+		s.channelLimits = make(map[string]int64)
+	}
+	s.channelLimits[subject] = maxBytes
+}
+
 // Close stops the embedded NATS server.
 func (s *Server) Close() {
 	s.Server.Shutdown()
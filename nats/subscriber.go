@@ -9,10 +9,21 @@ type Subscriber interface {
 	Subscribe(subject, group string, handler Handler) error
 }
 
+// DefaultMaxInflight is the default number of messages delivered to a
+// subscriber of a queue that are not acked yet.
+const DefaultMaxInflight = 25
+
 type QueueSubscriber struct {
 	ClientID   string
 	Connection stan.Conn
 	Addr       string
+
+	// MaxInflight is the number of messages delivered to the subscriber that
+	// are not acked yet. With a single subscriber, a value of 1 makes the
+	// messages be handled in order, as the messages after one that is not
+	// acked are only delivered once it is redelivered and acked. It is
+	// DefaultMaxInflight if zero.
+	MaxInflight int
 }
 
 func NewQueueSubscriber(clientID string, addr string) *QueueSubscriber {
@@ -43,8 +54,13 @@ func (s *QueueSubscriber) Subscribe(subject, group string, handler Handler) erro
 		return ErrNoNatsConnection
 	}
 
+	maxInflight := s.MaxInflight
+	if maxInflight == 0 {
+		maxInflight = DefaultMaxInflight
+	}
+
 	mh := messageHandler{handler: handler}
-	sub, err := s.Connection.QueueSubscribe(subject, group, mh.handle, stan.DurableName(group), stan.SetManualAckMode(), stan.MaxInflight(25))
+	sub, err := s.Connection.QueueSubscribe(subject, group, mh.handle, stan.DurableName(group), stan.SetManualAckMode(), stan.MaxInflight(maxInflight))
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,7 @@ type Organization struct {
 	ID          ID     `json:"id,omitempty"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	MaxSeries   int64  `json:"maxSeries,omitempty"` // Maximum number of series, unlimited if zero.
 	CRUDLog
 }
 
@@ -57,6 +58,7 @@ type OrganizationService interface {
 type OrganizationUpdate struct {
 	Name        *string
 	Description *string `json:"description,omitempty"`
+	MaxSeries   *int64  `json:"maxSeries,omitempty"`
 }
 
 // ErrInvalidOrgFilter is the error indicate org filter is empty
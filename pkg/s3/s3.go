@@ -0,0 +1,335 @@
+// Package s3 implements a minimal client for S3-compatible object storage
+// services.
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb"
+)
+
+// DefaultRegion is the region requests are signed for if none is configured.
+const DefaultRegion = "us-east-1"
+
+// EmptyPayloadHash is the SHA-256 checksum of an empty request body.
+const EmptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// Config configures a Client.
+type Config struct {
+	// Endpoint is the base URL of the storage service, such as
+	// https://s3.us-east-1.amazonaws.com or http://localhost:9000.
+	Endpoint string
+	// Region is the region requests are signed for.
+	Region string
+	// Bucket is the name of the bucket objects are stored in.
+	Bucket string
+
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// Client accesses the objects of a bucket of an S3-compatible object storage
+// service. Requests are signed with AWS Signature Version 4 and use path-style
+// addressing, which is supported by most S3-compatible services.
+type Client struct {
+	config Config
+	client *http.Client
+
+	now func() time.Time
+}
+
+// NewClient returns a new Client for the given configuration.
+func NewClient(c Config) *Client {
+	if c.Region == "" {
+		c.Region = DefaultRegion
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	return &Client{
+		config: c,
+		client: http.DefaultClient,
+		now:    time.Now,
+	}
+}
+
+// PutObject uploads size bytes read from r as the object with the given key.
+// The checksum is the hex encoded SHA-256 checksum of the content, which the
+// storage service verifies.
+func (c *Client) PutObject(ctx context.Context, key string, r io.Reader, size int64, checksum string) error {
+	var body io.ReadCloser = ioutil.NopCloser(r)
+	if size == 0 {
+		body = http.NoBody
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPut, key, nil, body, checksum)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	return c.do(req, nil)
+}
+
+// ReadObjectAt reads len(p) bytes of the object with the given key, starting at
+// offset off.
+func (c *Client) ReadObjectAt(ctx context.Context, key string, p []byte, off int64) error {
+	if len(p) == 0 {
+		return nil
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil, EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  fmt.Sprintf("s3: %s %s: range requests not supported", req.Method, req.URL.Path),
+		}
+	}
+	if _, err := io.ReadFull(resp.Body, p); err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Msg:  fmt.Sprintf("s3: %s %s: short read", req.Method, req.URL.Path),
+			Err:  err,
+		}
+	}
+	return nil
+}
+
+// DeleteObject deletes the object with the given key.
+func (c *Client) DeleteObject(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, key, nil, nil, EmptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
+
+// listBucketResult is the response to a ListObjectsV2 request.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects returns the keys of all objects starting with prefix.
+func (c *Client) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var (
+		keys  []string
+		token string
+	)
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		req, err := c.newRequest(ctx, http.MethodGet, "", query, nil, EmptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var res listBucketResult
+		if err := c.do(req, &res); err != nil {
+			return nil, err
+		}
+
+		for _, c := range res.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !res.IsTruncated || res.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = res.NextContinuationToken
+	}
+}
+
+// s3Error is the body of an error response.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+// send sends the request and returns the response if it is successful.
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+
+		var e s3Error
+		if err := xml.NewDecoder(resp.Body).Decode(&e); err != nil || e.Code == "" {
+			e.Code, e.Message = resp.Status, ""
+		}
+		code := influxdb.EInternal
+		if resp.StatusCode == http.StatusNotFound {
+			code = influxdb.ENotFound
+		}
+		return nil, &influxdb.Error{
+			Code: code,
+			Msg:  fmt.Sprintf("s3: %s %s: %s %s", req.Method, req.URL.Path, e.Code, e.Message),
+		}
+	}
+	return resp, nil
+}
+
+// do sends the request and decodes the XML response body into v, if not nil.
+func (c *Client) do(req *http.Request, v interface{}) error {
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if v == nil {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	return xml.NewDecoder(resp.Body).Decode(v)
+}
+
+// newRequest returns a signed request for the object with the given key, or for
+// the bucket if key is empty.
+func (c *Client) newRequest(ctx context.Context, method, key string, query url.Values, body io.ReadCloser, payloadHash string) (*http.Request, error) {
+	u, err := url.Parse(c.config.Endpoint)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "invalid s3 endpoint",
+			Err:  err,
+		}
+	}
+
+	p := "/" + c.config.Bucket
+	if key != "" {
+		p += "/" + key
+	}
+	u.Path = p
+	u.RawPath = uriEncode(p, false)
+	u.RawQuery = canonicalQuery(query)
+
+	req, err := http.NewRequest(method, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Body = body
+	}
+
+	c.sign(req, payloadHash)
+	return req, nil
+}
+
+// sign adds the AWS Signature Version 4 authorization headers to req.
+func (c *Client) sign(req *http.Request, payloadHash string) {
+	t := c.now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	req.Header.Set("x-amz-date", amzDate)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, c.config.Region, "s3", "aws4_request"}, "/")
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256(canonicalRequest),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+c.config.SecretAccessKey), date)
+	key = hmacSHA256(key, c.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data string) string {
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalQuery encodes the query parameters sorted by name, as required for
+// signing.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes all characters of s except the unreserved ones, and
+// slashes unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,176 @@
+// Package s3test provides a stand-in for an S3-compatible object storage
+// service for use in tests.
+package s3test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// AccessKeyID is the access key ID requests must be signed with.
+const AccessKeyID = "AKID"
+
+// Server holds the objects of a single bucket in memory. It supports uploads,
+// deletes, ranged reads and listings. Listings return two keys at a time to
+// exercise pagination.
+type Server struct {
+	*httptest.Server
+
+	t      *testing.T
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+// NewServer starts a new Server for the bucket.
+func NewServer(t *testing.T, bucket string) *Server {
+	s := &Server{
+		t:       t,
+		bucket:  bucket,
+		objects: make(map[string][]byte),
+	}
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+// Object returns the content of the object with the given key.
+func (s *Server) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.objects[key]
+	return b, ok
+}
+
+// Keys returns the keys of all objects, sorted.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential="+AccessKeyID+"/") || !strings.Contains(auth, "Signature=") {
+		s.error(w, http.StatusForbidden, "AccessDenied")
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/"+s.bucket)
+	key := strings.TrimPrefix(p, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			s.error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != r.Header.Get("x-amz-content-sha256") {
+			s.error(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch")
+			return
+		}
+		s.objects[key] = b
+	case r.Method == http.MethodDelete && key != "":
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key != "":
+		s.get(w, r, key)
+	case r.Method == http.MethodGet && p == "" && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r)
+	default:
+		s.error(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// get responds with the object, or the requested range of it.
+func (s *Server) get(w http.ResponseWriter, r *http.Request, key string) {
+	b, ok := s.objects[key]
+	if !ok {
+		s.error(w, http.StatusNotFound, "NoSuchKey")
+		return
+	}
+
+	rng := r.Header.Get("Range")
+	if rng == "" {
+		w.Write(b)
+		return
+	}
+
+	var start, end int
+	if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil || start > end || start >= len(b) {
+		s.error(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		return
+	}
+	if end >= len(b) {
+		end = len(b) - 1
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(b)))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(b[start : end+1])
+}
+
+// list responds with the objects matching the prefix, two at a time.
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	var keys []string
+	for key := range s.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	start, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+	end := start + 2
+	type content struct {
+		Key string
+	}
+	res := struct {
+		XMLName               xml.Name `xml:"ListBucketResult"`
+		Contents              []content
+		IsTruncated           bool
+		NextContinuationToken string `xml:",omitempty"`
+	}{}
+	if end < len(keys) {
+		res.IsTruncated = true
+		res.NextContinuationToken = strconv.Itoa(end)
+	} else {
+		end = len(keys)
+	}
+	for _, key := range keys[start:end] {
+		res.Contents = append(res.Contents, content{Key: key})
+	}
+
+	if err := xml.NewEncoder(w).Encode(res); err != nil {
+		s.t.Error(err)
+	}
+}
+
+func (s *Server) error(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string
+	}{Code: code})
+}
@@ -19,7 +19,7 @@ func AddDialectMappings(mappings flux.DialectMappings) error {
 type Dialect struct {
 	TimeFormat  TimeFormat        // TimeFormat is the format of the timestamp; defaults to RFC3339Nano.
 	Encoding    EncodingFormat    // Encoding is the format of the results; defaults to JSON.
-	ChunkSize   int               // Chunks is the number of points per chunk encoding batch; defaults to 0 or no chunking.
+	ChunkSize   int               // ChunkSize is the number of points per chunk encoding batch; defaults to 0 or no chunking.
 	Compression CompressionFormat // Compression is the compression of the result output; defaults to None.
 }
 
@@ -37,7 +37,10 @@ func (d *Dialect) SetHeaders(w http.ResponseWriter) {
 func (d *Dialect) Encoder() flux.MultiResultEncoder {
 	switch d.Encoding {
 	case JSON, JSONPretty:
-		return new(MultiResultEncoder)
+		return &MultiResultEncoder{
+			TimeFormat: d.TimeFormat,
+			ChunkSize:  d.ChunkSize,
+		}
 	default:
 		panic("not implemented")
 	}
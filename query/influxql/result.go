@@ -13,178 +13,317 @@ import (
 )
 
 // MultiResultEncoder encodes results as InfluxQL JSON format.
-type MultiResultEncoder struct{}
+type MultiResultEncoder struct {
+	// TimeFormat is the format of the timestamps; defaults to RFC3339Nano.
+	TimeFormat TimeFormat
+	// ChunkSize is the maximum number of values of a series in each response
+	// of a chunked encoding. Results are encoded in a single response if zero.
+	ChunkSize int
+}
 
 // Encode writes a collection of results to the influxdb 1.X http response format.
 // Expectations/Assumptions:
-//  1.  Each result will be published as a 'statement' in the top-level list of results. The result name
-//      will be interpreted as an integer and used as the statement id.
-//  2.  If the _measurement name is present in the group key, it will be used as the result name instead
-//      of as a normal tag.
-//  3.  All columns in the group key must be strings and they will be used as tags. There is no current way
-//      to have a tag and field be the same name in the results.
-//      TODO(jsternberg): For full compatibility, the above must be possible.
-//  4.  All other columns are fields and will be output in the order they are found.
-//      TODO(jsternberg): This function currently requires the first column to be a time field, but this isn't
-//      a strict requirement and will be lifted when we begin to work on transpiling meta queries.
+//  1. Each result will be published as a 'statement' in the top-level list of results. The result name
+//     will be interpreted as an integer and used as the statement id.
+//  2. If the _measurement name is present in the group key, it will be used as the result name instead
+//     of as a normal tag.
+//  3. All columns in the group key must be strings and they will be used as tags. There is no current way
+//     to have a tag and field be the same name in the results.
+//     TODO(jsternberg): For full compatibility, the above must be possible.
+//  4. All other columns are fields and will be output in the order they are found.
+//     TODO(jsternberg): This function currently requires the first column to be a time field, but this isn't
+//     a strict requirement and will be lifted when we begin to work on transpiling meta queries.
+//
+// When ChunkSize is set, each chunk of a series is written as a separate response, one per line,
+// like the chunked responses of influxdb 1.X. The partial flags of the rows and results of the
+// chunks tell whether more chunks follow for the series and the statement.
 func (e *MultiResultEncoder) Encode(w io.Writer, results flux.ResultIterator) (int64, error) {
+	if e.ChunkSize > 0 {
+		return e.encodeChunked(w, results)
+	}
+
 	resp := Response{}
 	wc := &iocounter.Writer{Writer: w}
 
 	for results.More() {
 		res := results.Next()
-		name := res.Name()
-		id, err := strconv.Atoi(name)
+		id, err := statementID(res)
 		if err != nil {
-			resp.error(fmt.Errorf("unable to parse statement id from result name: %s", err))
+			resp.error(err)
 			results.Release()
 			break
 		}
 
-		tables := res.Tables()
-
 		result := Result{StatementID: id}
-		if err := tables.Do(func(tbl flux.Table) error {
-			var row Row
-
-			for j, c := range tbl.Key().Cols() {
-				if c.Type != flux.TString {
-					// Skip any columns that aren't strings. They are extra ones that
-					// flux includes by default like the start and end times that we do not
-					// care about.
-					continue
-				}
-				v := tbl.Key().Value(j).Str()
-				if c.Label == "_measurement" {
-					row.Name = v
-				} else if c.Label == "_field" {
-					// If the field key was not removed by a previous operation, we explicitly
-					// ignore it here when encoding the result back.
-				} else {
-					if row.Tags == nil {
-						row.Tags = make(map[string]string)
-					}
-					row.Tags[c.Label] = v
-				}
+		if err := res.Tables().Do(func(tbl flux.Table) error {
+			row, err := e.encodeTable(tbl)
+			if err != nil {
+				return err
 			}
+			result.Series = append(result.Series, row)
+			return nil
+		}); err != nil {
+			resp.error(err)
+			results.Release()
+			break
+		}
+		resp.Results = append(resp.Results, result)
+	}
 
-			// TODO: resultColMap should be constructed from query metadata once it is provided.
-			// for now we know that an influxql query ALWAYS has time first, so we put this placeholder
-			// here to catch this most obvious requirement.  Column orderings should be explicitly determined
-			// from the ordering given in the original flux.
-			resultColMap := map[string]int{}
-			j := 1
-			for _, c := range tbl.Cols() {
-				if c.Label == execute.DefaultTimeColLabel {
-					resultColMap[c.Label] = 0
-				} else if !tbl.Key().HasCol(c.Label) {
-					resultColMap[c.Label] = j
-					j++
-				}
-			}
+	if err := results.Err(); err != nil && resp.Err == "" {
+		resp.error(err)
+	}
 
-			if _, ok := resultColMap[execute.DefaultTimeColLabel]; !ok {
-				for k, v := range resultColMap {
-					resultColMap[k] = v - 1
-				}
+	err := json.NewEncoder(wc).Encode(resp)
+	return wc.Count(), err
+}
+
+// encodeChunked writes the results as a stream of responses of at most
+// ChunkSize values. A chunk is only written once the next one is known, so
+// that the last chunk of a statement is not marked partial.
+func (e *MultiResultEncoder) encodeChunked(w io.Writer, results flux.ResultIterator) (int64, error) {
+	wc := &iocounter.Writer{Writer: w}
+	enc := json.NewEncoder(wc)
+
+	var failed bool
+	writeError := func(err error) error {
+		failed = true
+		return enc.Encode(Response{Err: err.Error()})
+	}
+
+	for results.More() {
+		res := results.Next()
+		id, err := statementID(res)
+		if err != nil {
+			results.Release()
+			if err := writeError(err); err != nil {
+				return wc.Count(), err
 			}
+			break
+		}
 
-			row.Columns = make([]string, len(resultColMap))
-			for k, v := range resultColMap {
-				if k == execute.DefaultTimeColLabel {
-					k = "time"
-				}
-				row.Columns[v] = k
+		var pending *Result
+		flush := func(partial bool) error {
+			pending.Partial = partial
+			err := enc.Encode(Response{Results: []Result{*pending}})
+			pending = nil
+			return err
+		}
+
+		var writeErr error
+		if err := res.Tables().Do(func(tbl flux.Table) error {
+			row, err := e.encodeTable(tbl)
+			if err != nil {
+				return err
 			}
 
-			if err := tbl.Do(func(cr flux.ColReader) error {
-				// Preallocate the number of rows for the response to make this section
-				// of code easier to read. Find a time column which should exist
-				// in the output.
-				values := make([][]interface{}, cr.Len())
-				for j := range values {
-					values[j] = make([]interface{}, len(row.Columns))
+			values := row.Values
+			for {
+				n := len(values)
+				if n > e.ChunkSize {
+					n = e.ChunkSize
 				}
 
-				j := 0
-				for idx, c := range tbl.Cols() {
-					if cr.Key().HasCol(c.Label) {
-						continue
-					}
-
-					j = resultColMap[c.Label]
-					// Fill in the values for each column.
-					switch c.Type {
-					case flux.TFloat:
-						vs := cr.Floats(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = vs.Value(i)
-							}
-						}
-					case flux.TInt:
-						vs := cr.Ints(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = vs.Value(i)
-							}
-						}
-					case flux.TString:
-						vs := cr.Strings(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = vs.ValueString(i)
-							}
-						}
-					case flux.TUInt:
-						vs := cr.UInts(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = vs.Value(i)
-							}
-						}
-					case flux.TBool:
-						vs := cr.Bools(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = vs.Value(i)
-							}
-						}
-					case flux.TTime:
-						vs := cr.Times(idx)
-						for i := 0; i < vs.Len(); i++ {
-							if vs.IsValid(i) {
-								values[i][j] = execute.Time(vs.Value(i)).Time().Format(time.RFC3339Nano)
-							}
-						}
-					default:
-						return fmt.Errorf("unsupported column type: %s", c.Type)
+				if pending != nil {
+					if err := flush(true); err != nil {
+						writeErr = err
+						return err
 					}
+				}
+				chunk := *row
+				chunk.Values = values[:n]
+				chunk.Partial = n < len(values)
+				pending = &Result{StatementID: id, Series: []*Row{&chunk}}
 
+				if values = values[n:]; len(values) == 0 {
+					return nil
 				}
-				row.Values = append(row.Values, values...)
-				return nil
-			}); err != nil {
-				return err
 			}
-
-			result.Series = append(result.Series, &row)
-			return nil
 		}); err != nil {
-			resp.error(err)
 			results.Release()
+			if writeErr != nil {
+				return wc.Count(), writeErr
+			}
+			if err := writeError(err); err != nil {
+				return wc.Count(), err
+			}
 			break
 		}
-		resp.Results = append(resp.Results, result)
+
+		if pending == nil {
+			pending = &Result{StatementID: id}
+		}
+		if err := flush(false); err != nil {
+			results.Release()
+			return wc.Count(), err
+		}
 	}
 
-	if err := results.Err(); err != nil && resp.Err == "" {
-		resp.error(err)
+	if err := results.Err(); err != nil && !failed {
+		if err := writeError(err); err != nil {
+			return wc.Count(), err
+		}
 	}
+	return wc.Count(), nil
+}
 
-	err := json.NewEncoder(wc).Encode(resp)
-	return wc.Count(), err
+// statementID returns the statement id of a result from its name.
+func statementID(res flux.Result) (int, error) {
+	id, err := strconv.Atoi(res.Name())
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse statement id from result name: %s", err)
+	}
+	return id, nil
+}
+
+// encodeTable returns the series of a table.
+func (e *MultiResultEncoder) encodeTable(tbl flux.Table) (*Row, error) {
+	var row Row
+
+	for j, c := range tbl.Key().Cols() {
+		if c.Type != flux.TString {
+			// Skip any columns that aren't strings. They are extra ones that
+			// flux includes by default like the start and end times that we do not
+			// care about.
+			continue
+		}
+		v := tbl.Key().Value(j).Str()
+		if c.Label == "_measurement" {
+			row.Name = v
+		} else if c.Label == "_field" {
+			// If the field key was not removed by a previous operation, we explicitly
+			// ignore it here when encoding the result back.
+		} else {
+			if row.Tags == nil {
+				row.Tags = make(map[string]string)
+			}
+			row.Tags[c.Label] = v
+		}
+	}
+
+	// TODO: resultColMap should be constructed from query metadata once it is provided.
+	// for now we know that an influxql query ALWAYS has time first, so we put this placeholder
+	// here to catch this most obvious requirement.  Column orderings should be explicitly determined
+	// from the ordering given in the original flux.
+	resultColMap := map[string]int{}
+	j := 1
+	for _, c := range tbl.Cols() {
+		if c.Label == execute.DefaultTimeColLabel {
+			resultColMap[c.Label] = 0
+		} else if !tbl.Key().HasCol(c.Label) {
+			resultColMap[c.Label] = j
+			j++
+		}
+	}
+
+	if _, ok := resultColMap[execute.DefaultTimeColLabel]; !ok {
+		for k, v := range resultColMap {
+			resultColMap[k] = v - 1
+		}
+	}
+
+	row.Columns = make([]string, len(resultColMap))
+	for k, v := range resultColMap {
+		if k == execute.DefaultTimeColLabel {
+			k = "time"
+		}
+		row.Columns[v] = k
+	}
+
+	if err := tbl.Do(func(cr flux.ColReader) error {
+		// Preallocate the number of rows for the response to make this section
+		// of code easier to read. Find a time column which should exist
+		// in the output.
+		values := make([][]interface{}, cr.Len())
+		for j := range values {
+			values[j] = make([]interface{}, len(row.Columns))
+		}
+
+		j := 0
+		for idx, c := range tbl.Cols() {
+			if cr.Key().HasCol(c.Label) {
+				continue
+			}
+
+			j = resultColMap[c.Label]
+			// Fill in the values for each column.
+			switch c.Type {
+			case flux.TFloat:
+				vs := cr.Floats(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = vs.Value(i)
+					}
+				}
+			case flux.TInt:
+				vs := cr.Ints(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = vs.Value(i)
+					}
+				}
+			case flux.TString:
+				vs := cr.Strings(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = vs.ValueString(i)
+					}
+				}
+			case flux.TUInt:
+				vs := cr.UInts(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = vs.Value(i)
+					}
+				}
+			case flux.TBool:
+				vs := cr.Bools(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = vs.Value(i)
+					}
+				}
+			case flux.TTime:
+				vs := cr.Times(idx)
+				for i := 0; i < vs.Len(); i++ {
+					if vs.IsValid(i) {
+						values[i][j] = e.TimeFormat.format(execute.Time(vs.Value(i)).Time())
+					}
+				}
+			default:
+				return fmt.Errorf("unsupported column type: %s", c.Type)
+			}
+
+		}
+		row.Values = append(row.Values, values...)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return &row, nil
+}
+
+// format returns the timestamp t in the time format f.
+func (f TimeFormat) format(t time.Time) interface{} {
+	switch f {
+	case Hour:
+		return t.UnixNano() / int64(time.Hour)
+	case Minute:
+		return t.UnixNano() / int64(time.Minute)
+	case Second:
+		return t.UnixNano() / int64(time.Second)
+	case Millisecond:
+		return t.UnixNano() / int64(time.Millisecond)
+	case Microsecond:
+		return t.UnixNano() / int64(time.Microsecond)
+	case Nanosecond:
+		return t.UnixNano()
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
 }
+
 func NewMultiResultEncoder() *MultiResultEncoder {
 	return new(MultiResultEncoder)
 }
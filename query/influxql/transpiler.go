@@ -53,6 +53,7 @@ func (t *Transpiler) Transpile(ctx context.Context, txt string) (*ast.Package, e
 }
 
 type transpilerState struct {
+	ctx            context.Context // context of the statement being transpiled
 	stmt           *influxql.SelectStatement
 	config         Config
 	file           *ast.File
@@ -83,6 +84,7 @@ func newTranspilerState(dbrpMappingSvc platform.DBRPMappingService, config *Conf
 }
 
 func (t *transpilerState) Transpile(ctx context.Context, id int, s influxql.Statement) error {
+	t.ctx = ctx
 	expr, err := t.transpile(ctx, s)
 	if err != nil {
 		return err
@@ -673,16 +675,23 @@ func (t *transpilerState) from(m *influxql.Measurement) (ast.Expression, error)
 	}
 	if rp != "" {
 		filter.RetentionPolicy = &rp
+	} else {
+		// Without a retention policy, the default mapping of the database is
+		// used. A retention policy selects its mapping, default or not.
+		defaultRP := true
+		filter.Default = &defaultRP
 	}
-	defaultRP := rp == ""
-	filter.Default = &defaultRP
-	mapping, err := t.dbrpMappingSvc.Find(context.TODO(), filter)
+	mapping, err := t.dbrpMappingSvc.Find(t.ctx, filter)
 	var args []ast.Expression
 	if err != nil {
 		if !t.config.FallbackToDBRP {
 			return nil, err
 		}
-		// use `db/rp` naming convention
+		// use `db/rp` naming convention, with the retention policy
+		// defaulting to the default one of influxdb 1.x
+		if rp == "" {
+			rp = "autogen"
+		}
 		args = []ast.Expression{
 			&ast.ObjectExpression{
 				Properties: []*ast.Property{
@@ -15,6 +15,7 @@ import (
 const (
 	ReadRangePhysKind     = "ReadRangePhysKind"
 	ReadGroupPhysKind     = "ReadGroupPhysKind"
+	ReadLastPhysKind      = "ReadLastPhysKind"
 	ReadTagKeysPhysKind   = "ReadTagKeysPhysKind"
 	ReadTagValuesPhysKind = "ReadTagValuesPhysKind"
 )
@@ -117,6 +118,21 @@ func (s *ReadRangePhysSpec) TimeBounds(predecessorBounds *plan.Bounds) *plan.Bou
 	}
 }
 
+// ReadLastPhysSpec reads the last value of each series.
+type ReadLastPhysSpec struct {
+	ReadRangePhysSpec
+}
+
+func (s *ReadLastPhysSpec) Kind() plan.ProcedureKind {
+	return ReadLastPhysKind
+}
+
+func (s *ReadLastPhysSpec) Copy() plan.ProcedureSpec {
+	ns := new(ReadLastPhysSpec)
+	ns.ReadRangePhysSpec = *s.ReadRangePhysSpec.Copy().(*ReadRangePhysSpec)
+	return ns
+}
+
 type ReadTagKeysPhysSpec struct {
 	ReadRangePhysSpec
 }
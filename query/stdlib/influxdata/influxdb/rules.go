@@ -14,6 +14,7 @@ func init() {
 		PushDownRangeRule{},
 		PushDownFilterRule{},
 		PushDownGroupRule{},
+		PushDownLastRule{},
 		PushDownReadTagKeysRule{},
 		PushDownReadTagValuesRule{},
 		SortedPivotRule{},
@@ -58,6 +59,34 @@ func (rule PushDownGroupRule) Rewrite(node plan.Node) (plan.Node, bool, error) {
 	}), true, nil
 }
 
+// PushDownLastRule pushes down a last selector to storage, so that only the
+// last value of each series is read.
+type PushDownLastRule struct{}
+
+func (rule PushDownLastRule) Name() string {
+	return "PushDownLastRule"
+}
+
+// Pattern matches 'ReadRange |> last'
+func (rule PushDownLastRule) Pattern() plan.Pattern {
+	return plan.Pat(universe.LastKind, plan.Pat(ReadRangePhysKind))
+}
+
+func (rule PushDownLastRule) Rewrite(node plan.Node) (plan.Node, bool, error) {
+	src := node.Predecessors()[0].ProcedureSpec().(*ReadRangePhysSpec)
+	last := node.ProcedureSpec().(*universe.LastProcedureSpec)
+
+	// Only the value column is known to exist in the tables read from
+	// storage. Selecting by another column fails if it does not exist.
+	if last.Column != execute.DefaultValueColLabel {
+		return node, false, nil
+	}
+
+	return plan.CreatePhysicalNode("ReadLast", &ReadLastPhysSpec{
+		ReadRangePhysSpec: *src.Copy().(*ReadRangePhysSpec),
+	}), true, nil
+}
+
 // PushDownRangeRule pushes down a range filter to storage
 type PushDownRangeRule struct{}
 
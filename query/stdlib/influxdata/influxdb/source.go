@@ -20,6 +20,7 @@ import (
 func init() {
 	execute.RegisterSource(ReadRangePhysKind, createReadFilterSource)
 	execute.RegisterSource(ReadGroupPhysKind, createReadGroupSource)
+	execute.RegisterSource(ReadLastPhysKind, createReadLastSource)
 	execute.RegisterSource(ReadTagKeysPhysKind, createReadTagKeysSource)
 	execute.RegisterSource(ReadTagValuesPhysKind, createReadTagValuesSource)
 }
@@ -280,6 +281,85 @@ func createReadGroupSource(s plan.ProcedureSpec, id execute.DatasetID, a execute
 	), nil
 }
 
+type readLastSource struct {
+	Source
+	reader   Reader
+	readSpec ReadLastSpec
+}
+
+func ReadLastSource(id execute.DatasetID, r Reader, readSpec ReadLastSpec, a execute.Administration) execute.Source {
+	src := new(readLastSource)
+
+	src.id = id
+	src.alloc = a.Allocator()
+
+	src.reader = r
+	src.readSpec = readSpec
+
+	src.m = GetStorageDependencies(a.Context()).FromDeps.Metrics
+	src.orgID = readSpec.OrganizationID
+	src.op = "readLast"
+
+	src.runner = src
+	return src
+}
+
+func (s *readLastSource) run(ctx context.Context) error {
+	stop := s.readSpec.Bounds.Stop
+	tables, err := s.reader.ReadLast(
+		ctx,
+		s.readSpec,
+		s.alloc,
+	)
+	if err != nil {
+		return err
+	}
+	return s.processTables(ctx, tables, stop)
+}
+
+func createReadLastSource(s plan.ProcedureSpec, id execute.DatasetID, a execute.Administration) (execute.Source, error) {
+	span, ctx := tracing.StartSpanFromContext(a.Context())
+	defer span.Finish()
+
+	spec := s.(*ReadLastPhysSpec)
+
+	bounds := a.StreamContext().Bounds()
+	if bounds == nil {
+		return nil, errors.New("nil bounds passed to from")
+	}
+
+	deps := GetStorageDependencies(a.Context()).FromDeps
+
+	req := query.RequestFromContext(a.Context())
+	if req == nil {
+		return nil, errors.New("missing request on context")
+	}
+
+	orgID := req.OrganizationID
+	bucketID, err := spec.LookupBucketID(ctx, orgID, deps.BucketLookup)
+	if err != nil {
+		return nil, err
+	}
+
+	var filter *semantic.FunctionExpression
+	if spec.FilterSet {
+		filter = spec.Filter
+	}
+	return ReadLastSource(
+		id,
+		deps.Reader,
+		ReadLastSpec{
+			ReadFilterSpec: ReadFilterSpec{
+				OrganizationID: orgID,
+				BucketID:       bucketID,
+				Bounds:         *bounds,
+				Predicate:      filter,
+			},
+		},
+		a,
+	), nil
+}
+
 func createReadTagKeysSource(prSpec plan.ProcedureSpec, dsid execute.DatasetID, a execute.Administration) (execute.Source, error) {
 	span, ctx := tracing.StartSpanFromContext(a.Context())
 	defer span.Finish()
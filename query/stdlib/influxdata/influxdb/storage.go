@@ -119,6 +119,10 @@ type ReadGroupSpec struct {
 	AggregateMethod string
 }
 
+type ReadLastSpec struct {
+	ReadFilterSpec
+}
+
 type ReadTagKeysSpec struct {
 	ReadFilterSpec
 }
@@ -131,6 +135,7 @@ type ReadTagValuesSpec struct {
 type Reader interface {
 	ReadFilter(ctx context.Context, spec ReadFilterSpec, alloc *memory.Allocator) (TableIterator, error)
 	ReadGroup(ctx context.Context, spec ReadGroupSpec, alloc *memory.Allocator) (TableIterator, error)
+	ReadLast(ctx context.Context, spec ReadLastSpec, alloc *memory.Allocator) (TableIterator, error)
 
 	ReadTagKeys(ctx context.Context, spec ReadTagKeysSpec, alloc *memory.Allocator) (TableIterator, error)
 	ReadTagValues(ctx context.Context, spec ReadTagValuesSpec, alloc *memory.Allocator) (TableIterator, error)
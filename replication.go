@@ -0,0 +1,90 @@
+package influxdb
+
+import (
+	"context"
+	"io"
+)
+
+// Ops for replication errors.
+const (
+	OpFindReplicationStatus = "FindReplicationStatus"
+	OpStreamReplication     = "StreamReplication"
+	OpPromoteStandby        = "PromoteStandby"
+)
+
+// Replication roles of an instance.
+const (
+	ReplicationRoleNone    = "none"
+	ReplicationRolePrimary = "primary"
+	ReplicationRoleStandby = "standby"
+)
+
+// ErrReplicationDisabled is returned when streaming from an instance that is
+// not a primary.
+var ErrReplicationDisabled = &Error{
+	Code: EUnavailable,
+	Msg:  "instance is not a replication primary",
+}
+
+// ErrNotStandby is returned when promoting an instance that is not a standby.
+var ErrNotStandby = &Error{
+	Code: EConflict,
+	Msg:  "instance is not a replication standby",
+}
+
+// ErrReplicationPositionLost is returned when streaming from a position the
+// primary no longer holds the entries following.
+var ErrReplicationPositionLost = &Error{
+	Code: EConflict,
+	Msg:  "replication position is no longer available on the primary; reseed the standby from a backup of the primary",
+}
+
+// ErrStandbyReadOnly is returned when writing to a standby that has not been
+// promoted.
+var ErrStandbyReadOnly = &Error{
+	Code: EForbidden,
+	Msg:  "instance is a replication standby and does not accept writes until promoted",
+}
+
+// ReplicationPosition identifies an entry of the replication log of a primary.
+// The epoch changes every time the primary starts, and sequences start over.
+type ReplicationPosition struct {
+	Epoch    uint64 `json:"epoch"`
+	Sequence uint64 `json:"sequence"`
+}
+
+// ReplicationStatus describes the replication state of an instance.
+type ReplicationStatus struct {
+	Role string `json:"role"`
+	// Position is the position of the last entry of the log of a primary, or
+	// of the last entry applied by a standby.
+	Position ReplicationPosition `json:"position"`
+	// Standbys is the number of standbys streaming from a primary.
+	Standbys int `json:"standbys"`
+	// PrimaryURL is the URL of the primary followed by a standby.
+	PrimaryURL string `json:"primaryURL,omitempty"`
+	// Connected is true if a standby is streaming from its primary.
+	Connected bool `json:"connected"`
+	// LagEntries is the number of entries of the log of the primary a standby
+	// has not applied yet, as of the last entry or heartbeat received.
+	LagEntries uint64 `json:"lagEntries"`
+	// LagSeconds is the age of the last entry applied by a standby if it has
+	// entries left to apply, and 0 otherwise.
+	LagSeconds float64 `json:"lagSeconds"`
+}
+
+// ReplicationService streams the writes, deletes and metadata changes of a
+// primary instance to hot-standby instances, and promotes standbys.
+type ReplicationService interface {
+	// FindReplicationStatus returns the replication state of the instance.
+	FindReplicationStatus(ctx context.Context) (*ReplicationStatus, error)
+
+	// StreamReplication writes the entries of the log of a primary following
+	// pos to w as they are appended, until ctx is done or writing fails. A
+	// zero position streams from the oldest entry held by the primary.
+	StreamReplication(ctx context.Context, pos ReplicationPosition, w io.Writer) error
+
+	// PromoteStandby stops a standby from following its primary and makes it
+	// accept writes.
+	PromoteStandby(ctx context.Context) error
+}
@@ -0,0 +1,102 @@
+package replication
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// EntryType is the type of an entry of the replication log.
+type EntryType byte
+
+const (
+	// EntryWAL holds an entry written to the WAL of the storage engine: its
+	// wal.WalEntryType followed by its snappy compressed encoding.
+	EntryWAL EntryType = 0x01
+
+	// EntryMetadata holds the changes committed to the metadata store by a
+	// transaction.
+	EntryMetadata EntryType = 0x02
+
+	// EntryStart is the first entry of a stream. Its sequence is the sequence
+	// of the entry the stream continues after. It is not stored in the log.
+	EntryStart EntryType = 0x03
+
+	// EntryHeartbeat is streamed when the log has no entries to stream. Its
+	// sequence is the sequence of the last entry of the log. It is not stored
+	// in the log.
+	EntryHeartbeat EntryType = 0x04
+)
+
+// entryHeaderSize is the size of the type, epoch, sequence, time and data
+// length of an encoded entry.
+const entryHeaderSize = 1 + 8 + 8 + 8 + 4
+
+// maxEntryDataSize is the maximum size of the data of an entry read from a
+// stream, to avoid allocating memory for corrupted lengths.
+const maxEntryDataSize = 1 << 30
+
+// Entry is an entry of the replication log.
+//
+// Entries are encoded as:
+//
+//	┌──────────────────────────────────────────────────────┐
+//	│                        Entry                         │
+//	├──────┬─────────┬──────────┬─────────┬────────┬───────┤
+//	│ Type │  Epoch  │ Sequence │  Time   │ Length │ Data  │
+//	│1 byte│ 8 bytes │ 8 bytes  │ 8 bytes │4 bytes │N bytes│
+//	└──────┴─────────┴──────────┴─────────┴────────┴───────┘
+type Entry struct {
+	Type     EntryType
+	Epoch    uint64
+	Sequence uint64
+	Time     int64 // Unix time in nanoseconds the entry was appended.
+	Data     []byte
+}
+
+// size returns the size of the encoded entry.
+func (e *Entry) size() int {
+	return entryHeaderSize + len(e.Data)
+}
+
+// MarshalBinary encodes the entry.
+func (e *Entry) MarshalBinary() ([]byte, error) {
+	b := make([]byte, e.size())
+	b[0] = byte(e.Type)
+	binary.BigEndian.PutUint64(b[1:9], e.Epoch)
+	binary.BigEndian.PutUint64(b[9:17], e.Sequence)
+	binary.BigEndian.PutUint64(b[17:25], uint64(e.Time))
+	binary.BigEndian.PutUint32(b[25:29], uint32(len(e.Data)))
+	copy(b[entryHeaderSize:], e.Data)
+	return b, nil
+}
+
+// ReadEntry reads the next encoded entry from r.
+func ReadEntry(r io.Reader) (*Entry, error) {
+	var hdr [entryHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+
+	e := &Entry{
+		Type:     EntryType(hdr[0]),
+		Epoch:    binary.BigEndian.Uint64(hdr[1:9]),
+		Sequence: binary.BigEndian.Uint64(hdr[9:17]),
+		Time:     int64(binary.BigEndian.Uint64(hdr[17:25])),
+	}
+
+	n := binary.BigEndian.Uint32(hdr[25:29])
+	if n > maxEntryDataSize {
+		return nil, fmt.Errorf("replication entry too large: %d bytes", n)
+	}
+	if n > 0 {
+		e.Data = make([]byte, n)
+		if _, err := io.ReadFull(r, e.Data); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+	}
+	return e, nil
+}
@@ -0,0 +1,314 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+const (
+	// minRetryInterval and maxRetryInterval bound the interval between
+	// attempts to stream from the primary after a failure.
+	minRetryInterval = time.Second
+	maxRetryInterval = 30 * time.Second
+
+	// savePositionInterval is the interval at which the position of a
+	// follower is saved. Entries applied since are applied again after a
+	// restart, which is harmless: writes overwrite the same values, and deletes
+	// and metadata changes give the same result.
+	savePositionInterval = time.Second
+)
+
+// Engine is the storage engine entries of the WAL of a primary are applied to.
+type Engine interface {
+	ApplyWALEntry(ctx context.Context, entry wal.WALEntry) error
+}
+
+// Follower streams the replication log of a primary and applies its entries
+// to the storage engine and metadata store of a standby.
+type Follower struct {
+	primary influxdb.ReplicationService
+	engine  Engine
+	store   kv.Store
+	path    string // Path of the position file; the position is not saved if empty.
+
+	logger  *zap.Logger
+	metrics *followerMetrics
+
+	mu          sync.Mutex
+	pos         influxdb.ReplicationPosition
+	saved       influxdb.ReplicationPosition
+	connected   bool
+	primaryLast uint64 // Sequence of the last entry of the primary, as last received.
+	appliedTime int64  // Time the last entry applied was appended by the primary.
+
+	cancel func()
+	wg     sync.WaitGroup
+}
+
+// NewFollower returns a follower applying the entries of the log of primary to
+// engine and store, which saves its position to the file at path.
+func NewFollower(primary influxdb.ReplicationService, engine Engine, store kv.Store, path string) *Follower {
+	return &Follower{
+		primary: primary,
+		engine:  engine,
+		store:   store,
+		path:    path,
+		logger:  zap.NewNop(),
+		metrics: newFollowerMetrics(),
+	}
+}
+
+// WithLogger sets the logger of the follower.
+func (f *Follower) WithLogger(log *zap.Logger) {
+	f.logger = log.With(zap.String("service", "replication-follower"))
+}
+
+// Open reads the saved position of the follower and starts following the
+// primary.
+func (f *Follower) Open(context.Context) error {
+	if f.path != "" {
+		if b, err := ioutil.ReadFile(f.path); err == nil {
+			if err := json.Unmarshal(b, &f.pos); err != nil {
+				return fmt.Errorf("reading replication position: %v", err)
+			}
+			f.saved = f.pos
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel = cancel
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		f.run(ctx)
+	}()
+	return nil
+}
+
+// Close stops following the primary and saves the position of the follower.
+// Entries are no longer applied once Close returns.
+func (f *Follower) Close() error {
+	if f.cancel == nil {
+		return nil
+	}
+	f.cancel()
+	f.wg.Wait()
+	f.cancel = nil
+	return f.savePosition()
+}
+
+// Status returns the replication status of the follower.
+func (f *Follower) Status() *influxdb.ReplicationStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	lagEntries, lagSeconds := f.lag()
+	return &influxdb.ReplicationStatus{
+		Role:       influxdb.ReplicationRoleStandby,
+		Position:   f.pos,
+		Connected:  f.connected,
+		LagEntries: lagEntries,
+		LagSeconds: lagSeconds,
+	}
+}
+
+// lag returns the number of entries left to apply and the age of the last
+// entry applied if there are. It must be called with f.mu held.
+func (f *Follower) lag() (uint64, float64) {
+	if f.primaryLast <= f.pos.Sequence {
+		return 0, 0
+	}
+	return f.primaryLast - f.pos.Sequence, time.Since(time.Unix(0, f.appliedTime)).Seconds()
+}
+
+// run streams from the primary until ctx is done, retrying with a backoff
+// after failures.
+func (f *Follower) run(ctx context.Context) {
+	interval := minRetryInterval
+	for {
+		applied, err := f.follow(ctx)
+		f.setConnected(false)
+		if ctx.Err() != nil {
+			return
+		}
+
+		f.metrics.errors.Inc()
+		if applied {
+			interval = minRetryInterval
+		}
+		f.logger.Warn("Replication stream from primary failed", zap.Error(err), zap.Duration("retry_in", interval))
+		if influxdb.ErrorCode(err) == influxdb.EConflict {
+			f.logger.Error("Standby can no longer follow primary", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > maxRetryInterval {
+			interval = maxRetryInterval
+		}
+	}
+}
+
+// follow streams from the primary and applies its entries until ctx is done
+// or the stream fails. It returns true if entries were applied.
+func (f *Follower) follow(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	f.mu.Lock()
+	pos := f.pos
+	f.mu.Unlock()
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		err := f.primary.StreamReplication(ctx, pos, pw)
+		if err == nil {
+			err = io.EOF
+		}
+		pw.CloseWithError(err)
+	}()
+
+	lastSave := time.Now()
+	applied := false
+	for {
+		e, err := ReadEntry(pr)
+		if err != nil {
+			if err == io.EOF {
+				err = fmt.Errorf("replication stream closed by primary")
+			}
+			return applied, err
+		}
+
+		switch e.Type {
+		case EntryStart:
+			f.mu.Lock()
+			f.pos = influxdb.ReplicationPosition{Epoch: e.Epoch, Sequence: e.Sequence}
+			f.primaryLast = e.Sequence
+			f.connected = true
+			f.mu.Unlock()
+			f.metrics.connected.Set(1)
+			f.logger.Info("Following primary", zap.Uint64("epoch", e.Epoch), zap.Uint64("sequence", e.Sequence))
+
+		case EntryHeartbeat:
+			f.mu.Lock()
+			f.primaryLast = e.Sequence
+			f.mu.Unlock()
+
+		case EntryWAL, EntryMetadata:
+			if err := f.apply(ctx, e); err != nil {
+				return applied, err
+			}
+			applied = true
+
+			f.mu.Lock()
+			f.pos = influxdb.ReplicationPosition{Epoch: e.Epoch, Sequence: e.Sequence}
+			if f.primaryLast < e.Sequence {
+				f.primaryLast = e.Sequence
+			}
+			f.appliedTime = e.Time
+			f.mu.Unlock()
+			f.metrics.applied.WithLabelValues(entryTypeLabel(e.Type)).Inc()
+
+		default:
+			return applied, fmt.Errorf("unknown replication entry type: %v", e.Type)
+		}
+
+		f.mu.Lock()
+		lagEntries, lagSeconds := f.lag()
+		f.mu.Unlock()
+		f.metrics.lagEntries.Set(float64(lagEntries))
+		f.metrics.lagSeconds.Set(lagSeconds)
+
+		if time.Since(lastSave) >= savePositionInterval {
+			if err := f.savePosition(); err != nil {
+				return applied, err
+			}
+			lastSave = time.Now()
+		}
+	}
+}
+
+// apply applies an entry of the log of the primary.
+func (f *Follower) apply(ctx context.Context, e *Entry) error {
+	switch e.Type {
+	case EntryWAL:
+		if len(e.Data) == 0 {
+			return fmt.Errorf("empty replicated WAL entry %d", e.Sequence)
+		}
+		entry, err := wal.DecodeEntry(wal.WalEntryType(e.Data[0]), e.Data[1:])
+		if err != nil {
+			return fmt.Errorf("decoding replicated WAL entry %d: %v", e.Sequence, err)
+		}
+		if err := f.engine.ApplyWALEntry(ctx, entry); err != nil {
+			return fmt.Errorf("applying replicated WAL entry %d: %v", e.Sequence, err)
+		}
+
+	case EntryMetadata:
+		changes, err := decodeChanges(e.Data)
+		if err != nil {
+			return fmt.Errorf("decoding replicated metadata entry %d: %v", e.Sequence, err)
+		}
+		if err := applyChanges(ctx, f.store, changes); err != nil {
+			return fmt.Errorf("applying replicated metadata entry %d: %v", e.Sequence, err)
+		}
+	}
+	return nil
+}
+
+func (f *Follower) setConnected(connected bool) {
+	f.mu.Lock()
+	f.connected = connected
+	f.mu.Unlock()
+	if connected {
+		f.metrics.connected.Set(1)
+	} else {
+		f.metrics.connected.Set(0)
+	}
+}
+
+// savePosition saves the position of the follower if it changed.
+func (f *Follower) savePosition() error {
+	f.mu.Lock()
+	pos := f.pos
+	f.mu.Unlock()
+
+	if f.path == "" || pos == f.saved {
+		return nil
+	}
+
+	b, err := json.Marshal(pos)
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	} else if err := os.Rename(tmp, f.path); err != nil {
+		return err
+	}
+	f.saved = pos
+	return nil
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (f *Follower) PrometheusCollectors() []prometheus.Collector {
+	return f.metrics.PrometheusCollectors()
+}
@@ -0,0 +1,309 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultLogSize is the default maximum size of the entries held by a Log.
+const DefaultLogSize = 256 * 1024 * 1024
+
+// HeartbeatInterval is the interval at which heartbeats are streamed to
+// standbys when there are no entries to stream.
+const HeartbeatInterval = time.Second
+
+// Log is the replication log of a primary. It holds the most recent entries
+// written to the WAL of the storage engine and committed to the metadata
+// store, up to a maximum size, and streams them to standbys.
+//
+// Entries are only held in memory: the sequence of entries starts over in a
+// new epoch every time the log is opened. A standby that followed the previous
+// epoch up to its last entry continues with the new epoch if the log was
+// closed cleanly and still holds its first entry; any other standby has to be
+// reseeded from a backup of the primary.
+type Log struct {
+	path    string // Path of the state file; the epoch is not persisted if empty.
+	maxSize int
+
+	mu       sync.Mutex
+	epoch    uint64
+	prev     *influxdb.ReplicationPosition // Last position of the previous epoch, if closed cleanly.
+	entries  []*Entry
+	first    uint64 // Sequence of entries[0], or of the next entry if there are none.
+	size     int
+	standbys int
+	changed  chan struct{} // Closed and replaced whenever an entry is appended.
+
+	metrics *logMetrics
+}
+
+// logState is the state of a log persisted across restarts.
+type logState struct {
+	Epoch    uint64 `json:"epoch"`
+	Sequence uint64 `json:"sequence"`
+	Clean    bool   `json:"clean"`
+}
+
+// NewLog returns a new log holding entries up to maxSize bytes, which
+// persists its epoch to the state file at path.
+func NewLog(path string, maxSize int) *Log {
+	if maxSize <= 0 {
+		maxSize = DefaultLogSize
+	}
+	return &Log{
+		path:    path,
+		maxSize: maxSize,
+		first:   1,
+		changed: make(chan struct{}),
+		metrics: newLogMetrics(),
+	}
+}
+
+// Open starts a new epoch of the log.
+func (l *Log) Open() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		l.epoch = uint64(time.Now().UnixNano())
+		return nil
+	}
+
+	var prev logState
+	if b, err := ioutil.ReadFile(l.path); err == nil {
+		if err := json.Unmarshal(b, &prev); err != nil {
+			return fmt.Errorf("reading replication state: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if prev.Clean {
+		l.prev = &influxdb.ReplicationPosition{Epoch: prev.Epoch, Sequence: prev.Sequence}
+	}
+	l.epoch = prev.Epoch + 1
+
+	// The state is only clean once the log is closed: if the process stops
+	// before, the last sequence of the epoch is unknown.
+	return l.saveState(logState{Epoch: l.epoch})
+}
+
+// Close records the last position of the epoch, so that standbys which
+// applied it continue with the next epoch.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.path == "" {
+		return nil
+	}
+	return l.saveState(logState{Epoch: l.epoch, Sequence: l.last(), Clean: true})
+}
+
+func (l *Log) saveState(state logState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
+
+// Position returns the position of the last entry of the log.
+func (l *Log) Position() influxdb.ReplicationPosition {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return influxdb.ReplicationPosition{Epoch: l.epoch, Sequence: l.last()}
+}
+
+// Standbys returns the number of standbys streaming the log.
+func (l *Log) Standbys() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.standbys
+}
+
+// last returns the sequence of the last entry of the log. It must be called
+// with l.mu held.
+func (l *Log) last() uint64 {
+	return l.first + uint64(len(l.entries)) - 1
+}
+
+// Replicate appends an entry written to the WAL of the storage engine to the
+// log. It satisfies the wal.Replicator interface.
+func (l *Log) Replicate(typ wal.WalEntryType, compressed []byte) {
+	data := make([]byte, 1+len(compressed))
+	data[0] = byte(typ)
+	copy(data[1:], compressed)
+	l.Append(EntryWAL, data)
+}
+
+// Append appends an entry of type typ holding data to the log, dropping the
+// oldest entries beyond the maximum size of the log. data must not be
+// modified once appended.
+func (l *Log) Append(typ EntryType, data []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := &Entry{
+		Type:     typ,
+		Epoch:    l.epoch,
+		Sequence: l.last() + 1,
+		Time:     time.Now().UnixNano(),
+		Data:     data,
+	}
+	l.entries = append(l.entries, e)
+	l.size += e.size()
+
+	// Always keep the last entry, even if it is larger than the log.
+	for l.size > l.maxSize && len(l.entries) > 1 {
+		l.size -= l.entries[0].size()
+		l.entries[0] = nil
+		l.entries = l.entries[1:]
+		l.first++
+	}
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+
+	l.metrics.sequence.Set(float64(e.Sequence))
+	l.metrics.size.Set(float64(l.size))
+	l.metrics.entries.WithLabelValues(entryTypeLabel(typ)).Inc()
+}
+
+// start returns the sequence of the next entry to stream to a standby at pos.
+func (l *Log) start(pos influxdb.ReplicationPosition) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var next uint64
+	switch {
+	case pos.Epoch == 0 && pos.Sequence == 0:
+		// A new standby starts with the oldest entry held.
+		next = l.first
+	case pos.Epoch == l.epoch:
+		if pos.Sequence > l.last() {
+			return 0, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("replication position %d is ahead of the primary at %d", pos.Sequence, l.last()),
+			}
+		}
+		next = pos.Sequence + 1
+	case l.prev != nil && pos == *l.prev:
+		next = 1
+	default:
+		return 0, influxdb.ErrReplicationPositionLost
+	}
+
+	if next < l.first {
+		return 0, influxdb.ErrReplicationPositionLost
+	}
+	return next, nil
+}
+
+// read returns the entries of the log from sequence next, and a channel closed
+// once more entries are appended.
+func (l *Log) read(next uint64) ([]*Entry, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if next < l.first {
+		return nil, nil, influxdb.ErrReplicationPositionLost
+	}
+	if i := next - l.first; i < uint64(len(l.entries)) {
+		return l.entries[i:len(l.entries):len(l.entries)], l.changed, nil
+	}
+	return nil, l.changed, nil
+}
+
+// heartbeat returns a heartbeat entry holding the last position of the log.
+func (l *Log) heartbeat(typ EntryType, seq uint64) *Entry {
+	return &Entry{
+		Type:     typ,
+		Epoch:    l.epoch,
+		Sequence: seq,
+		Time:     time.Now().UnixNano(),
+	}
+}
+
+// Stream writes the entries of the log following pos to w as they are
+// appended, until ctx is done or writing fails. A heartbeat is written when
+// there have been no entries to write for HeartbeatInterval.
+func (l *Log) Stream(ctx context.Context, pos influxdb.ReplicationPosition, w io.Writer) error {
+	next, err := l.start(pos)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.standbys++
+	l.metrics.standbys.Set(float64(l.standbys))
+	l.mu.Unlock()
+	defer func() {
+		l.mu.Lock()
+		l.standbys--
+		l.metrics.standbys.Set(float64(l.standbys))
+		l.mu.Unlock()
+	}()
+
+	if err := writeEntry(w, l.heartbeat(EntryStart, next-1)); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		entries, changed, err := l.read(next)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := writeEntry(w, e); err != nil {
+				return err
+			}
+			next = e.Sequence + 1
+		}
+		if len(entries) > 0 {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		case <-ticker.C:
+			if err := writeEntry(w, l.heartbeat(EntryHeartbeat, next-1)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeEntry(w io.Writer, e *Entry) error {
+	b, err := e.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (l *Log) PrometheusCollectors() []prometheus.Collector {
+	return l.metrics.PrometheusCollectors()
+}
@@ -0,0 +1,116 @@
+package replication
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	namespace = "replication"
+)
+
+// logMetrics are the metrics of the replication log of a primary.
+type logMetrics struct {
+	sequence prometheus.Gauge
+	size     prometheus.Gauge
+	standbys prometheus.Gauge
+	entries  *prometheus.CounterVec
+}
+
+func newLogMetrics() *logMetrics {
+	const subsystem = "primary"
+
+	return &logMetrics{
+		sequence: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "sequence",
+			Help:      "Sequence of the last entry of the replication log.",
+		}),
+		size: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "log_size_bytes",
+			Help:      "Size of the entries held by the replication log.",
+		}),
+		standbys: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "standbys",
+			Help:      "Number of standbys streaming the replication log.",
+		}),
+		entries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "entries_total",
+			Help:      "Number of entries appended to the replication log.",
+		}, []string{"type"}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *logMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{m.sequence, m.size, m.standbys, m.entries}
+}
+
+// followerMetrics are the metrics of a standby following a primary.
+type followerMetrics struct {
+	connected  prometheus.Gauge
+	lagEntries prometheus.Gauge
+	lagSeconds prometheus.Gauge
+	applied    *prometheus.CounterVec
+	errors     prometheus.Counter
+}
+
+func newFollowerMetrics() *followerMetrics {
+	const subsystem = "standby"
+
+	return &followerMetrics{
+		connected: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "connected",
+			Help:      "1 if the standby is streaming the replication log of its primary, 0 otherwise.",
+		}),
+		lagEntries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "lag_entries",
+			Help:      "Number of entries of the replication log of the primary not applied yet.",
+		}),
+		lagSeconds: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "lag_seconds",
+			Help:      "Age of the last entry applied if entries are left to apply, 0 otherwise.",
+		}),
+		applied: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "applied_entries_total",
+			Help:      "Number of entries of the replication log of the primary applied.",
+		}, []string{"type"}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "stream_errors_total",
+			Help:      "Number of streams of the replication log of the primary that failed.",
+		}),
+	}
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *followerMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{m.connected, m.lagEntries, m.lagSeconds, m.applied, m.errors}
+}
+
+// entryTypeLabel returns the label of the metrics of entries of type typ.
+func entryTypeLabel(typ EntryType) string {
+	switch typ {
+	case EntryWAL:
+		return "wal"
+	case EntryMetadata:
+		return "metadata"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,238 @@
+package replication_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/inmem"
+	"github.com/influxdata/influxdb/kv"
+	"github.com/influxdata/influxdb/replication"
+	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestLog_Stream(t *testing.T) {
+	log := replication.NewLog("", 2*(29+5))
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"one", "two", "three"} {
+		log.Append(replication.EntryMetadata, []byte(data))
+	}
+
+	// The first entry was dropped to keep the log within its maximum size.
+	if err := log.Stream(context.Background(), influxdb.ReplicationPosition{Epoch: log.Position().Epoch, Sequence: 0}, ioutil.Discard); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("got error %v streaming a dropped entry, exp %v", err, influxdb.ErrReplicationPositionLost)
+	}
+	if err := log.Stream(context.Background(), influxdb.ReplicationPosition{Epoch: 1, Sequence: 3}, ioutil.Discard); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("got error %v streaming another epoch, exp %v", err, influxdb.ErrReplicationPositionLost)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(log.Stream(ctx, influxdb.ReplicationPosition{}, pw))
+	}()
+
+	expect := func(typ replication.EntryType, seq uint64, data string) {
+		t.Helper()
+		e, err := replication.ReadEntry(pr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Type != typ || e.Sequence != seq || string(e.Data) != data {
+			t.Fatalf("got entry %v %d %q, exp %v %d %q", e.Type, e.Sequence, e.Data, typ, seq, data)
+		}
+	}
+	expect(replication.EntryStart, 1, "")
+	expect(replication.EntryMetadata, 2, "two")
+	expect(replication.EntryMetadata, 3, "three")
+
+	log.Append(replication.EntryMetadata, []byte("four"))
+	expect(replication.EntryMetadata, 4, "four")
+	expect(replication.EntryHeartbeat, 4, "")
+}
+
+func TestLog_Epochs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "replication.json")
+
+	log := replication.NewLog(path, 0)
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	log.Append(replication.EntryMetadata, []byte("one"))
+	last := log.Position()
+	if err := log.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	log = replication.NewLog(path, 0)
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := log.Position(), (influxdb.ReplicationPosition{Epoch: last.Epoch + 1}); got != exp {
+		t.Fatalf("got position %v, exp %v", got, exp)
+	}
+	log.Append(replication.EntryMetadata, []byte("two"))
+
+	// A standby at the end of the previous epoch continues with the new one.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(log.Stream(ctx, last, pw))
+	}()
+	for _, exp := range []string{"", "two"} {
+		e, err := replication.ReadEntry(pr)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(e.Data) != exp || e.Epoch != last.Epoch+1 {
+			t.Fatalf("got entry %q of epoch %d, exp %q of epoch %d", e.Data, e.Epoch, exp, last.Epoch+1)
+		}
+	}
+
+	// The last sequence of an epoch is unknown if the log was not closed.
+	log = replication.NewLog(path, 0)
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Stream(ctx, influxdb.ReplicationPosition{Epoch: last.Epoch + 1, Sequence: 1}, ioutil.Discard); influxdb.ErrorCode(err) != influxdb.EConflict {
+		t.Fatalf("got error %v, exp %v", err, influxdb.ErrReplicationPositionLost)
+	}
+}
+
+// engine collects the WAL entries applied to it.
+type engine struct {
+	mu      sync.Mutex
+	entries []wal.WALEntry
+}
+
+func (e *engine) ApplyWALEntry(ctx context.Context, entry wal.WALEntry) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.entries = append(e.entries, entry)
+	return nil
+}
+
+func (e *engine) len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.entries)
+}
+
+func TestFollower(t *testing.T) {
+	ctx := context.Background()
+	log := replication.NewLog("", 0)
+	if err := log.Open(); err != nil {
+		t.Fatal(err)
+	}
+	primary := replication.NewPrimaryService(log)
+	primaryStore := replication.NewStore(inmem.NewKVStore(), log)
+
+	// Changes made before the standby follows the primary are streamed too.
+	if err := primaryStore.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket([]byte("users"))
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte("a"), []byte("alice"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "replication")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var eng engine
+	standbyStore := inmem.NewKVStore()
+	follower := replication.NewFollower(primary, &eng, standbyStore, filepath.Join(dir, "position.json"))
+	standby := replication.NewStandbyService(follower, "http://primary")
+	if err := follower.Open(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer standby.Close()
+
+	entry := &wal.WriteWALEntry{Values: map[string][]value.Value{
+		"cpu,host=a#!~#value": {value.NewValue(1, 1.0)},
+	}}
+	b, err := entry.Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Replicate(entry.Type(), snappy.Encode(nil, b))
+
+	if err := primaryStore.Update(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket([]byte("users"))
+		if err != nil {
+			return err
+		}
+		return b.Delete([]byte("a"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool {
+		status, _ := standby.FindReplicationStatus(ctx)
+		return status.Position == log.Position() && status.Connected
+	})
+
+	if got, exp := eng.len(), 1; got != exp {
+		t.Fatalf("got %d WAL entries applied, exp %d", got, exp)
+	}
+	if err := standbyStore.View(ctx, func(tx kv.Tx) error {
+		b, err := tx.Bucket([]byte("users"))
+		if err != nil {
+			return err
+		}
+		_, err = b.Get([]byte("a"))
+		return err
+	}); err != kv.ErrKeyNotFound {
+		t.Fatalf("got error %v reading deleted key, exp %v", err, kv.ErrKeyNotFound)
+	}
+
+	// Standbys reject writes until promoted.
+	if err := standby.PointsWriter(nil).WritePoints(ctx, nil); err != influxdb.ErrStandbyReadOnly {
+		t.Fatalf("got error %v writing to standby, exp %v", err, influxdb.ErrStandbyReadOnly)
+	}
+	if err := standby.PromoteStandby(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := standby.FindReplicationStatus(ctx); status.Role != influxdb.ReplicationRoleNone {
+		t.Fatalf("got role %q after promotion, exp %q", status.Role, influxdb.ReplicationRoleNone)
+	}
+
+	// Entries are no longer applied once promoted.
+	log.Replicate(entry.Type(), snappy.Encode(nil, b))
+	time.Sleep(50 * time.Millisecond)
+	if got, exp := eng.len(), 1; got != exp {
+		t.Fatalf("got %d WAL entries applied after promotion, exp %d", got, exp)
+	}
+}
+
+func waitFor(t *testing.T, fn func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !fn() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,176 @@
+package replication
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/storage"
+)
+
+var _ influxdb.ReplicationService = (*Service)(nil)
+
+// Service is the replication service of a primary streaming its log, of a
+// standby following a primary, or of an instance that does neither.
+type Service struct {
+	log        *Log
+	follower   *Follower
+	primaryURL string
+
+	mu       sync.RWMutex
+	promoted bool
+}
+
+// NewPrimaryService returns the replication service of a primary streaming
+// log to standbys.
+func NewPrimaryService(log *Log) *Service {
+	return &Service{log: log}
+}
+
+// NewStandbyService returns the replication service of a standby following the
+// primary at primaryURL with f.
+func NewStandbyService(f *Follower, primaryURL string) *Service {
+	return &Service{follower: f, primaryURL: primaryURL}
+}
+
+// NewService returns the replication service of an instance that is neither a
+// primary nor a standby.
+func NewService() *Service {
+	return &Service{}
+}
+
+// FindReplicationStatus returns the replication state of the instance.
+func (s *Service) FindReplicationStatus(ctx context.Context) (*influxdb.ReplicationStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	switch {
+	case s.log != nil:
+		return &influxdb.ReplicationStatus{
+			Role:     influxdb.ReplicationRolePrimary,
+			Position: s.log.Position(),
+			Standbys: s.log.Standbys(),
+		}, nil
+	case s.follower != nil:
+		status := s.follower.Status()
+		if s.isPromoted() {
+			// The last position applied is kept to tell how far the standby got.
+			return &influxdb.ReplicationStatus{
+				Role:     influxdb.ReplicationRoleNone,
+				Position: status.Position,
+			}, nil
+		}
+		status.PrimaryURL = s.primaryURL
+		return status, nil
+	default:
+		return &influxdb.ReplicationStatus{Role: influxdb.ReplicationRoleNone}, nil
+	}
+}
+
+// StreamReplication writes the entries of the log of a primary following pos
+// to w as they are appended, until ctx is done or writing fails.
+func (s *Service) StreamReplication(ctx context.Context, pos influxdb.ReplicationPosition, w io.Writer) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if s.log == nil {
+		return influxdb.ErrReplicationDisabled
+	}
+	return s.log.Stream(ctx, pos, w)
+}
+
+// PromoteStandby stops a standby from following its primary and makes it
+// accept writes. The promoted standby does not stream to other standbys until
+// it is restarted as a primary.
+func (s *Service) PromoteStandby(ctx context.Context) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if s.follower == nil {
+		return influxdb.ErrNotStandby
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promoted {
+		return influxdb.ErrNotStandby
+	}
+
+	if err := s.follower.Close(); err != nil {
+		return &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   influxdb.OpPromoteStandby,
+			Err:  err,
+		}
+	}
+	s.promoted = true
+	return nil
+}
+
+func (s *Service) isPromoted() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.promoted
+}
+
+// readOnly returns ErrStandbyReadOnly if the instance is a standby that has not
+// been promoted.
+func (s *Service) readOnly() error {
+	if s.follower != nil && !s.isPromoted() {
+		return influxdb.ErrStandbyReadOnly
+	}
+	return nil
+}
+
+// Close stops following the primary of a standby.
+func (s *Service) Close() error {
+	if s.follower == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.promoted {
+		return nil
+	}
+	return s.follower.Close()
+}
+
+// PointsWriter returns a points writer rejecting writes to a standby until it
+// is promoted.
+func (s *Service) PointsWriter(w storage.PointsWriter) storage.PointsWriter {
+	return &pointsWriter{s: s, w: w}
+}
+
+type pointsWriter struct {
+	s *Service
+	w storage.PointsWriter
+}
+
+func (w *pointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	if err := w.s.readOnly(); err != nil {
+		return err
+	}
+	return w.w.WritePoints(ctx, points)
+}
+
+// DeleteService returns a delete service rejecting deletes from a standby until
+// it is promoted.
+func (s *Service) DeleteService(d influxdb.DeleteService) influxdb.DeleteService {
+	return &deleteService{s: s, d: d}
+}
+
+type deleteService struct {
+	s *Service
+	d influxdb.DeleteService
+}
+
+func (d *deleteService) DeleteBucketRangePredicate(ctx context.Context, orgID, bucketID influxdb.ID, min, max int64, pred influxdb.Predicate) error {
+	if err := d.s.readOnly(); err != nil {
+		return err
+	}
+	return d.d.DeleteBucketRangePredicate(ctx, orgID, bucketID, min, max, pred)
+}
@@ -0,0 +1,194 @@
+package replication
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/influxdata/influxdb/kv"
+)
+
+// Operations of a change to the metadata store.
+const (
+	opPut    = 0x01
+	opDelete = 0x02
+)
+
+// change is a change committed to the metadata store.
+type change struct {
+	op     byte
+	bucket []byte
+	key    []byte
+	value  []byte
+}
+
+var errInvalidChanges = errors.New("invalid replicated metadata changes")
+
+// encodeChanges encodes the changes of a transaction as a sequence of an
+// operation followed by the length prefixed bucket, key and, for puts, value.
+func encodeChanges(changes []change) []byte {
+	var b []byte
+	var buf [binary.MaxVarintLen64]byte
+	putBytes := func(v []byte) {
+		n := binary.PutUvarint(buf[:], uint64(len(v)))
+		b = append(b, buf[:n]...)
+		b = append(b, v...)
+	}
+
+	for _, c := range changes {
+		b = append(b, c.op)
+		putBytes(c.bucket)
+		putBytes(c.key)
+		if c.op == opPut {
+			putBytes(c.value)
+		}
+	}
+	return b
+}
+
+// decodeChanges decodes changes encoded by encodeChanges.
+func decodeChanges(b []byte) ([]change, error) {
+	getBytes := func() ([]byte, bool) {
+		n, i := binary.Uvarint(b)
+		if i <= 0 || uint64(len(b)-i) < n {
+			return nil, false
+		}
+		v := b[i : i+int(n)]
+		b = b[i+int(n):]
+		return v, true
+	}
+
+	var changes []change
+	for len(b) > 0 {
+		c := change{op: b[0]}
+		b = b[1:]
+
+		var ok bool
+		if c.bucket, ok = getBytes(); !ok {
+			return nil, errInvalidChanges
+		}
+		if c.key, ok = getBytes(); !ok {
+			return nil, errInvalidChanges
+		}
+		switch c.op {
+		case opPut:
+			if c.value, ok = getBytes(); !ok {
+				return nil, errInvalidChanges
+			}
+		case opDelete:
+		default:
+			return nil, errInvalidChanges
+		}
+		changes = append(changes, c)
+	}
+	return changes, nil
+}
+
+// applyChanges commits changes replicated from a primary to store.
+func applyChanges(ctx context.Context, store kv.Store, changes []change) error {
+	return store.Update(ctx, func(tx kv.Tx) error {
+		for _, c := range changes {
+			b, err := tx.Bucket(c.bucket)
+			if err != nil {
+				return err
+			}
+			if c.op == opPut {
+				err = b.Put(c.key, c.value)
+			} else {
+				err = b.Delete(c.key)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+var _ kv.Store = (*Store)(nil)
+
+// Store is a kv.Store appending the changes committed by its update
+// transactions to a replication log. Restoring the store is not replicated.
+type Store struct {
+	kv.Store
+	log *Log
+
+	// mu serializes update transactions, so that changes are appended to the
+	// log in the order they are committed.
+	mu sync.Mutex
+}
+
+// NewStore returns a store appending the changes committed to s to log.
+func NewStore(s kv.Store, log *Log) *Store {
+	return &Store{Store: s, log: log}
+}
+
+// Update opens up a transaction that will mutate data, and appends the
+// changes to the log once committed.
+func (s *Store) Update(ctx context.Context, fn func(kv.Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []change
+	err := s.Store.Update(ctx, func(tx kv.Tx) error {
+		changes = changes[:0]
+		return fn(&recordingTx{Tx: tx, changes: &changes})
+	})
+	if err != nil || len(changes) == 0 {
+		return err
+	}
+
+	s.log.Append(EntryMetadata, encodeChanges(changes))
+	return nil
+}
+
+// recordingTx is a transaction recording the changes made to its buckets.
+type recordingTx struct {
+	kv.Tx
+	changes *[]change
+}
+
+// Bucket possibly creates and returns bucket, b.
+func (tx *recordingTx) Bucket(b []byte) (kv.Bucket, error) {
+	bkt, err := tx.Tx.Bucket(b)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingBucket{Bucket: bkt, name: b, changes: tx.changes}, nil
+}
+
+// recordingBucket is a bucket recording the changes made to it.
+type recordingBucket struct {
+	kv.Bucket
+	name    []byte
+	changes *[]change
+}
+
+// Put records the change once made.
+func (b *recordingBucket) Put(key, value []byte) error {
+	if err := b.Bucket.Put(key, value); err != nil {
+		return err
+	}
+	b.record(opPut, key, value)
+	return nil
+}
+
+// Delete records the change once made.
+func (b *recordingBucket) Delete(key []byte) error {
+	if err := b.Bucket.Delete(key); err != nil {
+		return err
+	}
+	b.record(opDelete, key, nil)
+	return nil
+}
+
+func (b *recordingBucket) record(op byte, key, value []byte) {
+	// The caller may reuse the slices once the change is made.
+	*b.changes = append(*b.changes, change{
+		op:     op,
+		bucket: append([]byte(nil), b.name...),
+		key:    append([]byte(nil), key...),
+		value:  append([]byte(nil), value...),
+	})
+}
@@ -0,0 +1,73 @@
+package influxdb
+
+import (
+	"fmt"
+	"time"
+)
+
+// RollupFunction is an aggregate computed by a rollup policy.
+type RollupFunction string
+
+// Rollup functions.
+const (
+	RollupMean  RollupFunction = "mean"
+	RollupMin   RollupFunction = "min"
+	RollupMax   RollupFunction = "max"
+	RollupSum   RollupFunction = "sum"
+	RollupCount RollupFunction = "count"
+)
+
+// Valid returns an error if the function is unknown.
+func (f RollupFunction) Valid() error {
+	switch f {
+	case RollupMean, RollupMin, RollupMax, RollupSum, RollupCount:
+		return nil
+	}
+	return &Error{
+		Code: EInvalid,
+		Msg:  fmt.Sprintf("unknown rollup function %q", f),
+	}
+}
+
+// RollupPolicy describes data downsampled by the storage engine from a bucket
+// into another bucket of the same organization.
+//
+// Every field of the source bucket is aggregated over windows of the given
+// duration. The result of each function is written to the target bucket at
+// the start of its window, with the tags of the source series and a field
+// named after the source field and the function, e.g. usage_mean. Functions
+// that do not apply to the type of a field, such as mean for strings, are
+// skipped for it.
+type RollupPolicy struct {
+	Every          time.Duration    `json:"every"`
+	Functions      []RollupFunction `json:"functions"`
+	TargetBucketID ID               `json:"targetBucketID"`
+}
+
+// Valid returns an error if the policy is invalid.
+func (p RollupPolicy) Valid() error {
+	if p.Every <= 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "rollup window must be positive",
+		}
+	}
+	if len(p.Functions) == 0 {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "rollup policy requires at least one function",
+		}
+	}
+	for _, fn := range p.Functions {
+		if err := fn.Valid(); err != nil {
+			return err
+		}
+	}
+	if !p.TargetBucketID.Valid() {
+		return &Error{
+			Code: EInvalid,
+			Msg:  "rollup policy requires a valid target bucket ID",
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package influxdb
+
+import "context"
+
+// Ops for series garbage collection errors.
+const (
+	OpCollectSeries = "CollectSeries"
+)
+
+// SeriesGC describes a run of the series garbage collector of the storage
+// engine.
+type SeriesGC struct {
+	// SeriesRemoved is the number of series without any remaining data that
+	// were removed from the index and the series file.
+	SeriesRemoved int64 `json:"seriesRemoved"`
+	// SegmentsRemoved is the number of series file segments removed after
+	// compacting the series file.
+	SegmentsRemoved int64 `json:"segmentsRemoved"`
+}
+
+// SeriesGCService lets operators remove the series left behind by deleted
+// data on demand, rather than waiting for the next periodic run.
+type SeriesGCService interface {
+	// CollectSeries removes the series without any data in the storage engine
+	// from the index and the series file, and compacts the series file.
+	CollectSeries(ctx context.Context) (*SeriesGC, error)
+}
@@ -17,7 +17,8 @@ type BucketDeleter interface {
 //
 // BucketService ensures that when a bucket is deleted, all stored data
 // associated with the bucket is either removed, or marked to be removed via a
-// future compaction.
+// future compaction. If the engine is a BucketCodecsSetter, the codecs of
+// created and updated buckets are also applied to the engine.
 type BucketService struct {
 	inner  platform.BucketService
 	engine BucketDeleter
@@ -85,7 +86,20 @@ func (s *BucketService) CreateBucket(ctx context.Context, b *platform.Bucket) er
 	if s.inner == nil || s.engine == nil {
 		return errors.New("nil inner BucketService or Engine")
 	}
-	return s.inner.CreateBucket(ctx, b)
+
+	setter, ok := s.engine.(BucketCodecsSetter)
+	if ok {
+		if _, err := blockCodecs(b.Codecs); err != nil {
+			return err
+		}
+	}
+	if err := s.inner.CreateBucket(ctx, b); err != nil {
+		return err
+	}
+	if ok && len(b.Codecs) > 0 {
+		return setter.SetBucketCodecs(ctx, b.OrgID, b.ID, b.Codecs)
+	}
+	return nil
 }
 
 // UpdateBucket updates a single bucket with changeset.
@@ -97,7 +111,23 @@ func (s *BucketService) UpdateBucket(ctx context.Context, id platform.ID, upd pl
 	if s.inner == nil || s.engine == nil {
 		return nil, errors.New("nil inner BucketService or Engine")
 	}
-	return s.inner.UpdateBucket(ctx, id, upd)
+
+	setter, ok := s.engine.(BucketCodecsSetter)
+	if ok && upd.Codecs != nil {
+		if _, err := blockCodecs(*upd.Codecs); err != nil {
+			return nil, err
+		}
+	}
+	b, err := s.inner.UpdateBucket(ctx, id, upd)
+	if err != nil {
+		return nil, err
+	}
+	if ok && upd.Codecs != nil {
+		if err := setter.SetBucketCodecs(ctx, b.OrgID, b.ID, b.Codecs); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
 }
 
 // DeleteBucket removes a bucket by ID.
@@ -2,6 +2,7 @@ package storage_test
 
 import (
 	"context"
+	"reflect"
 	"testing"
 
 	platform "github.com/influxdata/influxdb"
@@ -55,6 +56,59 @@ func TestBucketService(t *testing.T) {
 	}
 }
 
+func TestBucketService_Codecs(t *testing.T) {
+	ctx := context.Background()
+	inmemService := newInMemKVSVC(t)
+	engine := &MockCodecsSetter{}
+	service := storage.NewBucketService(inmemService, engine)
+
+	org := &platform.Organization{Name: "org1"}
+	if err := inmemService.CreateOrganization(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+
+	// Buckets with unknown codecs are not created.
+	bucket := &platform.Bucket{OrgID: org.ID, Name: "unknown", Codecs: platform.BlockCodecs{platform.SchemaFieldString: "unknown"}}
+	if err := service.CreateBucket(ctx, bucket); platform.ErrorCode(err) != platform.EInvalid {
+		t.Fatalf("got error %v, expected invalid", err)
+	}
+	if _, err := inmemService.FindBucketByName(ctx, org.ID, bucket.Name); platform.ErrorCode(err) != platform.ENotFound {
+		t.Fatalf("got error %v, expected not found", err)
+	}
+
+	// Codecs of float values only are not accepted for other values.
+	bucket = &platform.Bucket{OrgID: org.ID, Name: "mismatch", Codecs: platform.BlockCodecs{platform.SchemaFieldString: "float-shuffle"}}
+	if err := service.CreateBucket(ctx, bucket); platform.ErrorCode(err) != platform.EInvalid {
+		t.Fatalf("got error %v, expected invalid", err)
+	}
+
+	bucket = &platform.Bucket{OrgID: org.ID, Name: "archive", Codecs: platform.BlockCodecs{platform.SchemaFieldFloat: "float-shuffle", platform.SchemaFieldString: "flate"}}
+	if err := service.CreateBucket(ctx, bucket); err != nil {
+		t.Fatal(err)
+	}
+	if engine.bucketID != bucket.ID || !reflect.DeepEqual(engine.codecs, bucket.Codecs) {
+		t.Fatalf("unexpected codecs set for bucket %s: %v", engine.bucketID, engine.codecs)
+	}
+
+	codecs := platform.BlockCodecs{}
+	if _, err := service.UpdateBucket(ctx, bucket.ID, platform.BucketUpdate{Codecs: &codecs}); err != nil {
+		t.Fatal(err)
+	}
+	if len(engine.codecs) != 0 {
+		t.Fatalf("unexpected codecs: %v", engine.codecs)
+	}
+}
+
+type MockCodecsSetter struct {
+	MockDeleter
+	codecs platform.BlockCodecs
+}
+
+func (m *MockCodecsSetter) SetBucketCodecs(_ context.Context, orgID, bucketID platform.ID, codecs platform.BlockCodecs) error {
+	m.orgID, m.bucketID, m.codecs = orgID, bucketID, codecs
+	return nil
+}
+
 type MockDeleter struct {
 	orgID, bucketID platform.ID
 }
@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// FindCardinality returns the series cardinality of the buckets matching
+// filter, as counted by the index. The buckets of all organizations are
+// returned if filter has no organization.
+func (e *Engine) FindCardinality(ctx context.Context, filter influxdb.CardinalityFilter) ([]*influxdb.BucketCardinality, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	if filter.BucketID != nil {
+		if !filter.OrgID.Valid() {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  "the organization of the bucket is required",
+			}
+		}
+
+		topValues := filter.TopValues
+		if topValues <= 0 {
+			topValues = influxdb.DefaultCardinalityTopValues
+		}
+
+		b, err := e.bucketCardinality(filter.OrgID, *filter.BucketID, filter.Measurement, topValues)
+		if err != nil {
+			return nil, err
+		}
+		return []*influxdb.BucketCardinality{b}, nil
+	}
+
+	stats, err := e.index.MeasurementCardinalityStats()
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets []*influxdb.BucketCardinality
+	for name, n := range stats {
+		if len(name) != influxdb.IDLength {
+			continue
+		}
+		var key [influxdb.IDLength]byte
+		copy(key[:], name)
+		orgID, bucketID := tsdb.DecodeName(key)
+		if filter.OrgID.Valid() && orgID != filter.OrgID {
+			continue
+		}
+
+		buckets = append(buckets, &influxdb.BucketCardinality{
+			OrgID:    orgID,
+			BucketID: bucketID,
+			Series:   int64(n),
+		})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		if buckets[i].Series != buckets[j].Series {
+			return buckets[i].Series > buckets[j].Series
+		}
+		return buckets[i].BucketID < buckets[j].BucketID
+	})
+	return buckets, nil
+}
+
+// bucketCardinality returns the cardinality of a bucket, and of its
+// measurements and their tag keys. If measurement is not nil, only that
+// measurement of the bucket is included.
+func (e *Engine) bucketCardinality(orgID, bucketID influxdb.ID, measurement *string, topValues int) (*influxdb.BucketCardinality, error) {
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := encoded[:]
+
+	itr, err := e.index.MeasurementSeriesIDIterator(name)
+	if err != nil {
+		return nil, err
+	}
+	all, err := seriesIDSet(itr)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &influxdb.BucketCardinality{
+		OrgID:    orgID,
+		BucketID: bucketID,
+		Series:   int64(all.Cardinality()),
+	}
+	if b.Series == 0 {
+		return b, nil
+	}
+
+	// The series of each measurement of the bucket.
+	var (
+		measurements []*influxdb.MeasurementCardinality
+		sets         []*tsdb.SeriesIDSet
+	)
+	err = e.forEachTagValue(name, models.MeasurementTagKeyBytes, func(value []byte, set *tsdb.SeriesIDSet) {
+		if measurement != nil && string(value) != *measurement {
+			return
+		}
+		measurements = append(measurements, &influxdb.MeasurementCardinality{
+			Name:    string(value),
+			Series:  int64(set.Cardinality()),
+			TagKeys: []*influxdb.TagKeyCardinality{},
+		})
+		sets = append(sets, set)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The series of each tag value of each measurement.
+	kitr, err := e.index.TagKeyIterator(name)
+	if err != nil {
+		return nil, err
+	} else if kitr != nil {
+		defer kitr.Close()
+		for {
+			key, err := kitr.Next()
+			if err != nil {
+				return nil, err
+			} else if key == nil {
+				break
+			} else if bytes.Equal(key, models.MeasurementTagKeyBytes) {
+				continue
+			}
+
+			keys := make([]*influxdb.TagKeyCardinality, len(measurements))
+			err = e.forEachTagValue(name, key, func(value []byte, set *tsdb.SeriesIDSet) {
+				for i, mset := range sets {
+					n := int64(set.And(mset).Cardinality())
+					if n == 0 {
+						continue
+					}
+
+					if keys[i] == nil {
+						keys[i] = &influxdb.TagKeyCardinality{Key: cardinalityTagKey(key)}
+					}
+					keys[i].Values++
+					keys[i].TopValues = addTopValue(keys[i].TopValues, influxdb.TagValueCardinality{
+						Value:  string(value),
+						Series: n,
+					}, topValues)
+				}
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for i, k := range keys {
+				if k != nil {
+					measurements[i].TagKeys = append(measurements[i].TagKeys, k)
+				}
+			}
+		}
+	}
+
+	for _, m := range measurements {
+		sort.SliceStable(m.TagKeys, func(i, j int) bool {
+			return m.TagKeys[i].Values > m.TagKeys[j].Values
+		})
+	}
+	sort.SliceStable(measurements, func(i, j int) bool {
+		return measurements[i].Series > measurements[j].Series
+	})
+	b.Measurements = measurements
+	return b, nil
+}
+
+// forEachTagValue calls fn with each value of the tag key of the bucket name
+// and the set of series with that value.
+func (e *Engine) forEachTagValue(name, key []byte, fn func(value []byte, set *tsdb.SeriesIDSet)) error {
+	vitr, err := e.index.TagValueIterator(name, key)
+	if err != nil {
+		return err
+	} else if vitr == nil {
+		return nil
+	}
+	defer vitr.Close()
+
+	for {
+		value, err := vitr.Next()
+		if err != nil {
+			return err
+		} else if value == nil {
+			return nil
+		}
+
+		itr, err := e.index.TagValueSeriesIDIterator(name, key, value)
+		if err != nil {
+			return err
+		}
+		set, err := seriesIDSet(itr)
+		if err != nil {
+			return err
+		}
+		if set.Cardinality() > 0 {
+			fn(value, set)
+		}
+	}
+}
+
+// seriesIDSet returns the set of the series of itr and closes it.
+func seriesIDSet(itr tsdb.SeriesIDIterator) (*tsdb.SeriesIDSet, error) {
+	if itr == nil {
+		return tsdb.NewSeriesIDSet(), nil
+	}
+	defer itr.Close()
+
+	if sitr, ok := itr.(tsdb.SeriesIDSetIterator); ok {
+		return sitr.SeriesIDSet(), nil
+	}
+
+	set := tsdb.NewSeriesIDSet()
+	for {
+		elem, err := itr.Next()
+		if err != nil {
+			return nil, err
+		} else if elem.SeriesID.IsZero() {
+			return set, nil
+		}
+		set.Add(elem.SeriesID)
+	}
+}
+
+// cardinalityTagKey returns the name of a tag key as used by queries.
+func cardinalityTagKey(key []byte) string {
+	if bytes.Equal(key, models.FieldKeyTagKeyBytes) {
+		return "_field"
+	}
+	return string(key)
+}
+
+// addTopValue adds v to values, the at most n tag values with the most series
+// in descending order.
+func addTopValue(values []influxdb.TagValueCardinality, v influxdb.TagValueCardinality, n int) []influxdb.TagValueCardinality {
+	if len(values) == n && values[n-1].Series >= v.Series {
+		return values
+	}
+
+	i := sort.Search(len(values), func(i int) bool { return values[i].Series < v.Series })
+	if len(values) < n {
+		values = append(values, influxdb.TagValueCardinality{})
+	}
+	copy(values[i+1:], values[i:])
+	values[i] = v
+	return values
+}
@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"go.uber.org/zap"
+)
+
+// BucketCodecsSetter defines the behaviour of setting the codecs compressing
+// the stored values of a bucket.
+type BucketCodecsSetter interface {
+	SetBucketCodecs(ctx context.Context, orgID, bucketID influxdb.ID, codecs influxdb.BlockCodecs) error
+}
+
+// blockCodecs returns the IDs of the codecs of the blocks by block type, or an
+// error if a field type or codec is unknown.
+func blockCodecs(codecs influxdb.BlockCodecs) (tsm1.BlockCodecs, error) {
+	var ids tsm1.BlockCodecs
+	if err := codecs.Valid(); err != nil {
+		return ids, err
+	}
+
+	for typ, name := range codecs {
+		id, ok := tsm1.CodecByName(name)
+		if !ok {
+			return ids, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("unknown codec %q, expected one of %s", name, strings.Join(tsm1.CodecNames(), ", ")),
+			}
+		}
+
+		var blockType byte
+		switch typ {
+		case influxdb.SchemaFieldFloat:
+			blockType = tsm1.BlockFloat64
+		case influxdb.SchemaFieldInteger:
+			blockType = tsm1.BlockInteger
+		case influxdb.SchemaFieldUnsigned:
+			blockType = tsm1.BlockUnsigned
+		case influxdb.SchemaFieldString:
+			blockType = tsm1.BlockString
+		case influxdb.SchemaFieldBoolean:
+			blockType = tsm1.BlockBoolean
+		}
+		if !tsm1.CodecSupports(id, blockType) {
+			return ids, &influxdb.Error{
+				Code: influxdb.EInvalid,
+				Msg:  fmt.Sprintf("codec %q does not compress %s values", name, typ),
+			}
+		}
+		ids[blockType] = id
+	}
+	return ids, nil
+}
+
+// SetBucketCodecs sets the codecs compressing the values of the bucket when
+// written to TSM files. Values already written are recompressed when their
+// files are next compacted.
+func (e *Engine) SetBucketCodecs(ctx context.Context, orgID, bucketID influxdb.ID, codecs influxdb.BlockCodecs) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	ids, err := blockCodecs(codecs)
+	if err != nil {
+		return err
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	e.engine.SetBlockCodecs(models.EscapeMeasurement(encoded[:]), ids)
+	return nil
+}
+
+// loadBucketCodecs sets the codecs of all buckets provided by finder.
+func (e *Engine) loadBucketCodecs(ctx context.Context, finder BucketFinder) error {
+	buckets, _, err := finder.FindBuckets(ctx, influxdb.BucketFilter{})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range buckets {
+		if len(b.Codecs) == 0 {
+			continue
+		}
+		// Buckets with unknown codecs, e.g. of codecs no longer registered,
+		// use the default codecs rather than failing to open the engine.
+		if err := e.SetBucketCodecs(ctx, b.OrgID, b.ID, b.Codecs); err != nil {
+			e.logger.Warn("Unable to set bucket codecs", zap.String("bucket_id", b.ID.String()), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+var _ influxdb.CompactionService = (*Engine)(nil)
+
+// newCompaction returns the compaction described by info.
+func newCompaction(info tsm1.CompactionInfo) *influxdb.Compaction {
+	c := &influxdb.Compaction{
+		ID:       info.ID,
+		Level:    info.Level,
+		Files:    info.Files,
+		Size:     info.Size,
+		Running:  info.Running,
+		Progress: info.Progress,
+	}
+	if info.Running {
+		started := info.Started.UTC()
+		c.StartedAt = &started
+	}
+	return c
+}
+
+// FindCompactions returns the running compactions of the engine, followed by
+// the planned compactions.
+func (e *Engine) FindCompactions(ctx context.Context) (*influxdb.CompactionStatus, error) {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	infos := e.engine.Compactions()
+	status := &influxdb.CompactionStatus{
+		Paused:      e.engine.CompactionsPaused(),
+		Compactions: make([]*influxdb.Compaction, 0, len(infos)),
+	}
+	for _, info := range infos {
+		status.Compactions = append(status.Compactions, newCompaction(info))
+	}
+	return status, nil
+}
+
+// CompactBucket schedules compactions of the TSM files holding data of the
+// bucket, one per partition, after writing the cache to TSM files. The
+// compactions start when compactions are not paused.
+func (e *Engine) CompactBucket(ctx context.Context, orgID, bucketID influxdb.ID, optimize bool) ([]*influxdb.Compaction, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	// The lock is not held while scheduling the compactions, as the cache is
+	// snapshotted under it.
+	e.mu.RLock()
+	closed := e.closing == nil
+	e.mu.RUnlock()
+	if closed {
+		return nil, ErrEngineClosed
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := models.EscapeMeasurement(encoded[:])
+
+	infos, err := e.engine.CompactPrefix(ctx, name, optimize)
+	if err != nil {
+		return nil, &influxdb.Error{
+			Code: influxdb.EInternal,
+			Op:   influxdb.OpCompactBucket,
+			Msg:  fmt.Sprintf("unable to schedule compaction of bucket %s", bucketID),
+			Err:  err,
+		}
+	}
+
+	compactions := make([]*influxdb.Compaction, 0, len(infos))
+	for _, info := range infos {
+		compactions = append(compactions, newCompaction(info))
+	}
+	return compactions, nil
+}
+
+// SetCompactionsPaused pauses or resumes the compactions of the engine.
+// Snapshots of the cache continue while compactions are paused.
+func (e *Engine) SetCompactionsPaused(ctx context.Context, paused bool) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	e.engine.SetCompactionsPaused(paused)
+	return nil
+}
+
+// CancelCompaction aborts the running compaction with the given ID. The
+// compaction may be planned again unless compactions are paused.
+func (e *Engine) CancelCompaction(ctx context.Context, id int) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	if err := e.engine.CancelCompaction(id); err == tsm1.ErrCompactionNotFound {
+		return influxdb.ErrCompactionNotFound
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
@@ -13,6 +13,9 @@ import (
 // Default configuration values.
 const (
 	DefaultRetentionInterval       = time.Hour
+	DefaultSeriesGCInterval        = 24 * time.Hour
+	DefaultScrubInterval           = 24 * time.Hour
+	DefaultScrubRate               = 8 * 1024 * 1024 // 8MB/s
 	DefaultSeriesFileDirectoryName = "_series"
 	DefaultIndexDirectoryName      = "index"
 	DefaultWALDirectoryName        = "wal"
@@ -24,6 +27,16 @@ type Config struct {
 	// Frequency of retention in seconds.
 	RetentionInterval toml.Duration `toml:"retention-interval"`
 
+	// Frequency at which series without any remaining data are removed.
+	SeriesGCInterval toml.Duration `toml:"series-gc-interval"`
+
+	// Frequency at which TSM and series files are read again to verify their
+	// integrity, and the maximum number of bytes read per second. Corrupt TSM
+	// files are removed from use if ScrubQuarantine is set.
+	ScrubInterval   toml.Duration `toml:"scrub-interval"`
+	ScrubRate       toml.Size     `toml:"scrub-rate"`
+	ScrubQuarantine bool          `toml:"scrub-quarantine"`
+
 	// Series file config.
 	SeriesFilePath string `toml:"series-file-path"` // Overrides the default path.
 
@@ -47,6 +60,9 @@ type Config struct {
 func NewConfig() Config {
 	return Config{
 		RetentionInterval: toml.Duration(DefaultRetentionInterval),
+		SeriesGCInterval:  toml.Duration(DefaultSeriesGCInterval),
+		ScrubInterval:     toml.Duration(DefaultScrubInterval),
+		ScrubRate:         toml.Size(DefaultScrubRate),
 		TSDB:              tsdb.NewConfig(),
 		WAL:               tsm1.NewWALConfig(),
 		Engine:            tsm1.NewConfig(),
@@ -3,12 +3,16 @@ package storage
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
@@ -61,6 +65,18 @@ type Engine struct {
 	retentionEnforcer        runner
 	retentionEnforcerLimiter runnable
 
+	rollups *rollupService
+
+	codecsFinder BucketFinder // Provides the codecs of the buckets on open.
+
+	seriesLimits *seriesLimiter
+
+	seriesGC *seriesGC
+
+	scrubber *scrubber
+
+	lastValues *lastValueCache
+
 	defaultMetricLabels prometheus.Labels
 
 	// Tracks all goroutines started by the Engine.
@@ -124,6 +140,41 @@ func WithRetentionEnforcerLimiter(f runnable) Option {
 	}
 }
 
+// WithRollups makes the engine maintain the rollup policies of the buckets
+// provided by finder.
+func WithRollups(finder BucketFinder) Option {
+	return func(e *Engine) {
+		e.rollups = newRollupService(e, finder)
+	}
+}
+
+// WithBucketCodecs makes the engine compress the data of the buckets provided
+// by finder with their codecs. Changes to the codecs of buckets are applied
+// with SetBucketCodecs.
+func WithBucketCodecs(finder BucketFinder) Option {
+	return func(e *Engine) {
+		e.codecsFinder = finder
+	}
+}
+
+// WithSeriesLimits makes the engine reject points creating series beyond the
+// series limits of the buckets and organizations provided by the finders.
+// Changes to the limits are picked up periodically.
+func WithSeriesLimits(buckets BucketFinder, orgs OrganizationFinder) Option {
+	return func(e *Engine) {
+		e.seriesLimits = newSeriesLimiter(buckets, orgs)
+	}
+}
+
+// WithLastValueCache makes the engine keep the last value of each series of
+// the buckets with a last-value cache provided by finder in memory. Changes to
+// the buckets are picked up periodically.
+func WithLastValueCache(finder BucketFinder) Option {
+	return func(e *Engine) {
+		e.lastValues = newLastValueCache(finder)
+	}
+}
+
 // WithFileStoreObserver makes the engine have the provided file store observer.
 func WithFileStoreObserver(obs tsm1.FileStoreObserver) Option {
 	return func(e *Engine) {
@@ -155,6 +206,14 @@ func WithCompactionSemaphore(s influxdb.Semaphore) Option {
 	}
 }
 
+// WithWALReplicator makes the engine pass the entries written to its WAL to r,
+// so that they can be applied to a standby engine with ApplyWALEntry.
+func WithWALReplicator(r wal.Replicator) Option {
+	return func(e *Engine) {
+		e.wal.WithReplicator(r)
+	}
+}
+
 // NewEngine initialises a new storage engine, including a series file, index and
 // TSM engine.
 func NewEngine(path string, c Config, options ...Option) *Engine {
@@ -176,10 +235,13 @@ func NewEngine(path string, c Config, options ...Option) *Engine {
 	// Initialize WAL
 	e.wal = wal.NewWAL(c.GetWALPath(path))
 	e.wal.WithFsyncDelay(time.Duration(c.WAL.FsyncDelay))
+	e.wal.WithArchivePath(c.WAL.ArchivePath)
 	e.wal.SetEnabled(c.WAL.Enabled)
 
 	// Initialise Engine
 	e.engine = tsm1.NewEngine(c.GetEnginePath(path), e.index, c.Engine, tsm1.WithSnapshotter(e))
+	e.seriesGC = newSeriesGC(e)
+	e.scrubber = newScrubber(e)
 
 	// Apply options.
 	for _, option := range options {
@@ -194,6 +256,9 @@ func NewEngine(path string, c Config, options ...Option) *Engine {
 	if r, ok := e.retentionEnforcer.(*retentionEnforcer); ok {
 		r.SetDefaultMetricLabels(e.defaultMetricLabels)
 	}
+	e.seriesLimits.SetDefaultMetricLabels(e.defaultMetricLabels)
+	e.seriesGC.SetDefaultMetricLabels(e.defaultMetricLabels)
+	e.scrubber.SetDefaultMetricLabels(e.defaultMetricLabels)
 
 	return e
 }
@@ -218,6 +283,11 @@ func (e *Engine) WithLogger(log *zap.Logger) {
 	if r, ok := e.retentionEnforcer.(*retentionEnforcer); ok {
 		r.WithLogger(e.logger)
 	}
+	e.rollups.WithLogger(e.logger)
+	e.seriesLimits.WithLogger(e.logger)
+	e.seriesGC.WithLogger(e.logger)
+	e.scrubber.WithLogger(e.logger)
+	e.lastValues.WithLogger(e.logger)
 }
 
 // PrometheusCollectors returns all the prometheus collectors associated with
@@ -229,6 +299,9 @@ func (e *Engine) PrometheusCollectors() []prometheus.Collector {
 	metrics = append(metrics, tsm1.PrometheusCollectors()...)
 	metrics = append(metrics, wal.PrometheusCollectors()...)
 	metrics = append(metrics, RetentionPrometheusCollectors()...)
+	metrics = append(metrics, SeriesLimitPrometheusCollectors()...)
+	metrics = append(metrics, SeriesGCPrometheusCollectors()...)
+	metrics = append(metrics, ScrubPrometheusCollectors()...)
 	return metrics
 }
 
@@ -245,6 +318,27 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
+	// The codecs are set before any data is written to TSM files.
+	if e.codecsFinder != nil {
+		if err := e.loadBucketCodecs(ctx, e.codecsFinder); err != nil {
+			return err
+		}
+	}
+
+	if e.seriesLimits != nil {
+		if err := e.seriesLimits.load(ctx); err != nil {
+			return err
+		}
+	}
+
+	// The buckets are loaded before the WAL is replayed, so that the values
+	// replayed are added to the cache.
+	if e.lastValues != nil {
+		if err := e.lastValues.load(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Open the services in order and clean up if any fail.
 	var oh openHelper
 	oh.Open(ctx, e.sfile)
@@ -268,6 +362,21 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 		e.runRetentionEnforcer()
 	}
 
+	if e.rollups != nil {
+		e.runRollups()
+	}
+
+	if e.seriesLimits != nil {
+		e.runSeriesLimits()
+	}
+
+	if e.lastValues != nil {
+		e.runLastValueCache()
+	}
+
+	e.runSeriesGC()
+	e.runScrubber()
+
 	return nil
 }
 
@@ -298,7 +407,7 @@ func (e *Engine) replayWAL() error {
 		switch en := entry.(type) {
 		case *wal.WriteWALEntry:
 			points := tsm1.ValuesToPoints(en.Values)
-			err := e.writePointsLocked(context.Background(), tsdb.NewSeriesCollection(points), en.Values)
+			err := e.writePointsLocked(context.Background(), tsdb.NewSeriesCollection(points), en.Values, nil)
 			if _, ok := err.(tsdb.PartialWriteError); ok {
 				err = nil
 			}
@@ -393,6 +502,32 @@ func (e *Engine) runRetentionEnforcer() {
 	}()
 }
 
+// runRollups recomputes rollups in a separate goroutine whenever the cache has
+// been snapshotted.
+func (e *Engine) runRollups() {
+	ctx, cancel := context.WithCancel(context.Background())
+	closing := e.closing
+
+	// Rollups in progress are cancelled when the engine is closed.
+	go func() {
+		<-closing
+		cancel()
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		for {
+			select {
+			case <-closing:
+				return
+			case <-e.rollups.trigger:
+				e.rollups.run(ctx)
+			}
+		}
+	}()
+}
+
 // Close closes the store and all underlying resources. It returns an error if
 // any of the underlying systems fail to close.
 func (e *Engine) Close() error {
@@ -456,14 +591,18 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 
 	collection, j := tsdb.NewSeriesCollection(points), 0
 
+	// The reasons points are dropped for, reported by the partial write error.
+	var drops []tsdb.Drop
+
 	// dropPoint should be called whenever there is reason to drop a point from
 	// the batch.
-	dropPoint := func(key []byte, reason string) {
+	dropPoint := func(iter tsdb.SeriesCollectionIterator, reason string) {
 		if collection.Reason == "" {
 			collection.Reason = reason
 		}
 		collection.Dropped++
-		collection.DroppedKeys = append(collection.DroppedKeys, key)
+		collection.DroppedKeys = append(collection.DroppedKeys, iter.Key())
+		drops = append(drops, tsdb.Drop{Point: iter.Point(), Code: influxdb.WriteRejectedInvalid, Reason: reason})
 	}
 
 	for iter := collection.Iterator(); iter.Next(); {
@@ -471,13 +610,13 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 
 		// Not enough tags present.
 		if tags.Len() < 2 {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required tags: parsed tags: %q", tags))
+			dropPoint(iter, fmt.Sprintf("missing required tags: parsed tags: %q", tags))
 			continue
 		}
 
 		// First tag key is not measurement tag.
 		if !bytes.Equal(tags[0].Key, models.MeasurementTagKeyBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required measurement tag as first tag, got: %q", tags[0].Key))
+			dropPoint(iter, fmt.Sprintf("missing required measurement tag as first tag, got: %q", tags[0].Key))
 			continue
 		}
 
@@ -485,26 +624,26 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 
 		// Last tag key is not field tag.
 		if !bytes.Equal(fkey, models.FieldKeyTagKeyBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("missing required field key tag as last tag, got: %q", tags[0].Key))
+			dropPoint(iter, fmt.Sprintf("missing required field key tag as last tag, got: %q", tags[0].Key))
 			continue
 		}
 
 		// The value representing the underlying field key is invalid if it's "time".
 		if bytes.Equal(fval, timeBytes) {
-			dropPoint(iter.Key(), fmt.Sprintf("invalid field key: input field %q is invalid", timeBytes))
+			dropPoint(iter, fmt.Sprintf("invalid field key: input field %q is invalid", timeBytes))
 			continue
 		}
 
 		// Filter out any tags with key equal to "time": they are invalid.
 		if tags.Get(timeBytes) != nil {
-			dropPoint(iter.Key(), fmt.Sprintf("invalid tag key: input tag %q on measurement %q is invalid", timeBytes, iter.Name()))
+			dropPoint(iter, fmt.Sprintf("invalid tag key: input tag %q on measurement %q is invalid", timeBytes, iter.Name()))
 			continue
 		}
 
 		// Drop any point with invalid unicode characters in any of the tag keys or values.
 		// This will also cover validating the value used to represent the field key.
 		if !models.ValidTagTokens(tags) {
-			dropPoint(iter.Key(), fmt.Sprintf("key contains invalid unicode: %q", iter.Key()))
+			dropPoint(iter, fmt.Sprintf("key contains invalid unicode: %q", iter.Key()))
 			continue
 		}
 
@@ -520,9 +659,19 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 		return ErrEngineClosed
 	}
 
+	// Drop the points that would create series beyond the series limits.
+	if err := recordDrops(collection, &drops, influxdb.WriteRejectedCardinalityLimit, nil, func() error {
+		return e.seriesLimits.limit(collection, e.sfile, e.index)
+	}); err != nil {
+		return err
+	}
+
 	// Convert the collection to values for adding to the WAL/Cache.
-	values, err := tsm1.CollectionToValues(collection)
-	if err != nil {
+	var values map[string][]value.Value
+	if err := recordDrops(collection, &drops, influxdb.WriteRejectedTypeConflict, typeConflictReason, func() (err error) {
+		values, err = tsm1.CollectionToValues(collection)
+		return err
+	}); err != nil {
 		return err
 	}
 
@@ -531,11 +680,73 @@ func (e *Engine) WritePoints(ctx context.Context, points []models.Point) error {
 		return err
 	}
 
-	return e.writePointsLocked(ctx, collection, values)
+	err := e.writePointsLocked(ctx, collection, values, &drops)
+	if pwe, ok := err.(tsdb.PartialWriteError); ok {
+		pwe.Drops = drops
+		return pwe
+	}
+	return err
+}
+
+// recordDrops calls fn and records the points it dropped from collection to
+// drops, with the code and the reason of each point. Without a function for
+// the reasons, the points are dropped for the first reason given by fn.
+func recordDrops(collection *tsdb.SeriesCollection, drops *[]tsdb.Drop, code string, reasonFn func(models.Point) string, fn func() error) error {
+	if drops == nil {
+		return fn()
+	}
+
+	points := append([]models.Point(nil), collection.Points...)
+	dropped, reason := collection.Dropped, collection.Reason
+	collection.Reason = ""
+	err := fn()
+
+	if collection.Dropped > dropped {
+		kept := make(map[models.Point]struct{}, len(collection.Points))
+		for _, pt := range collection.Points {
+			kept[pt] = struct{}{}
+		}
+		for _, pt := range points {
+			if _, ok := kept[pt]; ok {
+				continue
+			}
+			d := tsdb.Drop{Point: pt, Code: code, Reason: collection.Reason}
+			if reasonFn != nil {
+				d.Reason = reasonFn(pt)
+			}
+			*drops = append(*drops, d)
+		}
+	}
+
+	if reason != "" {
+		collection.Reason = reason
+	}
+	return err
+}
+
+// typeConflictReason returns the reason a point is dropped for when its field
+// has values of another type.
+func typeConflictReason(pt models.Point) string {
+	var typ models.FieldType
+	if iter := pt.FieldIterator(); iter.Next() {
+		typ = iter.Type()
+	}
+
+	tags := pt.Tags()
+	name, field := tags.Get(models.MeasurementTagKeyBytes), tags.Get(models.FieldKeyTagKeyBytes)
+	series := make(models.Tags, 0, len(tags))
+	for _, t := range tags {
+		if !bytes.Equal(t.Key, models.MeasurementTagKeyBytes) && !bytes.Equal(t.Key, models.FieldKeyTagKeyBytes) {
+			series = append(series, t)
+		}
+	}
+	return fmt.Sprintf("field type conflict: field %q of series %q has values of another type than %s",
+		field, models.MakeKey(name, series), strings.ToLower(typ.String()))
 }
 
 // writePointsLocked does the work of writing points and must be called under some sort of lock.
-func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesCollection, values map[string][]value.Value) error {
+// The reasons points are dropped for are recorded to drops if it is not nil.
+func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesCollection, values map[string][]value.Value, drops *[]tsdb.Drop) error {
 	span, _ := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
@@ -544,7 +755,9 @@ func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesC
 	// but if it ever did, the errors could end up missing some data.
 
 	// Add new series to the index and series file.
-	if err := e.index.CreateSeriesListIfNotExists(collection); err != nil {
+	if err := recordDrops(collection, drops, influxdb.WriteRejectedTypeConflict, typeConflictReason, func() error {
+		return e.index.CreateSeriesListIfNotExists(collection)
+	}); err != nil {
 		return err
 	}
 
@@ -559,13 +772,87 @@ func (e *Engine) writePointsLocked(ctx context.Context, collection *tsdb.SeriesC
 	}
 
 	// Write the values to the engine.
+	gen := e.lastValues.generation()
 	if err := e.engine.WriteValues(values); err != nil {
-		return err
+		// Any of the values may have been written.
+		e.lastValues.invalidateValues(values)
+
+		conflict, ok := err.(*tsm1.FieldTypeConflictError)
+		if !ok {
+			return err
+		}
+
+		// The values of the other keys were written.
+		conflicts := make(map[string]struct{}, len(conflict.Keys))
+		for _, key := range conflict.Keys {
+			delete(values, string(key))
+			seriesKey, _ := tsm1.SeriesAndFieldFromCompositeKey(key)
+			conflicts[string(seriesKey)] = struct{}{}
+			collection.Dropped++
+			collection.DroppedKeys = append(collection.DroppedKeys, seriesKey)
+		}
+		for iter := collection.Iterator(); iter.Next(); {
+			if _, ok := conflicts[string(iter.Key())]; !ok {
+				continue
+			}
+			reason := typeConflictReason(iter.Point())
+			if collection.Reason == "" {
+				collection.Reason = reason
+			}
+			if drops != nil {
+				*drops = append(*drops, tsdb.Drop{Point: iter.Point(), Code: influxdb.WriteRejectedTypeConflict, Reason: reason})
+			}
+		}
 	}
+	e.rollups.markDirty(collection)
+	e.lastValues.update(values, gen)
 
 	return collection.PartialWriteError()
 }
 
+// ApplyWALEntry applies an entry replicated from the WAL of another engine,
+// adding it to the WAL of the engine first. Writes and deletes are applied as
+// they were by the other engine, so they are not validated or limited again.
+func (e *Engine) ApplyWALEntry(ctx context.Context, entry wal.WALEntry) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	switch en := entry.(type) {
+	case *wal.WriteWALEntry:
+		if _, err := e.wal.WriteMulti(ctx, en.Values); err != nil {
+			return err
+		}
+		points := tsm1.ValuesToPoints(en.Values)
+		err := e.writePointsLocked(ctx, tsdb.NewSeriesCollection(points), en.Values, nil)
+		if _, ok := err.(tsdb.PartialWriteError); ok {
+			// The other engine dropped the same values.
+			err = nil
+		}
+		return err
+
+	case *wal.DeleteBucketRangeWALEntry:
+		var pred tsm1.Predicate
+		if len(en.Predicate) > 0 {
+			var err error
+			if pred, err = tsm1.UnmarshalPredicate(en.Predicate); err != nil {
+				return err
+			}
+		}
+		if _, err := e.wal.DeleteBucketRange(en.OrgID, en.BucketID, en.Min, en.Max, en.Predicate); err != nil {
+			return err
+		}
+		return e.deleteBucketRangeLocked(ctx, en.OrgID, en.BucketID, en.Min, en.Max, pred)
+	}
+
+	return nil
+}
+
 // AcquireSegments closes the current WAL segment, gets the set of all the currently closed
 // segments, and calls the callback. It does all of this under the lock on the engine.
 func (e *Engine) AcquireSegments(ctx context.Context, fn func(segs []string) error) error {
@@ -597,6 +884,9 @@ func (e *Engine) CommitSegments(ctx context.Context, segs []string, fn func() er
 		return err
 	}
 
+	// The snapshotted data can now be rolled up.
+	e.rollups.notify()
+
 	return e.wal.Remove(ctx, segs)
 }
 
@@ -664,14 +954,49 @@ func (e *Engine) deleteBucketRangeLocked(ctx context.Context, orgID, bucketID pl
 	encoded := tsdb.EncodeName(orgID, bucketID)
 	name := models.EscapeMeasurement(encoded[:])
 
-	return e.engine.DeletePrefixRange(ctx, name, min, max, pred)
+	if err := e.engine.DeletePrefixRange(ctx, name, min, max, pred); err != nil {
+		return err
+	}
+
+	// Deleted series no longer count towards the series limits.
+	e.seriesLimits.recount()
+
+	// The last values of the bucket are read again from storage.
+	e.lastValues.invalidate(encoded)
+	return nil
+}
+
+// MoveBucketToColdStorage moves the TSM files only holding data of the bucket
+// older than before to cold storage. It does nothing if cold storage is not
+// configured.
+func (e *Engine) MoveBucketToColdStorage(ctx context.Context, orgID, bucketID platform.ID, before int64) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	encoded := tsdb.EncodeName(orgID, bucketID)
+	name := models.EscapeMeasurement(encoded[:])
+
+	_, err := e.engine.MoveColdFiles(ctx, name, before)
+	return err
 }
 
 // CreateBackup creates a "snapshot" of all TSM data in the Engine.
-//   1) Snapshot the cache to ensure the backup includes all data written before now.
-//   2) Create hard links to all TSM files, in a new directory within the engine root directory.
-//   3) Return a unique backup ID (invalid after the process terminates) and list of files.
-func (e *Engine) CreateBackup(ctx context.Context) (int, []string, error) {
+//  1. Snapshot the cache to ensure the backup includes all data written before now.
+//  2. Create hard links to all TSM files, in a new directory within the engine root directory.
+//  3. Write a manifest describing every TSM and tombstone file in the snapshot.
+//  4. Return a unique backup ID (invalid after the process terminates) and list of files.
+//
+// If opts.Base is set, files that are unchanged since that backup are listed in
+// the manifest but no hard links are created for them. If opts.OrgID or
+// opts.BucketID are set, the backup only holds the data of that organization or
+// bucket, and the TSM files are rewritten to leave out all other data.
+func (e *Engine) CreateBackup(ctx context.Context, opts influxdb.BackupOptions) (int, []string, error) {
 	span, ctx := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
@@ -679,27 +1004,412 @@ func (e *Engine) CreateBackup(ctx context.Context) (int, []string, error) {
 		return 0, nil, ErrEngineClosed
 	}
 
+	// The creation time is taken before the snapshot, so that replaying the
+	// archived WAL from it onto the backup misses no writes.
+	manifest := influxdb.BackupManifest{CreatedAt: time.Now().UTC()}
+
 	if err := e.engine.WriteSnapshot(ctx, tsm1.CacheStatusBackup); err != nil {
 		return 0, nil, err
 	}
 
-	id, snapshotPath, err := e.engine.FileStore.CreateSnapshot(ctx)
+	prefix, err := backupPrefix(opts)
 	if err != nil {
 		return 0, nil, err
 	}
 
+	include := func(path string, size int64) bool {
+		if prefix != nil {
+			// Scoped backups are filtered after the snapshot is taken.
+			return true
+		}
+
+		name := filepath.Base(path)
+		generation, sequence, err := e.engine.FileStore.ParseFileName(path)
+		if err != nil {
+			// Always ship files we can't make sense of rather than risk losing them.
+			e.logger.Info("Unable to parse backup file name", zap.String("path", path), zap.Error(err))
+		}
+		f := influxdb.BackupManifestFile{
+			FileName:   name,
+			Generation: generation,
+			Sequence:   sequence,
+			Size:       size,
+		}
+		manifest.Files = append(manifest.Files, f)
+		if err != nil {
+			return true
+		}
+
+		// TSM files never change once written, so they are compared by name
+		// and size only. Tombstone files are replaced when entries are added,
+		// so those of the same size as in the base backup are read to compare
+		// their checksums.
+		if filepath.Ext(name) != "."+tsm1.TSMFileExtension {
+			prev, ok := opts.Base.File(name)
+			if !ok || prev.Size != size {
+				return true
+			}
+			if f.Checksum, err = fileChecksum(path); err != nil {
+				e.logger.Info("Unable to compute checksum of backup file", zap.String("path", path), zap.Error(err))
+				return true
+			}
+		}
+		return !opts.Base.Unchanged(f)
+	}
+
+	id, snapshotPath, err := e.engine.FileStore.CreateSnapshotFunc(ctx, include)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if prefix != nil {
+		if manifest.Files, err = e.filterBackup(snapshotPath, prefix, opts.Base); err != nil {
+			return 0, nil, multierr.Append(err, os.RemoveAll(snapshotPath))
+		}
+	} else if err := backupChecksums(snapshotPath, manifest.Files, opts.Base); err != nil {
+		return 0, nil, multierr.Append(err, os.RemoveAll(snapshotPath))
+	}
+
 	fileInfos, err := ioutil.ReadDir(snapshotPath)
 	if err != nil {
 		return 0, nil, err
 	}
-	filenames := make([]string, len(fileInfos))
-	for i, fi := range fileInfos {
-		filenames[i] = fi.Name()
+	filenames := make([]string, 0, len(fileInfos)+1)
+	for _, fi := range fileInfos {
+		filenames = append(filenames, fi.Name())
+	}
+
+	if err := writeBackupManifest(filepath.Join(snapshotPath, influxdb.BackupManifestFilename), &manifest); err != nil {
+		return 0, nil, multierr.Append(err, os.RemoveAll(snapshotPath))
 	}
+	filenames = append(filenames, influxdb.BackupManifestFilename)
 
 	return id, filenames, nil
 }
 
+// backupChecksums sets the checksums of files. Files linked into the snapshot
+// directory dir are read from their links, which keep the contents backed up
+// even if the files of the engine are replaced meanwhile. The checksums of
+// files left out of the snapshot are carried over from base.
+func backupChecksums(dir string, files []influxdb.BackupManifestFile, base *influxdb.BackupManifest) error {
+	for i := range files {
+		f := &files[i]
+		path := filepath.Join(dir, f.FileName)
+
+		fi, err := os.Stat(path)
+		if os.IsNotExist(err) {
+			prev, _ := base.File(f.FileName)
+			f.Checksum = prev.Checksum
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		f.Size = fi.Size()
+		if f.Checksum, err = fileChecksum(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupPrefix returns the key prefix of the data included in a backup with the
+// given options, or nil if the backup includes all data.
+func backupPrefix(opts influxdb.BackupOptions) ([]byte, error) {
+	switch {
+	case opts.BucketID != nil && opts.OrgID == nil:
+		return nil, &influxdb.Error{
+			Code: influxdb.EInvalid,
+			Msg:  "an organization ID is required to back up a bucket",
+		}
+	case opts.BucketID != nil:
+		encoded := tsdb.EncodeName(*opts.OrgID, *opts.BucketID)
+		return models.EscapeMeasurement(encoded[:]), nil
+	case opts.OrgID != nil:
+		encoded := tsdb.EncodeOrgName(*opts.OrgID)
+		return models.EscapeMeasurement(encoded[:]), nil
+	}
+	return nil, nil
+}
+
+// filterBackup rewrites the TSM files in the snapshot directory dir so that they
+// only hold keys starting with prefix, with tombstones applied. Files left
+// without any keys are removed, as are files that are unchanged since base.
+// It returns the manifest entries of the rewritten files.
+func (e *Engine) filterBackup(dir string, prefix []byte, base *influxdb.BackupManifest) ([]influxdb.BackupManifestFile, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*."+tsm1.TSMFileExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []influxdb.BackupManifestFile
+	for _, path := range paths {
+		size, err := filterTSMFile(path, prefix, e.engine.FileStore.ColdStore())
+		if err != nil {
+			return nil, err
+		} else if size == 0 {
+			continue
+		}
+
+		name := filepath.Base(path)
+		generation, sequence, err := e.engine.FileStore.ParseFileName(path)
+		if err != nil {
+			e.logger.Info("Unable to parse backup file name", zap.String("path", path), zap.Error(err))
+		}
+		// Rewritten files change with the tombstones of the original, so they
+		// are always compared by checksum.
+		checksum, cerr := fileChecksum(path)
+		if cerr != nil {
+			return nil, cerr
+		}
+		f := influxdb.BackupManifestFile{
+			FileName:   name,
+			Generation: generation,
+			Sequence:   sequence,
+			Size:       size,
+			Checksum:   checksum,
+		}
+		files = append(files, f)
+
+		if err == nil && base.Unchanged(f) {
+			if err := os.Remove(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return files, nil
+}
+
+// filterTSMFile replaces the TSM file at path, and its tombstone file, with a
+// TSM file holding only the keys starting with prefix. It returns the size of
+// the new file, or zero if there were no such keys and the file was removed.
+// The blocks of files in cold storage are read from store, so the new file does
+// not depend on it.
+func filterTSMFile(path string, prefix []byte, store tsm1.ColdStore) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	r, err := tsm1.NewTSMReader(f, tsm1.WithColdStore(store, nil))
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	tombstones := r.TombstoneFiles()
+
+	tmpPath := path + "." + tsm1.TmpTSMFileExtension
+	fd, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		r.Close()
+		return 0, err
+	}
+	// Write through a plain io.Writer so no stats file is created alongside.
+	w, err := tsm1.NewTSMWriter(struct{ io.Writer }{fd})
+	if err != nil {
+		r.Close()
+		fd.Close()
+		return 0, err
+	}
+
+	n, err := tsm1.CopyPrefix(w, r, prefix, nil)
+	if err == nil && n > 0 {
+		if err = w.WriteIndex(); err == nil {
+			err = w.Flush()
+		}
+	}
+	err = multierr.Combine(err, fd.Close(), r.Close(), os.Remove(path))
+	for _, ts := range tombstones {
+		err = multierr.Append(err, os.Remove(ts.Path))
+	}
+	if err != nil || n == 0 {
+		return 0, multierr.Append(err, os.Remove(tmpPath))
+	}
+
+	fi, err := os.Stat(tmpPath)
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), os.Rename(tmpPath, path)
+}
+
+// RestoreBucket loads the data of bucket srcBucketID in srcOrgID, from the TSM
+// file read from r, into bucket dstBucketID in dstOrgID. Data of other buckets in
+// the file is ignored.
+func (e *Engine) RestoreBucket(ctx context.Context, srcOrgID, srcBucketID, dstOrgID, dstBucketID platform.ID, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	path, err := e.writeRestoreFile(r)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	src := tsdb.EncodeName(srcOrgID, srcBucketID)
+	dst := tsdb.EncodeName(dstOrgID, dstBucketID)
+	srcPrefix := models.EscapeMeasurement(src[:])
+	dstPrefix := models.EscapeMeasurement(dst[:])
+
+	ranges, err := restoreTimeRanges(path, srcPrefix)
+	if err != nil {
+		return err
+	}
+	if r, ok := ranges[src]; ok {
+		ranges = map[[influxdb.IDLength]byte]timeRange{dst: r}
+	}
+
+	err = e.engine.ImportPrefix(ctx, path, srcPrefix, func(key []byte) []byte {
+		newKey := make([]byte, 0, len(dstPrefix)+len(key)-len(srcPrefix))
+		newKey = append(newKey, dstPrefix...)
+		return append(newKey, key[len(srcPrefix):]...)
+	})
+	e.restored(ranges)
+	return err
+}
+
+// RestoreTSMFile loads all data of the TSM file read from r into the engine,
+// and adds its series to the index.
+func (e *Engine) RestoreTSMFile(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return ErrEngineClosed
+	}
+
+	path, err := e.writeRestoreFile(r)
+	if err != nil {
+		return err
+	}
+	// The file is moved into the engine if it is imported as is.
+	defer os.Remove(path)
+
+	ranges, err := restoreTimeRanges(path, nil)
+	if err != nil {
+		return err
+	}
+
+	err = e.engine.ImportFile(ctx, path)
+	e.restored(ranges)
+	return err
+}
+
+// restoreTimeRanges returns the time range of the data of each bucket in the
+// TSM file at path, keyed by the encoded name of the bucket. Only keys starting
+// with prefix are read.
+func restoreTimeRanges(path string, prefix []byte) (map[[influxdb.IDLength]byte]timeRange, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := tsm1.NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	defer r.Close()
+
+	ranges := make(map[[influxdb.IDLength]byte]timeRange)
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+
+		raw := models.ParseName(key)
+		if len(raw) != influxdb.IDLength {
+			continue
+		}
+		var name [influxdb.IDLength]byte
+		copy(name[:], raw)
+
+		for _, ie := range iter.Entries() {
+			tr, ok := ranges[name]
+			if !ok {
+				tr = timeRange{min: ie.MinTime, max: ie.MaxTime}
+			}
+			if ie.MinTime < tr.min {
+				tr.min = ie.MinTime
+			}
+			if ie.MaxTime > tr.max {
+				tr.max = ie.MaxTime
+			}
+			ranges[name] = tr
+		}
+	}
+	return ranges, iter.Err()
+}
+
+// restored updates the state derived from the data of the engine after data
+// in the given time ranges has been restored into buckets, even partially.
+func (e *Engine) restored(ranges map[[influxdb.IDLength]byte]timeRange) {
+	for name, r := range ranges {
+		// The last values of the bucket are read again from storage.
+		e.lastValues.invalidate(name)
+		e.rollups.markDirtyRange(name, r)
+	}
+
+	// Restored series count towards the series limits.
+	e.seriesLimits.recount()
+
+	// Restored data is not written to the cache, so it can be rolled up now.
+	e.rollups.notify()
+}
+
+// writeRestoreFile writes r to a temporary file in the engine directory and
+// returns its path. Temporary files are removed by the TSM engine on startup
+// if a restore is interrupted.
+func (e *Engine) writeRestoreFile(r io.Reader) (string, error) {
+	f, err := ioutil.TempFile(e.engine.Path(), "restore-*."+tsm1.CompactionTempExtension)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := io.Copy(f, r); err != nil {
+		return "", multierr.Combine(err, f.Close(), os.Remove(f.Name()))
+	}
+	if err := f.Close(); err != nil {
+		return "", multierr.Append(err, os.Remove(f.Name()))
+	}
+	return f.Name(), nil
+}
+
+// fileChecksum returns the hex encoded SHA-256 of the contents of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeBackupManifest writes m as JSON to the file at path.
+func writeBackupManifest(path string, m *influxdb.BackupManifest) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0660)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(m); err != nil {
+		return multierr.Append(err, f.Close())
+	}
+	return f.Close()
+}
+
 // FetchBackupFile writes a given backup file to the provided writer.
 // After a successful write, the internal copy is removed.
 func (e *Engine) FetchBackupFile(ctx context.Context, backupID int, backupFile string, w io.Writer) error {
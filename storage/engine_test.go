@@ -1,12 +1,18 @@
 package storage_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +21,7 @@ import (
 	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage"
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
+	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 	"github.com/prometheus/client_golang/prometheus"
@@ -490,6 +497,470 @@ func TestEngine_WriteConflictingBatch(t *testing.T) {
 	}
 }
 
+func TestEngine_WriteConflictingPoints(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	name := tsdb.EncodeNameString(engine.org, engine.bucket)
+	newPoint := func(host string, value interface{}) models.Point {
+		return models.MustNewPoint(
+			name,
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": value},
+			time.Unix(1, 2),
+		)
+	}
+
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{newPoint("a", 1.0)}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The point of the other series is written despite the conflict.
+	conflict := newPoint("a", 2)
+	err := engine.Engine.WritePoints(context.TODO(), []models.Point{conflict, newPoint("b", 1.0)})
+	pwe, ok := err.(tsdb.PartialWriteError)
+	if !ok {
+		t.Fatal("expected partial write error. got:", err)
+	}
+	if got, exp := pwe.Dropped, 1; got != exp {
+		t.Fatalf("got %d dropped, exp %d", got, exp)
+	}
+	if got, exp := len(pwe.Drops), 1; got != exp {
+		t.Fatalf("got %d drops, exp %d", got, exp)
+	}
+	if got, exp := pwe.Drops[0].Code, influxdb.WriteRejectedTypeConflict; got != exp {
+		t.Fatalf("got drop code %q, exp %q", got, exp)
+	}
+	if pwe.Drops[0].Point != conflict {
+		t.Fatalf("got drop of point %v, exp %v", pwe.Drops[0].Point, conflict)
+	}
+
+	if err := engine.Engine.WritePoints(context.TODO(), []models.Point{newPoint("b", 2.0)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEngine_CreateBackup_Incremental(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	writePoint := func(ts int64) {
+		t.Helper()
+		err := engine.Engine.WritePoints(context.TODO(), []models.Point{models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": "server"}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(ts, 0),
+		)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readManifest := func(id int) *influxdb.BackupManifest {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := engine.FetchBackupFile(context.Background(), id, influxdb.BackupManifestFilename, &buf); err != nil {
+			t.Fatal(err)
+		}
+		var m influxdb.BackupManifest
+		if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+			t.Fatal(err)
+		}
+		return &m
+	}
+
+	writePoint(1)
+	id, files, err := engine.CreateBackup(context.Background(), influxdb.BackupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(files), 2; got != exp {
+		t.Fatalf("got %d files %v, exp %d", got, files, exp)
+	}
+	full := readManifest(id)
+	if got, exp := len(full.Files), 1; got != exp {
+		t.Fatalf("got %d manifest entries, exp %d", got, exp)
+	}
+
+	writePoint(2)
+	writePoint(3)
+	id, files, err = engine.CreateBackup(context.Background(), influxdb.BackupOptions{Base: full})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the newly snapshotted TSM file and the manifest are in the backup.
+	if got, exp := len(files), 2; got != exp {
+		t.Fatalf("got %d files %v, exp %d", got, files, exp)
+	}
+	if files[0] == full.Files[0].FileName {
+		t.Fatalf("unchanged file %s included in incremental backup", files[0])
+	}
+
+	// The manifest lists both the old and the new TSM file.
+	incr := readManifest(id)
+	if got, exp := len(incr.Files), 2; got != exp {
+		t.Fatalf("got %d manifest entries, exp %d", got, exp)
+	}
+	if _, ok := incr.File(full.Files[0].FileName); !ok {
+		t.Fatalf("manifest does not list %s", full.Files[0].FileName)
+	}
+	for _, f := range incr.Files {
+		if f.Checksum == "" {
+			t.Fatalf("manifest has no checksum for %s", f.FileName)
+		}
+	}
+	if f, _ := incr.File(full.Files[0].FileName); f.Checksum != full.Files[0].Checksum {
+		t.Fatalf("got checksum %s for carried over file, exp %s", f.Checksum, full.Files[0].Checksum)
+	}
+
+	// Deletes add tombstone files, which are copied along with the manifest
+	// but not the TSM files they apply to.
+	if err := engine.DeleteBucketRange(context.Background(), engine.org, engine.bucket, int64(2*time.Second), int64(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	id, files, err = engine.CreateBackup(context.Background(), influxdb.BackupOptions{Base: incr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := len(files), 2; got != exp {
+		t.Fatalf("got %d files %v, exp %d", got, files, exp)
+	}
+	if filepath.Ext(files[0]) != "."+tsm1.TombstoneFileExtension {
+		t.Fatalf("got file %s, exp a tombstone file", files[0])
+	}
+
+	// The checksum of the tombstone file is that of the copy in the backup.
+	var buf bytes.Buffer
+	if err := engine.FetchBackupFile(context.Background(), id, files[0], &buf); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	if f, _ := readManifest(id).File(files[0]); f.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("got checksum %s for %s, exp %x", f.Checksum, files[0], sum)
+	}
+}
+
+func TestEngine_CreateBackup_Bucket(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	other := engine.bucket + 1
+	writePoint := func(bucket influxdb.ID, host string) {
+		t.Helper()
+		err := engine.Engine.WritePoints(context.TODO(), []models.Point{models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePoint(engine.bucket, "a")
+	writePoint(engine.bucket, "b")
+	writePoint(other, "c")
+
+	// A bucket ID without an organization ID is rejected.
+	if _, _, err := engine.CreateBackup(context.Background(), influxdb.BackupOptions{BucketID: &engine.bucket}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("got error %v, exp %s", err, influxdb.EInvalid)
+	}
+
+	id, files, err := engine.CreateBackup(context.Background(), influxdb.BackupOptions{OrgID: &engine.org, BucketID: &engine.bucket})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tsmFile string
+	for _, f := range files {
+		if strings.HasSuffix(f, "."+tsm1.TSMFileExtension) {
+			tsmFile = f
+		}
+	}
+	if tsmFile == "" {
+		t.Fatalf("no TSM file in backup %v", files)
+	}
+
+	var buf bytes.Buffer
+	if err := engine.FetchBackupFile(context.Background(), id, tsmFile, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring the backup into a new bucket adds the series of the backed up bucket.
+	restored := engine.bucket + 2
+	if err := engine.RestoreBucket(context.Background(), engine.org, engine.bucket, engine.org, restored, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(5); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+
+	// The backup holds no data of the other bucket.
+	if err := engine.RestoreBucket(context.Background(), engine.org, other, engine.org, restored+1, bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := engine.SeriesCardinality(), int64(5); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+}
+
+func TestEngine_Compactions(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	if err := engine.SetCompactionsPaused(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Scheduling a compaction snapshots the cache, so each write is stored in
+	// its own generation.
+	for i, host := range []string{"a", "b"} {
+		err := engine.Engine.WritePoints(ctx, []models.Point{models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, engine.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(int64(i), 0),
+		)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := engine.CompactBucket(ctx, engine.org, engine.bucket+1, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	compactions, err := engine.CompactBucket(ctx, engine.org, engine.bucket, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compactions) != 1 || compactions[0].Level != influxdb.CompactionLevelOptimize || len(compactions[0].Files) != 2 {
+		t.Fatalf("unexpected compactions %+v", compactions)
+	}
+
+	status, err := engine.FindCompactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Paused || len(status.Compactions) != 1 || status.Compactions[0].Running {
+		t.Fatalf("unexpected compaction status %+v", status)
+	}
+
+	if err := engine.CancelCompaction(ctx, 1); influxdb.ErrorCode(err) != influxdb.ENotFound {
+		t.Fatalf("got error %v, exp %s", err, influxdb.ENotFound)
+	}
+
+	if err := engine.SetCompactionsPaused(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+	status, err = engine.FindCompactions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if status.Paused {
+		t.Fatal("expected compactions to be resumed")
+	}
+}
+
+func TestEngine_FindCardinality(t *testing.T) {
+	engine := NewDefaultEngine()
+	defer engine.Close()
+	engine.MustOpen()
+
+	ctx := context.Background()
+	var points []models.Point
+	write := func(bucket influxdb.ID, m, host, field string) {
+		points = append(points, models.MustNewPoint(
+			tsdb.EncodeNameString(engine.org, bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: field, models.MeasurementTagKey: m, "host": host}),
+			map[string]interface{}{field: 1.0},
+			time.Unix(1, 0),
+		))
+	}
+	for _, host := range []string{"a", "b", "c"} {
+		write(engine.bucket, "cpu", host, "usage")
+		write(engine.bucket, "cpu", host, "idle")
+	}
+	write(engine.bucket, "mem", "a", "used")
+	write(engine.bucket+1, "cpu", "a", "usage")
+	if err := engine.Engine.WritePoints(ctx, points); err != nil {
+		t.Fatal(err)
+	}
+
+	buckets, err := engine.FindCardinality(ctx, influxdb.CardinalityFilter{OrgID: engine.org})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 2 || buckets[0].BucketID != engine.bucket || buckets[0].Series != 7 || buckets[1].Series != 1 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+
+	buckets, err = engine.FindCardinality(ctx, influxdb.CardinalityFilter{OrgID: engine.org, BucketID: &engine.bucket, TopValues: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].Series != 7 || len(buckets[0].Measurements) != 2 {
+		t.Fatalf("unexpected buckets %+v", buckets)
+	}
+
+	cpu := buckets[0].Measurements[0]
+	if cpu.Name != "cpu" || cpu.Series != 6 || len(cpu.TagKeys) != 2 {
+		t.Fatalf("unexpected measurement %+v", cpu)
+	}
+	if host := cpu.TagKeys[0]; host.Key != "host" || host.Values != 3 || len(host.TopValues) != 2 || host.TopValues[0].Series != 2 {
+		t.Fatalf("unexpected tag key %+v", host)
+	}
+	if field := cpu.TagKeys[1]; field.Key != "_field" || field.Values != 2 || len(field.TopValues) != 2 || field.TopValues[0].Series != 3 {
+		t.Fatalf("unexpected tag key %+v", field)
+	}
+
+	m := "mem"
+	buckets, err = engine.FindCardinality(ctx, influxdb.CardinalityFilter{OrgID: engine.org, BucketID: &engine.bucket, Measurement: &m})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms := buckets[0].Measurements; len(ms) != 1 || ms[0].Name != "mem" || ms[0].Series != 1 || len(ms[0].TagKeys) != 2 {
+		t.Fatalf("unexpected measurements %+v", ms)
+	}
+
+	if _, err := engine.FindCardinality(ctx, influxdb.CardinalityFilter{BucketID: &engine.bucket}); influxdb.ErrorCode(err) != influxdb.EInvalid {
+		t.Fatalf("got error %v, exp %s", err, influxdb.EInvalid)
+	}
+}
+
+func TestEngine_RestoreTSMFile(t *testing.T) {
+	src := NewDefaultEngine()
+	defer src.Close()
+	src.MustOpen()
+
+	for _, host := range []string{"a", "b"} {
+		err := src.Engine.WritePoints(context.TODO(), []models.Point{models.MustNewPoint(
+			tsdb.EncodeNameString(src.org, src.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	id, files, err := src.CreateBackup(context.Background(), influxdb.BackupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := NewDefaultEngine()
+	defer dst.Close()
+	dst.MustOpen()
+
+	for _, f := range files {
+		if !strings.HasSuffix(f, "."+tsm1.TSMFileExtension) {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := src.FetchBackupFile(context.Background(), id, f, &buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.RestoreTSMFile(context.Background(), &buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, exp := dst.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+
+	// The restored data survives a restart.
+	if err := dst.Engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	dst.MustOpen()
+	if got, exp := dst.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series after reopening, exp %d", got, exp)
+	}
+}
+
+// walReplicator collects the entries written to a WAL.
+type walReplicator struct {
+	entries []wal.WALEntry
+}
+
+func (r *walReplicator) Replicate(typ wal.WalEntryType, compressed []byte) {
+	entry, err := wal.DecodeEntry(typ, compressed)
+	if err != nil {
+		panic(err)
+	}
+	r.entries = append(r.entries, entry)
+}
+
+func TestEngine_ApplyWALEntry(t *testing.T) {
+	var r walReplicator
+	primary := NewDefaultEngine()
+	defer primary.Close()
+	storage.WithWALReplicator(&r)(primary.Engine)
+	primary.MustOpen()
+
+	standby := NewDefaultEngine()
+	defer standby.Close()
+	standby.MustOpen()
+
+	apply := func() {
+		t.Helper()
+		for _, entry := range r.entries {
+			if err := standby.ApplyWALEntry(context.Background(), entry); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.entries = nil
+	}
+
+	for _, host := range []string{"a", "b"} {
+		err := primary.Engine.WritePoints(context.TODO(), []models.Point{models.MustNewPoint(
+			tsdb.EncodeNameString(primary.org, primary.bucket),
+			models.NewTags(map[string]string{models.FieldKeyTagKey: "value", models.MeasurementTagKey: "cpu", "host": host}),
+			map[string]interface{}{"value": 1.0},
+			time.Unix(1, 0),
+		)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, exp := len(r.entries), 2; got != exp {
+		t.Fatalf("got %d replicated entries, exp %d", got, exp)
+	}
+
+	apply()
+	if got, exp := standby.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("got %d series, exp %d", got, exp)
+	}
+
+	if err := primary.DeleteBucket(context.Background(), primary.org, primary.bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	apply()
+	if got, exp := standby.SeriesCardinality(), int64(0); got != exp {
+		t.Fatalf("got %d series after delete, exp %d", got, exp)
+	}
+
+	// The applied entries are in the WAL of the standby, and are replayed by
+	// a new engine opening its data.
+	if err := standby.Engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	standby.Engine = storage.NewEngine(standby.path, storage.NewConfig(), storage.WithEngineID(standby.engineID), storage.WithNodeID(standby.nodeID))
+	standby.MustOpen()
+	if got, exp := standby.SeriesCardinality(), int64(0); got != exp {
+		t.Fatalf("got %d series after reopening, exp %d", got, exp)
+	}
+}
+
 // BenchmarkWritePoints_100K demonstrates the impact that batch size has on
 // writing a fixed number of points into storage. In this case 100K points are
 // written according to varying batch sizes.
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// testEngine is an open engine of which the data is removed once closed.
+type testEngine struct {
+	*Engine
+	path string
+}
+
+// newTestEngine opens an engine in a temporary directory. Its metrics are
+// labelled with the engine and node IDs 0, like those of the engines of the
+// storage_test package.
+func newTestEngine(t *testing.T, c Config, options ...Option) *testEngine {
+	t.Helper()
+
+	path, err := ioutil.TempDir("", "storage_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options = append([]Option{WithEngineID(0), WithNodeID(0)}, options...)
+	e := &testEngine{
+		Engine: NewEngine(path, c, options...),
+		path:   path,
+	}
+	if err := e.Open(context.Background()); err != nil {
+		os.RemoveAll(path)
+		t.Fatal(err)
+	}
+	return e
+}
+
+// Close closes the engine and removes all temporary data.
+func (e *testEngine) Close() error {
+	defer os.RemoveAll(e.path)
+	return e.Engine.Close()
+}
+
+// cpuPoints returns a point of the value field of the cpu measurement for
+// each host.
+func cpuPoints(org, bucket influxdb.ID, hosts ...string) []models.Point {
+	points := make([]models.Point, 0, len(hosts))
+	for _, host := range hosts {
+		points = append(points, models.MustNewPoint(
+			tsdb.EncodeNameString(org, bucket),
+			models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", "host": host, models.FieldKeyTagKey: "value"}),
+			models.Fields{"value": 1.0},
+			time.Unix(1, 0),
+		))
+	}
+	return points
+}
+
+// tsmFile returns a TSM file holding values by TSM key.
+func tsmFile(t *testing.T, values map[string][]tsm1.Value) *bytes.Buffer {
+	t.Helper()
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	w, err := tsm1.NewTSMWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range keys {
+		if err := w.Write([]byte(k), values[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteIndex(); err != nil {
+		t.Fatal(err)
+	} else if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
@@ -0,0 +1,463 @@
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxdb/tsdb/value"
+	"go.uber.org/zap"
+)
+
+// lastValuesInterval is the interval at which the buckets keeping a last-value
+// cache are reloaded.
+const lastValuesInterval = time.Minute
+
+// lastValue is the last value of a series. The value is complete once it is
+// known to be the last value of the series, and otherwise only the last value
+// written since the series was added to the cache.
+type lastValue struct {
+	v        value.Value
+	complete bool
+}
+
+// The lastValueCache keeps the last value of each series of the buckets with
+// a last-value cache in memory, so that the latest points of their series are
+// read without touching TSM files.
+//
+// The values written to a bucket are added to the cache as they are written.
+// As older data may still be stored for a series, its value is only complete
+// once it has been merged with the last value read from storage, which is done
+// the first time the series is read. Deletes drop the values of the bucket,
+// which are read again from storage.
+type lastValueCache struct {
+	// BucketService provides the buckets with a last-value cache.
+	BucketService BucketFinder
+
+	mu      sync.RWMutex
+	gen     uint64 // Incremented whenever values are dropped.
+	buckets map[[influxdb.IDLength]byte]map[string]lastValue
+
+	logger *zap.Logger
+}
+
+// newLastValueCache returns a new cache of the last values of the buckets
+// provided by bucketService.
+func newLastValueCache(bucketService BucketFinder) *lastValueCache {
+	return &lastValueCache{
+		BucketService: bucketService,
+		buckets:       make(map[[influxdb.IDLength]byte]map[string]lastValue),
+		logger:        zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger l on the cache. It must be called before any load calls.
+func (c *lastValueCache) WithLogger(log *zap.Logger) {
+	if c == nil {
+		return // Not initialised
+	}
+	c.logger = log.With(zap.String("component", "last_values"))
+}
+
+// load reloads the buckets with a last-value cache. The values of the buckets
+// that no longer have one are dropped.
+func (c *lastValueCache) load(ctx context.Context) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	buckets, _, err := c.BucketService.FindBuckets(ctx, influxdb.BucketFilter{})
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	enabled := make(map[[influxdb.IDLength]byte]map[string]lastValue)
+	for _, b := range buckets {
+		if !b.LastValueCache {
+			continue
+		}
+		name := tsdb.EncodeName(b.OrgID, b.ID)
+		if values, ok := c.buckets[name]; ok {
+			enabled[name] = values
+		} else {
+			enabled[name] = make(map[string]lastValue)
+		}
+	}
+	c.buckets = enabled
+	return nil
+}
+
+// enabled returns true if the bucket with the given name has a last-value cache.
+func (c *lastValueCache) enabled(name []byte) bool {
+	if c == nil || len(name) != influxdb.IDLength {
+		return false
+	}
+
+	var key [influxdb.IDLength]byte
+	copy(key[:], name)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.buckets[key]
+	return ok
+}
+
+// generation returns the current generation of the cache. It is read before
+// data is written to or read from storage, so that the values are not added to
+// the cache if values have been dropped in the meantime.
+func (c *lastValueCache) generation() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.gen
+}
+
+// update adds the values written to the engine, keyed by TSM key, to the
+// cache. The values are ignored if values have been dropped since gen.
+func (c *lastValueCache) update(values map[string][]value.Value, gen uint64) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen || len(c.buckets) == 0 {
+		return
+	}
+
+	var name [influxdb.IDLength]byte
+	for key, vs := range values {
+		raw := models.ParseName([]byte(key))
+		if len(vs) == 0 || len(raw) != influxdb.IDLength {
+			continue
+		}
+		copy(name[:], raw)
+		bucket, ok := c.buckets[name]
+		if !ok {
+			continue
+		}
+
+		// Values with the same timestamp overwrite each other, so the last one wins.
+		last := vs[0]
+		for _, v := range vs[1:] {
+			if v.UnixNano() >= last.UnixNano() {
+				last = v
+			}
+		}
+
+		lv, ok := bucket[key]
+		if !ok {
+			bucket[key] = lastValue{v: last}
+		} else if last.UnixNano() >= lv.v.UnixNano() {
+			lv.v = last
+			bucket[key] = lv
+		}
+	}
+}
+
+// get returns the last value of the series with the given TSM key in the bucket
+// with the given name, if its value is complete.
+func (c *lastValueCache) get(name, key []byte) (value.Value, bool) {
+	var bucket [influxdb.IDLength]byte
+	copy(bucket[:], name)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	lv, ok := c.buckets[bucket][string(key)]
+	if !ok || !lv.complete {
+		return nil, false
+	}
+	return lv.v, true
+}
+
+// fill merges v, the last value read from storage for the series with the
+// given TSM key, with the values written since, and returns the complete last
+// value of the series. v is nil if no value is stored for the series. Nothing
+// is added to the cache if values have been dropped since gen.
+func (c *lastValueCache) fill(name, key []byte, v value.Value, gen uint64) value.Value {
+	var bucket [influxdb.IDLength]byte
+	copy(bucket[:], name)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values, ok := c.buckets[bucket]
+	if !ok || gen != c.gen {
+		return v
+	}
+
+	lv, ok := values[string(key)]
+	if ok && (v == nil || lv.v.UnixNano() >= v.UnixNano()) {
+		v = lv.v
+	} else if v == nil {
+		return nil
+	}
+	values[string(key)] = lastValue{v: v, complete: true}
+	return v
+}
+
+// invalidate drops the values of the bucket with the given name, such as after
+// data has been deleted from it.
+func (c *lastValueCache) invalidate(name [influxdb.IDLength]byte) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if _, ok := c.buckets[name]; ok {
+		c.buckets[name] = make(map[string]lastValue)
+	}
+}
+
+// invalidateValues drops the values of the buckets of values, keyed by TSM key,
+// such as after they have only been partially written.
+func (c *lastValueCache) invalidateValues(values map[string][]value.Value) {
+	if c == nil {
+		return
+	}
+
+	names := make(map[[influxdb.IDLength]byte]struct{})
+	for key := range values {
+		if raw := models.ParseName([]byte(key)); len(raw) == influxdb.IDLength {
+			var name [influxdb.IDLength]byte
+			copy(name[:], raw)
+			names[name] = struct{}{}
+		}
+	}
+	for name := range names {
+		c.invalidate(name)
+	}
+}
+
+// runLastValueCache periodically reloads the buckets with a last-value cache
+// in a separate goroutine.
+func (e *Engine) runLastValueCache() {
+	closing := e.closing
+
+	ticker := time.NewTicker(lastValuesInterval)
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closing:
+				return
+			case <-ticker.C:
+				if err := e.lastValues.load(context.Background()); err != nil {
+					e.lastValues.logger.Error("Unable to load last-value cache buckets", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+// CreateLastCursorIterator creates a CursorIterator for reading the last value
+// of series with the read service. Descending requests for series of buckets
+// with a last-value cache are answered from the cache when the last value of
+// the series is in the requested time range. As with the cursors of
+// CreateCursorIterator, a descending request excludes its start time and
+// includes its end time.
+func (e *Engine) CreateLastCursorIterator(ctx context.Context) (tsdb.CursorIterator, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	itr, err := e.engine.CreateCursorIterator(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if e.lastValues == nil {
+		return itr, nil
+	}
+	return &lastCursorIterator{itr: itr, cache: e.lastValues}, nil
+}
+
+// lastCursorIterator answers the descending requests for series of buckets
+// with a last-value cache from the cache, and all other requests with itr.
+type lastCursorIterator struct {
+	itr   cursors.CursorIterator
+	cache *lastValueCache
+	key   []byte
+}
+
+func (q *lastCursorIterator) Next(ctx context.Context, r *cursors.CursorRequest) (cursors.Cursor, error) {
+	if r.Ascending || !q.cache.enabled(r.Name) {
+		return q.itr.Next(ctx, r)
+	}
+
+	q.key = models.AppendMakeKey(q.key[:0], r.Name, r.Tags)
+	q.key = append(q.key, tsm1.KeyFieldSeparatorBytes...)
+	q.key = append(q.key, r.Field...)
+
+	v, ok := q.cache.get(r.Name, q.key)
+	if !ok {
+		var err error
+		if v, err = q.readLast(ctx, r); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case v == nil || v.UnixNano() <= r.StartTime:
+		return nil, nil // No data in the time range.
+	case v.UnixNano() > r.EndTime:
+		// The last value is after the time range, so the range is read.
+		return q.itr.Next(ctx, r)
+	default:
+		return newLastValueCursor(v), nil
+	}
+}
+
+// readLast reads the last value of the series of r from storage and adds it to
+// the cache. It returns nil if no value is stored for the series.
+func (q *lastCursorIterator) readLast(ctx context.Context, r *cursors.CursorRequest) (value.Value, error) {
+	gen := q.cache.generation()
+
+	cur, err := q.itr.Next(ctx, &cursors.CursorRequest{
+		Name:      r.Name,
+		Tags:      r.Tags,
+		Field:     r.Field,
+		Ascending: false,
+		StartTime: models.MinNanoTime - 1,
+		EndTime:   models.MaxNanoTime,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var v value.Value
+	if cur != nil {
+		v = firstValue(cur)
+		err = cur.Err()
+		cur.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return q.cache.fill(r.Name, q.key, v, gen), nil
+}
+
+func (q *lastCursorIterator) Stats() cursors.CursorStats { return q.itr.Stats() }
+
+// firstValue returns the first value read from cur, or nil if it is empty.
+func firstValue(cur cursors.Cursor) value.Value {
+	switch cur := cur.(type) {
+	case cursors.FloatArrayCursor:
+		if a := cur.Next(); a.Len() > 0 {
+			return value.NewFloatValue(a.Timestamps[0], a.Values[0])
+		}
+	case cursors.IntegerArrayCursor:
+		if a := cur.Next(); a.Len() > 0 {
+			return value.NewIntegerValue(a.Timestamps[0], a.Values[0])
+		}
+	case cursors.UnsignedArrayCursor:
+		if a := cur.Next(); a.Len() > 0 {
+			return value.NewUnsignedValue(a.Timestamps[0], a.Values[0])
+		}
+	case cursors.StringArrayCursor:
+		if a := cur.Next(); a.Len() > 0 {
+			return value.NewStringValue(a.Timestamps[0], a.Values[0])
+		}
+	case cursors.BooleanArrayCursor:
+		if a := cur.Next(); a.Len() > 0 {
+			return value.NewBooleanValue(a.Timestamps[0], a.Values[0])
+		}
+	}
+	return nil
+}
+
+// newLastValueCursor returns a cursor of the type of v only reading v.
+func newLastValueCursor(v value.Value) cursors.Cursor {
+	switch v := v.(type) {
+	case value.FloatValue:
+		return &floatLastValueCursor{a: &cursors.FloatArray{Timestamps: []int64{v.UnixNano()}, Values: []float64{v.RawValue()}}}
+	case value.IntegerValue:
+		return &integerLastValueCursor{a: &cursors.IntegerArray{Timestamps: []int64{v.UnixNano()}, Values: []int64{v.RawValue()}}}
+	case value.UnsignedValue:
+		return &unsignedLastValueCursor{a: &cursors.UnsignedArray{Timestamps: []int64{v.UnixNano()}, Values: []uint64{v.RawValue()}}}
+	case value.StringValue:
+		return &stringLastValueCursor{a: &cursors.StringArray{Timestamps: []int64{v.UnixNano()}, Values: []string{v.RawValue()}}}
+	case value.BooleanValue:
+		return &booleanLastValueCursor{a: &cursors.BooleanArray{Timestamps: []int64{v.UnixNano()}, Values: []bool{v.RawValue()}}}
+	default:
+		return nil
+	}
+}
+
+// lastValueCursor implements the methods shared by the last-value cursors.
+type lastValueCursor struct{}
+
+func (lastValueCursor) Close()                     {}
+func (lastValueCursor) Err() error                 { return nil }
+func (lastValueCursor) Stats() cursors.CursorStats { return cursors.CursorStats{} }
+
+type floatLastValueCursor struct {
+	lastValueCursor
+	a *cursors.FloatArray
+}
+
+func (c *floatLastValueCursor) Next() *cursors.FloatArray {
+	a := c.a
+	c.a = &cursors.FloatArray{}
+	return a
+}
+
+type integerLastValueCursor struct {
+	lastValueCursor
+	a *cursors.IntegerArray
+}
+
+func (c *integerLastValueCursor) Next() *cursors.IntegerArray {
+	a := c.a
+	c.a = &cursors.IntegerArray{}
+	return a
+}
+
+type unsignedLastValueCursor struct {
+	lastValueCursor
+	a *cursors.UnsignedArray
+}
+
+func (c *unsignedLastValueCursor) Next() *cursors.UnsignedArray {
+	a := c.a
+	c.a = &cursors.UnsignedArray{}
+	return a
+}
+
+type stringLastValueCursor struct {
+	lastValueCursor
+	a *cursors.StringArray
+}
+
+func (c *stringLastValueCursor) Next() *cursors.StringArray {
+	a := c.a
+	c.a = &cursors.StringArray{}
+	return a
+}
+
+type booleanLastValueCursor struct {
+	lastValueCursor
+	a *cursors.BooleanArray
+}
+
+func (c *booleanLastValueCursor) Next() *cursors.BooleanArray {
+	a := c.a
+	c.a = &cursors.BooleanArray{}
+	return a
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestEngine_LastValueCache(t *testing.T) {
+	const org, bucketA, bucketB = influxdb.ID(0x1000), influxdb.ID(0x2000), influxdb.ID(0x3000)
+
+	dir, err := ioutil.TempDir("", "storage_last_values_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buckets := NewTestBucketFinder()
+	buckets.FindBucketsFn = func(context.Context, influxdb.BucketFilter, ...influxdb.FindOptions) ([]*influxdb.Bucket, int, error) {
+		return []*influxdb.Bucket{
+			{OrgID: org, ID: bucketA, LastValueCache: true},
+			{OrgID: org, ID: bucketB},
+		}, 2, nil
+	}
+
+	e := NewEngine(dir, NewConfig(), WithEngineID(0), WithNodeID(0), WithLastValueCache(buckets))
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	tags := models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", "host": "a", models.FieldKeyTagKey: "value"})
+	write := func(bucket influxdb.ID, ts int64, v float64) {
+		t.Helper()
+		pt := models.MustNewPoint(tsdb.EncodeNameString(org, bucket), tags, models.Fields{"value": v}, time.Unix(0, ts))
+		if err := e.WritePoints(context.Background(), []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// last returns the last value of the series in [start, end), or nil.
+	last := func(bucket influxdb.ID, start, end int64) value.Value {
+		t.Helper()
+		itr, err := e.CreateLastCursorIterator(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := tsdb.EncodeName(org, bucket)
+		cur, err := itr.Next(context.Background(), &cursors.CursorRequest{
+			Name:      name[:],
+			Tags:      tags,
+			Field:     "value",
+			StartTime: start - 1,
+			EndTime:   end - 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if cur == nil {
+			return nil
+		}
+		defer cur.Close()
+		return firstValue(cur)
+	}
+	cached := func(bucket influxdb.ID) value.Value {
+		name := tsdb.EncodeName(org, bucket)
+		key := tsm1.AppendSeriesFieldKeyBytes(nil, models.MakeKey(name[:], tags), []byte("value"))
+		v, _ := e.lastValues.get(name[:], key)
+		return v
+	}
+	expLast := func(v value.Value, ts int64, exp float64) {
+		t.Helper()
+		if v == nil {
+			t.Fatalf("expected last value at %d, got none", ts)
+		} else if v.UnixNano() != ts || v.Value() != exp {
+			t.Fatalf("unexpected last value: got %v, exp %v at %d", v, exp, ts)
+		}
+	}
+
+	// Values stored before the bucket is read are read from storage first.
+	for _, bucket := range []influxdb.ID{bucketA, bucketB} {
+		write(bucket, 1, 1)
+		if err := e.engine.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+			t.Fatal(err)
+		}
+		write(bucket, 2, 2)
+	}
+	if v := cached(bucketA); v != nil {
+		t.Fatalf("unexpected cached value before read: %v", v)
+	}
+	expLast(last(bucketA, 0, 10), 2, 2)
+	expLast(cached(bucketA), 2, 2)
+
+	// Later writes replace the cached value.
+	write(bucketA, 3, 3)
+	expLast(cached(bucketA), 3, 3)
+	expLast(last(bucketA, 0, 10), 3, 3)
+
+	// Time ranges ending before the last value are read from storage.
+	expLast(last(bucketA, 0, 3), 2, 2)
+	expLast(last(bucketA, 2, 3), 2, 2)
+	if v := last(bucketA, 4, 10); v != nil {
+		t.Fatalf("unexpected last value after last write: %v", v)
+	}
+
+	// Buckets without a last-value cache are read from storage.
+	expLast(last(bucketB, 0, 10), 2, 2)
+	expLast(last(bucketB, 0, 2), 1, 1)
+	if v := cached(bucketB); v != nil {
+		t.Fatalf("unexpected cached value of bucket without cache: %v", v)
+	}
+
+	// Deletes drop the cached values, and older values written since are
+	// merged with the last value in storage.
+	if err := e.DeleteBucketRange(context.Background(), org, bucketA, 3, 3); err != nil {
+		t.Fatal(err)
+	} else if v := cached(bucketA); v != nil {
+		t.Fatalf("unexpected cached value after delete: %v", v)
+	}
+	write(bucketA, 0, 10)
+	expLast(last(bucketA, 0, 10), 2, 2)
+	expLast(cached(bucketA), 2, 2)
+
+	// Restores drop the cached values too.
+	name := tsdb.EncodeName(org, bucketA)
+	key := tsm1.AppendSeriesFieldKeyBytes(nil, models.MakeKey(name[:], tags), []byte("value"))
+	if err := e.RestoreTSMFile(context.Background(), tsmFile(t, map[string][]tsm1.Value{
+		string(key): {tsm1.NewValue(5, 5.0)},
+	})); err != nil {
+		t.Fatal(err)
+	} else if v := cached(bucketA); v != nil {
+		t.Fatalf("unexpected cached value after restore: %v", v)
+	}
+	expLast(last(bucketA, 0, 10), 5, 5)
+}
@@ -11,8 +11,11 @@ import (
 // storage.Engine instantiations. This allows multiple Engines to be
 // monitored within the same process.
 var (
-	rms *retentionMetrics
-	mmu sync.RWMutex
+	rms   *retentionMetrics
+	slms  *seriesLimitMetrics
+	sgcms *seriesGCMetrics
+	scms  *scrubMetrics
+	mmu   sync.RWMutex
 )
 
 // RetentionPrometheusCollectors returns all prometheus metrics for retention.
@@ -27,10 +30,52 @@ func RetentionPrometheusCollectors() []prometheus.Collector {
 	return collectors
 }
 
+// SeriesLimitPrometheusCollectors returns all prometheus metrics for series limits.
+func SeriesLimitPrometheusCollectors() []prometheus.Collector {
+	mmu.RLock()
+	defer mmu.RUnlock()
+
+	var collectors []prometheus.Collector
+	if slms != nil {
+		collectors = append(collectors, slms.PrometheusCollectors()...)
+	}
+	return collectors
+}
+
+// SeriesGCPrometheusCollectors returns all prometheus metrics for the series
+// garbage collector.
+func SeriesGCPrometheusCollectors() []prometheus.Collector {
+	mmu.RLock()
+	defer mmu.RUnlock()
+
+	var collectors []prometheus.Collector
+	if sgcms != nil {
+		collectors = append(collectors, sgcms.PrometheusCollectors()...)
+	}
+	return collectors
+}
+
+// ScrubPrometheusCollectors returns all prometheus metrics for the scrubber.
+func ScrubPrometheusCollectors() []prometheus.Collector {
+	mmu.RLock()
+	defer mmu.RUnlock()
+
+	var collectors []prometheus.Collector
+	if scms != nil {
+		collectors = append(collectors, scms.PrometheusCollectors()...)
+	}
+	return collectors
+}
+
 // namespace is the leading part of all published metrics for the Storage service.
 const namespace = "storage"
 
-const retentionSubsystem = "retention" // sub-system associated with metrics for writing points.
+const (
+	retentionSubsystem   = "retention"     // sub-system associated with metrics for writing points.
+	seriesLimitSubsystem = "series_limits" // sub-system associated with metrics for series limits.
+	seriesGCSubsystem    = "series_gc"     // sub-system associated with metrics for the series garbage collector.
+	scrubSubsystem       = "scrub"         // sub-system associated with metrics for the scrubber.
+)
 
 // retentionMetrics is a set of metrics concerned with tracking data about retention policies.
 type retentionMetrics struct {
@@ -88,3 +133,203 @@ func (rm *retentionMetrics) PrometheusCollectors() []prometheus.Collector {
 		rm.CheckDuration,
 	}
 }
+
+// seriesLimitMetrics is a set of metrics concerned with tracking the points
+// rejected by series limits.
+type seriesLimitMetrics struct {
+	labels         prometheus.Labels
+	RejectedPoints *prometheus.CounterVec
+}
+
+func newSeriesLimitMetrics(labels prometheus.Labels) *seriesLimitMetrics {
+	var names []string
+	for k := range labels {
+		names = append(names, k)
+	}
+	names = append(names, "bucket", "limit")
+	sort.Strings(names)
+
+	return &seriesLimitMetrics{
+		labels: labels,
+		RejectedPoints: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: seriesLimitSubsystem,
+			Name:      "rejected_points_total",
+			Help:      "Number of points rejected because they would have created series beyond the series limit of their bucket or organization.",
+		}, names),
+	}
+}
+
+// Labels returns a copy of labels for use with series limit metrics.
+func (m *seriesLimitMetrics) Labels() prometheus.Labels {
+	l := make(map[string]string, len(m.labels))
+	for k, v := range m.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *seriesLimitMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.RejectedPoints,
+	}
+}
+
+// seriesGCMetrics is a set of metrics concerned with tracking the runs of the
+// series garbage collector.
+type seriesGCMetrics struct {
+	labels          prometheus.Labels
+	Runs            *prometheus.CounterVec
+	RunDuration     *prometheus.HistogramVec
+	SeriesRemoved   *prometheus.CounterVec
+	SegmentsRemoved *prometheus.CounterVec
+}
+
+func newSeriesGCMetrics(labels prometheus.Labels) *seriesGCMetrics {
+	var names []string
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	statusNames := append(append([]string(nil), names...), "status")
+	sort.Strings(statusNames)
+
+	return &seriesGCMetrics{
+		labels: labels,
+		Runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: seriesGCSubsystem,
+			Name:      "runs_total",
+			Help:      "Number of series garbage collector runs.",
+		}, statusNames),
+
+		RunDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: seriesGCSubsystem,
+			Name:      "run_duration_seconds",
+			Help:      "Time taken by a series garbage collector run.",
+			// 25 buckets spaced exponentially between 1s and ~16m
+			Buckets: prometheus.ExponentialBuckets(1, 1.32, 25),
+		}, statusNames),
+
+		SeriesRemoved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: seriesGCSubsystem,
+			Name:      "series_removed_total",
+			Help:      "Number of series without data removed from the index and series file.",
+		}, names),
+
+		SegmentsRemoved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: seriesGCSubsystem,
+			Name:      "segments_removed_total",
+			Help:      "Number of series file segments removed after compacting the series file.",
+		}, names),
+	}
+}
+
+// Labels returns a copy of labels for use with series garbage collector metrics.
+func (m *seriesGCMetrics) Labels() prometheus.Labels {
+	l := make(map[string]string, len(m.labels))
+	for k, v := range m.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *seriesGCMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.Runs,
+		m.RunDuration,
+		m.SeriesRemoved,
+		m.SegmentsRemoved,
+	}
+}
+
+// scrubMetrics is a set of metrics concerned with tracking the runs of the
+// scrubber and the corruption it finds.
+type scrubMetrics struct {
+	labels      prometheus.Labels
+	Runs        *prometheus.CounterVec
+	RunDuration *prometheus.HistogramVec
+	Bytes       *prometheus.CounterVec
+	Corrupt     *prometheus.GaugeVec
+	Quarantined *prometheus.CounterVec
+}
+
+func newScrubMetrics(labels prometheus.Labels) *scrubMetrics {
+	var names []string
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	statusNames := append(append([]string(nil), names...), "status")
+	sort.Strings(statusNames)
+
+	fileNames := append(append([]string(nil), names...), "file")
+	sort.Strings(fileNames)
+
+	return &scrubMetrics{
+		labels: labels,
+		Runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: scrubSubsystem,
+			Name:      "runs_total",
+			Help:      "Number of scrubber runs.",
+		}, statusNames),
+
+		RunDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: scrubSubsystem,
+			Name:      "run_duration_seconds",
+			Help:      "Time taken by a scrubber run.",
+			// 25 buckets spaced exponentially between 1s and ~16m
+			Buckets: prometheus.ExponentialBuckets(1, 1.32, 25),
+		}, statusNames),
+
+		Bytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: scrubSubsystem,
+			Name:      "bytes_total",
+			Help:      "Number of bytes of TSM and series files verified by the scrubber.",
+		}, fileNames),
+
+		Corrupt: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: scrubSubsystem,
+			Name:      "corrupt_files",
+			Help:      "Number of corrupt TSM files and series partitions found by the scrubber.",
+		}, fileNames),
+
+		Quarantined: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: scrubSubsystem,
+			Name:      "quarantined_files_total",
+			Help:      "Number of corrupt TSM files removed from use by the scrubber.",
+		}, names),
+	}
+}
+
+// Labels returns a copy of labels for use with scrubber metrics.
+func (m *scrubMetrics) Labels() prometheus.Labels {
+	l := make(map[string]string, len(m.labels))
+	for k, v := range m.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// PrometheusCollectors satisfies the prom.PrometheusCollector interface.
+func (m *scrubMetrics) PrometheusCollectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.Runs,
+		m.RunDuration,
+		m.Bytes,
+		m.Corrupt,
+		m.Quarantined,
+	}
+}
@@ -8,6 +8,7 @@ package reads
 
 import (
 	"errors"
+	"math"
 
 	"github.com/influxdata/influxdb/tsdb/cursors"
 )
@@ -132,6 +133,19 @@ func (c *floatMultiShardArrayCursor) Next() *cursors.FloatArray {
 	}
 }
 
+// NextStats returns the statistics of the next block of values of the current
+// cursor, if the values are neither filtered nor limited.
+func (c *floatMultiShardArrayCursor) NextStats() (cursors.FloatBlockStats, bool) {
+	if c.filter != nil || c.limit != math.MaxInt64 {
+		return cursors.FloatBlockStats{}, false
+	}
+	cur, ok := c.FloatArrayCursor.(cursors.FloatArrayStatsCursor)
+	if !ok {
+		return cursors.FloatBlockStats{}, false
+	}
+	return cur.NextStats()
+}
+
 func (c *floatMultiShardArrayCursor) nextArrayCursor() bool {
 	if len(c.itrs) == 0 {
 		return false
@@ -186,26 +200,46 @@ func newFloatArraySumCursor(cur cursors.FloatArrayCursor) *floatArraySumCursor {
 func (c floatArraySumCursor) Stats() cursors.CursorStats { return c.FloatArrayCursor.Stats() }
 
 func (c floatArraySumCursor) Next() *cursors.FloatArray {
-	a := c.FloatArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return a
-	}
+	sc, _ := c.FloatArrayCursor.(cursors.FloatArrayStatsCursor)
 
-	ts := a.Timestamps[0]
-	var acc float64
+	var (
+		ts  int64 = math.MaxInt64
+		acc float64
+		ok  bool
+	)
 
 	for {
-		for _, v := range a.Values {
-			acc += v
+		// Blocks with statistics are summed without reading their values.
+		if sc != nil {
+			if s, sok := sc.NextStats(); sok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Sum
+				ok = true
+				continue
+			}
 		}
-		a = c.FloatArrayCursor.Next()
+
+		a := c.FloatArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if !ok {
+				return a
+			}
 			c.ts[0] = ts
 			c.vs[0] = acc
 			c.res.Timestamps = c.ts[:]
 			c.res.Values = c.vs[:]
 			return c.res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		for _, v := range a.Values {
+			acc += v
+		}
+		ok = true
 	}
 }
 
@@ -218,22 +252,40 @@ func (c *integerFloatCountArrayCursor) Stats() cursors.CursorStats {
 }
 
 func (c *integerFloatCountArrayCursor) Next() *cursors.IntegerArray {
-	a := c.FloatArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return &cursors.IntegerArray{}
-	}
+	sc, _ := c.FloatArrayCursor.(cursors.FloatArrayStatsCursor)
+
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+	)
 
-	ts := a.Timestamps[0]
-	var acc int64
 	for {
-		acc += int64(len(a.Timestamps))
-		a = c.FloatArrayCursor.Next()
+		// Blocks with statistics are counted without reading their values.
+		if sc != nil {
+			if s, ok := sc.NextStats(); ok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Count
+				continue
+			}
+		}
+
+		a := c.FloatArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if acc == 0 {
+				return &cursors.IntegerArray{}
+			}
 			res := cursors.NewIntegerArrayLen(1)
 			res.Timestamps[0] = ts
 			res.Values[0] = acc
 			return res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		acc += int64(len(a.Timestamps))
 	}
 }
 
@@ -361,6 +413,19 @@ func (c *integerMultiShardArrayCursor) Next() *cursors.IntegerArray {
 	}
 }
 
+// NextStats returns the statistics of the next block of values of the current
+// cursor, if the values are neither filtered nor limited.
+func (c *integerMultiShardArrayCursor) NextStats() (cursors.IntegerBlockStats, bool) {
+	if c.filter != nil || c.limit != math.MaxInt64 {
+		return cursors.IntegerBlockStats{}, false
+	}
+	cur, ok := c.IntegerArrayCursor.(cursors.IntegerArrayStatsCursor)
+	if !ok {
+		return cursors.IntegerBlockStats{}, false
+	}
+	return cur.NextStats()
+}
+
 func (c *integerMultiShardArrayCursor) nextArrayCursor() bool {
 	if len(c.itrs) == 0 {
 		return false
@@ -415,26 +480,46 @@ func newIntegerArraySumCursor(cur cursors.IntegerArrayCursor) *integerArraySumCu
 func (c integerArraySumCursor) Stats() cursors.CursorStats { return c.IntegerArrayCursor.Stats() }
 
 func (c integerArraySumCursor) Next() *cursors.IntegerArray {
-	a := c.IntegerArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return a
-	}
+	sc, _ := c.IntegerArrayCursor.(cursors.IntegerArrayStatsCursor)
 
-	ts := a.Timestamps[0]
-	var acc int64
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+		ok  bool
+	)
 
 	for {
-		for _, v := range a.Values {
-			acc += v
+		// Blocks with statistics are summed without reading their values.
+		if sc != nil {
+			if s, sok := sc.NextStats(); sok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Sum
+				ok = true
+				continue
+			}
 		}
-		a = c.IntegerArrayCursor.Next()
+
+		a := c.IntegerArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if !ok {
+				return a
+			}
 			c.ts[0] = ts
 			c.vs[0] = acc
 			c.res.Timestamps = c.ts[:]
 			c.res.Values = c.vs[:]
 			return c.res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		for _, v := range a.Values {
+			acc += v
+		}
+		ok = true
 	}
 }
 
@@ -447,22 +532,40 @@ func (c *integerIntegerCountArrayCursor) Stats() cursors.CursorStats {
 }
 
 func (c *integerIntegerCountArrayCursor) Next() *cursors.IntegerArray {
-	a := c.IntegerArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return &cursors.IntegerArray{}
-	}
+	sc, _ := c.IntegerArrayCursor.(cursors.IntegerArrayStatsCursor)
+
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+	)
 
-	ts := a.Timestamps[0]
-	var acc int64
 	for {
-		acc += int64(len(a.Timestamps))
-		a = c.IntegerArrayCursor.Next()
+		// Blocks with statistics are counted without reading their values.
+		if sc != nil {
+			if s, ok := sc.NextStats(); ok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Count
+				continue
+			}
+		}
+
+		a := c.IntegerArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if acc == 0 {
+				return &cursors.IntegerArray{}
+			}
 			res := cursors.NewIntegerArrayLen(1)
 			res.Timestamps[0] = ts
 			res.Values[0] = acc
 			return res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		acc += int64(len(a.Timestamps))
 	}
 }
 
@@ -590,6 +693,19 @@ func (c *unsignedMultiShardArrayCursor) Next() *cursors.UnsignedArray {
 	}
 }
 
+// NextStats returns the statistics of the next block of values of the current
+// cursor, if the values are neither filtered nor limited.
+func (c *unsignedMultiShardArrayCursor) NextStats() (cursors.UnsignedBlockStats, bool) {
+	if c.filter != nil || c.limit != math.MaxInt64 {
+		return cursors.UnsignedBlockStats{}, false
+	}
+	cur, ok := c.UnsignedArrayCursor.(cursors.UnsignedArrayStatsCursor)
+	if !ok {
+		return cursors.UnsignedBlockStats{}, false
+	}
+	return cur.NextStats()
+}
+
 func (c *unsignedMultiShardArrayCursor) nextArrayCursor() bool {
 	if len(c.itrs) == 0 {
 		return false
@@ -644,26 +760,46 @@ func newUnsignedArraySumCursor(cur cursors.UnsignedArrayCursor) *unsignedArraySu
 func (c unsignedArraySumCursor) Stats() cursors.CursorStats { return c.UnsignedArrayCursor.Stats() }
 
 func (c unsignedArraySumCursor) Next() *cursors.UnsignedArray {
-	a := c.UnsignedArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return a
-	}
+	sc, _ := c.UnsignedArrayCursor.(cursors.UnsignedArrayStatsCursor)
 
-	ts := a.Timestamps[0]
-	var acc uint64
+	var (
+		ts  int64 = math.MaxInt64
+		acc uint64
+		ok  bool
+	)
 
 	for {
-		for _, v := range a.Values {
-			acc += v
+		// Blocks with statistics are summed without reading their values.
+		if sc != nil {
+			if s, sok := sc.NextStats(); sok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Sum
+				ok = true
+				continue
+			}
 		}
-		a = c.UnsignedArrayCursor.Next()
+
+		a := c.UnsignedArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if !ok {
+				return a
+			}
 			c.ts[0] = ts
 			c.vs[0] = acc
 			c.res.Timestamps = c.ts[:]
 			c.res.Values = c.vs[:]
 			return c.res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		for _, v := range a.Values {
+			acc += v
+		}
+		ok = true
 	}
 }
 
@@ -676,22 +812,40 @@ func (c *integerUnsignedCountArrayCursor) Stats() cursors.CursorStats {
 }
 
 func (c *integerUnsignedCountArrayCursor) Next() *cursors.IntegerArray {
-	a := c.UnsignedArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return &cursors.IntegerArray{}
-	}
+	sc, _ := c.UnsignedArrayCursor.(cursors.UnsignedArrayStatsCursor)
+
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+	)
 
-	ts := a.Timestamps[0]
-	var acc int64
 	for {
-		acc += int64(len(a.Timestamps))
-		a = c.UnsignedArrayCursor.Next()
+		// Blocks with statistics are counted without reading their values.
+		if sc != nil {
+			if s, ok := sc.NextStats(); ok {
+				if s.MinTime < ts {
+					ts = s.MinTime
+				}
+				acc += s.Count
+				continue
+			}
+		}
+
+		a := c.UnsignedArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if acc == 0 {
+				return &cursors.IntegerArray{}
+			}
 			res := cursors.NewIntegerArrayLen(1)
 			res.Timestamps[0] = ts
 			res.Values[0] = acc
 			return res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		acc += int64(len(a.Timestamps))
 	}
 }
 
@@ -865,22 +1019,28 @@ func (c *integerStringCountArrayCursor) Stats() cursors.CursorStats {
 }
 
 func (c *integerStringCountArrayCursor) Next() *cursors.IntegerArray {
-	a := c.StringArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return &cursors.IntegerArray{}
-	}
 
-	ts := a.Timestamps[0]
-	var acc int64
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+	)
+
 	for {
-		acc += int64(len(a.Timestamps))
-		a = c.StringArrayCursor.Next()
+		a := c.StringArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if acc == 0 {
+				return &cursors.IntegerArray{}
+			}
 			res := cursors.NewIntegerArrayLen(1)
 			res.Timestamps[0] = ts
 			res.Values[0] = acc
 			return res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		acc += int64(len(a.Timestamps))
 	}
 }
 
@@ -1054,22 +1214,28 @@ func (c *integerBooleanCountArrayCursor) Stats() cursors.CursorStats {
 }
 
 func (c *integerBooleanCountArrayCursor) Next() *cursors.IntegerArray {
-	a := c.BooleanArrayCursor.Next()
-	if len(a.Timestamps) == 0 {
-		return &cursors.IntegerArray{}
-	}
 
-	ts := a.Timestamps[0]
-	var acc int64
+	var (
+		ts  int64 = math.MaxInt64
+		acc int64
+	)
+
 	for {
-		acc += int64(len(a.Timestamps))
-		a = c.BooleanArrayCursor.Next()
+		a := c.BooleanArrayCursor.Next()
 		if len(a.Timestamps) == 0 {
+			if acc == 0 {
+				return &cursors.IntegerArray{}
+			}
 			res := cursors.NewIntegerArrayLen(1)
 			res.Timestamps[0] = ts
 			res.Values[0] = acc
 			return res
 		}
+
+		if a.Timestamps[0] < ts {
+			ts = a.Timestamps[0]
+		}
+		acc += int64(len(a.Timestamps))
 	}
 }
 
@@ -0,0 +1,71 @@
+package reads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+// floatStatsCursor returns the statistics of its blocks before its arrays.
+type floatStatsCursor struct {
+	stats  []cursors.FloatBlockStats
+	arrays []*cursors.FloatArray
+}
+
+func (c *floatStatsCursor) Close()                     {}
+func (c *floatStatsCursor) Err() error                 { return nil }
+func (c *floatStatsCursor) Stats() cursors.CursorStats { return cursors.CursorStats{} }
+
+func (c *floatStatsCursor) NextStats() (cursors.FloatBlockStats, bool) {
+	if len(c.stats) == 0 {
+		return cursors.FloatBlockStats{}, false
+	}
+	s := c.stats[0]
+	c.stats = c.stats[1:]
+	return s, true
+}
+
+func (c *floatStatsCursor) Next() *cursors.FloatArray {
+	if len(c.arrays) == 0 {
+		return &cursors.FloatArray{}
+	}
+	a := c.arrays[0]
+	c.arrays = c.arrays[1:]
+	return a
+}
+
+func newFloatStatsCursor() *floatStatsCursor {
+	return &floatStatsCursor{
+		stats: []cursors.FloatBlockStats{
+			{MinTime: 20, MaxTime: 29, Count: 10, Sum: 5},
+			{MinTime: 30, MaxTime: 39, Count: 5, Sum: 2.5},
+		},
+		arrays: []*cursors.FloatArray{
+			{Timestamps: []int64{10, 11}, Values: []float64{1, 2}},
+			{Timestamps: []int64{40}, Values: []float64{3}},
+		},
+	}
+}
+
+func TestFloatArraySumCursor_BlockStats(t *testing.T) {
+	a := newSumArrayCursor(newFloatStatsCursor()).(cursors.FloatArrayCursor).Next()
+	if a.Len() != 1 || a.Timestamps[0] != 10 || a.Values[0] != 13.5 {
+		t.Fatalf("unexpected sum: got %v %v, exp [10] [13.5]", a.Timestamps, a.Values)
+	}
+}
+
+func TestFloatArrayCountCursor_BlockStats(t *testing.T) {
+	a := newCountArrayCursor(newFloatStatsCursor()).(cursors.IntegerArrayCursor).Next()
+	if a.Len() != 1 || a.Timestamps[0] != 10 || a.Values[0] != 18 {
+		t.Fatalf("unexpected count: got %v %v, exp [10] [18]", a.Timestamps, a.Values)
+	}
+}
+
+func TestFloatMultiShardArrayCursor_NextStats(t *testing.T) {
+	m := newMultiShardArrayCursors(context.Background(), 0, 100, true, 1)
+	m.cursors.f.reset(newFloatStatsCursor(), nil, nil)
+	if _, ok := m.cursors.f.NextStats(); ok {
+		t.Fatal("unexpected statistics of limited cursor")
+	}
+}
@@ -51,6 +51,17 @@ func (r *storeReader) ReadGroup(ctx context.Context, spec influxdb.ReadGroupSpec
 	}, nil
 }
 
+func (r *storeReader) ReadLast(ctx context.Context, spec influxdb.ReadLastSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
+	return &filterIterator{
+		ctx:   ctx,
+		s:     r.s,
+		spec:  spec.ReadFilterSpec,
+		last:  true,
+		cache: newTagsCache(0),
+		alloc: alloc,
+	}, nil
+}
+
 func (r *storeReader) ReadTagKeys(ctx context.Context, spec influxdb.ReadTagKeysSpec, alloc *memory.Allocator) (influxdb.TableIterator, error) {
 	var predicate *datatypes.Predicate
 	if spec.Predicate != nil {
@@ -97,6 +108,7 @@ type filterIterator struct {
 	ctx   context.Context
 	s     Store
 	spec  influxdb.ReadFilterSpec
+	last  bool // Only read the last value of each series.
 	stats cursors.CursorStats
 	cache *tagsCache
 	alloc *memory.Allocator
@@ -131,7 +143,12 @@ func (fi *filterIterator) Do(f func(flux.Table) error) error {
 	req.Range.Start = int64(fi.spec.Bounds.Start)
 	req.Range.End = int64(fi.spec.Bounds.Stop)
 
-	rs, err := fi.s.ReadFilter(fi.ctx, &req)
+	var rs ResultSet
+	if fi.last {
+		rs, err = fi.s.ReadLast(fi.ctx, &req)
+	} else {
+		rs, err = fi.s.ReadFilter(fi.ctx, &req)
+	}
 	if err != nil {
 		return err
 	}
@@ -30,6 +30,22 @@ func NewFilteredResultSet(ctx context.Context, req *datatypes.ReadFilterRequest,
 	}
 }
 
+// NewLastResultSet returns a result set of the last value of each series of
+// cur in the time range of req.
+func NewLastResultSet(ctx context.Context, req *datatypes.ReadFilterRequest, cur SeriesCursor) ResultSet {
+	// Descending cursors exclude their start time and include their end time,
+	// whereas the end time of req is excluded.
+	start, end := req.Range.Start, req.Range.End-1
+	if start > math.MinInt64 {
+		start--
+	}
+	return &resultSet{
+		ctx: ctx,
+		cur: cur,
+		mb:  newMultiShardArrayCursors(ctx, start, end, false, 1),
+	}
+}
+
 func (r *resultSet) Err() error { return nil }
 
 // Close closes the result set. Close is idempotent.
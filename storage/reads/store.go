@@ -77,6 +77,10 @@ type Store interface {
 	ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest) (ResultSet, error)
 	ReadGroup(ctx context.Context, req *datatypes.ReadGroupRequest) (GroupResultSet, error)
 
+	// ReadLast reads the last value of each series matching req in its time
+	// range.
+	ReadLast(ctx context.Context, req *datatypes.ReadFilterRequest) (ResultSet, error)
+
 	TagKeys(ctx context.Context, req *datatypes.TagKeysRequest) (cursors.StringIterator, error)
 	TagValues(ctx context.Context, req *datatypes.TagValuesRequest) (cursors.StringIterator, error)
 
@@ -35,7 +35,13 @@ type indexSeriesCursor struct {
 }
 
 func newIndexSeriesCursor(ctx context.Context, src *readSource, predicate *datatypes.Predicate, viewer Viewer) (*indexSeriesCursor, error) {
-	queries, err := viewer.CreateCursorIterator(ctx)
+	return newIndexSeriesCursorWithIterator(ctx, src, predicate, viewer, viewer.CreateCursorIterator)
+}
+
+// newIndexSeriesCursorWithIterator returns a cursor of the series matching
+// predicate, whose data is read with the cursor iterator of createIterator.
+func newIndexSeriesCursorWithIterator(ctx context.Context, src *readSource, predicate *datatypes.Predicate, viewer Viewer, createIterator func(context.Context) (tsdb.CursorIterator, error)) (*indexSeriesCursor, error) {
+	queries, err := createIterator(ctx)
 	if err != nil {
 		return nil, err
 	}
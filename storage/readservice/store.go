@@ -20,6 +20,7 @@ import (
 // Viewer is used by the store to query data from time-series files.
 type Viewer interface {
 	CreateCursorIterator(ctx context.Context) (tsdb.CursorIterator, error)
+	CreateLastCursorIterator(ctx context.Context) (tsdb.CursorIterator, error)
 	CreateSeriesCursor(ctx context.Context, req storage.SeriesCursorRequest, cond influxql.Expr) (storage.SeriesCursor, error)
 	TagKeys(ctx context.Context, orgID, bucketID influxdb.ID, start, end int64, predicate influxql.Expr) (cursors.StringIterator, error)
 	TagValues(ctx context.Context, orgID, bucketID influxdb.ID, tagKey string, start, end int64, predicate influxql.Expr) (cursors.StringIterator, error)
@@ -56,6 +57,44 @@ func (s *store) ReadFilter(ctx context.Context, req *datatypes.ReadFilterRequest
 	return reads.NewFilteredResultSet(ctx, req, cur), nil
 }
 
+// ReadLast reads the last value of each series matching the request. The
+// values of buckets with a last-value cache are read from the cache, unless
+// the predicate filters field values.
+func (s *store) ReadLast(ctx context.Context, req *datatypes.ReadFilterRequest) (reads.ResultSet, error) {
+	if req.ReadSource == nil {
+		return nil, errors.New("missing read source")
+	}
+
+	source, err := getReadSource(*req.ReadSource)
+	if err != nil {
+		return nil, err
+	}
+
+	createIterator := s.viewer.CreateLastCursorIterator
+	if root := req.Predicate.GetRoot(); root != nil {
+		expr, err := reads.NodeToExpr(root, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		// The last value of a series may not match the field value condition.
+		if reads.HasFieldValueKey(expr) {
+			createIterator = s.viewer.CreateCursorIterator
+		}
+	}
+
+	var cur reads.SeriesCursor
+	if ic, err := newIndexSeriesCursorWithIterator(ctx, &source, req.Predicate, s.viewer, createIterator); err != nil {
+		return nil, err
+	} else if ic == nil {
+		return nil, nil
+	} else {
+		cur = ic
+	}
+
+	return reads.NewLastResultSet(ctx, req, cur), nil
+}
+
 func (s *store) ReadGroup(ctx context.Context, req *datatypes.ReadGroupRequest) (reads.GroupResultSet, error) {
 	if req.ReadSource == nil {
 		return nil, errors.New("missing read source")
@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+)
+
+// Restorer defines the behaviour of loading backed up data into the engine.
+type Restorer interface {
+	RestoreBucket(ctx context.Context, srcOrgID, srcBucketID, dstOrgID, dstBucketID platform.ID, r io.Reader) error
+	RestoreTSMFile(ctx context.Context, r io.Reader) error
+}
+
+var _ platform.RestoreService = (*RestoreService)(nil)
+
+// RestoreService restores backups into a running instance. Bucket metadata is
+// restored through the platform services, the metadata database through the
+// KVRestoreService and data through the Restorer, which typically will be an Engine.
+type RestoreService struct {
+	BucketService              platform.BucketService
+	LabelService               platform.LabelService
+	UserResourceMappingService platform.UserResourceMappingService
+	KVRestoreService           platform.KVRestoreService
+
+	engine Restorer
+}
+
+// NewRestoreService returns a new RestoreService for the provided Restorer.
+func NewRestoreService(engine Restorer, kvs platform.KVRestoreService, bs platform.BucketService, ls platform.LabelService, us platform.UserResourceMappingService) *RestoreService {
+	return &RestoreService{
+		BucketService:              bs,
+		LabelService:               ls,
+		UserResourceMappingService: us,
+		KVRestoreService:           kvs,
+		engine:                     engine,
+	}
+}
+
+// RestoreBucketMetadata creates a new bucket from m. Labels that do not exist in
+// the organization of the bucket are created.
+func (s *RestoreService) RestoreBucketMetadata(ctx context.Context, m platform.BucketMetadataBackup) (*platform.Bucket, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	b := &platform.Bucket{
+		OrgID:               m.Bucket.OrgID,
+		Type:                m.Bucket.Type,
+		Name:                m.Bucket.Name,
+		Description:         m.Bucket.Description,
+		RetentionPolicyName: m.Bucket.RetentionPolicyName,
+		RetentionPeriod:     m.Bucket.RetentionPeriod,
+	}
+	if err := s.BucketService.CreateBucket(ctx, b); err != nil {
+		return nil, err
+	}
+
+	for _, l := range m.Labels {
+		label, err := s.findOrCreateLabel(ctx, b.OrgID, l)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.LabelService.CreateLabelMapping(ctx, &platform.LabelMapping{
+			LabelID:      label.ID,
+			ResourceID:   b.ID,
+			ResourceType: platform.BucketsResourceType,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, urm := range m.UserResourceMappings {
+		if err := s.UserResourceMappingService.CreateUserResourceMapping(ctx, &platform.UserResourceMapping{
+			UserID:       urm.UserID,
+			UserType:     urm.UserType,
+			MappingType:  urm.MappingType,
+			ResourceType: platform.BucketsResourceType,
+			ResourceID:   b.ID,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+func (s *RestoreService) findOrCreateLabel(ctx context.Context, orgID platform.ID, l *platform.Label) (*platform.Label, error) {
+	labels, err := s.LabelService.FindLabels(ctx, platform.LabelFilter{Name: l.Name, OrgID: &orgID})
+	if err != nil {
+		return nil, err
+	}
+	if len(labels) > 0 {
+		return labels[0], nil
+	}
+
+	label := &platform.Label{
+		OrgID:      orgID,
+		Name:       l.Name,
+		Properties: l.Properties,
+	}
+	if err := s.LabelService.CreateLabel(ctx, label); err != nil {
+		return nil, err
+	}
+	return label, nil
+}
+
+// RestoreBucket loads the data of the backed up bucket src, read from r, into
+// the bucket with the provided ID.
+func (s *RestoreService) RestoreBucket(ctx context.Context, bucketID platform.ID, src *platform.Bucket, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	b, err := s.BucketService.FindBucketByID(ctx, bucketID)
+	if err != nil {
+		return err
+	}
+	return s.engine.RestoreBucket(ctx, src.OrgID, src.ID, b.OrgID, b.ID, r)
+}
+
+// RestoreTSMFile loads all data of the TSM file read from r into the engine.
+func (s *RestoreService) RestoreTSMFile(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.engine.RestoreTSMFile(ctx, r)
+}
+
+// RestoreKVStore replaces the metadata database with the backup copy read from r.
+func (s *RestoreService) RestoreKVStore(ctx context.Context, r io.Reader) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	return s.KVRestoreService.Restore(ctx, r)
+}
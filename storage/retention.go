@@ -24,6 +24,12 @@ type Deleter interface {
 	DeleteBucketRange(ctx context.Context, orgID, bucketID influxdb.ID, min, max int64) error
 }
 
+// A ColdMover implementation is capable of moving data of a storage engine to
+// cold storage.
+type ColdMover interface {
+	MoveBucketToColdStorage(ctx context.Context, orgID, bucketID influxdb.ID, before int64) error
+}
+
 // A Snapshotter implementation can take snapshots of the entire engine.
 type Snapshotter interface {
 	WriteSnapshot(ctx context.Context, status tsm1.CacheStatus) error
@@ -43,6 +49,10 @@ type retentionEnforcer struct {
 	// Engine provides access to data stored on the engine
 	Engine Deleter
 
+	// ColdMover, if set, moves data that is older than the cold storage period
+	// of its bucket to cold storage.
+	ColdMover ColdMover
+
 	Snapshotter Snapshotter
 
 	// BucketService provides an API for retrieving buckets associated with
@@ -58,13 +68,17 @@ type retentionEnforcer struct {
 // deleted every interval period. Setting interval to 0 is equivalent to
 // disabling the service.
 func newRetentionEnforcer(engine Deleter, snapshotter Snapshotter, bucketService BucketFinder) *retentionEnforcer {
-	return &retentionEnforcer{
+	s := &retentionEnforcer{
 		Engine:        engine,
 		Snapshotter:   snapshotter,
 		BucketService: bucketService,
 		logger:        zap.NewNop(),
 		tracker:       newRetentionTracker(newRetentionMetrics(nil), nil),
 	}
+	if m, ok := engine.(ColdMover); ok {
+		s.ColdMover = m
+	}
+	return s
 }
 
 // SetDefaultMetricLabels sets the default labels for the retention metrics.
@@ -109,6 +123,7 @@ func (s *retentionEnforcer) run() {
 		log.Error("Unable to determine bucket information", zap.Error(err))
 	} else {
 		s.expireData(ctx, buckets, now)
+		s.moveColdData(ctx, buckets, now)
 	}
 	s.tracker.CheckDuration(time.Since(now), err == nil)
 }
@@ -117,6 +132,8 @@ func (s *retentionEnforcer) run() {
 //
 // Any series data that (1) belongs to a bucket in the provided list and
 // (2) falls outside the bucket's indicated retention period will be deleted.
+// TSM files that only hold expired data of a bucket, such as the files of the
+// expired time partitions of the bucket, are removed rather than rewritten.
 func (s *retentionEnforcer) expireData(ctx context.Context, buckets []*influxdb.Bucket, now time.Time) {
 	logger, logEnd := logger.NewOperation(ctx, s.logger, "Data deletion", "data_deletion",
 		zap.Int("buckets", len(buckets)))
@@ -175,6 +192,46 @@ func (s *retentionEnforcer) expireData(ctx context.Context, buckets []*influxdb.
 	}
 }
 
+// moveColdData moves the data of the buckets with a cold storage period that
+// has fallen outside of it to cold storage. Data is moved in whole TSM files,
+// so data only becomes cold once all other data in its file has, which is
+// usually when its time partition has.
+func (s *retentionEnforcer) moveColdData(ctx context.Context, buckets []*influxdb.Bucket, now time.Time) {
+	if s.ColdMover == nil {
+		return
+	}
+
+	logger, logEnd := logger.NewOperation(ctx, s.logger, "Cold storage move", "cold_storage_move",
+		zap.Int("buckets", len(buckets)))
+	defer logEnd()
+
+	for _, b := range buckets {
+		if b.ColdStoragePeriod == 0 || !b.OrgID.Valid() || !b.ID.Valid() {
+			continue
+		}
+
+		before := now.Add(-b.ColdStoragePeriod).UnixNano()
+
+		span, ctx := tracing.StartSpanFromContext(ctx)
+		span.LogKV(
+			"bucket_id", b.ID,
+			"org_id", b.OrgID,
+			"cold_storage_period", b.ColdStoragePeriod,
+			"before", time.Unix(0, before).UTC(),
+		)
+
+		if err := s.ColdMover.MoveBucketToColdStorage(ctx, b.OrgID, b.ID, before); err != nil {
+			logger.Info("Unable to move bucket data to cold storage",
+				zap.String("org_id", b.OrgID.String()),
+				zap.String("bucket_id", b.ID.String()),
+				zap.Time("before", time.Unix(0, before)),
+				zap.Error(err))
+			tracing.LogError(span, err)
+		}
+		span.Finish()
+	}
+}
+
 // getBucketInformation returns a slice of buckets to run retention on.
 func (s *retentionEnforcer) getBucketInformation(ctx context.Context) ([]*influxdb.Bucket, error) {
 	ctx, cancel := context.WithTimeout(ctx, bucketAPITimeout)
@@ -240,6 +240,37 @@ func TestRetentionService(t *testing.T) {
 	})
 }
 
+func TestRetentionService_ColdStorage(t *testing.T) {
+	t.Parallel()
+	engine := &TestColdEngine{TestEngine: NewTestEngine()}
+	service := newRetentionEnforcer(engine, &TestSnapshotter{}, NewTestBucketFinder())
+	if service.ColdMover == nil {
+		t.Fatal("expected engine to be used as cold mover")
+	}
+	now := time.Date(2018, 4, 10, 23, 12, 33, 0, time.UTC)
+
+	buckets := []*influxdb.Bucket{
+		{OrgID: 1, ID: 2, ColdStoragePeriod: time.Hour},
+		{OrgID: 1, ID: 3, RetentionPeriod: 3 * time.Hour},
+		{OrgID: 1, ID: 4, RetentionPeriod: 3 * time.Hour, ColdStoragePeriod: 2 * time.Hour},
+	}
+
+	got := map[influxdb.ID]int64{}
+	engine.MoveBucketToColdStorageFn = func(ctx context.Context, orgID, bucketID influxdb.ID, before int64) error {
+		got[bucketID] = before
+		return nil
+	}
+
+	service.moveColdData(context.Background(), buckets, now)
+	exp := map[influxdb.ID]int64{
+		2: now.Add(-time.Hour).UnixNano(),
+		4: now.Add(-2 * time.Hour).UnixNano(),
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got\n%#v\nexpected\n%#v", got, exp)
+	}
+}
+
 func TestMetrics_Retention(t *testing.T) {
 	t.Parallel()
 	// metrics to be shared by multiple file stores.
@@ -327,6 +358,15 @@ func (e *TestEngine) DeleteBucketRange(ctx context.Context, orgID, bucketID infl
 	return e.DeleteBucketRangeFn(ctx, orgID, bucketID, min, max)
 }
 
+type TestColdEngine struct {
+	*TestEngine
+	MoveBucketToColdStorageFn func(context.Context, influxdb.ID, influxdb.ID, int64) error
+}
+
+func (e *TestColdEngine) MoveBucketToColdStorage(ctx context.Context, orgID, bucketID influxdb.ID, before int64) error {
+	return e.MoveBucketToColdStorageFn(ctx, orgID, bucketID, before)
+}
+
 type TestSnapshotter struct{}
 
 func (s *TestSnapshotter) WriteSnapshot(ctx context.Context, status tsm1.CacheStatus) error {
@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxql"
+	"go.uber.org/zap"
+)
+
+const (
+	// rollupMergeGap is the largest gap between two time ranges written to a
+	// bucket for which they are merged into one.
+	rollupMergeGap = int64(time.Minute)
+
+	// maxRollupRanges is the number of time ranges tracked for a bucket above
+	// which they are all merged into one.
+	maxRollupRanges = 1000
+
+	// rollupBatchSize is the number of rollup points written at once.
+	rollupBatchSize = 5000
+)
+
+// A rollupEngine provides read and write access to the data of a storage
+// engine.
+type rollupEngine interface {
+	CreateSeriesCursor(ctx context.Context, req SeriesCursorRequest, cond influxql.Expr) (SeriesCursor, error)
+	CreateCursorIterator(ctx context.Context) (tsdb.CursorIterator, error)
+	WritePoints(ctx context.Context, points []models.Point) error
+}
+
+// timeRange is a range of time, including both min and max.
+type timeRange struct {
+	min, max int64
+}
+
+// The rollupService maintains the rollup policies of buckets.
+//
+// The time ranges of the data written to each bucket are tracked, and after
+// the cache has been snapshotted the windows of the rollup policies of the
+// bucket overlapping them are recomputed from the raw data. Late writes to a
+// window that has already been rolled up therefore replace its rollups.
+type rollupService struct {
+	// Engine provides access to the data stored on the engine.
+	Engine rollupEngine
+
+	// BucketService provides access to the rollup policies of buckets.
+	BucketService BucketFinder
+
+	mu    sync.Mutex
+	dirty map[[influxdb.IDLength]byte][]timeRange
+
+	runMu   sync.Mutex
+	trigger chan struct{}
+
+	logger *zap.Logger
+}
+
+// newRollupService returns a new service maintaining the rollup policies of
+// the buckets provided by bucketService.
+func newRollupService(engine rollupEngine, bucketService BucketFinder) *rollupService {
+	return &rollupService{
+		Engine:        engine,
+		BucketService: bucketService,
+		dirty:         make(map[[influxdb.IDLength]byte][]timeRange),
+		trigger:       make(chan struct{}, 1),
+		logger:        zap.NewNop(),
+	}
+}
+
+// WithLogger sets the logger l on the service. It must be called before any run calls.
+func (s *rollupService) WithLogger(l *zap.Logger) {
+	if s == nil {
+		return // Not initialised
+	}
+	s.logger = l.With(zap.String("component", "rollups"))
+}
+
+// markDirty records the time ranges of the points in collection, so that the
+// rollups of their windows are recomputed.
+func (s *rollupService) markDirty(collection *tsdb.SeriesCollection) {
+	if s == nil || collection.Length() == 0 {
+		return
+	}
+
+	ranges := make(map[string]timeRange)
+	for i, pt := range collection.Points {
+		name := collection.Names[i]
+		if len(name) != influxdb.IDLength {
+			continue
+		}
+
+		t := pt.UnixNano()
+		r, ok := ranges[string(name)]
+		if !ok {
+			r = timeRange{min: t, max: t}
+		} else if t < r.min {
+			r.min = t
+		} else if t > r.max {
+			r.max = t
+		}
+		ranges[string(name)] = r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, r := range ranges {
+		var key [influxdb.IDLength]byte
+		copy(key[:], name)
+		s.dirty[key] = addTimeRange(s.dirty[key], r)
+	}
+}
+
+// markDirtyRange records the time range r of the data of the bucket with the
+// given name, so that the rollups of its windows are recomputed.
+func (s *rollupService) markDirtyRange(name [influxdb.IDLength]byte, r timeRange) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty[name] = addTimeRange(s.dirty[name], r)
+}
+
+// addTimeRange adds r to the sorted time ranges a, merging it with the ranges
+// it overlaps or that are at most rollupMergeGap apart from it.
+func addTimeRange(a []timeRange, r timeRange) []timeRange {
+	i := sort.Search(len(a), func(i int) bool { return a[i].max >= r.min-rollupMergeGap })
+	j := i
+	for ; j < len(a) && a[j].min <= r.max+rollupMergeGap; j++ {
+		if a[j].min < r.min {
+			r.min = a[j].min
+		}
+		if a[j].max > r.max {
+			r.max = a[j].max
+		}
+	}
+
+	if i == j {
+		a = append(a, timeRange{})
+		copy(a[i+1:], a[i:])
+	} else {
+		a = append(a[:i+1], a[j:]...)
+	}
+	a[i] = r
+
+	if len(a) > maxRollupRanges {
+		a = []timeRange{{min: a[0].min, max: a[len(a)-1].max}}
+	}
+	return a
+}
+
+// notify signals that the cache has been snapshotted.
+func (s *rollupService) notify() {
+	if s == nil {
+		return
+	}
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// run recomputes the rollups of all windows overlapping data written since
+// the last run. Ranges that could not be rolled up are tracked again and
+// retried on the next run.
+func (s *rollupService) run(ctx context.Context) {
+	if s == nil {
+		return // Not initialized
+	}
+
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	s.mu.Lock()
+	dirty := s.dirty
+	s.dirty = make(map[[influxdb.IDLength]byte][]timeRange)
+	s.mu.Unlock()
+	if len(dirty) == 0 {
+		return
+	}
+
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	log, logEnd := logger.NewOperation(ctx, s.logger, "Rollup", "rollup")
+	defer logEnd()
+
+	buckets, err := s.getBucketInformation(ctx)
+	if err != nil {
+		log.Error("Unable to determine bucket information", zap.Error(err))
+		s.restore(dirty)
+		return
+	}
+
+	for _, b := range buckets {
+		if len(b.RollupPolicies) == 0 {
+			continue
+		}
+		name := tsdb.EncodeName(b.OrgID, b.ID)
+		ranges := dirty[name]
+
+		var failed []timeRange
+		for _, r := range ranges {
+			for _, p := range b.RollupPolicies {
+				if err := s.rollup(ctx, b.OrgID, name, p, r); err != nil {
+					log.Info("Unable to roll up bucket data",
+						zap.String("org_id", b.OrgID.String()),
+						zap.String("bucket_id", b.ID.String()),
+						zap.String("target_bucket_id", p.TargetBucketID.String()),
+						zap.Time("min", time.Unix(0, r.min)),
+						zap.Time("max", time.Unix(0, r.max)),
+						zap.Error(err))
+					tracing.LogError(span, err)
+					failed = append(failed, r)
+					break
+				}
+			}
+		}
+		if len(failed) > 0 {
+			s.restore(map[[influxdb.IDLength]byte][]timeRange{name: failed})
+		}
+	}
+}
+
+// restore tracks the time ranges of dirty again.
+func (s *rollupService) restore(dirty map[[influxdb.IDLength]byte][]timeRange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name, ranges := range dirty {
+		for _, r := range ranges {
+			s.dirty[name] = addTimeRange(s.dirty[name], r)
+		}
+	}
+}
+
+// getBucketInformation returns all buckets.
+func (s *rollupService) getBucketInformation(ctx context.Context) ([]*influxdb.Bucket, error) {
+	ctx, cancel := context.WithTimeout(ctx, bucketAPITimeout)
+	defer cancel()
+
+	buckets, _, err := s.BucketService.FindBuckets(ctx, influxdb.BucketFilter{})
+	return buckets, err
+}
+
+// rollup recomputes the rollups of policy p for the windows overlapping r of
+// the bucket with the tsdb encoded name.
+func (s *rollupService) rollup(ctx context.Context, orgID influxdb.ID, name [influxdb.IDLength]byte, p influxdb.RollupPolicy, r timeRange) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	every := int64(p.Every)
+	start, end := windowStart(r.min, every), windowStart(r.max, every)+every
+
+	scur, err := s.Engine.CreateSeriesCursor(ctx, SeriesCursorRequest{Name: name}, nil)
+	if err != nil {
+		return err
+	}
+	defer scur.Close()
+
+	itr, err := s.Engine.CreateCursorIterator(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := &rollupWriter{
+		ctx:       ctx,
+		engine:    s.Engine,
+		name:      tsdb.EncodeNameString(orgID, p.TargetBucketID),
+		functions: p.Functions,
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		row, err := scur.Next()
+		if err != nil {
+			return err
+		} else if row == nil {
+			break
+		}
+
+		field := row.Tags.Get(models.FieldKeyTagKeyBytes)
+		cur, err := itr.Next(ctx, &cursors.CursorRequest{
+			Name:      row.Name,
+			Tags:      row.Tags,
+			Field:     string(field),
+			Ascending: true,
+			StartTime: start,
+			EndTime:   end,
+		})
+		if err != nil {
+			return err
+		} else if cur == nil {
+			continue
+		}
+
+		w.series(row.Tags, string(field))
+		err = w.aggregate(cur, start, end, every)
+		cur.Close()
+		if err != nil {
+			return err
+		}
+	}
+	span.LogKV("points_written", w.n)
+
+	return w.flush()
+}
+
+// windowStart returns the start of the window of the given duration holding t.
+func windowStart(t, every int64) int64 {
+	start := t - t%every
+	if t < 0 && start != t {
+		start -= every
+	}
+	return start
+}
+
+// rollupWindow accumulates the values of a field in a window.
+type rollupWindow struct {
+	start int64
+	count int64
+	typ   models.FieldType
+
+	fsum, fmin, fmax float64
+	isum, imin, imax int64
+	usum, umin, umax uint64
+}
+
+// rollupWriter writes the rollups of a series to the target bucket.
+type rollupWriter struct {
+	ctx       context.Context
+	engine    rollupEngine
+	name      string
+	functions []influxdb.RollupFunction
+
+	tags   models.Tags
+	field  string
+	points []models.Point
+	n      int
+	err    error
+}
+
+// series sets the series whose rollups are written.
+func (w *rollupWriter) series(tags models.Tags, field string) {
+	w.tags = tags.Clone()
+	w.field = field
+}
+
+// aggregate reads the values of cur in [start, end) and accumulates them in
+// windows of the given duration.
+func (w *rollupWriter) aggregate(cur cursors.Cursor, start, end, every int64) error {
+	win := rollupWindow{start: start - 1}
+	add := func(ts int64, typ models.FieldType) bool {
+		if ts < start || ts >= end {
+			return false
+		}
+		if ws := windowStart(ts, every); ws != win.start {
+			w.emit(&win)
+			win = rollupWindow{start: ws, typ: typ}
+		}
+		win.count++
+		return true
+	}
+
+	switch cur := cur.(type) {
+	case cursors.FloatArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if v := a.Values[i]; add(ts, models.Float) {
+					win.fsum += v
+					if win.count == 1 || v < win.fmin {
+						win.fmin = v
+					}
+					if win.count == 1 || v > win.fmax {
+						win.fmax = v
+					}
+				}
+			}
+		}
+	case cursors.IntegerArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if v := a.Values[i]; add(ts, models.Integer) {
+					win.isum += v
+					win.fsum += float64(v)
+					if win.count == 1 || v < win.imin {
+						win.imin = v
+					}
+					if win.count == 1 || v > win.imax {
+						win.imax = v
+					}
+				}
+			}
+		}
+	case cursors.UnsignedArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for i, ts := range a.Timestamps {
+				if v := a.Values[i]; add(ts, models.Unsigned) {
+					win.usum += v
+					win.fsum += float64(v)
+					if win.count == 1 || v < win.umin {
+						win.umin = v
+					}
+					if win.count == 1 || v > win.umax {
+						win.umax = v
+					}
+				}
+			}
+		}
+	case cursors.StringArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for _, ts := range a.Timestamps {
+				add(ts, models.String)
+			}
+		}
+	case cursors.BooleanArrayCursor:
+		for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+			for _, ts := range a.Timestamps {
+				add(ts, models.Boolean)
+			}
+		}
+	}
+	w.emit(&win)
+
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return w.err
+}
+
+// emit adds the rollup points of the window to the batch.
+func (w *rollupWriter) emit(win *rollupWindow) {
+	if win.count == 0 || w.err != nil {
+		return
+	}
+
+	for _, fn := range w.functions {
+		var v interface{}
+		switch fn {
+		case influxdb.RollupCount:
+			v = win.count
+		case influxdb.RollupMean:
+			if win.typ == models.Float || win.typ == models.Integer || win.typ == models.Unsigned {
+				v = win.fsum / float64(win.count)
+			}
+		case influxdb.RollupSum:
+			v = win.pick(win.fsum, win.isum, win.usum)
+		case influxdb.RollupMin:
+			v = win.pick(win.fmin, win.imin, win.umin)
+		case influxdb.RollupMax:
+			v = win.pick(win.fmax, win.imax, win.umax)
+		}
+		if v == nil {
+			continue
+		}
+
+		field := w.field + "_" + string(fn)
+		w.tags[len(w.tags)-1].Value = []byte(field)
+		pt, err := models.NewPoint(w.name, w.tags, models.Fields{field: v}, time.Unix(0, win.start))
+		if err != nil {
+			w.err = err
+			return
+		}
+		w.points = append(w.points, pt)
+	}
+
+	if len(w.points) >= rollupBatchSize {
+		w.err = w.flush()
+	}
+}
+
+// pick returns the value of the type of the window.
+func (win *rollupWindow) pick(f float64, i int64, u uint64) interface{} {
+	switch win.typ {
+	case models.Float:
+		return f
+	case models.Integer:
+		return i
+	case models.Unsigned:
+		return u
+	}
+	return nil
+}
+
+// flush writes the batched points to the engine.
+func (w *rollupWriter) flush() error {
+	if len(w.points) == 0 {
+		return nil
+	}
+	if err := w.engine.WritePoints(w.ctx, w.points); err != nil {
+		return err
+	}
+	w.n += len(w.points)
+	w.points = w.points[:0]
+	return nil
+}
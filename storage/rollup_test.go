@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestEngine_Rollups(t *testing.T) {
+	const org, raw, rollup = influxdb.ID(0x1000), influxdb.ID(0x2000), influxdb.ID(0x3000)
+
+	finder := NewTestBucketFinder()
+	finder.FindBucketsFn = func(context.Context, influxdb.BucketFilter, ...influxdb.FindOptions) ([]*influxdb.Bucket, int, error) {
+		return []*influxdb.Bucket{
+			{OrgID: org, ID: raw, RollupPolicies: []influxdb.RollupPolicy{{
+				Every:          time.Minute,
+				Functions:      []influxdb.RollupFunction{influxdb.RollupMean, influxdb.RollupMax, influxdb.RollupCount},
+				TargetBucketID: rollup,
+			}}},
+			{OrgID: org, ID: rollup},
+		}, 2, nil
+	}
+
+	e := newTestEngine(t, NewConfig(), WithRollups(finder))
+	defer e.Close()
+
+	write := func(field string, v interface{}, ts time.Duration) {
+		t.Helper()
+		pt := models.MustNewPoint(
+			tsdb.EncodeNameString(org, raw),
+			models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", "host": "a", models.FieldKeyTagKey: field}),
+			models.Fields{field: v},
+			time.Unix(0, int64(ts)),
+		)
+		if err := e.WritePoints(context.Background(), []models.Point{pt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("usage", 1.0, 10*time.Second)
+	write("usage", 3.0, 20*time.Second)
+	write("usage", 5.0, 70*time.Second)
+	write("active", int64(2), 30*time.Second)
+	write("state", "up", 30*time.Second)
+
+	// Snapshotting the cache triggers the rollups.
+	if err := e.engine.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := map[string]map[int64]interface{}{
+		"usage_mean":   {0: 2.0, int64(time.Minute): 5.0},
+		"usage_max":    {0: 3.0, int64(time.Minute): 5.0},
+		"usage_count":  {0: int64(2), int64(time.Minute): int64(1)},
+		"active_mean":  {0: 2.0},
+		"active_max":   {0: int64(2)},
+		"active_count": {0: int64(1)},
+		"state_count":  {0: int64(1)},
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for got := readRollups(t, e.Engine, org, rollup); !reflect.DeepEqual(got, exp); got = readRollups(t, e.Engine, org, rollup) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got rollups\n%v\nexpected\n%v", got, exp)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A late write recomputes the rollups of its window only.
+	write("usage", 8.0, 30*time.Second)
+	e.rollups.run(context.Background())
+
+	exp["usage_mean"][0] = 4.0
+	exp["usage_max"][0] = 8.0
+	exp["usage_count"][0] = int64(3)
+	if got := readRollups(t, e.Engine, org, rollup); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got rollups\n%v\nexpected\n%v", got, exp)
+	}
+
+	// Restored data is rolled up as well.
+	name := tsdb.EncodeName(org, raw)
+	tags := models.NewTags(map[string]string{models.MeasurementTagKey: "cpu", "host": "a", models.FieldKeyTagKey: "usage"})
+	key := tsm1.AppendSeriesFieldKeyBytes(nil, models.MakeKey(name[:], tags), []byte("usage"))
+	if err := e.RestoreTSMFile(context.Background(), tsmFile(t, map[string][]tsm1.Value{
+		string(key): {tsm1.NewValue(int64(80*time.Second), 11.0)},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	e.rollups.run(context.Background())
+
+	exp["usage_mean"][int64(time.Minute)] = 8.0
+	exp["usage_max"][int64(time.Minute)] = 11.0
+	exp["usage_count"][int64(time.Minute)] = int64(2)
+	if got := readRollups(t, e.Engine, org, rollup); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got rollups\n%v\nexpected\n%v", got, exp)
+	}
+}
+
+// readRollups returns the values of all fields of the bucket by time.
+func readRollups(t *testing.T, e *Engine, orgID, bucketID influxdb.ID) map[string]map[int64]interface{} {
+	t.Helper()
+
+	ctx := context.Background()
+	scur, err := e.CreateSeriesCursor(ctx, SeriesCursorRequest{Name: tsdb.EncodeName(orgID, bucketID)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scur.Close()
+
+	itr, err := e.CreateCursorIterator(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]map[int64]interface{})
+	for {
+		row, err := scur.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if row == nil {
+			break
+		}
+
+		field := string(row.Tags.Get(models.FieldKeyTagKeyBytes))
+		cur, err := itr.Next(ctx, &cursors.CursorRequest{
+			Name:      row.Name,
+			Tags:      row.Tags,
+			Field:     field,
+			Ascending: true,
+			StartTime: 0,
+			EndTime:   int64(time.Hour),
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if cur == nil {
+			continue
+		}
+
+		values := make(map[int64]interface{})
+		switch cur := cur.(type) {
+		case cursors.FloatArrayCursor:
+			for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+				for i, ts := range a.Timestamps {
+					values[ts] = a.Values[i]
+				}
+			}
+		case cursors.IntegerArrayCursor:
+			for a := cur.Next(); a.Len() > 0; a = cur.Next() {
+				for i, ts := range a.Timestamps {
+					values[ts] = a.Values[i]
+				}
+			}
+		default:
+			t.Fatalf("unexpected cursor %T", cur)
+		}
+		cur.Close()
+		got[field] = values
+	}
+	return got
+}
+
+func TestAddTimeRange(t *testing.T) {
+	const gap = rollupMergeGap
+
+	var a []timeRange
+	a = addTimeRange(a, timeRange{min: 10 * gap, max: 11 * gap})
+	a = addTimeRange(a, timeRange{min: 0, max: gap})
+	a = addTimeRange(a, timeRange{min: 20 * gap, max: 20 * gap})
+	a = addTimeRange(a, timeRange{min: 5 * gap, max: 5 * gap})
+	if exp := []timeRange{{0, gap}, {5 * gap, 5 * gap}, {10 * gap, 11 * gap}, {20 * gap, 20 * gap}}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("got %v, expected %v", a, exp)
+	}
+
+	// Ranges at most the gap apart are merged.
+	a = addTimeRange(a, timeRange{min: 6 * gap, max: 9 * gap})
+	if exp := []timeRange{{0, gap}, {5 * gap, 11 * gap}, {20 * gap, 20 * gap}}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("got %v, expected %v", a, exp)
+	}
+
+	a = addTimeRange(a, timeRange{min: 2 * gap, max: 30 * gap})
+	if exp := []timeRange{{0, 30 * gap}}; !reflect.DeepEqual(a, exp) {
+		t.Fatalf("got %v, expected %v", a, exp)
+	}
+}
+
+func TestWindowStart(t *testing.T) {
+	for _, tt := range []struct{ t, every, exp int64 }{
+		{t: 0, every: 10, exp: 0},
+		{t: 9, every: 10, exp: 0},
+		{t: 10, every: 10, exp: 10},
+		{t: -1, every: 10, exp: -10},
+		{t: -10, every: 10, exp: -10},
+	} {
+		if got := windowStart(tt.t, tt.every); got != tt.exp {
+			t.Errorf("windowStart(%d, %d) = %d, expected %d", tt.t, tt.every, got, tt.exp)
+		}
+	}
+}
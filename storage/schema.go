@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// A BucketSchemaFinder provides the explicit schemas of buckets.
+type BucketSchemaFinder interface {
+	FindBucketSchema(ctx context.Context, bucketID influxdb.ID) (*influxdb.BucketSchema, error)
+}
+
+// SchemaPointsWriter enforces the explicit schemas of buckets on the points
+// written to them. Points of buckets without a schema are written unchanged.
+//
+// Points not conforming to the schema of their bucket are routed to another
+// bucket or rejected, depending on the schema. Rejected points are dropped and
+// the remaining points are written, after which an error describing the
+// rejected points is returned.
+type SchemaPointsWriter struct {
+	Underlying    PointsWriter
+	SchemaService BucketSchemaFinder
+}
+
+// NewSchemaPointsWriter returns a new SchemaPointsWriter enforcing the schemas
+// of schemaService on the points written to w.
+func NewSchemaPointsWriter(w PointsWriter, schemaService BucketSchemaFinder) *SchemaPointsWriter {
+	return &SchemaPointsWriter{
+		Underlying:    w,
+		SchemaService: schemaService,
+	}
+}
+
+// WritePoints writes the points conforming to the schemas of their buckets
+// and the routed points to the underlying PointsWriter.
+func (w *SchemaPointsWriter) WritePoints(ctx context.Context, points []models.Point) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	schemas := make(map[string]*influxdb.BucketSchema)
+	out := points[:0:0]
+
+	var rejected int
+	var reason string
+	var drops []tsdb.Drop
+	for _, pt := range points {
+		name := pt.Name()
+		schema, ok := schemas[string(name)]
+		if !ok {
+			var err error
+			if schema, err = w.findSchema(ctx, name); err != nil {
+				return err
+			}
+			schemas[string(name)] = schema
+		}
+		if schema == nil {
+			out = append(out, pt)
+			continue
+		}
+
+		err := checkSchema(schema, pt)
+		if err == "" {
+			out = append(out, pt)
+			continue
+		}
+
+		switch schema.Action {
+		case influxdb.SchemaActionRoute:
+			routed, err := routePoint(pt, schema.OrgID, schema.RouteBucketID)
+			if err != nil {
+				return err
+			}
+			out = append(out, routed)
+		default:
+			if rejected == 0 {
+				reason = err
+			}
+			rejected++
+			drops = append(drops, tsdb.Drop{Point: pt, Code: influxdb.WriteRejectedSchema, Reason: err})
+		}
+	}
+	span.LogKV("points_rejected", rejected)
+
+	// The points dropped by the underlying writer are reported along with
+	// those rejected by the schemas.
+	pwe := tsdb.PartialWriteError{Reason: reason, Dropped: rejected, Drops: drops}
+	if len(out) > 0 {
+		if err := w.Underlying.WritePoints(ctx, out); err != nil {
+			upwe, ok := err.(tsdb.PartialWriteError)
+			if !ok || rejected == 0 {
+				return err
+			}
+			pwe.Dropped += upwe.Dropped
+			pwe.Drops = append(pwe.Drops, upwe.Drops...)
+		}
+	}
+
+	if rejected > 0 {
+		return &influxdb.Error{
+			Code: influxdb.EUnprocessableEntity,
+			Msg:  fmt.Sprintf("%d points rejected by bucket schema: %s", rejected, reason),
+			Err:  pwe,
+		}
+	}
+	return nil
+}
+
+// findSchema returns the schema of the bucket with the tsdb encoded name, or
+// nil if it does not have one.
+func (w *SchemaPointsWriter) findSchema(ctx context.Context, name []byte) (*influxdb.BucketSchema, error) {
+	if len(name) != influxdb.IDLength {
+		return nil, nil
+	}
+
+	_, bucketID := tsdb.DecodeNameSlice(name)
+	schema, err := w.SchemaService.FindBucketSchema(ctx, bucketID)
+	if influxdb.ErrorCode(err) == influxdb.ENotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// checkSchema returns why the point does not conform to the schema, or an
+// empty string if it does.
+func checkSchema(schema *influxdb.BucketSchema, pt models.Point) string {
+	tags := pt.Tags()
+	if len(tags) < 2 || !bytes.Equal(tags[0].Key, models.MeasurementTagKeyBytes) || !bytes.Equal(tags[len(tags)-1].Key, models.FieldKeyTagKeyBytes) {
+		return "point is missing measurement or field"
+	}
+
+	measurement := string(tags[0].Value)
+	m := schema.Measurement(measurement)
+	if m == nil {
+		return fmt.Sprintf("measurement %q is not in the schema", measurement)
+	}
+
+	for _, t := range tags[1 : len(tags)-1] {
+		if !m.HasTag(string(t.Key)) {
+			return fmt.Sprintf("tag %q is not in the schema of measurement %q", t.Key, measurement)
+		}
+	}
+
+	field := string(tags[len(tags)-1].Value)
+	f := m.Field(field)
+	if f == nil {
+		return fmt.Sprintf("field %q is not in the schema of measurement %q", field, measurement)
+	}
+
+	itr := pt.FieldIterator()
+	if !itr.Next() {
+		return fmt.Sprintf("point has no value for field %q", field)
+	}
+	if typ := schemaFieldType(itr.Type()); typ != f.Type {
+		return fmt.Sprintf("field %q of measurement %q is %s, expected %s", field, measurement, typ, f.Type)
+	}
+	return ""
+}
+
+// schemaFieldType returns the schema field type of values of type typ.
+func schemaFieldType(typ models.FieldType) influxdb.SchemaFieldType {
+	switch typ {
+	case models.Float:
+		return influxdb.SchemaFieldFloat
+	case models.Integer:
+		return influxdb.SchemaFieldInteger
+	case models.Unsigned:
+		return influxdb.SchemaFieldUnsigned
+	case models.String:
+		return influxdb.SchemaFieldString
+	case models.Boolean:
+		return influxdb.SchemaFieldBoolean
+	}
+	return ""
+}
+
+// routePoint returns a copy of the point written to another bucket.
+func routePoint(pt models.Point, orgID, bucketID influxdb.ID) (models.Point, error) {
+	fields, err := pt.Fields()
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPoint(tsdb.EncodeNameString(orgID, bucketID), pt.Tags(), fields, pt.Time())
+}
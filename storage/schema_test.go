@@ -0,0 +1,107 @@
+package storage_test
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+	"github.com/influxdata/influxdb/storage"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+type bucketSchemaFinder map[influxdb.ID]*influxdb.BucketSchema
+
+func (f bucketSchemaFinder) FindBucketSchema(ctx context.Context, bucketID influxdb.ID) (*influxdb.BucketSchema, error) {
+	if s, ok := f[bucketID]; ok {
+		return s, nil
+	}
+	return nil, influxdb.ErrBucketSchemaNotFound
+}
+
+func TestSchemaPointsWriter(t *testing.T) {
+	const org, strict, routed, rejected, free = 1, 2, 3, 4, 5
+
+	measurements := []influxdb.MeasurementSchema{{
+		Name: "cpu",
+		Tags: []string{"host", "region"},
+		Fields: []influxdb.FieldSchema{
+			{Name: "usage", Type: influxdb.SchemaFieldFloat},
+			{Name: "cores", Type: influxdb.SchemaFieldInteger},
+		},
+	}}
+	schemas := bucketSchemaFinder{
+		strict: {BucketID: strict, OrgID: org, Action: influxdb.SchemaActionReject, Measurements: measurements},
+		routed: {BucketID: routed, OrgID: org, Action: influxdb.SchemaActionRoute, RouteBucketID: rejected, Measurements: measurements},
+	}
+
+	data := `cpu,host=a usage=1.5,cores=4i 1
+cpu,host=a,region=west usage=2 2
+cpu,host=a,rack=1 usage=3 3
+cpu,host=a usage="high" 4
+cpu,host=a load=1 5
+mem,host=a used=1 6
+`
+
+	for _, tt := range []struct {
+		name     string
+		bucketID influxdb.ID
+		exp      map[influxdb.ID][]string
+		rejected bool
+	}{
+		{
+			name:     "no schema",
+			bucketID: free,
+			exp: map[influxdb.ID][]string{
+				free: {"cpu.cores=1", "cpu.load=5", "cpu.usage=1", "cpu.usage=2", "cpu.usage=3", "cpu.usage=4", "mem.used=6"},
+			},
+		},
+		{
+			name:     "reject",
+			bucketID: strict,
+			exp: map[influxdb.ID][]string{
+				strict: {"cpu.cores=1", "cpu.usage=1", "cpu.usage=2"},
+			},
+			rejected: true,
+		},
+		{
+			name:     "route",
+			bucketID: routed,
+			exp: map[influxdb.ID][]string{
+				routed:   {"cpu.cores=1", "cpu.usage=1", "cpu.usage=2"},
+				rejected: {"cpu.load=5", "cpu.usage=3", "cpu.usage=4", "mem.used=6"},
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			pw := &mock.PointsWriter{}
+			w := storage.NewSchemaPointsWriter(pw, schemas)
+
+			err := w.WritePoints(context.Background(), mockPoints(org, tt.bucketID, data))
+			if tt.rejected {
+				if influxdb.ErrorCode(err) != influxdb.EUnprocessableEntity {
+					t.Fatalf("got error %v, expected points to be rejected", err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := make(map[influxdb.ID][]string)
+			for _, pt := range pw.Points {
+				_, bucketID := tsdb.DecodeNameSlice(pt.Name())
+				tags := pt.Tags()
+				field := string(tags[len(tags)-1].Value)
+				got[bucketID] = append(got[bucketID], string(tags[0].Value)+"."+field+"="+strconv.FormatInt(pt.UnixNano(), 10))
+			}
+			for _, keys := range got {
+				sort.Strings(keys)
+			}
+			if diff := cmp.Diff(tt.exp, got); diff != "" {
+				t.Fatalf("unexpected points written (-want/+got):\n%s", diff)
+			}
+		})
+	}
+}
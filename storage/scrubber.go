@@ -0,0 +1,393 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/influxdata/influxdb/pkg/limiter"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var _ check.NamedChecker = (*Engine)(nil)
+
+// Kinds of files verified by the scrubber.
+const (
+	scrubFileTSM    = "tsm"
+	scrubFileSeries = "series"
+)
+
+// The scrubber reads the TSM files and series file of the engine again, at a
+// throttled rate, to find corruption before it is read by queries or
+// compactions. The blocks of TSM files are verified against their checksums
+// and index entries, and the entries of series segments against the series
+// index. Corrupt TSM files can be quarantined, while corrupt series partitions
+// are only reported, as dropping them would lose the keys of their series.
+type scrubber struct {
+	engine *Engine
+
+	limiter limiter.Rate // Nil if the rate is unlimited.
+
+	mu      sync.Mutex
+	corrupt map[string]*scrubCorruption // by path of TSM file or series partition
+
+	tracker *scrubTracker
+	logger  *zap.Logger
+}
+
+// scrubCorruption is the corruption found in a TSM file or series partition.
+type scrubCorruption struct {
+	file        string // scrubFileTSM or scrubFileSeries
+	err         error
+	quarantined bool
+}
+
+// newScrubber returns a new scrubber of the engine.
+func newScrubber(e *Engine) *scrubber {
+	s := &scrubber{
+		engine:  e,
+		corrupt: make(map[string]*scrubCorruption),
+		tracker: newScrubTracker(newScrubMetrics(nil), nil),
+		logger:  zap.NewNop(),
+	}
+	if rate := int(e.config.ScrubRate); rate > 0 {
+		s.limiter = limiter.NewRate(rate, rate)
+	}
+	return s
+}
+
+// SetDefaultMetricLabels sets the default labels for the scrubber metrics.
+func (s *scrubber) SetDefaultMetricLabels(defaultLabels prometheus.Labels) {
+	mmu.Lock()
+	if scms == nil {
+		scms = newScrubMetrics(defaultLabels)
+	}
+	mmu.Unlock()
+
+	s.tracker = newScrubTracker(scms, defaultLabels)
+}
+
+// WithLogger sets the logger l on the scrubber. It must be called before any
+// run calls.
+func (s *scrubber) WithLogger(log *zap.Logger) {
+	s.logger = log.With(zap.String("component", "scrubber"))
+}
+
+// run verifies the TSM files and series partitions of the engine.
+func (s *scrubber) run(ctx context.Context) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	log, logEnd := logger.NewOperation(ctx, s.logger, "Storage scrub", "storage_scrub")
+	defer logEnd()
+
+	now := time.Now()
+	err := s.scrub(ctx, log)
+	s.tracker.IncRuns(time.Since(now), err == nil)
+	if err != nil {
+		log.Error("Storage scrub failed", zap.Error(err))
+		return err
+	}
+
+	log.Info("Storage scrub complete", zap.Int("corrupt_files", s.corruptCount()))
+	return nil
+}
+
+// scrub verifies the TSM files and series partitions, skipping those already
+// found corrupt. It only returns an error if verification could not complete.
+func (s *scrubber) scrub(ctx context.Context, log *zap.Logger) error {
+	e := s.engine
+
+	files := e.engine.FileStore.RefFiles()
+	defer func() {
+		for _, f := range files {
+			f.Unref()
+		}
+	}()
+
+	// Corrupt files that were removed since, such as by retention or a
+	// delete, no longer affect the data served.
+	live := make(map[string]bool, len(files))
+	for _, f := range files {
+		live[f.Path()] = true
+	}
+	s.removeCorruption(func(path string, c *scrubCorruption) bool {
+		return c.file == scrubFileTSM && !c.quarantined && !live[path]
+	})
+
+	wait := s.wait(ctx, scrubFileTSM)
+	for len(files) > 0 {
+		f := files[0]
+		path := f.Path()
+
+		var err error
+		if r, ok := f.(*tsm1.TSMReader); ok && !r.IsCold() && !s.isCorrupt(path) {
+			// Blocks of files in cold storage are only read when queried, to
+			// avoid the cost of downloading them.
+			err = tsm1.VerifyBlocks(r, wait)
+		}
+
+		// Files are released once verified, so that the files replaced by
+		// compactions in the meantime can be removed.
+		f.Unref()
+		files = files[1:]
+
+		if err != nil {
+			if _, ok := err.(*tsm1.CorruptBlockError); !ok {
+				return err
+			}
+			s.corruptTSMFile(log, path, err)
+		}
+	}
+
+	wait = s.wait(ctx, scrubFileSeries)
+	for _, p := range e.sfile.Partitions() {
+		if s.isCorrupt(p.Path()) {
+			continue
+		}
+
+		if err := p.Verify(wait); err != nil {
+			if _, ok := err.(*tsdb.CorruptSeriesError); !ok {
+				return err
+			}
+			log.Error("Corrupt series partition", zap.String("path", p.Path()), zap.Error(err))
+			s.addCorruption(p.Path(), &scrubCorruption{file: scrubFileSeries, err: err})
+		}
+	}
+	return nil
+}
+
+// corruptTSMFile records a corrupt TSM file, and quarantines it if configured
+// to.
+func (s *scrubber) corruptTSMFile(log *zap.Logger, path string, err error) {
+	log.Error("Corrupt TSM file", zap.String("path", path), zap.Error(err))
+	c := &scrubCorruption{file: scrubFileTSM, err: err}
+
+	if s.engine.config.ScrubQuarantine {
+		if ok, err := s.engine.engine.FileStore.Quarantine(path); err != nil {
+			log.Error("Cannot quarantine corrupt TSM file", zap.String("path", path), zap.Error(err))
+		} else if ok {
+			log.Warn("Quarantined corrupt TSM file", zap.String("path", path), zap.String("quarantine_path", path+"."+tsm1.BadTSMFileExtension))
+			c.quarantined = true
+			s.tracker.IncQuarantined()
+		} else {
+			// The file was replaced by a compaction while verified.
+			return
+		}
+	}
+	s.addCorruption(path, c)
+}
+
+// wait returns the function throttling the reads of files of the given kind.
+func (s *scrubber) wait(ctx context.Context, file string) func(n int) error {
+	return func(n int) error {
+		s.tracker.AddBytes(file, n)
+		if s.limiter == nil {
+			return ctx.Err()
+		}
+
+		// Reads larger than the burst of the limiter are waited for in parts.
+		for burst := s.limiter.Burst(); n > 0; n -= burst {
+			m := n
+			if m > burst {
+				m = burst
+			}
+			if err := s.limiter.WaitN(ctx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func (s *scrubber) addCorruption(path string, c *scrubCorruption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.corrupt[path] = c
+	s.setCorrupt(c.file)
+}
+
+// removeCorruption removes the corruption for which fn returns true.
+func (s *scrubber) removeCorruption(fn func(path string, c *scrubCorruption) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for path, c := range s.corrupt {
+		if fn(path, c) {
+			delete(s.corrupt, path)
+			s.setCorrupt(c.file)
+		}
+	}
+}
+
+// setCorrupt sets the number of corrupt files of a kind. s.mu must be held.
+func (s *scrubber) setCorrupt(file string) {
+	var n int
+	for _, c := range s.corrupt {
+		if c.file == file {
+			n++
+		}
+	}
+	s.tracker.SetCorrupt(file, n)
+}
+
+func (s *scrubber) isCorrupt(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.corrupt[path]
+	return ok
+}
+
+func (s *scrubber) corruptCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.corrupt)
+}
+
+// check reports the corruption found by the scrubber. Corrupt files that are
+// still read fail the check, while quarantined files only warn, as their data
+// is no longer served.
+func (s *scrubber) check() check.Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.corrupt) == 0 {
+		return check.Pass()
+	}
+
+	paths := make([]string, 0, len(s.corrupt))
+	for path := range s.corrupt {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var failed bool
+	msgs := make([]string, 0, len(paths))
+	for _, path := range paths {
+		c := s.corrupt[path]
+		msg := fmt.Sprintf("%s: %v", path, c.err)
+		if c.quarantined {
+			msg += " (quarantined)"
+		} else {
+			failed = true
+		}
+		msgs = append(msgs, msg)
+	}
+	if !failed {
+		return check.Warn("corrupt files quarantined: %s", strings.Join(msgs, "; "))
+	}
+	return check.Error(fmt.Errorf("corrupt files found: %s", strings.Join(msgs, "; ")))
+}
+
+// runScrubber runs the scrubber in a separate goroutine, periodically.
+func (e *Engine) runScrubber() {
+	interval := time.Duration(e.config.ScrubInterval)
+	if interval == 0 {
+		e.logger.Info("Storage scrubber disabled")
+		return
+	} else if interval < 0 {
+		e.logger.Error("Negative storage scrub interval", logger.DurationLiteral("check_interval", interval))
+		return
+	}
+
+	// Runs in progress are cancelled when the engine is closed.
+	closing := e.closing
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-closing
+		cancel()
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-closing:
+				return
+			case <-ticker.C:
+				e.scrubber.run(ctx)
+			}
+		}
+	}()
+}
+
+// CheckName returns the name of the health check of the engine.
+func (e *Engine) CheckName() string {
+	return "storage"
+}
+
+// Check reports the engine unhealthy once the scrubber has found corrupt TSM
+// files or series partitions.
+func (e *Engine) Check(ctx context.Context) check.Response {
+	return e.scrubber.check()
+}
+
+//
+// metrics tracker
+//
+
+type scrubTracker struct {
+	metrics *scrubMetrics
+	labels  prometheus.Labels
+}
+
+func newScrubTracker(metrics *scrubMetrics, defaultLabels prometheus.Labels) *scrubTracker {
+	return &scrubTracker{metrics: metrics, labels: defaultLabels}
+}
+
+// Labels returns a copy of labels for use with scrubber metrics.
+func (t *scrubTracker) Labels() prometheus.Labels {
+	l := make(map[string]string, len(t.labels))
+	for k, v := range t.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// IncRuns signals that a run of the scrubber completed, taking dur.
+func (t *scrubTracker) IncRuns(dur time.Duration, success bool) {
+	labels := t.Labels()
+
+	if success {
+		labels["status"] = "ok"
+	} else {
+		labels["status"] = "error"
+	}
+
+	t.metrics.Runs.With(labels).Inc()
+	t.metrics.RunDuration.With(labels).Observe(dur.Seconds())
+}
+
+// AddBytes adds the bytes of files of the given kind verified.
+func (t *scrubTracker) AddBytes(file string, n int) {
+	labels := t.Labels()
+	labels["file"] = file
+	t.metrics.Bytes.With(labels).Add(float64(n))
+}
+
+// SetCorrupt sets the number of corrupt files of the given kind found.
+func (t *scrubTracker) SetCorrupt(file string, n int) {
+	labels := t.Labels()
+	labels["file"] = file
+	t.metrics.Corrupt.With(labels).Set(float64(n))
+}
+
+// IncQuarantined signals that a corrupt TSM file was quarantined.
+func (t *scrubTracker) IncQuarantined() {
+	t.metrics.Quarantined.With(t.Labels()).Inc()
+}
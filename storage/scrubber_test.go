@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/check"
+	"github.com/influxdata/influxdb/kit/prom/promtest"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEngine_Scrub(t *testing.T) {
+	const org, bucket = influxdb.ID(0x1000), influxdb.ID(0x2000)
+
+	for _, quarantine := range []bool{false, true} {
+		t.Run(fmt.Sprintf("quarantine=%v", quarantine), func(t *testing.T) {
+			c := NewConfig()
+			c.ScrubInterval = 0
+			c.ScrubRate = 0
+			c.ScrubQuarantine = quarantine
+
+			e := newTestEngine(t, c)
+			defer e.Close()
+
+			if err := e.WritePoints(context.Background(), cpuPoints(org, bucket, "a", "b", "c")); err != nil {
+				t.Fatal(err)
+			} else if err := e.engine.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.scrubber.run(context.Background()); err != nil {
+				t.Fatal(err)
+			} else if resp := e.Check(context.Background()); resp.Status != check.StatusPass {
+				t.Fatalf("unexpected health check failure: %s", resp.Message)
+			}
+
+			// Corrupt the data of the first block, following the file header
+			// and the checksum of the block.
+			files := e.engine.FileStore.Files()
+			if len(files) != 1 {
+				t.Fatalf("unexpected TSM files: %d", len(files))
+			}
+			path := files[0].Path()
+			f, err := os.OpenFile(path, os.O_WRONLY, 0666)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := f.WriteAt([]byte{0xff, 0xff}, 5+4+2); err != nil {
+				t.Fatal(err)
+			} else if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.scrubber.run(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			reg := prometheus.NewRegistry()
+			reg.MustRegister(e.PrometheusCollectors()...)
+			checkCorruptFiles := func(exp float64) {
+				t.Helper()
+				mfs, err := reg.Gather()
+				if err != nil {
+					t.Fatal(err)
+				}
+				labels := prometheus.Labels{"engine_id": "0", "node_id": "0", "file": "tsm"}
+				m := promtest.MustFindMetric(t, mfs, "storage_scrub_corrupt_files", labels)
+				if got := m.GetGauge().GetValue(); got != exp {
+					t.Fatalf("unexpected corrupt files metric: got %v, exp %v", got, exp)
+				}
+			}
+			checkCorruptFiles(1)
+
+			if !quarantine {
+				// The corrupt file is still read, until it is removed.
+				if resp := e.Check(context.Background()); resp.Status != check.StatusFail {
+					t.Fatal("expected health check to fail")
+				}
+				if err := e.DeleteBucket(context.Background(), org, bucket); err != nil {
+					t.Fatal(err)
+				}
+				if err := e.scrubber.run(context.Background()); err != nil {
+					t.Fatal(err)
+				} else if resp := e.Check(context.Background()); resp.Status != check.StatusPass {
+					t.Fatalf("unexpected health check failure: %s", resp.Message)
+				}
+				checkCorruptFiles(0)
+				return
+			}
+
+			// The corrupt file is no longer read, but kept.
+			if resp := e.Check(context.Background()); resp.Status != check.StatusWarn {
+				t.Fatalf("expected health check to warn, got %s", resp.Status)
+			}
+			if n := e.engine.FileStore.Count(); n != 0 {
+				t.Fatalf("unexpected TSM files after quarantine: %d", n)
+			} else if _, err := os.Stat(path + "." + tsm1.BadTSMFileExtension); err != nil {
+				t.Fatal(err)
+			}
+
+			mfs, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels := prometheus.Labels{"engine_id": "0", "node_id": "0"}
+			m := promtest.MustFindMetric(t, mfs, "storage_scrub_quarantined_files_total", labels)
+			if got, exp := m.GetCounter().GetValue(), 1.0; got != exp {
+				t.Fatalf("unexpected quarantined files metric: got %v, exp %v", got, exp)
+			}
+		})
+	}
+}
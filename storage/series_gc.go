@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+var _ influxdb.SeriesGCService = (*Engine)(nil)
+
+// The seriesGC removes the series without any data in the cache or TSM files,
+// such as the series of data removed by retention or deletes, from the index
+// and the series file. The series file is then compacted, so that the
+// segments only holding removed series are deleted.
+type seriesGC struct {
+	engine *Engine
+
+	// trigger receives the requests to run the collector on demand, which
+	// are answered on the given channel.
+	trigger chan chan seriesGCResult
+
+	tracker *seriesGCTracker
+	logger  *zap.Logger
+}
+
+// seriesGCResult is the outcome of a run of the series garbage collector.
+type seriesGCResult struct {
+	gc  *influxdb.SeriesGC
+	err error
+}
+
+// newSeriesGC returns a new series garbage collector of the engine.
+func newSeriesGC(e *Engine) *seriesGC {
+	return &seriesGC{
+		engine:  e,
+		trigger: make(chan chan seriesGCResult),
+		tracker: newSeriesGCTracker(newSeriesGCMetrics(nil), nil),
+		logger:  zap.NewNop(),
+	}
+}
+
+// SetDefaultMetricLabels sets the default labels for the series garbage
+// collector metrics.
+func (g *seriesGC) SetDefaultMetricLabels(defaultLabels prometheus.Labels) {
+	mmu.Lock()
+	if sgcms == nil {
+		sgcms = newSeriesGCMetrics(defaultLabels)
+	}
+	mmu.Unlock()
+
+	g.tracker = newSeriesGCTracker(sgcms, defaultLabels)
+}
+
+// WithLogger sets the logger l on the collector. It must be called before any
+// run calls.
+func (g *seriesGC) WithLogger(log *zap.Logger) {
+	g.logger = log.With(zap.String("component", "series_gc"))
+}
+
+// run removes the series without data and compacts the series file.
+func (g *seriesGC) run(ctx context.Context) (*influxdb.SeriesGC, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	log, logEnd := logger.NewOperation(ctx, g.logger, "Series garbage collection", "series_gc")
+	defer logEnd()
+
+	now := time.Now()
+	gc, err := g.collect(ctx)
+	g.tracker.AddRemoved(gc)
+	g.tracker.IncRuns(time.Since(now), err == nil)
+	if err != nil {
+		log.Error("Series garbage collection failed", zap.Int64("series_removed", gc.SeriesRemoved), zap.Error(err))
+		return nil, err
+	}
+
+	log.Info("Series garbage collection complete",
+		zap.Int64("series_removed", gc.SeriesRemoved),
+		zap.Int64("segments_removed", gc.SegmentsRemoved))
+	return gc, nil
+}
+
+// collect removes the series without data and compacts the series file. The
+// series removed before any error are included in the returned run.
+func (g *seriesGC) collect(ctx context.Context) (*influxdb.SeriesGC, error) {
+	e := g.engine
+	gc := &influxdb.SeriesGC{}
+
+	dead, err := e.engine.DeadSeries(ctx)
+	if err != nil {
+		return gc, err
+	}
+
+	if dead.Cardinality() > 0 {
+		// Writes are blocked while the series are checked again and removed,
+		// so that no data is written to the series being removed.
+		e.mu.Lock()
+		n, err := e.engine.DropDeadSeries(ctx, dead)
+		e.mu.Unlock()
+
+		gc.SeriesRemoved = int64(n)
+		if n > 0 {
+			e.seriesLimits.recount()
+		}
+		if err != nil {
+			return gc, err
+		}
+	}
+
+	n, err := e.sfile.Compact(ctx)
+	gc.SegmentsRemoved = int64(n)
+	return gc, err
+}
+
+// runSeriesGC runs the series garbage collector in a separate goroutine,
+// periodically and when requested by CollectSeries.
+func (e *Engine) runSeriesGC() {
+	interval := time.Duration(e.config.SeriesGCInterval)
+	closing := e.closing
+
+	var ticker *time.Ticker
+	if interval == 0 {
+		e.logger.Info("Periodic series garbage collection disabled")
+	} else if interval < 0 {
+		e.logger.Error("Negative series garbage collection interval", logger.DurationLiteral("check_interval", interval))
+	} else {
+		ticker = time.NewTicker(interval)
+	}
+
+	// Runs in progress are cancelled when the engine is closed.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-closing
+		cancel()
+	}()
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+
+		var tick <-chan time.Time
+		if ticker != nil {
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-closing:
+				return
+			case <-tick:
+				e.seriesGC.run(ctx)
+			case done := <-e.seriesGC.trigger:
+				gc, err := e.seriesGC.run(ctx)
+				done <- seriesGCResult{gc: gc, err: err}
+			}
+		}
+	}()
+}
+
+// CollectSeries runs the series garbage collector of the engine and waits for
+// it to complete. The run continues in the background if ctx is done first.
+func (e *Engine) CollectSeries(ctx context.Context) (*influxdb.SeriesGC, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	e.mu.RLock()
+	closing := e.closing
+	e.mu.RUnlock()
+	if closing == nil {
+		return nil, ErrEngineClosed
+	}
+
+	done := make(chan seriesGCResult, 1)
+	select {
+	case e.seriesGC.trigger <- done:
+	case <-closing:
+		return nil, ErrEngineClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return nil, &influxdb.Error{
+				Code: influxdb.EInternal,
+				Op:   influxdb.OpCollectSeries,
+				Msg:  "unable to collect series",
+				Err:  r.err,
+			}
+		}
+		return r.gc, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//
+// metrics tracker
+//
+
+type seriesGCTracker struct {
+	metrics *seriesGCMetrics
+	labels  prometheus.Labels
+}
+
+func newSeriesGCTracker(metrics *seriesGCMetrics, defaultLabels prometheus.Labels) *seriesGCTracker {
+	return &seriesGCTracker{metrics: metrics, labels: defaultLabels}
+}
+
+// Labels returns a copy of labels for use with series garbage collector metrics.
+func (t *seriesGCTracker) Labels() prometheus.Labels {
+	l := make(map[string]string, len(t.labels))
+	for k, v := range t.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// IncRuns signals that a run of the collector completed, taking dur.
+func (t *seriesGCTracker) IncRuns(dur time.Duration, success bool) {
+	labels := t.Labels()
+
+	if success {
+		labels["status"] = "ok"
+	} else {
+		labels["status"] = "error"
+	}
+
+	t.metrics.Runs.With(labels).Inc()
+	t.metrics.RunDuration.With(labels).Observe(dur.Seconds())
+}
+
+// AddRemoved adds the series and segments removed by a run of the collector.
+func (t *seriesGCTracker) AddRemoved(gc *influxdb.SeriesGC) {
+	labels := t.Labels()
+	t.metrics.SeriesRemoved.With(labels).Add(float64(gc.SeriesRemoved))
+	t.metrics.SegmentsRemoved.With(labels).Add(float64(gc.SegmentsRemoved))
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/prom/promtest"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEngine_CollectSeries(t *testing.T) {
+	const org, bucket = influxdb.ID(0x1000), influxdb.ID(0x2000)
+
+	e := newTestEngine(t, NewConfig())
+	defer e.Close()
+
+	points := cpuPoints(org, bucket, "a", "b", "c")
+	if err := e.WritePoints(context.Background(), points[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	// A series left in the index without any data.
+	if err := e.index.CreateSeriesListIfNotExists(tsdb.NewSeriesCollection(points[2:])); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := e.SeriesCardinality(), int64(3); got != exp {
+		t.Fatalf("unexpected series cardinality: got %d, exp %d", got, exp)
+	}
+
+	gc, err := e.CollectSeries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := gc.SeriesRemoved, int64(1); got != exp {
+		t.Fatalf("unexpected series removed: got %d, exp %d", got, exp)
+	} else if got, exp := e.SeriesCardinality(), int64(2); got != exp {
+		t.Fatalf("unexpected series cardinality: got %d, exp %d", got, exp)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(e.PrometheusCollectors()...)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := prometheus.Labels{"engine_id": "0", "node_id": "0"}
+	m := promtest.MustFindMetric(t, mfs, "storage_series_gc_series_removed_total", labels)
+	if got, exp := m.GetCounter().GetValue(), 1.0; got != exp {
+		t.Fatalf("unexpected series removed metric: got %v, exp %v", got, exp)
+	}
+	labels["status"] = "ok"
+	m = promtest.MustFindMetric(t, mfs, "storage_series_gc_runs_total", labels)
+	if got, exp := m.GetCounter().GetValue(), 1.0; got != exp {
+		t.Fatalf("unexpected runs metric: got %v, exp %v", got, exp)
+	}
+
+	// Nothing is left to collect.
+	if gc, err := e.CollectSeries(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if gc.SeriesRemoved != 0 {
+		t.Fatalf("unexpected series removed: %d", gc.SeriesRemoved)
+	}
+
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	} else if _, err := e.CollectSeries(context.Background()); err != ErrEngineClosed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,370 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsi1"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// seriesLimitsInterval is the interval at which the series limits of buckets
+// and organizations are reloaded.
+const seriesLimitsInterval = time.Minute
+
+// OrganizationFinder provides access to organizations.
+type OrganizationFinder interface {
+	FindOrganizations(context.Context, influxdb.OrganizationFilter, ...influxdb.FindOptions) ([]*influxdb.Organization, int, error)
+}
+
+// The seriesLimiter enforces the maximum number of series of buckets and
+// organizations, by rejecting the points of a write that would create series
+// beyond them.
+//
+// The number of series of a limited bucket or organization is estimated from
+// the cardinality stats of the index when its limit is first enforced, and
+// incremented by the new series allowed since. The counts are kept across
+// reloads of the limits, and only computed again, exactly, after series have
+// been deleted. The series are counted without holding the lock of the
+// limiter, so that writes are not blocked meanwhile.
+type seriesLimiter struct {
+	// BucketService provides the series limits of buckets.
+	BucketService BucketFinder
+
+	// OrganizationService provides the series limits of organizations.
+	OrganizationService OrganizationFinder
+
+	mu           sync.Mutex
+	bucketLimits map[[influxdb.IDLength]byte]int64
+	orgLimits    map[influxdb.ID]int64
+	// bucketSeries and orgSeries hold the number of series of the limited
+	// buckets and organizations counted so far.
+	bucketSeries map[[influxdb.IDLength]byte]int64
+	orgSeries    map[influxdb.ID]int64
+	// generation is incremented whenever the series are to be recounted, so
+	// that counts started before are discarded.
+	generation uint64
+	// exact is set when the series are to be recounted from the index rather
+	// than estimated from its cached stats, which may predate deletes.
+	exact bool
+
+	// countMu serializes the counts of the series, so that writes waiting
+	// for the series to be counted count them once.
+	countMu sync.Mutex
+
+	tracker *seriesLimitTracker
+	logger  *zap.Logger
+}
+
+// newSeriesLimiter returns a new limiter enforcing the series limits of the
+// buckets and organizations provided by the services.
+func newSeriesLimiter(bucketService BucketFinder, orgService OrganizationFinder) *seriesLimiter {
+	return &seriesLimiter{
+		BucketService:       bucketService,
+		OrganizationService: orgService,
+		bucketSeries:        make(map[[influxdb.IDLength]byte]int64),
+		orgSeries:           make(map[influxdb.ID]int64),
+		tracker:             newSeriesLimitTracker(newSeriesLimitMetrics(nil), nil),
+		logger:              zap.NewNop(),
+	}
+}
+
+// SetDefaultMetricLabels sets the default labels for the series limit metrics.
+func (l *seriesLimiter) SetDefaultMetricLabels(defaultLabels prometheus.Labels) {
+	if l == nil {
+		return // Not initialized
+	}
+
+	mmu.Lock()
+	if slms == nil {
+		slms = newSeriesLimitMetrics(defaultLabels)
+	}
+	mmu.Unlock()
+
+	l.tracker = newSeriesLimitTracker(slms, defaultLabels)
+}
+
+// WithLogger sets the logger l on the limiter. It must be called before any load calls.
+func (l *seriesLimiter) WithLogger(log *zap.Logger) {
+	if l == nil {
+		return // Not initialised
+	}
+	l.logger = log.With(zap.String("component", "series_limits"))
+}
+
+// load reloads the series limits of all buckets and organizations. The series
+// counted so far are kept, except those of buckets and organizations no longer
+// limited.
+func (l *seriesLimiter) load(ctx context.Context) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	buckets, _, err := l.BucketService.FindBuckets(ctx, influxdb.BucketFilter{})
+	if err != nil {
+		return err
+	}
+	orgs, _, err := l.OrganizationService.FindOrganizations(ctx, influxdb.OrganizationFilter{})
+	if err != nil {
+		return err
+	}
+
+	bucketLimits := make(map[[influxdb.IDLength]byte]int64)
+	for _, b := range buckets {
+		if b.MaxSeries > 0 {
+			bucketLimits[tsdb.EncodeName(b.OrgID, b.ID)] = b.MaxSeries
+		}
+	}
+	orgLimits := make(map[influxdb.ID]int64)
+	for _, o := range orgs {
+		if o.MaxSeries > 0 {
+			orgLimits[o.ID] = o.MaxSeries
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucketLimits, l.orgLimits = bucketLimits, orgLimits
+	for key := range l.bucketSeries {
+		if _, ok := bucketLimits[key]; !ok {
+			delete(l.bucketSeries, key)
+		}
+	}
+	for orgID := range l.orgSeries {
+		if _, ok := orgLimits[orgID]; !ok {
+			delete(l.orgSeries, orgID)
+		}
+	}
+	return nil
+}
+
+// recount makes the series of the buckets be recounted exactly before the
+// limits are next enforced, such as after series have been deleted.
+func (l *seriesLimiter) recount() {
+	if l == nil {
+		return // Not initialized
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bucketSeries = make(map[[influxdb.IDLength]byte]int64)
+	l.orgSeries = make(map[influxdb.ID]int64)
+	l.generation++
+	l.exact = true
+}
+
+// counted returns whether the series of the bucket key and its organization
+// have been counted, if limited. It must be called under the lock.
+func (l *seriesLimiter) counted(key [influxdb.IDLength]byte, orgID influxdb.ID) bool {
+	if _, ok := l.bucketLimits[key]; ok {
+		if _, ok := l.bucketSeries[key]; !ok {
+			return false
+		}
+	}
+	if _, ok := l.orgLimits[orgID]; ok {
+		if _, ok := l.orgSeries[orgID]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// count sets the number of series of the limited buckets and organizations
+// not counted yet from the cardinality stats of index. The stats cached by
+// the index are used, unless the series are to be recounted exactly. It must
+// not be called under the lock.
+func (l *seriesLimiter) count(index *tsi1.Index) error {
+	l.countMu.Lock()
+	defer l.countMu.Unlock()
+
+	l.mu.Lock()
+	generation, exact := l.generation, l.exact
+	l.mu.Unlock()
+
+	var (
+		stats tsi1.MeasurementCardinalityStats
+		err   error
+	)
+	if exact {
+		stats, err = index.ComputeMeasurementCardinalityStats()
+	} else {
+		stats, err = index.MeasurementCardinalityStats()
+	}
+	if err != nil {
+		return err
+	}
+
+	bucketSeries := make(map[[influxdb.IDLength]byte]int64, len(stats))
+	orgSeries := make(map[influxdb.ID]int64)
+	for name, n := range stats {
+		if len(name) != influxdb.IDLength {
+			continue
+		}
+		var key [influxdb.IDLength]byte
+		copy(key[:], name)
+		orgID, _ := tsdb.DecodeName(key)
+		bucketSeries[key] += int64(n)
+		orgSeries[orgID] += int64(n)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.generation != generation {
+		return nil
+	}
+	for key := range l.bucketLimits {
+		if _, ok := l.bucketSeries[key]; !ok {
+			l.bucketSeries[key] = bucketSeries[key]
+		}
+	}
+	for orgID := range l.orgLimits {
+		if _, ok := l.orgSeries[orgID]; !ok {
+			l.orgSeries[orgID] = orgSeries[orgID]
+		}
+	}
+	l.exact = false
+	return nil
+}
+
+// limit drops the points of collection that would create series beyond the
+// series limits of their bucket or organization. Dropped points are reported
+// by the partial write error of collection.
+func (l *seriesLimiter) limit(collection *tsdb.SeriesCollection, sfile *tsdb.SeriesFile, index *tsi1.Index) error {
+	if l == nil {
+		return nil // Not initialized
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.bucketLimits) == 0 && len(l.orgLimits) == 0 {
+		return nil
+	}
+
+	var (
+		buf     []byte
+		created map[string]struct{} // Series created by earlier points of the collection.
+		dropped bool
+	)
+	for iter := collection.Iterator(); iter.Next(); {
+		name := iter.Name()
+		if len(name) != influxdb.IDLength {
+			continue
+		}
+
+		var key [influxdb.IDLength]byte
+		copy(key[:], name)
+		orgID, bucketID := tsdb.DecodeName(key)
+
+		bucketLimit, orgLimit := l.bucketLimits[key], l.orgLimits[orgID]
+		if bucketLimit == 0 && orgLimit == 0 {
+			continue
+		}
+
+		if _, ok := created[string(iter.Key())]; ok || sfile.HasSeries(name, iter.Tags(), buf) {
+			continue
+		}
+
+		for !l.counted(key, orgID) {
+			l.mu.Unlock()
+			err := l.count(index)
+			l.mu.Lock()
+			if err != nil {
+				return err
+			}
+			// The limits may have been reloaded meanwhile.
+			bucketLimit, orgLimit = l.bucketLimits[key], l.orgLimits[orgID]
+		}
+		if bucketLimit == 0 && orgLimit == 0 {
+			continue
+		}
+
+		if bucketLimit > 0 && l.bucketSeries[key] >= bucketLimit {
+			iter.Invalid(fmt.Sprintf("series limit of %d exceeded for bucket %s", bucketLimit, bucketID))
+			l.tracker.IncRejectedPoints(bucketID, "bucket")
+			dropped = true
+			continue
+		}
+		if orgLimit > 0 && l.orgSeries[orgID] >= orgLimit {
+			iter.Invalid(fmt.Sprintf("series limit of %d exceeded for organization %s", orgLimit, orgID))
+			l.tracker.IncRejectedPoints(bucketID, "org")
+			dropped = true
+			continue
+		}
+
+		if created == nil {
+			created = make(map[string]struct{})
+		}
+		created[string(iter.Key())] = struct{}{}
+		if bucketLimit > 0 {
+			l.bucketSeries[key]++
+		}
+		if orgLimit > 0 {
+			l.orgSeries[orgID]++
+		}
+	}
+
+	if dropped {
+		collection.ApplyConcurrentDrops()
+	}
+	return nil
+}
+
+// runSeriesLimits periodically reloads the series limits of the engine in a
+// separate goroutine.
+func (e *Engine) runSeriesLimits() {
+	closing := e.closing
+
+	ticker := time.NewTicker(seriesLimitsInterval)
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closing:
+				return
+			case <-ticker.C:
+				if err := e.seriesLimits.load(context.Background()); err != nil {
+					e.seriesLimits.logger.Error("Unable to load series limits", zap.Error(err))
+				}
+			}
+		}
+	}()
+}
+
+//
+// metrics tracker
+//
+
+type seriesLimitTracker struct {
+	metrics *seriesLimitMetrics
+	labels  prometheus.Labels
+}
+
+func newSeriesLimitTracker(metrics *seriesLimitMetrics, defaultLabels prometheus.Labels) *seriesLimitTracker {
+	return &seriesLimitTracker{metrics: metrics, labels: defaultLabels}
+}
+
+// Labels returns a copy of labels for use with series limit metrics.
+func (t *seriesLimitTracker) Labels() prometheus.Labels {
+	l := make(map[string]string, len(t.labels))
+	for k, v := range t.labels {
+		l[k] = v
+	}
+	return l
+}
+
+// IncRejectedPoints signals that a point of the bucket was rejected by the
+// limit of the bucket or its organization.
+func (t *seriesLimitTracker) IncRejectedPoints(bucketID influxdb.ID, limit string) {
+	labels := t.Labels()
+	labels["bucket"] = bucketID.String()
+	labels["limit"] = limit
+	t.metrics.RejectedPoints.With(labels).Inc()
+}
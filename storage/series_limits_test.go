@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/kit/prom/promtest"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestEngine_SeriesLimits(t *testing.T) {
+	const org, bucketA, bucketB = influxdb.ID(0x1000), influxdb.ID(0x2000), influxdb.ID(0x3000)
+
+	var bucketLimit, orgLimit int64
+	buckets := NewTestBucketFinder()
+	buckets.FindBucketsFn = func(context.Context, influxdb.BucketFilter, ...influxdb.FindOptions) ([]*influxdb.Bucket, int, error) {
+		return []*influxdb.Bucket{
+			{OrgID: org, ID: bucketA, MaxSeries: bucketLimit},
+			{OrgID: org, ID: bucketB},
+		}, 2, nil
+	}
+	orgs := &TestOrganizationFinder{
+		FindOrganizationsFn: func(context.Context, influxdb.OrganizationFilter, ...influxdb.FindOptions) ([]*influxdb.Organization, int, error) {
+			return []*influxdb.Organization{{ID: org, MaxSeries: orgLimit}}, 1, nil
+		},
+	}
+
+	// The series are estimated from the stats cached by the index, and
+	// counted exactly after deletes.
+	c := NewConfig()
+	c.Index.StatsTTL = time.Hour
+	e := newTestEngine(t, c, WithSeriesLimits(buckets, orgs))
+	defer e.Close()
+
+	write := func(bucket influxdb.ID, hosts ...string) error {
+		return e.WritePoints(context.Background(), cpuPoints(org, bucket, hosts...))
+	}
+	expDropped := func(err error, n int, reason string) {
+		t.Helper()
+		pwe, ok := err.(tsdb.PartialWriteError)
+		if !ok {
+			t.Fatalf("expected partial write error, got %v", err)
+		}
+		if pwe.Dropped != n {
+			t.Fatalf("unexpected number of dropped points: got %d, exp %d", pwe.Dropped, n)
+		}
+		if !strings.Contains(pwe.Reason, reason) {
+			t.Fatalf("unexpected reason %q, expected it to contain %q", pwe.Reason, reason)
+		}
+	}
+
+	// Series written before the limits are set are counted once they are.
+	if err := write(bucketA, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	bucketLimit, orgLimit = 2, 4
+	if err := e.seriesLimits.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the point creating a third series of the bucket is dropped.
+	expDropped(write(bucketA, "a", "b", "a", "c"), 1, "series limit of 2 exceeded for bucket "+bucketA.String())
+
+	// Points of existing series are still written.
+	if err := write(bucketA, "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The organization allows two more series across its buckets.
+	expDropped(write(bucketB, "a", "b", "c"), 1, "series limit of 4 exceeded for organization "+org.String())
+
+	if got, exp := e.SeriesCardinality(), int64(4); got != exp {
+		t.Fatalf("unexpected series cardinality: got %d, exp %d", got, exp)
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(e.PrometheusCollectors()...)
+	mfs, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for limit, bucket := range map[string]influxdb.ID{"bucket": bucketA, "org": bucketB} {
+		m := promtest.MustFindMetric(t, mfs, "storage_series_limits_rejected_points_total", prometheus.Labels{
+			"engine_id": "0",
+			"node_id":   "0",
+			"bucket":    bucket.String(),
+			"limit":     limit,
+		})
+		if got, exp := m.GetCounter().GetValue(), 1.0; got != exp {
+			t.Fatalf("unexpected rejected points by %s limit: got %v, exp %v", limit, got, exp)
+		}
+	}
+
+	// Reloading the limits keeps the series counted since the stats were
+	// cached.
+	if err := e.seriesLimits.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e.seriesLimits.mu.Lock()
+	n := e.seriesLimits.bucketSeries[tsdb.EncodeName(org, bucketA)]
+	e.seriesLimits.mu.Unlock()
+	if got, exp := n, int64(2); got != exp {
+		t.Fatalf("unexpected series of bucket after reload: got %d, exp %d", got, exp)
+	}
+	expDropped(write(bucketA, "c"), 1, "series limit of 2 exceeded for bucket "+bucketA.String())
+	expDropped(write(bucketB, "c"), 1, "series limit of 4 exceeded for organization "+org.String())
+
+	// The series of deleted buckets are no longer counted.
+	if err := e.DeleteBucket(context.Background(), org, bucketA); err != nil {
+		t.Fatal(err)
+	}
+	if err := write(bucketA, "d", "e"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type TestOrganizationFinder struct {
+	FindOrganizationsFn func(context.Context, influxdb.OrganizationFilter, ...influxdb.FindOptions) ([]*influxdb.Organization, int, error)
+}
+
+func (f *TestOrganizationFinder) FindOrganizations(ctx context.Context, filter influxdb.OrganizationFilter, opts ...influxdb.FindOptions) ([]*influxdb.Organization, int, error) {
+	return f.FindOrganizationsFn(ctx, filter, opts...)
+}
@@ -0,0 +1,120 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// archiveSegment adds the closed segment file at path to the archive in dir.
+// Archived segments are named after the time they were archived, followed by
+// their original name, so they sort in the order they were written even if
+// segment IDs start over.
+func archiveSegment(path, dir string) error {
+	dst := filepath.Join(dir, fmt.Sprintf("%019d%s", time.Now().UnixNano(), filepath.Base(path)))
+
+	// The segment is removed from the WAL once archived, so a link is enough
+	// if the archive is on the same file system.
+	if err := os.Link(path, dst); err == nil {
+		return nil
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	f, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(dst)
+		return err
+	}
+	return f.Close()
+}
+
+// ArchivedSegmentFileNames returns all archived segment files in dir, in the
+// order they were written.
+func ArchivedSegmentFileNames(dir string) ([]string, error) {
+	names, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("*%s*.%s", WALFilePrefix, WALFileExtension)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CopyArchivedEntries writes the entries of the archived segment files that
+// were written from the time from up to and including the time until to w, in
+// the order they were written. Entries written before the WAL was archived
+// carry no time and are skipped. Copying stops at the first corrupt entry of a
+// file. It returns the number of entries copied.
+func CopyArchivedEntries(w *WALSegmentWriter, files []string, from, until time.Time) (int, error) {
+	var (
+		n  int
+		ts *TimestampWALEntry
+	)
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return n, err
+		}
+
+		r := NewWALSegmentReader(f)
+		for r.Next() {
+			entry, err := r.Read()
+			if err != nil {
+				break
+			}
+
+			if t, ok := entry.(*TimestampWALEntry); ok {
+				if time.Unix(0, t.Time).After(until) {
+					r.Close()
+					return n, w.Flush()
+				}
+				ts = &TimestampWALEntry{Time: t.Time}
+				continue
+			}
+			if ts == nil || time.Unix(0, ts.Time).Before(from) {
+				continue
+			}
+
+			if err := writeEntry(w, ts); err != nil {
+				r.Close()
+				return n, err
+			}
+			if err := writeEntry(w, entry); err != nil {
+				r.Close()
+				return n, err
+			}
+			n++
+		}
+		if err := r.Close(); err != nil {
+			return n, err
+		}
+	}
+	return n, w.Flush()
+}
+
+// writeEntry encodes, compresses and writes entry to w.
+func writeEntry(w *WALSegmentWriter, entry WALEntry) error {
+	b, err := entry.Encode(nil)
+	if err != nil {
+		return err
+	}
+	return w.Write(entry.Type(), snappy.Encode(nil, b))
+}
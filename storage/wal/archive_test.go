@@ -0,0 +1,107 @@
+package wal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/value"
+)
+
+func TestWAL_Archive(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	archive := filepath.Join(dir, "archive")
+
+	w := NewWAL(filepath.Join(dir, "wal"))
+	w.WithArchivePath(archive)
+	if err := w.Open(context.Background()); err != nil {
+		t.Fatalf("error opening WAL: %v", err)
+	}
+	defer w.Close()
+
+	write := func(key string, v float64) {
+		t.Helper()
+		if _, err := w.WriteMulti(context.Background(), map[string][]value.Value{
+			key: []value.Value{value.NewValue(1, v)},
+		}); err != nil {
+			t.Fatalf("error writing points: %v", err)
+		}
+	}
+
+	write("cpu,host=A#!~#value", 1.1)
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+	write("cpu,host=B#!~#value", 2.2)
+
+	if err := w.CloseSegment(); err != nil {
+		t.Fatalf("error closing segment: %v", err)
+	}
+	files, err := w.ClosedSegments()
+	if err != nil {
+		t.Fatalf("error getting closed segments: %v", err)
+	}
+	if err := w.Remove(context.Background(), files); err != nil {
+		t.Fatalf("error removing segments: %v", err)
+	}
+
+	for _, fn := range files {
+		if _, err := os.Stat(fn); !os.IsNotExist(err) {
+			t.Fatalf("segment %s not removed: %v", fn, err)
+		}
+	}
+
+	archived, err := ArchivedSegmentFileNames(archive)
+	if err != nil {
+		t.Fatalf("error listing archive: %v", err)
+	}
+	if got, exp := len(archived), len(files); got != exp {
+		t.Fatalf("archived segment length mismatch: got %v, exp %v", got, exp)
+	}
+
+	f := MustTempFile(dir)
+	n, err := CopyArchivedEntries(NewWALSegmentWriter(f), archived, time.Time{}, mid)
+	if err != nil {
+		t.Fatalf("error copying archived entries: %v", err)
+	}
+	if got, exp := n, 1; got != exp {
+		t.Fatalf("copied entries mismatch: got %v, exp %v", got, exp)
+	}
+
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatalf("error seeking: %v", err)
+	}
+	r := NewWALSegmentReader(f)
+	defer r.Close()
+
+	var entries []WALEntry
+	for r.Next() {
+		entry, err := r.Read()
+		if err != nil {
+			t.Fatalf("error reading entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if got, exp := len(entries), 2; got != exp {
+		t.Fatalf("entry length mismatch: got %v, exp %v", got, exp)
+	}
+
+	ts, ok := entries[0].(*TimestampWALEntry)
+	if !ok {
+		t.Fatalf("expected *TimestampWALEntry: got %#v", entries[0])
+	}
+	if !time.Unix(0, ts.Time).Before(mid) {
+		t.Fatalf("entry written after %v: got %v", mid, time.Unix(0, ts.Time))
+	}
+
+	e, ok := entries[1].(*WriteWALEntry)
+	if !ok {
+		t.Fatalf("expected *WriteWALEntry: got %#v", entries[1])
+	}
+	if _, ok := e.Values["cpu,host=A#!~#value"]; !ok || len(e.Values) != 1 {
+		t.Fatalf("unexpected values: got %v", e.Values)
+	}
+}
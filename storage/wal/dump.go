@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/influxdata/influxdb/storage/reads/datatypes"
 	"github.com/influxdata/influxdb/tsdb"
@@ -206,6 +207,10 @@ func (w *Dump) process(path string, stdout, stderr io.Writer) (*DumpReport, erro
 				fmt.Fprintf(stdout, "[delete-bucket-range] org=%s bucket=%s min=%d max=%d sz=%d pred=%s\n", orgID, bucketID, entry.Min, entry.Max, sz, pred.String())
 			}
 			report.Deletes = append(report.Deletes, entry)
+		case *TimestampWALEntry:
+			if !w.FindDuplicates {
+				fmt.Fprintf(stdout, "[timestamp] %s\n", time.Unix(0, entry.Time).UTC().Format(time.RFC3339Nano))
+			}
 		default:
 			return nil, fmt.Errorf("invalid wal entry: %#v", entry)
 		}
@@ -63,6 +63,10 @@ const (
 
 	// DeleteBucketRangeWALEntryType indicates a delete bucket range entry.
 	DeleteBucketRangeWALEntryType WalEntryType = 0x04
+
+	// TimestampWALEntryType indicates the wall-clock time at which the following
+	// entry was written. It is only written when the WAL is archived.
+	TimestampWALEntryType WalEntryType = 0x05
 )
 
 var (
@@ -109,6 +113,13 @@ type WAL struct {
 	// SegmentSize is the file size at which a segment file will be rotated
 	SegmentSize int
 
+	// archivePath is the directory closed segments are archived to before they
+	// are removed. Archiving is disabled if it is empty.
+	archivePath string
+
+	// replicator is passed the entries written to the log, if set.
+	replicator Replicator
+
 	tracker             *walTracker
 	defaultMetricLabels prometheus.Labels // N.B this must not be mutated after Open is called.
 
@@ -136,6 +147,30 @@ func (l *WAL) WithFsyncDelay(delay time.Duration) {
 	l.syncDelay = delay
 }
 
+// WithArchivePath sets the directory closed segments are archived to before
+// they are removed, and should be called before the WAL is opened. Entries of
+// an archived WAL are preceded by the time they were written, so the archive
+// can be replayed up to a point in time.
+func (l *WAL) WithArchivePath(path string) {
+	l.archivePath = path
+}
+
+// Replicator receives the entries written to a WAL, in the order they are
+// written, so they can be applied to a copy of the WAL elsewhere.
+type Replicator interface {
+	// Replicate is called with the type and the snappy compressed encoding of
+	// each entry once it is written to the current segment. compressed is only
+	// valid until Replicate returns. Replicate must not block.
+	Replicate(typ WalEntryType, compressed []byte)
+}
+
+// WithReplicator sets the replicator entries are passed to once written, and
+// should be called before the WAL is opened. Entries are not replicated if the
+// WAL is disabled.
+func (l *WAL) WithReplicator(r Replicator) {
+	l.replicator = r
+}
+
 // SetEnabled sets if the WAL is enabled and should be called before the WAL is opened.
 func (l *WAL) SetEnabled(enabled bool) {
 	l.enabled = enabled
@@ -190,6 +225,11 @@ func (l *WAL) Open(ctx context.Context) error {
 	if err := os.MkdirAll(l.path, 0777); err != nil {
 		return err
 	}
+	if l.archivePath != "" {
+		if err := os.MkdirAll(l.archivePath, 0777); err != nil {
+			return err
+		}
+	}
 
 	segments, err := SegmentFileNames(l.path)
 	if err != nil {
@@ -380,6 +420,14 @@ func (l *WAL) Remove(ctx context.Context, files []string) error {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	if l.archivePath != "" {
+		for _, fn := range files {
+			if err := archiveSegment(fn, l.archivePath); err != nil {
+				return fmt.Errorf("error archiving WAL segment: %v", err)
+			}
+		}
+	}
+
 	for i, fn := range files {
 		span.LogKV(fmt.Sprintf("path-%d", i), fn)
 		os.RemoveAll(fn)
@@ -452,11 +500,25 @@ func (l *WAL) writeToLog(entry WALEntry) (int, error) {
 			return -1, fmt.Errorf("error rolling WAL segment: %v", err)
 		}
 
+		// record when the entry was written, so an archive can be replayed up to a point in time
+		if l.archivePath != "" {
+			ts := TimestampWALEntry{Time: time.Now().UnixNano()}
+			b, _ := ts.MarshalBinary()
+			if err := l.currentSegmentWriter.Write(ts.Type(), snappy.Encode(nil, b)); err != nil {
+				return -1, fmt.Errorf("error writing WAL entry: %v", err)
+			}
+		}
+
 		// write and sync
 		if err := l.currentSegmentWriter.Write(entry.Type(), compressed); err != nil {
 			return -1, fmt.Errorf("error writing WAL entry: %v", err)
 		}
 
+		// replicate under the lock, so entries are replicated in the order they are written
+		if l.replicator != nil {
+			l.replicator.Replicate(entry.Type(), compressed)
+		}
+
 		select {
 		case l.syncWaiters <- syncErr:
 		default:
@@ -1065,6 +1127,50 @@ func (w *DeleteBucketRangeWALEntry) Type() WalEntryType {
 	return DeleteBucketRangeWALEntryType
 }
 
+// timestampEntrySize is the marshaled size of a TimestampWALEntry.
+const timestampEntrySize = 8
+
+// TimestampWALEntry records the wall-clock time, in nanoseconds since the
+// Unix epoch, at which the following entry was written.
+type TimestampWALEntry struct {
+	Time int64
+}
+
+// MarshalBinary returns a binary representation of the entry in a new byte slice.
+func (w *TimestampWALEntry) MarshalBinary() ([]byte, error) {
+	b := make([]byte, w.MarshalSize())
+	return w.Encode(b)
+}
+
+// UnmarshalBinary deserializes the byte slice into w.
+func (w *TimestampWALEntry) UnmarshalBinary(b []byte) error {
+	if len(b) != timestampEntrySize {
+		return ErrWALCorrupt
+	}
+	w.Time = int64(binary.BigEndian.Uint64(b))
+	return nil
+}
+
+// MarshalSize returns the number of bytes the entry takes when marshaled.
+func (w *TimestampWALEntry) MarshalSize() int {
+	return timestampEntrySize
+}
+
+// Encode converts the entry into a byte stream using b if it is large enough.
+// If b is too small, a newly allocated slice is returned.
+func (w *TimestampWALEntry) Encode(b []byte) ([]byte, error) {
+	if len(b) < timestampEntrySize {
+		b = make([]byte, timestampEntrySize)
+	}
+	binary.BigEndian.PutUint64(b, uint64(w.Time))
+	return b[:timestampEntrySize], nil
+}
+
+// Type returns TimestampWALEntryType.
+func (w *TimestampWALEntry) Type() WalEntryType {
+	return TimestampWALEntryType
+}
+
 // WALSegmentWriter writes WAL segments.
 type WALSegmentWriter struct {
 	bw   *bufio.Writer
@@ -1188,39 +1294,48 @@ func (r *WALSegmentReader) Next() bool {
 	}
 	nReadOK += n
 
-	decLen, err := snappy.DecodedLen(b[:length])
+	r.entry, r.err = DecodeEntry(WalEntryType(entryType), b[:length])
+	if r.err == nil {
+		// Read and decode of this entry was successful.
+		r.n += int64(nReadOK)
+	}
+
+	return true
+}
+
+// DecodeEntry decodes an entry of type typ from its snappy compressed encoding,
+// as written to a segment or passed to a Replicator.
+func DecodeEntry(typ WalEntryType, compressed []byte) (WALEntry, error) {
+	decLen, err := snappy.DecodedLen(compressed)
 	if err != nil {
-		r.err = err
-		return true
+		return nil, err
 	}
 	decBuf := *(getBuf(decLen))
 	defer putBuf(&decBuf)
 
-	data, err := snappy.Decode(decBuf, b[:length])
+	data, err := snappy.Decode(decBuf, compressed)
 	if err != nil {
-		r.err = err
-		return true
+		return nil, err
 	}
 
 	// and marshal it and send it to the cache
-	switch WalEntryType(entryType) {
+	var entry WALEntry
+	switch typ {
 	case WriteWALEntryType:
-		r.entry = &WriteWALEntry{
+		entry = &WriteWALEntry{
 			Values: make(map[string][]value.Value),
 		}
 	case DeleteBucketRangeWALEntryType:
-		r.entry = &DeleteBucketRangeWALEntry{}
+		entry = &DeleteBucketRangeWALEntry{}
+	case TimestampWALEntryType:
+		entry = &TimestampWALEntry{}
 	default:
-		r.err = fmt.Errorf("unknown wal entry type: %v", entryType)
-		return true
+		return nil, fmt.Errorf("unknown wal entry type: %v", byte(typ))
 	}
-	r.err = r.entry.UnmarshalBinary(data)
-	if r.err == nil {
-		// Read and decode of this entry was successful.
-		r.n += int64(nReadOK)
+	if err := entry.UnmarshalBinary(data); err != nil {
+		return nil, err
 	}
-
-	return true
+	return entry, nil
 }
 
 // Read returns the next entry in the reader.
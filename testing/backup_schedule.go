@@ -0,0 +1,813 @@
+package testing
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+const (
+	backupScheduleOneID = "020f755c3c084000"
+	backupScheduleTwoID = "020f755c3c084001"
+	backupScheduleNewID = "020f755c3c084002"
+	backupRunNewID      = "020f755c3c08ffff"
+)
+
+var backupScheduleCmpOptions = cmp.Options{
+	cmp.Transformer("Sort", func(in []*platform.BackupSchedule) []*platform.BackupSchedule {
+		out := append([]*platform.BackupSchedule(nil), in...) // Copy input to avoid mutating it
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].ID.String() < out[j].ID.String()
+		})
+		return out
+	}),
+}
+
+// BackupScheduleRunService is the service of backup schedules and their runs.
+type BackupScheduleRunService interface {
+	platform.BackupScheduleService
+	platform.BackupRunService
+}
+
+// BackupScheduleFields will include the IDGenerator, TimeGenerator, backup
+// schedules, and backup runs
+type BackupScheduleFields struct {
+	IDGenerator     platform.IDGenerator
+	TimeGenerator   platform.TimeGenerator
+	BackupSchedules []*platform.BackupSchedule
+	BackupRuns      []*platform.BackupRun
+}
+
+func nightlyBackupSchedule() *platform.BackupSchedule {
+	return &platform.BackupSchedule{
+		ID:        MustIDBase16(backupScheduleOneID),
+		Name:      "nightly",
+		Cron:      "0 2 * * *",
+		Path:      "nightly",
+		Retention: 7,
+		Status:    platform.Active,
+		CRUDLog: platform.CRUDLog{
+			CreatedAt: oldFakeDate,
+			UpdatedAt: oldFakeDate,
+		},
+	}
+}
+
+func weeklyBackupSchedule() *platform.BackupSchedule {
+	return &platform.BackupSchedule{
+		ID:     MustIDBase16(backupScheduleTwoID),
+		Name:   "weekly",
+		Cron:   "0 3 * * 0",
+		Status: platform.Inactive,
+		CRUDLog: platform.CRUDLog{
+			CreatedAt: oldFakeDate,
+			UpdatedAt: oldFakeDate,
+		},
+	}
+}
+
+// backupRuns returns n runs of the schedule, oldest first.
+func backupRuns(scheduleID platform.ID, n int) []*platform.BackupRun {
+	runs := make([]*platform.BackupRun, n)
+	for i := range runs {
+		runs[i] = &platform.BackupRun{
+			ID:         platform.ID(0x1000 + i),
+			ScheduleID: scheduleID,
+			Status:     platform.BackupRunSuccess,
+		}
+	}
+	return runs
+}
+
+// BackupScheduleService tests all the service functions.
+func BackupScheduleService(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()), t *testing.T,
+) {
+	tests := []struct {
+		name string
+		fn   func(init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+			t *testing.T)
+	}{
+		{
+			name: "CreateBackupSchedule",
+			fn:   CreateBackupSchedule,
+		},
+		{
+			name: "FindBackupScheduleByID",
+			fn:   FindBackupScheduleByID,
+		},
+		{
+			name: "FindBackupSchedules",
+			fn:   FindBackupSchedules,
+		},
+		{
+			name: "UpdateBackupSchedule",
+			fn:   UpdateBackupSchedule,
+		},
+		{
+			name: "DeleteBackupSchedule",
+			fn:   DeleteBackupSchedule,
+		},
+		{
+			name: "FindBackupRuns",
+			fn:   FindBackupRuns,
+		},
+		{
+			name: "CreateBackupRun",
+			fn:   CreateBackupRun,
+		},
+		{
+			name: "UpdateBackupRun",
+			fn:   UpdateBackupRun,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(init, t)
+		})
+	}
+}
+
+// CreateBackupSchedule testing
+func CreateBackupSchedule(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		schedule *platform.BackupSchedule
+	}
+	type wants struct {
+		err       error
+		schedules []*platform.BackupSchedule
+	}
+
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "create schedule assigns an id and active status",
+			fields: BackupScheduleFields{
+				IDGenerator:     mock.NewIDGenerator(backupScheduleNewID, t),
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				schedule: &platform.BackupSchedule{
+					Name: "hourly",
+					Cron: "0 * * * *",
+				},
+			},
+			wants: wants{
+				schedules: []*platform.BackupSchedule{
+					nightlyBackupSchedule(),
+					{
+						ID:     MustIDBase16(backupScheduleNewID),
+						Name:   "hourly",
+						Cron:   "0 * * * *",
+						Status: platform.Active,
+						CRUDLog: platform.CRUDLog{
+							CreatedAt: fakeDate,
+							UpdatedAt: fakeDate,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid cron expression",
+			fields: BackupScheduleFields{
+				IDGenerator:     mock.NewIDGenerator(backupScheduleNewID, t),
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				schedule: &platform.BackupSchedule{
+					Name: "hourly",
+					Cron: "not cron",
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  `invalid cron expression "not cron"`,
+				},
+				schedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+		},
+		{
+			name: "names are unique",
+			fields: BackupScheduleFields{
+				IDGenerator:     mock.NewIDGenerator(backupScheduleNewID, t),
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				schedule: &platform.BackupSchedule{
+					Name: "nightly",
+					Cron: "0 3 * * *",
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EConflict,
+					Msg:  "backup schedule with name nightly already exists",
+				},
+				schedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.CreateBackupSchedule(ctx, tt.args.schedule)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			schedules, _, err := s.FindBackupSchedules(ctx, platform.BackupScheduleFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve backup schedules: %v", err)
+			}
+			if diff := cmp.Diff(schedules, tt.wants.schedules, backupScheduleCmpOptions...); diff != "" {
+				t.Errorf("backup schedules are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindBackupScheduleByID testing
+func FindBackupScheduleByID(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err      error
+		schedule *platform.BackupSchedule
+	}
+
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find schedule by id",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			args: args{
+				id: MustIDBase16(backupScheduleTwoID),
+			},
+			wants: wants{
+				schedule: weeklyBackupSchedule(),
+			},
+		},
+		{
+			name: "schedule not found",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				id: MustIDBase16(backupScheduleTwoID),
+			},
+			wants: wants{
+				err: platform.ErrBackupScheduleNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			schedule, err := s.FindBackupScheduleByID(ctx, tt.args.id)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(schedule, tt.wants.schedule); diff != "" {
+				t.Errorf("backup schedules are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindBackupSchedules testing
+func FindBackupSchedules(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		filter platform.BackupScheduleFilter
+	}
+	type wants struct {
+		err       error
+		schedules []*platform.BackupSchedule
+	}
+
+	name := "weekly"
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find all schedules",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			wants: wants{
+				schedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+		},
+		{
+			name: "find active schedules",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			args: args{
+				filter: platform.BackupScheduleFilter{Status: platform.Active.Ptr()},
+			},
+			wants: wants{
+				schedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+		},
+		{
+			name: "find schedules by name",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			args: args{
+				filter: platform.BackupScheduleFilter{Name: &name},
+			},
+			wants: wants{
+				schedules: []*platform.BackupSchedule{weeklyBackupSchedule()},
+			},
+		},
+		{
+			name: "no schedules",
+			wants: wants{
+				schedules: []*platform.BackupSchedule{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			schedules, n, err := s.FindBackupSchedules(ctx, tt.args.filter)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if n != len(tt.wants.schedules) {
+				t.Errorf("got %d backup schedules, want %d", n, len(tt.wants.schedules))
+			}
+			if diff := cmp.Diff(schedules, tt.wants.schedules, backupScheduleCmpOptions...); diff != "" {
+				t.Errorf("backup schedules are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// UpdateBackupSchedule testing
+func UpdateBackupSchedule(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id  platform.ID
+		upd platform.BackupScheduleUpdate
+	}
+	type wants struct {
+		err      error
+		schedule *platform.BackupSchedule
+	}
+
+	cron, name := "0 4 * * *", "weekly"
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "update cron",
+			fields: BackupScheduleFields{
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			args: args{
+				id:  MustIDBase16(backupScheduleOneID),
+				upd: platform.BackupScheduleUpdate{Cron: &cron},
+			},
+			wants: wants{
+				schedule: &platform.BackupSchedule{
+					ID:        MustIDBase16(backupScheduleOneID),
+					Name:      "nightly",
+					Cron:      cron,
+					Path:      "nightly",
+					Retention: 7,
+					Status:    platform.Active,
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: oldFakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+		{
+			name: "update name to the name of another schedule",
+			fields: BackupScheduleFields{
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+			},
+			args: args{
+				id:  MustIDBase16(backupScheduleOneID),
+				upd: platform.BackupScheduleUpdate{Name: &name},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EConflict,
+					Msg:  "backup schedule with name weekly already exists",
+				},
+			},
+		},
+		{
+			name: "update missing schedule",
+			fields: BackupScheduleFields{
+				TimeGenerator:   fakeGenerator,
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				id:  MustIDBase16(backupScheduleTwoID),
+				upd: platform.BackupScheduleUpdate{Cron: &cron},
+			},
+			wants: wants{
+				err: platform.ErrBackupScheduleNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			schedule, err := s.UpdateBackupSchedule(ctx, tt.args.id, tt.args.upd)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(schedule, tt.wants.schedule); diff != "" {
+				t.Errorf("backup schedules are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// DeleteBackupSchedule testing
+func DeleteBackupSchedule(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err       error
+		schedules []*platform.BackupSchedule
+	}
+
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "delete schedule and its runs",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+				BackupRuns:      backupRuns(MustIDBase16(backupScheduleOneID), 2),
+			},
+			args: args{
+				id: MustIDBase16(backupScheduleOneID),
+			},
+			wants: wants{
+				schedules: []*platform.BackupSchedule{weeklyBackupSchedule()},
+			},
+		},
+		{
+			name: "delete missing schedule",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{weeklyBackupSchedule()},
+			},
+			args: args{
+				id: MustIDBase16(backupScheduleOneID),
+			},
+			wants: wants{
+				err:       platform.ErrBackupScheduleNotFound,
+				schedules: []*platform.BackupSchedule{weeklyBackupSchedule()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.DeleteBackupSchedule(ctx, tt.args.id)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			schedules, _, err := s.FindBackupSchedules(ctx, platform.BackupScheduleFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve backup schedules: %v", err)
+			}
+			if diff := cmp.Diff(schedules, tt.wants.schedules, backupScheduleCmpOptions...); diff != "" {
+				t.Errorf("backup schedules are different -got/+want\ndiff %s", diff)
+			}
+
+			for _, r := range tt.fields.BackupRuns {
+				if _, err := s.FindBackupRunByID(ctx, r.ScheduleID, r.ID); platform.ErrorCode(err) != platform.ENotFound {
+					t.Errorf("expected backup run %s to be deleted: %v", r.ID, err)
+				}
+			}
+		})
+	}
+}
+
+// FindBackupRuns testing
+func FindBackupRuns(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		scheduleID platform.ID
+	}
+	type wants struct {
+		err  error
+		runs []*platform.BackupRun
+	}
+
+	runs := append(backupRuns(MustIDBase16(backupScheduleOneID), 2), backupRuns(MustIDBase16(backupScheduleTwoID), 1)...)
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find runs of a schedule newest first",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule(), weeklyBackupSchedule()},
+				BackupRuns:      runs,
+			},
+			args: args{
+				scheduleID: MustIDBase16(backupScheduleOneID),
+			},
+			wants: wants{
+				runs: []*platform.BackupRun{runs[1], runs[0]},
+			},
+		},
+		{
+			name: "schedule without runs",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				scheduleID: MustIDBase16(backupScheduleOneID),
+			},
+			wants: wants{
+				runs: []*platform.BackupRun{},
+			},
+		},
+		{
+			name: "missing schedule",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				scheduleID: MustIDBase16(backupScheduleTwoID),
+			},
+			wants: wants{
+				err: platform.ErrBackupScheduleNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			runs, n, err := s.FindBackupRuns(ctx, tt.args.scheduleID)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if n != len(tt.wants.runs) {
+				t.Errorf("got %d backup runs, want %d", n, len(tt.wants.runs))
+			}
+			if diff := cmp.Diff(runs, tt.wants.runs); diff != "" {
+				t.Errorf("backup runs are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// CreateBackupRun testing
+func CreateBackupRun(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		run *platform.BackupRun
+	}
+	type wants struct {
+		err     error
+		deleted []platform.ID
+		runs    int
+	}
+
+	full := backupRuns(MustIDBase16(backupScheduleOneID), platform.MaxBackupRuns)
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "create run assigns an id",
+			fields: BackupScheduleFields{
+				IDGenerator:     mock.NewIDGenerator(backupRunNewID, t),
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				run: &platform.BackupRun{
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunStarted,
+				},
+			},
+			wants: wants{
+				runs: 1,
+			},
+		},
+		{
+			name: "oldest runs are deleted",
+			fields: BackupScheduleFields{
+				IDGenerator:     mock.NewIDGenerator(backupRunNewID, t),
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+				BackupRuns:      full,
+			},
+			args: args{
+				run: &platform.BackupRun{
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunStarted,
+				},
+			},
+			wants: wants{
+				deleted: []platform.ID{full[0].ID},
+				runs:    platform.MaxBackupRuns,
+			},
+		},
+		{
+			name: "missing schedule",
+			fields: BackupScheduleFields{
+				IDGenerator: mock.NewIDGenerator(backupRunNewID, t),
+			},
+			args: args{
+				run: &platform.BackupRun{
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunStarted,
+				},
+			},
+			wants: wants{
+				err: platform.ErrBackupScheduleNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.CreateBackupRun(ctx, tt.args.run)
+			ErrorsEqual(t, err, tt.wants.err)
+			if tt.wants.err != nil {
+				return
+			}
+
+			if got, want := tt.args.run.ID, MustIDBase16(backupRunNewID); got != want {
+				t.Errorf("got backup run ID %s, want %s", got, want)
+			}
+			runs, n, err := s.FindBackupRuns(ctx, tt.args.run.ScheduleID)
+			if err != nil {
+				t.Fatalf("failed to retrieve backup runs: %v", err)
+			}
+			if n != tt.wants.runs {
+				t.Errorf("got %d backup runs, want %d", n, tt.wants.runs)
+			}
+			if diff := cmp.Diff(runs[0], tt.args.run); diff != "" {
+				t.Errorf("latest backup run is different -got/+want\ndiff %s", diff)
+			}
+			for _, id := range tt.wants.deleted {
+				if _, err := s.FindBackupRunByID(ctx, tt.args.run.ScheduleID, id); platform.ErrorCode(err) != platform.ENotFound {
+					t.Errorf("expected backup run %s to be deleted: %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+// UpdateBackupRun testing
+func UpdateBackupRun(
+	init func(BackupScheduleFields, *testing.T) (BackupScheduleRunService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		run *platform.BackupRun
+	}
+	type wants struct {
+		err error
+		run *platform.BackupRun
+	}
+
+	tests := []struct {
+		name   string
+		fields BackupScheduleFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "update status of a run",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+				BackupRuns:      backupRuns(MustIDBase16(backupScheduleOneID), 1),
+			},
+			args: args{
+				run: &platform.BackupRun{
+					ID:         platform.ID(0x1000),
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunFailed,
+					Error:      "upload failed",
+				},
+			},
+			wants: wants{
+				run: &platform.BackupRun{
+					ID:         platform.ID(0x1000),
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunFailed,
+					Error:      "upload failed",
+				},
+			},
+		},
+		{
+			name: "update missing run",
+			fields: BackupScheduleFields{
+				BackupSchedules: []*platform.BackupSchedule{nightlyBackupSchedule()},
+			},
+			args: args{
+				run: &platform.BackupRun{
+					ID:         platform.ID(0x1000),
+					ScheduleID: MustIDBase16(backupScheduleOneID),
+					Status:     platform.BackupRunFailed,
+				},
+			},
+			wants: wants{
+				err: platform.ErrBackupRunNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.UpdateBackupRun(ctx, tt.args.run)
+			ErrorsEqual(t, err, tt.wants.err)
+			if tt.wants.err != nil {
+				return
+			}
+
+			run, err := s.FindBackupRunByID(ctx, tt.args.run.ScheduleID, tt.args.run.ID)
+			if err != nil {
+				t.Fatalf("failed to retrieve backup run: %v", err)
+			}
+			if diff := cmp.Diff(run, tt.wants.run); diff != "" {
+				t.Errorf("backup runs are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
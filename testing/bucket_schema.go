@@ -0,0 +1,455 @@
+package testing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+)
+
+const (
+	schemaOrgOneID    = "020f755c3c083000"
+	schemaOrgTwoID    = "020f755c3c083001"
+	schemaBucketOneID = "020f755c3c083010"
+	schemaBucketTwoID = "020f755c3c083011"
+	schemaForeignID   = "020f755c3c083012"
+	schemaMissingID   = "020f755c3c083013"
+)
+
+// BucketSchemaFields will include the TimeGenerator, buckets, and bucket schemas
+type BucketSchemaFields struct {
+	TimeGenerator platform.TimeGenerator
+	Buckets       []*platform.Bucket
+	BucketSchemas []*platform.BucketSchema
+}
+
+func schemaBuckets() []*platform.Bucket {
+	return []*platform.Bucket{
+		{
+			ID:    MustIDBase16(schemaBucketOneID),
+			OrgID: MustIDBase16(schemaOrgOneID),
+			Name:  "metrics",
+		},
+		{
+			ID:    MustIDBase16(schemaBucketTwoID),
+			OrgID: MustIDBase16(schemaOrgOneID),
+			Name:  "rejected",
+		},
+		{
+			ID:    MustIDBase16(schemaForeignID),
+			OrgID: MustIDBase16(schemaOrgTwoID),
+			Name:  "foreign",
+		},
+	}
+}
+
+func cpuSchema() []platform.MeasurementSchema {
+	return []platform.MeasurementSchema{
+		{
+			Name:   "cpu",
+			Tags:   []string{"host"},
+			Fields: []platform.FieldSchema{{Name: "usage", Type: platform.SchemaFieldFloat}},
+		},
+	}
+}
+
+// BucketSchemaService tests all the service functions.
+func BucketSchemaService(
+	init func(BucketSchemaFields, *testing.T) (platform.BucketSchemaService, func()), t *testing.T,
+) {
+	tests := []struct {
+		name string
+		fn   func(init func(BucketSchemaFields, *testing.T) (platform.BucketSchemaService, func()),
+			t *testing.T)
+	}{
+		{
+			name: "PutBucketSchema",
+			fn:   PutBucketSchema,
+		},
+		{
+			name: "FindBucketSchema",
+			fn:   FindBucketSchema,
+		},
+		{
+			name: "DeleteBucketSchema",
+			fn:   DeleteBucketSchema,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(init, t)
+		})
+	}
+}
+
+// PutBucketSchema testing
+func PutBucketSchema(
+	init func(BucketSchemaFields, *testing.T) (platform.BucketSchemaService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		schema *platform.BucketSchema
+	}
+	type wants struct {
+		err    error
+		schema *platform.BucketSchema
+	}
+
+	tests := []struct {
+		name   string
+		fields BucketSchemaFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "put schema of a bucket",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID:     MustIDBase16(schemaBucketOneID),
+					Action:       platform.SchemaActionReject,
+					Measurements: cpuSchema(),
+				},
+			},
+			wants: wants{
+				schema: &platform.BucketSchema{
+					BucketID:     MustIDBase16(schemaBucketOneID),
+					OrgID:        MustIDBase16(schemaOrgOneID),
+					Action:       platform.SchemaActionReject,
+					Measurements: cpuSchema(),
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: fakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+		{
+			name: "replacing a schema keeps its creation time",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+				BucketSchemas: []*platform.BucketSchema{
+					{
+						BucketID:     MustIDBase16(schemaBucketOneID),
+						Action:       platform.SchemaActionReject,
+						Measurements: cpuSchema(),
+						CRUDLog: platform.CRUDLog{
+							CreatedAt: oldFakeDate,
+						},
+					},
+				},
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID:      MustIDBase16(schemaBucketOneID),
+					Action:        platform.SchemaActionRoute,
+					RouteBucketID: MustIDBase16(schemaBucketTwoID),
+					Measurements:  cpuSchema(),
+				},
+			},
+			wants: wants{
+				schema: &platform.BucketSchema{
+					BucketID:      MustIDBase16(schemaBucketOneID),
+					OrgID:         MustIDBase16(schemaOrgOneID),
+					Action:        platform.SchemaActionRoute,
+					RouteBucketID: MustIDBase16(schemaBucketTwoID),
+					Measurements:  cpuSchema(),
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: oldFakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+		{
+			name: "unknown action",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID: MustIDBase16(schemaBucketOneID),
+					Action:   "drop",
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  `unknown bucket schema action "drop"`,
+				},
+			},
+		},
+		{
+			name: "route without route bucket",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID: MustIDBase16(schemaBucketOneID),
+					Action:   platform.SchemaActionRoute,
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "routing bucket schema requires a valid route bucket ID",
+				},
+			},
+		},
+		{
+			name: "route to a bucket of another organization",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID:      MustIDBase16(schemaBucketOneID),
+					Action:        platform.SchemaActionRoute,
+					RouteBucketID: MustIDBase16(schemaForeignID),
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "route bucket must belong to the organization of the bucket",
+				},
+			},
+		},
+		{
+			name: "unknown field type",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID: MustIDBase16(schemaBucketOneID),
+					Action:   platform.SchemaActionReject,
+					Measurements: []platform.MeasurementSchema{
+						{Name: "cpu", Fields: []platform.FieldSchema{{Name: "usage", Type: "decimal"}}},
+					},
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  `unknown field type "decimal"`,
+				},
+			},
+		},
+		{
+			name: "measurement defined twice",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID:     MustIDBase16(schemaBucketOneID),
+					Action:       platform.SchemaActionReject,
+					Measurements: []platform.MeasurementSchema{{Name: "cpu"}, {Name: "cpu"}},
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  `measurement "cpu" is defined more than once`,
+				},
+			},
+		},
+		{
+			name: "missing bucket",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				schema: &platform.BucketSchema{
+					BucketID: MustIDBase16(schemaMissingID),
+					Action:   platform.SchemaActionReject,
+				},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.ENotFound,
+					Msg:  "bucket not found",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.PutBucketSchema(ctx, tt.args.schema)
+			ErrorsEqual(t, err, tt.wants.err)
+			if tt.wants.err != nil {
+				return
+			}
+
+			schema, err := s.FindBucketSchema(ctx, tt.args.schema.BucketID)
+			if err != nil {
+				t.Fatalf("failed to retrieve bucket schema: %v", err)
+			}
+			if diff := cmp.Diff(schema, tt.wants.schema); diff != "" {
+				t.Errorf("bucket schemas are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindBucketSchema testing
+func FindBucketSchema(
+	init func(BucketSchemaFields, *testing.T) (platform.BucketSchemaService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		bucketID platform.ID
+	}
+	type wants struct {
+		err    error
+		schema *platform.BucketSchema
+	}
+
+	tests := []struct {
+		name   string
+		fields BucketSchemaFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find schema of a bucket",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+				BucketSchemas: []*platform.BucketSchema{
+					{
+						BucketID:     MustIDBase16(schemaBucketOneID),
+						Action:       platform.SchemaActionReject,
+						Measurements: cpuSchema(),
+						CRUDLog: platform.CRUDLog{
+							CreatedAt: fakeDate,
+						},
+					},
+				},
+			},
+			args: args{
+				bucketID: MustIDBase16(schemaBucketOneID),
+			},
+			wants: wants{
+				schema: &platform.BucketSchema{
+					BucketID:     MustIDBase16(schemaBucketOneID),
+					OrgID:        MustIDBase16(schemaOrgOneID),
+					Action:       platform.SchemaActionReject,
+					Measurements: cpuSchema(),
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: fakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+		{
+			name: "bucket without schema",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				bucketID: MustIDBase16(schemaBucketOneID),
+			},
+			wants: wants{
+				err: platform.ErrBucketSchemaNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			schema, err := s.FindBucketSchema(ctx, tt.args.bucketID)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(schema, tt.wants.schema); diff != "" {
+				t.Errorf("bucket schemas are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// DeleteBucketSchema testing
+func DeleteBucketSchema(
+	init func(BucketSchemaFields, *testing.T) (platform.BucketSchemaService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		bucketID platform.ID
+	}
+	type wants struct {
+		err error
+	}
+
+	tests := []struct {
+		name   string
+		fields BucketSchemaFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "delete schema of a bucket",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+				BucketSchemas: []*platform.BucketSchema{
+					{
+						BucketID: MustIDBase16(schemaBucketOneID),
+						Action:   platform.SchemaActionReject,
+					},
+				},
+			},
+			args: args{
+				bucketID: MustIDBase16(schemaBucketOneID),
+			},
+		},
+		{
+			name: "delete missing schema",
+			fields: BucketSchemaFields{
+				TimeGenerator: fakeGenerator,
+				Buckets:       schemaBuckets(),
+			},
+			args: args{
+				bucketID: MustIDBase16(schemaBucketOneID),
+			},
+			wants: wants{
+				err: platform.ErrBucketSchemaNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.DeleteBucketSchema(ctx, tt.args.bucketID)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if _, err := s.FindBucketSchema(ctx, tt.args.bucketID); platform.ErrorCode(err) != platform.ENotFound {
+				t.Errorf("expected bucket schema to be deleted: %v", err)
+			}
+		})
+	}
+}
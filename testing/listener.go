@@ -0,0 +1,730 @@
+package testing
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+const (
+	listenerOrgOneID    = "020f755c3c085000"
+	listenerOrgTwoID    = "020f755c3c085001"
+	listenerBucketID    = "020f755c3c085010"
+	listenerForeignID   = "020f755c3c085011"
+	listenerWriterID    = "020f755c3c085020"
+	listenerReaderID    = "020f755c3c085021"
+	listenerOneID       = "020f755c3c085030"
+	listenerTwoID       = "020f755c3c085031"
+	listenerNewID       = "020f755c3c085032"
+	listenerUserID      = "020f755c3c085040"
+	listenerWriterToken = "writer"
+	listenerReaderToken = "reader"
+)
+
+var listenerCmpOptions = cmp.Options{
+	cmp.Transformer("Sort", func(in []*platform.Listener) []*platform.Listener {
+		out := append([]*platform.Listener(nil), in...) // Copy input to avoid mutating it
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].ID.String() < out[j].ID.String()
+		})
+		return out
+	}),
+}
+
+// ListenerAndOffsetService is the service of listeners and their offsets.
+type ListenerAndOffsetService interface {
+	platform.ListenerService
+	platform.ListenerOffsetService
+}
+
+// ListenerFields will include the IDGenerator, TimeGenerator, buckets,
+// authorizations, and listeners
+type ListenerFields struct {
+	IDGenerator    platform.IDGenerator
+	TimeGenerator  platform.TimeGenerator
+	Buckets        []*platform.Bucket
+	Authorizations []*platform.Authorization
+	Listeners      []*platform.Listener
+}
+
+func listenerBuckets() []*platform.Bucket {
+	return []*platform.Bucket{
+		{
+			ID:    MustIDBase16(listenerBucketID),
+			OrgID: MustIDBase16(listenerOrgOneID),
+			Name:  "sensors",
+		},
+		{
+			ID:    MustIDBase16(listenerForeignID),
+			OrgID: MustIDBase16(listenerOrgTwoID),
+			Name:  "foreign",
+		},
+	}
+}
+
+// listenerAuthorizations returns an authorization allowed to write to the
+// bucket of the listeners, and one only allowed to read it.
+func listenerAuthorizations() []*platform.Authorization {
+	orgID, bucketID := MustIDBase16(listenerOrgOneID), MustIDBase16(listenerBucketID)
+	return []*platform.Authorization{
+		{
+			ID:     MustIDBase16(listenerWriterID),
+			OrgID:  orgID,
+			UserID: MustIDBase16(listenerUserID),
+			Token:  listenerWriterToken,
+			Status: platform.Active,
+			Permissions: []platform.Permission{
+				{
+					Action:   platform.WriteAction,
+					Resource: platform.Resource{Type: platform.BucketsResourceType, ID: &bucketID, OrgID: &orgID},
+				},
+			},
+		},
+		{
+			ID:     MustIDBase16(listenerReaderID),
+			OrgID:  orgID,
+			UserID: MustIDBase16(listenerUserID),
+			Token:  listenerReaderToken,
+			Status: platform.Active,
+			Permissions: []platform.Permission{
+				{
+					Action:   platform.ReadAction,
+					Resource: platform.Resource{Type: platform.BucketsResourceType, ID: &bucketID, OrgID: &orgID},
+				},
+			},
+		},
+	}
+}
+
+func mqttListener() *platform.Listener {
+	return &platform.Listener{
+		ID:              MustIDBase16(listenerOneID),
+		OrgID:           MustIDBase16(listenerOrgOneID),
+		BucketID:        MustIDBase16(listenerBucketID),
+		Name:            "sensors",
+		Type:            platform.ListenerTypeMQTT,
+		Brokers:         []string{"tcp://localhost:1883"},
+		Topics:          []string{"sensors/#"},
+		AuthorizationID: MustIDBase16(listenerWriterID),
+		Status:          platform.Active,
+		CRUDLog: platform.CRUDLog{
+			CreatedAt: oldFakeDate,
+			UpdatedAt: oldFakeDate,
+		},
+	}
+}
+
+func kafkaListener() *platform.Listener {
+	return &platform.Listener{
+		ID:              MustIDBase16(listenerTwoID),
+		OrgID:           MustIDBase16(listenerOrgOneID),
+		BucketID:        MustIDBase16(listenerBucketID),
+		Name:            "metrics",
+		Type:            platform.ListenerTypeKafka,
+		Brokers:         []string{"localhost:9092"},
+		Topics:          []string{"metrics"},
+		AuthorizationID: MustIDBase16(listenerWriterID),
+		Status:          platform.Inactive,
+		CRUDLog: platform.CRUDLog{
+			CreatedAt: oldFakeDate,
+			UpdatedAt: oldFakeDate,
+		},
+	}
+}
+
+// ListenerService tests all the service functions.
+func ListenerService(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()), t *testing.T,
+) {
+	tests := []struct {
+		name string
+		fn   func(init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+			t *testing.T)
+	}{
+		{
+			name: "CreateListener",
+			fn:   CreateListener,
+		},
+		{
+			name: "FindListenerByID",
+			fn:   FindListenerByID,
+		},
+		{
+			name: "FindListeners",
+			fn:   FindListeners,
+		},
+		{
+			name: "UpdateListener",
+			fn:   UpdateListener,
+		},
+		{
+			name: "DeleteListener",
+			fn:   DeleteListener,
+		},
+		{
+			name: "ListenerOffsets",
+			fn:   ListenerOffsets,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(init, t)
+		})
+	}
+}
+
+// CreateListener testing
+func CreateListener(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		listener *platform.Listener
+	}
+	type wants struct {
+		err       error
+		listeners []*platform.Listener
+	}
+
+	newListener := func(bucketID, token string) *platform.Listener {
+		return &platform.Listener{
+			OrgID:    MustIDBase16(listenerOrgOneID),
+			BucketID: MustIDBase16(bucketID),
+			Name:     "new",
+			Type:     platform.ListenerTypeMQTT,
+			Brokers:  []string{"tcp://localhost:1883"},
+			Topics:   []string{"new/#"},
+			Token:    token,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "create listener replaces its token with its authorization",
+			fields: ListenerFields{
+				IDGenerator:    mock.NewIDGenerator(listenerNewID, t),
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+				Listeners:      []*platform.Listener{mqttListener()},
+			},
+			args: args{
+				listener: func() *platform.Listener {
+					l := newListener(listenerBucketID, listenerWriterToken)
+					// The authorization of a listener is only ever the one of its token.
+					l.AuthorizationID = MustIDBase16(listenerReaderID)
+					return l
+				}(),
+			},
+			wants: wants{
+				listeners: []*platform.Listener{
+					mqttListener(),
+					{
+						ID:              MustIDBase16(listenerNewID),
+						OrgID:           MustIDBase16(listenerOrgOneID),
+						BucketID:        MustIDBase16(listenerBucketID),
+						Name:            "new",
+						Type:            platform.ListenerTypeMQTT,
+						Brokers:         []string{"tcp://localhost:1883"},
+						Topics:          []string{"new/#"},
+						AuthorizationID: MustIDBase16(listenerWriterID),
+						Status:          platform.Active,
+						CRUDLog: platform.CRUDLog{
+							CreatedAt: fakeDate,
+							UpdatedAt: fakeDate,
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "listener requires a token",
+			fields: ListenerFields{
+				IDGenerator:    mock.NewIDGenerator(listenerNewID, t),
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+			},
+			args: args{
+				listener: newListener(listenerBucketID, ""),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "listener requires a token",
+				},
+				listeners: []*platform.Listener{},
+			},
+		},
+		{
+			name: "unknown token",
+			fields: ListenerFields{
+				IDGenerator:    mock.NewIDGenerator(listenerNewID, t),
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+			},
+			args: args{
+				listener: newListener(listenerBucketID, "unknown"),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "invalid listener token",
+				},
+				listeners: []*platform.Listener{},
+			},
+		},
+		{
+			name: "read-only token",
+			fields: ListenerFields{
+				IDGenerator:    mock.NewIDGenerator(listenerNewID, t),
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+			},
+			args: args{
+				listener: newListener(listenerBucketID, listenerReaderToken),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "listener token is not allowed to write to the bucket",
+				},
+				listeners: []*platform.Listener{},
+			},
+		},
+		{
+			name: "bucket of another organization",
+			fields: ListenerFields{
+				IDGenerator:    mock.NewIDGenerator(listenerNewID, t),
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+			},
+			args: args{
+				listener: newListener(listenerForeignID, listenerWriterToken),
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "bucket of listener is not in its organization",
+				},
+				listeners: []*platform.Listener{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.CreateListener(ctx, tt.args.listener)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			listeners, _, err := s.FindListeners(ctx, platform.ListenerFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve listeners: %v", err)
+			}
+			if diff := cmp.Diff(listeners, tt.wants.listeners, listenerCmpOptions...); diff != "" {
+				t.Errorf("listeners are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindListenerByID testing
+func FindListenerByID(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err      error
+		listener *platform.Listener
+	}
+
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find listener by id",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+			args: args{
+				id: MustIDBase16(listenerTwoID),
+			},
+			wants: wants{
+				listener: kafkaListener(),
+			},
+		},
+		{
+			name: "listener not found",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener()},
+			},
+			args: args{
+				id: MustIDBase16(listenerTwoID),
+			},
+			wants: wants{
+				err: platform.ErrListenerNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			listener, err := s.FindListenerByID(ctx, tt.args.id)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(listener, tt.wants.listener); diff != "" {
+				t.Errorf("listeners are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindListeners testing
+func FindListeners(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		filter platform.ListenerFilter
+	}
+	type wants struct {
+		err       error
+		listeners []*platform.Listener
+	}
+
+	orgOneID, orgTwoID := MustIDBase16(listenerOrgOneID), MustIDBase16(listenerOrgTwoID)
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find all listeners",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+			wants: wants{
+				listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+		},
+		{
+			name: "find active listeners of an organization",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+			args: args{
+				filter: platform.ListenerFilter{OrgID: &orgOneID, Status: platform.Active.Ptr()},
+			},
+			wants: wants{
+				listeners: []*platform.Listener{mqttListener()},
+			},
+		},
+		{
+			name: "find listeners of another organization",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+			args: args{
+				filter: platform.ListenerFilter{OrgID: &orgTwoID},
+			},
+			wants: wants{
+				listeners: []*platform.Listener{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			listeners, n, err := s.FindListeners(ctx, tt.args.filter)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if n != len(tt.wants.listeners) {
+				t.Errorf("got %d listeners, want %d", n, len(tt.wants.listeners))
+			}
+			if diff := cmp.Diff(listeners, tt.wants.listeners, listenerCmpOptions...); diff != "" {
+				t.Errorf("listeners are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// UpdateListener testing
+func UpdateListener(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id  platform.ID
+		upd platform.ListenerUpdate
+	}
+	type wants struct {
+		err      error
+		listener *platform.Listener
+	}
+
+	topics, reader := []string{"sensors/+/temperature"}, listenerReaderToken
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "update topics and status",
+			fields: ListenerFields{
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+				Listeners:      []*platform.Listener{mqttListener()},
+			},
+			args: args{
+				id:  MustIDBase16(listenerOneID),
+				upd: platform.ListenerUpdate{Topics: &topics, Status: platform.Inactive.Ptr()},
+			},
+			wants: wants{
+				listener: func() *platform.Listener {
+					l := mqttListener()
+					l.Topics = topics
+					l.Status = platform.Inactive
+					l.UpdatedAt = fakeDate
+					return l
+				}(),
+			},
+		},
+		{
+			name: "update with read-only token",
+			fields: ListenerFields{
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+				Listeners:      []*platform.Listener{mqttListener()},
+			},
+			args: args{
+				id:  MustIDBase16(listenerOneID),
+				upd: platform.ListenerUpdate{Token: &reader},
+			},
+			wants: wants{
+				err: &platform.Error{
+					Code: platform.EInvalid,
+					Msg:  "listener token is not allowed to write to the bucket",
+				},
+			},
+		},
+		{
+			name: "update missing listener",
+			fields: ListenerFields{
+				TimeGenerator:  fakeGenerator,
+				Buckets:        listenerBuckets(),
+				Authorizations: listenerAuthorizations(),
+			},
+			args: args{
+				id:  MustIDBase16(listenerOneID),
+				upd: platform.ListenerUpdate{Topics: &topics},
+			},
+			wants: wants{
+				err: platform.ErrListenerNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			listener, err := s.UpdateListener(ctx, tt.args.id, tt.args.upd)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(listener, tt.wants.listener); diff != "" {
+				t.Errorf("listeners are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// DeleteListener testing
+func DeleteListener(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err       error
+		listeners []*platform.Listener
+	}
+
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "delete listener and its offsets",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener(), kafkaListener()},
+			},
+			args: args{
+				id: MustIDBase16(listenerTwoID),
+			},
+			wants: wants{
+				listeners: []*platform.Listener{mqttListener()},
+			},
+		},
+		{
+			name: "delete missing listener",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{mqttListener()},
+			},
+			args: args{
+				id: MustIDBase16(listenerTwoID),
+			},
+			wants: wants{
+				err:       platform.ErrListenerNotFound,
+				listeners: []*platform.Listener{mqttListener()},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			if tt.wants.err == nil {
+				if err := s.SetListenerOffset(ctx, tt.args.id, "metrics", 0, 42); err != nil {
+					t.Fatalf("failed to set listener offset: %v", err)
+				}
+			}
+
+			err := s.DeleteListener(ctx, tt.args.id)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			listeners, _, err := s.FindListeners(ctx, platform.ListenerFilter{})
+			if err != nil {
+				t.Fatalf("failed to retrieve listeners: %v", err)
+			}
+			if diff := cmp.Diff(listeners, tt.wants.listeners, listenerCmpOptions...); diff != "" {
+				t.Errorf("listeners are different -got/+want\ndiff %s", diff)
+			}
+
+			offset, err := s.FindListenerOffset(ctx, tt.args.id, "metrics", 0)
+			if err != nil {
+				t.Fatalf("failed to retrieve listener offset: %v", err)
+			}
+			if offset != -1 {
+				t.Errorf("expected offsets of deleted listener to be removed, got offset %d", offset)
+			}
+		})
+	}
+}
+
+// ListenerOffsets testing
+func ListenerOffsets(
+	init func(ListenerFields, *testing.T) (ListenerAndOffsetService, func()),
+	t *testing.T,
+) {
+	type offset struct {
+		topic     string
+		partition int
+		offset    int64
+	}
+	type args struct {
+		offsets []offset
+	}
+	type wants struct {
+		offsets []offset
+	}
+
+	tests := []struct {
+		name   string
+		fields ListenerFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "partitions not consumed yet",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{kafkaListener()},
+			},
+			wants: wants{
+				offsets: []offset{{topic: "metrics", partition: 0, offset: -1}},
+			},
+		},
+		{
+			name: "offsets are kept per topic and partition",
+			fields: ListenerFields{
+				Listeners: []*platform.Listener{kafkaListener()},
+			},
+			args: args{
+				offsets: []offset{
+					{topic: "metrics", partition: 0, offset: 42},
+					{topic: "metrics", partition: 1, offset: 7},
+					{topic: "metrics", partition: 0, offset: 43},
+				},
+			},
+			wants: wants{
+				offsets: []offset{
+					{topic: "metrics", partition: 0, offset: 43},
+					{topic: "metrics", partition: 1, offset: 7},
+					{topic: "metrics", partition: 2, offset: -1},
+					{topic: "logs", partition: 0, offset: -1},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			id := MustIDBase16(listenerTwoID)
+			for _, o := range tt.args.offsets {
+				if err := s.SetListenerOffset(ctx, id, o.topic, o.partition, o.offset); err != nil {
+					t.Fatalf("failed to set listener offset: %v", err)
+				}
+			}
+
+			for _, o := range tt.wants.offsets {
+				got, err := s.FindListenerOffset(ctx, id, o.topic, o.partition)
+				if err != nil {
+					t.Fatalf("failed to retrieve listener offset: %v", err)
+				}
+				if got != o.offset {
+					t.Errorf("got offset %d of partition %d of topic %q, want %d", got, o.partition, o.topic, o.offset)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package testing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/mock"
+)
+
+const (
+	writeReceiptOneID = "020f755c3c082000"
+	writeReceiptTwoID = "020f755c3c082001"
+	writeReceiptOrgID = "020f755c3c082010"
+	writeReceiptBktID = "020f755c3c082020"
+)
+
+// WriteReceiptFields will include the IDGenerator, TimeGenerator, and write
+// receipts
+type WriteReceiptFields struct {
+	IDGenerator   platform.IDGenerator
+	TimeGenerator platform.TimeGenerator
+	WriteReceipts []*platform.WriteReceipt
+}
+
+// WriteReceiptService tests all the service functions.
+func WriteReceiptService(
+	init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()), t *testing.T,
+) {
+	tests := []struct {
+		name string
+		fn   func(init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()),
+			t *testing.T)
+	}{
+		{
+			name: "CreateWriteReceipt",
+			fn:   CreateWriteReceipt,
+		},
+		{
+			name: "FindWriteReceiptByID",
+			fn:   FindWriteReceiptByID,
+		},
+		{
+			name: "UpdateWriteReceipt",
+			fn:   UpdateWriteReceipt,
+		},
+		{
+			name: "DeleteWriteReceipts",
+			fn:   DeleteWriteReceipts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(init, t)
+		})
+	}
+}
+
+// CreateWriteReceipt testing
+func CreateWriteReceipt(
+	init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		receipt *platform.WriteReceipt
+	}
+	type wants struct {
+		err     error
+		receipt *platform.WriteReceipt
+	}
+
+	tests := []struct {
+		name   string
+		fields WriteReceiptFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "create receipt assigns an id and pending status",
+			fields: WriteReceiptFields{
+				IDGenerator:   mock.NewIDGenerator(writeReceiptTwoID, t),
+				TimeGenerator: fakeGenerator,
+				WriteReceipts: []*platform.WriteReceipt{
+					{
+						ID:       MustIDBase16(writeReceiptOneID),
+						OrgID:    MustIDBase16(writeReceiptOrgID),
+						BucketID: MustIDBase16(writeReceiptBktID),
+						Status:   platform.WriteReceiptWritten,
+					},
+				},
+			},
+			args: args{
+				receipt: &platform.WriteReceipt{
+					OrgID:    MustIDBase16(writeReceiptOrgID),
+					BucketID: MustIDBase16(writeReceiptBktID),
+					Status:   platform.WriteReceiptWritten,
+				},
+			},
+			wants: wants{
+				receipt: &platform.WriteReceipt{
+					ID:       MustIDBase16(writeReceiptTwoID),
+					OrgID:    MustIDBase16(writeReceiptOrgID),
+					BucketID: MustIDBase16(writeReceiptBktID),
+					Status:   platform.WriteReceiptPending,
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: fakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.CreateWriteReceipt(ctx, tt.args.receipt)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(tt.args.receipt, tt.wants.receipt); diff != "" {
+				t.Errorf("write receipts are different -got/+want\ndiff %s", diff)
+			}
+
+			receipt, err := s.FindWriteReceiptByID(ctx, tt.wants.receipt.ID)
+			if err != nil {
+				t.Fatalf("failed to retrieve write receipt: %v", err)
+			}
+			if diff := cmp.Diff(receipt, tt.wants.receipt); diff != "" {
+				t.Errorf("write receipts are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// FindWriteReceiptByID testing
+func FindWriteReceiptByID(
+	init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		id platform.ID
+	}
+	type wants struct {
+		err     error
+		receipt *platform.WriteReceipt
+	}
+
+	receipts := []*platform.WriteReceipt{
+		{
+			ID:       MustIDBase16(writeReceiptOneID),
+			OrgID:    MustIDBase16(writeReceiptOrgID),
+			BucketID: MustIDBase16(writeReceiptBktID),
+			Status:   platform.WriteReceiptPartial,
+			Message:  "some lines were rejected",
+			Accepted: 1,
+			Rejected: 1,
+			Rejections: []*platform.WriteRejection{
+				{Line: 2, Reason: platform.WriteRejectedParseError, Message: "invalid field format"},
+			},
+			CRUDLog: platform.CRUDLog{
+				CreatedAt: oldFakeDate,
+				UpdatedAt: fakeDate,
+			},
+		},
+		{
+			ID:       MustIDBase16(writeReceiptTwoID),
+			OrgID:    MustIDBase16(writeReceiptOrgID),
+			BucketID: MustIDBase16(writeReceiptBktID),
+			Status:   platform.WriteReceiptPending,
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields WriteReceiptFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "find receipt by id",
+			fields: WriteReceiptFields{
+				WriteReceipts: receipts,
+			},
+			args: args{
+				id: MustIDBase16(writeReceiptOneID),
+			},
+			wants: wants{
+				receipt: receipts[0],
+			},
+		},
+		{
+			name: "receipt not found",
+			fields: WriteReceiptFields{
+				WriteReceipts: receipts[1:],
+			},
+			args: args{
+				id: MustIDBase16(writeReceiptOneID),
+			},
+			wants: wants{
+				err: platform.ErrWriteReceiptNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			receipt, err := s.FindWriteReceiptByID(ctx, tt.args.id)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			if diff := cmp.Diff(receipt, tt.wants.receipt); diff != "" {
+				t.Errorf("write receipts are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// UpdateWriteReceipt testing
+func UpdateWriteReceipt(
+	init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		receipt *platform.WriteReceipt
+	}
+	type wants struct {
+		err     error
+		receipt *platform.WriteReceipt
+	}
+
+	tests := []struct {
+		name   string
+		fields WriteReceiptFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "update status keeps the creation time",
+			fields: WriteReceiptFields{
+				TimeGenerator: fakeGenerator,
+				WriteReceipts: []*platform.WriteReceipt{
+					{
+						ID:       MustIDBase16(writeReceiptOneID),
+						OrgID:    MustIDBase16(writeReceiptOrgID),
+						BucketID: MustIDBase16(writeReceiptBktID),
+						Status:   platform.WriteReceiptPending,
+						CRUDLog: platform.CRUDLog{
+							CreatedAt: oldFakeDate,
+							UpdatedAt: oldFakeDate,
+						},
+					},
+				},
+			},
+			args: args{
+				receipt: &platform.WriteReceipt{
+					ID:       MustIDBase16(writeReceiptOneID),
+					OrgID:    MustIDBase16(writeReceiptOrgID),
+					BucketID: MustIDBase16(writeReceiptBktID),
+					Status:   platform.WriteReceiptPartial,
+					Accepted: 1,
+					Rejected: 1,
+					Rejections: []*platform.WriteRejection{
+						{Line: 2, Reason: platform.WriteRejectedParseError, Message: "invalid field format"},
+					},
+				},
+			},
+			wants: wants{
+				receipt: &platform.WriteReceipt{
+					ID:       MustIDBase16(writeReceiptOneID),
+					OrgID:    MustIDBase16(writeReceiptOrgID),
+					BucketID: MustIDBase16(writeReceiptBktID),
+					Status:   platform.WriteReceiptPartial,
+					Accepted: 1,
+					Rejected: 1,
+					Rejections: []*platform.WriteRejection{
+						{Line: 2, Reason: platform.WriteRejectedParseError, Message: "invalid field format"},
+					},
+					CRUDLog: platform.CRUDLog{
+						CreatedAt: oldFakeDate,
+						UpdatedAt: fakeDate,
+					},
+				},
+			},
+		},
+		{
+			name: "update missing receipt",
+			fields: WriteReceiptFields{
+				TimeGenerator: fakeGenerator,
+			},
+			args: args{
+				receipt: &platform.WriteReceipt{
+					ID:     MustIDBase16(writeReceiptOneID),
+					Status: platform.WriteReceiptWritten,
+				},
+			},
+			wants: wants{
+				err: platform.ErrWriteReceiptNotFound,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.UpdateWriteReceipt(ctx, tt.args.receipt)
+			ErrorsEqual(t, err, tt.wants.err)
+			if tt.wants.err != nil {
+				return
+			}
+
+			receipt, err := s.FindWriteReceiptByID(ctx, tt.args.receipt.ID)
+			if err != nil {
+				t.Fatalf("failed to retrieve write receipt: %v", err)
+			}
+			if diff := cmp.Diff(receipt, tt.wants.receipt); diff != "" {
+				t.Errorf("write receipts are different -got/+want\ndiff %s", diff)
+			}
+		})
+	}
+}
+
+// DeleteWriteReceipts testing
+func DeleteWriteReceipts(
+	init func(WriteReceiptFields, *testing.T) (platform.WriteReceiptService, func()),
+	t *testing.T,
+) {
+	type args struct {
+		before time.Time
+	}
+	type wants struct {
+		err     error
+		kept    []platform.ID
+		deleted []platform.ID
+	}
+
+	receipts := []*platform.WriteReceipt{
+		{
+			ID:       MustIDBase16(writeReceiptOneID),
+			OrgID:    MustIDBase16(writeReceiptOrgID),
+			BucketID: MustIDBase16(writeReceiptBktID),
+			Status:   platform.WriteReceiptWritten,
+			CRUDLog: platform.CRUDLog{
+				CreatedAt: oldFakeDate,
+				UpdatedAt: oldFakeDate,
+			},
+		},
+		{
+			ID:       MustIDBase16(writeReceiptTwoID),
+			OrgID:    MustIDBase16(writeReceiptOrgID),
+			BucketID: MustIDBase16(writeReceiptBktID),
+			Status:   platform.WriteReceiptWritten,
+			CRUDLog: platform.CRUDLog{
+				CreatedAt: oldFakeDate,
+				UpdatedAt: fakeDate,
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		fields WriteReceiptFields
+		args   args
+		wants  wants
+	}{
+		{
+			name: "delete receipts updated before a time",
+			fields: WriteReceiptFields{
+				WriteReceipts: receipts,
+			},
+			args: args{
+				before: fakeDate,
+			},
+			wants: wants{
+				kept:    []platform.ID{MustIDBase16(writeReceiptTwoID)},
+				deleted: []platform.ID{MustIDBase16(writeReceiptOneID)},
+			},
+		},
+		{
+			name: "delete all receipts",
+			fields: WriteReceiptFields{
+				WriteReceipts: receipts,
+			},
+			args: args{
+				before: fakeDate.Add(time.Second),
+			},
+			wants: wants{
+				deleted: []platform.ID{MustIDBase16(writeReceiptOneID), MustIDBase16(writeReceiptTwoID)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, done := init(tt.fields, t)
+			defer done()
+			ctx := context.Background()
+			err := s.DeleteWriteReceipts(ctx, tt.args.before)
+			ErrorsEqual(t, err, tt.wants.err)
+
+			for _, id := range tt.wants.kept {
+				if _, err := s.FindWriteReceiptByID(ctx, id); err != nil {
+					t.Errorf("expected write receipt %s to be kept: %v", id, err)
+				}
+			}
+			for _, id := range tt.wants.deleted {
+				if _, err := s.FindWriteReceiptByID(ctx, id); platform.ErrorCode(err) != platform.ENotFound {
+					t.Errorf("expected write receipt %s to be deleted: %v", id, err)
+				}
+			}
+		})
+	}
+}
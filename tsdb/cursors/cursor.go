@@ -39,6 +39,51 @@ type BooleanArrayCursor interface {
 	Next() *BooleanArray
 }
 
+// FloatBlockStats holds the statistics of a block of float values.
+type FloatBlockStats struct {
+	MinTime, MaxTime int64
+	Count            int64
+	Sum, Min, Max    float64
+}
+
+// IntegerBlockStats holds the statistics of a block of integer values.
+type IntegerBlockStats struct {
+	MinTime, MaxTime int64
+	Count            int64
+	Sum, Min, Max    int64
+}
+
+// UnsignedBlockStats holds the statistics of a block of unsigned values.
+type UnsignedBlockStats struct {
+	MinTime, MaxTime int64
+	Count            int64
+	Sum, Min, Max    uint64
+}
+
+// FloatArrayStatsCursor is a FloatArrayCursor that can return the statistics
+// of blocks of values in place of the values themselves.
+type FloatArrayStatsCursor interface {
+	FloatArrayCursor
+	// NextStats returns the statistics of the next block of values and moves
+	// the cursor past it. If no statistics are available for the next values,
+	// it returns false and the values must be read with Next.
+	NextStats() (FloatBlockStats, bool)
+}
+
+// IntegerArrayStatsCursor is an IntegerArrayCursor that can return the
+// statistics of blocks of values in place of the values themselves.
+type IntegerArrayStatsCursor interface {
+	IntegerArrayCursor
+	NextStats() (IntegerBlockStats, bool)
+}
+
+// UnsignedArrayStatsCursor is an UnsignedArrayCursor that can return the
+// statistics of blocks of values in place of the values themselves.
+type UnsignedArrayStatsCursor interface {
+	UnsignedArrayCursor
+	NextStats() (UnsignedBlockStats, bool)
+}
+
 type CursorRequest struct {
 	Name      []byte
 	Tags      models.Tags
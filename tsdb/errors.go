@@ -3,6 +3,8 @@ package tsdb
 import (
 	"errors"
 	"fmt"
+
+	"github.com/influxdata/influxdb/models"
 )
 
 var (
@@ -21,6 +23,17 @@ type PartialWriteError struct {
 
 	// A sorted slice of series keys that were dropped.
 	DroppedKeys [][]byte
+
+	// The points that were dropped and the reasons they were dropped for,
+	// when known.
+	Drops []Drop
+}
+
+// Drop is the reason a point was dropped from a write.
+type Drop struct {
+	Point  models.Point
+	Code   string // One of the influxdb.WriteRejected reasons.
+	Reason string
 }
 
 func (e PartialWriteError) Error() string {
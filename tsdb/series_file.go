@@ -212,6 +212,24 @@ func (f *SeriesFile) DisableCompactions() {
 	}
 }
 
+// Compact compacts each partition, removing the deleted series from their
+// indexes and the segments only holding deleted series. It returns the number
+// of segments removed.
+func (f *SeriesFile) Compact(ctx context.Context) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var n int
+	for _, p := range f.partitions {
+		m, err := p.Compact(ctx)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
 // CreateSeriesListIfNotExists creates a list of series in bulk if they don't exist. It overwrites
 // the collection's Keys and SeriesIDs fields. The collection's SeriesIDs slice will have IDs for
 // every name+tags, creating new series IDs as needed. If any SeriesID is zero, then a type
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"testing"
 
 	"github.com/influxdata/influxdb/logger"
@@ -151,6 +152,97 @@ func TestSeriesFileCompactor(t *testing.T) {
 	}
 }
 
+// Ensure series partition compaction removes the segments of deleted series.
+func TestSeriesPartition_Compact(t *testing.T) {
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	// Disable automatic compactions.
+	for _, p := range sfile.Partitions() {
+		p.CompactThreshold = 0
+	}
+
+	// Write enough series to the first partition to fill its first segment.
+	value := strings.Repeat("x", 1000)
+	collection := new(tsdb.SeriesCollection)
+	for i := 0; collection.Length() < 5000; i++ {
+		tags := models.NewTags(map[string]string{"host": fmt.Sprintf("%s%d", value, i)})
+		if sfile.SeriesKeyPartitionID(tsdb.AppendSeriesKey(nil, []byte("cpu"), tags)) != 0 {
+			continue
+		}
+		collection.Names = append(collection.Names, []byte("cpu"))
+		collection.Tags = append(collection.Tags, tags)
+		collection.Types = append(collection.Types, models.Float)
+	}
+	if err := sfile.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := sfile.SeriesPartitionPath(0)
+	segments := func() int {
+		fis, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var n int
+		for _, fi := range fis {
+			if tsdb.IsValidSeriesSegmentFilename(fi.Name()) {
+				n++
+			}
+		}
+		return n
+	}
+	if got := segments(); got < 2 {
+		t.Fatalf("expected at least 2 segments, got %d", got)
+	}
+
+	// Segments with remaining series are kept.
+	if n, err := sfile.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("unexpected removed segments: %d", n)
+	}
+
+	// Delete all the series but the last.
+	last := collection.Length() - 1
+	for _, id := range collection.SeriesIDs[:last] {
+		if err := sfile.DeleteSeriesID(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := segments() - 1
+	if n, err := sfile.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n != exp {
+		t.Fatalf("unexpected removed segments: got %d, exp %d", n, exp)
+	} else if got := segments(); got != 1 {
+		t.Fatalf("unexpected segments after compaction: %d", got)
+	}
+
+	// The remaining series and the sequence of ids survive reopening.
+	if err := sfile.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := sfile.SeriesCount(), uint64(1); got != exp {
+		t.Fatalf("unexpected series count: got %d, exp %d", got, exp)
+	} else if key := sfile.SeriesKey(collection.SeriesIDs[last]); !bytes.Equal(key, collection.SeriesKeys[last]) {
+		t.Fatalf("unexpected series key: %q", key)
+	} else if !sfile.IsDeleted(collection.SeriesIDs[0]) {
+		t.Fatal("expected series to be deleted after reopen")
+	}
+
+	next := &tsdb.SeriesCollection{
+		Names: [][]byte{collection.Names[0]},
+		Tags:  []models.Tags{collection.Tags[0]},
+		Types: []models.FieldType{models.Float},
+	}
+	if err := sfile.CreateSeriesListIfNotExists(next); err != nil {
+		t.Fatal(err)
+	} else if got, max := next.SeriesIDs[0], collection.SeriesIDs[last]; !max.Less(got) {
+		t.Fatalf("expected new series id %d greater than %d", got.RawID(), max.RawID())
+	}
+}
+
 // Ensures that types are tracked and checked by the series file.
 func TestSeriesFile_Type(t *testing.T) {
 	sfile := MustOpenSeriesFile()
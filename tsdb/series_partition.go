@@ -41,6 +41,7 @@ type SeriesPartition struct {
 	once    sync.Once
 
 	segments []*SeriesSegment
+	retired  []*SeriesSegment // removed segments, unmapped by the next compaction
 	index    *SeriesIndex
 	seq      uint64 // series id sequence
 
@@ -165,6 +166,13 @@ func (p *SeriesPartition) Close() (err error) {
 	}
 	p.segments = nil
 
+	for _, s := range p.retired {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	p.retired = nil
+
 	if p.index != nil {
 		if e := p.index.Close(); e != nil && err == nil {
 			err = e
@@ -342,6 +350,189 @@ func (p *SeriesPartition) CreateSeriesListIfNotExists(collection *SeriesCollecti
 	return nil
 }
 
+// Compact rebuilds the partition index without the deleted series, and then
+// removes the segments only holding entries of deleted series. It returns the
+// number of segments removed. Nothing is done if the partition is already
+// compacting or compactions are disabled.
+func (p *SeriesPartition) Compact(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return 0, ErrSeriesPartitionClosed
+	} else if p.compacting || !p.compactionsEnabled() {
+		p.mu.Unlock()
+		return 0, nil
+	}
+	p.compacting = true
+
+	// Series keys read from the segments removed by the previous compaction
+	// are no longer in use, so they can be unmapped.
+	var err error
+	for _, s := range p.retired {
+		if e := s.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	p.retired = nil
+	p.wg.Add(1)
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		p.compacting = false
+		p.mu.Unlock()
+		p.wg.Done()
+	}()
+	if err != nil {
+		return 0, err
+	}
+
+	log, logEnd := logger.NewOperation(ctx, p.Logger, "Series partition compaction", "series_partition_compaction", zap.String("path", p.path))
+	defer logEnd()
+
+	p.tracker.IncCompactionsActive()
+	compactor := NewSeriesPartitionCompactor()
+	compactor.cancel = p.closing
+	duration, err := compactor.Compact(p)
+	if err != nil {
+		p.tracker.IncCompactionErr()
+		p.tracker.DecCompactionsActive()
+		log.Error("Series partition compaction failed", zap.Error(err))
+		return 0, err
+	}
+	p.tracker.IncCompactionOK(duration)
+	p.tracker.DecCompactionsActive()
+
+	n, err := p.removeDeletedSegments()
+	if err != nil {
+		log.Error("Failed to remove series segments", zap.Error(err))
+		return n, err
+	}
+	if n > 0 {
+		log.Info("Removed series segments", zap.Int("segments", n))
+	}
+
+	// Disk size changes with the compacted index and removed segments.
+	p.tracker.SetDiskSize(p.DiskSize())
+	return n, nil
+}
+
+// removeDeletedSegments removes the sealed segments in which every inserted
+// series has been deleted since the index was last compacted. A segment is
+// kept if its tombstones are still needed to recover the index from the
+// segments, or if it holds the highest series id, from which the sequence of
+// ids resumes when the partition is opened.
+//
+// Removed segments are only unmapped by the next compaction, as series keys
+// read from them may still be in use.
+func (p *SeriesPartition) removeDeletedSegments() (int, error) {
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return 0, ErrSeriesPartitionClosed
+	}
+	segments := CloneSeriesSegments(p.segments)
+	maxOffset := p.index.maxOffset
+	p.mu.RUnlock()
+
+	if len(segments) < 2 {
+		return 0, nil
+	}
+
+	last := -1
+	for i := len(segments) - 1; i >= 0; i-- {
+		if !segments[i].MaxSeriesID().IsZero() {
+			last = i
+			break
+		}
+	}
+
+	var (
+		kept       = NewSeriesIDSet() // series inserted in kept segments
+		inserts    = make(map[uint16][]SeriesID)
+		tombstones = make(map[uint16][]SeriesID)
+	)
+	for i, segment := range segments {
+		var (
+			removable = i < last && i < len(segments)-1
+			ins, tss  []SeriesID
+		)
+		if err := segment.ForEachEntry(func(flag uint8, id SeriesIDTyped, offset int64, key []byte) error {
+			untypedID := id.SeriesID()
+			switch flag {
+			case SeriesEntryInsertFlag:
+				ins = append(ins, untypedID)
+				if removable && !p.IsDeleted(untypedID) {
+					removable = false
+				}
+			case SeriesEntryTombstoneFlag:
+				tss = append(tss, untypedID)
+
+				// The compacted index may still refer to the series.
+				if offset > maxOffset {
+					removable = false
+				}
+			}
+			return nil
+		}); err != nil {
+			return 0, err
+		}
+
+		if removable {
+			inserts[segment.ID()], tombstones[segment.ID()] = ins, tss
+		} else {
+			kept.AddMany(ins...)
+		}
+	}
+
+	// Dropping the tombstones of series inserted in kept segments would
+	// revive them if the index is recovered from the segments.
+	for changed := true; changed; {
+		changed = false
+		for id, tss := range tombstones {
+			for _, sid := range tss {
+				if kept.Contains(sid) {
+					kept.AddMany(inserts[id]...)
+					delete(inserts, id)
+					delete(tombstones, id)
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	if len(inserts) == 0 {
+		return 0, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return 0, ErrSeriesPartitionClosed
+	}
+
+	var (
+		n     int
+		err   error
+		other = make([]*SeriesSegment, 0, len(p.segments))
+	)
+	for _, segment := range p.segments {
+		if _, ok := inserts[segment.ID()]; !ok || err != nil {
+			other = append(other, segment)
+			continue
+		}
+		if err = os.Remove(segment.path); err != nil {
+			other = append(other, segment)
+			continue
+		}
+		p.retired = append(p.retired, segment)
+		n++
+	}
+	p.segments = other
+	p.tracker.SetSegments(uint64(len(p.segments)))
+	return n, err
+}
+
 // Compacting returns if the SeriesPartition is currently compacting.
 func (p *SeriesPartition) Compacting() bool {
 	p.mu.RLock()
@@ -1,9 +1,11 @@
 package tsdb_test
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -44,6 +46,59 @@ func BenchmarkSeriesPartition_CreateSeriesListIfNotExists(b *testing.B) {
 	}
 }
 
+func TestSeriesPartition_Verify(t *testing.T) {
+	sfile := MustOpenSeriesFile()
+	defer sfile.Close()
+
+	collection := new(tsdb.SeriesCollection)
+	for _, host := range []string{"a", "b", "c"} {
+		collection.Names = append(collection.Names, []byte("cpu"))
+		collection.Tags = append(collection.Tags, models.NewTags(map[string]string{"host": "server" + host}))
+		collection.Types = append(collection.Types, models.Float)
+	}
+	if err := sfile.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	}
+
+	var read int
+	wait := func(n int) error {
+		read += n
+		return nil
+	}
+	for _, p := range sfile.Partitions() {
+		if err := p.Verify(wait); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if read == 0 {
+		t.Fatal("expected entries to be read")
+	}
+
+	// Change the key of a series, which the index no longer finds.
+	p := sfile.SeriesKeyPartition(tsdb.AppendSeriesKey(nil, []byte("cpu"), models.NewTags(map[string]string{"host": "serverb"})))
+	path := filepath.Join(p.Path(), "0000")
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteAt([]byte("x"), int64(bytes.Index(data, []byte("serverb")))); err != nil {
+		t.Fatal(err)
+	} else if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = p.Verify(wait)
+	if e, ok := err.(*tsdb.CorruptSeriesError); !ok {
+		t.Fatalf("unexpected error: %v", err)
+	} else if e.Path != path {
+		t.Fatalf("unexpected path: got %s, exp %s", e.Path, path)
+	}
+}
+
 // SeriesPartition is a test wrapper for tsdb.SeriesPartition.
 type SeriesPartition struct {
 	*tsdb.SeriesPartition
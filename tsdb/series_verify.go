@@ -410,3 +410,135 @@ func (b *buffer) advance(n int64) error {
 	b.offset += n
 	return nil
 }
+
+// CorruptSeriesError is returned by SeriesPartition.Verify for a corrupt
+// segment or index of a partition.
+type CorruptSeriesError struct {
+	Path   string // Path of the segment.
+	Offset int64  // Offset of the entry in the segment.
+	Err    error
+}
+
+func (e *CorruptSeriesError) Error() string {
+	return fmt.Sprintf("corrupt series segment %s at offset %d: %v", e.Path, e.Offset, e.Err)
+}
+
+// seriesVerifyWaitSize is the number of bytes of segment entries verified
+// between calls to the wait function of SeriesPartition.Verify.
+const seriesVerifyWaitSize = 64 * 1024
+
+// Verify checks the segments and index of the partition while it is in use.
+// The entries of every segment must parse, with increasing series ids, and the
+// index must find the id of every live series they hold. Segments have no
+// checksums, so corruption is only found where it breaks these checks.
+//
+// Segments are mapped again rather than read through the partition, as they
+// may be removed and unmapped concurrently. wait is called with the size of
+// the entries before they are verified, to throttle reads, and verification
+// stops with the first error it returns. A *CorruptSeriesError is returned for
+// the first corruption found.
+func (p *SeriesPartition) Verify(wait func(n int) error) error {
+	type segmentInfo struct {
+		id   uint16
+		path string
+		size int64 // Size of the data written, or zero if the segment is full.
+	}
+
+	p.mu.RLock()
+	if p.closed {
+		p.mu.RUnlock()
+		return ErrSeriesPartitionClosed
+	}
+	infos := make([]segmentInfo, 0, len(p.segments))
+	for i, segment := range p.segments {
+		info := segmentInfo{id: segment.ID(), path: segment.path}
+		if i == len(p.segments)-1 {
+			// Entries written to the active segment are flushed before the
+			// lock is released, so that they are all readable up to its size.
+			info.size = segment.Size()
+		}
+		infos = append(infos, info)
+	}
+	p.mu.RUnlock()
+
+	for _, info := range infos {
+		segment := NewSeriesSegment(info.id, info.path)
+		if err := segment.Open(); os.IsNotExist(err) {
+			continue // Removed since listed.
+		} else if err != nil {
+			return &CorruptSeriesError{Path: info.path, Err: err}
+		}
+
+		data := segment.Data()
+		if info.size > 0 && info.size < int64(len(data)) {
+			data = data[:info.size]
+		}
+		err := p.verifySegment(data, wait)
+		segment.Close()
+		if e, ok := err.(*CorruptSeriesError); ok {
+			e.Path = info.path
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifySegment verifies the entries of the data of a segment of the
+// partition.
+func (p *SeriesPartition) verifySegment(data []byte, wait func(n int) error) (err error) {
+	pos := int64(SeriesSegmentHeaderSize)
+
+	// Corrupt lengths can make the entries be read out of range.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("panic reading entry: %v", rec)}
+		}
+	}()
+
+	var prevID uint64
+	var pending int64
+	for pos < int64(len(data)) {
+		flag, id, key, sz := ReadSeriesEntry(data[pos:])
+		if flag == 0 {
+			if data[pos] != 0 {
+				return &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("invalid flag %d", data[pos])}
+			}
+			break // No more entries.
+		}
+		if pos+sz > int64(len(data)) {
+			return &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("entry of %d bytes past end of segment", sz)}
+		}
+
+		if pending += sz; pending >= seriesVerifyWaitSize {
+			if err := wait(int(pending)); err != nil {
+				return err
+			}
+			pending = 0
+		}
+
+		if flag == SeriesEntryInsertFlag {
+			if id.RawID() < prevID {
+				return &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("series id %d not increasing after %d", id.RawID(), prevID)}
+			}
+			prevID = id.RawID()
+
+			if name, _ := ParseSeriesKey(key); len(name) == 0 {
+				return &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("invalid series key for id %d", id.RawID())}
+			}
+
+			// Series deleted concurrently are not found by the index.
+			sid := id.SeriesID()
+			if got := p.FindIDBySeriesKey(key); got != sid && !p.IsDeleted(sid) {
+				return &CorruptSeriesError{Offset: pos, Err: fmt.Errorf("index finds series id %d for series of id %d", got.RawID(), sid.RawID())}
+			}
+		}
+		pos += sz
+	}
+
+	if pending > 0 {
+		return wait(int(pending))
+	}
+	return nil
+}
@@ -1215,6 +1215,23 @@ func (i *Index) MeasurementCardinalityStats() (MeasurementCardinalityStats, erro
 	return stats, nil
 }
 
+// ComputeMeasurementCardinalityStats returns cardinality stats for all
+// measurements, computed again rather than taken from the stats cache.
+func (i *Index) ComputeMeasurementCardinalityStats() (MeasurementCardinalityStats, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	stats := NewMeasurementCardinalityStats()
+	for _, p := range i.partitions {
+		pstats, err := p.ComputeMeasurementCardinalityStats()
+		if err != nil {
+			return nil, err
+		}
+		stats.Add(pstats)
+	}
+	return stats, nil
+}
+
 func (i *Index) seriesByExprIterator(name []byte, expr influxql.Expr) (tsdb.SeriesIDIterator, error) {
 	switch expr := expr.(type) {
 	case *influxql.BinaryExpr:
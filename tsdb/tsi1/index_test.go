@@ -395,6 +395,39 @@ func TestIndex_MeasurementCardinalityStats(t *testing.T) {
 		}
 	})
 
+	t.Run("Computed", func(t *testing.T) {
+		c := tsi1.NewConfig()
+		c.StatsTTL = time.Hour
+		idx := MustOpenIndex(1, c)
+		defer idx.Close()
+
+		if err := idx.CreateSeriesSliceIfNotExists([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "east"})},
+		}); err != nil {
+			t.Fatal(err)
+		} else if _, err := idx.MeasurementCardinalityStats(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := idx.CreateSeriesSliceIfNotExists([]Series{
+			{Name: []byte("cpu"), Tags: models.NewTags(map[string]string{"region": "west"})},
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		// The cached stats are kept until they expire, computed ones are not.
+		if stats, err := idx.MeasurementCardinalityStats(); err != nil {
+			t.Fatal(err)
+		} else if diff := cmp.Diff(stats, tsi1.MeasurementCardinalityStats{"cpu": 1}); diff != "" {
+			t.Fatal(diff)
+		}
+		if stats, err := idx.ComputeMeasurementCardinalityStats(); err != nil {
+			t.Fatal(err)
+		} else if diff := cmp.Diff(stats, tsi1.MeasurementCardinalityStats{"cpu": 2}); diff != "" {
+			t.Fatal(diff)
+		}
+	})
+
 	t.Run("Large", func(t *testing.T) {
 		t.Skip("https://github.com/influxdata/influxdb/issues/15220")
 		if testing.Short() {
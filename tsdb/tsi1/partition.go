@@ -1321,6 +1321,14 @@ func (p *Partition) MeasurementCardinalityStats() (MeasurementCardinalityStats,
 	return stats, nil
 }
 
+// ComputeMeasurementCardinalityStats returns cardinality stats for all
+// measurements, computed again rather than taken from the stats cache.
+func (p *Partition) ComputeMeasurementCardinalityStats() (MeasurementCardinalityStats, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.measurementCardinalityStats()
+}
+
 func (p *Partition) measurementCardinalityStats() (MeasurementCardinalityStats, error) {
 	fs, err := p.fileSet.Duplicate()
 	if err != nil {
@@ -26,6 +26,11 @@ type floatArrayAscendingCursor struct {
 		values    *tsdb.FloatArray
 		pos       int
 		keyCursor *KeyCursor
+
+		// unread is set when the cursor has moved to the block of keyCursor
+		// without reading it into values.
+		unread bool
+		seek   int64
 	}
 
 	end   int64
@@ -49,10 +54,9 @@ func (c *floatArrayAscendingCursor) reset(seek, end int64, cacheValues Values, t
 	})
 
 	c.tsm.keyCursor = tsmKeyCursor
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
-		return c.tsm.values.Timestamps[i] >= seek
-	})
+	c.tsm.values = c.tsm.buf
+	c.tsm.unread = true
+	c.tsm.seek = seek
 }
 
 func (c *floatArrayAscendingCursor) Err() error { return nil }
@@ -73,7 +77,7 @@ func (c *floatArrayAscendingCursor) Stats() cursors.CursorStats { return c.stats
 func (c *floatArrayAscendingCursor) Next() *tsdb.FloatArray {
 	pos := 0
 	cvals := c.cache.values
-	tvals := c.tsm.values
+	tvals := c.readTSM()
 
 	c.res.Timestamps = c.res.Timestamps[:cap(c.res.Timestamps)]
 	c.res.Values = c.res.Values[:cap(c.res.Values)]
@@ -111,7 +115,7 @@ func (c *floatArrayAscendingCursor) Next() *tsdb.FloatArray {
 				// the buffer.
 				copy(c.res.Timestamps, tvals.Timestamps)
 				pos += copy(c.res.Values, tvals.Values)
-				c.nextTSM()
+				c.skipTSM()
 			} else {
 				// copy as much as we can
 				n := copy(c.res.Timestamps[pos:], tvals.Timestamps[c.tsm.pos:])
@@ -119,7 +123,7 @@ func (c *floatArrayAscendingCursor) Next() *tsdb.FloatArray {
 				pos += n
 				c.tsm.pos += n
 				if c.tsm.pos >= len(tvals.Timestamps) {
-					c.nextTSM()
+					c.skipTSM()
 				}
 			}
 		}
@@ -154,12 +158,55 @@ func (c *floatArrayAscendingCursor) Next() *tsdb.FloatArray {
 }
 
 func (c *floatArrayAscendingCursor) nextTSM() *tsdb.FloatArray {
+	c.skipTSM()
+	return c.readTSM()
+}
+
+// skipTSM moves the cursor to the next TSM block without reading it.
+func (c *floatArrayAscendingCursor) skipTSM() {
 	c.tsm.keyCursor.Next()
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = 0
+	c.tsm.unread = true
+}
+
+// readTSM returns the values of the current TSM block, reading the block if
+// the cursor has not done so yet.
+func (c *floatArrayAscendingCursor) readTSM() *tsdb.FloatArray {
+	if c.tsm.unread {
+		c.tsm.unread = false
+		c.tsm.values = c.readArrayBlock()
+		c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
+			return c.tsm.values.Timestamps[i] >= c.tsm.seek
+		})
+	}
 	return c.tsm.values
 }
 
+// NextStats returns the statistics of the next TSM block and moves the cursor
+// past it, if all of the values of the block are returned by the cursor and
+// the statistics are stored in the TSM file.
+func (c *floatArrayAscendingCursor) NextStats() (cursors.FloatBlockStats, bool) {
+	if !c.tsm.unread {
+		return cursors.FloatBlockStats{}, false
+	}
+
+	loc := c.tsm.keyCursor.statsBlock(c.tsm.seek, c.end)
+	if loc == nil || valuesOverlap(c.cache.values, c.cache.pos, loc.entry.MinTime, loc.entry.MaxTime) {
+		return cursors.FloatBlockStats{}, false
+	}
+
+	bs, ok := loc.r.BlockStats(&loc.entry)
+	if !ok {
+		return cursors.FloatBlockStats{}, false
+	}
+	s, ok := bs.Float()
+	if !ok {
+		return cursors.FloatBlockStats{}, false
+	}
+
+	c.tsm.keyCursor.skipBlock(loc)
+	return s, true
+}
+
 func (c *floatArrayAscendingCursor) readArrayBlock() *tsdb.FloatArray {
 	values, _ := c.tsm.keyCursor.ReadFloatArrayBlock(c.tsm.buf)
 	return values
@@ -336,6 +383,11 @@ type integerArrayAscendingCursor struct {
 		values    *tsdb.IntegerArray
 		pos       int
 		keyCursor *KeyCursor
+
+		// unread is set when the cursor has moved to the block of keyCursor
+		// without reading it into values.
+		unread bool
+		seek   int64
 	}
 
 	end   int64
@@ -359,10 +411,9 @@ func (c *integerArrayAscendingCursor) reset(seek, end int64, cacheValues Values,
 	})
 
 	c.tsm.keyCursor = tsmKeyCursor
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
-		return c.tsm.values.Timestamps[i] >= seek
-	})
+	c.tsm.values = c.tsm.buf
+	c.tsm.unread = true
+	c.tsm.seek = seek
 }
 
 func (c *integerArrayAscendingCursor) Err() error { return nil }
@@ -383,7 +434,7 @@ func (c *integerArrayAscendingCursor) Stats() cursors.CursorStats { return c.sta
 func (c *integerArrayAscendingCursor) Next() *tsdb.IntegerArray {
 	pos := 0
 	cvals := c.cache.values
-	tvals := c.tsm.values
+	tvals := c.readTSM()
 
 	c.res.Timestamps = c.res.Timestamps[:cap(c.res.Timestamps)]
 	c.res.Values = c.res.Values[:cap(c.res.Values)]
@@ -421,7 +472,7 @@ func (c *integerArrayAscendingCursor) Next() *tsdb.IntegerArray {
 				// the buffer.
 				copy(c.res.Timestamps, tvals.Timestamps)
 				pos += copy(c.res.Values, tvals.Values)
-				c.nextTSM()
+				c.skipTSM()
 			} else {
 				// copy as much as we can
 				n := copy(c.res.Timestamps[pos:], tvals.Timestamps[c.tsm.pos:])
@@ -429,7 +480,7 @@ func (c *integerArrayAscendingCursor) Next() *tsdb.IntegerArray {
 				pos += n
 				c.tsm.pos += n
 				if c.tsm.pos >= len(tvals.Timestamps) {
-					c.nextTSM()
+					c.skipTSM()
 				}
 			}
 		}
@@ -464,12 +515,55 @@ func (c *integerArrayAscendingCursor) Next() *tsdb.IntegerArray {
 }
 
 func (c *integerArrayAscendingCursor) nextTSM() *tsdb.IntegerArray {
+	c.skipTSM()
+	return c.readTSM()
+}
+
+// skipTSM moves the cursor to the next TSM block without reading it.
+func (c *integerArrayAscendingCursor) skipTSM() {
 	c.tsm.keyCursor.Next()
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = 0
+	c.tsm.unread = true
+}
+
+// readTSM returns the values of the current TSM block, reading the block if
+// the cursor has not done so yet.
+func (c *integerArrayAscendingCursor) readTSM() *tsdb.IntegerArray {
+	if c.tsm.unread {
+		c.tsm.unread = false
+		c.tsm.values = c.readArrayBlock()
+		c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
+			return c.tsm.values.Timestamps[i] >= c.tsm.seek
+		})
+	}
 	return c.tsm.values
 }
 
+// NextStats returns the statistics of the next TSM block and moves the cursor
+// past it, if all of the values of the block are returned by the cursor and
+// the statistics are stored in the TSM file.
+func (c *integerArrayAscendingCursor) NextStats() (cursors.IntegerBlockStats, bool) {
+	if !c.tsm.unread {
+		return cursors.IntegerBlockStats{}, false
+	}
+
+	loc := c.tsm.keyCursor.statsBlock(c.tsm.seek, c.end)
+	if loc == nil || valuesOverlap(c.cache.values, c.cache.pos, loc.entry.MinTime, loc.entry.MaxTime) {
+		return cursors.IntegerBlockStats{}, false
+	}
+
+	bs, ok := loc.r.BlockStats(&loc.entry)
+	if !ok {
+		return cursors.IntegerBlockStats{}, false
+	}
+	s, ok := bs.Integer()
+	if !ok {
+		return cursors.IntegerBlockStats{}, false
+	}
+
+	c.tsm.keyCursor.skipBlock(loc)
+	return s, true
+}
+
 func (c *integerArrayAscendingCursor) readArrayBlock() *tsdb.IntegerArray {
 	values, _ := c.tsm.keyCursor.ReadIntegerArrayBlock(c.tsm.buf)
 	return values
@@ -646,6 +740,11 @@ type unsignedArrayAscendingCursor struct {
 		values    *tsdb.UnsignedArray
 		pos       int
 		keyCursor *KeyCursor
+
+		// unread is set when the cursor has moved to the block of keyCursor
+		// without reading it into values.
+		unread bool
+		seek   int64
 	}
 
 	end   int64
@@ -669,10 +768,9 @@ func (c *unsignedArrayAscendingCursor) reset(seek, end int64, cacheValues Values
 	})
 
 	c.tsm.keyCursor = tsmKeyCursor
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
-		return c.tsm.values.Timestamps[i] >= seek
-	})
+	c.tsm.values = c.tsm.buf
+	c.tsm.unread = true
+	c.tsm.seek = seek
 }
 
 func (c *unsignedArrayAscendingCursor) Err() error { return nil }
@@ -693,7 +791,7 @@ func (c *unsignedArrayAscendingCursor) Stats() cursors.CursorStats { return c.st
 func (c *unsignedArrayAscendingCursor) Next() *tsdb.UnsignedArray {
 	pos := 0
 	cvals := c.cache.values
-	tvals := c.tsm.values
+	tvals := c.readTSM()
 
 	c.res.Timestamps = c.res.Timestamps[:cap(c.res.Timestamps)]
 	c.res.Values = c.res.Values[:cap(c.res.Values)]
@@ -731,7 +829,7 @@ func (c *unsignedArrayAscendingCursor) Next() *tsdb.UnsignedArray {
 				// the buffer.
 				copy(c.res.Timestamps, tvals.Timestamps)
 				pos += copy(c.res.Values, tvals.Values)
-				c.nextTSM()
+				c.skipTSM()
 			} else {
 				// copy as much as we can
 				n := copy(c.res.Timestamps[pos:], tvals.Timestamps[c.tsm.pos:])
@@ -739,7 +837,7 @@ func (c *unsignedArrayAscendingCursor) Next() *tsdb.UnsignedArray {
 				pos += n
 				c.tsm.pos += n
 				if c.tsm.pos >= len(tvals.Timestamps) {
-					c.nextTSM()
+					c.skipTSM()
 				}
 			}
 		}
@@ -774,12 +872,55 @@ func (c *unsignedArrayAscendingCursor) Next() *tsdb.UnsignedArray {
 }
 
 func (c *unsignedArrayAscendingCursor) nextTSM() *tsdb.UnsignedArray {
+	c.skipTSM()
+	return c.readTSM()
+}
+
+// skipTSM moves the cursor to the next TSM block without reading it.
+func (c *unsignedArrayAscendingCursor) skipTSM() {
 	c.tsm.keyCursor.Next()
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = 0
+	c.tsm.unread = true
+}
+
+// readTSM returns the values of the current TSM block, reading the block if
+// the cursor has not done so yet.
+func (c *unsignedArrayAscendingCursor) readTSM() *tsdb.UnsignedArray {
+	if c.tsm.unread {
+		c.tsm.unread = false
+		c.tsm.values = c.readArrayBlock()
+		c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
+			return c.tsm.values.Timestamps[i] >= c.tsm.seek
+		})
+	}
 	return c.tsm.values
 }
 
+// NextStats returns the statistics of the next TSM block and moves the cursor
+// past it, if all of the values of the block are returned by the cursor and
+// the statistics are stored in the TSM file.
+func (c *unsignedArrayAscendingCursor) NextStats() (cursors.UnsignedBlockStats, bool) {
+	if !c.tsm.unread {
+		return cursors.UnsignedBlockStats{}, false
+	}
+
+	loc := c.tsm.keyCursor.statsBlock(c.tsm.seek, c.end)
+	if loc == nil || valuesOverlap(c.cache.values, c.cache.pos, loc.entry.MinTime, loc.entry.MaxTime) {
+		return cursors.UnsignedBlockStats{}, false
+	}
+
+	bs, ok := loc.r.BlockStats(&loc.entry)
+	if !ok {
+		return cursors.UnsignedBlockStats{}, false
+	}
+	s, ok := bs.Unsigned()
+	if !ok {
+		return cursors.UnsignedBlockStats{}, false
+	}
+
+	c.tsm.keyCursor.skipBlock(loc)
+	return s, true
+}
+
 func (c *unsignedArrayAscendingCursor) readArrayBlock() *tsdb.UnsignedArray {
 	values, _ := c.tsm.keyCursor.ReadUnsignedArrayBlock(c.tsm.buf)
 	return values
@@ -956,6 +1097,11 @@ type stringArrayAscendingCursor struct {
 		values    *tsdb.StringArray
 		pos       int
 		keyCursor *KeyCursor
+
+		// unread is set when the cursor has moved to the block of keyCursor
+		// without reading it into values.
+		unread bool
+		seek   int64
 	}
 
 	end   int64
@@ -979,10 +1125,9 @@ func (c *stringArrayAscendingCursor) reset(seek, end int64, cacheValues Values,
 	})
 
 	c.tsm.keyCursor = tsmKeyCursor
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
-		return c.tsm.values.Timestamps[i] >= seek
-	})
+	c.tsm.values = c.tsm.buf
+	c.tsm.unread = true
+	c.tsm.seek = seek
 }
 
 func (c *stringArrayAscendingCursor) Err() error { return nil }
@@ -1003,7 +1148,7 @@ func (c *stringArrayAscendingCursor) Stats() cursors.CursorStats { return c.stat
 func (c *stringArrayAscendingCursor) Next() *tsdb.StringArray {
 	pos := 0
 	cvals := c.cache.values
-	tvals := c.tsm.values
+	tvals := c.readTSM()
 
 	c.res.Timestamps = c.res.Timestamps[:cap(c.res.Timestamps)]
 	c.res.Values = c.res.Values[:cap(c.res.Values)]
@@ -1041,7 +1186,7 @@ func (c *stringArrayAscendingCursor) Next() *tsdb.StringArray {
 				// the buffer.
 				copy(c.res.Timestamps, tvals.Timestamps)
 				pos += copy(c.res.Values, tvals.Values)
-				c.nextTSM()
+				c.skipTSM()
 			} else {
 				// copy as much as we can
 				n := copy(c.res.Timestamps[pos:], tvals.Timestamps[c.tsm.pos:])
@@ -1049,7 +1194,7 @@ func (c *stringArrayAscendingCursor) Next() *tsdb.StringArray {
 				pos += n
 				c.tsm.pos += n
 				if c.tsm.pos >= len(tvals.Timestamps) {
-					c.nextTSM()
+					c.skipTSM()
 				}
 			}
 		}
@@ -1086,9 +1231,26 @@ func (c *stringArrayAscendingCursor) Next() *tsdb.StringArray {
 }
 
 func (c *stringArrayAscendingCursor) nextTSM() *tsdb.StringArray {
+	c.skipTSM()
+	return c.readTSM()
+}
+
+// skipTSM moves the cursor to the next TSM block without reading it.
+func (c *stringArrayAscendingCursor) skipTSM() {
 	c.tsm.keyCursor.Next()
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = 0
+	c.tsm.unread = true
+}
+
+// readTSM returns the values of the current TSM block, reading the block if
+// the cursor has not done so yet.
+func (c *stringArrayAscendingCursor) readTSM() *tsdb.StringArray {
+	if c.tsm.unread {
+		c.tsm.unread = false
+		c.tsm.values = c.readArrayBlock()
+		c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
+			return c.tsm.values.Timestamps[i] >= c.tsm.seek
+		})
+	}
 	return c.tsm.values
 }
 
@@ -1270,6 +1432,11 @@ type booleanArrayAscendingCursor struct {
 		values    *tsdb.BooleanArray
 		pos       int
 		keyCursor *KeyCursor
+
+		// unread is set when the cursor has moved to the block of keyCursor
+		// without reading it into values.
+		unread bool
+		seek   int64
 	}
 
 	end   int64
@@ -1293,10 +1460,9 @@ func (c *booleanArrayAscendingCursor) reset(seek, end int64, cacheValues Values,
 	})
 
 	c.tsm.keyCursor = tsmKeyCursor
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
-		return c.tsm.values.Timestamps[i] >= seek
-	})
+	c.tsm.values = c.tsm.buf
+	c.tsm.unread = true
+	c.tsm.seek = seek
 }
 
 func (c *booleanArrayAscendingCursor) Err() error { return nil }
@@ -1317,7 +1483,7 @@ func (c *booleanArrayAscendingCursor) Stats() cursors.CursorStats { return c.sta
 func (c *booleanArrayAscendingCursor) Next() *tsdb.BooleanArray {
 	pos := 0
 	cvals := c.cache.values
-	tvals := c.tsm.values
+	tvals := c.readTSM()
 
 	c.res.Timestamps = c.res.Timestamps[:cap(c.res.Timestamps)]
 	c.res.Values = c.res.Values[:cap(c.res.Values)]
@@ -1355,7 +1521,7 @@ func (c *booleanArrayAscendingCursor) Next() *tsdb.BooleanArray {
 				// the buffer.
 				copy(c.res.Timestamps, tvals.Timestamps)
 				pos += copy(c.res.Values, tvals.Values)
-				c.nextTSM()
+				c.skipTSM()
 			} else {
 				// copy as much as we can
 				n := copy(c.res.Timestamps[pos:], tvals.Timestamps[c.tsm.pos:])
@@ -1363,7 +1529,7 @@ func (c *booleanArrayAscendingCursor) Next() *tsdb.BooleanArray {
 				pos += n
 				c.tsm.pos += n
 				if c.tsm.pos >= len(tvals.Timestamps) {
-					c.nextTSM()
+					c.skipTSM()
 				}
 			}
 		}
@@ -1398,9 +1564,26 @@ func (c *booleanArrayAscendingCursor) Next() *tsdb.BooleanArray {
 }
 
 func (c *booleanArrayAscendingCursor) nextTSM() *tsdb.BooleanArray {
+	c.skipTSM()
+	return c.readTSM()
+}
+
+// skipTSM moves the cursor to the next TSM block without reading it.
+func (c *booleanArrayAscendingCursor) skipTSM() {
 	c.tsm.keyCursor.Next()
-	c.tsm.values = c.readArrayBlock()
-	c.tsm.pos = 0
+	c.tsm.unread = true
+}
+
+// readTSM returns the values of the current TSM block, reading the block if
+// the cursor has not done so yet.
+func (c *booleanArrayAscendingCursor) readTSM() *tsdb.BooleanArray {
+	if c.tsm.unread {
+		c.tsm.unread = false
+		c.tsm.values = c.readArrayBlock()
+		c.tsm.pos = sort.Search(c.tsm.values.Len(), func(i int) bool {
+			return c.tsm.values.Timestamps[i] >= c.tsm.seek
+		})
+	}
 	return c.tsm.values
 }
 
@@ -9,6 +9,11 @@ import (
 // DecodeBooleanArrayBlock decodes the boolean block from the byte slice
 // and writes the values to a.
 func DecodeBooleanArrayBlock(block []byte, a *tsdb.BooleanArray) error {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return err
+	}
+
 	blockType := block[0]
 	if blockType != BlockBoolean {
 		return fmt.Errorf("invalid block type: exp %d, got %d", BlockBoolean, blockType)
@@ -30,6 +35,11 @@ func DecodeBooleanArrayBlock(block []byte, a *tsdb.BooleanArray) error {
 // DecodeFloatArrayBlock decodes the float block from the byte slice
 // and writes the values to a.
 func DecodeFloatArrayBlock(block []byte, a *tsdb.FloatArray) error {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return err
+	}
+
 	blockType := block[0]
 	if blockType != BlockFloat64 {
 		return fmt.Errorf("invalid block type: exp %d, got %d", BlockFloat64, blockType)
@@ -51,6 +61,11 @@ func DecodeFloatArrayBlock(block []byte, a *tsdb.FloatArray) error {
 // DecodeIntegerArrayBlock decodes the integer block from the byte slice
 // and writes the values to a.
 func DecodeIntegerArrayBlock(block []byte, a *tsdb.IntegerArray) error {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return err
+	}
+
 	blockType := block[0]
 	if blockType != BlockInteger {
 		return fmt.Errorf("invalid block type: exp %d, got %d", BlockInteger, blockType)
@@ -72,6 +87,11 @@ func DecodeIntegerArrayBlock(block []byte, a *tsdb.IntegerArray) error {
 // DecodeUnsignedArrayBlock decodes the unsigned integer block from the byte slice
 // and writes the values to a.
 func DecodeUnsignedArrayBlock(block []byte, a *tsdb.UnsignedArray) error {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return err
+	}
+
 	blockType := block[0]
 	if blockType != BlockUnsigned {
 		return fmt.Errorf("invalid block type: exp %d, got %d", BlockUnsigned, blockType)
@@ -93,6 +113,11 @@ func DecodeUnsignedArrayBlock(block []byte, a *tsdb.UnsignedArray) error {
 // DecodeStringArrayBlock decodes the string block from the byte slice
 // and writes the values to a.
 func DecodeStringArrayBlock(block []byte, a *tsdb.StringArray) error {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return err
+	}
+
 	blockType := block[0]
 	if blockType != BlockString {
 		return fmt.Errorf("invalid block type: exp %d, got %d", BlockString, blockType)
@@ -70,9 +70,12 @@ func StringArrayEncodeAll(src []string, b []byte) ([]byte, error) {
 }
 
 func StringArrayDecodeAll(b []byte, dst []string) ([]string, error) {
-	// First byte stores the encoding type, only have snappy format
-	// currently so ignore for now.
-	if len(b) > 0 {
+	// First byte stores the encoding type.
+	if len(b) > 0 && b[0]>>4 == stringUncompressed {
+		// The final strings reference the decoded slice directly, so it is
+		// copied as b may be a block of a mapped file.
+		b = append([]byte(nil), b[1:]...)
+	} else if len(b) > 0 {
 		var err error
 		// it is important that to note that `snappy.Decode` always returns
 		// a newly allocated slice as the final strings reference this slice
@@ -0,0 +1,349 @@
+package tsm1
+
+/*
+A TSM file may store statistics of its float, integer and unsigned blocks in a
+section between the blocks and the index, so that aggregates of blocks can be
+computed without decoding them. Readers that do not know about the section are
+unaffected, as blocks are located through the index.
+
+┌─────────────────────────────────────────────────────────┬─────────────────────────┐
+│                    Block Statistics                     │         Trailer         │
+├────────┬──────┬─────────┬─────────┬─────────┬─────────┬─┼─────────┬───────┬───────┤
+│ Offset │ Type │  Count  │   Sum   │   Min   │   Max   │…│  Count  │ CRC32 │ Magic │
+│8 bytes │1 byte│ 8 bytes │ 8 bytes │ 8 bytes │ 8 bytes │ │ 4 bytes │4 bytes│4 bytes│
+└────────┴──────┴─────────┴─────────┴─────────┴─────────┴─┴─────────┴───────┴───────┘
+
+Each entry holds the offset of its block in the file, the block type, the number
+of values in the block and the bits of the sum, minimum and maximum of the values.
+Entries are ordered by offset. The trailer holds the number of entries, the CRC32
+of the entries and a magic number identifying the section.
+*/
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/cursors"
+)
+
+const (
+	// blockStatsMagic identifies the block statistics section of a TSM file.
+	blockStatsMagic uint32 = 0x16D1B57A
+
+	// Size in bytes of a block statistics entry
+	blockStatsEntrySize = 41
+
+	// Size in bytes of the block statistics trailer
+	blockStatsTrailerSize = 12
+)
+
+// BlockStats holds the statistics of a block of float, integer or unsigned
+// values.
+type BlockStats struct {
+	MinTime, MaxTime int64
+	Type             byte
+	Count            int64
+
+	// sum, min and max hold the bits of the values of the block type.
+	sum, min, max uint64
+}
+
+// Float returns the statistics of a block of float values.
+func (s BlockStats) Float() (cursors.FloatBlockStats, bool) {
+	if s.Type != BlockFloat64 {
+		return cursors.FloatBlockStats{}, false
+	}
+	return cursors.FloatBlockStats{
+		MinTime: s.MinTime,
+		MaxTime: s.MaxTime,
+		Count:   s.Count,
+		Sum:     math.Float64frombits(s.sum),
+		Min:     math.Float64frombits(s.min),
+		Max:     math.Float64frombits(s.max),
+	}, true
+}
+
+// Integer returns the statistics of a block of integer values.
+func (s BlockStats) Integer() (cursors.IntegerBlockStats, bool) {
+	if s.Type != BlockInteger {
+		return cursors.IntegerBlockStats{}, false
+	}
+	return cursors.IntegerBlockStats{
+		MinTime: s.MinTime,
+		MaxTime: s.MaxTime,
+		Count:   s.Count,
+		Sum:     int64(s.sum),
+		Min:     int64(s.min),
+		Max:     int64(s.max),
+	}, true
+}
+
+// Unsigned returns the statistics of a block of unsigned values.
+func (s BlockStats) Unsigned() (cursors.UnsignedBlockStats, bool) {
+	if s.Type != BlockUnsigned {
+		return cursors.UnsignedBlockStats{}, false
+	}
+	return cursors.UnsignedBlockStats{
+		MinTime: s.MinTime,
+		MaxTime: s.MaxTime,
+		Count:   s.Count,
+		Sum:     s.sum,
+		Min:     s.min,
+		Max:     s.max,
+	}, true
+}
+
+func floatBlockStats(values []float64) BlockStats {
+	s := BlockStats{Type: BlockFloat64, Count: int64(len(values))}
+	var sum float64
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	s.sum, s.min, s.max = math.Float64bits(sum), math.Float64bits(min), math.Float64bits(max)
+	return s
+}
+
+func integerBlockStats(values []int64) BlockStats {
+	s := BlockStats{Type: BlockInteger, Count: int64(len(values))}
+	var sum int64
+	min, max := int64(math.MaxInt64), int64(math.MinInt64)
+	for _, v := range values {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	s.sum, s.min, s.max = uint64(sum), uint64(min), uint64(max)
+	return s
+}
+
+func unsignedBlockStats(values []uint64) BlockStats {
+	s := BlockStats{Type: BlockUnsigned, Count: int64(len(values))}
+	var sum uint64
+	min, max := uint64(math.MaxUint64), uint64(0)
+	for _, v := range values {
+		sum += v
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	s.sum, s.min, s.max = sum, min, max
+	return s
+}
+
+// blockStatsWriter accumulates the block statistics section of a TSM file.
+type blockStatsWriter struct {
+	buf []byte
+
+	// scratch space to decode blocks
+	f tsdb.FloatArray
+	i tsdb.IntegerArray
+	u tsdb.UnsignedArray
+}
+
+// addValues adds the statistics of the block at offset holding values.
+// Values of types without statistics are ignored.
+func (w *blockStatsWriter) addValues(offset int64, typ byte, values Values) {
+	switch typ {
+	case BlockFloat64:
+		w.f.Values = w.f.Values[:0]
+		for _, v := range values {
+			w.f.Values = append(w.f.Values, v.(FloatValue).RawValue())
+		}
+		w.add(offset, floatBlockStats(w.f.Values))
+	case BlockInteger:
+		w.i.Values = w.i.Values[:0]
+		for _, v := range values {
+			w.i.Values = append(w.i.Values, v.(IntegerValue).RawValue())
+		}
+		w.add(offset, integerBlockStats(w.i.Values))
+	case BlockUnsigned:
+		w.u.Values = w.u.Values[:0]
+		for _, v := range values {
+			w.u.Values = append(w.u.Values, v.(UnsignedValue).RawValue())
+		}
+		w.add(offset, unsignedBlockStats(w.u.Values))
+	}
+}
+
+// addBlock decodes block and adds the statistics of the block at offset.
+// Blocks of types without statistics are ignored.
+func (w *blockStatsWriter) addBlock(offset int64, typ byte, block []byte) error {
+	switch typ {
+	case BlockFloat64:
+		if err := DecodeFloatArrayBlock(block, &w.f); err != nil {
+			return err
+		}
+		w.add(offset, floatBlockStats(w.f.Values))
+	case BlockInteger:
+		if err := DecodeIntegerArrayBlock(block, &w.i); err != nil {
+			return err
+		}
+		w.add(offset, integerBlockStats(w.i.Values))
+	case BlockUnsigned:
+		if err := DecodeUnsignedArrayBlock(block, &w.u); err != nil {
+			return err
+		}
+		w.add(offset, unsignedBlockStats(w.u.Values))
+	}
+	return nil
+}
+
+func (w *blockStatsWriter) add(offset int64, s BlockStats) {
+	var buf [blockStatsEntrySize]byte
+	binary.BigEndian.PutUint64(buf[0:8], uint64(offset))
+	buf[8] = s.Type
+	binary.BigEndian.PutUint64(buf[9:17], uint64(s.Count))
+	binary.BigEndian.PutUint64(buf[17:25], s.sum)
+	binary.BigEndian.PutUint64(buf[25:33], s.min)
+	binary.BigEndian.PutUint64(buf[33:41], s.max)
+	w.buf = append(w.buf, buf[:]...)
+}
+
+// Size returns the size in bytes of the section, or 0 if there are no entries.
+func (w *blockStatsWriter) Size() int {
+	if len(w.buf) == 0 {
+		return 0
+	}
+	return len(w.buf) + blockStatsTrailerSize
+}
+
+// WriteTo writes the entries and the trailer of the section to dst. Nothing is
+// written if there are no entries.
+func (w *blockStatsWriter) WriteTo(dst io.Writer) (int64, error) {
+	if len(w.buf) == 0 {
+		return 0, nil
+	}
+
+	var trailer [blockStatsTrailerSize]byte
+	binary.BigEndian.PutUint32(trailer[0:4], uint32(len(w.buf)/blockStatsEntrySize))
+	binary.BigEndian.PutUint32(trailer[4:8], crc32.ChecksumIEEE(w.buf))
+	binary.BigEndian.PutUint32(trailer[8:12], blockStatsMagic)
+
+	n, err := dst.Write(w.buf)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := dst.Write(trailer[:])
+	return int64(n + m), err
+}
+
+// blockStatsSection locates the block statistics entries of a TSM file.
+type blockStatsSection struct {
+	ofs, n int
+}
+
+// readBlockStatsSection returns the block statistics section preceding the
+// index at indexStart in b. A zero section is returned if the file has none.
+func readBlockStatsSection(b []byte, indexStart int) blockStatsSection {
+	if indexStart > len(b) || indexStart < 5+blockStatsTrailerSize {
+		return blockStatsSection{}
+	}
+
+	trailer := b[indexStart-blockStatsTrailerSize : indexStart]
+	if binary.BigEndian.Uint32(trailer[8:12]) != blockStatsMagic {
+		return blockStatsSection{}
+	}
+
+	n := int(binary.BigEndian.Uint32(trailer[0:4]))
+	end := indexStart - blockStatsTrailerSize
+	if n > (end-5)/blockStatsEntrySize {
+		return blockStatsSection{}
+	}
+
+	ofs := end - n*blockStatsEntrySize
+	if crc32.ChecksumIEEE(b[ofs:end]) != binary.BigEndian.Uint32(trailer[4:8]) {
+		return blockStatsSection{}
+	}
+	return blockStatsSection{ofs: ofs, n: n}
+}
+
+// find returns the statistics of the block at offset within b.
+func (s blockStatsSection) find(b []byte, offset int64) (BlockStats, bool) {
+	if s.n == 0 || len(b) < s.ofs+s.n*blockStatsEntrySize {
+		return BlockStats{}, false
+	}
+
+	entry := func(i int) []byte {
+		return b[s.ofs+i*blockStatsEntrySize : s.ofs+(i+1)*blockStatsEntrySize]
+	}
+	i := sort.Search(s.n, func(i int) bool {
+		return int64(binary.BigEndian.Uint64(entry(i)[0:8])) >= offset
+	})
+	if i == s.n {
+		return BlockStats{}, false
+	}
+
+	buf := entry(i)
+	if int64(binary.BigEndian.Uint64(buf[0:8])) != offset {
+		return BlockStats{}, false
+	}
+	return BlockStats{
+		Type:  buf[8],
+		Count: int64(binary.BigEndian.Uint64(buf[9:17])),
+		sum:   binary.BigEndian.Uint64(buf[17:25]),
+		min:   binary.BigEndian.Uint64(buf[25:33]),
+		max:   binary.BigEndian.Uint64(buf[33:41]),
+	}, true
+}
+
+// statsBlock returns the location of the next block of an ascending cursor if
+// all of its values are returned by the cursor unchanged: none of them have been
+// read, they are within [min, max), and no other block or tombstone overlaps the
+// block. Otherwise it returns nil.
+func (c *KeyCursor) statsBlock(min, max int64) *location {
+	if !c.ascending || len(c.current) == 0 {
+		return nil
+	}
+
+	loc := c.current[0]
+	if loc.readMax >= loc.entry.MinTime || loc.entry.MinTime < min || loc.entry.MaxTime >= max {
+		return nil
+	}
+
+	for _, l := range c.seeks {
+		if l != loc && l.entry.OverlapsTimeRange(loc.entry.MinTime, loc.entry.MaxTime) {
+			return nil
+		}
+	}
+
+	c.trbuf = loc.r.TombstoneRange(c.key, c.trbuf[:0])
+	for _, t := range c.trbuf {
+		if loc.entry.OverlapsTimeRange(t.Min, t.Max) {
+			return nil
+		}
+	}
+	return loc
+}
+
+// skipBlock marks the block at loc as read and moves the cursor past it.
+func (c *KeyCursor) skipBlock(loc *location) {
+	loc.markRead(loc.entry.MinTime, loc.entry.MaxTime)
+	c.Next()
+}
+
+// valuesOverlap returns true if any of the sorted values from pos are within
+// [min, max].
+func valuesOverlap(values Values, pos int, min, max int64) bool {
+	i := pos + sort.Search(len(values)-pos, func(i int) bool {
+		return values[pos+i].UnixNano() >= min
+	})
+	return i < len(values) && values[i].UnixNano() <= max
+}
@@ -0,0 +1,109 @@
+package tsm1
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/fs"
+)
+
+func TestFloatArrayAscendingCursor_NextStats(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	const key = "m,_field=v#!~#v"
+	makeVals := func(min, max int64) []Value {
+		var vals []Value
+		for ts := min; ts <= max; ts++ {
+			vals = append(vals, NewFloatValue(ts, float64(ts)))
+		}
+		return vals
+	}
+
+	// Write a file per block, all but the last with block statistics.
+	var files []string
+	for i, vals := range [][]Value{makeVals(0, 9), makeVals(20, 29), makeVals(40, 49), makeVals(60, 69)} {
+		f := MustTempFile(dir)
+		w, err := NewTSMWriter(f, WithBlockStats(i < 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write([]byte(key), vals); err != nil {
+			t.Fatal(err)
+		} else if err := w.WriteIndex(); err != nil {
+			t.Fatal(err)
+		} else if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		name := filepath.Join(dir, DefaultFormatFileName(i+1, 1)+".tsm")
+		if err := fs.RenameFile(f.Name(), name); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, name)
+	}
+
+	store := NewFileStore(dir)
+	if err := store.Replace(nil, files); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Values of the third block are deleted, and values of the second are
+	// overwritten by the cache.
+	if err := store.DeleteRange([][]byte{[]byte(key)}, 45, 45); err != nil {
+		t.Fatal(err)
+	}
+	cache := Values{NewFloatValue(25, 100), NewFloatValue(80, 80)}
+
+	// aggregate returns the count and sum of the values in [start, end), and
+	// the number of blocks aggregated with their statistics.
+	aggregate := func(start, end int64, stats bool) (count int64, sum float64, blocks int) {
+		kc := store.KeyCursor(context.Background(), []byte(key), start, true)
+		cur := newFloatArrayAscendingCursor()
+		cur.reset(start, end, cache, kc)
+		defer cur.Close()
+
+		for {
+			if stats {
+				if s, ok := cur.NextStats(); ok {
+					count += s.Count
+					sum += s.Sum
+					blocks++
+					continue
+				}
+			}
+			a := cur.Next()
+			if a.Len() == 0 {
+				return count, sum, blocks
+			}
+			count += int64(a.Len())
+			for _, v := range a.Values {
+				sum += v
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		start, end int64
+		blocks     int
+	}{
+		{name: "all", start: 0, end: 100, blocks: 1},
+		{name: "partial first block", start: 5, end: 100, blocks: 0},
+		{name: "partial last block", start: 0, end: 9, blocks: 0},
+		{name: "first block", start: 0, end: 10, blocks: 1},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			expCount, expSum, _ := aggregate(tt.start, tt.end, false)
+			count, sum, blocks := aggregate(tt.start, tt.end, true)
+			if count != expCount || sum != expSum {
+				t.Fatalf("unexpected aggregates: got count %d sum %v, exp count %d sum %v", count, sum, expCount, expSum)
+			} else if blocks != tt.blocks {
+				t.Fatalf("unexpected number of blocks aggregated with statistics: got %d, exp %d", blocks, tt.blocks)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/influxdata/influxdb/kit/tracing"
 	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
 	"github.com/influxdata/influxdb/storage/wal"
 	"github.com/influxdata/influxdb/tsdb"
 	"github.com/influxdata/influxql"
@@ -40,6 +41,22 @@ func ErrCacheMemorySizeLimitExceeded(n, limit uint64) error {
 	return CacheMemorySizeLimitExceededError{Size: n, Limit: limit}
 }
 
+// FieldTypeConflictError is the type of error returned from the cache when the
+// values of some keys conflict with the type of the values of the cache. The
+// values of the other keys are written.
+type FieldTypeConflictError struct {
+	Keys [][]byte // The composite keys of the values not written.
+}
+
+func (e *FieldTypeConflictError) Error() string {
+	return tsdb.ErrFieldTypeConflict.Error()
+}
+
+// Unwrap returns tsdb.ErrFieldTypeConflict.
+func (e *FieldTypeConflictError) Unwrap() error {
+	return tsdb.ErrFieldTypeConflict
+}
+
 // Cache maintains an in-memory store of Values for a set of keys.
 type Cache struct {
 	mu      sync.RWMutex
@@ -52,6 +69,14 @@ type Cache struct {
 	snapshot     *Cache
 	snapshotting bool
 
+	// spills are the partitions of the cache spilled to disk, oldest first.
+	// Partitions are spilled to files in spillDir if it is set, rather than
+	// rejecting writes that would exceed maxSize.
+	spills   []*cacheSpill
+	spillDir string
+	spillSeq int
+	spillMu  sync.Mutex // Serializes spills.
+
 	tracker       *cacheTracker
 	lastSnapshot  time.Time
 	lastWriteTime time.Time
@@ -77,13 +102,26 @@ func (c *Cache) Write(key []byte, values []Value) error {
 	limit := c.maxSize
 	n := c.Size() + addedSize
 
+	if limit > 0 && n > limit {
+		if err := c.spill(addedSize); err != nil {
+			c.tracker.IncWritesErr()
+			c.tracker.AddWrittenBytesErr(uint64(addedSize))
+			return err
+		}
+		n = c.Size() + addedSize
+	}
+
 	if limit > 0 && n > limit {
 		c.tracker.IncWritesErr()
 		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
 		return ErrCacheMemorySizeLimitExceeded(n, limit)
 	}
 
-	newKey, err := c.store.write(key, values)
+	// The cache stays locked while writing, so that the partition of the key is
+	// not spilled between checking the type of its spilled values and the write.
+	c.mu.RLock()
+	newKey, err := c.writeStore(c.store, key, values)
+	c.mu.RUnlock()
 	if err != nil {
 		c.tracker.IncWritesErr()
 		c.tracker.AddWrittenBytesErr(uint64(addedSize))
@@ -116,25 +154,43 @@ func (c *Cache) WriteMulti(values map[string][]Value) error {
 	// Enough room in the cache?
 	limit := c.maxSize // maxSize is safe for reading without a lock.
 	n := c.Size() + addedSize
+	if limit > 0 && n > limit {
+		if err := c.spill(addedSize); err != nil {
+			c.tracker.IncWritesErr()
+			c.tracker.AddWrittenBytesErr(uint64(addedSize))
+			return err
+		}
+		n = c.Size() + addedSize
+	}
+
 	if limit > 0 && n > limit {
 		c.tracker.IncWritesErr()
 		c.tracker.AddWrittenBytesDrop(uint64(addedSize))
 		return ErrCacheMemorySizeLimitExceeded(n, limit)
 	}
 
-	var werr error
-	c.mu.RLock()
-	store := c.store
-	c.mu.RUnlock()
+	var (
+		werr      error
+		conflicts [][]byte
+	)
 
 	var bytesWrittenErr uint64
 
+	// The cache stays locked while writing, so that no partition is spilled
+	// between checking the type of the spilled values of a key and the write.
+	c.mu.RLock()
+	store := c.store
+
 	// We'll optimistically set size here, and then decrement it for write errors.
 	for k, v := range values {
-		newKey, err := store.write([]byte(k), v)
+		newKey, err := c.writeStore(store, []byte(k), v)
 		if err != nil {
 			// The write failed, hold onto the error and adjust the size delta.
-			werr = err
+			if err == tsdb.ErrFieldTypeConflict {
+				conflicts = append(conflicts, []byte(k))
+			} else {
+				werr = err
+			}
 			addedSize -= uint64(Values(v).Size())
 			bytesWrittenErr += uint64(Values(v).Size())
 		}
@@ -143,6 +199,12 @@ func (c *Cache) WriteMulti(values map[string][]Value) error {
 			addedSize += uint64(len(k))
 		}
 	}
+	c.mu.RUnlock()
+
+	// Other errors take precedence over conflicts, which only drop some of the values.
+	if werr == nil && len(conflicts) > 0 {
+		werr = &FieldTypeConflictError{Keys: conflicts}
+	}
 
 	// Some points in the batch were dropped.  An error is returned so
 	// error stat is incremented as well.
@@ -188,11 +250,12 @@ func (c *Cache) Snapshot() (*Cache, error) {
 
 	// Did a prior snapshot exist that failed?  If so, return the existing
 	// snapshot to retry.
-	if c.snapshot.Size() > 0 {
+	if c.snapshot.Size() > 0 || len(c.snapshot.spills) > 0 {
 		return c.snapshot, nil
 	}
 
 	c.snapshot.store, c.store = c.store, c.snapshot.store
+	c.snapshot.spills, c.spills = c.spills, nil
 	snapshotSize := c.Size()
 
 	c.snapshot.tracker.SetSnapshotSize(snapshotSize) // Save the size of the snapshot on the snapshot cache
@@ -234,12 +297,20 @@ func (c *Cache) ClearSnapshot(success bool) {
 		snapStore.reset()
 	}
 
+	var spills []*cacheSpill
+	defer func() {
+		// Spills of the snapshot are removed once no longer referenced by
+		// readers of the cache. They have been written to TSM files.
+		_ = closeSpills(spills)
+	}()
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	c.snapshotting = false
 
 	if success {
+		spills = c.snapshot.spills
 		snapshotSize := c.tracker.SnapshotSize()
 		c.tracker.SetSnapshotsActive(0)
 		c.tracker.SubMemBytes(snapshotSize) // decrement the number of bytes in cache
@@ -273,12 +344,18 @@ func (c *Cache) Count() int {
 	return n
 }
 
-// Keys returns a sorted slice of all keys under management by the cache.
+// Keys returns a sorted slice of all keys under management by the cache,
+// including keys spilled to disk.
 func (c *Cache) Keys() [][]byte {
 	c.mu.RLock()
 	store := c.store
+	spilled := spilledKeys(c.spills)
 	c.mu.RUnlock()
-	return store.keys(true)
+
+	if len(spilled) == 0 {
+		return store.keys(true)
+	}
+	return bytesutil.SortDedup(append(store.keys(false), spilled...))
 }
 
 func (c *Cache) Split(n int) []*Cache {
@@ -293,18 +370,63 @@ func (c *Cache) Split(n int) []*Cache {
 			store: storers[i],
 		}
 	}
+
+	// Spills go to the cache holding the partition they were spilled from.
+	for _, s := range c.spills {
+		caches[s.partition%n].spills = append(caches[s.partition%n].spills, s)
+	}
 	return caches
 }
 
+// Contains returns true if the cache, or its snapshot, holds values for key.
+func (c *Cache) Contains(key []byte) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if e := c.store.entry(key); e != nil && e.count() > 0 {
+		return true
+	}
+	if c.snapshot != nil {
+		if e := c.snapshot.store.entry(key); e != nil && e.count() > 0 {
+			return true
+		}
+	}
+	if c.snapshot != nil {
+		for _, s := range c.snapshot.spills {
+			if s.r.Contains(key) {
+				return true
+			}
+		}
+	}
+	for _, s := range c.spills {
+		if s.r.Contains(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Type returns the series type for a key.
 func (c *Cache) Type(key []byte) (models.FieldType, error) {
+	var (
+		blockType byte
+		spilled   bool
+	)
+
 	c.mu.RLock()
 	e := c.store.entry(key)
 	if e == nil && c.snapshot != nil {
 		e = c.snapshot.store.entry(key)
 	}
+	if e == nil {
+		blockType, spilled = c.spilledType(key)
+	}
 	c.mu.RUnlock()
 
+	if spilled {
+		return BlockTypeToFieldType(blockType), nil
+	}
+
 	if e != nil {
 		typ, err := e.InfluxQLType()
 		if err != nil {
@@ -330,18 +452,28 @@ func (c *Cache) Type(key []byte) (models.FieldType, error) {
 
 // Values returns a copy of all values, deduped and sorted, for the given key.
 func (c *Cache) Values(key []byte) Values {
-	var snapshotEntries *entry
+	var (
+		snapshotEntries *entry
+		snapshotSpills  []*cacheSpill
+	)
 
 	c.mu.RLock()
 	e := c.store.entry(key)
 	if c.snapshot != nil {
 		snapshotEntries = c.snapshot.store.entry(key)
+		snapshotSpills = acquireSpills(c.snapshot.spills)
 	}
+	spills := acquireSpills(c.spills)
 	c.mu.RUnlock()
 
+	snapshotSpilled := spilledValues(snapshotSpills, key)
+	spilled := spilledValues(spills, key)
+	releaseSpills(snapshotSpills)
+	releaseSpills(spills)
+
 	if e == nil {
-		if snapshotEntries == nil {
-			// No values in hot cache or snapshots.
+		if snapshotEntries == nil && len(snapshotSpilled) == 0 && len(spilled) == 0 {
+			// No values in hot cache, snapshots or spills.
 			return nil
 		}
 	} else {
@@ -349,16 +481,27 @@ func (c *Cache) Values(key []byte) Values {
 	}
 
 	// Build the sequence of entries that will be returned, in the correct order.
-	// Calculate the required size of the destination buffer.
+	// Values spilled to disk were written before the values of the store they
+	// were spilled from. Calculate the required size of the destination buffer.
 	var entries []*entry
 	sz := 0
 
+	if len(snapshotSpilled) > 0 {
+		entries = append(entries, spilledEntry(snapshotSpilled))
+		sz += len(snapshotSpilled)
+	}
+
 	if snapshotEntries != nil {
 		snapshotEntries.deduplicate() // guarantee we are deduplicated
 		entries = append(entries, snapshotEntries)
 		sz += snapshotEntries.count()
 	}
 
+	if len(spilled) > 0 {
+		entries = append(entries, spilledEntry(spilled))
+		sz += len(spilled)
+	}
+
 	if e != nil {
 		entries = append(entries, e)
 		sz += e.count()
@@ -436,6 +579,12 @@ func (c *Cache) DeleteBucketRange(ctx context.Context, name string, min, max int
 		c.store.remove([]byte(k))
 	}
 
+	// The index of a spill file is updated even if writing its tombstone fails,
+	// and spill files are never reopened.
+	for _, s := range c.spills {
+		_ = s.r.DeletePrefix([]byte(name), min, max, pred, nil)
+	}
+
 	c.tracker.DecCacheSize(total)
 	c.tracker.SetMemBytes(uint64(c.Size()))
 }
@@ -451,24 +600,49 @@ func (c *Cache) SetMaxSize(size uint64) {
 // It doesn't lock the cache but it does read-lock the entry if there is one for the key.
 // values should only be used in compact.go in the CacheKeyIterator.
 func (c *Cache) values(key []byte) Values {
-	e := c.store.entry(key)
-	if e == nil {
-		return nil
+	var v Values
+	if e := c.store.entry(key); e != nil {
+		e.mu.RLock()
+		v = e.values
+		e.mu.RUnlock()
+	}
+
+	if len(c.spills) == 0 {
+		return v
 	}
-	e.mu.RLock()
-	v := e.values
-	e.mu.RUnlock()
-	return v
+	spilled := spilledValues(c.spills, key)
+	if len(spilled) == 0 {
+		return v
+	}
+	return append(spilled, v...).Deduplicate()
 }
 
 // ApplyEntryFn applies the function f to each entry in the Cache.
 // ApplyEntryFn calls f on each entry in turn, within the same goroutine.
+// Keys spilled to disk are passed to f with a temporary entry holding their
+// spilled values, so f may be called twice for a key.
 // It is safe for use by multiple goroutines.
 func (c *Cache) ApplyEntryFn(f func(key string, entry *entry) error) error {
 	c.mu.RLock()
 	store := c.store
+	spills := acquireSpills(c.spills)
 	c.mu.RUnlock()
-	return store.applySerial(f)
+	defer releaseSpills(spills)
+
+	if err := store.applySerial(f); err != nil {
+		return err
+	}
+
+	for _, key := range bytesutil.SortDedup(spilledKeys(spills)) {
+		values := spilledValues(spills, key)
+		if len(values) == 0 {
+			continue
+		}
+		if err := f(string(key), spilledEntry(values)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // CacheLoader processes a set of WAL segment files, and loads a cache with the data
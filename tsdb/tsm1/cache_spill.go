@@ -0,0 +1,334 @@
+package tsm1
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// cacheSpill is a partition of the cache that has been spilled to a TSM file
+// on disk to keep the cache within its maximum size. Spill files are temporary:
+// their values are also in the WAL, so they are removed once snapshotted or when
+// the engine is closed.
+type cacheSpill struct {
+	partition int // Index of the ring partition the values were spilled from.
+	r         *TSMReader
+}
+
+// SetSpillDir makes the cache spill its least recently written partitions to
+// TSM files in dir when a write would exceed its maximum size, instead of
+// rejecting the write. Spilling is disabled if dir is empty.
+func (c *Cache) SetSpillDir(dir string) {
+	c.mu.Lock()
+	c.spillDir = dir
+	c.mu.Unlock()
+}
+
+// spill spills partitions of the cache to disk, least recently written first,
+// until n more bytes fit within the maximum size of the cache. It stops early if
+// spilling is disabled or no partition holds values.
+func (c *Cache) spill(n uint64) error {
+	c.spillMu.Lock()
+	defer c.spillMu.Unlock()
+
+	for c.Size()+n > c.maxSize {
+		if ok, err := c.spillPartition(); err != nil {
+			return fmt.Errorf("spilling cache: %v", err)
+		} else if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// spillPartition spills the least recently written partition of the cache
+// holding values. It returns false if spilling is disabled or there is nothing
+// to spill.
+func (c *Cache) spillPartition() (bool, error) {
+	// The cache is locked so that readers see the values of the partition either
+	// in the partition or in the spill file.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.spillDir == "" {
+		return false, nil
+	}
+
+	i := -1
+	for j, p := range c.store.partitions {
+		if p.count() == 0 {
+			continue
+		}
+		if i < 0 || p.lastWrite() < c.store.partitions[i].lastWrite() {
+			i = j
+		}
+	}
+	if i < 0 {
+		return false, nil
+	}
+
+	p := c.store.partitions[i]
+	p.mu.Lock()
+	store := p.store
+	p.store = make(map[string]*entry)
+	p.mu.Unlock()
+
+	var size uint64
+	keys := make([]string, 0, len(store))
+	for k, e := range store {
+		size += uint64(e.size() + len(k))
+		if e.count() > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	r, err := c.writeSpill(keys, store)
+	if err != nil {
+		p.restore(store)
+		return false, err
+	}
+
+	c.spills = append(c.spills, &cacheSpill{partition: i, r: r})
+	c.tracker.DecCacheSize(size)
+	c.tracker.SubMemBytes(size)
+	return true, nil
+}
+
+// writeSpill writes the values of the keys of store to a new spill file and
+// returns a reader of the file.
+func (c *Cache) writeSpill(keys []string, store map[string]*entry) (_ *TSMReader, err error) {
+	if err := os.MkdirAll(c.spillDir, 0777); err != nil {
+		return nil, err
+	}
+
+	c.spillSeq++
+	path := filepath.Join(c.spillDir, fmt.Sprintf("%09d.%s", c.spillSeq, TSMFileExtension))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(path)
+			os.RemoveAll(StatsFilename(path))
+		}
+	}()
+
+	w, err := NewTSMWriter(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	for _, k := range keys {
+		e := store[k]
+		e.deduplicate()
+
+		e.mu.RLock()
+		values := e.values
+		e.mu.RUnlock()
+
+		for len(values) > 0 {
+			end := len(values)
+			if end > MaxPointsPerBlock {
+				end = MaxPointsPerBlock
+			}
+			if err := w.Write([]byte(k), values[:end]); err != nil {
+				w.Close()
+				return nil, err
+			}
+			values = values[end:]
+		}
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		w.Close()
+		return nil, err
+	} else if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	if f, err = os.Open(path); err != nil {
+		return nil, err
+	}
+	r, err := NewTSMReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// spilled returns true if the cache has values spilled to disk.
+func (c *Cache) spilled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.spills) > 0 || (c.snapshot != nil && len(c.snapshot.spills) > 0)
+}
+
+// acquireSpills holds a reference to each of spills, so that they can be read
+// once the cache is unlocked. The references must be released with
+// releaseSpills. acquireSpills must be called with c.mu held.
+func acquireSpills(spills []*cacheSpill) []*cacheSpill {
+	for _, s := range spills {
+		s.r.Ref()
+	}
+	return spills
+}
+
+func releaseSpills(spills []*cacheSpill) {
+	for _, s := range spills {
+		s.r.Unref()
+	}
+}
+
+// spilledValues returns the values of key in spills, in the order they were
+// written.
+func spilledValues(spills []*cacheSpill, key []byte) Values {
+	var values Values
+	for _, s := range spills {
+		if !s.r.Contains(key) {
+			continue
+		}
+		// Spill files are written and read by this process only, so errors
+		// reading them are not expected.
+		if vals, err := s.r.ReadAll(key); err == nil {
+			values = append(values, vals...)
+		}
+	}
+	return values
+}
+
+// spilledType returns the block type of the values of key spilled to disk by
+// the cache or its snapshot. It must be called with c.mu held.
+func (c *Cache) spilledType(key []byte) (byte, bool) {
+	for _, s := range c.spills {
+		if typ, err := s.r.Type(key); err == nil {
+			return typ, true
+		}
+	}
+	if c.snapshot != nil {
+		for _, s := range c.snapshot.spills {
+			if typ, err := s.r.Type(key); err == nil {
+				return typ, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// spilledEntry returns a temporary entry holding values read from spill files.
+func spilledEntry(values Values) *entry {
+	e := &entry{values: values, n: int64(len(values))}
+	if len(values) > 0 {
+		e.vtype = valueType(values[0])
+	}
+	return e
+}
+
+// spilledKeys returns the unsorted keys with values in spills.
+func spilledKeys(spills []*cacheSpill) [][]byte {
+	var keys [][]byte
+	for _, s := range spills {
+		iter := s.r.Iterator(nil)
+		for iter.Next() {
+			keys = append(keys, append([]byte(nil), iter.Key()...))
+		}
+	}
+	return keys
+}
+
+// removeSpills closes and removes the spill files of the cache and its
+// snapshot.
+func (c *Cache) removeSpills() error {
+	c.mu.Lock()
+	spills := c.spills
+	c.spills = nil
+	if c.snapshot != nil {
+		spills = append(spills, c.snapshot.spills...)
+		c.snapshot.spills = nil
+	}
+	c.mu.Unlock()
+
+	return closeSpills(spills)
+}
+
+// closeSpills closes and removes spill files once they are no longer in use.
+func closeSpills(spills []*cacheSpill) error {
+	for _, s := range spills {
+		if err := s.r.Close(); err != nil {
+			return err
+		} else if err := s.r.Remove(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastWrite returns the time in nanoseconds of the last write to the partition.
+func (p *partition) lastWrite() int64 {
+	return atomic.LoadInt64(&p.lastWriteNs)
+}
+
+// touch records a write to the partition.
+func (p *partition) touch() {
+	atomic.StoreInt64(&p.lastWriteNs, time.Now().UnixNano())
+}
+
+// restore makes store the store of the partition again after a failed spill,
+// keeping the values written to the partition since.
+func (p *partition) restore(store map[string]*entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k, e := range p.store {
+		if old := store[k]; old != nil {
+			e.mu.RLock()
+			// The values were added to an entry of the same key, so their
+			// type is the type of old.
+			_ = old.add(e.values)
+			e.mu.RUnlock()
+		} else {
+			store[k] = e
+		}
+	}
+	p.store = store
+}
+
+// writeStore writes the values of key to store, unless values of key of a
+// different type were spilled to disk, which would mix types once the spilled
+// and in-memory values are merged. It must be called with c.mu held.
+func (c *Cache) writeStore(store *ring, key []byte, values Values) (bool, error) {
+	if len(values) > 0 {
+		for _, s := range c.spills {
+			if typ, err := s.r.Type(key); err == nil && typ != valueBlockType(values[0]) {
+				return false, tsdb.ErrFieldTypeConflict
+			}
+		}
+	}
+	return store.write(key, values)
+}
+
+// valueBlockType returns the type of the block encoding v.
+func valueBlockType(v Value) byte {
+	switch v.(type) {
+	case FloatValue:
+		return BlockFloat64
+	case IntegerValue:
+		return BlockInteger
+	case UnsignedValue:
+		return BlockUnsigned
+	case BooleanValue:
+		return BlockBoolean
+	case StringValue:
+		return BlockString
+	default:
+		return 0
+	}
+}
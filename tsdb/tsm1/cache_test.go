@@ -16,7 +16,9 @@ import (
 	"testing"
 
 	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
 	"github.com/influxdata/influxdb/storage/wal"
+	"github.com/influxdata/influxdb/tsdb"
 
 	"github.com/golang/snappy"
 )
@@ -560,6 +562,129 @@ func TestCache_CacheWriteMemoryExceeded(t *testing.T) {
 	}
 }
 
+func TestCache_CacheWriteMemoryExceeded_SpillToDisk(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	v0 := NewValue(1, 1.0)
+	v1 := NewValue(2, 2.0)
+	v2 := NewValue(1, 3.0)
+
+	c := NewCache(uint64(2 * v0.Size()))
+	c.SetSpillDir(dir)
+	defer c.removeSpills()
+
+	// Each write exceeds the size of the cache, spilling the previous ones.
+	if err := c.Write([]byte("foo"), Values{v0}); err != nil {
+		t.Fatalf("failed to write key foo to cache: %s", err.Error())
+	}
+	if err := c.Write([]byte("bar"), Values{v1}); err != nil {
+		t.Fatalf("failed to write key bar to cache: %s", err.Error())
+	}
+	if err := c.WriteMulti(map[string][]Value{"foo": {v1, v2}}); err != nil {
+		t.Fatalf("failed to write key foo to cache: %s", err.Error())
+	}
+	if !c.spilled() {
+		t.Fatal("expected values to be spilled to disk")
+	}
+
+	if exp, keys := [][]byte{[]byte("bar"), []byte("foo")}, c.Keys(); !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("cache keys incorrect after writes, exp %v, got %v", exp, keys)
+	}
+	if exp, values := (Values{v2, v1}), c.Values([]byte("foo")); !reflect.DeepEqual(values, exp) {
+		t.Fatalf("values for foo incorrect, exp: %v, got %v", exp, values)
+	}
+	if exp, values := (Values{v1}), c.Values([]byte("bar")); !reflect.DeepEqual(values, exp) {
+		t.Fatalf("values for bar incorrect, exp: %v, got %v", exp, values)
+	}
+	if !c.Contains([]byte("bar")) {
+		t.Fatal("expected cache to contain bar")
+	}
+	if typ, err := c.Type([]byte("bar")); err != nil || typ != models.Float {
+		t.Fatalf("unexpected type of bar: %v, %v", typ, err)
+	}
+
+	// Deleted values are removed from spill files.
+	c.DeleteBucketRange(context.Background(), "bar", 0, 10, nil)
+	if values := c.Values([]byte("bar")); len(values) != 0 {
+		t.Fatalf("unexpected values for bar after delete: %v", values)
+	}
+
+	// The snapshot takes the spilled values, which are removed once cleared.
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to snapshot cache: %v", err)
+	}
+	snapshot.Deduplicate()
+	if exp, values := (Values{v2, v1}), snapshot.values([]byte("foo")); !reflect.DeepEqual(values, exp) {
+		t.Fatalf("snapshot values for foo incorrect, exp: %v, got %v", exp, values)
+	}
+	var n int
+	for _, s := range snapshot.Split(2) {
+		for _, k := range s.Keys() {
+			n += len(s.values(k))
+		}
+	}
+	if n != 2 {
+		t.Fatalf("unexpected number of values in snapshot splits: got %d, exp 2", n)
+	}
+	if exp, values := (Values{v2, v1}), c.Values([]byte("foo")); !reflect.DeepEqual(values, exp) {
+		t.Fatalf("values for foo incorrect while snapshotting, exp: %v, got %v", exp, values)
+	}
+
+	c.ClearSnapshot(true)
+	if c.spilled() {
+		t.Fatal("unexpected spilled values after clearing snapshot")
+	}
+	if files, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(files) != 0 {
+		t.Fatalf("unexpected spill files after clearing snapshot: %d", len(files))
+	}
+}
+
+func TestCache_SpillToDisk_TypeConflict(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	v0 := NewValue(1, 1.0)
+	v1 := NewValue(2, int64(2))
+
+	c := NewCache(uint64(2 * v0.Size()))
+	c.SetSpillDir(dir)
+	defer c.removeSpills()
+
+	if err := c.Write([]byte("foo"), Values{v0}); err != nil {
+		t.Fatalf("failed to write key foo to cache: %s", err.Error())
+	}
+	if ok, err := c.spillPartition(); err != nil || !ok {
+		t.Fatalf("failed to spill cache: %v, %v", ok, err)
+	}
+
+	// The values of foo are no longer in memory, but their type still applies.
+	if err := c.Write([]byte("foo"), Values{v1}); err != tsdb.ErrFieldTypeConflict {
+		t.Fatalf("unexpected error writing integer to spilled float key: got %v, exp %v", err, tsdb.ErrFieldTypeConflict)
+	}
+	if err := c.WriteMulti(map[string][]Value{"foo": {v1}}); err == nil {
+		t.Fatal("expected error writing integer to spilled float key")
+	}
+
+	snapshot, err := c.Snapshot()
+	if err != nil {
+		t.Fatalf("failed to snapshot cache: %v", err)
+	}
+	iter := NewCacheKeyIterator(snapshot, 1, nil)
+	for iter.Next() {
+		if _, _, _, _, err := iter.Read(); err != nil {
+			t.Fatalf("unexpected error reading snapshot: %v", err)
+		}
+	}
+	if exp, values := (Values{v0}), snapshot.values([]byte("foo")); !reflect.DeepEqual(values, exp) {
+		t.Fatalf("snapshot values for foo incorrect, exp: %v, got %v", exp, values)
+	}
+	c.ClearSnapshot(true)
+}
+
 func TestCache_Deduplicate_Concurrent(t *testing.T) {
 	if testing.Short() || os.Getenv("GORACE") != "" || os.Getenv("APPVEYOR") != "" {
 		t.Skip("Skipping test in short, race, appveyor mode.")
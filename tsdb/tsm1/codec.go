@@ -0,0 +1,283 @@
+package tsm1
+
+import (
+	"bytes"
+	"compress/flate"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/golang/snappy"
+)
+
+// Blocks are encoded with the built-in encodings of their type, after which a
+// codec may compress the encoded values further. The high 4 bits of the first
+// byte of a block hold the ID of that codec and the low 4 bits the block type.
+// Blocks of the default codec, ID 0, are stored as encoded, which keeps files
+// written before codecs were introduced readable, and files may mix blocks of
+// any codecs.
+//
+// The timestamps of a block are never compressed by a codec, so that the
+// timestamps of any block can be read without knowing its codec.
+
+const (
+	// DefaultCodec is the name of the codec storing blocks as encoded by the
+	// built-in encodings.
+	DefaultCodec = "default"
+
+	// FlateCodec is the name of the codec compressing blocks with DEFLATE,
+	// trading compression and decompression speed for a higher ratio.
+	FlateCodec = "flate"
+
+	// FloatShuffleCodec is the name of the codec compressing float blocks with
+	// DEFLATE, after regrouping the bytes of the values by position if that
+	// compresses better. It is meant for archived data, trading speed for a
+	// ratio no worse than that of FlateCodec, but for a byte per block. It only
+	// compresses float blocks.
+	FloatShuffleCodec = "float-shuffle"
+
+	// ZstdCodec is the name of the codec compressing blocks with Zstandard,
+	// which decompresses faster than DEFLATE for a similar ratio. It is meant
+	// for string blocks, but compresses blocks of any type.
+	ZstdCodec = "zstd"
+
+	// maxCodecID is the largest ID a codec can be registered with.
+	maxCodecID = 0x0f
+)
+
+// Codec compresses the encoded values of blocks.
+type Codec interface {
+	// Encode appends the compressed form of src to dst.
+	Encode(dst, src []byte) ([]byte, error)
+
+	// Decode appends the decompressed form of src, as compressed by Encode,
+	// to dst.
+	Decode(dst, src []byte) ([]byte, error)
+}
+
+var codecs = struct {
+	mu    sync.RWMutex
+	byID  [maxCodecID + 1]Codec
+	names [maxCodecID + 1]string
+}{
+	names: [maxCodecID + 1]string{0: DefaultCodec},
+}
+
+// BlockTypeCodec is implemented by codecs that only compress blocks of some
+// types.
+type BlockTypeCodec interface {
+	Codec
+
+	// SupportsBlockType reports whether the codec compresses blocks of the type.
+	SupportsBlockType(typ byte) bool
+}
+
+func init() {
+	RegisterCodec(1, FlateCodec, flateCodec{})
+	RegisterCodec(2, FloatShuffleCodec, floatShuffleCodec{})
+	RegisterCodec(3, ZstdCodec, zstdCodec{})
+}
+
+// RegisterCodec makes a codec available by the provided ID and name. The ID is
+// recorded in the blocks compressed with the codec and must never be reused for
+// another codec. If RegisterCodec is called twice with the same ID or name, or
+// if the ID is out of range, it panics.
+func RegisterCodec(id byte, name string, c Codec) {
+	codecs.mu.Lock()
+	defer codecs.mu.Unlock()
+
+	if id == 0 || id > maxCodecID {
+		panic(fmt.Sprintf("tsm1: codec ID %d out of range", id))
+	} else if codecs.names[id] != "" {
+		panic(fmt.Sprintf("tsm1: codec ID %d registered twice", id))
+	}
+	for _, n := range codecs.names {
+		if n == name {
+			panic(fmt.Sprintf("tsm1: codec %q registered twice", name))
+		}
+	}
+	codecs.byID[id], codecs.names[id] = c, name
+}
+
+// CodecByName returns the ID of the codec registered with the name.
+func CodecByName(name string) (byte, bool) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	for id, n := range codecs.names {
+		if n != "" && n == name {
+			return byte(id), true
+		}
+	}
+	return 0, false
+}
+
+// CodecName returns the name of the codec with the ID.
+func CodecName(id byte) string {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	if int(id) < len(codecs.names) && codecs.names[id] != "" {
+		return codecs.names[id]
+	}
+	return fmt.Sprintf("unknown(%d)", id)
+}
+
+// CodecNames returns the sorted names of the registered codecs.
+func CodecNames() []string {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	var names []string
+	for _, n := range codecs.names {
+		if n != "" {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CodecSupports reports whether the codec with the ID compresses blocks of the
+// type.
+func CodecSupports(id, typ byte) bool {
+	if id == 0 {
+		return true
+	}
+	c, err := codecByID(id)
+	if err != nil {
+		return false
+	}
+	if c, ok := c.(BlockTypeCodec); ok {
+		return c.SupportsBlockType(typ)
+	}
+	return true
+}
+
+func codecByID(id byte) (Codec, error) {
+	codecs.mu.RLock()
+	defer codecs.mu.RUnlock()
+
+	if int(id) >= len(codecs.byID) || codecs.byID[id] == nil {
+		return nil, fmt.Errorf("unknown block codec: %d", id)
+	}
+	return codecs.byID[id], nil
+}
+
+// BlockCodec returns the ID of the codec of a block.
+func BlockCodec(block []byte) byte {
+	return block[0] >> 4
+}
+
+// BlockCodecs are the IDs of the codecs blocks are compressed with, indexed by
+// block type. The zero value uses the default codec for all blocks.
+type BlockCodecs [BlockUnsigned + 1]byte
+
+// For returns the ID of the codec for blocks of the type.
+func (c BlockCodecs) For(typ byte) byte {
+	if int(typ) < len(c) {
+		return c[typ]
+	}
+	return 0
+}
+
+// transcodeBlock returns the block compressed with the codec with the ID. The
+// block is returned unchanged if it already is, and as stored by the default
+// codec if the codec does not compress blocks of its type.
+func transcodeBlock(block []byte, id byte) ([]byte, error) {
+	if BlockCodec(block) == id {
+		return block, nil
+	}
+
+	block, err := decodeBlockCodec(block)
+	if err != nil || id == 0 || !CodecSupports(id, block[0]) {
+		return block, err
+	}
+
+	c, err := codecByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	typ := block[0]
+	tb, vb, err := unpackBlock(block[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	// Snappy compressed strings are compressed by the codec instead.
+	if typ == BlockString && len(vb) > 0 && vb[0]>>4 == stringCompressedSnappy {
+		data, err := snappy.Decode(nil, vb[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode string block: %v", err.Error())
+		}
+		vb = append([]byte{stringUncompressed << 4}, data...)
+	}
+
+	cb, err := c.Encode(nil, vb)
+	if err != nil {
+		return nil, err
+	}
+	return packBlock(nil, id<<4|typ, tb, cb), nil
+}
+
+// decodeBlockCodec returns the block decompressed by its codec, as stored by
+// the default codec.
+func decodeBlockCodec(block []byte) ([]byte, error) {
+	id := BlockCodec(block)
+	if id == 0 {
+		return block, nil
+	}
+
+	c, err := codecByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tb, cb, err := unpackBlock(block[1:])
+	if err != nil {
+		return nil, err
+	}
+	vb, err := c.Decode(nil, cb)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s block: %v", CodecName(id), err)
+	}
+	return packBlock(nil, block[0]&0x0f, tb, vb), nil
+}
+
+// flateCodec compresses blocks with DEFLATE at the best compression level.
+type flateCodec struct{}
+
+var flateWriterPool = sync.Pool{
+	New: func() interface{} {
+		w, _ := flate.NewWriter(nil, flate.BestCompression)
+		return w
+	},
+}
+
+func (flateCodec) Encode(dst, src []byte) ([]byte, error) {
+	buf := bytes.NewBuffer(dst)
+	w := flateWriterPool.Get().(*flate.Writer)
+	defer flateWriterPool.Put(w)
+
+	w.Reset(buf)
+	if _, err := w.Write(src); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (flateCodec) Decode(dst, src []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(src))
+	defer r.Close()
+
+	buf := bytes.NewBuffer(dst)
+	if _, err := io.Copy(buf, r); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
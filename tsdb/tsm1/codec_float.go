@@ -0,0 +1,101 @@
+package tsm1
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+const (
+	// floatShuffleGorilla marks values compressed as encoded by the built-in
+	// float encoding.
+	floatShuffleGorilla = 0
+
+	// floatShuffleBytes marks values compressed with the bytes of the values
+	// grouped by position, all first bytes followed by all second bytes and so
+	// on. Bytes at the same position of values of similar magnitude tend to be
+	// equal, which DEFLATE compresses well.
+	floatShuffleBytes = 1
+)
+
+// floatShuffleCodec compresses float blocks with DEFLATE, either as encoded
+// or with the bytes of the values shuffled, whichever is smaller. The first
+// byte of a compressed block records the layout.
+type floatShuffleCodec struct{}
+
+func (floatShuffleCodec) SupportsBlockType(typ byte) bool {
+	return typ == BlockFloat64
+}
+
+func (floatShuffleCodec) Encode(dst, src []byte) ([]byte, error) {
+	values, err := FloatArrayDecodeAll(src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode float block: %v", err)
+	}
+
+	gorilla, err := flateCodec{}.Encode([]byte{floatShuffleGorilla}, src)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, binary.MaxVarintLen64+8*len(values))
+	n := binary.PutUvarint(buf, uint64(len(values)))
+	shuffleFloats(buf[n:n+8*len(values)], values)
+	shuffled, err := flateCodec{}.Encode([]byte{floatShuffleBytes}, buf[:n+8*len(values)])
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shuffled) < len(gorilla) {
+		return append(dst, shuffled...), nil
+	}
+	return append(dst, gorilla...), nil
+}
+
+func (floatShuffleCodec) Decode(dst, src []byte) ([]byte, error) {
+	if len(src) == 0 {
+		return nil, fmt.Errorf("float-shuffle: empty block")
+	}
+
+	data, err := flateCodec{}.Decode(nil, src[1:])
+	if err != nil {
+		return nil, err
+	}
+
+	switch src[0] {
+	case floatShuffleGorilla:
+		return append(dst, data...), nil
+	case floatShuffleBytes:
+		count, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) != 8*count {
+			return nil, fmt.Errorf("float-shuffle: invalid block length")
+		}
+		values := make([]float64, count)
+		unshuffleFloats(values, data[n:])
+		return FloatArrayEncodeAll(values, dst)
+	default:
+		return nil, fmt.Errorf("float-shuffle: unknown layout %d", src[0])
+	}
+}
+
+// shuffleFloats writes the bytes of the bits of values to dst, grouped by
+// position from the most significant byte.
+func shuffleFloats(dst []byte, values []float64) {
+	for i, v := range values {
+		bits := math.Float64bits(v)
+		for j := 0; j < 8; j++ {
+			dst[j*len(values)+i] = byte(bits >> uint(56-8*j))
+		}
+	}
+}
+
+// unshuffleFloats reads the values written to src by shuffleFloats.
+func unshuffleFloats(values []float64, src []byte) {
+	for i := range values {
+		var bits uint64
+		for j := 0; j < 8; j++ {
+			bits |= uint64(src[j*len(values)+i]) << uint(56-8*j)
+		}
+		values[i] = math.Float64frombits(bits)
+	}
+}
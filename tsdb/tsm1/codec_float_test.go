@@ -0,0 +1,88 @@
+package tsm1
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFloatShuffleCodec(t *testing.T) {
+	flate, _ := CodecByName(FlateCodec)
+	shuffle, _ := CodecByName(FloatShuffleCodec)
+
+	rng := rand.New(rand.NewSource(1))
+	walk := 20.0
+	series := map[string]func(i int) float64{
+		"counts": func(int) float64 { return float64(rng.Intn(100)) },
+		"decimals": func(int) float64 {
+			walk += rng.NormFloat64() / 10
+			return math.Round(walk*100) / 100
+		},
+		"wave":     func(i int) float64 { return math.Sin(float64(i) / 50) },
+		"constant": func(int) float64 { return 1.5 },
+	}
+	var shuffleSize, flateSize int
+	for name, fn := range series {
+		t.Run(name, func(t *testing.T) {
+			values := make([]Value, 1000)
+			for i := range values {
+				values[i] = NewValue(int64(i), fn(i))
+			}
+			block, err := Values(values).Encode(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compressed, err := transcodeBlock(block, shuffle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := BlockCodec(compressed); got != shuffle {
+				t.Fatalf("unexpected codec: got %d, exp %d", got, shuffle)
+			}
+			decoded, err := DecodeBlock(compressed, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, values) {
+				t.Fatal("decoded values differ from those written")
+			}
+
+			// The values are compressed as well as by flate, but for the
+			// byte recording the layout.
+			deflated, err := transcodeBlock(block, flate)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(compressed) > len(deflated)+1 {
+				t.Fatalf("compressed to %d bytes, more than the %d bytes of flate", len(compressed), len(deflated))
+			}
+			shuffleSize += len(compressed)
+			flateSize += len(deflated)
+		})
+	}
+	if shuffleSize >= flateSize {
+		t.Fatalf("compressed to %d bytes, no less than the %d bytes of flate", shuffleSize, flateSize)
+	}
+}
+
+func TestFloatShuffleCodec_Types(t *testing.T) {
+	shuffle, _ := CodecByName(FloatShuffleCodec)
+	if !CodecSupports(shuffle, BlockFloat64) || CodecSupports(shuffle, BlockString) {
+		t.Fatal("expected the codec to only compress float blocks")
+	}
+
+	// Blocks of other types are stored with the default codec.
+	block, err := Values{NewValue(1, "a"), NewValue(2, "b")}.Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := transcodeBlock(block, shuffle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if BlockCodec(got) != 0 {
+		t.Fatalf("unexpected codec of string block: %d", BlockCodec(got))
+	}
+}
@@ -0,0 +1,129 @@
+package tsm1_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestCodecByName(t *testing.T) {
+	if id, ok := tsm1.CodecByName(tsm1.DefaultCodec); !ok || id != 0 {
+		t.Fatalf("unexpected default codec: %d, %v", id, ok)
+	}
+
+	id, ok := tsm1.CodecByName(tsm1.FlateCodec)
+	if !ok || id == 0 {
+		t.Fatalf("unexpected flate codec: %d, %v", id, ok)
+	}
+	if got, exp := tsm1.CodecName(id), tsm1.FlateCodec; got != exp {
+		t.Fatalf("unexpected codec name: got %q, exp %q", got, exp)
+	}
+
+	if _, ok := tsm1.CodecByName("unknown"); ok {
+		t.Fatal("expected unknown codec not to be found")
+	}
+
+	if got, exp := tsm1.CodecNames(), []string{tsm1.DefaultCodec, tsm1.FlateCodec, tsm1.FloatShuffleCodec, tsm1.ZstdCodec}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected codec names: got %v, exp %v", got, exp)
+	}
+}
+
+func TestRegisterCodec_Duplicate(t *testing.T) {
+	id, _ := tsm1.CodecByName(tsm1.FlateCodec)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected duplicate codec ID to panic")
+		}
+	}()
+	tsm1.RegisterCodec(id, "other", nil)
+}
+
+func TestEngine_SetBlockCodecs(t *testing.T) {
+	const org, bucketA, bucketB = 0x1000, 0x2000, 0x3000
+
+	e := MustOpenEngine(t)
+	defer e.Close()
+
+	flate, _ := tsm1.CodecByName(tsm1.FlateCodec)
+	var codecs tsm1.BlockCodecs
+	codecs[tsm1.BlockFloat64] = flate
+	codecs[tsm1.BlockString] = flate
+	e.SetBlockCodecs(bucketName(org, bucketA), codecs)
+	if got := e.BucketBlockCodecs(bucketName(org, bucketA)); got != codecs {
+		t.Fatalf("unexpected codecs: got %v, exp %v", got, codecs)
+	}
+
+	msg := strings.Repeat("a repetitive log message ", 10)
+	for _, bucket := range []influxdb.ID{bucketA, bucketB} {
+		e.MustWritePointsString(org, bucket, `
+cpu,host=A value=1.5 1
+cpu,host=A value=2.5 2
+cpu,host=A count=3i 1
+log,host=A msg="`+msg+`" 1
+log,host=A msg="other" 2
+`)
+	}
+	e.MustWriteSnapshot()
+
+	var blocks int
+	for _, f := range e.FileStore.Files() {
+		itr := f.(*tsm1.TSMReader).BlockIterator()
+		for itr.Next() {
+			key, _, _, typ, _, block, err := itr.Read()
+			if err != nil {
+				t.Fatal(err)
+			}
+			blocks++
+
+			exp := byte(0)
+			if bytes.HasPrefix(key, bucketName(org, bucketA)) {
+				exp = codecs.For(typ)
+			}
+			if got := tsm1.BlockCodec(block); got != exp {
+				t.Fatalf("unexpected codec of %s block of %q: got %d, exp %d", tsm1.BlockTypeName(typ), key, got, exp)
+			}
+			if got, err := tsm1.BlockType(block); err != nil || got != typ {
+				t.Fatalf("unexpected block type: got %d, exp %d: %v", got, typ, err)
+			}
+
+			// Blocks of all codecs decode to the values written.
+			values, err := tsm1.DecodeBlock(block, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(values) == 0 {
+				t.Fatalf("no values decoded from block of %q", key)
+			}
+			if typ == tsm1.BlockString && values[0].Value() != msg {
+				t.Fatalf("unexpected string value: got %q, exp %q", values[0].Value(), msg)
+			}
+		}
+	}
+	if got, exp := blocks, 6; got != exp {
+		t.Fatalf("unexpected number of blocks: got %d, exp %d", got, exp)
+	}
+
+	report := &tsm1.Report{Dir: e.Path(), Compression: true}
+	summary, err := report.Run(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, exp := range map[string]uint64{"flate/float64": 1, "flate/string": 1, "default/float64": 1, "default/string": 1, "default/integer": 2} {
+		if stats := summary.Compression[k]; stats == nil || stats.Blocks != exp {
+			t.Fatalf("unexpected compression stats of %s: %+v", k, stats)
+		}
+	}
+	if flate, def := summary.Compression["flate/string"], summary.Compression["default/string"]; flate.Ratio() <= def.Ratio() {
+		t.Fatalf("expected flate to compress strings better than the default: %.2f <= %.2f", flate.Ratio(), def.Ratio())
+	}
+
+	e.SetBlockCodecs(bucketName(org, bucketA), tsm1.BlockCodecs{})
+	if got := e.BucketBlockCodecs(bucketName(org, bucketA)); got != (tsm1.BlockCodecs{}) {
+		t.Fatalf("unexpected codecs: got %v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package tsm1
+
+import (
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdCodec compresses blocks with Zstandard at the default compression level.
+type zstdCodec struct{}
+
+// The zstd encoder and decoder are safe for concurrent use, and are created on
+// first use as they allocate sizeable buffers.
+var zstdCoders struct {
+	once sync.Once
+	enc  *zstd.Encoder
+	dec  *zstd.Decoder
+	err  error
+}
+
+func zstdInit() error {
+	zstdCoders.once.Do(func() {
+		zstdCoders.enc, zstdCoders.err = zstd.NewWriter(nil)
+		if zstdCoders.err == nil {
+			zstdCoders.dec, zstdCoders.err = zstd.NewReader(nil)
+		}
+	})
+	return zstdCoders.err
+}
+
+func (zstdCodec) Encode(dst, src []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdCoders.enc.EncodeAll(src, dst), nil
+}
+
+func (zstdCodec) Decode(dst, src []byte) ([]byte, error) {
+	if err := zstdInit(); err != nil {
+		return nil, err
+	}
+	return zstdCoders.dec.DecodeAll(src, dst)
+}
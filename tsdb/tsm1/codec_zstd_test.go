@@ -0,0 +1,79 @@
+package tsm1
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestZstdCodec(t *testing.T) {
+	zstd, _ := CodecByName(ZstdCodec)
+
+	for _, tt := range []struct {
+		name   string
+		values func(i int) Value
+	}{
+		{"float", func(i int) Value { return NewValue(int64(i), float64(i)/3) }},
+		{"integer", func(i int) Value { return NewValue(int64(i), int64(i*i)) }},
+		{"unsigned", func(i int) Value { return NewValue(int64(i), uint64(i)) }},
+		{"boolean", func(i int) Value { return NewValue(int64(i), i%3 == 0) }},
+		{"string", func(i int) Value {
+			return NewValue(int64(i), fmt.Sprintf("request %d served %s", i, strings.Repeat("ok ", i%5)))
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			values := make([]Value, 1000)
+			for i := range values {
+				values[i] = tt.values(i)
+			}
+			block, err := Values(values).Encode(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			compressed, err := transcodeBlock(block, zstd)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := BlockCodec(compressed); got != zstd {
+				t.Fatalf("unexpected codec: got %d, exp %d", got, zstd)
+			}
+			decoded, err := DecodeBlock(compressed, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, values) {
+				t.Fatal("decoded values differ from those written")
+			}
+
+			// Blocks are decompressed back to the default codec.
+			restored, err := transcodeBlock(compressed, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decoded, err := DecodeBlock(restored, nil); err != nil {
+				t.Fatal(err)
+			} else if !reflect.DeepEqual(decoded, values) {
+				t.Fatal("values decoded from default codec differ from those written")
+			}
+		})
+	}
+
+	// Strings compress better than with snappy.
+	values := make([]Value, 1000)
+	for i := range values {
+		values[i] = NewValue(int64(i), fmt.Sprintf("GET /api/v2/query?orgID=%d 200", i%7))
+	}
+	block, err := Values(values).Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed, err := transcodeBlock(block, zstd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(compressed) >= len(block) {
+		t.Fatalf("compressed to %d bytes, no less than the %d bytes of snappy", len(compressed), len(block))
+	}
+}
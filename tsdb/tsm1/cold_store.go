@@ -0,0 +1,191 @@
+package tsm1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/pkg/s3"
+)
+
+// ColdStore stores the content of TSM files that have been moved to cold
+// storage. Objects are identified by the base name of the TSM file they were
+// created from and are never modified once stored.
+type ColdStore interface {
+	// Put stores size bytes read from r as the named object.
+	Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error
+
+	// ReadAt reads len(p) bytes of the named object, starting at offset off.
+	ReadAt(ctx context.Context, name string, p []byte, off int64) error
+
+	// Delete deletes the named object. Deleting an object that does not
+	// exist is not an error.
+	Delete(ctx context.Context, name string) error
+}
+
+// NewColdStore returns the cold store described by c, or nil if cold storage
+// is not configured.
+func NewColdStore(c ColdStorageConfig) (ColdStore, error) {
+	if c.URL == "" {
+		return nil, nil
+	} else if !strings.Contains(c.URL, "://") {
+		return NewDirColdStore(c.URL), nil
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cold storage URL: %v", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewDirColdStore(u.Path), nil
+	case "s3":
+		region := c.S3Region
+		if region == "" {
+			region = s3.DefaultRegion
+		}
+		endpoint := c.S3Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+		return NewS3ColdStore(s3.NewClient(s3.Config{
+			Endpoint:        endpoint,
+			Region:          region,
+			Bucket:          u.Host,
+			AccessKeyID:     c.S3AccessKeyID,
+			SecretAccessKey: c.S3SecretAccessKey,
+		}), strings.Trim(u.Path, "/")), nil
+	default:
+		return nil, fmt.Errorf("unsupported cold storage scheme %q", u.Scheme)
+	}
+}
+
+// DirColdStore stores objects as files in a directory, such as one on a slower
+// but larger disk.
+type DirColdStore struct {
+	dir string
+}
+
+// NewDirColdStore returns a new DirColdStore storing objects in dir.
+func NewDirColdStore(dir string) *DirColdStore {
+	return &DirColdStore{dir: dir}
+}
+
+// path returns the path of the named object. Names can not refer to files
+// outside of the directory.
+func (s *DirColdStore) path(name string) string {
+	return filepath.Join(s.dir, filepath.Base(name))
+}
+
+// Put writes the named object to a temporary file first and moves it into
+// place once it has been synced.
+func (s *DirColdStore) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	if err := os.MkdirAll(s.dir, 0777); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(s.dir, filepath.Base(name)+".*."+TmpTSMFileExtension)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if n, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	} else if n != size {
+		f.Close()
+		return fmt.Errorf("cold storage: %s: wrote %d bytes, expected %d", name, n, size)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := fs.RenameFile(f.Name(), s.path(name)); err != nil {
+		return err
+	}
+	return fs.SyncDir(s.dir)
+}
+
+// ReadAt reads from the file of the named object.
+func (s *DirColdStore) ReadAt(ctx context.Context, name string, p []byte, off int64) error {
+	f, err := os.Open(s.path(name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.ReadAt(p, off)
+	return err
+}
+
+// Delete removes the file of the named object.
+func (s *DirColdStore) Delete(ctx context.Context, name string) error {
+	if err := os.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// S3ColdStore stores objects in a bucket of an S3-compatible object storage
+// service.
+type S3ColdStore struct {
+	client *s3.Client
+	prefix string
+}
+
+// NewS3ColdStore returns a new S3ColdStore storing objects with the client,
+// with keys starting with prefix.
+func NewS3ColdStore(client *s3.Client, prefix string) *S3ColdStore {
+	return &S3ColdStore{client: client, prefix: prefix}
+}
+
+// key returns the object key of the named object.
+func (s *S3ColdStore) key(name string) string {
+	return strings.TrimPrefix(path.Join("/", s.prefix, path.Base(name)), "/")
+}
+
+// Put uploads the named object. The content is read twice, first to compute
+// its checksum, which the storage service verifies the upload with.
+func (s *S3ColdStore) Put(ctx context.Context, name string, r io.ReadSeeker, size int64) error {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	h := sha256.New()
+	if n, err := io.Copy(h, r); err != nil {
+		return err
+	} else if n != size {
+		return fmt.Errorf("cold storage: %s: read %d bytes, expected %d", name, n, size)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.client.PutObject(ctx, s.key(name), r, size, hex.EncodeToString(h.Sum(nil)))
+}
+
+// ReadAt reads a range of the named object.
+func (s *S3ColdStore) ReadAt(ctx context.Context, name string, p []byte, off int64) error {
+	return s.client.ReadObjectAt(ctx, s.key(name), p, off)
+}
+
+// Delete deletes the named object.
+func (s *S3ColdStore) Delete(ctx context.Context, name string) error {
+	return s.client.DeleteObject(ctx, s.key(name))
+}
@@ -0,0 +1,90 @@
+package tsm1_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/influxdata/influxdb/pkg/s3/s3test"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestDirColdStore(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	store, err := tsm1.NewColdStore(tsm1.ColdStorageConfig{URL: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testColdStore(t, store)
+
+	if _, err := os.Stat(filepath.Join(dir, "000000001-000000001.tsm")); !os.IsNotExist(err) {
+		t.Fatalf("expected object to be removed, got %v", err)
+	}
+}
+
+func TestS3ColdStore(t *testing.T) {
+	server := s3test.NewServer(t, "cold")
+	defer server.Close()
+
+	store, err := tsm1.NewColdStore(tsm1.ColdStorageConfig{
+		URL:               "s3://cold/influxdb/",
+		S3Endpoint:        server.URL,
+		S3AccessKeyID:     s3test.AccessKeyID,
+		S3SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testColdStore(t, store)
+
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Fatalf("unexpected objects %v", keys)
+	}
+}
+
+func TestNewColdStore(t *testing.T) {
+	if store, err := tsm1.NewColdStore(tsm1.ColdStorageConfig{}); err != nil || store != nil {
+		t.Fatalf("got %v, %v, expected no store", store, err)
+	}
+	if _, err := tsm1.NewColdStore(tsm1.ColdStorageConfig{URL: "ftp://host/path"}); err == nil {
+		t.Fatal("expected error for unsupported scheme")
+	}
+}
+
+func testColdStore(t *testing.T, store tsm1.ColdStore) {
+	t.Helper()
+
+	ctx := context.Background()
+	const name = "000000001-000000001.tsm"
+	content := []byte("0123456789")
+
+	if err := store.Put(ctx, name, bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("error storing object: %v", err)
+	}
+
+	p := make([]byte, 4)
+	if err := store.ReadAt(ctx, name, p, 3); err != nil {
+		t.Fatalf("error reading object: %v", err)
+	} else if got, exp := string(p), "3456"; got != exp {
+		t.Fatalf("got %q, expected %q", got, exp)
+	}
+
+	// Content of a different size than announced is not stored.
+	if err := store.Put(ctx, "x.tsm", bytes.NewReader(content), 4); err == nil {
+		t.Fatal("expected size mismatch")
+	}
+
+	if err := store.Delete(ctx, name); err != nil {
+		t.Fatalf("error deleting object: %v", err)
+	}
+	if err := store.Delete(ctx, name); err != nil {
+		t.Fatalf("error deleting missing object: %v", err)
+	}
+	if err := store.ReadAt(ctx, name, p, 0); err == nil {
+		t.Fatal("expected error reading deleted object")
+	}
+}
@@ -42,6 +42,9 @@ const (
 
 	// TSSFileExtension is the extension used for TSM stats files.
 	TSSFileExtension = "tss"
+
+	// TombstoneFileExtension is the extension used for TSM tombstone files.
+	TombstoneFileExtension = "tombstone"
 )
 
 var (
@@ -92,6 +95,14 @@ type CompactionPlanner interface {
 	SetFileStore(fs *FileStore)
 }
 
+// PrefixCompactionPlanner is implemented by compaction planners that can plan
+// compactions of the TSM files holding the data of a single bucket.
+type PrefixCompactionPlanner interface {
+	// PlanPrefix returns the sets of TSM files holding keys with the escaped
+	// prefix to rewrite into a single generation each.
+	PlanPrefix(prefix []byte) []CompactionGroup
+}
+
 // DefaultPlanner implements CompactionPlanner using a strategy to roll up
 // multiple generations of TSM files into larger files in stages.  It attempts
 // to minimize the number of TSM files on disk while rolling up a bounder number
@@ -105,6 +116,11 @@ type DefaultPlanner struct {
 	// should always be greater than the CacheFlushWriteColdDuraion
 	compactFullWriteColdDuration time.Duration
 
+	// PartitionDuration, when set, restricts compactions to files within the
+	// same partition of a bucket and time window of this duration. Files that
+	// do not belong to a single partition are compacted together.
+	PartitionDuration time.Duration
+
 	// lastPlanCheck is the last time Plan was called
 	lastPlanCheck time.Time
 
@@ -194,6 +210,31 @@ func (t *tsmGeneration) hasTombstones() bool {
 	return false
 }
 
+// overlapsPrefix returns true if any file of the generation may hold keys with
+// the escaped bucket prefix.
+func (t *tsmGeneration) overlapsPrefix(prefix []byte) bool {
+	for _, f := range t.files {
+		if bytes.Compare(bucketPrefix(f.MinKey), prefix) <= 0 && bytes.Compare(bucketPrefix(f.MaxKey), prefix) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// partition returns the key of the partition of duration d that all the files in
+// the generation belong to, or an empty key if they do not belong to one.
+func (t *tsmGeneration) partition(d time.Duration) string {
+	var key string
+	for i, f := range t.files {
+		k, ok := filePartition(f, d)
+		if !ok || (i > 0 && k != key) {
+			return ""
+		}
+		key = k
+	}
+	return key
+}
+
 func (c *DefaultPlanner) SetFileStore(fs *FileStore) {
 	c.FileStore = fs
 }
@@ -204,8 +245,12 @@ func (c *DefaultPlanner) ParseFileName(path string) (int, int, error) {
 
 // FullyCompacted returns true if the shard is fully compacted.
 func (c *DefaultPlanner) FullyCompacted() bool {
-	gens := c.findGenerations(false)
-	return len(gens) <= 1 && !gens.hasTombstones()
+	for _, gens := range c.partitions(c.findGenerations(false)) {
+		if len(gens) > 1 || gens.hasTombstones() {
+			return false
+		}
+	}
+	return true
 }
 
 // ForceFull causes the planner to return a full compaction plan the next time
@@ -239,6 +284,145 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 		return nil
 	}
 
+	var cGroups []CompactionGroup
+	for _, generations := range c.partitions(generations) {
+		cGroups = append(cGroups, c.planLevel(generations, level)...)
+	}
+
+	if !c.acquire(cGroups) {
+		return nil
+	}
+
+	return cGroups
+}
+
+// PlanOptimize returns all TSM files if they are in different generations in order
+// to optimize the index across TSM files.  Each returned compaction group can be
+// compacted concurrently.
+func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
+	// If a full plan has been requested, don't plan any levels which will prevent
+	// the full plan from acquiring them.
+	c.mu.RLock()
+	if c.forceFull {
+		c.mu.RUnlock()
+		return nil
+	}
+	c.mu.RUnlock()
+
+	// Determine the generations from all files on disk.  We need to treat
+	// a generation conceptually as a single file even though it may be
+	// split across several files in sequence.
+	generations := c.findGenerations(true)
+
+	// If there is only one generation and no tombstones, then there's nothing to
+	// do.
+	if len(generations) <= 1 && !generations.hasTombstones() {
+		return nil
+	}
+
+	var cGroups []CompactionGroup
+	for _, generations := range c.partitions(generations) {
+		cGroups = append(cGroups, c.planOptimize(generations)...)
+	}
+
+	if !c.acquire(cGroups) {
+		return nil
+	}
+
+	return cGroups
+}
+
+// Plan returns a set of TSM files to rewrite for level 4 or higher.  The planning returns
+// multiple groups if possible to allow compactions to run concurrently.
+func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
+	generations := c.findGenerations(true)
+
+	c.mu.RLock()
+	forceFull := c.forceFull
+	c.mu.RUnlock()
+
+	// first check if we should be doing a full compaction because nothing has been written in a long time
+	if forceFull || c.compactFullWriteColdDuration > 0 && time.Since(lastWrite) > c.compactFullWriteColdDuration && len(generations) > 1 {
+
+		// Reset the full schedule if we planned because of it.
+		if forceFull {
+			c.mu.Lock()
+			c.forceFull = false
+			c.mu.Unlock()
+		}
+
+		var groups []CompactionGroup
+		for _, generations := range c.partitions(generations) {
+			if tsmFiles := c.planFull(generations); tsmFiles != nil {
+				groups = append(groups, tsmFiles)
+			}
+		}
+
+		if len(groups) == 0 || !c.acquire(groups) {
+			return nil
+		}
+		return groups
+	}
+
+	// don't plan if nothing has changed in the filestore
+	if c.lastPlanCheck.After(c.FileStore.LastModified()) && !generations.hasTombstones() {
+		return nil
+	}
+
+	c.lastPlanCheck = time.Now()
+
+	var tsmFiles []CompactionGroup
+	for _, generations := range c.partitions(generations) {
+		tsmFiles = append(tsmFiles, c.plan(generations)...)
+	}
+
+	if len(tsmFiles) == 0 {
+		return nil
+	}
+
+	if !c.acquire(tsmFiles) {
+		return nil
+	}
+	return tsmFiles
+}
+
+// PlanPrefix returns the sets of TSM files holding keys with the escaped
+// prefix, one per partition, to rewrite into a single generation each. Files
+// that are part of an existing compaction plan are not planned.
+func (c *DefaultPlanner) PlanPrefix(prefix []byte) []CompactionGroup {
+	var matched tsmGenerations
+	for _, g := range c.findGenerations(true) {
+		if g.overlapsPrefix(prefix) {
+			matched = append(matched, g)
+		}
+	}
+
+	var groups []CompactionGroup
+	for _, generations := range c.partitions(matched) {
+		// A single generation only needs to be rewritten to remove tombstoned data.
+		if len(generations) <= 1 && !generations.hasTombstones() {
+			continue
+		}
+
+		var group CompactionGroup
+		for _, g := range generations {
+			for _, f := range g.files {
+				group = append(group, f.Path)
+			}
+		}
+		sort.Strings(group)
+		groups = append(groups, group)
+	}
+
+	if len(groups) == 0 || !c.acquire(groups) {
+		return nil
+	}
+	return groups
+}
+
+// planLevel returns the sets of TSM files of the generations to rewrite for a
+// specific level.
+func (c *DefaultPlanner) planLevel(generations tsmGenerations, level int) []CompactionGroup {
 	// Group each generation by level such that two adjacent generations in the same
 	// level become part of the same group.
 	var currentGen tsmGenerations
@@ -304,37 +488,12 @@ func (c *DefaultPlanner) PlanLevel(level int) []CompactionGroup {
 		}
 	}
 
-	if !c.acquire(cGroups) {
-		return nil
-	}
-
 	return cGroups
 }
 
-// PlanOptimize returns all TSM files if they are in different generations in order
-// to optimize the index across TSM files.  Each returned compaction group can be
-// compacted concurrently.
-func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
-	// If a full plan has been requested, don't plan any levels which will prevent
-	// the full plan from acquiring them.
-	c.mu.RLock()
-	if c.forceFull {
-		c.mu.RUnlock()
-		return nil
-	}
-	c.mu.RUnlock()
-
-	// Determine the generations from all files on disk.  We need to treat
-	// a generation conceptually as a single file even though it may be
-	// split across several files in sequence.
-	generations := c.findGenerations(true)
-
-	// If there is only one generation and no tombstones, then there's nothing to
-	// do.
-	if len(generations) <= 1 && !generations.hasTombstones() {
-		return nil
-	}
-
+// planOptimize returns the sets of level 4 TSM files of the generations to
+// rewrite to optimize the index across them.
+func (c *DefaultPlanner) planOptimize(generations tsmGenerations) []CompactionGroup {
 	// Group each generation by level such that two adjacent generations in the same
 	// level become part of the same group.
 	var currentGen tsmGenerations
@@ -396,82 +555,53 @@ func (c *DefaultPlanner) PlanOptimize() []CompactionGroup {
 		cGroups = append(cGroups, cGroup)
 	}
 
-	if !c.acquire(cGroups) {
-		return nil
-	}
-
 	return cGroups
 }
 
-// Plan returns a set of TSM files to rewrite for level 4 or higher.  The planning returns
-// multiple groups if possible to allow compactions to run concurrently.
-func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
-	generations := c.findGenerations(true)
-
-	c.mu.RLock()
-	forceFull := c.forceFull
-	c.mu.RUnlock()
-
-	// first check if we should be doing a full compaction because nothing has been written in a long time
-	if forceFull || c.compactFullWriteColdDuration > 0 && time.Since(lastWrite) > c.compactFullWriteColdDuration && len(generations) > 1 {
+// planFull returns the TSM files of the generations to rewrite in a full
+// compaction, or nil if there is nothing to compact.
+func (c *DefaultPlanner) planFull(generations tsmGenerations) CompactionGroup {
+	var tsmFiles []string
+	var genCount int
+	for i, group := range generations {
+		var skip bool
 
-		// Reset the full schedule if we planned because of it.
-		if forceFull {
-			c.mu.Lock()
-			c.forceFull = false
-			c.mu.Unlock()
+		// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
+		if len(generations) > 2 && group.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
+			skip = true
 		}
 
-		var tsmFiles []string
-		var genCount int
-		for i, group := range generations {
-			var skip bool
-
-			// Skip the file if it's over the max size and contains a full block and it does not have any tombstones
-			if len(generations) > 2 && group.size() > uint64(maxTSMFileSize) && c.FileStore.BlockCount(group.files[0].Path, 1) == MaxPointsPerBlock && !group.hasTombstones() {
-				skip = true
-			}
-
-			// We need to look at the level of the next file because it may need to be combined with this generation
-			// but won't get picked up on it's own if this generation is skipped.  This allows the most recently
-			// created files to get picked up by the full compaction planner and avoids having a few less optimally
-			// compressed files.
-			if i < len(generations)-1 {
-				if generations[i+1].level() <= 3 {
-					skip = false
-				}
-			}
-
-			if skip {
-				continue
-			}
-
-			for _, f := range group.files {
-				tsmFiles = append(tsmFiles, f.Path)
+		// We need to look at the level of the next file because it may need to be combined with this generation
+		// but won't get picked up on it's own if this generation is skipped.  This allows the most recently
+		// created files to get picked up by the full compaction planner and avoids having a few less optimally
+		// compressed files.
+		if i < len(generations)-1 {
+			if generations[i+1].level() <= 3 {
+				skip = false
 			}
-			genCount += 1
 		}
-		sort.Strings(tsmFiles)
 
-		// Make sure we have more than 1 file and more than 1 generation
-		if len(tsmFiles) <= 1 || genCount <= 1 {
-			return nil
+		if skip {
+			continue
 		}
 
-		group := []CompactionGroup{tsmFiles}
-		if !c.acquire(group) {
-			return nil
+		for _, f := range group.files {
+			tsmFiles = append(tsmFiles, f.Path)
 		}
-		return group
+		genCount += 1
 	}
+	sort.Strings(tsmFiles)
 
-	// don't plan if nothing has changed in the filestore
-	if c.lastPlanCheck.After(c.FileStore.LastModified()) && !generations.hasTombstones() {
+	// Make sure we have more than 1 file and more than 1 generation
+	if len(tsmFiles) <= 1 || genCount <= 1 {
 		return nil
 	}
+	return tsmFiles
+}
 
-	c.lastPlanCheck = time.Now()
-
+// plan returns the sets of level 4 or higher TSM files of the generations to
+// rewrite.
+func (c *DefaultPlanner) plan(generations tsmGenerations) []CompactionGroup {
 	// If there is only one generation, return early to avoid re-compacting the same file
 	// over and over again.
 	if len(generations) <= 1 && !generations.hasTombstones() {
@@ -595,12 +725,31 @@ func (c *DefaultPlanner) Plan(lastWrite time.Time) []CompactionGroup {
 		tsmFiles = append(tsmFiles, cGroup)
 	}
 
-	if !c.acquire(tsmFiles) {
-		return nil
-	}
 	return tsmFiles
 }
 
+// partitions groups the generations by partition, keeping them in order. All
+// generations are returned in a single group if partitioning is disabled.
+func (c *DefaultPlanner) partitions(generations tsmGenerations) []tsmGenerations {
+	if c.PartitionDuration <= 0 {
+		return []tsmGenerations{generations}
+	}
+
+	var groups []tsmGenerations
+	index := make(map[string]int)
+	for _, gen := range generations {
+		key := gen.partition(c.PartitionDuration)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], gen)
+	}
+	return groups
+}
+
 // findGenerations groups all the TSM files by generation based
 // on their filename, then returns the generations in descending order (newest first).
 // If skipInUse is true, tsm files that are part of an existing compaction plan
@@ -698,6 +847,19 @@ type Compactor struct {
 	// RateLimit is the limit for disk writes for all concurrent compactions.
 	RateLimit limiter.Rate
 
+	// PartitionDuration, when set, causes snapshots to write the data of each
+	// bucket and time window of this duration to separate TSM files.
+	PartitionDuration time.Duration
+
+	// BlockCodecs, when set, returns the codecs the blocks of the bucket with
+	// the escaped name are compressed with. Blocks compressed with other codecs
+	// are recompressed when written.
+	BlockCodecs func(name []byte) BlockCodecs
+
+	// BlockStats, when set, causes the statistics of float, integer and unsigned
+	// blocks to be stored in the files written.
+	BlockStats bool
+
 	formatFileName FormatFileNameFunc
 	parseFileName  ParseFileNameFunc
 
@@ -842,16 +1004,25 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 		throttle = false
 	}
 
-	splits := cache.Split(concurrency)
+	var splits []*Cache
+	if c.PartitionDuration > 0 {
+		splits = cache.SplitPartitions(c.PartitionDuration)
+	} else {
+		splits = cache.Split(concurrency)
+	}
 
 	type res struct {
 		files []string
 		err   error
 	}
 
-	resC := make(chan res, concurrency)
-	for i := 0; i < concurrency; i++ {
+	resC := make(chan res, len(splits))
+	limit := make(chan struct{}, concurrency)
+	for i := range splits {
 		go func(sp *Cache) {
+			limit <- struct{}{}
+			defer func() { <-limit }()
+
 			iter := NewCacheKeyIterator(sp, MaxPointsPerBlock, intC)
 			files, err := c.writeNewFiles(c.FileStore.NextGeneration(), 0, nil, iter, throttle)
 			resC <- res{files: files, err: err}
@@ -860,8 +1031,8 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 	}
 
 	var err error
-	files := make([]string, 0, concurrency)
-	for i := 0; i < concurrency; i++ {
+	files := make([]string, 0, len(splits))
+	for range splits {
 		result := <-resC
 		if result.err != nil {
 			err = result.err
@@ -887,7 +1058,7 @@ func (c *Compactor) WriteSnapshot(ctx context.Context, cache *Cache) ([]string,
 }
 
 // compact writes multiple smaller TSM files into 1 or more larger files.
-func (c *Compactor) compact(fast bool, tsmFiles []string) ([]string, error) {
+func (c *Compactor) compact(fast bool, tsmFiles []string, job *compactionJob) ([]string, error) {
 	size := c.Size
 	if size <= 0 {
 		size = MaxPointsPerBlock
@@ -897,6 +1068,12 @@ func (c *Compactor) compact(fast bool, tsmFiles []string) ([]string, error) {
 	intC := c.compactionsInterrupt
 	c.mu.RUnlock()
 
+	if job != nil {
+		var stop func()
+		intC, stop = job.interrupt(intC)
+		defer stop()
+	}
+
 	// The new compacted files need to added to the max generation in the
 	// set.  We need to find that max generation as well as the max sequence
 	// number to ensure we write to the next unique location.
@@ -941,48 +1118,33 @@ func (c *Compactor) compact(fast bool, tsmFiles []string) ([]string, error) {
 		return nil, nil
 	}
 
+	var tsm KeyIterator
 	tsm, err := NewTSMBatchKeyIterator(size, fast, intC, trs...)
 	if err != nil {
 		return nil, err
 	}
 
+	if job != nil {
+		tsm = job.track(tsm, trs)
+	}
+
 	return c.writeNewFiles(maxGeneration, maxSequence, tsmFiles, tsm, true)
 }
 
 // CompactFull writes multiple smaller TSM files into 1 or more larger files.
 func (c *Compactor) CompactFull(tsmFiles []string) ([]string, error) {
-	c.mu.RLock()
-	enabled := c.compactionsEnabled
-	c.mu.RUnlock()
-
-	if !enabled {
-		return nil, errCompactionsDisabled
-	}
-
-	if !c.add(tsmFiles) {
-		return nil, errCompactionInProgress{}
-	}
-	defer c.remove(tsmFiles)
-
-	files, err := c.compact(false, tsmFiles)
-
-	// See if we were disabled while writing a snapshot
-	c.mu.RLock()
-	enabled = c.compactionsEnabled
-	c.mu.RUnlock()
-
-	if !enabled {
-		if err := c.removeTmpFiles(files); err != nil {
-			return nil, err
-		}
-		return nil, errCompactionsDisabled
-	}
-
-	return files, err
+	return c.compactGroup(false, tsmFiles, nil)
 }
 
 // CompactFast writes multiple smaller TSM files into 1 or more larger files.
 func (c *Compactor) CompactFast(tsmFiles []string) ([]string, error) {
+	return c.compactGroup(true, tsmFiles, nil)
+}
+
+// compactGroup writes multiple smaller TSM files into 1 or more larger files.
+// If job is not nil, the compaction reports its progress to the job and is
+// aborted when the job is cancelled.
+func (c *Compactor) compactGroup(fast bool, tsmFiles []string, job *compactionJob) ([]string, error) {
 	c.mu.RLock()
 	enabled := c.compactionsEnabled
 	c.mu.RUnlock()
@@ -996,7 +1158,7 @@ func (c *Compactor) CompactFast(tsmFiles []string) ([]string, error) {
 	}
 	defer c.remove(tsmFiles)
 
-	files, err := c.compact(true, tsmFiles)
+	files, err := c.compact(fast, tsmFiles, job)
 
 	// See if we were disabled while writing a snapshot
 	c.mu.RLock()
@@ -1011,7 +1173,6 @@ func (c *Compactor) CompactFast(tsmFiles []string) ([]string, error) {
 	}
 
 	return files, err
-
 }
 
 // removeTmpFiles is responsible for cleaning up a compaction that
@@ -1111,12 +1272,12 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 	// Use a disk based TSM buffer if it looks like we might create a big index
 	// in memory.
 	if iter.EstimatedIndexSize() > 64*1024*1024 {
-		w, err = NewTSMWriterWithDiskBuffer(limitWriter)
+		w, err = NewTSMWriterWithDiskBuffer(limitWriter, WithBlockStats(c.BlockStats))
 		if err != nil {
 			return err
 		}
 	} else {
-		w, err = NewTSMWriter(limitWriter)
+		w, err = NewTSMWriter(limitWriter, WithBlockStats(c.BlockStats))
 		if err != nil {
 			return err
 		}
@@ -1141,6 +1302,12 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 		}
 	}()
 
+	// The codecs of the bucket of the last block written.
+	var (
+		prefix []byte
+		codecs BlockCodecs
+	)
+
 	for iter.Next() {
 		c.mu.RLock()
 		enabled := c.snapshotsEnabled || c.compactionsEnabled
@@ -1161,6 +1328,20 @@ func (c *Compactor) write(path string, iter KeyIterator, throttle bool) (err err
 			return fmt.Errorf("invalid index entry for block. min=%d, max=%d", minTime, maxTime)
 		}
 
+		if c.BlockCodecs != nil {
+			if p := bucketPrefix(key); !bytes.Equal(p, prefix) {
+				prefix, codecs = append(prefix[:0], p...), c.BlockCodecs(p)
+			}
+
+			typ, err := BlockType(block)
+			if err != nil {
+				return err
+			}
+			if block, err = transcodeBlock(block, codecs.For(typ)); err != nil {
+				return err
+			}
+		}
+
 		// Write the key and value
 		if err := w.WriteBlock(key, minTime, maxTime, block); err == ErrMaxBlocksExceeded {
 			if err := w.WriteIndex(); err != nil {
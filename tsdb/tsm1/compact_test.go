@@ -2364,6 +2364,50 @@ func TestDefaultPlanner_PlanOptimize_Optimized(t *testing.T) {
 	}
 }
 
+// Ensure that files in cold storage are compacted to remove their tombstones.
+func TestDefaultPlanner_PlanLevel_ColdTombstone(t *testing.T) {
+	data := []tsm1.FileStat{
+		{
+			Path:         "01-03.tsm1",
+			Size:         251 * 1024 * 1024,
+			HasTombstone: true,
+			Cold:         true,
+		},
+		{
+			Path: "02-03.tsm1",
+			Size: 1 * 1024 * 1024,
+			Cold: true,
+		},
+		{
+			Path: "03-01.tsm1",
+			Size: 2 * 1024 * 1024 * 1024,
+		},
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+
+	expFiles := []tsm1.FileStat{data[0], data[1]}
+	tsm := cp.PlanLevel(3)
+	if exp, got := 1, len(tsm); got != exp {
+		t.Fatalf("group length mismatch: got %v, exp %v", got, exp)
+	}
+	if exp, got := len(expFiles), len(tsm[0]); got != exp {
+		t.Fatalf("tsm file length mismatch: got %v, exp %v", got, exp)
+	}
+
+	for i, p := range expFiles {
+		if got, exp := tsm[0][i], p.Path; got != exp {
+			t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+		}
+	}
+}
+
 func TestDefaultPlanner_PlanOptimize_Tombstones(t *testing.T) {
 	data := []tsm1.FileStat{
 		{
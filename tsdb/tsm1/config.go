@@ -33,8 +33,21 @@ type Config struct {
 	// preallocation to improve throughput. Currently used in the series file.
 	LargeSeriesWriteThreshold int `toml:"large-series-write-threshold"`
 
-	Compaction CompactionConfig `toml:"compaction"`
-	Cache      CacheConfig      `toml:"cache"`
+	// PartitionDuration, when set, partitions TSM files by bucket and by time
+	// windows of this duration, so that data falling out of a bucket's retention
+	// period can be removed by deleting whole files. Compactions only merge
+	// files within the same partition. A value of 0 disables partitioning.
+	PartitionDuration toml.Duration `toml:"partition-duration"`
+
+	// BlockStats, when enabled, stores the count, sum, minimum and maximum of
+	// the values of float, integer and unsigned blocks in the TSM files written
+	// by snapshots and compactions, so that sum and count aggregates can be
+	// computed without decoding the blocks.
+	BlockStats bool `toml:"block-stats"`
+
+	Compaction  CompactionConfig  `toml:"compaction"`
+	Cache       CacheConfig       `toml:"cache"`
+	ColdStorage ColdStorageConfig `toml:"cold-storage"`
 }
 
 // NewConfig constructs a Config with the default values.
@@ -44,7 +57,8 @@ func NewConfig() Config {
 		MADVWillNeed:              DefaultMADVWillNeed,
 		LargeSeriesWriteThreshold: DefaultLargeSeriesWriteThreshold,
 
-		Cache: NewCacheConfig(),
+		Cache:       NewCacheConfig(),
+		ColdStorage: NewColdStorageConfig(),
 		Compaction: CompactionConfig{
 			FullWriteColdDuration: toml.Duration(DefaultCompactFullWriteColdDuration),
 			Throughput:            toml.Size(DefaultCompactThroughput),
@@ -96,9 +110,14 @@ const (
 // are waiting to be snapshot.
 type CacheConfig struct {
 	// MaxMemorySize is the maximum size a shard's cache can reach before it starts
-	// rejecting writes.
+	// rejecting writes, or spilling to disk if SpillToDisk is set.
 	MaxMemorySize toml.Size `toml:"max-memory-size"`
 
+	// SpillToDisk makes the cache spill its least recently written values to
+	// temporary TSM files once it reaches MaxMemorySize, rather than rejecting
+	// writes. Spilled values remain readable until the cache is snapshotted.
+	SpillToDisk bool `toml:"spill-to-disk"`
+
 	// SnapshotMemorySize is the size at which the engine will snapshot the cache and
 	// write it to a TSM file, freeing up memory
 	SnapshotMemorySize toml.Size `toml:"snapshot-memory-size"`
@@ -125,6 +144,37 @@ func NewCacheConfig() CacheConfig {
 	}
 }
 
+// DefaultColdStorageBlockCacheSize is the default size of the cache of blocks
+// read from cold storage.
+const DefaultColdStorageBlockCacheSize = toml.Size(64 << 20) // 64MB
+
+// ColdStorageConfig holds the configuration of the tier TSM files are moved to
+// once all of their data is older than the cold storage period of their bucket.
+type ColdStorageConfig struct {
+	// URL locates the cold store. It is either a directory, optionally given
+	// as a file:// URL, or an s3://bucket/prefix URL for S3-compatible storage.
+	// Cold storage is disabled if it is empty.
+	URL string `toml:"url"`
+
+	// S3Endpoint is the base URL of the S3-compatible storage service. It
+	// defaults to the AWS endpoint of S3Region.
+	S3Endpoint        string `toml:"s3-endpoint"`
+	S3Region          string `toml:"s3-region"`
+	S3AccessKeyID     string `toml:"s3-access-key-id"`
+	S3SecretAccessKey string `toml:"s3-secret-access-key"`
+
+	// BlockCacheSize is the maximum size of the blocks read from cold storage
+	// that are kept in memory.
+	BlockCacheSize toml.Size `toml:"block-cache-size"`
+}
+
+// NewColdStorageConfig initialises a new ColdStorageConfig with default values.
+func NewColdStorageConfig() ColdStorageConfig {
+	return ColdStorageConfig{
+		BlockCacheSize: DefaultColdStorageBlockCacheSize,
+	}
+}
+
 // Default WAL configuration values.
 const (
 	DefaultWALEnabled    = true
@@ -141,6 +191,10 @@ type WALConfig struct {
 	// useful for slower disks or when WAL write contention is seen.  A value of 0 fsyncs
 	// every write to the WAL.
 	FsyncDelay toml.Duration `toml:"fsync-delay"`
+
+	// ArchivePath is the directory WAL segments are copied to before they are
+	// removed, for point-in-time recovery. Segments are not archived if it is empty.
+	ArchivePath string `toml:"archive-path"`
 }
 
 func NewWALConfig() WALConfig {
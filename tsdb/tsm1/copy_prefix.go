@@ -0,0 +1,85 @@
+package tsm1
+
+import (
+	"bytes"
+)
+
+// CopyPrefix writes the blocks of every key in r that begins with prefix to w.
+// Values covered by tombstones in r are left out. Blocks without deleted values
+// are copied as they are; others are decoded, filtered and encoded again.
+//
+// If rename is not nil, each key is written as rename(key) instead, or skipped
+// if rename returns nil. rename must preserve the order of the keys. CopyPrefix
+// returns the number of keys written.
+func CopyPrefix(w TSMWriter, r *TSMReader, prefix []byte, rename func(key []byte) []byte) (int, error) {
+	var (
+		n          int
+		tombstones []TimeRange
+		values     []Value
+	)
+
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+
+		dst := key
+		if rename != nil {
+			if dst = rename(key); dst == nil {
+				continue
+			}
+		}
+
+		tombstones = r.TombstoneRange(key, tombstones[:0])
+
+		var written bool
+		for _, entry := range iter.Entries() {
+			entry := entry
+
+			if !overlapsAny(tombstones, entry.MinTime, entry.MaxTime) {
+				_, block, err := r.ReadBytes(&entry, nil)
+				if err != nil {
+					return n, err
+				}
+				if err := w.WriteBlock(dst, entry.MinTime, entry.MaxTime, block); err != nil {
+					return n, err
+				}
+				written = true
+				continue
+			}
+
+			var err error
+			values, err = r.ReadAt(&entry, values[:0])
+			if err != nil {
+				return n, err
+			}
+			v := Values(values)
+			for _, ts := range tombstones {
+				v = v.Exclude(ts.Min, ts.Max)
+			}
+			if len(v) == 0 {
+				continue
+			}
+			if err := w.Write(dst, v); err != nil {
+				return n, err
+			}
+			written = true
+		}
+
+		if written {
+			n++
+		}
+	}
+	return n, iter.Err()
+}
+
+func overlapsAny(ranges []TimeRange, min, max int64) bool {
+	for _, tr := range ranges {
+		if tr.Overlaps(min, max) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package tsm1
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCopyPrefix(t *testing.T) {
+	dir := mustTempDir()
+	defer os.RemoveAll(dir)
+
+	f := mustTempFile(dir)
+	w, err := NewTSMWriter(f)
+	fatalIfErr(t, "creating writer", err)
+
+	values := []Value{NewValue(1, 1.0), NewValue(2, 2.0), NewValue(3, 3.0)}
+	for _, key := range []string{"a#!~#v", "b,host=x#!~#v", "b,host=y#!~#v", "c#!~#v"} {
+		fatalIfErr(t, "writing", w.Write([]byte(key), values))
+	}
+	fatalIfErr(t, "writing index", w.WriteIndex())
+	fatalIfErr(t, "closing", w.Close())
+
+	f, err = os.Open(f.Name())
+	fatalIfErr(t, "opening", err)
+	r, err := NewTSMReader(f)
+	fatalIfErr(t, "creating reader", err)
+	defer r.Close()
+
+	fatalIfErr(t, "deleting", r.DeleteRange([][]byte{[]byte("b,host=x#!~#v")}, 2, 2))
+	fatalIfErr(t, "deleting", r.Delete([][]byte{[]byte("b,host=y#!~#v")}))
+
+	f = mustTempFile(dir)
+	w, err = NewTSMWriter(f)
+	fatalIfErr(t, "creating writer", err)
+
+	n, err := CopyPrefix(w, r, []byte("b"), func(key []byte) []byte {
+		return append([]byte("z"), key[1:]...)
+	})
+	fatalIfErr(t, "copying", err)
+	if got, exp := n, 1; got != exp {
+		t.Fatalf("got %d keys, exp %d", got, exp)
+	}
+	fatalIfErr(t, "writing index", w.WriteIndex())
+	fatalIfErr(t, "closing", w.Close())
+
+	f, err = os.Open(f.Name())
+	fatalIfErr(t, "opening", err)
+	copied, err := NewTSMReader(f)
+	fatalIfErr(t, "creating reader", err)
+	defer copied.Close()
+
+	var keys []string
+	iter := copied.Iterator(nil)
+	for iter.Next() {
+		keys = append(keys, string(iter.Key()))
+	}
+	fatalIfErr(t, "iterating", iter.Err())
+	if exp := []string{"z,host=x#!~#v"}; !reflect.DeepEqual(keys, exp) {
+		t.Fatalf("got keys %v, exp %v", keys, exp)
+	}
+
+	got, err := copied.ReadAll([]byte("z,host=x#!~#v"))
+	fatalIfErr(t, "reading", err)
+	if exp := []Value{values[0], values[2]}; fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Fatalf("got values %v, exp %v", got, exp)
+	}
+}
@@ -162,7 +162,7 @@ func (a Values) InfluxQLType() (influxql.DataType, error) {
 // BlockType returns the type of value encoded in a block or an error
 // if the block type is unknown.
 func BlockType(block []byte) (byte, error) {
-	blockType := block[0]
+	blockType := block[0] & 0x0f
 	switch blockType {
 	case BlockFloat64, BlockInteger, BlockUnsigned, BlockBoolean, BlockString:
 		return blockType, nil
@@ -309,6 +309,11 @@ func encodeFloatBlockUsing(buf []byte, values []Value, tsenc TimeEncoder, venc *
 // DecodeFloatBlock decodes the float block from the byte slice
 // and appends the float values to a.
 func DecodeFloatBlock(block []byte, a *[]FloatValue) ([]FloatValue, error) {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return nil, err
+	}
+
 	// Block type is the next block, make sure we actually have a float block
 	blockType := block[0]
 	if blockType != BlockFloat64 {
@@ -414,6 +419,11 @@ func encodeBooleanBlockUsing(buf []byte, values []Value, tenc TimeEncoder, venc
 // DecodeBooleanBlock decodes the boolean block from the byte slice
 // and appends the boolean values to a.
 func DecodeBooleanBlock(block []byte, a *[]BooleanValue) ([]BooleanValue, error) {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return nil, err
+	}
+
 	// Block type is the next block, make sure we actually have a float block
 	blockType := block[0]
 	if blockType != BlockBoolean {
@@ -506,6 +516,11 @@ func encodeIntegerBlockUsing(buf []byte, values []Value, tenc TimeEncoder, venc
 // DecodeIntegerBlock decodes the integer block from the byte slice
 // and appends the integer values to a.
 func DecodeIntegerBlock(block []byte, a *[]IntegerValue) ([]IntegerValue, error) {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return nil, err
+	}
+
 	blockType := block[0]
 	if blockType != BlockInteger {
 		return nil, fmt.Errorf("invalid block type: exp %d, got %d", BlockInteger, blockType)
@@ -599,6 +614,11 @@ func encodeUnsignedBlockUsing(buf []byte, values []Value, tenc TimeEncoder, venc
 // DecodeUnsignedBlock decodes the unsigned integer block from the byte slice
 // and appends the unsigned integer values to a.
 func DecodeUnsignedBlock(block []byte, a *[]UnsignedValue) ([]UnsignedValue, error) {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return nil, err
+	}
+
 	blockType := block[0]
 	if blockType != BlockUnsigned {
 		return nil, fmt.Errorf("invalid block type: exp %d, got %d", BlockUnsigned, blockType)
@@ -692,6 +712,11 @@ func encodeStringBlockUsing(buf []byte, values []Value, tenc TimeEncoder, venc S
 // DecodeStringBlock decodes the string block from the byte slice
 // and appends the string values to a.
 func DecodeStringBlock(block []byte, a *[]StringValue) ([]StringValue, error) {
+	block, err := decodeBlockCodec(block)
+	if err != nil {
+		return nil, err
+	}
+
 	blockType := block[0]
 	if blockType != BlockString {
 		return nil, fmt.Errorf("invalid block type: exp %d, got %d", BlockString, blockType)
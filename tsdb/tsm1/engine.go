@@ -168,6 +168,20 @@ type Engine struct {
 
 	scheduler   *scheduler
 	snapshotter Snapshotter
+
+	coldStorage ColdStorageConfig
+	coldMu      sync.Mutex // Serializes moves to cold storage with deletes.
+
+	compactionsMu      sync.Mutex
+	compactionJobs     map[int]*compactionJob // Running compactions by ID.
+	lastCompactionID   int
+	plannedCompactions []plannedCompaction // Compactions planned but not started by the last planning round.
+	queuedCompactions  []plannedCompaction // Compactions requested with CompactPrefix.
+	compactionsPaused  bool
+	pauseMu            sync.Mutex // Serializes pausing and resuming compactions.
+
+	codecsMu    sync.RWMutex
+	blockCodecs map[string]BlockCodecs // Codecs of buckets by escaped name.
 }
 
 // NewEngine returns a new instance of Engine.
@@ -177,6 +191,11 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 	fs.tsmMMAPWillNeed = config.MADVWillNeed
 
 	cache := NewCache(uint64(config.Cache.MaxMemorySize))
+	if config.Cache.SpillToDisk {
+		// The directory is removed by cleanup when the engine is opened, as
+		// spilled values are reloaded from the WAL.
+		cache.SetSpillDir(filepath.Join(path, "cache."+TmpTSMFileExtension))
+	}
 
 	c := NewCompactor()
 	c.Dir = path
@@ -184,6 +203,11 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 	c.RateLimit = limiter.NewRate(
 		int(config.Compaction.Throughput),
 		int(config.Compaction.ThroughputBurst))
+	c.PartitionDuration = time.Duration(config.PartitionDuration)
+	c.BlockStats = config.BlockStats
+
+	planner := NewDefaultPlanner(fs, time.Duration(config.Compaction.FullWriteColdDuration))
+	planner.PartitionDuration = time.Duration(config.PartitionDuration)
 
 	// determine max concurrent compactions informed by the system
 	maxCompactions := config.Compaction.MaxConcurrent
@@ -214,10 +238,9 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 
 		Cache: cache,
 
-		FileStore: fs,
-		Compactor: c,
-		CompactionPlan: NewDefaultPlanner(fs,
-			time.Duration(config.Compaction.FullWriteColdDuration)),
+		FileStore:      fs,
+		Compactor:      c,
+		CompactionPlan: planner,
 
 		CacheFlushMemorySizeThreshold:  uint64(config.Cache.SnapshotMemorySize),
 		CacheFlushWriteColdDuration:    time.Duration(config.Cache.SnapshotWriteColdDuration),
@@ -228,7 +251,11 @@ func NewEngine(path string, idx *tsi1.Index, config Config, options ...EngineOpt
 		fullCompactionSemaphore:        influxdb.NopSemaphore,
 		scheduler:                      newScheduler(maxCompactions),
 		snapshotter:                    new(noSnapshotter),
+		coldStorage:                    config.ColdStorage,
+		compactionJobs:                 make(map[int]*compactionJob),
+		blockCodecs:                    make(map[string]BlockCodecs),
 	}
+	c.BlockCodecs = e.BucketBlockCodecs
 
 	for _, option := range options {
 		option(e)
@@ -549,6 +576,13 @@ func (e *Engine) Open(ctx context.Context) (err error) {
 		return err
 	}
 
+	store, err := NewColdStore(e.coldStorage)
+	if err != nil {
+		return err
+	} else if store != nil {
+		e.FileStore.WithColdStore(store, NewBlockCache(int64(e.coldStorage.BlockCacheSize)))
+	}
+
 	if err := e.FileStore.Open(ctx); err != nil {
 		return err
 	}
@@ -577,6 +611,10 @@ func (e *Engine) Close() error {
 		return err
 	}
 
+	if err := e.Cache.removeSpills(); err != nil {
+		return err
+	}
+
 	// Release our references.
 	if e.sfileref != nil {
 		e.sfileref.Release()
@@ -601,7 +639,7 @@ func (e *Engine) WithLogger(log *zap.Logger) {
 // IsIdle returns true if the cache is empty, there are no running compactions and the
 // shard is fully compacted.
 func (e *Engine) IsIdle() bool {
-	cacheEmpty := e.Cache.Size() == 0
+	cacheEmpty := e.Cache.Size() == 0 && !e.Cache.spilled()
 	return cacheEmpty && e.compactionTracker.AllActive() == 0 && e.CompactionPlan.FullyCompacted()
 }
 
@@ -843,7 +881,7 @@ func (e *Engine) writeSnapshot(ctx context.Context) error {
 		return err
 	}
 
-	if snapshot.Size() == 0 {
+	if snapshot.Size() == 0 && len(snapshot.spills) == 0 {
 		e.Cache.ClearSnapshot(true)
 		return nil
 	}
@@ -939,11 +977,16 @@ const (
 // ShouldCompactCache returns a status indicating if the Cache should be
 // snapshotted. There are three situations when the cache should be snapshotted:
 //
-// - the Cache size is over its flush size threshold;
+// - the Cache size is over its flush size threshold, or values of the Cache have
+//   been spilled to disk;
 // - the Cache has not been snapshotted for longer than its flush time threshold; or
 // - the Cache has not been written since the write cold threshold.
 //
 func (e *Engine) ShouldCompactCache(t time.Time) CacheStatus {
+	if e.Cache.spilled() {
+		return CacheStatusSizeExceeded
+	}
+
 	sz := e.Cache.Size()
 	if sz == 0 {
 		return 0
@@ -988,12 +1031,16 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 
 		select {
 		case <-quit:
+			e.setPlannedCompactions(nil)
 			return
 
 		case <-t.C:
 
 			span, ctx := tracing.StartSpanFromContext(context.Background())
 
+			// Start the compactions requested with CompactPrefix first
+			e.startQueuedCompactions(ctx, wg)
+
 			// Find our compaction plans
 			level1Groups := e.CompactionPlan.PlanLevel(1)
 			level2Groups := e.CompactionPlan.PlanLevel(2)
@@ -1036,12 +1083,24 @@ func (e *Engine) compact(wg *sync.WaitGroup) {
 						level3Groups = level3Groups[1:]
 					}
 				case 4:
-					if e.compactFull(ctx, level4Groups[0], wg) {
+					if e.compactFull(ctx, level4Groups[0], false, wg) {
 						level4Groups = level4Groups[1:]
 					}
 				}
 			}
 
+			// Record the plans we didn't start so they can be listed.
+			var planned []plannedCompaction
+			for i, groups := range [][]CompactionGroup{level1Groups, level2Groups, level3Groups} {
+				for _, group := range groups {
+					planned = append(planned, plannedCompaction{level: compactionLevel(i + 1), group: group})
+				}
+			}
+			for _, group := range level4Groups {
+				planned = append(planned, plannedCompaction{level: 5, group: group})
+			}
+			e.setPlannedCompactions(planned)
+
 			// Release all the plans we didn't start.
 			e.CompactionPlan.Release(level1Groups)
 			e.CompactionPlan.Release(level2Groups)
@@ -1110,8 +1169,8 @@ func (e *Engine) compactLoPriorityLevel(ctx context.Context, grp CompactionGroup
 
 // compactFull kicks off full and optimize compactions using the lo priority policy. It returns
 // the plans that were not able to be started.
-func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, wg *sync.WaitGroup) bool {
-	s := e.fullCompactionStrategy(grp, false)
+func (e *Engine) compactFull(ctx context.Context, grp CompactionGroup, optimize bool, wg *sync.WaitGroup) bool {
+	s := e.fullCompactionStrategy(grp, optimize)
 	if s == nil {
 		return false
 	}
@@ -1221,6 +1280,10 @@ func (s *compactionStrategy) compactGroup(ctx context.Context) {
 	log, logEnd := logger.NewOperation(ctx, s.logger, "TSM compaction", "tsm1_compact_group")
 	defer logEnd()
 
+	job := s.engine.startCompactionJob(s.level, group)
+	defer s.engine.finishCompactionJob(job)
+	log = log.With(zap.Int("tsm1_compaction_id", job.id))
+
 	log.Info("Beginning compaction", zap.Int("tsm1_files_n", len(group)))
 	span.LogKV("file qty", len(group), "fast", s.fast)
 	for i, f := range group {
@@ -1233,14 +1296,15 @@ func (s *compactionStrategy) compactGroup(ctx context.Context) {
 		files []string
 	)
 
-	if s.fast {
-		files, err = s.compactor.CompactFast(group)
-	} else {
-		files, err = s.compactor.CompactFull(group)
-	}
+	files, err = s.compactor.compactGroup(s.fast, group, job)
 
 	if err != nil {
 		tracing.LogError(span, err)
+		if job.cancelled() {
+			log.Info("Cancelled compaction", zap.Error(err))
+			return
+		}
+
 		_, inProgress := err.(errCompactionInProgress)
 		if err == errCompactionsDisabled || inProgress {
 			log.Info("Aborted compaction", zap.Error(err))
@@ -0,0 +1,23 @@
+package tsm1
+
+// SetBlockCodecs sets the codecs the blocks of the bucket with the escaped
+// name are compressed with when written to TSM files. Blocks already written
+// are recompressed when their files are next compacted.
+func (e *Engine) SetBlockCodecs(name []byte, codecs BlockCodecs) {
+	e.codecsMu.Lock()
+	defer e.codecsMu.Unlock()
+
+	if codecs == (BlockCodecs{}) {
+		delete(e.blockCodecs, string(name))
+		return
+	}
+	e.blockCodecs[string(name)] = codecs
+}
+
+// BucketBlockCodecs returns the codecs the blocks of the bucket with the
+// escaped name are compressed with.
+func (e *Engine) BucketBlockCodecs(name []byte) BlockCodecs {
+	e.codecsMu.RLock()
+	defer e.codecsMu.RUnlock()
+	return e.blockCodecs[string(name)]
+}
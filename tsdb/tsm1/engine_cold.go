@@ -0,0 +1,149 @@
+package tsm1
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+	"go.uber.org/zap"
+)
+
+// MoveColdFiles moves the TSM files only holding data of keys beginning with
+// name that is older than before to cold storage, and returns the number of
+// files moved. Each file is stored in the cold store and replaced by a stub
+// holding its index. It does nothing if cold storage is not configured.
+//
+// Files with tombstones are skipped until a compaction has removed the
+// deleted data. Files in cold storage are planned for compactions like any
+// other file, reading their blocks from the cold store; the output is written
+// to local disk and moved to cold storage again by a later call.
+func (e *Engine) MoveColdFiles(ctx context.Context, name []byte, before int64) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	store := e.FileStore.ColdStore()
+	if store == nil {
+		return 0, nil
+	}
+
+	// Deletes must not add tombstones to files while they are moved.
+	e.coldMu.Lock()
+	defer e.coldMu.Unlock()
+
+	var n int
+	for _, f := range e.FileStore.Stats() {
+		if f.Cold || f.HasTombstone || f.MaxTime >= before || !bytes.HasPrefix(f.MinKey, name) || !bytes.HasPrefix(f.MaxKey, name) {
+			continue
+		}
+
+		// Claim the file so that it is not compacted while it is moved.
+		if !e.Compactor.add([]string{f.Path}) {
+			continue
+		}
+		err := e.moveColdFile(ctx, store, f.Path)
+		e.Compactor.remove([]string{f.Path})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	span.LogKV("files_moved", n)
+
+	return n, nil
+}
+
+// moveColdFile stores the TSM file at path in the cold store and replaces it
+// with a stub referring to the stored object.
+func (e *Engine) moveColdFile(ctx context.Context, store ColdStore, path string) error {
+	start := time.Now()
+	name := coldObjectName(path)
+
+	index, err := func() ([]byte, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		stat, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		if err := store.Put(ctx, name, f, stat.Size()); err != nil {
+			return nil, fmt.Errorf("cold storage: %s: %v", name, err)
+		}
+		return readIndexSection(f, stat.Size())
+	}()
+	if err != nil {
+		return err
+	}
+
+	// The stub keeps the generation of the file, so that the blocks of files
+	// of later generations still override its blocks. Its sequence follows
+	// those of the files of the generation, as the output of compactions.
+	generation, sequence, err := e.FileStore.ParseFileName(path)
+	if err != nil {
+		return err
+	}
+	for _, f := range e.FileStore.Stats() {
+		if gen, seq, err := e.FileStore.ParseFileName(f.Path); err == nil && gen == generation && seq > sequence {
+			sequence = seq
+		}
+	}
+	stub := filepath.Join(e.path, e.formatFileName(generation, sequence+1)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+	if err := func() error {
+		if err := writeColdStub(stub, name, index); err != nil {
+			return err
+		}
+		if err := os.Link(StatsFilename(path), StatsFilename(stub)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}(); err != nil {
+		os.Remove(stub)
+		os.Remove(StatsFilename(stub))
+		if derr := store.Delete(ctx, name); derr != nil {
+			e.logger.Warn("Failed to delete object from cold storage", zap.String("name", name), zap.Error(derr))
+		}
+		return err
+	}
+
+	// Once the stub is live the object is owned by it, and is deleted along
+	// with it.
+	if err := e.FileStore.Replace([]string{path}, []string{stub}); err != nil {
+		return err
+	}
+
+	e.logger.Info("Moved TSM file to cold storage",
+		zap.String("path", path),
+		zap.String("name", name),
+		zap.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// readIndexSection returns the index section of the TSM file of the given size.
+func readIndexSection(f *os.File, size int64) ([]byte, error) {
+	if size < 8 {
+		return nil, fmt.Errorf("readIndexSection: file too small")
+	}
+
+	var footer [8]byte
+	if _, err := f.ReadAt(footer[:], size-8); err != nil {
+		return nil, err
+	}
+	indexStart := int64(binary.BigEndian.Uint64(footer[:]))
+	if indexStart < 5 || indexStart >= size-8 {
+		return nil, fmt.Errorf("readIndexSection: invalid indexStart")
+	}
+
+	index := make([]byte, size-8-indexStart)
+	if _, err := f.ReadAt(index, indexStart); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
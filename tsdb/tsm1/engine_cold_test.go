@@ -0,0 +1,275 @@
+package tsm1_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestEngine_MoveColdFiles(t *testing.T) {
+	const org, bucketA, bucketB = 0x1000, 0x2000, 0x3000
+
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	config := tsm1.NewConfig()
+	config.PartitionDuration = 10
+	config.ColdStorage.URL = dir
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var points []models.Point
+	points = append(points, MustParseExplodePoints(org, bucketA, "cpu,host=A value=1.1 1\ncpu,host=A value=1.2 5\ncpu,host=A value=1.3 12")...)
+	points = append(points, MustParseExplodePoints(org, bucketB, "cpu,host=A value=2.1 1")...)
+	if err := e.writePoints(points...); err != nil {
+		t.Fatalf("failed to write points: %v", err)
+	}
+	if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+
+	// Only the first partition of bucket A is old enough.
+	n, err := e.MoveColdFiles(context.Background(), bucketName(org, bucketA), 10)
+	if err != nil {
+		t.Fatalf("failed to move files: %v", err)
+	} else if n != 1 {
+		t.Fatalf("got %d files moved, expected 1", n)
+	}
+
+	objects, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(objects) != 1 {
+		t.Fatalf("got %d objects in cold storage, expected 1", len(objects))
+	}
+
+	var key []byte
+	for k := range e.FileStore.Keys() {
+		if bytes.HasPrefix([]byte(k), bucketName(org, bucketA)) {
+			key = []byte(k)
+		}
+	}
+	checkValues := func() {
+		t.Helper()
+
+		var cold int
+		for _, f := range e.FileStore.Stats() {
+			if f.Cold {
+				cold++
+			}
+		}
+		if got, exp := e.FileStore.Count(), 3; got != exp {
+			t.Fatalf("file count mismatch: got %v, exp %v", got, exp)
+		} else if cold != 1 {
+			t.Fatalf("got %d files in cold storage, expected 1", cold)
+		}
+
+		c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+		defer c.Close()
+
+		var got []float64
+		for {
+			values, err := c.ReadFloatArrayBlock(tsdb.NewFloatArrayLen(tsm1.MaxPointsPerBlock))
+			if err != nil {
+				t.Fatalf("failed to read block: %v", err)
+			} else if values.Len() == 0 {
+				break
+			}
+			got = append(got, values.Values...)
+			c.Next()
+		}
+		if exp := []float64{1.1, 1.2, 1.3}; len(got) != len(exp) || got[0] != exp[0] || got[1] != exp[1] || got[2] != exp[2] {
+			t.Fatalf("got values %v, expected %v", got, exp)
+		}
+	}
+	checkValues()
+
+	// Files in cold storage are opened from their stubs.
+	if err := e.Reopen(); err != nil {
+		t.Fatalf("failed to reopen engine: %v", err)
+	}
+	checkValues()
+
+	for _, f := range e.FileStore.Stats() {
+		if !f.Cold {
+			continue
+		}
+		fd, err := os.Open(f.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tsm1.NewTSMReader(fd); err != tsm1.ErrColdStoreNotConfigured {
+			t.Fatalf("got error %v, expected %v", err, tsm1.ErrColdStoreNotConfigured)
+		}
+		fd.Close()
+	}
+
+	// Dropping the file also removes it from cold storage.
+	if err := e.DeletePrefixRange(context.Background(), bucketName(org, bucketA), math.MinInt64, 9, nil); err != nil {
+		t.Fatalf("failed to delete prefix range: %v", err)
+	}
+	if got, exp := e.FileStore.Count(), 2; got != exp {
+		t.Fatalf("file count mismatch: got %v, exp %v", got, exp)
+	}
+	if objects, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(objects) != 0 {
+		t.Fatalf("got %d objects in cold storage, expected none", len(objects))
+	}
+}
+
+func TestEngine_MoveColdFiles_Overwrite(t *testing.T) {
+	const org, bucketA, bucketB = 0x1000, 0x2000, 0x3000
+
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	// Snapshots are not partitioned, so that the data of both buckets is
+	// written to the same file.
+	config := tsm1.NewConfig()
+	config.ColdStorage.URL = dir
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	write := func(points ...models.Point) {
+		t.Helper()
+		if err := e.writePoints(points...); err != nil {
+			t.Fatalf("failed to write points: %v", err)
+		}
+		if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+			t.Fatalf("failed to snapshot: %v", err)
+		}
+	}
+
+	// The point is overwritten in a later file, which is not moved as it
+	// also holds the data of another bucket.
+	write(MustParseExplodePoints(org, bucketA, "cpu,host=A value=1.1 1")...)
+	write(append(
+		MustParseExplodePoints(org, bucketA, "cpu,host=A value=9.9 1"),
+		MustParseExplodePoints(org, bucketB, "cpu,host=A value=2.1 1")...,
+	)...)
+
+	n, err := e.MoveColdFiles(context.Background(), bucketName(org, bucketA), 10)
+	if err != nil {
+		t.Fatalf("failed to move files: %v", err)
+	} else if n != 1 {
+		t.Fatalf("got %d files moved, expected 1", n)
+	}
+
+	var key []byte
+	for k := range e.FileStore.Keys() {
+		if bytes.HasPrefix([]byte(k), bucketName(org, bucketA)) {
+			key = []byte(k)
+		}
+	}
+	checkValues := func() {
+		t.Helper()
+
+		c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+		defer c.Close()
+
+		values, err := c.ReadFloatArrayBlock(tsdb.NewFloatArrayLen(tsm1.MaxPointsPerBlock))
+		if err != nil {
+			t.Fatalf("failed to read block: %v", err)
+		}
+		if values.Len() != 1 || values.Values[0] != 9.9 {
+			t.Fatalf("got values %v, expected [9.9]", values.Values)
+		}
+	}
+	checkValues()
+
+	if err := e.Reopen(); err != nil {
+		t.Fatalf("failed to reopen engine: %v", err)
+	}
+	checkValues()
+}
+
+func TestEngine_CompactColdFiles(t *testing.T) {
+	const org, bucket = 0x1000, 0x2000
+
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	config := tsm1.NewConfig()
+	config.ColdStorage.URL = dir
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	for _, lp := range []string{"cpu,host=A value=1.1 1", "cpu,host=A value=1.2 2"} {
+		if err := e.writePoints(MustParseExplodePoints(org, bucket, lp)...); err != nil {
+			t.Fatalf("failed to write points: %v", err)
+		}
+		if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+			t.Fatalf("failed to snapshot: %v", err)
+		}
+	}
+	if n, err := e.MoveColdFiles(context.Background(), bucketName(org, bucket), 10); err != nil {
+		t.Fatalf("failed to move files: %v", err)
+	} else if n != 2 {
+		t.Fatalf("got %d files moved, expected 2", n)
+	}
+
+	// The blocks of the files are read from the cold store, and the objects
+	// are deleted once the compacted file replaces them.
+	var paths []string
+	for _, f := range e.FileStore.Stats() {
+		paths = append(paths, f.Path)
+	}
+	files, err := e.Compactor.CompactFull(paths)
+	if err != nil {
+		t.Fatalf("failed to compact files: %v", err)
+	}
+	if err := e.FileStore.Replace(paths, files); err != nil {
+		t.Fatalf("failed to replace files: %v", err)
+	}
+
+	stats := e.FileStore.Stats()
+	if len(stats) != 1 || stats[0].Cold {
+		t.Fatalf("got files %v, expected one file out of cold storage", stats)
+	}
+	if objects, err := ioutil.ReadDir(dir); err != nil {
+		t.Fatal(err)
+	} else if len(objects) != 0 {
+		t.Fatalf("got %d objects in cold storage, expected none", len(objects))
+	}
+
+	var key []byte
+	for k := range e.FileStore.Keys() {
+		key = []byte(k)
+	}
+	c := e.FileStore.KeyCursor(context.Background(), key, 0, true)
+	defer c.Close()
+
+	values, err := c.ReadFloatArrayBlock(tsdb.NewFloatArrayLen(tsm1.MaxPointsPerBlock))
+	if err != nil {
+		t.Fatalf("failed to read block: %v", err)
+	}
+	if values.Len() != 2 || values.Values[0] != 1.1 || values.Values[1] != 1.2 {
+		t.Fatalf("got values %v, expected [1.1 1.2]", values.Values)
+	}
+}
@@ -0,0 +1,317 @@
+package tsm1
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCompactionNotFound is returned when cancelling a compaction that is not running.
+	ErrCompactionNotFound = errors.New("compaction not found")
+
+	// ErrPrefixCompactionsUnsupported is returned by CompactPrefix when the
+	// compaction planner of the engine cannot plan compactions of a prefix.
+	ErrPrefixCompactionsUnsupported = errors.New("compaction planner does not support prefix compactions")
+)
+
+// CompactionInfo describes a running or planned compaction of TSM files.
+type CompactionInfo struct {
+	// ID identifies a running compaction. It is zero for planned compactions.
+	ID int
+
+	// Level is 1 to 3 for level compactions, 4 for optimize compactions and 5
+	// for full compactions.
+	Level int
+
+	Files []string
+	Size  int64 // Total size of the files in bytes.
+
+	Running bool
+	Started time.Time
+
+	// Progress is the approximate fraction of the keys of the files that
+	// have been compacted, between 0 and 1.
+	Progress float64
+}
+
+// plannedCompaction is a compaction group waiting to be compacted at a level.
+type plannedCompaction struct {
+	level compactionLevel
+	group CompactionGroup
+}
+
+// compactionJob tracks a running compaction.
+type compactionJob struct {
+	id      int
+	level   compactionLevel
+	group   CompactionGroup
+	started time.Time
+
+	keysN    int64 // Keys in the files being compacted, accessed atomically.
+	keysDone int64 // Keys compacted so far, accessed atomically.
+
+	cancelOnce sync.Once
+	cancelC    chan struct{}
+}
+
+// cancel aborts the compaction.
+func (j *compactionJob) cancel() {
+	j.cancelOnce.Do(func() { close(j.cancelC) })
+}
+
+// cancelled returns true if the compaction was cancelled.
+func (j *compactionJob) cancelled() bool {
+	select {
+	case <-j.cancelC:
+		return true
+	default:
+		return false
+	}
+}
+
+// interrupt returns a channel that is closed when either intC is closed or the
+// job is cancelled, and a function that must be called once the channel is no
+// longer used.
+func (j *compactionJob) interrupt(intC chan struct{}) (chan struct{}, func()) {
+	c := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-intC:
+		case <-j.cancelC:
+		case <-done:
+			return
+		}
+		close(c)
+	}()
+	return c, func() { close(done) }
+}
+
+// track returns a KeyIterator that reports the progress of iterating over the
+// keys of the readers to the job.
+func (j *compactionJob) track(itr KeyIterator, readers []*TSMReader) KeyIterator {
+	var n int
+	for _, r := range readers {
+		n += r.KeyCount()
+	}
+	atomic.StoreInt64(&j.keysN, int64(n))
+	return &progressKeyIterator{KeyIterator: itr, job: j}
+}
+
+// progress returns the approximate fraction of the keys compacted. Keys found
+// in several files are counted once per file, so it may stay below 1.
+func (j *compactionJob) progress() float64 {
+	n := atomic.LoadInt64(&j.keysN)
+	if n == 0 {
+		return 0
+	}
+	p := float64(atomic.LoadInt64(&j.keysDone)) / float64(n)
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// progressKeyIterator counts the distinct keys read from a KeyIterator.
+type progressKeyIterator struct {
+	KeyIterator
+	job  *compactionJob
+	last []byte
+}
+
+func (itr *progressKeyIterator) Read() ([]byte, int64, int64, []byte, error) {
+	key, minTime, maxTime, block, err := itr.KeyIterator.Read()
+	if err == nil && !bytes.Equal(key, itr.last) {
+		itr.last = append(itr.last[:0], key...)
+		atomic.AddInt64(&itr.job.keysDone, 1)
+	}
+	return key, minTime, maxTime, block, err
+}
+
+// startCompactionJob registers a compaction of the group at level as running.
+func (e *Engine) startCompactionJob(level compactionLevel, group CompactionGroup) *compactionJob {
+	e.compactionsMu.Lock()
+	defer e.compactionsMu.Unlock()
+
+	e.lastCompactionID++
+	job := &compactionJob{
+		id:      e.lastCompactionID,
+		level:   level,
+		group:   group,
+		started: time.Now(),
+		cancelC: make(chan struct{}),
+	}
+	e.compactionJobs[job.id] = job
+	return job
+}
+
+// finishCompactionJob removes a compaction from the running compactions.
+func (e *Engine) finishCompactionJob(job *compactionJob) {
+	e.compactionsMu.Lock()
+	delete(e.compactionJobs, job.id)
+	e.compactionsMu.Unlock()
+}
+
+// setPlannedCompactions records the compactions planned but not started.
+func (e *Engine) setPlannedCompactions(planned []plannedCompaction) {
+	e.compactionsMu.Lock()
+	e.plannedCompactions = planned
+	e.compactionsMu.Unlock()
+}
+
+// Compactions returns the running compactions, ordered by ID, followed by the
+// planned compactions of the engine.
+func (e *Engine) Compactions() []CompactionInfo {
+	sizes := make(map[string]int64)
+	for _, f := range e.FileStore.Stats() {
+		sizes[f.Path] = int64(f.Size)
+	}
+	groupSize := func(group CompactionGroup) int64 {
+		var n int64
+		for _, f := range group {
+			n += sizes[f]
+		}
+		return n
+	}
+
+	e.compactionsMu.Lock()
+	defer e.compactionsMu.Unlock()
+
+	infos := make([]CompactionInfo, 0, len(e.compactionJobs)+len(e.queuedCompactions)+len(e.plannedCompactions))
+	for _, job := range e.compactionJobs {
+		infos = append(infos, CompactionInfo{
+			ID:       job.id,
+			Level:    int(job.level),
+			Files:    job.group,
+			Size:     groupSize(job.group),
+			Running:  true,
+			Started:  job.started,
+			Progress: job.progress(),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+
+	for _, planned := range [][]plannedCompaction{e.queuedCompactions, e.plannedCompactions} {
+		for _, p := range planned {
+			infos = append(infos, CompactionInfo{
+				Level: int(p.level),
+				Files: p.group,
+				Size:  groupSize(p.group),
+			})
+		}
+	}
+	return infos
+}
+
+// CancelCompaction aborts the running compaction with the given ID. Its files
+// remain as they were and may be planned again, so compactions should be
+// paused first to keep it from being restarted.
+func (e *Engine) CancelCompaction(id int) error {
+	e.compactionsMu.Lock()
+	job, ok := e.compactionJobs[id]
+	e.compactionsMu.Unlock()
+	if !ok {
+		return ErrCompactionNotFound
+	}
+
+	e.logger.Info("Cancelling compaction", zap.Int("tsm1_compaction_id", id))
+	job.cancel()
+	return nil
+}
+
+// SetCompactionsPaused pauses or resumes level, optimize and full compactions.
+// Running compactions are aborted when pausing. Snapshots of the cache are not
+// affected.
+func (e *Engine) SetCompactionsPaused(paused bool) {
+	e.pauseMu.Lock()
+	defer e.pauseMu.Unlock()
+
+	e.compactionsMu.Lock()
+	if e.compactionsPaused == paused {
+		e.compactionsMu.Unlock()
+		return
+	}
+	e.compactionsPaused = paused
+	e.compactionsMu.Unlock()
+
+	if paused {
+		e.logger.Info("Pausing compactions")
+		e.disableLevelCompactions(true)
+	} else {
+		e.logger.Info("Resuming compactions")
+		e.enableLevelCompactions(true)
+	}
+}
+
+// CompactionsPaused returns true if compactions are paused.
+func (e *Engine) CompactionsPaused() bool {
+	e.compactionsMu.Lock()
+	defer e.compactionsMu.Unlock()
+	return e.compactionsPaused
+}
+
+// CompactPrefix schedules the compaction of the TSM files holding keys with
+// the escaped prefix name, such as the encoded organization and bucket of a
+// bucket, into one generation per partition. Files that are already being
+// compacted are not included. If optimize is true, the blocks of the files are
+// copied rather than decoded and merged where possible.
+//
+// The compactions start once compactions are enabled and not paused. The
+// scheduled compactions are returned.
+func (e *Engine) CompactPrefix(ctx context.Context, name []byte, optimize bool) ([]CompactionInfo, error) {
+	planner, ok := e.CompactionPlan.(PrefixCompactionPlanner)
+	if !ok {
+		return nil, ErrPrefixCompactionsUnsupported
+	}
+
+	// Data in the cache is compacted too.
+	if err := e.WriteSnapshot(ctx, CacheStatusFullCompaction); err != nil {
+		return nil, err
+	}
+
+	level := compactionLevel(5)
+	if optimize {
+		level = 4
+	}
+
+	groups := planner.PlanPrefix(name)
+	infos := make([]CompactionInfo, 0, len(groups))
+	e.compactionsMu.Lock()
+	for _, group := range groups {
+		e.queuedCompactions = append(e.queuedCompactions, plannedCompaction{level: level, group: group})
+		infos = append(infos, CompactionInfo{Level: int(level), Files: group})
+	}
+	e.compactionsMu.Unlock()
+
+	e.logger.Info("Scheduled prefix compactions", zap.Int("tsm1_compactions_n", len(groups)), zap.Bool("tsm1_optimize", optimize))
+	return infos, nil
+}
+
+// startQueuedCompactions starts the compactions scheduled with CompactPrefix,
+// in order, until one cannot be started.
+func (e *Engine) startQueuedCompactions(ctx context.Context, wg *sync.WaitGroup) {
+	for {
+		e.compactionsMu.Lock()
+		if len(e.queuedCompactions) == 0 {
+			e.compactionsMu.Unlock()
+			return
+		}
+		next := e.queuedCompactions[0]
+		e.compactionsMu.Unlock()
+
+		if !e.compactFull(ctx, next.group, next.level == 4, wg) {
+			return
+		}
+
+		e.compactionsMu.Lock()
+		e.queuedCompactions = e.queuedCompactions[1:]
+		e.compactionsMu.Unlock()
+	}
+}
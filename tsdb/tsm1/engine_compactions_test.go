@@ -0,0 +1,120 @@
+package tsm1_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestDefaultPlanner_PlanPrefix(t *testing.T) {
+	// Bucket IDs that are not escaped, so that the names sort like the IDs.
+	nameA, nameB := bucketName(0x1000, 0x3000), bucketName(0x1000, 0x4000)
+
+	// Generations of bucket A, bucket B and both buckets.
+	var data []tsm1.FileStat
+	for i, names := range [][2][]byte{{nameA, nameA}, {nameB, nameB}, {nameA, nameB}, {nameA, nameA}, {nameB, nameB}} {
+		data = append(data, tsm1.FileStat{
+			Path:   fmt.Sprintf("%02d-01.tsm1", i+1),
+			Size:   1 * 1024 * 1024,
+			MinKey: append(append([]byte{}, names[0]...), ",host=A#!~#value"...),
+			MaxKey: append(append([]byte{}, names[1]...), ",host=B#!~#value"...),
+		})
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+
+	groups := cp.PlanPrefix(nameA)
+	if got, exp := len(groups), 1; got != exp {
+		t.Fatalf("compaction group length mismatch: got %v, exp %v", got, exp)
+	}
+	exp := tsm1.CompactionGroup{data[0].Path, data[2].Path, data[3].Path}
+	if got := groups[0]; fmt.Sprint(got) != fmt.Sprint(exp) {
+		t.Fatalf("tsm file mismatch: got %v, exp %v", got, exp)
+	}
+
+	// The files are in use until released.
+	if groups := cp.PlanPrefix(nameA); len(groups) != 0 {
+		t.Fatalf("unexpected compaction groups: %v", groups)
+	}
+	cp.Release(groups)
+
+	// Nothing is planned for buckets without files.
+	if groups := cp.PlanPrefix(bucketName(0x1000, 0x5000)); len(groups) != 0 {
+		t.Fatalf("unexpected compaction groups: %v", groups)
+	}
+}
+
+func TestEngine_CompactPrefix(t *testing.T) {
+	const org, bucketA, bucketB = 0x1000, 0x2000, 0x3000
+
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.WithCompactionPlanner(tsm1.NewDefaultPlanner(e.FileStore, tsm1.DefaultCompactFullWriteColdDuration))
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	e.SetCompactionsPaused(true)
+	if !e.CompactionsPaused() {
+		t.Fatal("expected compactions to be paused")
+	}
+
+	e.MustWritePointsString(org, bucketA, "cpu,host=A value=1.1 1")
+	e.MustWriteSnapshot()
+	e.MustWritePointsString(org, bucketA, "cpu,host=B value=1.2 2")
+	e.MustWriteSnapshot()
+	e.MustWritePointsString(org, bucketB, "cpu,host=A value=2.1 1")
+	e.MustWriteSnapshot()
+
+	// The cache is snapshotted before planning.
+	e.MustWritePointsString(org, bucketA, "cpu,host=C value=1.3 3")
+
+	scheduled, err := e.CompactPrefix(context.Background(), bucketName(org, bucketA), false)
+	if err != nil {
+		t.Fatalf("failed to compact prefix: %v", err)
+	}
+	if got, exp := len(scheduled), 1; got != exp {
+		t.Fatalf("scheduled compactions mismatch: got %v, exp %v", got, exp)
+	}
+	if got, exp := len(scheduled[0].Files), 3; got != exp {
+		t.Fatalf("tsm file length mismatch: got %v, exp %v", got, exp)
+	}
+
+	// The compaction is listed while paused.
+	compactions := e.Compactions()
+	if got, exp := len(compactions), 1; got != exp {
+		t.Fatalf("compactions mismatch: got %v, exp %v", got, exp)
+	}
+	if c := compactions[0]; c.Running || c.Level != 5 || c.Size == 0 {
+		t.Fatalf("unexpected compaction %+v", c)
+	}
+
+	if err := e.CancelCompaction(1); err != tsm1.ErrCompactionNotFound {
+		t.Fatalf("got error %v, expected %v", err, tsm1.ErrCompactionNotFound)
+	}
+
+	e.SetCompactionsPaused(false)
+	deadline := time.Now().Add(10 * time.Second)
+	for e.FileStore.Count() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for compaction: %d files", e.FileStore.Count())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got, exp := len(e.FileStore.Keys()), 4; got != exp {
+		t.Fatalf("series count mismatch: got %v, exp %v", got, exp)
+	}
+}
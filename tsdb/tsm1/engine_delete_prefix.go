@@ -34,6 +34,10 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 	// now we know that the series file or index won't be closed out from underneath
 	// of us.
 
+	// Ensure that no files are moved to cold storage while tombstones are added.
+	e.coldMu.Lock()
+	defer e.coldMu.Unlock()
+
 	// Ensure that the index does not compact away the measurement or series we're
 	// going to delete before we're done with them.
 	span, _ = tracing.StartSpanFromContextWithOperationName(rootCtx, "disable index compactions")
@@ -79,6 +83,16 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 	}
 	possiblyDead.keys = make(map[string]struct{})
 
+	// Files only holding data of the prefix within the time range, such as the
+	// files of expired partitions, are removed rather than tombstoned.
+	if pred == nil {
+		if err := e.dropPrefixFiles(ctx, name, min, max, func(key []byte) {
+			possiblyDead.keys[string(key)] = struct{}{}
+		}); err != nil {
+			return err
+		}
+	}
+
 	if err := e.FileStore.Apply(func(r TSMFile) error {
 		var predClone Predicate // Apply executes concurrently across files.
 		if pred != nil {
@@ -281,3 +295,36 @@ func (e *Engine) DeletePrefixRange(rootCtx context.Context, name []byte, min, ma
 
 	return nil
 }
+
+// dropPrefixFiles removes the TSM files that only contain keys with the prefix
+// name and data within [min, max]. The keys of each removed file are passed to
+// fn.
+func (e *Engine) dropPrefixFiles(ctx context.Context, name []byte, min, max int64, fn func(key []byte)) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var paths []string
+	for _, f := range e.FileStore.Stats() {
+		if f.MinTime < min || f.MaxTime > max || !bytes.HasPrefix(f.MinKey, name) || !bytes.HasPrefix(f.MaxKey, name) {
+			continue
+		}
+
+		r := e.FileStore.TSMReader(f.Path)
+		if r == nil {
+			continue // The file was removed by a compaction.
+		}
+		iter := r.Iterator(nil)
+		for iter.Next() {
+			fn(iter.Key())
+		}
+		err := iter.Err()
+		r.Unref()
+		if err != nil {
+			return err
+		}
+		paths = append(paths, f.Path)
+	}
+	span.LogKV("files_dropped", len(paths))
+
+	return e.FileStore.Replace(paths, nil)
+}
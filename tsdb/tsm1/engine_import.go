@@ -0,0 +1,249 @@
+package tsm1
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/pkg/bytesutil"
+	"github.com/influxdata/influxdb/pkg/fs"
+	"github.com/influxdata/influxdb/tsdb"
+	"go.uber.org/zap"
+)
+
+// importSeriesBatchSize is the number of series added to the index at a time
+// when importing a TSM file.
+const importSeriesBatchSize = 10000
+
+// ImportPrefix copies the keys beginning with prefix from the TSM file at path
+// into a new TSM file of the engine, and adds their series to the index. If
+// rename is not nil, keys are renamed by it as in CopyPrefix.
+//
+// Keys whose series already exist with a different field type are not
+// imported. If there are any, a tsdb.PartialWriteError is returned after the
+// rest of the data has been imported.
+func (e *Engine) ImportPrefix(ctx context.Context, path string, prefix []byte, rename func(key []byte) []byte) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	r, err := e.openImportFile(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if rename == nil {
+		rename = func(key []byte) []byte { return key }
+	}
+
+	dropped, err := e.importSeries(r, prefix, rename)
+	if err != nil {
+		return err
+	}
+	if err := e.importCopy(r, prefix, rename, dropped); err != nil {
+		return err
+	}
+	return dropped.partialWriteError()
+}
+
+// ImportFile adds all data of the TSM file at path to the engine, and its
+// series to the index. If no data has to be left out, the file itself is moved
+// into the engine; otherwise it is copied as in ImportPrefix.
+func (e *Engine) ImportFile(ctx context.Context, path string) error {
+	span, _ := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	r, err := e.openImportFile(path)
+	if err != nil {
+		return err
+	}
+
+	rename := func(key []byte) []byte { return key }
+	dropped, err := e.importSeries(r, nil, rename)
+	if err != nil {
+		r.Close()
+		return err
+	}
+
+	if len(dropped.keys) > 0 || r.HasTombstones() {
+		err := e.importCopy(r, nil, rename, dropped)
+		if cerr := r.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+		return dropped.partialWriteError()
+	}
+
+	if err := r.Close(); err != nil {
+		return err
+	}
+	generation := e.FileStore.NextGeneration()
+	fileName := filepath.Join(e.path, e.formatFileName(generation, 1)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+	if err := fs.RenameFile(path, fileName); err != nil {
+		return err
+	}
+	if err := e.FileStore.Replace(nil, []string{fileName}); err != nil {
+		return fmt.Errorf("error adding imported file: %v", err)
+	}
+	return nil
+}
+
+func (e *Engine) openImportFile(path string) (*TSMReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewTSMReader(f, WithTSMReaderLogger(e.logger))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// importCopy writes the keys in r beginning with prefix, except those of the
+// dropped series, to a new TSM file of the engine.
+func (e *Engine) importCopy(r *TSMReader, prefix []byte, rename func(key []byte) []byte, dropped *droppedSeries) error {
+	generation := e.FileStore.NextGeneration()
+	fileName := filepath.Join(e.path, e.formatFileName(generation, 1)+"."+TSMFileExtension+"."+TmpTSMFileExtension)
+	fd, err := os.OpenFile(fileName, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+	w, err := NewTSMWriter(fd)
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	n, err := CopyPrefix(w, r, prefix, func(key []byte) []byte {
+		key = rename(key)
+		if seriesKey, _ := SeriesAndFieldFromCompositeKey(key); dropped.contains(seriesKey) {
+			return nil
+		}
+		return key
+	})
+	if err == nil && n > 0 {
+		err = w.WriteIndex()
+	}
+	if err != nil || n == 0 {
+		if rerr := w.Remove(); rerr != nil {
+			e.logger.Info("Failed to remove import file", zap.String("path", fileName), zap.Error(rerr))
+		}
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := e.FileStore.Replace(nil, []string{fileName}); err != nil {
+		return fmt.Errorf("error adding imported file: %v", err)
+	}
+	return nil
+}
+
+// importSeries adds the series of the keys in r beginning with prefix to the
+// index. It returns the series the index rejected.
+func (e *Engine) importSeries(r *TSMReader, prefix []byte, rename func(key []byte) []byte) (*droppedSeries, error) {
+	dropped := &droppedSeries{keys: make(map[string]struct{})}
+	collection := &tsdb.SeriesCollection{}
+
+	flush := func() error {
+		if collection.Length() == 0 {
+			return nil
+		}
+		if err := e.index.CreateSeriesListIfNotExists(collection); err != nil {
+			return err
+		}
+		dropped.add(collection)
+		collection = &tsdb.SeriesCollection{}
+		return nil
+	}
+
+	iter := r.Iterator(prefix)
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) {
+			break
+		}
+
+		seriesKey, _ := SeriesAndFieldFromCompositeKey(rename(key))
+		name, tags := models.ParseKeyBytes(seriesKey)
+		collection.Keys = append(collection.Keys, seriesKey)
+		collection.Names = append(collection.Names, name)
+		collection.Tags = append(collection.Tags, tags)
+		collection.Types = append(collection.Types, BlockTypeToFieldType(iter.Type()))
+
+		if collection.Length() >= importSeriesBatchSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return dropped, nil
+}
+
+// droppedSeries tracks the series keys rejected by the index during an import.
+type droppedSeries struct {
+	keys   map[string]struct{}
+	reason string
+}
+
+func (d *droppedSeries) add(collection *tsdb.SeriesCollection) {
+	for _, key := range collection.DroppedKeys {
+		d.keys[string(key)] = struct{}{}
+	}
+	if d.reason == "" {
+		d.reason = collection.Reason
+	}
+}
+
+// contains returns true if the series key was rejected.
+func (d *droppedSeries) contains(seriesKey []byte) bool {
+	_, ok := d.keys[string(seriesKey)]
+	return ok
+}
+
+// partialWriteError returns an error describing the rejected series, or nil
+// if there are none.
+func (d *droppedSeries) partialWriteError() error {
+	if len(d.keys) == 0 {
+		return nil
+	}
+	keys := make([][]byte, 0, len(d.keys))
+	for key := range d.keys {
+		keys = append(keys, []byte(key))
+	}
+	keys = bytesutil.SortDedup(keys)
+	return tsdb.PartialWriteError{Reason: d.reason, Dropped: len(keys), DroppedKeys: keys}
+}
+
+// BlockTypeToFieldType returns the field type of the values in blocks of the given type.
+func BlockTypeToFieldType(typ byte) models.FieldType {
+	switch typ {
+	case BlockFloat64:
+		return models.Float
+	case BlockInteger:
+		return models.Integer
+	case BlockBoolean:
+		return models.Boolean
+	case BlockString:
+		return models.String
+	case BlockUnsigned:
+		return models.Unsigned
+	default:
+		return models.Empty
+	}
+}
@@ -0,0 +1,96 @@
+package tsm1
+
+import (
+	"context"
+
+	"github.com/influxdata/influxdb/kit/tracing"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+)
+
+// DeadSeries returns the set of series in the index without any data in the
+// cache or TSM files. Data may be written to the series afterwards, so the set
+// must be checked again by DropDeadSeries while writes are blocked.
+func (e *Engine) DeadSeries(ctx context.Context) (*tsdb.SeriesIDSet, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	dead := tsdb.NewSeriesIDSet()
+	var buf []byte
+	for i, itr := 0, e.index.SeriesIDSet().Iterator(); itr.HasNext(); i++ {
+		// Check for cancellation periodically.
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+
+		id := tsdb.NewSeriesID(uint64(itr.Next()))
+		var ok bool
+		if ok, buf = e.seriesHasData(id, buf); !ok {
+			dead.AddNoLock(id)
+		}
+	}
+
+	span.LogKV("dead_series", dead.Cardinality())
+	return dead, nil
+}
+
+// DropDeadSeries removes the series of ids that still have no data in the
+// cache or TSM files from the index and the series file, and returns the
+// number of series removed. Writes must be blocked while it runs, so that no
+// data is written to the series being removed.
+func (e *Engine) DropDeadSeries(ctx context.Context, ids *tsdb.SeriesIDSet) (int, error) {
+	span, ctx := tracing.StartSpanFromContext(ctx)
+	defer span.Finish()
+
+	var (
+		n   int
+		buf []byte
+	)
+	for i, itr := 0, ids.Iterator(); itr.HasNext(); i++ {
+		if i%1000 == 0 {
+			if err := ctx.Err(); err != nil {
+				return n, err
+			}
+		}
+
+		id := tsdb.NewSeriesID(uint64(itr.Next()))
+		var ok bool
+		if ok, buf = e.seriesHasData(id, buf); ok {
+			continue
+		}
+
+		name, tags := e.sfile.Series(id)
+		if name == nil {
+			continue
+		}
+
+		// Remove the series from the index before the series file.
+		if err := e.index.DropSeries(id, models.MakeKey(name, tags), true); err != nil {
+			return n, err
+		} else if err := e.sfile.DeleteSeriesID(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	span.LogKV("dropped_series", n)
+	return n, nil
+}
+
+// seriesHasData returns true if the cache or any TSM file holds data for the
+// series id. Series no longer in the series file are reported to hold data, so
+// that they are left alone. buf is used to build the TSM key of the series and
+// returned for reuse.
+func (e *Engine) seriesHasData(id tsdb.SeriesID, buf []byte) (bool, []byte) {
+	name, tags := e.sfile.Series(id)
+	if name == nil {
+		return true, buf
+	}
+	buf = AppendSeriesFieldKeyBytes(buf[:0], models.MakeKey(name, tags), tags.Get(models.FieldKeyTagKeyBytes))
+
+	// The cache is checked first, as snapshots only leave the cache once they
+	// have been written to a TSM file.
+	return e.Cache.Contains(buf) || e.FileStore.Contains(buf), buf
+}
@@ -0,0 +1,82 @@
+package tsm1_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+func TestEngine_DropDeadSeries(t *testing.T) {
+	e, err := NewEngine(tsm1.NewConfig(), t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	// One series with data in a TSM file, and another in the cache.
+	if err := e.WritePointsString("mm0", "cpu,host=A value=1.1 1"); err != nil {
+		t.Fatal(err)
+	} else if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+		t.Fatal(err)
+	} else if err := e.WritePointsString("mm0", "cpu,host=B value=1.2 2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A series only in the index.
+	points, err := models.ParsePointsString("cpu,host=C value=1.3 3", "mm0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := tsdb.NewSeriesCollection(points)
+	if err := e.index.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	}
+	deadID := collection.SeriesIDs[0]
+
+	dead, err := e.DeadSeries(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if got, exp := dead.Cardinality(), uint64(1); got != exp {
+		t.Fatalf("unexpected dead series: got %d, exp %d", got, exp)
+	} else if !dead.Contains(deadID) {
+		t.Fatalf("expected series %d to be dead", deadID.RawID())
+	}
+
+	// Series written to since are not dropped.
+	if err := e.Engine.WritePoints(points); err != nil {
+		t.Fatal(err)
+	} else if n, err := e.DropDeadSeries(context.Background(), dead); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Fatalf("unexpected dropped series: %d", n)
+	}
+
+	// Dead series are removed from the index and series file.
+	if err := e.DeletePrefixRange(context.Background(), []byte("mm0"), 3, 3, nil); err != nil {
+		t.Fatal(err)
+	} else if err := e.index.CreateSeriesListIfNotExists(collection); err != nil {
+		t.Fatal(err)
+	}
+	deadID = collection.SeriesIDs[0]
+	if dead, err = e.DeadSeries(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if n, err := e.DropDeadSeries(context.Background(), dead); err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("unexpected dropped series: %d", n)
+	}
+
+	if got, exp := e.SeriesIDSet().Cardinality(), uint64(2); got != exp {
+		t.Fatalf("unexpected series in index: got %d, exp %d", got, exp)
+	} else if e.SeriesIDSet().Contains(deadID) {
+		t.Fatal("expected dead series to be removed from the index")
+	} else if !e.sfile.IsDeleted(deadID) {
+		t.Fatal("expected dead series to be deleted from the series file")
+	}
+}
@@ -366,6 +366,7 @@ type Engine struct {
 	indexPath string
 	index     *tsi1.Index
 	sfile     *tsdb.SeriesFile
+	config    tsm1.Config
 }
 
 // NewEngine returns a new instance of Engine at a temporary location.
@@ -397,6 +398,7 @@ func NewEngine(config tsm1.Config, tb testing.TB) (*Engine, error) {
 		indexPath: idxPath,
 		index:     idx,
 		sfile:     sfile,
+		config:    config,
 	}, nil
 }
 
@@ -456,8 +458,7 @@ func (e *Engine) Reopen() error {
 	e.index = MustOpenIndex(e.indexPath, tsdb.NewSeriesIDSet(), e.sfile)
 
 	// Re-initialize engine.
-	config := tsm1.NewConfig()
-	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, config,
+	e.Engine = tsm1.NewEngine(filepath.Join(e.root, "data"), e.index, e.config,
 		tsm1.WithCompactionPlanner(newMockPlanner()))
 
 	// Reopen engine
@@ -90,6 +90,10 @@ type TSMFile interface {
 	// TombstoneRange returns ranges of time that are deleted for the given key.
 	TombstoneRange(key []byte, buf []TimeRange) []TimeRange
 
+	// BlockStats returns the statistics of the block identified by entry, if
+	// they are stored in the file.
+	BlockStats(entry *IndexEntry) (BlockStats, bool)
+
 	// KeyRange returns the min and max keys in the file.
 	KeyRange() ([]byte, []byte)
 
@@ -217,12 +221,16 @@ type FileStore struct {
 	parseFileName ParseFileNameFunc
 
 	obs FileStoreObserver
+
+	coldStore  ColdStore   // Holds the contents of files moved to cold storage.
+	blockCache *BlockCache // Caches blocks read from the cold store.
 }
 
 // FileStat holds information about a TSM file on disk.
 type FileStat struct {
 	Path             string
 	HasTombstone     bool
+	Cold             bool
 	Size             uint32
 	LastModified     int64
 	MinTime, MaxTime int64
@@ -285,6 +293,28 @@ func (f *FileStore) SetCurrentGenerationFunc(fn func() int) {
 	f.currentGenerationFunc = fn
 }
 
+// WithColdStore sets the cold store and block cache used to read files that
+// have been moved to cold storage. It must be set before opening the file store.
+func (f *FileStore) WithColdStore(store ColdStore, cache *BlockCache) {
+	f.coldStore = store
+	f.blockCache = cache
+}
+
+// ColdStore returns the cold store of the file store, or nil if cold storage
+// is not configured.
+func (f *FileStore) ColdStore() ColdStore {
+	return f.coldStore
+}
+
+// readerOptions returns the options for opening the TSM files of the file store.
+func (f *FileStore) readerOptions() []tsmReaderOption {
+	return []tsmReaderOption{
+		WithMadviseWillNeed(f.tsmMMAPWillNeed),
+		WithTSMReaderLogger(f.logger),
+		WithColdStore(f.coldStore, f.blockCache),
+	}
+}
+
 // WithLogger sets the logger on the file store.
 func (f *FileStore) WithLogger(log *zap.Logger) {
 	f.logger = log.With(zap.String("service", "filestore"))
@@ -475,6 +505,19 @@ func (f *FileStore) Keys() map[string]byte {
 	return uniqueKeys
 }
 
+// Contains returns true if any file holds blocks for key.
+func (f *FileStore) Contains(key []byte) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	for _, f := range f.files {
+		if f.Contains(key) {
+			return true
+		}
+	}
+	return false
+}
+
 // Type returns the type of values store at the block for key.
 func (f *FileStore) Type(key []byte) (byte, error) {
 	f.mu.RLock()
@@ -518,6 +561,21 @@ func (f *FileStore) ForEachFile(fn func(f TSMFile) bool) {
 	f.mu.RUnlock()
 }
 
+// RefFiles returns the TSM files currently loaded with a reference held on
+// each, so that they remain readable while compacted or replaced. Unref must
+// be called on each file once done.
+func (f *FileStore) RefFiles() []TSMFile {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	files := make([]TSMFile, 0, len(f.files))
+	for _, file := range f.files {
+		file.Ref()
+		files = append(files, file)
+	}
+	return files
+}
+
 // Apply calls fn on each TSMFile in the store concurrently. The level of
 // concurrency is set to GOMAXPROCS.
 func (f *FileStore) Apply(fn func(r TSMFile) error) error {
@@ -661,14 +719,20 @@ func (f *FileStore) Open(ctx context.Context) error {
 			defer f.openLimiter.Release()
 
 			start := time.Now()
-			df, err := NewTSMReader(file,
-				WithMadviseWillNeed(f.tsmMMAPWillNeed),
-				WithTSMReaderLogger(f.logger))
+			df, err := NewTSMReader(file, f.readerOptions()...)
 			f.logger.Info("Opened file",
 				zap.String("path", file.Name()),
 				zap.Int("id", idx),
 				zap.Duration("duration", time.Since(start)))
 
+			// Files in cold storage are not corrupt but can not be read
+			// without the cold store, so they must not be renamed.
+			if err == ErrColdStoreNotConfigured {
+				file.Close()
+				readerC <- &res{err: fmt.Errorf("cannot open file %s: %v", file.Name(), err)}
+				return
+			}
+
 			// If we are unable to read a TSM file then log the error, rename
 			// the file, and continue loading the shard without it.
 			if err != nil {
@@ -844,6 +908,38 @@ func (f *FileStore) ReplaceWithCallback(oldFiles, newFiles []string, updatedFn f
 	return f.replace(oldFiles, newFiles, updatedFn)
 }
 
+// Quarantine removes the TSM file at path from the store, so that it is no
+// longer read, and keeps its data under the path with the BadTSMFileExtension
+// appended, like the corrupt files found on open. It returns false if the file
+// is no longer in the store.
+func (f *FileStore) Quarantine(path string) (bool, error) {
+	f.mu.RLock()
+	found := false
+	for _, file := range f.files {
+		if file.Path() == path {
+			found = true
+			break
+		}
+	}
+	f.mu.RUnlock()
+	if !found {
+		return false, nil
+	}
+
+	// The file is linked rather than renamed, as queries may be reading it.
+	badPath := path + "." + BadTSMFileExtension
+	if err := os.Remove(badPath); err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	if err := os.Link(path, badPath); err != nil {
+		return false, err
+	}
+	if err := f.Replace([]string{path}, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Replace replaces oldFiles with newFiles.
 func (f *FileStore) Replace(oldFiles, newFiles []string) error {
 	return f.replace(oldFiles, newFiles, nil)
@@ -902,9 +998,7 @@ func (f *FileStore) replace(oldFiles, newFiles []string, updatedFn func(r []TSMF
 			}
 		}
 
-		tsm, err := NewTSMReader(fd,
-			WithMadviseWillNeed(f.tsmMMAPWillNeed),
-			WithTSMReaderLogger(f.logger))
+		tsm, err := NewTSMReader(fd, f.readerOptions()...)
 		if err != nil {
 			return err
 		}
@@ -1211,6 +1305,13 @@ func (f *FileStore) locations(key []byte, t int64, ascending bool) []*location {
 // CreateSnapshot creates hardlinks for all tsm and tombstone files
 // in the path provided.
 func (f *FileStore) CreateSnapshot(ctx context.Context) (backupID int, backupDirFullPath string, err error) {
+	return f.CreateSnapshotFunc(ctx, nil)
+}
+
+// CreateSnapshotFunc is like CreateSnapshot, but calls include with the path and
+// size of each tsm and tombstone file, and only creates a hardlink for the file if
+// include returns true. A nil include creates hardlinks for all files.
+func (f *FileStore) CreateSnapshotFunc(ctx context.Context, include func(path string, size int64) bool) (backupID int, backupDirFullPath string, err error) {
 	span, _ := tracing.StartSpanFromContext(ctx)
 	defer span.Finish()
 
@@ -1242,11 +1343,16 @@ func (f *FileStore) CreateSnapshot(ctx context.Context) (backupID int, backupDir
 		return 0, "", err
 	}
 	for _, tsmf := range files {
-		newpath := filepath.Join(backupDirFullPath, filepath.Base(tsmf.Path()))
-		if err := os.Link(tsmf.Path(), newpath); err != nil {
-			return 0, "", fmt.Errorf("error creating tsm hard link: %q", err)
+		if include == nil || include(tsmf.Path(), int64(tsmf.Size())) {
+			newpath := filepath.Join(backupDirFullPath, filepath.Base(tsmf.Path()))
+			if err := os.Link(tsmf.Path(), newpath); err != nil {
+				return 0, "", fmt.Errorf("error creating tsm hard link: %q", err)
+			}
 		}
 		for _, tf := range tsmf.TombstoneFiles() {
+			if include != nil && !include(tf.Path, int64(tf.Size)) {
+				continue
+			}
 			newpath := filepath.Join(backupDirFullPath, filepath.Base(tf.Path))
 			if err := os.Link(tf.Path, newpath); err != nil {
 				return 0, "", fmt.Errorf("error creating tombstone hard link: %q", err)
@@ -0,0 +1,113 @@
+package tsm1
+
+import (
+	"time"
+)
+
+// TSM files can be partitioned by bucket and time, similar to the shard groups
+// of 1.x. A partitioned file only holds the data of a single bucket within a
+// single time window of the partition duration, which allows the data of a
+// bucket that falls out of its retention period to be removed by unlinking
+// whole files rather than by rewriting them.
+//
+// Partitions are not recorded in the files themselves. A file belongs to a
+// partition when all of its keys share a bucket prefix and its time range lies
+// within one window, so files written before partitioning was enabled are
+// treated as unpartitioned.
+
+// partitionWindow returns the start of the time window of duration d that
+// contains the timestamp t.
+func partitionWindow(t int64, d time.Duration) int64 {
+	w := t - t%int64(d)
+	if t < 0 && w != t {
+		w -= int64(d)
+	}
+	return w
+}
+
+// bucketPrefix returns the prefix of the key holding the escaped organization
+// and bucket name.
+func bucketPrefix(key []byte) []byte {
+	var i int
+	for n := 0; n < 16 && i < len(key); n++ {
+		if key[i] == '\\' && i+1 < len(key) && (key[i+1] == ',' || key[i+1] == ' ') {
+			i++
+		}
+		i++
+	}
+	return key[:i]
+}
+
+// partitionKey returns the key identifying the partition of the key and
+// timestamp for the partition duration d.
+func partitionKey(key []byte, t int64, d time.Duration) string {
+	var buf [8]byte
+	w := uint64(partitionWindow(t, d))
+	for i := range buf {
+		buf[i] = byte(w >> (56 - 8*uint(i)))
+	}
+	return string(bucketPrefix(key)) + string(buf[:])
+}
+
+// filePartition returns the partition key of the file for the partition
+// duration d, and false if the file is not partitioned.
+func filePartition(f FileStat, d time.Duration) (string, bool) {
+	if d <= 0 || len(f.MinKey) == 0 || len(f.MaxKey) == 0 {
+		return "", false
+	}
+
+	min := partitionKey(f.MinKey, f.MinTime, d)
+	if min != partitionKey(f.MaxKey, f.MaxTime, d) {
+		return "", false
+	}
+	return min, true
+}
+
+// SplitPartitions splits the cache into one cache per partition of duration d,
+// so that each can be written to its own TSM files. The returned caches share
+// no state with c.
+func (c *Cache) SplitPartitions(d time.Duration) []*Cache {
+	c.mu.RLock()
+	store := c.store
+	spilled := len(c.spills) > 0
+	c.mu.RUnlock()
+
+	var caches []*Cache
+	partitions := make(map[string]*Cache)
+
+	split := func(key []byte, entryValues Values) {
+		values := make(map[string]Values)
+		for _, v := range entryValues {
+			pk := partitionKey(key, v.UnixNano(), d)
+			values[pk] = append(values[pk], v)
+		}
+
+		for pk, vals := range values {
+			pc := partitions[pk]
+			if pc == nil {
+				pc = &Cache{store: newRing()}
+				partitions[pk] = pc
+				caches = append(caches, pc)
+			}
+			// Values of an entry always have a single type.
+			_, _ = pc.store.write(key, vals)
+		}
+	}
+
+	// Values spilled to disk are read back, merged with the values of the store.
+	if spilled {
+		for _, key := range c.Keys() {
+			split(key, c.values(key))
+		}
+		return caches
+	}
+
+	// applySerial never returns an error here.
+	_ = store.applySerial(func(k string, e *entry) error {
+		e.mu.RLock()
+		split([]byte(k), e.values)
+		e.mu.RUnlock()
+		return nil
+	})
+	return caches
+}
@@ -0,0 +1,214 @@
+package tsm1_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+	"github.com/influxdata/influxdb/tsdb"
+	"github.com/influxdata/influxdb/tsdb/tsm1"
+)
+
+// bucketName returns the escaped measurement name of the org and bucket.
+func bucketName(org, bucket influxdb.ID) []byte {
+	encoded := tsdb.EncodeName(org, bucket)
+	return models.EscapeMeasurement(encoded[:])
+}
+
+// generationFileStore is a fakeFileStore that hands out new generations.
+type generationFileStore struct {
+	fakeFileStore
+	gen int64
+}
+
+func (w *generationFileStore) NextGeneration() int {
+	return int(atomic.AddInt64(&w.gen, 1))
+}
+
+func TestCompactor_Snapshot_Partitioned(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	nameA, nameB := bucketName(0x1000, 0x2000), bucketName(0x1000, 0x3000)
+	c := tsm1.NewCache(0)
+	for k, v := range map[string][]tsm1.Value{
+		string(nameA) + ",host=A#!~#value": {tsm1.NewValue(1, 1.0), tsm1.NewValue(9, 2.0), tsm1.NewValue(10, 3.0)},
+		string(nameA) + ",host=B#!~#value": {tsm1.NewValue(25, 4.0)},
+		string(nameB) + ",host=A#!~#value": {tsm1.NewValue(2, 5.0)},
+	} {
+		if err := c.Write([]byte(k), v); err != nil {
+			t.Fatalf("failed to write key %q to cache: %v", k, err)
+		}
+	}
+
+	compactor := tsm1.NewCompactor()
+	compactor.Dir = dir
+	compactor.FileStore = &generationFileStore{}
+	compactor.PartitionDuration = 10
+	compactor.Open()
+
+	files, err := compactor.WriteSnapshot(context.Background(), c)
+	if err != nil {
+		t.Fatalf("unexpected error writing snapshot: %v", err)
+	}
+
+	// One file per bucket and time window.
+	if got, exp := len(files), 4; got != exp {
+		t.Fatalf("files length mismatch: got %v, exp %v", got, exp)
+	}
+
+	var points int
+	for _, f := range files {
+		r := MustOpenTSMReader(f)
+		min, max := r.TimeRange()
+		if min/10 != max/10 {
+			t.Fatalf("file %s spans multiple partitions: [%d, %d]", f, min, max)
+		}
+
+		iter := r.Iterator(nil)
+		var prefix []byte
+		for iter.Next() {
+			key := iter.Key()
+			switch {
+			case bytes.HasPrefix(key, nameA) && (prefix == nil || bytes.Equal(prefix, nameA)):
+				prefix = nameA
+			case bytes.HasPrefix(key, nameB) && (prefix == nil || bytes.Equal(prefix, nameB)):
+				prefix = nameB
+			default:
+				t.Fatalf("file %s holds keys of multiple buckets", f)
+			}
+
+			values, err := r.ReadAll(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			points += len(values)
+		}
+		r.Close()
+	}
+	if points != 5 {
+		t.Fatalf("got %d points, exp 5", points)
+	}
+}
+
+func TestDefaultPlanner_PlanLevel_Partitioned(t *testing.T) {
+	nameA, nameB := bucketName(0x1000, 0x2000), bucketName(0x1000, 0x3000)
+
+	// Level 1 generations of two partitions, interleaved.
+	var data []tsm1.FileStat
+	for i := 1; i <= 16; i++ {
+		name := nameA
+		if i%2 == 0 {
+			name = nameB
+		}
+		data = append(data, tsm1.FileStat{
+			Path:    fmt.Sprintf("%02d-01.tsm1", i),
+			Size:    1 * 1024 * 1024,
+			MinKey:  append(append([]byte{}, name...), ",host=A#!~#value"...),
+			MaxKey:  append(append([]byte{}, name...), ",host=B#!~#value"...),
+			MinTime: int64(time.Hour),
+			MaxTime: int64(2 * time.Hour),
+		})
+	}
+
+	cp := tsm1.NewDefaultPlanner(
+		&fakeFileStore{
+			PathsFn: func() []tsm1.FileStat {
+				return data
+			},
+		}, tsm1.DefaultCompactFullWriteColdDuration,
+	)
+	cp.PartitionDuration = 24 * time.Hour
+
+	groups := cp.PlanLevel(1)
+	if got, exp := len(groups), 2; got != exp {
+		t.Fatalf("compaction group length mismatch: got %v, exp %v", got, exp)
+	}
+	for i, group := range groups {
+		if got, exp := len(group), 8; got != exp {
+			t.Fatalf("tsm file length mismatch: got %v, exp %v", got, exp)
+		}
+		for j, p := range group {
+			if exp := data[2*j+i].Path; p != exp {
+				t.Fatalf("tsm file mismatch: got %v, exp %v", p, exp)
+			}
+		}
+	}
+}
+
+func TestEngine_DeletePrefixRange_Partitioned(t *testing.T) {
+	const org, bucketA, bucketB = 0x1000, 0x2000, 0x3000
+
+	config := tsm1.NewConfig()
+	config.PartitionDuration = 10
+	e, err := NewEngine(config, t)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Open(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+
+	var points []models.Point
+	points = append(points, MustParseExplodePoints(org, bucketA, "cpu,host=A value=1.1 1\ncpu,host=B value=1.2 5\ncpu,host=A value=1.3 12")...)
+	points = append(points, MustParseExplodePoints(org, bucketB, "cpu,host=A value=2.1 1")...)
+	if err := e.writePoints(points...); err != nil {
+		t.Fatalf("failed to write points: %v", err)
+	}
+	if err := e.WriteSnapshot(context.Background(), tsm1.CacheStatusColdNoWrites); err != nil {
+		t.Fatalf("failed to snapshot: %v", err)
+	}
+	if got, exp := e.FileStore.Count(), 3; got != exp {
+		t.Fatalf("file count mismatch: got %v, exp %v", got, exp)
+	}
+
+	// Expire the first partition of bucket A.
+	if err := e.DeletePrefixRange(context.Background(), bucketName(org, bucketA), math.MinInt64, 9, nil); err != nil {
+		t.Fatalf("failed to delete prefix range: %v", err)
+	}
+
+	if got, exp := e.FileStore.Count(), 2; got != exp {
+		t.Fatalf("file count mismatch: got %v, exp %v", got, exp)
+	}
+	for _, f := range e.FileStore.Files() {
+		if len(f.TombstoneFiles()) != 0 {
+			t.Fatalf("unexpected tombstones for %s", f.Path())
+		}
+	}
+
+	keys := e.FileStore.Keys()
+	if got, exp := len(keys), 2; got != exp {
+		t.Fatalf("series count mismatch: got %v, exp %v: %v", got, exp, keys)
+	}
+
+	// The series only held by the removed file is removed from the index, which
+	// does not store names in escaped form.
+	encoded := tsdb.EncodeName(org, bucketA)
+	iter, err := e.index.MeasurementSeriesIDIterator(encoded[:])
+	if err != nil {
+		t.Fatalf("iterator error: %v", err)
+	}
+	defer iter.Close()
+
+	var n int
+	for {
+		elem, err := iter.Next()
+		if err != nil {
+			t.Fatal(err)
+		} else if elem.SeriesID.IsZero() {
+			break
+		}
+		n++
+	}
+	if n != 1 {
+		t.Fatalf("got %d series in index, exp 1", n)
+	}
+}
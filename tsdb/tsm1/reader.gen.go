@@ -108,6 +108,7 @@ type blockAccessor interface {
 	readBooleanBlock(entry *IndexEntry, values *[]BooleanValue) ([]BooleanValue, error)
 	readBooleanArrayBlock(entry *IndexEntry, values *tsdb.BooleanArray) error
 	readBytes(entry *IndexEntry, buf []byte) (uint32, []byte, error)
+	blockStats(entry *IndexEntry) (BlockStats, bool)
 	rename(path string) error
 	path() string
 	close() error
@@ -283,3 +284,83 @@ func (m *mmapAccessor) readBooleanArrayBlock(entry *IndexEntry, values *tsdb.Boo
 
 	return err
 }
+
+func (m *coldAccessor) readFloatBlock(entry *IndexEntry, values *[]FloatValue) ([]FloatValue, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeFloatBlock(b, values)
+}
+
+func (m *coldAccessor) readFloatArrayBlock(entry *IndexEntry, values *tsdb.FloatArray) error {
+	b, err := m.block(entry)
+	if err != nil {
+		return err
+	}
+	return DecodeFloatArrayBlock(b, values)
+}
+
+func (m *coldAccessor) readIntegerBlock(entry *IndexEntry, values *[]IntegerValue) ([]IntegerValue, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeIntegerBlock(b, values)
+}
+
+func (m *coldAccessor) readIntegerArrayBlock(entry *IndexEntry, values *tsdb.IntegerArray) error {
+	b, err := m.block(entry)
+	if err != nil {
+		return err
+	}
+	return DecodeIntegerArrayBlock(b, values)
+}
+
+func (m *coldAccessor) readUnsignedBlock(entry *IndexEntry, values *[]UnsignedValue) ([]UnsignedValue, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeUnsignedBlock(b, values)
+}
+
+func (m *coldAccessor) readUnsignedArrayBlock(entry *IndexEntry, values *tsdb.UnsignedArray) error {
+	b, err := m.block(entry)
+	if err != nil {
+		return err
+	}
+	return DecodeUnsignedArrayBlock(b, values)
+}
+
+func (m *coldAccessor) readStringBlock(entry *IndexEntry, values *[]StringValue) ([]StringValue, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeStringBlock(b, values)
+}
+
+func (m *coldAccessor) readStringArrayBlock(entry *IndexEntry, values *tsdb.StringArray) error {
+	b, err := m.block(entry)
+	if err != nil {
+		return err
+	}
+	return DecodeStringArrayBlock(b, values)
+}
+
+func (m *coldAccessor) readBooleanBlock(entry *IndexEntry, values *[]BooleanValue) ([]BooleanValue, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBooleanBlock(b, values)
+}
+
+func (m *coldAccessor) readBooleanArrayBlock(entry *IndexEntry, values *tsdb.BooleanArray) error {
+	b, err := m.block(entry)
+	if err != nil {
+		return err
+	}
+	return DecodeBooleanArrayBlock(b, values)
+}
@@ -23,6 +23,10 @@ type TSMReader struct {
 	madviseWillNeed bool // Hint to the kernel with MADV_WILLNEED.
 	mu              sync.RWMutex
 
+	// coldStore and blockCache are used to read files in cold storage.
+	coldStore  ColdStore
+	blockCache *BlockCache
+
 	// accessor provides access and decoding of blocks for the reader.
 	accessor blockAccessor
 
@@ -57,6 +61,15 @@ var WithTSMReaderLogger = func(logger *zap.Logger) tsmReaderOption {
 	}
 }
 
+// WithColdStore is an option for specifying the cold store and block cache used
+// to read files that have been moved to cold storage.
+var WithColdStore = func(store ColdStore, cache *BlockCache) tsmReaderOption {
+	return func(r *TSMReader) {
+		r.coldStore = store
+		r.blockCache = cache
+	}
+}
+
 // NewTSMReader returns a new TSMReader from the given file.
 func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	t := &TSMReader{
@@ -72,10 +85,27 @@ func NewTSMReader(f *os.File, options ...tsmReaderOption) (*TSMReader, error) {
 	}
 	t.size = stat.Size()
 	t.lastModified = stat.ModTime().UnixNano()
-	t.accessor = &mmapAccessor{
-		logger:       t.logger,
-		f:            f,
-		mmapWillNeed: t.madviseWillNeed,
+
+	cold, err := isColdStub(f)
+	if err != nil {
+		return nil, err
+	} else if cold && t.coldStore == nil {
+		return nil, ErrColdStoreNotConfigured
+	}
+
+	if cold {
+		t.accessor = &coldAccessor{
+			logger: t.logger,
+			store:  t.coldStore,
+			cache:  t.blockCache,
+			f:      f,
+		}
+	} else {
+		t.accessor = &mmapAccessor{
+			logger:       t.logger,
+			f:            f,
+			mmapWillNeed: t.madviseWillNeed,
+		}
 	}
 
 	index, err := t.accessor.init()
@@ -286,9 +316,21 @@ func (t *TSMReader) remove() error {
 	if err := t.tombstoner.Delete(); err != nil {
 		return err
 	}
+
+	// The object in cold storage is removed last, so the file is never left
+	// referring to a missing object.
+	if a, ok := t.accessor.(*coldAccessor); ok {
+		return a.remove()
+	}
 	return nil
 }
 
+// IsCold returns true if the file has been moved to cold storage.
+func (t *TSMReader) IsCold() bool {
+	_, ok := t.accessor.(*coldAccessor)
+	return ok
+}
+
 // Contains returns whether the given key is present in the index.
 func (t *TSMReader) Contains(key []byte) bool {
 	return t.index.Contains(key)
@@ -450,6 +492,15 @@ func (t *TSMReader) TombstoneRange(key []byte, buf []TimeRange) []TimeRange {
 	return tr
 }
 
+// BlockStats returns the statistics of the block identified by entry, if they
+// are stored in the file.
+func (t *TSMReader) BlockStats(entry *IndexEntry) (BlockStats, bool) {
+	t.mu.RLock()
+	s, ok := t.accessor.blockStats(entry)
+	t.mu.RUnlock()
+	return s, ok
+}
+
 // Stats returns the FileStat for the TSMReader's underlying file.
 func (t *TSMReader) Stats() FileStat {
 	minTime, maxTime := t.index.TimeRange()
@@ -463,6 +514,7 @@ func (t *TSMReader) Stats() FileStat {
 		MinKey:       minKey,
 		MaxKey:       maxKey,
 		HasTombstone: t.tombstoner.HasTombstones(),
+		Cold:         t.IsCold(),
 	}
 }
 
@@ -0,0 +1,387 @@
+package tsm1
+
+/*
+A TSM file moved to cold storage is replaced by a stub that keeps the index of
+the file on local disk, so that keys and time ranges can be looked up without
+accessing the cold store. The blocks are read from the object holding the
+complete original file, at the offsets recorded in the index.
+
+┌────────┬───────────────┬──────────────┬─────────────┐
+│ Header │  Object Name  │    Index     │   Footer    │
+│5 bytes │N + 2 bytes    │   N bytes    │   8 bytes   │
+└────────┴───────────────┴──────────────┴─────────────┘
+
+The header is composed of the cold magic number and the version of the TSM file
+format. The object name is prefixed with its length, and the footer holds the
+offset of the start of the index within the stub.
+*/
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/influxdata/influxdb/pkg/fs"
+	"go.uber.org/zap"
+)
+
+// ColdMagicNumber is written as the first 4 bytes of a stub of a TSM file that
+// has been moved to cold storage.
+const ColdMagicNumber uint32 = 0x16D1C01D
+
+// ErrColdStoreNotConfigured is returned when opening a stub of a TSM file in
+// cold storage without a cold store.
+var ErrColdStoreNotConfigured = fmt.Errorf("tsm file is in cold storage but no cold store is configured")
+
+// isColdStub returns true if the file is a stub of a TSM file in cold storage.
+func isColdStub(f *os.File) (bool, error) {
+	var b [4]byte
+	if _, err := f.ReadAt(b[:], 0); err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("init: error reading magic number of file: %v", err)
+	}
+	return binary.BigEndian.Uint32(b[:]) == ColdMagicNumber, nil
+}
+
+// writeColdStub writes a stub for the TSM file stored as the named object to
+// path. index holds the index section of the TSM file.
+func writeColdStub(path, name string, index []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_EXCL, 0666)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, 7+len(name)+len(index)+8)
+	buf = append(buf, 0, 0, 0, 0, Version, 0, 0)
+	binary.BigEndian.PutUint32(buf[0:4], ColdMagicNumber)
+	binary.BigEndian.PutUint16(buf[5:7], uint16(len(name)))
+	buf = append(buf, name...)
+	indexStart := uint64(len(buf))
+	buf = append(buf, index...)
+	buf = append(buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], indexStart)
+
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// BlockCache caches blocks read from a cold store, evicting the least recently
+// used blocks once the cached blocks exceed its size. It is shared by all
+// files in cold storage. A nil BlockCache caches nothing.
+type BlockCache struct {
+	mu      sync.Mutex
+	maxSize int64
+	size    int64
+	ll      *list.List
+	items   map[blockCacheKey]*list.Element
+}
+
+type blockCacheKey struct {
+	name   string
+	offset int64
+}
+
+type blockCacheEntry struct {
+	key blockCacheKey
+	b   []byte
+}
+
+// NewBlockCache returns a new BlockCache holding up to maxSize bytes of blocks.
+func NewBlockCache(maxSize int64) *BlockCache {
+	return &BlockCache{
+		maxSize: maxSize,
+		ll:      list.New(),
+		items:   make(map[blockCacheKey]*list.Element),
+	}
+}
+
+// Size returns the number of bytes of cached blocks.
+func (c *BlockCache) Size() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// get returns the block of the named object at the offset, if cached.
+func (c *BlockCache) get(name string, offset int64) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[blockCacheKey{name: name, offset: offset}]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(e)
+	return e.Value.(*blockCacheEntry).b, true
+}
+
+// add caches the block of the named object at the offset. Cached blocks must
+// not be modified.
+func (c *BlockCache) add(name string, offset int64, b []byte) {
+	if c == nil || int64(len(b)) > c.maxSize {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := blockCacheKey{name: name, offset: offset}
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.ll.PushFront(&blockCacheEntry{key: key, b: b})
+	c.size += int64(len(b))
+
+	for c.size > c.maxSize {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+// evict removes all cached blocks of the named object.
+func (c *BlockCache) evict(name string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for e := c.ll.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*blockCacheEntry).key.name == name {
+			c.removeElement(e)
+		}
+		e = next
+	}
+}
+
+func (c *BlockCache) removeElement(e *list.Element) {
+	entry := c.ll.Remove(e).(*blockCacheEntry)
+	delete(c.items, entry.key)
+	c.size -= int64(len(entry.b))
+}
+
+// coldAccessor is a block accessor for TSM files in cold storage. The index is
+// read from the mmapped stub while blocks are read from the cold store, through
+// the block cache.
+type coldAccessor struct {
+	logger *zap.Logger
+	store  ColdStore
+	cache  *BlockCache
+
+	mu    sync.RWMutex
+	b     []byte
+	f     *os.File
+	_path string // If the underlying file is renamed then this gets updated
+	name  string // Name of the object holding the TSM file
+
+	index *indirectIndex
+}
+
+func (m *coldAccessor) init() (*indirectIndex, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m._path = m.f.Name()
+
+	stat, err := m.f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	m.b, err = mmap(m.f, 0, int(stat.Size()))
+	if err != nil {
+		return nil, err
+	}
+	if len(m.b) < 15 || m.b[4] != Version {
+		return nil, fmt.Errorf("coldAccessor: invalid stub")
+	}
+
+	n := int(binary.BigEndian.Uint16(m.b[5:7]))
+	indexOfsPos := len(m.b) - 8
+	if 7+n > indexOfsPos {
+		return nil, fmt.Errorf("coldAccessor: invalid object name")
+	}
+	m.name = string(m.b[7 : 7+n])
+
+	indexStart := binary.BigEndian.Uint64(m.b[indexOfsPos : indexOfsPos+8])
+	if indexStart != uint64(7+n) {
+		return nil, fmt.Errorf("coldAccessor: invalid indexStart")
+	}
+
+	m.index = NewIndirectIndex()
+	if err := m.index.UnmarshalBinary(m.b[indexStart:indexOfsPos]); err != nil {
+		return nil, err
+	}
+	m.index.logger = m.logger
+
+	return m.index, nil
+}
+
+// free is a no-op as only the index of the file is mapped into memory.
+func (m *coldAccessor) free() error { return nil }
+
+func (m *coldAccessor) rename(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := fs.RenameFileWithReplacement(m._path, path); err != nil {
+		return err
+	}
+	m._path = path
+	return nil
+}
+
+// block returns the data of the block of the entry, without its checksum.
+func (m *coldAccessor) block(entry *IndexEntry) ([]byte, error) {
+	m.mu.RLock()
+	closed := m.b == nil
+	m.mu.RUnlock()
+	if closed {
+		return nil, ErrTSMClosed
+	}
+
+	if b, ok := m.cache.get(m.name, entry.Offset); ok {
+		return b, nil
+	}
+
+	b := make([]byte, entry.Size)
+	if err := m.store.ReadAt(context.Background(), m.name, b, entry.Offset); err != nil {
+		return nil, fmt.Errorf("cold storage: %s: %v", m.name, err)
+	}
+	if len(b) < crc32.Size || binary.BigEndian.Uint32(b[:crc32.Size]) != crc32.ChecksumIEEE(b[crc32.Size:]) {
+		return nil, fmt.Errorf("cold storage: %s: block checksum mismatch at offset %d", m.name, entry.Offset)
+	}
+
+	b = b[crc32.Size:]
+	m.cache.add(m.name, entry.Offset, b)
+	return b, nil
+}
+
+func (m *coldAccessor) read(key []byte, timestamp int64) ([]Value, error) {
+	entry := m.index.Entry(key, timestamp)
+	if entry == nil {
+		return nil, nil
+	}
+
+	return m.readBlock(entry, nil)
+}
+
+func (m *coldAccessor) readBlock(entry *IndexEntry, values []Value) ([]Value, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBlock(b, values)
+}
+
+func (m *coldAccessor) readBytes(entry *IndexEntry, _ []byte) (uint32, []byte, error) {
+	b, err := m.block(entry)
+	if err != nil {
+		return 0, nil, err
+	}
+	return crc32.ChecksumIEEE(b), b, nil
+}
+
+// blockStats always returns false, as stubs do not keep the block statistics of
+// the file.
+func (m *coldAccessor) blockStats(entry *IndexEntry) (BlockStats, bool) {
+	return BlockStats{}, false
+}
+
+// readAll returns all values for a key in all blocks.
+func (m *coldAccessor) readAll(key []byte) ([]Value, error) {
+	blocks, err := m.index.ReadEntries(key, nil)
+	if len(blocks) == 0 || err != nil {
+		return nil, err
+	}
+
+	tombstones := m.index.TombstoneRange(key, nil)
+
+	var temp []Value
+	var values []Value
+	for i := range blocks {
+		var skip bool
+		for _, t := range tombstones {
+			// Should we skip this block because it contains points that have been deleted
+			if t.Min <= blocks[i].MinTime && t.Max >= blocks[i].MaxTime {
+				skip = true
+				break
+			}
+		}
+
+		if skip {
+			continue
+		}
+
+		temp, err = m.readBlock(&blocks[i], temp[:0])
+		if err != nil {
+			return nil, err
+		}
+
+		// Filter out any values that were deleted
+		for _, t := range tombstones {
+			temp = Values(temp).Exclude(t.Min, t.Max)
+		}
+
+		values = append(values, temp...)
+	}
+
+	return values, nil
+}
+
+func (m *coldAccessor) path() string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m._path
+}
+
+func (m *coldAccessor) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.b == nil {
+		return nil
+	}
+
+	err := munmap(m.b)
+	if err != nil {
+		return err
+	}
+
+	m.b = nil
+	return m.f.Close()
+}
+
+// remove deletes the object holding the TSM file from the cold store.
+func (m *coldAccessor) remove() error {
+	m.cache.evict(m.name)
+	if err := m.store.Delete(context.Background(), m.name); err != nil {
+		return fmt.Errorf("cold storage: %s: %v", m.name, err)
+	}
+	return nil
+}
+
+// coldObjectName returns the name of the object a TSM file is stored as in
+// cold storage.
+func coldObjectName(path string) string {
+	return filepath.Base(path)
+}
@@ -0,0 +1,45 @@
+package tsm1
+
+import (
+	"testing"
+)
+
+func TestBlockCache(t *testing.T) {
+	c := NewBlockCache(10)
+	c.add("a", 0, make([]byte, 4))
+	c.add("a", 4, make([]byte, 4))
+
+	// Reading the first block makes the second the least recently used.
+	if _, ok := c.get("a", 0); !ok {
+		t.Fatal("expected block to be cached")
+	}
+	c.add("b", 0, make([]byte, 4))
+
+	if _, ok := c.get("a", 4); ok {
+		t.Fatal("expected least recently used block to be evicted")
+	} else if _, ok := c.get("a", 0); !ok {
+		t.Fatal("expected block to be cached")
+	} else if got, exp := c.Size(), int64(8); got != exp {
+		t.Fatalf("got size %d, expected %d", got, exp)
+	}
+
+	// Blocks larger than the cache are not cached.
+	c.add("c", 0, make([]byte, 11))
+	if _, ok := c.get("c", 0); ok {
+		t.Fatal("expected block not to be cached")
+	}
+
+	c.evict("a")
+	if _, ok := c.get("a", 0); ok {
+		t.Fatal("expected block to be evicted")
+	} else if got, exp := c.Size(), int64(4); got != exp {
+		t.Fatalf("got size %d, expected %d", got, exp)
+	}
+
+	// A nil cache caches nothing.
+	var nc *BlockCache
+	nc.add("a", 0, make([]byte, 4))
+	if _, ok := nc.get("a", 0); ok {
+		t.Fatal("expected nil cache to be empty")
+	}
+}
@@ -26,6 +26,7 @@ type mmapAccessor struct {
 	_path string // If the underlying file is renamed then this gets updated
 
 	index *indirectIndex
+	stats blockStatsSection
 }
 
 func (m *mmapAccessor) init() (*indirectIndex, error) {
@@ -73,6 +74,8 @@ func (m *mmapAccessor) init() (*indirectIndex, error) {
 		return nil, fmt.Errorf("mmapAccessor: invalid indexStart")
 	}
 
+	m.stats = readBlockStatsSection(m.b, int(indexStart))
+
 	m.index = NewIndirectIndex()
 	if err := m.index.UnmarshalBinary(m.b[indexStart:indexOfsPos]); err != nil {
 		return nil, err
@@ -174,6 +177,17 @@ func (m *mmapAccessor) readBytes(entry *IndexEntry, b []byte) (uint32, []byte, e
 	return crc, block, nil
 }
 
+func (m *mmapAccessor) blockStats(entry *IndexEntry) (BlockStats, bool) {
+	m.incAccess()
+
+	m.mu.RLock()
+	s, ok := m.stats.find(m.b, entry.Offset)
+	m.mu.RUnlock()
+
+	s.MinTime, s.MaxTime = entry.MinTime, entry.MaxTime
+	return s, ok
+}
+
 // readAll returns all values for a key in all blocks.
 func (m *mmapAccessor) readAll(key []byte) ([]Value, error) {
 	m.incAccess()
@@ -31,6 +31,7 @@ type Report struct {
 	Pattern         string       // Providing "01.tsm" for example would filter for level 1 files.
 	Detailed        bool         // Detailed will segment cardinality by tag keys.
 	Exact           bool         // Exact determines if estimation or exact methods are used to determine cardinality.
+	Compression     bool         // Compression reads all blocks to report compression ratios by codec.
 }
 
 // ReportSummary provides a summary of the cardinalities in the processed fileset.
@@ -44,6 +45,43 @@ type ReportSummary struct {
 	Measurements map[string]uint64 // The exact or estimated unique set of series keys segmented by the measurement tag.
 	FieldKeys    map[string]uint64 // The exact or estimated unique set of series keys segmented by the field tag.
 	TagKeys      map[string]uint64 // The exact or estimated unique set of series keys segmented by tag keys.
+
+	// Calculated when the compression flag is in use.
+	Compression map[string]*CompressionStats // Compression of blocks segmented by codec and block type, e.g. "flate/string".
+}
+
+// CompressionStats summarizes the compression of a set of blocks.
+type CompressionStats struct {
+	Blocks  uint64
+	Values  uint64
+	Size    uint64 // The size of the blocks.
+	RawSize uint64 // The size of the timestamps and values of the blocks.
+}
+
+// Ratio returns the ratio of the uncompressed to compressed size of the blocks.
+func (s *CompressionStats) Ratio() float64 {
+	if s.Size == 0 {
+		return 0
+	}
+	return float64(s.RawSize) / float64(s.Size)
+}
+
+// add adds the decoded values of a block of size bytes to the stats.
+func (s *CompressionStats) add(size int, values []Value) {
+	s.Blocks++
+	s.Values += uint64(len(values))
+	s.Size += uint64(size)
+	for _, v := range values {
+		// Each value has an 8 byte timestamp.
+		switch v := v.(type) {
+		case StringValue:
+			s.RawSize += 8 + uint64(len(v.RawValue()))
+		case BooleanValue:
+			s.RawSize += 8 + 1
+		default:
+			s.RawSize += 8 + 8
+		}
+	}
 }
 
 func newReportSummary() *ReportSummary {
@@ -53,6 +91,7 @@ func newReportSummary() *ReportSummary {
 		Measurements:  map[string]uint64{},
 		FieldKeys:     map[string]uint64{},
 		TagKeys:       map[string]uint64{},
+		Compression:   map[string]*CompressionStats{},
 	}
 }
 
@@ -95,6 +134,9 @@ func (r *Report) Run(print bool) (*ReportSummary, error) {
 	fCardinalities := map[string]counter{} // The exact or estimated unique set of series keys segmented by the field tag.
 	tCardinalities := map[string]counter{} // The exact or estimated unique set of series keys segmented by tag keys.
 
+	// Calculated when the compression flag is in use.
+	compression := map[string]*CompressionStats{} // Compression of blocks segmented by codec and block type.
+
 	start := time.Now()
 
 	tw := tabwriter.NewWriter(r.Stdout, 8, 2, 1, ' ', 0)
@@ -208,6 +250,13 @@ func (r *Report) Run(print bool) (*ReportSummary, error) {
 			}
 		}
 
+		if r.Compression {
+			if err := r.addCompression(reader, compression); err != nil {
+				fmt.Fprintf(r.Stderr, "error: %s: %v. Exiting.\n", path, err)
+				return nil, err
+			}
+		}
+
 		minT, maxT := reader.TimeRange()
 		if minT < minTime {
 			minTime = minT
@@ -296,6 +345,21 @@ func (r *Report) Run(print bool) (*ReportSummary, error) {
 		}
 	}
 
+	if r.Compression {
+		keys := make([]string, 0, len(compression))
+		for k := range compression {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Printf("\n  Compression By Codec (%d):\n", len(compression))
+		for _, k := range keys {
+			stats := compression[k]
+			summary.Compression[k] = stats
+			fmt.Printf("    - %v: %d blocks, %d values, %d bytes (ratio %.2f)\n", k, stats.Blocks, stats.Values, stats.Size, stats.Ratio())
+		}
+	}
+
 	fmt.Printf("\nCompleted in %s\n", time.Since(start))
 	return summary, nil
 }
@@ -339,3 +403,36 @@ func newExactCounter() counter {
 		m: make(map[string]struct{}),
 	}
 }
+
+// addCompression adds the compression of the blocks of the file, matching the
+// org and bucket filters, to stats.
+func (r *Report) addCompression(reader *TSMReader, stats map[string]*CompressionStats) error {
+	var values []Value
+	itr := reader.BlockIterator()
+	for itr.Next() {
+		key, _, _, typ, _, block, err := itr.Read()
+		if err != nil {
+			return err
+		}
+
+		var a [16]byte
+		copy(a[:], key[:16])
+		org, bucket := tsdb.DecodeName(a)
+		if r.OrgID != nil && *r.OrgID != org {
+			continue
+		} else if r.BucketID != nil && *r.BucketID != bucket {
+			continue
+		}
+
+		if values, err = DecodeBlock(block, values); err != nil {
+			return err
+		}
+
+		k := CodecName(BlockCodec(block)) + "/" + BlockTypeName(typ)
+		if stats[k] == nil {
+			stats[k] = &CompressionStats{}
+		}
+		stats[k].add(len(block), values)
+	}
+	return itr.Err()
+}
@@ -78,7 +78,9 @@ func (r *ring) entry(key []byte) *entry {
 // If no entry exists for the key then one will be created.
 // write is safe for use by multiple goroutines.
 func (r *ring) write(key []byte, values Values) (bool, error) {
-	return r.getPartition(key).write(key, values)
+	p := r.getPartition(key)
+	p.touch()
+	return p.write(key, values)
 }
 
 // add adds an entry to the ring.
@@ -198,6 +200,8 @@ func (r *ring) split(n int) []*ring {
 
 // partition provides safe access to a map of series keys to entries.
 type partition struct {
+	lastWriteNs int64 // Must always be accessed via atomic.
+
 	mu    sync.RWMutex
 	store map[string]*entry
 }
@@ -215,19 +219,22 @@ func (p *partition) entry(key []byte) *entry {
 // if it does not exist.
 // write is safe for use by multiple goroutines.
 func (p *partition) write(key []byte, values Values) (bool, error) {
+	// The partition stays locked while adding values, so that they are not added
+	// to an entry of a store being spilled to disk.
 	p.mu.RLock()
-	e := p.store[string(key)]
-	p.mu.RUnlock()
-	if e != nil {
+	if e := p.store[string(key)]; e != nil {
 		// Hot path.
-		return false, e.add(values)
+		err := e.add(values)
+		p.mu.RUnlock()
+		return false, err
 	}
+	p.mu.RUnlock()
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	// Check again.
-	if e = p.store[string(key)]; e != nil {
+	if e := p.store[string(key)]; e != nil {
 		return false, e.add(values)
 	}
 
@@ -12,10 +12,14 @@ import (
 	"github.com/golang/snappy"
 )
 
-// Note: an uncompressed format is not yet implemented.
+const (
+	// stringUncompressed is an uncompressed encoding, used for blocks
+	// compressed by a codec other than the default.
+	stringUncompressed = 0
 
-// stringCompressedSnappy is a compressed encoding using Snappy compression
-const stringCompressedSnappy = 1
+	// stringCompressedSnappy is a compressed encoding using Snappy compression
+	stringCompressedSnappy = 1
+)
 
 // StringEncoder encodes multiple strings into a byte slice.
 type StringEncoder struct {
@@ -68,10 +72,11 @@ type StringDecoder struct {
 // SetBytes initializes the decoder with bytes to read from.
 // This must be called before calling any other method.
 func (e *StringDecoder) SetBytes(b []byte) error {
-	// First byte stores the encoding type, only have snappy format
-	// currently so ignore for now.
+	// First byte stores the encoding type.
 	var data []byte
-	if len(b) > 0 {
+	if len(b) > 0 && b[0]>>4 == stringUncompressed {
+		data = b[1:]
+	} else if len(b) > 0 {
 		var err error
 		data, err = snappy.Decode(nil, b[1:])
 		if err != nil {
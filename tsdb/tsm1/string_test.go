@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/influxdata/influxdb/internal/testutil"
+	"github.com/influxdata/influxdb/tsdb"
 )
 
 func Test_StringEncoder_NoValues(t *testing.T) {
@@ -227,3 +228,56 @@ func BenchmarkStringDecoder_DecodeAll(b *testing.B) {
 		})
 	}
 }
+
+func Test_StringBlock_Transcode(t *testing.T) {
+	values := []Value{NewValue(1, "foo"), NewValue(2, "bar"), NewValue(3, "foo")}
+	block, err := Values(values).Encode(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flate, _ := CodecByName(FlateCodec)
+	compressed, err := transcodeBlock(block, flate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := BlockCodec(compressed); got != flate {
+		t.Fatalf("unexpected codec: got %d, exp %d", got, flate)
+	}
+
+	// The strings are stored uncompressed in the value bytes decompressed by
+	// the codec.
+	decompressed, err := decodeBlockCodec(compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, vb, err := unpackBlock(decompressed[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := vb[0] >> 4; got != stringUncompressed {
+		t.Fatalf("unexpected string encoding: got %d, exp %d", got, stringUncompressed)
+	}
+
+	var a []StringValue
+	if got, err := DecodeStringBlock(compressed, &a); err != nil {
+		t.Fatal(err)
+	} else if exp := []StringValue{values[0].(StringValue), values[1].(StringValue), values[2].(StringValue)}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", got, exp)
+	}
+
+	// Transcoding back to the default codec restores the original values.
+	restored, err := transcodeBlock(compressed, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := BlockCodec(restored); got != 0 {
+		t.Fatalf("unexpected codec: got %d, exp 0", got)
+	}
+	var arr tsdb.StringArray
+	if err := DecodeStringArrayBlock(restored, &arr); err != nil {
+		t.Fatal(err)
+	} else if exp := []string{"foo", "bar", "foo"}; !reflect.DeepEqual(arr.Values, exp) {
+		t.Fatalf("unexpected values: got %v, exp %v", arr.Values, exp)
+	}
+}
@@ -101,3 +101,73 @@ func (v *VerifyTSM) processFile(path string) error {
 
 	return nil
 }
+
+// CorruptBlockError is returned by VerifyBlocks for a corrupt block of a TSM
+// file.
+type CorruptBlockError struct {
+	Key []byte // Key of the block, if the index could be read.
+	Err error
+}
+
+func (e *CorruptBlockError) Error() string {
+	if e.Key == nil {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("corrupt block for key %q: %v", e.Key, e.Err)
+}
+
+// VerifyBlocks reads every block of the TSM file r and verifies its checksum
+// and that its timestamps decode and match the time range of its index entry.
+// It is safe to call while the file is in use. wait is called with the size of
+// each block before the block is read, to throttle reads, and verification
+// stops with the first error it returns. A *CorruptBlockError is returned for
+// the first corrupt block found.
+func VerifyBlocks(r *TSMReader, wait func(n int) error) (err error) {
+	var key []byte
+
+	// A corrupt index can make the reader index out of range.
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = &CorruptBlockError{Key: key, Err: fmt.Errorf("panic reading block: %v", rec)}
+		}
+	}()
+
+	var ts cursors.TimestampArray
+	iter := r.Iterator(nil)
+	for iter.Next() {
+		key = append(key[:0], iter.Key()...)
+
+		entries := iter.Entries()
+		for i := range entries {
+			entry := &entries[i]
+			if err := wait(int(entry.Size)); err != nil {
+				return err
+			}
+
+			checksum, buf, err := r.ReadBytes(entry, nil)
+			if err != nil {
+				return &CorruptBlockError{Key: key, Err: err}
+			}
+			if exp := crc32.ChecksumIEEE(buf); checksum != exp {
+				return &CorruptBlockError{Key: key, Err: fmt.Errorf("unexpected checksum %d, expected %d", checksum, exp)}
+			}
+
+			if err := DecodeTimestampArrayBlock(buf, &ts); err != nil {
+				return &CorruptBlockError{Key: key, Err: fmt.Errorf("unable to decode timestamps: %v", err)}
+			} else if ts.Len() == 0 {
+				return &CorruptBlockError{Key: key, Err: fmt.Errorf("block without timestamps")}
+			}
+			if got, exp := ts.MinTime(), entry.MinTime; got != exp {
+				return &CorruptBlockError{Key: key, Err: fmt.Errorf("unexpected min time %d, expected %d", got, exp)}
+			}
+			if got, exp := ts.MaxTime(), entry.MaxTime; got != exp {
+				return &CorruptBlockError{Key: key, Err: fmt.Errorf("unexpected max time %d, expected %d", got, exp)}
+			}
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return &CorruptBlockError{Err: err}
+	}
+	return nil
+}
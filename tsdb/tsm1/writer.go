@@ -31,6 +31,9 @@ is stored in the index.
 │ 4 bytes │ N bytes │ 4 bytes │ N bytes │ 4 bytes │ N bytes │
 └─────────┴─────────┴─────────┴─────────┴─────────┴─────────┘
 
+The blocks may be followed by the statistics of the float, integer and unsigned
+blocks, as described in block_stats.go.
+
 Following the blocks is the index for the blocks in the file.  The index is
 composed of a sequence of index entries ordered lexicographically by key and
 then by time.  Each index entry starts with a key length and key followed by a
@@ -593,22 +596,43 @@ type tsmWriter struct {
 	lastSync int64
 
 	stats MeasurementStats
+
+	// blockStats, when set, accumulates the statistics of the blocks written.
+	blockStats *blockStatsWriter
+}
+
+type tsmWriterOption func(*tsmWriter)
+
+// WithBlockStats is an option for specifying whether the statistics of float,
+// integer and unsigned blocks are stored in the file.
+var WithBlockStats = func(enabled bool) tsmWriterOption {
+	return func(t *tsmWriter) {
+		if enabled {
+			t.blockStats = &blockStatsWriter{}
+		} else {
+			t.blockStats = nil
+		}
+	}
 }
 
 // NewTSMWriter returns a new TSMWriter writing to w.
-func NewTSMWriter(w io.Writer) (TSMWriter, error) {
+func NewTSMWriter(w io.Writer, options ...tsmWriterOption) (TSMWriter, error) {
 	index := NewIndexWriter()
-	return &tsmWriter{
+	t := &tsmWriter{
 		wrapped: w,
 		w:       bufio.NewWriterSize(w, 1024*1024),
 		index:   index,
 		stats:   NewMeasurementStats(),
-	}, nil
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t, nil
 }
 
 // NewTSMWriterWithDiskBuffer returns a new TSMWriter writing to w and will use a disk
 // based buffer for the TSM index if possible.
-func NewTSMWriterWithDiskBuffer(w io.Writer) (TSMWriter, error) {
+func NewTSMWriterWithDiskBuffer(w io.Writer, options ...tsmWriterOption) (TSMWriter, error) {
 	var index IndexWriter
 	// Make sure is a File so we can write the temp index alongside it.
 	if fw, ok := w.(syncer); ok {
@@ -622,12 +646,16 @@ func NewTSMWriterWithDiskBuffer(w io.Writer) (TSMWriter, error) {
 		index = NewIndexWriter()
 	}
 
-	return &tsmWriter{
+	t := &tsmWriter{
 		wrapped: w,
 		w:       bufio.NewWriterSize(w, 1024*1024),
 		index:   index,
 		stats:   NewMeasurementStats(),
-	}, nil
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t, nil
 }
 
 // MeasurementStats returns the measurement statistics generated by the writer.
@@ -690,6 +718,9 @@ func (t *tsmWriter) Write(key []byte, values Values) error {
 
 	// Record this block in index
 	t.index.Add(key, blockType, values[0].UnixNano(), values[len(values)-1].UnixNano(), t.n, uint32(n))
+	if t.blockStats != nil {
+		t.blockStats.addValues(t.n, blockType, values)
+	}
 
 	// Add block size to measurement stats.
 	name := models.ParseName(key)
@@ -746,6 +777,11 @@ func (t *tsmWriter) WriteBlock(key []byte, minTime, maxTime int64, block []byte)
 
 	// Record this block in index
 	t.index.Add(key, blockType, minTime, maxTime, t.n, uint32(n))
+	if t.blockStats != nil {
+		if err := t.blockStats.addBlock(t.n, blockType, block); err != nil {
+			return err
+		}
+	}
 
 	// Add block size to measurement stats.
 	name := models.ParseName(key)
@@ -772,12 +808,21 @@ func (t *tsmWriter) WriteBlock(key []byte, minTime, maxTime int64, block []byte)
 // WriteIndex writes the index section of the file.  If there are no index entries to write,
 // this returns ErrNoValues.
 func (t *tsmWriter) WriteIndex() error {
-	indexPos := t.n
-
 	if t.index.KeyCount() == 0 {
 		return ErrNoValues
 	}
 
+	// Write the block statistics preceding the index
+	if t.blockStats != nil {
+		n, err := t.blockStats.WriteTo(t.w)
+		if err != nil {
+			return err
+		}
+		t.n += n
+		t.blockStats.buf = t.blockStats.buf[:0]
+	}
+	indexPos := t.n
+
 	// Set the destination file on the index so we can periodically
 	// fsync while writing the index.
 	if f, ok := t.wrapped.(syncer); ok {
@@ -889,7 +934,11 @@ func (t *tsmWriter) Remove() error {
 }
 
 func (t *tsmWriter) Size() uint32 {
-	return uint32(t.n) + t.index.Size()
+	size := uint32(t.n) + t.index.Size()
+	if t.blockStats != nil {
+		size += uint32(t.blockStats.Size())
+	}
+	return size
 }
 
 // verifyVersion verifies that the reader's bytes are a TSM byte
@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/influxdata/influxdb/tsdb/cursors"
 	"github.com/influxdata/influxdb/tsdb/tsm1"
 )
 
@@ -645,3 +646,107 @@ func TestTSMWriter_Sync(t *testing.T) {
 		t.Fatal("failed to sync")
 	}
 }
+
+func TestTSMWriter_BlockStats(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+	f := MustTempFile(dir)
+
+	w, err := tsm1.NewTSMWriter(f, tsm1.WithBlockStats(true))
+	if err != nil {
+		t.Fatalf("unexpected error creating writer: %v", err)
+	}
+
+	floats := []tsm1.Value{tsm1.NewValue(0, 1.5), tsm1.NewValue(1, -2.0), tsm1.NewValue(2, 4.0)}
+	if err := w.Write([]byte("cpu"), floats); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	// Blocks written as encoded bytes are decoded to compute their statistics.
+	integers := []tsm1.Value{tsm1.NewValue(3, int64(7)), tsm1.NewValue(4, int64(-3))}
+	block, err := tsm1.Values(integers).Encode(nil)
+	if err != nil {
+		t.Fatalf("unexpected error encoding: %v", err)
+	}
+	if err := w.WriteBlock([]byte("mem"), 3, 4, block); err != nil {
+		t.Fatalf("unexpected error writing block: %v", err)
+	}
+
+	if err := w.Write([]byte("str"), []tsm1.Value{tsm1.NewValue(5, "a")}); err != nil {
+		t.Fatalf("unexpected error writing: %v", err)
+	}
+
+	if err := w.WriteIndex(); err != nil {
+		t.Fatalf("unexpected error writing index: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error closing: %v", err)
+	}
+
+	r := MustOpenTSMReader(f.Name())
+	defer r.Close()
+
+	entry := func(key string) *tsm1.IndexEntry {
+		t.Helper()
+		entries, err := r.ReadEntries([]byte(key), nil)
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("unexpected entries for %s: %v %v", key, entries, err)
+		}
+		return &entries[0]
+	}
+
+	bs, ok := r.BlockStats(entry("cpu"))
+	if !ok {
+		t.Fatal("expected float block stats")
+	}
+	fs, ok := bs.Float()
+	if !ok {
+		t.Fatal("expected float block")
+	} else if exp := (cursors.FloatBlockStats{MinTime: 0, MaxTime: 2, Count: 3, Sum: 3.5, Min: -2, Max: 4}); fs != exp {
+		t.Fatalf("unexpected float stats: got %+v, exp %+v", fs, exp)
+	}
+	if _, ok := bs.Integer(); ok {
+		t.Fatal("unexpected integer stats of float block")
+	}
+
+	bs, ok = r.BlockStats(entry("mem"))
+	if !ok {
+		t.Fatal("expected integer block stats")
+	}
+	is, _ := bs.Integer()
+	if exp := (cursors.IntegerBlockStats{MinTime: 3, MaxTime: 4, Count: 2, Sum: 4, Min: -3, Max: 7}); is != exp {
+		t.Fatalf("unexpected integer stats: got %+v, exp %+v", is, exp)
+	}
+
+	if _, ok := r.BlockStats(entry("str")); ok {
+		t.Fatal("unexpected stats of string block")
+	}
+
+	// The values are read as usual.
+	values, err := r.ReadAll([]byte("cpu"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(values) != len(floats) {
+		t.Fatalf("unexpected values: got %v, exp %v", values, floats)
+	}
+	for i := range values {
+		if values[i].String() != floats[i].String() {
+			t.Fatalf("unexpected value %d: got %v, exp %v", i, values[i], floats[i])
+		}
+	}
+}
+
+func TestTSMWriter_NoBlockStats(t *testing.T) {
+	dir := MustTempDir()
+	defer os.RemoveAll(dir)
+
+	r := MustTSMReader(dir, 1, map[string][]tsm1.Value{"cpu": {tsm1.NewValue(0, 1.0)}})
+	defer r.Close()
+
+	entries, err := r.ReadEntries([]byte("cpu"), nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, ok := r.BlockStats(&entries[0]); ok {
+		t.Fatal("unexpected stats of file written without them")
+	}
+}
@@ -2,10 +2,67 @@ package influxdb
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sort"
 )
 
 // WriteService writes data read from the reader.
 type WriteService interface {
 	Write(ctx context.Context, org, bucket ID, r io.Reader) error
 }
+
+// Reasons for rejecting the lines of a write.
+const (
+	WriteRejectedParseError       = "parse_error"
+	WriteRejectedTypeConflict     = "type_conflict"
+	WriteRejectedCardinalityLimit = "cardinality_limit"
+	WriteRejectedOutOfRetention   = "out_of_retention"
+	WriteRejectedSchema           = "schema"
+	WriteRejectedInvalid          = "invalid"
+)
+
+// WriteRejection is the rejection of a line of a write.
+type WriteRejection struct {
+	Line    int    `json:"line"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// PartialWriteError is the error of a write of which some lines were rejected.
+// The other lines were written. A line is rejected if any of its fields was
+// not written.
+type PartialWriteError struct {
+	Code       string            `json:"code"`
+	Message    string            `json:"message"`
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Rejections []*WriteRejection `json:"rejections"`
+}
+
+// NewPartialWriteError returns the error of a write of lines with the
+// rejections, which it orders by line.
+func NewPartialWriteError(lines int, rejections []*WriteRejection) *PartialWriteError {
+	sort.SliceStable(rejections, func(i, j int) bool {
+		return rejections[i].Line < rejections[j].Line
+	})
+
+	rejected := make(map[int]struct{}, len(rejections))
+	for _, r := range rejections {
+		rejected[r.Line] = struct{}{}
+	}
+
+	e := &PartialWriteError{
+		Code:       EInvalid,
+		Accepted:   lines - len(rejected),
+		Rejected:   len(rejected),
+		Rejections: rejections,
+	}
+	e.Message = fmt.Sprintf("partial write: %d of %d lines rejected", e.Rejected, lines)
+	return e
+}
+
+// Error implements the error interface.
+func (e *PartialWriteError) Error() string {
+	return e.Message
+}
@@ -80,7 +80,9 @@ func (b *Batcher) read(ctx context.Context, r io.Reader, lines chan<- []byte, er
 
 // finishes when the lines channel is closed or context is done.
 // if an error occurs while writing data to the write service, the error is send in the
-// errC channel and the function returns.
+// errC channel and the function returns. The lines rejected by the write service
+// are collected instead, and sent as a *platform.PartialWriteError once all the
+// batches are written.
 func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-chan []byte, errC chan<- error) {
 	flushInterval := b.MaxFlushInterval
 	if flushInterval == 0 {
@@ -98,6 +100,30 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 	buf := make([]byte, 0, maxBytes)
 	r := bytes.NewReader(buf)
 
+	var (
+		offset     int // the number of lines of the batches written
+		n          int // the number of lines in buf
+		total      int // the number of lines with points
+		rejections []*platform.WriteRejection
+	)
+	flush := func() error {
+		r.Reset(buf)
+		timer.Reset(flushInterval)
+		err := b.Service.Write(ctx, org, bucket, r)
+		if pwe, ok := err.(*platform.PartialWriteError); ok {
+			// The lines of the rejections are those of the batch.
+			for _, rejection := range pwe.Rejections {
+				rejection.Line += offset
+			}
+			rejections = append(rejections, pwe.Rejections...)
+			err = nil
+		}
+		buf = buf[:0]
+		offset += n
+		n = 0
+		return err
+	}
+
 	var line []byte
 	var more = true
 	// if read closes the channel normally, exit the loop
@@ -106,26 +132,24 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 		case line, more = <-lines:
 			if more {
 				buf = append(buf, line...)
+				n++
+				if hasPoints(line) {
+					total++
+				}
 			}
 			// write if we exceed the max lines OR read routine has finished
 			if len(buf) >= maxBytes || (!more && len(buf) > 0) {
-				r.Reset(buf)
-				timer.Reset(flushInterval)
-				if err := b.Service.Write(ctx, org, bucket, r); err != nil {
+				if err := flush(); err != nil {
 					errC <- err
 					return
 				}
-				buf = buf[:0]
 			}
 		case <-timer.C:
 			if len(buf) > 0 {
-				r.Reset(buf)
-				timer.Reset(flushInterval)
-				if err := b.Service.Write(ctx, org, bucket, r); err != nil {
+				if err := flush(); err != nil {
 					errC <- err
 					return
 				}
-				buf = buf[:0]
 			}
 		case <-ctx.Done():
 			errC <- ctx.Err()
@@ -133,9 +157,20 @@ func (b *Batcher) write(ctx context.Context, org, bucket platform.ID, lines <-ch
 		}
 	}
 
+	if len(rejections) > 0 {
+		errC <- platform.NewPartialWriteError(total, rejections)
+		return
+	}
 	errC <- nil
 }
 
+// hasPoints returns true if a line of line protocol is neither empty nor a
+// comment.
+func hasPoints(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	return len(line) > 0 && line[0] != '#'
+}
+
 // ScanLines is used in bufio.Scanner.Split to split lines of line protocol.
 func ScanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	if atEOF && len(data) == 0 {
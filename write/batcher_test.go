@@ -397,6 +397,45 @@ func TestBatcher_Write(t *testing.T) {
 	}
 }
 
+func TestBatcher_WritePartial(t *testing.T) {
+	// The second line of each batch is rejected.
+	svc := &mock.WriteService{
+		WriteF: func(ctx context.Context, org, bucket platform.ID, r io.Reader) error {
+			if _, err := ioutil.ReadAll(r); err != nil {
+				return err
+			}
+			return platform.NewPartialWriteError(2, []*platform.WriteRejection{
+				{Line: 2, Reason: platform.WriteRejectedParseError, Message: "bad"},
+			})
+		},
+	}
+
+	b := &Batcher{
+		MaxFlushBytes: len([]byte("m1 f1=1\nm2 f2=\n")),
+		Service:       svc,
+	}
+
+	err := b.Write(context.Background(), platform.ID(1), platform.ID(2), strings.NewReader("m1 f1=1\nm2 f2=\nm3 f3=3\nm4 f4=\n"))
+	pwe, ok := err.(*platform.PartialWriteError)
+	if !ok {
+		t.Fatalf("Batcher.Write() error = %v, want a partial write error", err)
+	}
+
+	want := &platform.PartialWriteError{
+		Code:     platform.EInvalid,
+		Message:  "partial write: 2 of 4 lines rejected",
+		Accepted: 2,
+		Rejected: 2,
+		Rejections: []*platform.WriteRejection{
+			{Line: 2, Reason: platform.WriteRejectedParseError, Message: "bad"},
+			{Line: 4, Reason: platform.WriteRejectedParseError, Message: "bad"},
+		},
+	}
+	if !cmp.Equal(pwe, want) {
+		t.Errorf("Batcher.Write() error -got/+want %s", cmp.Diff(pwe, want))
+	}
+}
+
 func TestBatcher_WriteTimeout(t *testing.T) {
 	// mocking the write service here to either return an error
 	// or get back all the bytes from the reader.
@@ -0,0 +1,350 @@
+package write
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The annotated CSV emitted by Flux is made of tables, each starting with
+// annotation rows followed by a header row. The annotation rows, such as
+// #datatype, #group and #default, have a value for each column.
+//
+// A row is converted to a point as follows:
+//   - _measurement, or a column of datatype measurement, is the measurement.
+//   - _time, or a column of datatype dateTime, is the timestamp.
+//   - _field and _value are the key and value of a field.
+//   - result, table, _start, _stop and columns of datatype ignored are ignored.
+//   - columns of datatype tag, and string columns of the group key, are tags.
+//   - other columns are fields, typed by their datatype.
+//
+// Columns without a datatype are strings. Empty values take the default of
+// their column, and fields and tags without a value are omitted.
+
+const (
+	csvAnnotationDatatype = "#datatype"
+	csvAnnotationGroup    = "#group"
+	csvAnnotationDefault  = "#default"
+)
+
+type csvColumnRole int
+
+const (
+	csvColumnIgnored csvColumnRole = iota
+	csvColumnMeasurement
+	csvColumnTime
+	csvColumnTag
+	csvColumnField
+	csvColumnFieldKey
+	csvColumnFieldValue
+)
+
+type csvColumn struct {
+	name     string
+	datatype string
+	def      string
+	role     csvColumnRole
+}
+
+// csvTable is the header of a table of annotated CSV.
+type csvTable struct {
+	columns []csvColumn
+	field   int // index of the _field column
+	value   int // index of the _value column
+}
+
+// newCSVTable returns the table of a header row and the annotations
+// preceding it.
+func newCSVTable(header []string, annotations map[string][]string) (*csvTable, error) {
+	annotation := func(name string, i int) string {
+		if values := annotations[name]; i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	t := &csvTable{field: -1, value: -1}
+	var measurement, timestamp bool
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		c := csvColumn{
+			name:     name,
+			datatype: annotation(csvAnnotationDatatype, i),
+			def:      annotation(csvAnnotationDefault, i),
+		}
+		group := annotation(csvAnnotationGroup, i) == "true"
+
+		// The datatype of the time is followed by its format, as in dateTime:RFC3339.
+		datatype := c.datatype
+		if i := strings.IndexByte(datatype, ':'); i != -1 {
+			datatype = datatype[:i]
+		}
+
+		switch {
+		case name == "" || datatype == "ignored" || name == "result" || name == "table" || name == "_start" || name == "_stop":
+			c.role = csvColumnIgnored
+		case name == "_measurement" || datatype == "measurement":
+			if measurement {
+				return nil, fmt.Errorf("duplicate measurement column %q", name)
+			}
+			measurement = true
+			c.role = csvColumnMeasurement
+		case name == "_time" || datatype == "dateTime":
+			if timestamp {
+				return nil, fmt.Errorf("duplicate time column %q", name)
+			}
+			timestamp = true
+			c.role = csvColumnTime
+		case name == "_field":
+			t.field = i
+			c.role = csvColumnFieldKey
+		case name == "_value":
+			t.value = i
+			c.role = csvColumnFieldValue
+		case datatype == "tag" || group && (datatype == "" || datatype == "string"):
+			c.role = csvColumnTag
+		default:
+			c.role = csvColumnField
+		}
+
+		if c.role == csvColumnField || c.role == csvColumnFieldValue {
+			switch datatype {
+			case "", "string", "double", "long", "unsignedLong", "boolean":
+			default:
+				return nil, fmt.Errorf("unsupported datatype %q of field column %q", c.datatype, name)
+			}
+		}
+		t.columns = append(t.columns, c)
+	}
+
+	if !measurement {
+		return nil, fmt.Errorf("missing measurement column")
+	} else if (t.field == -1) != (t.value == -1) {
+		return nil, fmt.Errorf("_field and _value columns must be used together")
+	}
+	return t, nil
+}
+
+// point returns the point of a row of the table.
+func (t *csvTable) point(row []string, precision string) (*point, error) {
+	if len(row) != len(t.columns) {
+		return nil, fmt.Errorf("expected %d columns, got %d", len(t.columns), len(row))
+	}
+
+	p := &point{}
+	for i, c := range t.columns {
+		v := row[i]
+		if v == "" {
+			v = c.def
+		}
+
+		switch c.role {
+		case csvColumnMeasurement:
+			p.measurement = v
+		case csvColumnTime:
+			if v == "" {
+				continue
+			}
+			ts, err := parseCSVTime(v, precision)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", c.name, err)
+			}
+			p.time = ts
+		case csvColumnTag:
+			if v != "" {
+				p.addTag(c.name, v)
+			}
+		case csvColumnField:
+			if v == "" {
+				continue
+			}
+			fv, err := formatCSVField(c.datatype, v)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", c.name, err)
+			}
+			p.addField(c.name, fv)
+		}
+	}
+
+	if t.value != -1 {
+		key, v := row[t.field], row[t.value]
+		if key == "" {
+			key = t.columns[t.field].def
+		}
+		if v == "" {
+			v = t.columns[t.value].def
+		}
+		if v != "" {
+			fv, err := formatCSVField(t.columns[t.value].datatype, v)
+			if err != nil {
+				return nil, fmt.Errorf("column %q: %v", "_value", err)
+			}
+			p.addField(key, fv)
+		}
+	}
+	return p, nil
+}
+
+func formatCSVField(datatype, v string) (string, error) {
+	switch datatype {
+	case "double":
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return "", err
+		}
+		return formatFloat(f), nil
+	case "long":
+		i, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return formatInteger(i), nil
+	case "unsignedLong":
+		u, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		return formatUnsigned(u), nil
+	case "boolean":
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return "", err
+		}
+		return formatBoolean(b), nil
+	default:
+		return formatString(v), nil
+	}
+}
+
+// parseCSVTime returns the timestamp of a time, either an integer in the
+// precision or an RFC3339 time.
+func parseCSVTime(v, precision string) (string, error) {
+	if _, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return v, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return "", err
+	}
+	return formatTime(t, precision)
+}
+
+// csvToLineProtocol converts annotated CSV to line protocol. Rows without
+// field values, such as those of null values, are skipped.
+func csvToLineProtocol(data []byte, precision string) (*Conversion, error) {
+	var (
+		c           = &Conversion{}
+		annotations = make(map[string][]string)
+		table       *csvTable
+		invalid     bool // the header of the table could not be used
+	)
+
+	records := newCSVRecords(data)
+	for {
+		line, record, err := records.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			c.Errors.add("line", line, err)
+			continue
+		}
+
+		// An empty line, or annotations following rows, start a new table.
+		if len(record) == 0 || (table != nil || invalid) && strings.HasPrefix(record[0], "#") {
+			annotations = make(map[string][]string)
+			table, invalid = nil, false
+			if len(record) == 0 {
+				continue
+			}
+		}
+
+		if strings.HasPrefix(record[0], "#") {
+			annotations[strings.TrimSpace(record[0])] = record
+			continue
+		}
+
+		if invalid {
+			continue
+		}
+		if table == nil {
+			if table, err = newCSVTable(record, annotations); err != nil {
+				c.Errors.add("line", line, err)
+				invalid = true
+			}
+			continue
+		}
+
+		p, err := table.point(record, precision)
+		if err != nil {
+			c.Errors.add("line", line, err)
+			continue
+		}
+		if len(p.fields) == 0 {
+			continue
+		}
+		if err := c.appendPoint(p, line); err != nil {
+			c.Errors.add("line", line, err)
+		}
+	}
+	return c, nil
+}
+
+// csvRecords splits CSV into records, keeping track of the line of each
+// record, as a quoted value may span several lines.
+type csvRecords struct {
+	data []byte
+	line int
+}
+
+func newCSVRecords(data []byte) *csvRecords {
+	return &csvRecords{data: data}
+}
+
+// next returns the first line of the next record, and its values. The
+// values of an empty line are empty.
+func (r *csvRecords) next() (int, []string, error) {
+	if len(r.data) == 0 {
+		return r.line, nil, io.EOF
+	}
+
+	var (
+		start  = r.line + 1
+		n      int
+		quotes int
+	)
+	for {
+		i := bytes.IndexByte(r.data[n:], '\n')
+		r.line++
+		if i == -1 {
+			n = len(r.data)
+			break
+		}
+		quotes += bytes.Count(r.data[n:n+i], []byte{'"'})
+		n += i + 1
+		if quotes%2 == 0 {
+			break
+		}
+	}
+	record := r.data[:n]
+	r.data = r.data[n:]
+
+	if len(bytes.TrimSpace(record)) == 0 {
+		return start, []string{}, nil
+	}
+
+	cr := csv.NewReader(bytes.NewReader(record))
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	values, err := cr.Read()
+	if pe, ok := err.(*csv.ParseError); ok {
+		// The line of the error is relative to the record.
+		return start, nil, pe.Err
+	} else if err != nil {
+		return start, nil, err
+	}
+	return start, values, nil
+}
@@ -0,0 +1,254 @@
+package write
+
+import (
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	platform "github.com/influxdata/influxdb"
+	"github.com/influxdata/influxdb/models"
+)
+
+// Format is a format of the points written to InfluxDB.
+type Format string
+
+const (
+	// FormatLineProtocol is the line protocol.
+	FormatLineProtocol Format = "lp"
+	// FormatCSV is the annotated CSV emitted by Flux.
+	FormatCSV Format = "csv"
+	// FormatJSON is an array of JSON points.
+	FormatJSON Format = "json"
+)
+
+// Valid returns an error if f is not a known format.
+func (f Format) Valid() error {
+	switch f {
+	case FormatLineProtocol, FormatCSV, FormatJSON:
+		return nil
+	default:
+		return &platform.Error{
+			Code: platform.EInvalid,
+			Msg:  fmt.Sprintf("invalid format %q; valid formats are lp, csv and json", string(f)),
+		}
+	}
+}
+
+// ContentType returns the media type of the format.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSON:
+		return "application/json; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// FormatFromContentType returns the format of a media type. Media types
+// other than those of CSV and JSON are line protocol, as clients of the
+// write API do not always set the media type of line protocol.
+func FormatFromContentType(contentType string) Format {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return FormatLineProtocol
+	}
+
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV
+	case "application/json":
+		return FormatJSON
+	default:
+		return FormatLineProtocol
+	}
+}
+
+// ToLineProtocol converts points in the format f to line protocol, with the
+// timestamps in the precision, one point per line. The conversion errors
+// of all the points are returned together, one per line.
+func ToLineProtocol(f Format, data []byte, precision string) ([]byte, error) {
+	c, err := Convert(f, data, precision)
+	if err != nil {
+		return nil, err
+	}
+	return c.LineProtocol, c.Errors.err()
+}
+
+// Conversion is the line protocol converted from points in another format.
+type Conversion struct {
+	LineProtocol []byte
+	// Sources are the source of each line of the line protocol: the line of
+	// CSV, or the 1-based position of a JSON point.
+	Sources []int
+	// Errors are the errors of the points that were not converted.
+	Errors ConversionErrors
+}
+
+// Convert converts points in the format f to line protocol, with the
+// timestamps in the precision, one point per line. The points that cannot
+// be converted are skipped and their errors are part of the conversion; an
+// error is returned only if none of the data can be converted.
+func Convert(f Format, data []byte, precision string) (*Conversion, error) {
+	switch f {
+	case FormatLineProtocol:
+		return &Conversion{LineProtocol: data}, nil
+	case FormatCSV:
+		return csvToLineProtocol(data, precision)
+	case FormatJSON:
+		return jsonToLineProtocol(data, precision)
+	default:
+		return nil, f.Valid()
+	}
+}
+
+// appendPoint appends the line of a point from source to the conversion.
+func (c *Conversion) appendPoint(p *point, source int) error {
+	buf, err := p.appendTo(c.LineProtocol)
+	if err != nil {
+		return err
+	}
+	c.LineProtocol = buf
+	c.Sources = append(c.Sources, source)
+	return nil
+}
+
+var (
+	// Tag keys, tag values and field keys escape the same characters.
+	tagEscaper = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// point is a point being converted to line protocol. Field values are
+// encoded as in line protocol.
+type point struct {
+	measurement string
+	tags        [][2]string
+	fields      [][2]string
+	time        string
+}
+
+func (p *point) addTag(key, value string) {
+	p.tags = append(p.tags, [2]string{key, value})
+}
+
+func (p *point) addField(key, value string) {
+	p.fields = append(p.fields, [2]string{key, value})
+}
+
+// appendTo appends the line of the point to buf.
+func (p *point) appendTo(buf []byte) ([]byte, error) {
+	if p.measurement == "" {
+		return buf, fmt.Errorf("missing measurement")
+	} else if len(p.fields) == 0 {
+		return buf, fmt.Errorf("missing fields")
+	}
+
+	if err := checkName("measurement", p.measurement); err != nil {
+		return buf, err
+	}
+	buf = append(buf, models.EscapeMeasurement([]byte(p.measurement))...)
+	for _, tag := range p.tags {
+		if err := checkName("tag", tag[0]); err != nil {
+			return buf, err
+		} else if err := checkName("tag value", tag[1]); err != nil {
+			return buf, err
+		}
+		buf = append(buf, ',')
+		buf = append(buf, tagEscaper.Replace(tag[0])...)
+		buf = append(buf, '=')
+		buf = append(buf, tagEscaper.Replace(tag[1])...)
+	}
+	for i, field := range p.fields {
+		if err := checkName("field", field[0]); err != nil {
+			return buf, err
+		}
+		if i == 0 {
+			buf = append(buf, ' ')
+		} else {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, tagEscaper.Replace(field[0])...)
+		buf = append(buf, '=')
+		buf = append(buf, field[1]...)
+	}
+	if p.time != "" {
+		buf = append(buf, ' ')
+		buf = append(buf, p.time...)
+	}
+	return append(buf, '\n'), nil
+}
+
+func checkName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("empty %s name", kind)
+	} else if strings.ContainsAny(name, "\r\n") || strings.HasSuffix(name, `\`) {
+		return fmt.Errorf("invalid %s %q", kind, name)
+	}
+	return nil
+}
+
+// formatFloat encodes a float field value.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// formatInteger encodes an integer field value.
+func formatInteger(v int64) string {
+	return strconv.FormatInt(v, 10) + "i"
+}
+
+// formatUnsigned encodes an unsigned field value.
+func formatUnsigned(v uint64) string {
+	return strconv.FormatUint(v, 10) + "u"
+}
+
+// formatBoolean encodes a boolean field value.
+func formatBoolean(v bool) string {
+	return strconv.FormatBool(v)
+}
+
+// formatString encodes a string field value.
+func formatString(v string) string {
+	return `"` + models.EscapeStringField(v) + `"`
+}
+
+// formatTime encodes a time as a timestamp in the precision.
+func formatTime(t time.Time, precision string) (string, error) {
+	if err := models.CheckTime(t); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(t.UnixNano()/models.GetPrecisionMultiplier(precision), 10), nil
+}
+
+// ConversionError is the error of a point that could not be converted.
+type ConversionError struct {
+	Kind   string // line or point
+	Source int
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("unable to convert %s %d: %v", e.Kind, e.Source, e.Err)
+}
+
+// ConversionErrors are the errors of the points of a conversion.
+type ConversionErrors []*ConversionError
+
+func (e *ConversionErrors) add(kind string, source int, err error) {
+	*e = append(*e, &ConversionError{Kind: kind, Source: source, Err: err})
+}
+
+func (e ConversionErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "\n"))
+}
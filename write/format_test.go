@@ -0,0 +1,196 @@
+package write
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatFromContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Format
+	}{
+		{contentType: "", want: FormatLineProtocol},
+		{contentType: "text/plain; charset=utf-8", want: FormatLineProtocol},
+		{contentType: "application/x-www-form-urlencoded", want: FormatLineProtocol},
+		{contentType: "text/csv", want: FormatCSV},
+		{contentType: "application/csv; charset=utf-8", want: FormatCSV},
+		{contentType: "application/json", want: FormatJSON},
+		{contentType: FormatJSON.ContentType(), want: FormatJSON},
+	}
+	for _, tt := range tests {
+		if got := FormatFromContentType(tt.contentType); got != tt.want {
+			t.Errorf("FormatFromContentType(%q) = %q, want %q", tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestToLineProtocol_CSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		precision string
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "flux output",
+			input: `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:01Z,1.5,usage,cpu,a
+,,0,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:02Z,,usage,cpu,a
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,long,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,1,2020-01-01T00:00:00Z,2020-01-02T00:00:00Z,2020-01-01T00:00:01Z,4,cores,cpu,b
+`,
+			precision: "s",
+			want: "cpu,host=a usage=1.5 1577836801\n" +
+				"cpu,host=b cores=4i 1577836801\n",
+		},
+		{
+			name: "pivoted columns",
+			input: `#datatype,measurement,tag,dateTime:number,double,boolean,string,unsignedLong
+,name,host,time,usage,up,note,count
+,cpu,a b,1,0.5,true,"say ""hi""",3
+`,
+			precision: "ns",
+			want:      "cpu,host=a\\ b usage=0.5,up=true,note=\"say \\\"hi\\\"\",count=3u 1\n",
+		},
+		{
+			name: "plain csv",
+			input: `_measurement,_field,_value
+mem,free,used
+`,
+			precision: "ns",
+			want:      "mem free=\"used\"\n",
+		},
+		{
+			name: "errors of each line",
+			input: `#datatype,string,string,double
+,_measurement,_field,_value
+,cpu,usage,x
+,cpu,usage,1
+,,usage,2
+`,
+			precision: "ns",
+			wantErr: "unable to convert line 3: column \"_value\": strconv.ParseFloat: parsing \"x\": invalid syntax\n" +
+				"unable to convert line 5: missing measurement",
+		},
+		{
+			name: "missing measurement column",
+			input: `_field,_value
+usage,1
+`,
+			precision: "ns",
+			wantErr:   "unable to convert line 1: missing measurement column",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToLineProtocol(FormatCSV, []byte(tt.input), tt.precision)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("unexpected error: got %v, want %s", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("unexpected line protocol -want/+got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToLineProtocol_JSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		precision string
+		want      string
+		wantErr   string
+	}{
+		{
+			name: "points",
+			input: `[
+  {"measurement": "cpu", "tags": {"host": "a", "dc": "x,y"}, "fields": {"usage": 1, "cores": {"type": "integer", "value": 8}}, "time": 1600000000},
+  {"measurement": "cpu", "fields": {"up": true, "note": "ok", "n": {"type": "unsigned", "value": 2}, "skip": null}, "time": "2020-09-13T12:26:40.5Z"},
+  {"measurement": "mem", "fields": {"free": 2.5}}
+]`,
+			precision: "ms",
+			want: "cpu,dc=x\\,y,host=a cores=8i,usage=1 1600000000\n" +
+				"cpu n=2u,note=\"ok\",up=true 1600000000500\n" +
+				"mem free=2.5\n",
+		},
+		{
+			name: "errors of each point",
+			input: `[
+  {"measurement": "cpu", "fields": {"usage": {"type": "integer", "value": 1.5}}},
+  {"measurement": "cpu", "fields": {"usage": 1}},
+  {"measurement": "cpu", "fields": {}},
+  {"measurement": "cpu", "fields": {"usage": 1}, "time": "yesterday"}
+]`,
+			precision: "ns",
+			wantErr: "unable to convert point 1: field \"usage\": invalid integer 1.5\n" +
+				"unable to convert point 3: missing fields\n" +
+				"unable to convert point 4: time: parsing time \"yesterday\" as \"2006-01-02T15:04:05.999999999Z07:00\": cannot parse \"yesterday\" as \"2006\"",
+		},
+		{
+			name:      "not an array",
+			input:     `{"measurement": "cpu"}`,
+			precision: "ns",
+			wantErr:   "points must be a JSON array",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToLineProtocol(FormatJSON, []byte(tt.input), tt.precision)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("unexpected error: got %v, want %s", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("unexpected line protocol -want/+got\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConvert_Sources(t *testing.T) {
+	input := `#datatype,string,string,double
+,_measurement,_field,_value
+,cpu,usage,x
+,cpu,usage,1
+
+#datatype,string,string,double
+,_measurement,_field,_value
+,mem,free,2
+`
+	c, err := Convert(FormatCSV, []byte(input), "ns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff("cpu usage=1\nmem free=2\n", string(c.LineProtocol)); diff != "" {
+		t.Errorf("unexpected line protocol -want/+got\n%s", diff)
+	}
+	if diff := cmp.Diff([]int{4, 8}, c.Sources); diff != "" {
+		t.Errorf("unexpected sources -want/+got\n%s", diff)
+	}
+	if got, want := len(c.Errors), 1; got != want {
+		t.Fatalf("got %d errors, want %d", got, want)
+	}
+	if got, want := c.Errors[0].Source, 3; got != want {
+		t.Errorf("got error of line %d, want %d", got, want)
+	}
+}
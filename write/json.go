@@ -0,0 +1,213 @@
+package write
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The JSON format is an array of points such as:
+//
+//	[
+//	  {
+//	    "measurement": "cpu",
+//	    "tags": {"host": "a"},
+//	    "fields": {"usage": 0.5, "cores": {"type": "integer", "value": 8}},
+//	    "time": 1600000000
+//	  }
+//	]
+//
+// Numbers are float fields. Integer and unsigned fields are objects with a
+// type and a value; the type is one of float, integer, unsigned, string
+// and boolean. The time is either an integer in the precision or an RFC3339
+// string; points without a time are written at the time of the server.
+// Null fields are omitted.
+
+// jsonPoint is a point of the JSON format.
+type jsonPoint struct {
+	Measurement string                     `json:"measurement"`
+	Tags        map[string]string          `json:"tags"`
+	Fields      map[string]json.RawMessage `json:"fields"`
+	Time        json.RawMessage            `json:"time"`
+}
+
+// jsonTypedValue is a field value with an explicit type.
+type jsonTypedValue struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonToLineProtocol converts an array of JSON points to line protocol. The
+// points are numbered from 1.
+func jsonToLineProtocol(data []byte, precision string) (*Conversion, error) {
+	if data = bytes.TrimSpace(data); len(data) == 0 || data[0] != '[' {
+		return nil, fmt.Errorf("points must be a JSON array")
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	c := &Conversion{}
+	for i, r := range raw {
+		p, err := newJSONPoint(r, precision)
+		if err == nil {
+			err = c.appendPoint(p, i+1)
+		}
+		if err != nil {
+			c.Errors.add("point", i+1, err)
+		}
+	}
+	return c, nil
+}
+
+func newJSONPoint(data json.RawMessage, precision string) (*point, error) {
+	var jp jsonPoint
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&jp); err != nil {
+		return nil, err
+	}
+
+	p := &point{measurement: jp.Measurement}
+	keys := make([]string, 0, len(jp.Tags))
+	for k := range jp.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p.addTag(k, jp.Tags[k])
+	}
+
+	keys = keys[:0]
+	for k := range jp.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, err := formatJSONField(jp.Fields[k])
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", k, err)
+		}
+		if v != "" {
+			p.addField(k, v)
+		}
+	}
+
+	ts, err := parseJSONTime(jp.Time, precision)
+	if err != nil {
+		return nil, fmt.Errorf("time: %v", err)
+	}
+	p.time = ts
+	return p, nil
+}
+
+// formatJSONField encodes a field value, or returns an empty string for a
+// null value.
+func formatJSONField(data json.RawMessage) (string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return "", err
+		}
+		return formatString(s), nil
+	case 't', 'f':
+		var b bool
+		if err := json.Unmarshal(data, &b); err != nil {
+			return "", err
+		}
+		return formatBoolean(b), nil
+	case '{':
+		var tv jsonTypedValue
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&tv); err != nil {
+			return "", err
+		}
+		return formatJSONTypedField(tv)
+	default:
+		f, err := strconv.ParseFloat(string(data), 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %s", data)
+		}
+		return formatFloat(f), nil
+	}
+}
+
+func formatJSONTypedField(tv jsonTypedValue) (string, error) {
+	v := bytes.TrimSpace(tv.Value)
+	if len(v) == 0 || bytes.Equal(v, []byte("null")) {
+		return "", nil
+	}
+
+	switch tv.Type {
+	case "float":
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid float %s", v)
+		}
+		return formatFloat(f), nil
+	case "integer":
+		i, err := strconv.ParseInt(string(v), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid integer %s", v)
+		}
+		return formatInteger(i), nil
+	case "unsigned":
+		u, err := strconv.ParseUint(string(v), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid unsigned %s", v)
+		}
+		return formatUnsigned(u), nil
+	case "string":
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return "", fmt.Errorf("invalid string %s", v)
+		}
+		return formatString(s), nil
+	case "boolean":
+		var b bool
+		if err := json.Unmarshal(v, &b); err != nil {
+			return "", fmt.Errorf("invalid boolean %s", v)
+		}
+		return formatBoolean(b), nil
+	default:
+		return "", fmt.Errorf("invalid type %q; valid types are float, integer, unsigned, string and boolean", tv.Type)
+	}
+}
+
+// parseJSONTime returns the timestamp of a time, either an integer in the
+// precision or an RFC3339 string, or an empty string if there is no time.
+func parseJSONTime(data json.RawMessage, precision string) (string, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+
+	if data[0] != '"' {
+		i, err := strconv.ParseInt(string(data), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid timestamp %s", data)
+		}
+		return strconv.FormatInt(i, 10), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", err
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return "", err
+	}
+	return formatTime(t, precision)
+}
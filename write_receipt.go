@@ -0,0 +1,68 @@
+package influxdb
+
+import (
+	"context"
+	"time"
+)
+
+// ErrWriteReceiptNotFound is returned when a write receipt does not exist.
+var ErrWriteReceiptNotFound = &Error{
+	Code: ENotFound,
+	Msg:  "write receipt not found",
+}
+
+// ops for write receipt errors.
+var (
+	OpFindWriteReceiptByID = "FindWriteReceiptByID"
+	OpCreateWriteReceipt   = "CreateWriteReceipt"
+	OpUpdateWriteReceipt   = "UpdateWriteReceipt"
+	OpDeleteWriteReceipts  = "DeleteWriteReceipts"
+)
+
+// WriteReceiptStatus is the status of a queued write.
+type WriteReceiptStatus string
+
+const (
+	// WriteReceiptPending is the status of a write waiting in the queue.
+	WriteReceiptPending WriteReceiptStatus = "pending"
+	// WriteReceiptWritten is the status of a write of which all the points
+	// were written.
+	WriteReceiptWritten WriteReceiptStatus = "written"
+	// WriteReceiptPartial is the status of a write of which some lines were
+	// rejected. The points of the other lines were written.
+	WriteReceiptPartial WriteReceiptStatus = "partial"
+	// WriteReceiptFailed is the status of a write of which no points were
+	// written.
+	WriteReceiptFailed WriteReceiptStatus = "failed"
+)
+
+// WriteReceipt acknowledges a write queued to be written asynchronously, and
+// reports its status once written.
+type WriteReceipt struct {
+	ID       ID                 `json:"id"`
+	OrgID    ID                 `json:"orgID"`
+	BucketID ID                 `json:"bucketID"`
+	Status   WriteReceiptStatus `json:"status"`
+	// Message is the error of a write that failed or was partially written.
+	Message    string            `json:"message,omitempty"`
+	Accepted   int               `json:"accepted"`
+	Rejected   int               `json:"rejected"`
+	Rejections []*WriteRejection `json:"rejections,omitempty"`
+	CRUDLog
+}
+
+// WriteReceiptService stores the receipts of queued writes.
+type WriteReceiptService interface {
+	// FindWriteReceiptByID returns a single receipt by ID.
+	FindWriteReceiptByID(ctx context.Context, id ID) (*WriteReceipt, error)
+
+	// CreateWriteReceipt creates a pending receipt and sets r.ID with the new
+	// identifier.
+	CreateWriteReceipt(ctx context.Context, r *WriteReceipt) error
+
+	// UpdateWriteReceipt sets the status of a receipt.
+	UpdateWriteReceipt(ctx context.Context, r *WriteReceipt) error
+
+	// DeleteWriteReceipts removes the receipts last updated before a time.
+	DeleteWriteReceipts(ctx context.Context, before time.Time) error
+}